@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// Feeds the SHA-256 precompile's output into further EVM work in the same
+// view call. zk executors count sha256 and keccak256 with different
+// counters, so a bug may only show once both run in one transaction.
+contract ComposedHashes {
+    // keccak256 of the sha256 digest.
+    function sha256ThenKeccak(bytes calldata input) external view returns (bytes32) {
+        return keccak256(abi.encodePacked(sha256(input)));
+    }
+
+    // sha256 of the keccak256 hash: the precompile's input is computed
+    // in memory by the previous step.
+    function keccakThenSha256(bytes calldata input) external view returns (bytes32) {
+        return sha256(abi.encodePacked(keccak256(input)));
+    }
+
+    // The digest, its keccak256 and the input length, ABI-encoded together,
+    // as a contract building a commitment would.
+    function pipeline(bytes calldata input) external view returns (bytes memory) {
+        bytes32 digest = sha256(input);
+        return abi.encode(digest, keccak256(abi.encodePacked(digest)), input.length);
+    }
+
+    // Hashes input with sha256, then alternates keccak256 and sha256 over
+    // the previous hash for the given number of rounds.
+    function alternate(bytes calldata input, uint256 rounds) external view returns (bytes32 h) {
+        h = sha256(input);
+        for (uint256 i = 1; i <= rounds; i++) {
+            if (i % 2 == 1) {
+                h = keccak256(abi.encodePacked(h));
+            } else {
+                h = sha256(abi.encodePacked(h));
+            }
+        }
+    }
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+interface IERC1271 {
+    function isValidSignature(bytes32 hash, bytes calldata signature) external view returns (bytes4);
+}
+
+// A minimal ERC-1271 smart wallet: a signature is valid when the ecrecover
+// precompile recovers the owner from it. A wallet owned by another contract
+// defers to that contract's isValidSignature, as nested smart accounts do,
+// so the precompile is then reached one call deeper.
+contract ERC1271Wallet is IERC1271 {
+    bytes4 private constant MAGIC = 0x1626ba7e;
+    bytes4 private constant INVALID = 0xffffffff;
+
+    // secp256k1n / 2: EIP-2 rejects signatures with a larger s.
+    uint256 private constant HALF_N = 0x7fffffffffffffffffffffffffffffff5d576e7357a4501ddfe92f46681b20a0;
+
+    address public immutable owner;
+
+    constructor(address _owner) {
+        owner = _owner;
+    }
+
+    // The signature is r, s and v packed into 65 bytes, v being 27 or 28.
+    function isValidSignature(bytes32 hash, bytes calldata signature) external view returns (bytes4) {
+        if (owner.code.length > 0) {
+            try IERC1271(owner).isValidSignature(hash, signature) returns (bytes4 value) {
+                return value == MAGIC ? MAGIC : INVALID;
+            } catch {
+                return INVALID;
+            }
+        }
+        if (signature.length != 65) {
+            return INVALID;
+        }
+        bytes32 r;
+        bytes32 s;
+        uint8 v;
+        assembly {
+            r := calldataload(signature.offset)
+            s := calldataload(add(signature.offset, 32))
+            v := byte(0, calldataload(add(signature.offset, 64)))
+        }
+        if (uint256(s) > HALF_N || (v != 27 && v != 28)) {
+            return INVALID;
+        }
+        address recovered = ecrecover(hash, v, r, s);
+        return recovered != address(0) && recovered == owner ? MAGIC : INVALID;
+    }
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// Code generated by pkg/groth16 from the toy setup of the circuit
+// "x^3 + x + 5 = out", seeded with "cdk-erigon-precompile groth16 toy setup". DO NOT EDIT.
+// Regenerate with: go run scripts/groth16.go --write-verifier contracts/Groth16Verifier.sol
+//
+// Verifies Groth16 proofs over bn256 with the ecAdd (0x06), ecMul (0x07)
+// and ecPairing (0x08) precompiles. The setup's trapdoor is public, so
+// anyone can forge proofs: the contract tests the EVM, not the statement.
+contract Groth16Verifier {
+    address constant EC_ADD = address(uint160(0x06));
+    address constant EC_MUL = address(uint160(0x07));
+    address constant EC_PAIRING = address(uint160(0x08));
+
+    // Order of the bn256 groups: public inputs must be below it.
+    uint256 constant R = 21888242871839275222246405745257275088548364400416034343698204186575808495617;
+    // Modulus of the field the points lie over.
+    uint256 constant Q = 21888242871839275222246405745257275088696311157297823662689037894645226208583;
+
+    // Verifying key. G2 points have the imaginary part of each coordinate
+    // first, as ecPairing takes them.
+    uint256 constant ALPHA_X = 16083279108990055435639850991107096756763771389050184318812744492451866527492;
+    uint256 constant ALPHA_Y = 12553028807221726731266337744040468529527852625996566812816349698561810552376;
+    uint256 constant BETA_X1 = 3772964523740711072147651801310599031642202739132046672898623089263696855885;
+    uint256 constant BETA_X0 = 17582812874300538931661586697618891051420267705317970169068612050633548467580;
+    uint256 constant BETA_Y1 = 17472823714054949985595661136353236905509715016569404910646165909381610973708;
+    uint256 constant BETA_Y0 = 6644245112283944717529771859825489023785353560386284299796492974184813146950;
+    uint256 constant GAMMA_X1 = 11000270486928717244462252493233923834711531000786285041053238769656734588248;
+    uint256 constant GAMMA_X0 = 1849844471623165840059548068260386431980320000598899193610143751311709990020;
+    uint256 constant GAMMA_Y1 = 11050155244664289280420570582415626900312665527771105225063271308795891784379;
+    uint256 constant GAMMA_Y0 = 14334317413645694277157912706704418130618275738205549867958962590392089995941;
+    uint256 constant DELTA_X1 = 3961598269879614558121143205226036691153460339550763140962033719183343619094;
+    uint256 constant DELTA_X0 = 8662720225483056785401186464141483894094892915166163979594590001608135405110;
+    uint256 constant DELTA_Y1 = 1516541084538936050179236516525642249185357687590261989470633799203000205;
+    uint256 constant DELTA_Y0 = 8639583663900143010283398253683648278405030214390477592947051249322812935001;
+    uint256 constant IC0_X = 333875727428221477918923943059243276138275837486285836872176329845225412165;
+    uint256 constant IC0_Y = 21175575469292045723513411317807915711038922631660493449511705034490730681905;
+    uint256 constant IC1_X = 14087031841430480421566520980134693215471387419310138944266163809308417345286;
+    uint256 constant IC1_Y = 17315439396771903629802933227393200894750555259128711461360819213723719870539;
+
+    // Checks one proof of the public inputs.
+    function verifyProof(uint256[2] calldata a, uint256[2][2] calldata b, uint256[2] calldata c, uint256[1] calldata input) public view returns (bool) {
+        // vk_x = IC0 + input[0] * IC1 + ...
+        uint256[2] memory x = [IC0_X, IC0_Y];
+        bool ok;
+        if (input[0] >= R) {
+            return false;
+        }
+        (ok, x) = ecMulAdd(x, [IC1_X, IC1_Y], input[0]);
+        if (!ok) {
+            return false;
+        }
+        if (a[1] >= Q) {
+            return false;
+        }
+
+        // e(-A, B) * e(alpha, beta) * e(vk_x, gamma) * e(C, delta) == 1
+        bytes memory pairs = abi.encode(a[0], (Q - a[1]) % Q, b[0][0], b[0][1], b[1][0], b[1][1]);
+        pairs = bytes.concat(pairs, abi.encode(ALPHA_X, ALPHA_Y, BETA_X1, BETA_X0, BETA_Y1, BETA_Y0));
+        pairs = bytes.concat(pairs, abi.encode(x[0], x[1], GAMMA_X1, GAMMA_X0, GAMMA_Y1, GAMMA_Y0));
+        pairs = bytes.concat(pairs, abi.encode(c[0], c[1], DELTA_X1, DELTA_X0, DELTA_Y1, DELTA_Y0));
+        (bool success, bytes memory out) = EC_PAIRING.staticcall(pairs);
+        return success && out.length == 32 && abi.decode(out, (uint256)) == 1;
+    }
+
+    // Checks a batch of proofs in one call, each on its own.
+    function verifyBatch(uint256[2][] calldata a, uint256[2][2][] calldata b, uint256[2][] calldata c, uint256[1][] calldata input) external view returns (bool[] memory valid) {
+        require(b.length == a.length && c.length == a.length && input.length == a.length, "batch length mismatch");
+        valid = new bool[](a.length);
+        for (uint256 i = 0; i < a.length; i++) {
+            valid[i] = verifyProof(a[i], b[i], c[i], input[i]);
+        }
+    }
+
+    // Returns sum + s * p with ecMul and ecAdd.
+    function ecMulAdd(uint256[2] memory sum, uint256[2] memory p, uint256 s) internal view returns (bool, uint256[2] memory) {
+        (bool ok, bytes memory out) = EC_MUL.staticcall(abi.encode(p[0], p[1], s));
+        if (!ok || out.length != 64) {
+            return (false, sum);
+        }
+        (uint256 x, uint256 y) = abi.decode(out, (uint256, uint256));
+        (ok, out) = EC_ADD.staticcall(abi.encode(sum[0], sum[1], x, y));
+        if (!ok || out.length != 64) {
+            return (false, sum);
+        }
+        (x, y) = abi.decode(out, (uint256, uint256));
+        return (true, [x, y]);
+    }
+}
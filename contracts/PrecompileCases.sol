@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// Calls any precompile with the same input taken from different places, so
+// each data path of the executor (CALLDATACOPY, MSTORE, SSTORE/SLOAD) feeds
+// the precompile. Every function answers the precompile's success flag and
+// output instead of reverting, so failures can be compared too.
+contract PrecompileCases {
+    bytes private stored;
+
+    // The input is copied from calldata into memory in one CALLDATACOPY.
+    function fromCalldata(address precompile, bytes calldata input) external view returns (bool success, bytes memory output) {
+        bytes memory buf = new bytes(input.length);
+        assembly {
+            calldatacopy(add(buf, 0x20), input.offset, input.length)
+        }
+        return invoke(precompile, buf);
+    }
+
+    // The input is rebuilt in freshly allocated memory one MSTORE per word.
+    // The last word may spill past the length but stays inside the
+    // allocation, which is rounded up to whole words.
+    function fromMemory(address precompile, bytes calldata input) external view returns (bool success, bytes memory output) {
+        bytes memory buf = new bytes(input.length);
+        assembly {
+            let dst := add(buf, 0x20)
+            for { let i := 0 } lt(i, input.length) { i := add(i, 0x20) } {
+                mstore(add(dst, i), calldataload(add(input.offset, i)))
+            }
+        }
+        return invoke(precompile, buf);
+    }
+
+    // The input is written to storage and read back before the call. It
+    // changes state, so it is meant for eth_call, where the write is
+    // discarded.
+    function fromStorage(address precompile, bytes calldata input) external returns (bool success, bytes memory output) {
+        stored = input;
+        return invoke(precompile, stored);
+    }
+
+    function invoke(address precompile, bytes memory input) private view returns (bool success, bytes memory output) {
+        (success, output) = precompile.staticcall(input);
+    }
+}
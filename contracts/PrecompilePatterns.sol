@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// Calls any precompile with the same input through different call
+// patterns: the one the compiler generates for a high-level staticcall, and
+// hand-written assembly. When a precompile answers wrongly through one
+// pattern, comparing the patterns with a direct call tells the compiler's
+// call sequence apart from the EVM's own semantics. Nothing reverts except
+// checkedCall, so failures can be compared too.
+contract PrecompilePatterns {
+    error ReturnSize(bool success, uint256 size);
+
+    // Compiler-generated: the high-level staticcall, which copies the
+    // return data with RETURNDATACOPY sized by RETURNDATASIZE.
+    function solidityCall(address precompile, bytes calldata input) external view returns (bool success, bytes memory output) {
+        (success, output) = precompile.staticcall(input);
+    }
+
+    // A STATICCALL with no output area, the return data then sized with
+    // RETURNDATASIZE and copied with RETURNDATACOPY by hand.
+    function assemblyCall(address precompile, bytes calldata input) external view returns (bool success, bytes memory output) {
+        assembly {
+            let ptr := mload(0x40)
+            calldatacopy(ptr, input.offset, input.length)
+            success := staticcall(gas(), precompile, ptr, input.length, 0, 0)
+            let size := returndatasize()
+            output := ptr
+            mstore(output, size)
+            returndatacopy(add(output, 0x20), 0, size)
+            mstore(0x40, add(add(output, 0x20), and(add(size, 0x1f), not(0x1f))))
+        }
+    }
+
+    // A STATICCALL writing straight into a zeroed output area of outSize
+    // bytes, as hand-optimized wrappers do. The area is returned whatever
+    // the precompile wrote into it, with RETURNDATASIZE as returnSize, so
+    // the caller does the size check.
+    function fixedCall(address precompile, bytes calldata input, uint256 outSize) external view returns (bool success, uint256 returnSize, bytes memory output) {
+        output = new bytes(outSize);
+        assembly {
+            let ptr := mload(0x40)
+            calldatacopy(ptr, input.offset, input.length)
+            success := staticcall(gas(), precompile, ptr, input.length, add(output, 0x20), outSize)
+            returnSize := returndatasize()
+        }
+    }
+
+    // fixedCall with the size check done in the contract: it reverts with
+    // ReturnSize unless the call succeeded with exactly outSize bytes.
+    function checkedCall(address precompile, bytes calldata input, uint256 outSize) external view returns (bytes memory output) {
+        output = new bytes(outSize);
+        bool success;
+        uint256 size;
+        assembly {
+            let ptr := mload(0x40)
+            calldatacopy(ptr, input.offset, input.length)
+            success := staticcall(gas(), precompile, ptr, input.length, add(output, 0x20), outSize)
+            size := returndatasize()
+        }
+        if (!success || size != outSize) {
+            revert ReturnSize(success, size);
+        }
+    }
+}
@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+interface ISha256Wrapper {
+    function sha256Hash(bytes memory input) external view returns (bytes32);
+}
+
+// Calls the precompile through an already deployed wrapper, exercising a
+// contract-to-contract path into 0x02.
+contract Sha256Client {
+    ISha256Wrapper public immutable wrapper;
+
+    constructor(address wrapperAddress) {
+        wrapper = ISha256Wrapper(wrapperAddress);
+    }
+
+    function check(bytes memory input, bytes32 expected) external view returns (bool) {
+        return wrapper.sha256Hash(input) == expected;
+    }
+}
@@ -0,0 +1,10 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import "./Sha256Lib.sol";
+
+contract Sha256LinkedWrapper {
+    function sha256Hash(bytes memory input) public view returns (bytes32) {
+        return Sha256Lib.hash(input);
+    }
+}
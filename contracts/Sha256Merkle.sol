@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// Builds and checks SHA-256 Merkle trees the way bridges and light clients
+// do: one sha256 precompile call per node, in a loop. Leaves are padded
+// with zero to a power of two and a node is sha256(left ++ right), as in
+// SSZ and the beacon chain deposit contract.
+contract Sha256Merkle {
+    // The root of leaves: zero for none, the leaf itself for one.
+    function root(bytes32[] calldata leaves) external view returns (bytes32) {
+        if (leaves.length == 0) {
+            return bytes32(0);
+        }
+        uint256 width = 1;
+        while (width < leaves.length) {
+            width <<= 1;
+        }
+        bytes32[] memory level = new bytes32[](width);
+        for (uint256 i = 0; i < leaves.length; i++) {
+            level[i] = leaves[i];
+        }
+        for (; width > 1; width >>= 1) {
+            for (uint256 i = 0; i < width / 2; i++) {
+                level[i] = sha256(abi.encodePacked(level[2 * i], level[2 * i + 1]));
+            }
+        }
+        return level[0];
+    }
+
+    // Checks that leaf is at index under expected, with branch listing the
+    // sibling at each level from the leaves up. An index beyond the
+    // branch's depth doesn't verify.
+    function verify(bytes32 leaf, bytes32[] calldata branch, uint256 index, bytes32 expected) external view returns (bool) {
+        if ((index >> branch.length) != 0) {
+            return false;
+        }
+        bytes32 node = leaf;
+        for (uint256 i = 0; i < branch.length; i++) {
+            if (((index >> i) & 1) == 1) {
+                node = sha256(abi.encodePacked(branch[i], node));
+            } else {
+                node = sha256(abi.encodePacked(node, branch[i]));
+            }
+        }
+        return node == expected;
+    }
+}
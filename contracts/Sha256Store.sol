@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+contract Sha256Store {
+    uint256 public count;
+    mapping(uint256 => bytes32) public hashes;
+
+    event Stored(uint256 indexed index, bytes32 hash);
+
+    function store(bytes calldata input) external returns (bytes32 result) {
+        result = sha256(input);
+        hashes[count] = result;
+        emit Stored(count, result);
+        count++;
+    }
+}
@@ -13,4 +13,55 @@ contract Sha256Wrapper {
             result := mload(outPtr)
         }
     }
+
+    // selftest runs known-answer checks against the precompile and sets bit
+    // i of passed for each check i that passed, so a deployment can confirm
+    // the basics in one call. A failing precompile call fails its check
+    // instead of reverting.
+    //
+    //   0: empty input
+    //   1: "abc"
+    //   2: "hello world"
+    //   3: the 56-byte FIPS 180-2 message, padded into two blocks
+    //   4: 64 zero bytes, exactly one block
+    //   5: the answer to "abc" is exactly 32 bytes
+    function selftest() public view returns (uint256 passed) {
+        bool ok;
+        bytes32 digest;
+        uint256 size;
+
+        (ok, digest, ) = _probe("");
+        if (ok && digest == 0xe3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855) {
+            passed |= 1 << 0;
+        }
+        (ok, digest, size) = _probe("abc");
+        if (ok && digest == 0xba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad) {
+            passed |= 1 << 1;
+        }
+        if (ok && size == 32) {
+            passed |= 1 << 5;
+        }
+        (ok, digest, ) = _probe("hello world");
+        if (ok && digest == 0xb94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9) {
+            passed |= 1 << 2;
+        }
+        (ok, digest, ) = _probe("abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq");
+        if (ok && digest == 0x248d6a61d20638b8e5c026930c3e6039a33ce45964ff2167f6ecedd419db06c1) {
+            passed |= 1 << 3;
+        }
+        (ok, digest, ) = _probe(new bytes(64));
+        if (ok && digest == 0xf5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b) {
+            passed |= 1 << 4;
+        }
+    }
+
+    function _probe(bytes memory input) private view returns (bool ok, bytes32 digest, uint256 size) {
+        assembly {
+            let outPtr := mload(0x40)
+            mstore(outPtr, 0)
+            ok := staticcall(gas(), 0x02, add(input, 0x20), mload(input), outPtr, 32)
+            digest := mload(outPtr)
+            size := returndatasize()
+        }
+    }
 }
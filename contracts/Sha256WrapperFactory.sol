@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// Deploys contracts with its own CREATE or CREATE2 rather than from an EOA,
+// so stage 2 can check the addresses a contract derives and that precompile
+// calls work in contracts created by contracts.
+contract Sha256WrapperFactory {
+    event Deployed(address indexed deployed);
+
+    // deploy runs initCode with CREATE and reverts if the creation failed,
+    // so a receipt with status 1 always carries the Deployed event.
+    function deploy(bytes memory initCode) external returns (address deployed) {
+        assembly {
+            deployed := create(0, add(initCode, 0x20), mload(initCode))
+        }
+        require(deployed != address(0), "create failed");
+        emit Deployed(deployed);
+    }
+
+    // deploy2 is deploy with CREATE2 and salt, placing the contract at an
+    // address independent of the factory's nonce.
+    function deploy2(bytes memory initCode, bytes32 salt) external returns (address deployed) {
+        assembly {
+            deployed := create2(0, add(initCode, 0x20), mload(initCode), salt)
+        }
+        require(deployed != address(0), "create2 failed");
+        emit Deployed(deployed);
+    }
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// Verifies EIP-712 typed-data signatures through the ecrecover precompile,
+// the way permits, meta-transactions and off-chain orders do. The typed data
+// is the Mail example of the EIP itself, whose nested Person struct exercises
+// both the encodeType ordering and the recursion of hashStruct.
+contract TypedDataVerifier {
+    struct Person {
+        string name;
+        address wallet;
+    }
+
+    struct Mail {
+        Person from;
+        Person to;
+        string contents;
+    }
+
+    string public constant NAME = "PrecompileTest";
+    string public constant VERSION = "1";
+
+    bytes32 private constant DOMAIN_TYPEHASH =
+        keccak256("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)");
+    bytes32 private constant PERSON_TYPEHASH = keccak256("Person(string name,address wallet)");
+    bytes32 private constant MAIL_TYPEHASH =
+        keccak256("Mail(Person from,Person to,string contents)Person(string name,address wallet)");
+
+    // secp256k1n / 2: EIP-2 rejects signatures with a larger s.
+    uint256 private constant HALF_N = 0x7fffffffffffffffffffffffffffffff5d576e7357a4501ddfe92f46681b20a0;
+
+    // The domain is rebuilt on every call rather than cached, so a fork
+    // changing block.chainid is picked up.
+    function domainSeparator() public view returns (bytes32) {
+        return keccak256(
+            abi.encode(DOMAIN_TYPEHASH, keccak256(bytes(NAME)), keccak256(bytes(VERSION)), block.chainid, address(this))
+        );
+    }
+
+    function digest(Mail calldata mail) public view returns (bytes32) {
+        bytes32 structHash = keccak256(
+            abi.encode(MAIL_TYPEHASH, hashPerson(mail.from), hashPerson(mail.to), keccak256(bytes(mail.contents)))
+        );
+        return keccak256(abi.encodePacked("\x19\x01", domainSeparator(), structHash));
+    }
+
+    // recover answers whatever the precompile does, the zero address
+    // included, without the checks verify adds.
+    function recover(Mail calldata mail, uint8 v, bytes32 r, bytes32 s) public view returns (address) {
+        return ecrecover(digest(mail), v, r, s);
+    }
+
+    // verify accepts only canonical signatures (v of 27 or 28, low s) by
+    // signer, as OpenZeppelin's ECDSA does.
+    function verify(Mail calldata mail, address signer, uint8 v, bytes32 r, bytes32 s) external view returns (bool) {
+        if (uint256(s) > HALF_N || (v != 27 && v != 28)) {
+            return false;
+        }
+        address recovered = recover(mail, v, r, s);
+        return recovered != address(0) && recovered == signer;
+    }
+
+    function hashPerson(Person calldata p) private pure returns (bytes32) {
+        return keccak256(abi.encode(PERSON_TYPEHASH, keccak256(bytes(p.name)), p.wallet));
+    }
+}
@@ -0,0 +1,88 @@
+// Package fuzz exposes the node comparison to Go's native fuzzing, so
+// `go test -fuzz` generates inputs, keeps the interesting ones in its
+// corpus and minimizes any input the node gets wrong:
+//
+//	go test ./fuzz -run '^$' -fuzz FuzzSha256Precompile -fuzztime 5m
+//
+// Each target sends its input to a precompile with eth_call and compares
+// the answer with the reference implementation. The node is FUZZ_RPC_URL,
+// or RPC_HOST and RPC_PORT from the environment or the repository's .env;
+// without one the targets skip, so `go test ./...` stays offline.
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+// URLEnv overrides the node the targets fuzz.
+const URLEnv = "FUZZ_RPC_URL"
+
+// Endpoint returns the node to fuzz, and false when none is configured.
+func Endpoint() (string, bool) {
+	if url := os.Getenv(URLEnv); url != "" {
+		return url, true
+	}
+	host, port := os.Getenv("RPC_HOST"), os.Getenv("RPC_PORT")
+	if host == "" || port == "" {
+		return "", false
+	}
+	return fmt.Sprintf("http://%s:%s", host, port), true
+}
+
+// ErrMismatch is matched by MismatchError.
+var ErrMismatch = errors.New("node and reference disagree")
+
+// MismatchError reports an input the node answered differently from the
+// reference implementation: with other bytes, or failing where the
+// reference succeeds, or the other way round.
+type MismatchError struct {
+	Precompile string
+	Input      []byte
+	Want       []byte
+	WantErr    error
+	Got        []byte
+	GotErr     error
+}
+
+func (e *MismatchError) Error() string {
+	want := fmt.Sprintf("%x", e.Want)
+	if e.WantErr != nil {
+		want = "failure (" + e.WantErr.Error() + ")"
+	}
+	got := fmt.Sprintf("%x", e.Got)
+	if e.GotErr != nil {
+		got = "failure (" + e.GotErr.Error() + ")"
+	}
+	return fmt.Sprintf("%s(%x): node answered %s, reference %s", e.Precompile, e.Input, got, want)
+}
+
+func (e *MismatchError) Is(target error) bool { return target == ErrMismatch }
+
+// Check calls p on the node with input and compares the answer with the
+// reference. Both failing agrees, whatever the reasons. It returns a
+// *MismatchError when they disagree, and the transport's error when the
+// node couldn't be asked.
+func Check(ctx context.Context, client *ethclient.Client, p reference.Precompile, input []byte) error {
+	want, wantErr := p.Run(input)
+	got, gotErr := client.CallContract(ctx, ethereum.CallMsg{To: &p.Address, Data: input}, nil)
+	// Only a JSON-RPC error is the node's answer; anything else, such as a
+	// timeout or a dropped connection, is the transport's
+	var rpcErr rpc.Error
+	if gotErr != nil && !errors.As(gotErr, &rpcErr) {
+		return gotErr
+	}
+	if (wantErr != nil) == (gotErr != nil) && (wantErr != nil || bytes.Equal(got, want)) {
+		return nil
+	}
+	return &MismatchError{Precompile: p.Name, Input: input, Want: want, WantErr: wantErr, Got: got, GotErr: gotErr}
+}
@@ -0,0 +1,56 @@
+package fuzz
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+func TestCheck(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	sha, _ := reference.ByName("sha256")
+	add, _ := reference.ByName("bn256add")
+	sum := sha256.Sum256([]byte("abc"))
+
+	s.Result("eth_call", hexutil.Bytes(sum[:]))
+	if err := Check(context.Background(), client, sha, []byte("abc")); err != nil {
+		t.Errorf("right answer: %v", err)
+	}
+	if err := Check(context.Background(), client, sha, []byte("abd")); !errors.Is(err, ErrMismatch) {
+		t.Errorf("wrong answer: got %v", err)
+	}
+
+	// The node failing where the reference does agrees
+	s.Handle("eth_call", mockrpc.Fail(&mockrpc.Error{Code: -32000, Message: "out of gas"}))
+	offCurve := make([]byte, 128)
+	offCurve[31], offCurve[63] = 1, 1
+	if _, err := add.Run(offCurve); err == nil {
+		t.Fatal("reference accepted a point off the curve")
+	}
+	if err := Check(context.Background(), client, add, offCurve); err != nil {
+		t.Errorf("both failing: %v", err)
+	}
+	var mismatch *MismatchError
+	if err := Check(context.Background(), client, sha, []byte("abc")); !errors.As(err, &mismatch) || mismatch.GotErr == nil {
+		t.Errorf("node failing a valid input: got %v", err)
+	}
+
+	// A dropped connection isn't the node's answer
+	s.Handle("eth_call", mockrpc.Fail(mockrpc.ErrDrop))
+	if err := Check(context.Background(), client, sha, []byte("abc")); err == nil || errors.Is(err, ErrMismatch) {
+		t.Errorf("dropped connection: got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package fuzz
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/reference"
+	"cdk-erigon-precompile/pkg/rpcclient"
+)
+
+// maxInput keeps hashing and copying inputs well under the eth_call gas cap.
+const maxInput = 16 << 10
+
+var (
+	nodeOnce   sync.Once
+	nodeClient *ethclient.Client
+	nodeErr    error
+)
+
+// node connects once per process to the configured node, skipping t when
+// there is none. The repository's .env fills in what the environment
+// doesn't set, as for the scripts.
+func node(t *testing.T) *ethclient.Client {
+	t.Helper()
+	nodeOnce.Do(func() {
+		dotenv := filepath.Join("..", envfile.Default)
+		if _, err := os.Stat(dotenv); err == nil {
+			if nodeErr = (&envfile.Options{Files: []string{dotenv}}).Load(); nodeErr != nil {
+				return
+			}
+		}
+		url, ok := Endpoint()
+		if !ok {
+			nodeErr = errors.New("no node configured: set " + URLEnv + " or RPC_HOST and RPC_PORT")
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		nodeClient, nodeErr = rpcclient.Connect(ctx, url)
+	})
+	if nodeErr != nil {
+		t.Skip(nodeErr)
+	}
+	return nodeClient
+}
+
+// check compares the node's answer for input with the reference of the
+// precompile called name. Inputs the node couldn't be asked about are
+// skipped rather than reported, so a flaky node doesn't pass for a bug.
+func check(t *testing.T, name string, input []byte) {
+	t.Helper()
+	p, err := reference.ByName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := node(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	switch err := Check(ctx, client, p, input); {
+	case errors.Is(err, ErrMismatch):
+		t.Fatal(err)
+	case err != nil:
+		t.Skipf("node unavailable: %v", err)
+	}
+}
+
+// bounded fuzzes the precompile called name with raw inputs up to maxInput
+// bytes, seeded with seeds.
+func bounded(f *testing.F, name string, seeds ...[]byte) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if len(input) > maxInput {
+			t.Skip()
+		}
+		check(t, name, input)
+	})
+}
+
+func FuzzSha256Precompile(f *testing.F) {
+	bounded(f, "sha256", nil, []byte("abc"), make([]byte, 55), make([]byte, 56), make([]byte, 64))
+}
+
+func FuzzRipemd160(f *testing.F) {
+	bounded(f, "ripemd160", nil, []byte("abc"), make([]byte, 64))
+}
+
+func FuzzIdentity(f *testing.F) {
+	bounded(f, "identity", nil, []byte{0x00}, make([]byte, 33))
+}
+
+func FuzzBN256Add(f *testing.F) {
+	g1 := common.FromHex("0x0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000002")
+	bounded(f, "bn256add", nil, append(g1, g1...), g1)
+}
+
+func FuzzBN256Mul(f *testing.F) {
+	g1 := common.FromHex("0x0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000002")
+	bounded(f, "bn256mul", nil, append(g1, common.LeftPadBytes([]byte{2}, 32)...))
+}
+
+func FuzzBlake2F(f *testing.F) {
+	bounded(f, "blake2f", nil, make([]byte, 213), append(make([]byte, 212), 1))
+}
+
+// FuzzModexp builds the input from its operands, so the declared lengths
+// always match and stay small enough to price under the gas cap.
+func FuzzModexp(f *testing.F) {
+	f.Add([]byte{3}, []byte{0xff, 0xff}, []byte{0x01, 0x00, 0x01})
+	f.Add([]byte{}, []byte{}, []byte{})
+	f.Add([]byte{2}, []byte{1}, []byte{0})
+	f.Fuzz(func(t *testing.T, base, exp, mod []byte) {
+		if len(base) > 256 || len(exp) > 64 || len(mod) > 256 {
+			t.Skip()
+		}
+		input := make([]byte, 0, 96+len(base)+len(exp)+len(mod))
+		for _, operand := range [][]byte{base, exp, mod} {
+			input = append(input, common.LeftPadBytes(big.NewInt(int64(len(operand))).Bytes(), 32)...)
+		}
+		input = append(append(append(input, base...), exp...), mod...)
+		check(t, "modexp", input)
+	})
+}
+
+// FuzzECRecover builds the input from its fields, with v mostly 27 or 28 so
+// most inputs are signatures rather than rejected outright.
+func FuzzECRecover(f *testing.F) {
+	f.Add(make([]byte, 32), byte(27), make([]byte, 32), make([]byte, 32))
+	f.Add(common.FromHex("0x456e9aea5e197a1f1af7a3e85a3212fa4049a3ba34c2289b4c860fc0b0c64ef3"), byte(28),
+		common.FromHex("0x9242685bf161793cc25603c231bc2f568eb630ea16aa137d2664ac8038825608"),
+		common.FromHex("0x4f8ae3bd7535248d0bd448298cc2e2071e56992d0774dc340c368ae950852ada"))
+	f.Fuzz(func(t *testing.T, hash []byte, v byte, r, s []byte) {
+		if len(hash) > 32 || len(r) > 32 || len(s) > 32 {
+			t.Skip()
+		}
+		input := common.LeftPadBytes(hash, 32)
+		input = append(input, common.LeftPadBytes([]byte{v}, 32)...)
+		input = append(append(input, common.LeftPadBytes(r, 32)...), common.LeftPadBytes(s, 32)...)
+		check(t, "ecrecover", input)
+	})
+}
@@ -0,0 +1,192 @@
+// Package hooks runs code at fixed points of the harness, inside the
+// process doing the work: before every contract creation a chain.Sender
+// signs, around each case of stages 1, 3 and 4, and after those stages and
+// each run.go pass. Since run.go starts every stage as its own process,
+// only code built into the harness can register hooks; pkg/plugin uses
+// them to run external validators, which is how programs outside the
+// harness judge cases.
+//
+// Before hooks veto: the first error stops the case or deployment, and no
+// later hook runs. After hooks assert: every one runs, and any error fails
+// the case or run. Errors come back as *Error, matching ErrHook.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Points at which hooks run, naming them in errors.
+const (
+	PointBeforeCase   = "BeforeCase"
+	PointAfterCase    = "AfterCase"
+	PointBeforeDeploy = "BeforeDeploy"
+	PointAfterRun     = "AfterRun"
+)
+
+// ErrHook matches every error returned by a hook.
+var ErrHook = errors.New("hook failed")
+
+// Error is a hook's error, with the point it ran at.
+type Error struct {
+	Point string
+	Err   error
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s hook: %v", e.Point, e.Err) }
+
+func (e *Error) Is(target error) bool { return target == ErrHook }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Case is one test case about to run.
+type Case struct {
+	// Stage names the stage running the case, as Run does.
+	Stage      string
+	ID         string
+	Precompile string
+	Input      []byte
+	// Target is the contract called, empty when the precompile is called
+	// directly.
+	Target string
+}
+
+// Outcome is how a case ended, as the stage judged it before the AfterCase
+// hooks ran.
+type Outcome struct {
+	Case
+	Passed bool
+	Error  string
+	// Output is what the node answered, for cases that call it.
+	Output []byte
+	// TxHash and Block are set for cases that mined a transaction.
+	TxHash string
+	Block  uint64
+}
+
+// Deployment is a contract creation about to be signed.
+type Deployment struct {
+	// Contract is the name given with chain.WithContract, if any.
+	Contract string
+	From     common.Address
+	Data     []byte
+	Gas      uint64
+}
+
+// Run is a finished stage or suite run.
+type Run struct {
+	// Stage is the stage's name, e.g. "Stage 3 - Invoke Solidity
+	// Wrapper".
+	Stage string
+	// Results is the path of the results file written.
+	Results string
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// Hooks are the functions to run at each point; nil ones are left out.
+type Hooks struct {
+	BeforeCase   func(context.Context, Case) error
+	AfterCase    func(context.Context, Outcome) error
+	BeforeDeploy func(context.Context, Deployment) error
+	AfterRun     func(context.Context, Run) error
+}
+
+var (
+	mu         sync.Mutex
+	registered []*Hooks
+)
+
+// Register adds h after those already registered and returns a function
+// removing it again.
+func Register(h Hooks) (unregister func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	entry := &h
+	registered = append(registered, entry)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for i, r := range registered {
+			if r == entry {
+				registered = append(registered[:i:i], registered[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func snapshot() []*Hooks {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]*Hooks(nil), registered...)
+}
+
+// BeforeCase runs the BeforeCase hooks in order until one fails. A case
+// whose hooks fail isn't run and counts as failed.
+func BeforeCase(ctx context.Context, c Case) error {
+	for _, h := range snapshot() {
+		if h.BeforeCase == nil {
+			continue
+		}
+		if err := h.BeforeCase(ctx, c); err != nil {
+			return &Error{Point: PointBeforeCase, Err: err}
+		}
+	}
+	return nil
+}
+
+// AfterCase runs every AfterCase hook. Any failing fails the case, even one
+// the stage passed.
+func AfterCase(ctx context.Context, o Outcome) error {
+	var errs []error
+	for _, h := range snapshot() {
+		if h.AfterCase == nil {
+			continue
+		}
+		if err := h.AfterCase(ctx, o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return wrap(PointAfterCase, errs)
+}
+
+// BeforeDeploy runs the BeforeDeploy hooks in order until one fails, which
+// stops the deployment before it is signed.
+func BeforeDeploy(ctx context.Context, d Deployment) error {
+	for _, h := range snapshot() {
+		if h.BeforeDeploy == nil {
+			continue
+		}
+		if err := h.BeforeDeploy(ctx, d); err != nil {
+			return &Error{Point: PointBeforeDeploy, Err: err}
+		}
+	}
+	return nil
+}
+
+// AfterRun runs every AfterRun hook. Any failing fails the run.
+func AfterRun(ctx context.Context, r Run) error {
+	var errs []error
+	for _, h := range snapshot() {
+		if h.AfterRun == nil {
+			continue
+		}
+		if err := h.AfterRun(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return wrap(PointAfterRun, errs)
+}
+
+func wrap(point string, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &Error{Point: point, Err: errors.Join(errs...)}
+}
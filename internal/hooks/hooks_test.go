@@ -0,0 +1,68 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestOrderAndErrors(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	refuse := errors.New("indexer down")
+	removeFirst := Register(Hooks{
+		BeforeCase: func(_ context.Context, c Case) error {
+			calls = append(calls, "first before "+c.ID)
+			return refuse
+		},
+		AfterCase: func(_ context.Context, o Outcome) error {
+			calls = append(calls, "first after "+o.ID)
+			return errors.New("not indexed")
+		},
+	})
+	removeSecond := Register(Hooks{
+		BeforeCase: func(_ context.Context, c Case) error {
+			calls = append(calls, "second before "+c.ID)
+			return nil
+		},
+		AfterCase: func(_ context.Context, o Outcome) error {
+			calls = append(calls, "second after "+o.ID)
+			return errors.New("wrong block")
+		},
+	})
+	defer removeSecond()
+
+	// A failing before hook stops the later ones; after hooks all run
+	err := BeforeCase(ctx, Case{ID: "a"})
+	var hookErr *Error
+	if !errors.Is(err, ErrHook) || !errors.Is(err, refuse) || !errors.As(err, &hookErr) || hookErr.Point != PointBeforeCase {
+		t.Errorf("BeforeCase: %v", err)
+	}
+	if err := AfterCase(ctx, Outcome{Case: Case{ID: "a"}}); !errors.Is(err, ErrHook) {
+		t.Errorf("AfterCase: %v", err)
+	}
+	if want := []string{"first before a", "first after a", "second after a"}; !slices.Equal(calls, want) {
+		t.Errorf("calls %q, want %q", calls, want)
+	}
+
+	removeFirst()
+	calls = nil
+	if err := BeforeCase(ctx, Case{ID: "b"}); err != nil {
+		t.Errorf("BeforeCase after removal: %v", err)
+	}
+	if want := []string{"second before b"}; !slices.Equal(calls, want) {
+		t.Errorf("calls %q, want %q", calls, want)
+	}
+	// Points without hooks pass
+	if err := BeforeDeploy(ctx, Deployment{}); err != nil {
+		t.Errorf("BeforeDeploy: %v", err)
+	}
+	if err := AfterRun(ctx, Run{}); err != nil {
+		t.Errorf("AfterRun: %v", err)
+	}
+	removeSecond()
+	if len(snapshot()) != 0 {
+		t.Error("hooks left registered")
+	}
+}
@@ -0,0 +1,164 @@
+// Package anchor records the chain state a run observed: the number, hash
+// and state root of the head block when the run started and when it
+// finished. The anchors of each results file are kept in the work
+// directory, so a later investigation can pin eth_call replays by block
+// hash to the exact state a run saw, even after the chain moved on.
+package anchor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// File holds the anchors of the last run of each results file, kept in the
+// work directory.
+const File = "anchors.json"
+
+// Point is the head of the chain at one moment of a run.
+type Point struct {
+	Block     uint64 `json:"block"`
+	Hash      string `json:"hash"`
+	StateRoot string `json:"stateRoot"`
+	// BlockTime is the block's timestamp, ObservedAt when it was read.
+	BlockTime  uint64 `json:"blockTime"`
+	ObservedAt string `json:"observedAt"`
+}
+
+// Ref pins calls to p's block by hash, so they fail rather than silently
+// run against another block if it was reorganized away.
+func (p Point) Ref() rpc.BlockNumberOrHash {
+	return rpc.BlockNumberOrHashWithHash(common.HexToHash(p.Hash), true)
+}
+
+// Take reads the head of the chain.
+func Take(ctx context.Context, client *ethclient.Client) (Point, error) {
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return Point{}, fmt.Errorf("failed to read the head block: %w", err)
+	}
+	return Point{
+		Block:      header.Number.Uint64(),
+		Hash:       header.Hash().Hex(),
+		StateRoot:  header.Root.Hex(),
+		BlockTime:  header.Time,
+		ObservedAt: time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// Run is the chain state one run of a script observed.
+type Run struct {
+	Results string `json:"results"`
+	ChainID string `json:"chainId"`
+	Start   *Point `json:"start,omitempty"`
+	End     *Point `json:"end,omitempty"`
+}
+
+// Moved reports whether the chain advanced, or reorganized, during the run.
+func (r Run) Moved() bool {
+	return r.Start != nil && r.End != nil && r.Start.Hash != r.End.Hash
+}
+
+// Recorder takes the anchors of one run.
+type Recorder struct {
+	client *ethclient.Client
+	run    Run
+}
+
+// Begin takes the start anchor of a run writing results, the results file
+// name in the work directory. Failing to read the chain only warns: the
+// run goes ahead without that anchor.
+func Begin(ctx context.Context, client *ethclient.Client, results string) *Recorder {
+	r := &Recorder{client: client, run: Run{Results: filepath.Base(results)}}
+	if id, err := client.ChainID(ctx); err == nil {
+		r.run.ChainID = id.String()
+	}
+	start, err := Take(ctx, client)
+	if err != nil {
+		log.Printf("⚠️  No start anchor for %s: %v", r.run.Results, err)
+		return r
+	}
+	r.run.Start = &start
+	return r
+}
+
+// Finish takes the end anchor and saves both to File. It still reads the
+// chain when ctx was cancelled, so interrupted runs are anchored too.
+func (r *Recorder) Finish(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	end, err := Take(ctx, r.client)
+	if err != nil {
+		log.Printf("⚠️  No end anchor for %s: %v", r.run.Results, err)
+	} else {
+		r.run.End = &end
+	}
+	if err := Save(paths.Work(File), r.run); err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+	if r.run.Start != nil && r.run.End != nil {
+		output.Logf(output.ModuleReport, output.Verbose, "chain anchors of %s: block %d (%s) to %d (%s)",
+			r.run.Results, r.run.Start.Block, r.run.Start.Hash, r.run.End.Block, r.run.End.Hash)
+	}
+}
+
+// Load reads the anchors at path by results file name; a missing file has
+// none.
+func Load(path string) (map[string]Run, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]Run{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anchors: %w", err)
+	}
+	runs := map[string]Run{}
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return runs, nil
+}
+
+// Lookup returns the anchors recorded for the results file at results,
+// looked up by file name in the anchors of the work directory.
+func Lookup(results string) (Run, error) {
+	runs, err := Load(paths.Work(File))
+	if err != nil {
+		return Run{}, err
+	}
+	run, ok := runs[filepath.Base(results)]
+	if !ok {
+		return Run{}, fmt.Errorf("no anchors recorded for %s in %s", filepath.Base(results), File)
+	}
+	return run, nil
+}
+
+// Save replaces the anchors of run's results file in the file at path.
+func Save(path string, run Run) error {
+	runs, err := Load(path)
+	if err != nil {
+		return err
+	}
+	runs[run.Results] = run
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := paths.WriteFile(path, data); err != nil {
+		return fmt.Errorf("failed to save anchors: %w", err)
+	}
+	return nil
+}
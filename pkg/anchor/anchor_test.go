@@ -0,0 +1,53 @@
+package anchor
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Setenv("WORK_DIR", t.TempDir())
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_chainId", "0x2775")
+	header := func(n int64) mockrpc.Handler {
+		return mockrpc.Static(&types.Header{Number: big.NewInt(n), Difficulty: new(big.Int), Root: common.HexToHash("0xaa")})
+	}
+	s.Handle("eth_getBlockByNumber", mockrpc.Sequence(header(100), header(104)))
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// The run is interrupted: the end anchor is still taken
+	ctx, cancel := context.WithCancel(context.Background())
+	r := Begin(ctx, client, paths.Work("results_stage3.json"))
+	cancel()
+	r.Finish(ctx)
+
+	run, err := Lookup("elsewhere/results_stage3.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ChainID != "10101" || run.Start == nil || run.End == nil || run.Start.Block != 100 || run.End.Block != 104 || !run.Moved() {
+		t.Fatalf("anchors %+v", run)
+	}
+	if run.Start.StateRoot != common.HexToHash("0xaa").Hex() {
+		t.Errorf("state root %s", run.Start.StateRoot)
+	}
+	if ref := run.End.Ref(); ref.BlockHash == nil || ref.BlockHash.Hex() != run.End.Hash || !ref.RequireCanonical {
+		t.Errorf("ref %+v", ref)
+	}
+	if _, err := Lookup("results_fuzz.json"); err == nil {
+		t.Error("found anchors of a results file never anchored")
+	}
+}
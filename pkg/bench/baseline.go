@@ -0,0 +1,81 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// Baseline is a previously recorded summary that later runs are compared
+// against.
+type Baseline struct {
+	Name     string  `json:"name"`
+	Recorded string  `json:"recorded"`
+	RPCURL   string  `json:"rpcUrl"`
+	Summary  Summary `json:"summary"`
+}
+
+// Comparison is the outcome of checking a run against its baseline.
+type Comparison struct {
+	BaselineMedian float64 `json:"baselineMedianMs"`
+	CurrentMedian  float64 `json:"currentMedianMs"`
+	DeltaPct       float64 `json:"deltaPct"`
+	ThresholdPct   float64 `json:"thresholdPct"`
+	Regressed      bool    `json:"regressed"`
+}
+
+// LoadBaseline reads a baseline file. A missing file returns nil without error
+// so the first run can record one.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+	return &baseline, nil
+}
+
+// SaveBaseline records summary as the new baseline for name.
+func SaveBaseline(path, name, rpcURL string, summary Summary) error {
+	baseline := Baseline{
+		Name:     name,
+		Recorded: time.Now().UTC().Format(time.RFC3339),
+		RPCURL:   rpcURL,
+		Summary:  summary,
+	}
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %w", err)
+	}
+	if err := paths.WriteFile(path, data); err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+	return nil
+}
+
+// Compare checks the median latency of current against the baseline. The
+// median is used rather than the mean because it is far less sensitive to
+// the odd slow round trip that survives outlier rejection. A threshold of
+// zero or less only reports the delta and never flags a regression.
+func Compare(baseline *Baseline, current Summary, thresholdPct float64) Comparison {
+	cmp := Comparison{
+		BaselineMedian: baseline.Summary.Median,
+		CurrentMedian:  current.Median,
+		ThresholdPct:   thresholdPct,
+	}
+	if baseline.Summary.Median > 0 {
+		cmp.DeltaPct = (current.Median - baseline.Summary.Median) / baseline.Summary.Median * 100
+	}
+	cmp.Regressed = thresholdPct > 0 && cmp.DeltaPct > thresholdPct
+	return cmp
+}
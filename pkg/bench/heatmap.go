@@ -0,0 +1,188 @@
+package bench
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sort"
+	"time"
+)
+
+// HeatCell summarizes the calls made in one hour of one day. Day is empty
+// for the hour-of-day aggregates of HeatmapData.Hours.
+type HeatCell struct {
+	Day      string  `json:"day,omitempty"`
+	Hour     int     `json:"hour"`
+	Calls    int     `json:"calls"`
+	Failures int     `json:"failures"`
+	Latency  Summary `json:"latency"`
+}
+
+// HeatmapData is the heatmap-ready dataset of a Heatmap: one cell per day
+// and hour with calls, plus each hour of the day over all days.
+type HeatmapData struct {
+	Timezone string     `json:"timezone"`
+	Cells    []HeatCell `json:"cells"`
+	Hours    []HeatCell `json:"hours"`
+}
+
+// Heatmap buckets latency samples by day and hour of day in a time zone,
+// so slowdowns that recur at the same time of day, such as a node's batch
+// verification or pruning cycles, line up in a column.
+type Heatmap struct {
+	loc   *time.Location
+	cells map[heatKey]*heatSamples
+}
+
+type heatKey struct {
+	day  string
+	hour int
+}
+
+type heatSamples struct {
+	latencies []float64
+	failures  int
+}
+
+// NewHeatmap returns an empty heatmap bucketing times in loc.
+func NewHeatmap(loc *time.Location) *Heatmap {
+	return &Heatmap{loc: loc, cells: map[heatKey]*heatSamples{}}
+}
+
+// Add records a call made at t. Failed calls are counted but their latency
+// is left out of the summaries.
+func (h *Heatmap) Add(t time.Time, latencyMs float64, failed bool) {
+	t = t.In(h.loc)
+	key := heatKey{t.Format(time.DateOnly), t.Hour()}
+	s := h.cells[key]
+	if s == nil {
+		s = &heatSamples{}
+		h.cells[key] = s
+	}
+	if failed {
+		s.failures++
+		return
+	}
+	s.latencies = append(s.latencies, latencyMs)
+}
+
+// Data returns the dataset, cells in time order.
+func (h *Heatmap) Data() HeatmapData {
+	data := HeatmapData{Timezone: h.loc.String()}
+	var hours [24]heatSamples
+	for key, s := range h.cells {
+		data.Cells = append(data.Cells, cell(key.day, key.hour, s))
+		hours[key.hour].latencies = append(hours[key.hour].latencies, s.latencies...)
+		hours[key.hour].failures += s.failures
+	}
+	sort.Slice(data.Cells, func(i, j int) bool {
+		a, b := data.Cells[i], data.Cells[j]
+		return a.Day < b.Day || a.Day == b.Day && a.Hour < b.Hour
+	})
+	for hour := range hours {
+		if s := &hours[hour]; len(s.latencies) > 0 || s.failures > 0 {
+			data.Hours = append(data.Hours, cell("", hour, s))
+		}
+	}
+	return data
+}
+
+func cell(day string, hour int, s *heatSamples) HeatCell {
+	return HeatCell{
+		Day:      day,
+		Hour:     hour,
+		Calls:    len(s.latencies) + s.failures,
+		Failures: s.failures,
+		Latency:  Summarize(s.latencies, 0),
+	}
+}
+
+// Heatmap image geometry, in pixels.
+const (
+	cellSize = 20
+	cellGap  = 1
+	tickRow  = 4
+)
+
+var (
+	emptyColor      = color.RGBA{0xee, 0xee, 0xee, 0xff}
+	failedColor     = color.RGBA{0x55, 0x22, 0x77, 0xff}
+	tickColor       = color.RGBA{0x66, 0x66, 0x66, 0xff}
+	backgroundColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// WritePNG renders the heatmap with one row per day with calls, oldest on
+// top, and one column per hour from midnight, ticked every six hours above
+// the grid. Cells shade from green at the lowest median latency to red at
+// the highest; hours where every call failed are purple and hours without
+// calls grey.
+func (h *Heatmap) WritePNG(w io.Writer) error {
+	data := h.Data()
+	var days []string
+	row := map[string]int{}
+	for _, c := range data.Cells {
+		if _, ok := row[c.Day]; !ok {
+			row[c.Day] = len(days)
+			days = append(days, c.Day)
+		}
+	}
+	lo, hi := -1.0, 0.0
+	for _, c := range data.Cells {
+		if c.Latency.Samples == 0 {
+			continue
+		}
+		if lo < 0 || c.Latency.Median < lo {
+			lo = c.Latency.Median
+		}
+		hi = max(hi, c.Latency.Median)
+	}
+
+	width := 24*(cellSize+cellGap) + cellGap
+	height := tickRow + max(1, len(days))*(cellSize+cellGap) + cellGap
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill(img, img.Bounds(), backgroundColor)
+	for hour := 0; hour < 24; hour += 6 {
+		x := cellGap + hour*(cellSize+cellGap)
+		fill(img, image.Rect(x, 0, x+cellSize, tickRow-1), tickColor)
+	}
+	for r := range days {
+		for hour := 0; hour < 24; hour++ {
+			fill(img, cellRect(r, hour), emptyColor)
+		}
+	}
+	for _, c := range data.Cells {
+		shade := failedColor
+		if c.Latency.Samples > 0 {
+			shade = scale(c.Latency.Median, lo, hi)
+		}
+		fill(img, cellRect(row[c.Day], c.Hour), shade)
+	}
+	return png.Encode(w, img)
+}
+
+func cellRect(row, hour int) image.Rectangle {
+	x := cellGap + hour*(cellSize+cellGap)
+	y := tickRow + cellGap + row*(cellSize+cellGap)
+	return image.Rect(x, y, x+cellSize, y+cellSize)
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// scale shades v between lo (green) and hi (red) through yellow.
+func scale(v, lo, hi float64) color.RGBA {
+	f := 0.0
+	if hi > lo {
+		f = (v - lo) / (hi - lo)
+	}
+	if f < 0.5 {
+		return color.RGBA{uint8(0x2e + (0xf1-0x2e)*f*2), 0xcc, 0x40, 0xff}
+	}
+	return color.RGBA{0xf1, uint8(0xcc - (0xcc-0x2a)*(f-0.5)*2), 0x40, 0xff}
+}
@@ -0,0 +1,70 @@
+package bench
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestHeatmapBuckets(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*3600)
+	h := NewHeatmap(loc)
+	day1 := time.Date(2026, 3, 1, 22, 30, 0, 0, time.UTC) // 00:30 on the 2nd in UTC+2
+	h.Add(day1, 10, false)
+	h.Add(day1.Add(time.Minute), 30, false)
+	h.Add(day1.Add(2*time.Minute), 0, true)
+	h.Add(day1.Add(24*time.Hour), 50, false)
+	h.Add(day1.Add(25*time.Hour), 5, false)
+
+	data := h.Data()
+	if data.Timezone != "UTC+2" || len(data.Cells) != 3 {
+		t.Fatalf("data %+v", data)
+	}
+	first := data.Cells[0]
+	if first.Day != "2026-03-02" || first.Hour != 0 || first.Calls != 3 || first.Failures != 1 || first.Latency.Median != 20 {
+		t.Errorf("first cell %+v", first)
+	}
+	if last := data.Cells[2]; last.Day != "2026-03-03" || last.Hour != 1 {
+		t.Errorf("last cell %+v", last)
+	}
+
+	// Midnight over both days
+	if len(data.Hours) != 2 || data.Hours[0].Hour != 0 || data.Hours[0].Calls != 4 || data.Hours[0].Latency.Median != 30 {
+		t.Errorf("hours %+v", data.Hours)
+	}
+}
+
+func TestHeatmapPNG(t *testing.T) {
+	h := NewHeatmap(time.UTC)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	h.Add(start, 1, false)
+	h.Add(start.Add(5*time.Hour), 9, false)
+	h.Add(start.Add(30*time.Hour), 0, true)
+
+	var buf bytes.Buffer
+	if err := h.WritePNG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two days of 24 hours
+	if b := img.Bounds(); b.Dx() != 24*(cellSize+cellGap)+cellGap || b.Dy() != tickRow+2*(cellSize+cellGap)+cellGap {
+		t.Fatalf("bounds %v", b)
+	}
+	at := func(row, hour int) [3]uint32 {
+		r, g, b, _ := img.At(cellRect(row, hour).Min.X+1, cellRect(row, hour).Min.Y+1).RGBA()
+		return [3]uint32{r >> 8, g >> 8, b >> 8}
+	}
+	if fast, slow := at(0, 0), at(0, 5); fast[0] >= slow[0] || fast[1] < slow[1] {
+		t.Errorf("fast hour %v not greener than slow hour %v", fast, slow)
+	}
+	if c := at(1, 6); c != [3]uint32{0x55, 0x22, 0x77} {
+		t.Errorf("failed hour %v", c)
+	}
+	if c := at(1, 0); c != [3]uint32{0xee, 0xee, 0xee} {
+		t.Errorf("empty hour %v", c)
+	}
+}
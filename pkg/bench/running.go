@@ -0,0 +1,52 @@
+package bench
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Running accumulates the count, extremes, mean and standard deviation of a
+// series without keeping its values, for runs too long to hold every
+// sample. Percentiles need the values and are left to Summarize.
+type Running struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	// m2 is the sum of squared differences from the mean (Welford).
+	m2 float64
+}
+
+// Add accumulates v.
+func (r *Running) Add(v float64) {
+	r.Count++
+	if r.Count == 1 || v < r.Min {
+		r.Min = v
+	}
+	if r.Count == 1 || v > r.Max {
+		r.Max = v
+	}
+	delta := v - r.Mean
+	r.Mean += delta / float64(r.Count)
+	r.m2 += delta * (v - r.Mean)
+	if r.Count > 1 {
+		r.StdDev = math.Sqrt(r.m2 / float64(r.Count-1))
+	}
+}
+
+// LengthBucket groups inputs whose length has the same bit length: empty
+// inputs, then 1, 2-3, 4-7 bytes and so on.
+type LengthBucket struct {
+	MinLength int `json:"minLength"`
+	MaxLength int `json:"maxLength"`
+}
+
+// BucketOf returns the bucket of an input of length n.
+func BucketOf(n int) LengthBucket {
+	b := bits.Len(uint(n))
+	if b == 0 {
+		return LengthBucket{}
+	}
+	return LengthBucket{MinLength: 1 << (b - 1), MaxLength: 1<<b - 1}
+}
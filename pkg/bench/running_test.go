@@ -0,0 +1,27 @@
+package bench
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRunningMatchesSummarize(t *testing.T) {
+	samples := []float64{12, 7.5, 9, 30, 11, 8.25, 10}
+	var r Running
+	for _, s := range samples {
+		r.Add(s)
+	}
+	want := Summarize(samples, 0)
+	if r.Count != want.Samples || r.Min != want.Min || r.Max != want.Max ||
+		math.Abs(r.Mean-want.Mean) > 1e-9 || math.Abs(r.StdDev-want.StdDev) > 1e-9 {
+		t.Errorf("running %+v, summary %+v", r, want)
+	}
+}
+
+func TestBucketOf(t *testing.T) {
+	for n, want := range map[int]LengthBucket{0: {0, 0}, 1: {1, 1}, 3: {2, 3}, 64: {64, 127}, 1023: {512, 1023}} {
+		if got := BucketOf(n); got != want {
+			t.Errorf("BucketOf(%d) = %+v, want %+v", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package bench holds the statistics used by the benchmark mode: summary
+// figures over repeated latency samples, outlier rejection and comparison
+// against a stored baseline. It also buckets the latencies of watch mode
+// into a time-of-day heatmap, and keeps running statistics by input length
+// for fuzz runs too long to keep every sample.
+package bench
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Summary describes a set of latency samples, all values in milliseconds.
+type Summary struct {
+	Samples   int     `json:"samples"`
+	Discarded int     `json:"discarded"`
+	Mean      float64 `json:"meanMs"`
+	Median    float64 `json:"medianMs"`
+	StdDev    float64 `json:"stdDevMs"`
+	Min       float64 `json:"minMs"`
+	Max       float64 `json:"maxMs"`
+	P95       float64 `json:"p95Ms"`
+}
+
+// Millis converts durations to float milliseconds.
+func Millis(samples []time.Duration) []float64 {
+	out := make([]float64, len(samples))
+	for i, d := range samples {
+		out[i] = float64(d) / float64(time.Millisecond)
+	}
+	return out
+}
+
+// RejectOutliers drops samples outside the Tukey fences
+// [Q1 - k*IQR, Q3 + k*IQR]. A k of zero or less keeps every sample.
+func RejectOutliers(samples []float64, k float64) (kept, discarded []float64) {
+	if k <= 0 || len(samples) < 4 {
+		return append([]float64(nil), samples...), nil
+	}
+
+	sorted := sortedCopy(samples)
+	q1 := percentile(sorted, 25)
+	q3 := percentile(sorted, 75)
+	iqr := q3 - q1
+	low, high := q1-k*iqr, q3+k*iqr
+
+	for _, s := range samples {
+		if s < low || s > high {
+			discarded = append(discarded, s)
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept, discarded
+}
+
+// Summarize computes summary statistics over samples after rejecting
+// outliers with the given fence multiplier.
+func Summarize(samples []float64, k float64) Summary {
+	kept, discarded := RejectOutliers(samples, k)
+	summary := Summary{Samples: len(kept), Discarded: len(discarded)}
+	if len(kept) == 0 {
+		return summary
+	}
+
+	sorted := sortedCopy(kept)
+	var sum float64
+	for _, s := range sorted {
+		sum += s
+	}
+	summary.Mean = sum / float64(len(sorted))
+	summary.Median = percentile(sorted, 50)
+	summary.P95 = percentile(sorted, 95)
+	summary.Min = sorted[0]
+	summary.Max = sorted[len(sorted)-1]
+
+	if len(sorted) > 1 {
+		var sq float64
+		for _, s := range sorted {
+			sq += (s - summary.Mean) * (s - summary.Mean)
+		}
+		summary.StdDev = math.Sqrt(sq / float64(len(sorted)-1))
+	}
+	return summary
+}
+
+func sortedCopy(samples []float64) []float64 {
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// percentile uses linear interpolation between closest ranks on an already
+// sorted slice.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
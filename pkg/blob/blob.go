@@ -0,0 +1,78 @@
+// Package blob builds the sidecars of EIP-4844 blob transactions locally:
+// it packs a payload into blobs, commits to each with KZG and proves the
+// commitments, so blob submission can be tested on nodes that have no
+// blob tooling of their own.
+package blob
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// elements is the number of field elements in a blob.
+	elements = len(kzg4844.Blob{}) / 32
+	// usable is how many payload bytes a field element carries. The top
+	// byte stays zero so every element is below the BLS12-381 modulus.
+	usable = 31
+	// Capacity is how many payload bytes a blob carries.
+	Capacity = elements * usable
+)
+
+// GasPerBlob is the blob gas each blob consumes.
+const GasPerBlob = params.BlobTxBlobGasPerBlob
+
+// Encode packs payload into as many blobs as it needs, at least one, 31
+// bytes per field element.
+func Encode(payload []byte) []kzg4844.Blob {
+	n := max(1, (len(payload)+Capacity-1)/Capacity)
+	blobs := make([]kzg4844.Blob, n)
+	for i := range payload {
+		b, e, off := i/Capacity, (i%Capacity)/usable, (i%Capacity)%usable
+		blobs[b][32*e+1+off] = payload[i]
+	}
+	return blobs
+}
+
+// Decode unpacks the payload bytes of blobs, including the zero padding of
+// the last one.
+func Decode(blobs []kzg4844.Blob) []byte {
+	payload := make([]byte, 0, len(blobs)*Capacity)
+	for i := range blobs {
+		for e := 0; e < elements; e++ {
+			payload = append(payload, blobs[i][32*e+1:32*(e+1)]...)
+		}
+	}
+	return payload
+}
+
+// Sidecar commits to each blob and proves the commitment.
+func Sidecar(blobs []kzg4844.Blob) (*types.BlobTxSidecar, error) {
+	sc := &types.BlobTxSidecar{Blobs: blobs}
+	for i := range blobs {
+		commitment, err := kzg4844.BlobToCommitment(&blobs[i])
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: commitment: %w", i, err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(&blobs[i], commitment)
+		if err != nil {
+			return nil, fmt.Errorf("blob %d: proof: %w", i, err)
+		}
+		sc.Commitments = append(sc.Commitments, commitment)
+		sc.Proofs = append(sc.Proofs, proof)
+	}
+	return sc, nil
+}
+
+// VersionedHash is the versioned hash of a commitment with its version
+// byte replaced, version 1 being the valid one.
+func VersionedHash(c kzg4844.Commitment, version byte) common.Hash {
+	h := common.Hash(kzg4844.CalcBlobHashV1(sha256.New(), &c))
+	h[0] = version
+	return h
+}
@@ -0,0 +1,55 @@
+package blob
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+func TestEncode(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, Capacity, Capacity + 1} {
+		payload := make([]byte, n)
+		for i := range payload {
+			payload[i] = byte(i*7 + 1)
+		}
+		blobs := Encode(payload)
+		if want := max(1, (n+Capacity-1)/Capacity); len(blobs) != want {
+			t.Errorf("%d bytes: %d blobs, want %d", n, len(blobs), want)
+		}
+		decoded := Decode(blobs)
+		if !bytes.Equal(decoded[:n], payload) || len(bytes.Trim(decoded[n:], "\x00")) != 0 {
+			t.Errorf("%d bytes: round trip differs", n)
+		}
+		for i := range blobs {
+			for e := 0; e < elements; e++ {
+				if blobs[i][32*e] != 0 {
+					t.Fatalf("%d bytes: blob %d element %d has a non-zero top byte", n, i, e)
+				}
+			}
+		}
+	}
+}
+
+func TestSidecar(t *testing.T) {
+	blobs := Encode(bytes.Repeat([]byte("cdk-erigon"), Capacity/5))
+	sc, err := Sidecar(blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Commitments) != 2 || len(sc.Proofs) != 2 {
+		t.Fatalf("%d commitments, %d proofs for 2 blobs", len(sc.Commitments), len(sc.Proofs))
+	}
+	for i := range blobs {
+		if err := kzg4844.VerifyBlobProof(&sc.Blobs[i], sc.Commitments[i], sc.Proofs[i]); err != nil {
+			t.Errorf("blob %d: %v", i, err)
+		}
+	}
+	hashes := sc.BlobHashes()
+	if h := VersionedHash(sc.Commitments[1], 1); h != hashes[1] {
+		t.Errorf("versioned hash %s, sidecar %s", h.Hex(), hashes[1].Hex())
+	}
+	if h := VersionedHash(sc.Commitments[0], 2); h[0] != 2 || !bytes.Equal(h[1:], hashes[0][1:]) {
+		t.Errorf("version 2 hash %s", h.Hex())
+	}
+}
@@ -0,0 +1,188 @@
+// Package bundle simulates many calls in one request through the bundle
+// endpoints some nodes offer: Erigon's eth_callMany and the trace_callMany
+// of the trace namespace. Both run the calls of a bundle in order, each on
+// the state the previous ones left, without committing anything, so a large
+// corpus costs a handful of round trips instead of one eth_call per input.
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Bundle methods.
+const (
+	CallMany      = "eth_callMany"
+	TraceCallMany = "trace_callMany"
+)
+
+// Methods lists the bundle methods.
+var Methods = []string{CallMany, TraceCallMany}
+
+// ErrUnsupported is returned when the node doesn't offer the method.
+var ErrUnsupported = errors.New("bundle simulation not supported")
+
+// Call is one call of a bundle. A zero From calls from the zero address;
+// a zero Gas leaves the limit to the node.
+type Call struct {
+	From common.Address
+	To   common.Address
+	Data []byte
+	Gas  uint64
+}
+
+func (c Call) args() map[string]any {
+	args := map[string]any{"from": c.From, "to": c.To, "data": hexutil.Bytes(c.Data)}
+	if c.Gas > 0 {
+		args["gas"] = hexutil.Uint64(c.Gas)
+	}
+	return args
+}
+
+// Result is the outcome of one call. Error is set when the call reverted or
+// failed; Output then holds the revert data, if any.
+type Result struct {
+	Output []byte
+	Error  string
+}
+
+// Simulate runs calls with method in bundles of at most size calls on the
+// state of block ("latest", a number or a hash), and returns one result per
+// call in order along with the number of requests made. State carries over
+// between the calls of a bundle but not between bundles.
+func Simulate(ctx context.Context, client *rpc.Client, method string, calls []Call, size int, block string) ([]Result, int, error) {
+	if size <= 0 {
+		size = len(calls)
+	}
+	var results []Result
+	requests := 0
+	for start := 0; start < len(calls); start += size {
+		end := min(start+size, len(calls))
+		requests++
+		var (
+			out []Result
+			err error
+		)
+		switch method {
+		case CallMany:
+			out, err = callMany(ctx, client, calls[start:end], block)
+		case TraceCallMany:
+			out, err = traceCallMany(ctx, client, calls[start:end], block)
+		default:
+			return nil, requests, fmt.Errorf("unknown bundle method %q", method)
+		}
+		if err != nil {
+			return results, requests, classify(method, err)
+		}
+		if len(out) != end-start {
+			return results, requests, fmt.Errorf("%s returned %d results for %d calls", method, len(out), end-start)
+		}
+		results = append(results, out...)
+	}
+	return results, requests, nil
+}
+
+// classify turns the errors of nodes without the method into
+// ErrUnsupported.
+func classify(method string, err error) error {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return fmt.Errorf("%w: %s: %v", ErrUnsupported, method, err)
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"method not found", "does not exist", "not available", "not supported"} {
+		if strings.Contains(msg, s) {
+			return fmt.Errorf("%w: %s: %v", ErrUnsupported, method, err)
+		}
+	}
+	return fmt.Errorf("%s: %w", method, err)
+}
+
+// callManyResult is an eth_callMany answer. Erigon returns the output hex
+// without a 0x prefix, and the error as a string, or as an object carrying
+// the revert data for reverts.
+type callManyResult struct {
+	Value *string         `json:"value"`
+	Error json.RawMessage `json:"error"`
+}
+
+func callMany(ctx context.Context, client *rpc.Client, calls []Call, block string) ([]Result, error) {
+	txs := make([]map[string]any, len(calls))
+	for i, c := range calls {
+		txs[i] = c.args()
+	}
+	bundles := []map[string]any{{"transactions": txs}}
+	stateContext := map[string]any{"blockNumber": block, "transactionIndex": -1}
+	var raw [][]callManyResult
+	if err := client.CallContext(ctx, &raw, CallMany, bundles, stateContext); err != nil {
+		return nil, err
+	}
+	if len(raw) != 1 {
+		return nil, fmt.Errorf("returned %d bundles for 1", len(raw))
+	}
+	results := make([]Result, len(raw[0]))
+	for i, r := range raw[0] {
+		if r.Value != nil {
+			results[i].Output = common.FromHex(*r.Value)
+		}
+		if len(r.Error) > 0 && string(r.Error) != "null" {
+			results[i].Output, results[i].Error = decodeError(r.Error)
+		}
+	}
+	return results, nil
+}
+
+// decodeError reads an error given as a string or as a JSON-RPC error
+// object with optional revert data.
+func decodeError(raw json.RawMessage) ([]byte, string) {
+	var msg string
+	if err := json.Unmarshal(raw, &msg); err == nil {
+		return nil, msg
+	}
+	var obj struct {
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil && obj.Message != "" {
+		return common.FromHex(obj.Data), obj.Message
+	}
+	return nil, string(raw)
+}
+
+// traceCallManyResult is a trace_callMany answer with the trace requested:
+// the top-level frame carries the error of a failed call.
+type traceCallManyResult struct {
+	Output hexutil.Bytes `json:"output"`
+	Trace  []struct {
+		Error        string `json:"error"`
+		TraceAddress []int  `json:"traceAddress"`
+	} `json:"trace"`
+}
+
+func traceCallMany(ctx context.Context, client *rpc.Client, calls []Call, block string) ([]Result, error) {
+	params := make([][]any, len(calls))
+	for i, c := range calls {
+		params[i] = []any{c.args(), []string{"trace"}}
+	}
+	var raw []traceCallManyResult
+	if err := client.CallContext(ctx, &raw, TraceCallMany, params, block); err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(raw))
+	for i, r := range raw {
+		results[i].Output = r.Output
+		for _, frame := range r.Trace {
+			if len(frame.TraceAddress) == 0 {
+				results[i].Error = frame.Error
+			}
+		}
+	}
+	return results, nil
+}
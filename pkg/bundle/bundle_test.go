@@ -0,0 +1,155 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+var (
+	sha256Address = common.HexToAddress("0x02")
+	// reverter reverts every call with revert data 0xdead
+	reverter = common.HexToAddress("0xbad")
+)
+
+type callArgs struct {
+	To   common.Address `json:"to"`
+	Data hexutil.Bytes  `json:"data"`
+}
+
+func answer(a callArgs) (out []byte, failed bool) {
+	if a.To == reverter {
+		return []byte{0xde, 0xad}, true
+	}
+	sum := sha256.Sum256(a.Data)
+	return sum[:], false
+}
+
+func dial(t *testing.T, s *mockrpc.Server) *rpc.Client {
+	t.Helper()
+	client, err := rpc.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func corpus(n int) []Call {
+	calls := make([]Call, n)
+	for i := range calls {
+		calls[i] = Call{To: sha256Address, Data: []byte{byte(i)}}
+	}
+	return calls
+}
+
+func TestCallMany(t *testing.T) {
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	s.Handle(CallMany, func(c mockrpc.Call) (any, error) {
+		var bundles []struct {
+			Transactions []callArgs `json:"transactions"`
+		}
+		if err := c.Param(0, &bundles); err != nil {
+			return nil, err
+		}
+		var out []map[string]any
+		for _, tx := range bundles[0].Transactions {
+			data, failed := answer(tx)
+			if failed {
+				out = append(out, map[string]any{"error": map[string]any{"code": 3, "message": "execution reverted", "data": hexutil.Bytes(data)}})
+				continue
+			}
+			// Erigon leaves out the 0x prefix
+			out = append(out, map[string]any{"value": common.Bytes2Hex(data)})
+		}
+		return [][]map[string]any{out}, nil
+	})
+
+	calls := append(corpus(5), Call{To: reverter})
+	results, requests, err := Simulate(context.Background(), dial(t, s), CallMany, calls, 4, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || s.Calls(CallMany) != 2 {
+		t.Errorf("%d requests, %d calls, want 6 calls in 2 bundles", requests, s.Calls(CallMany))
+	}
+	for i, c := range calls[:5] {
+		if want, _ := answer(callArgs{To: c.To, Data: c.Data}); !bytes.Equal(results[i].Output, want) || results[i].Error != "" {
+			t.Errorf("call %d: %+v, want %x", i, results[i], want)
+		}
+	}
+	if r := results[5]; r.Error != "execution reverted" || !bytes.Equal(r.Output, []byte{0xde, 0xad}) {
+		t.Errorf("revert: %+v", r)
+	}
+}
+
+func TestTraceCallMany(t *testing.T) {
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	s.Handle(TraceCallMany, func(c mockrpc.Call) (any, error) {
+		// Each entry is [callArgs, ["trace"]]
+		var raw [][2]json.RawMessage
+		if err := c.Param(0, &raw); err != nil {
+			return nil, err
+		}
+		var out []map[string]any
+		for _, entry := range raw {
+			var a callArgs
+			if err := json.Unmarshal(entry[0], &a); err != nil {
+				return nil, err
+			}
+			data, failed := answer(a)
+			frame := map[string]any{"traceAddress": []int{}}
+			if failed {
+				frame["error"] = "Reverted"
+			}
+			out = append(out, map[string]any{"output": hexutil.Bytes(data), "trace": []any{frame}})
+		}
+		return out, nil
+	})
+
+	calls := append(corpus(3), Call{To: reverter})
+	results, requests, err := Simulate(context.Background(), dial(t, s), TraceCallMany, calls, 0, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("%d requests, want one bundle", requests)
+	}
+	if want, _ := answer(callArgs{To: sha256Address, Data: []byte{2}}); !bytes.Equal(results[2].Output, want) {
+		t.Errorf("call 2 returned %x, want %x", results[2].Output, want)
+	}
+	if results[3].Error != "Reverted" || results[0].Error != "" {
+		t.Errorf("errors %q %q", results[0].Error, results[3].Error)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	for _, method := range Methods {
+		_, _, err := Simulate(context.Background(), dial(t, s), method, corpus(2), 10, "latest")
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: %v, want ErrUnsupported", method, err)
+		}
+	}
+}
+
+func TestShortAnswer(t *testing.T) {
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	s.Result(CallMany, [][]map[string]any{{{"value": "00"}}})
+	if _, _, err := Simulate(context.Background(), dial(t, s), CallMany, corpus(2), 10, "latest"); err == nil || errors.Is(err, ErrUnsupported) {
+		t.Errorf("one result for two calls: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package capability probes what an endpoint supports beyond the standard
+// eth namespace — historical state, debug and trace — so test groups that
+// depend on them can be skipped with a reported gap on plain full nodes
+// instead of failing.
+package capability
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Capability names.
+const (
+	HistoricalState = "historical_state"
+	Debug           = "debug"
+	Trace           = "trace"
+)
+
+// Probe is the detection outcome of one capability.
+type Probe struct {
+	Name      string `json:"name"`
+	Supported bool   `json:"supported"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Set holds the probes of an endpoint.
+type Set struct {
+	Head   uint64           `json:"head"`
+	Probes map[string]Probe `json:"probes"`
+}
+
+// Has reports whether capability name was detected.
+func (s Set) Has(name string) bool {
+	return s.Probes[name].Supported
+}
+
+// Missing returns the probes of names that aren't supported.
+func (s Set) Missing(names ...string) []Probe {
+	var missing []Probe
+	for _, name := range names {
+		if p := s.Probes[name]; !p.Supported {
+			p.Name = name
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// probeTarget is the SHA256 precompile, which every EVM chain has, so the
+// probes don't depend on any deployed contract.
+var probeTarget = common.HexToAddress("0x02")
+
+// Detect probes the endpoint. Errors from the probes themselves mark the
+// capability as unsupported; only failing to read the head is fatal.
+func Detect(ctx context.Context, client *ethclient.Client) (Set, error) {
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return Set{}, fmt.Errorf("failed to get block number: %w", err)
+	}
+	s := Set{Head: head, Probes: map[string]Probe{}}
+
+	s.Probes[HistoricalState] = probe(HistoricalState, historicalState(ctx, client, head))
+	s.Probes[Debug] = probe(Debug, client.Client().CallContext(ctx, new(any), "debug_traceCall",
+		callArgs(), "latest", map[string]string{"tracer": "callTracer"}))
+	s.Probes[Trace] = probe(Trace, client.Client().CallContext(ctx, new(any), "trace_call",
+		callArgs(), []string{"trace"}, "latest"))
+	return s, nil
+}
+
+func probe(name string, err error) Probe {
+	if err != nil {
+		return Probe{Name: name, Reason: err.Error()}
+	}
+	return Probe{Name: name, Supported: true}
+}
+
+// historicalState calls the precompile at block 1, which full nodes have
+// long pruned on any chain older than a few hundred blocks.
+func historicalState(ctx context.Context, client *ethclient.Client, head uint64) error {
+	if head < 2 {
+		return fmt.Errorf("chain has only %d blocks, nothing historical to query", head)
+	}
+	_, err := client.CallContract(ctx, ethereum.CallMsg{To: &probeTarget, Data: []byte{}}, big.NewInt(1))
+	return err
+}
+
+func callArgs() map[string]any {
+	return map[string]any{"to": probeTarget, "data": hexutil.Bytes{}}
+}
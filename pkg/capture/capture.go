@@ -0,0 +1,194 @@
+// Package capture records JSON-RPC exchanges into a HAR-like file and
+// serves a recording back as a mock endpoint, so an issue seen against a
+// node can be reproduced offline and the harness can be tested hermetically.
+//
+// The file follows the HAR 1.2 layout (log.entries[].request/response) with
+// only the fields that matter for JSON-RPC, so it opens in HAR viewers.
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// EnvCapture names the file every RPC client of a script records into.
+const EnvCapture = "RPC_CAPTURE"
+
+// File is a capture file.
+type File struct {
+	Log Log `json:"log"`
+}
+
+// Log holds the recorded entries.
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator identifies the recording tool.
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is one HTTP exchange.
+type Entry struct {
+	StartedDateTime string   `json:"startedDateTime"`
+	Time            float64  `json:"time"`
+	Request         Request  `json:"request"`
+	Response        Response `json:"response"`
+	Comment         string   `json:"comment,omitempty"`
+}
+
+// Request is the recorded HTTP request.
+type Request struct {
+	Method   string   `json:"method"`
+	URL      string   `json:"url"`
+	PostData PostData `json:"postData"`
+}
+
+// PostData is the request body.
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Response is the recorded HTTP response.
+type Response struct {
+	Status  int     `json:"status"`
+	Content Content `json:"content"`
+}
+
+// Content is the response body.
+type Content struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Load reads a capture file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+	f := new(File)
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse capture %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Recorder is an http.RoundTripper that appends every exchange to a capture
+// file. The file is rewritten after each exchange so a script exiting
+// through log.Fatal still leaves a complete capture, and entries already in
+// the file are kept so consecutive scripts accumulate into one capture.
+type Recorder struct {
+	Base http.RoundTripper
+
+	sink *sink
+}
+
+// sink is the capture file shared by all recorders writing to one path.
+type sink struct {
+	path string
+	mu   sync.Mutex
+	file *File
+}
+
+var (
+	sinksMu sync.Mutex
+	sinks   = map[string]*sink{}
+)
+
+// NewRecorder returns a recorder appending to path.
+func NewRecorder(base http.RoundTripper, path string) (*Recorder, error) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	if s, ok := sinks[path]; ok {
+		return &Recorder{Base: base, sink: s}, nil
+	}
+
+	f, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = &File{Log: Log{Version: "1.2", Creator: Creator{Name: "cdk-erigon-precompile", Version: "1"}}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &sink{path: path, file: f}
+	sinks[path] = s
+	return &Recorder{Base: base, sink: s}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	start := time.Now()
+	entry := Entry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Request: Request{
+			Method:   req.Method,
+			URL:      req.URL.String(),
+			PostData: PostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)},
+		},
+	}
+
+	resp, err := base.RoundTrip(req)
+	entry.Time = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		entry.Comment = err.Error()
+		r.sink.append(entry)
+		return nil, err
+	}
+
+	data, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	entry.Response = Response{
+		Status:  resp.StatusCode,
+		Content: Content{MimeType: resp.Header.Get("Content-Type"), Text: string(data)},
+	}
+	if readErr != nil {
+		entry.Comment = readErr.Error()
+	}
+	r.sink.append(entry)
+	return resp, nil
+}
+
+func (s *sink) append(e Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file.Log.Entries = append(s.file.Log.Entries, e)
+	data, err := json.MarshalIndent(s.file, "", "  ")
+	if err == nil {
+		err = paths.WriteFile(s.path, data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "capture: failed to write %s: %v\n", s.path, err)
+	}
+}
@@ -0,0 +1,121 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func dial(t *testing.T, url string, transport http.RoundTripper) *ethclient.Client {
+	t.Helper()
+	c, err := rpc.DialOptions(context.Background(), url, rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ethclient.NewClient(c)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := t.TempDir() + "/capture.har"
+
+	// Record a receipt appearing on the second poll and a node error
+	node := mockrpc.New()
+	node.Result("eth_chainId", "0x2775")
+	node.Handle("eth_blockNumber", mockrpc.Sequence(mockrpc.Static("0x1"), mockrpc.Static("0x2")))
+	node.Handle("eth_gasPrice", mockrpc.Fail(&mockrpc.Error{Code: -32000, Message: "boom"}))
+
+	recorder, err := NewRecorder(nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := dial(t, node.URL, recorder)
+	ctx := context.Background()
+	if _, err := live.ChainID(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := live.BlockNumber(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := live.SuggestGasPrice(ctx); err == nil {
+		t.Fatal("expected the recorded node error")
+	}
+	node.Close()
+
+	file, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Log.Entries) != 4 {
+		t.Fatalf("recorded %d entries, want 4", len(file.Log.Entries))
+	}
+
+	replay := NewReplay(file)
+	srv := httptest.NewServer(replay)
+	defer srv.Close()
+	offline := dial(t, srv.URL, http.DefaultTransport)
+
+	id, err := offline.ChainID(ctx)
+	if err != nil || id.Uint64() != 10101 {
+		t.Fatalf("chain id: %v, %v", id, err)
+	}
+	// Identical requests replay in order, the last answer repeating
+	for i, want := range []uint64{1, 2, 2} {
+		n, err := offline.BlockNumber(ctx)
+		if err != nil || n != want {
+			t.Fatalf("block number %d: got %d, %v; want %d", i, n, err, want)
+		}
+	}
+	var rpcErr rpc.Error
+	if _, err := offline.SuggestGasPrice(ctx); !errors.As(err, &rpcErr) || rpcErr.Error() != "boom" {
+		t.Fatalf("gas price: got %v, want the recorded error", err)
+	}
+	if _, err := offline.NetworkID(ctx); err == nil {
+		t.Fatal("expected an error for a request not in the capture")
+	}
+
+	stats := replay.Stats()
+	if stats.Served != 5 || stats.Missed["net_version"] != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestRecorderAppends(t *testing.T) {
+	path := t.TempDir() + "/capture.har"
+	node := mockrpc.New()
+	defer node.Close()
+	node.Result("eth_chainId", "0x1")
+
+	for run := 0; run < 2; run++ {
+		// Fresh sink per run, as in consecutive scripts
+		sinksMu.Lock()
+		delete(sinks, path)
+		sinksMu.Unlock()
+
+		recorder, err := NewRecorder(nil, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dial(t, node.URL, recorder).ChainID(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Log.Entries) != 2 {
+		t.Fatalf("capture has %d entries, want 2", len(file.Log.Entries))
+	}
+}
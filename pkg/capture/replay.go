@@ -0,0 +1,218 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Replay serves a capture as a JSON-RPC endpoint. Requests are matched on
+// their content with the ids ignored; repeated identical requests (receipt
+// polling, for instance) get the recorded responses in order, the last one
+// repeating. Response ids are rewritten to the ids of the incoming request.
+type Replay struct {
+	mu      sync.Mutex
+	queues  map[string]*queue
+	served  int
+	missed  map[string]int
+	ordered []string
+}
+
+type queue struct {
+	entries []Entry
+	next    int
+}
+
+// ReplayStats counts what a replay served.
+type ReplayStats struct {
+	Served int            `json:"served"`
+	Missed map[string]int `json:"missed,omitempty"`
+}
+
+// NewReplay indexes the entries of f.
+func NewReplay(f *File) *Replay {
+	r := &Replay{queues: map[string]*queue{}, missed: map[string]int{}}
+	for _, e := range f.Log.Entries {
+		key, _, err := requestKey([]byte(e.Request.PostData.Text))
+		if err != nil {
+			continue
+		}
+		q, ok := r.queues[key]
+		if !ok {
+			q = &queue{}
+			r.queues[key] = q
+			r.ordered = append(r.ordered, key)
+		}
+		q.entries = append(q.entries, e)
+	}
+	return r
+}
+
+// Len returns the number of distinct recorded requests.
+func (r *Replay) Len() int { return len(r.ordered) }
+
+// Stats returns what has been served so far.
+func (r *Replay) Stats() ReplayStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	missed := map[string]int{}
+	for k, v := range r.missed {
+		missed[k] = v
+	}
+	return ReplayStats{Served: r.served, Missed: missed}
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Replay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key, ids, err := requestKey(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON-RPC request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	q, ok := r.queues[key]
+	var e Entry
+	if ok {
+		e = q.entries[q.next]
+		if q.next < len(q.entries)-1 {
+			q.next++
+		}
+		r.served++
+	} else {
+		r.missed[methodsOf(body)]++
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		writeMiss(w, body, ids)
+		return
+	}
+	if e.Response.Status == 0 {
+		// The recorded exchange failed at the transport level
+		panic(http.ErrAbortHandler)
+	}
+
+	text := []byte(e.Response.Content.Text)
+	_, recordedIDs, _ := requestKey([]byte(e.Request.PostData.Text))
+	if rewritten, err := rewriteIDs(text, recordedIDs, ids); err == nil {
+		text = rewritten
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Response.Status)
+	w.Write(text)
+}
+
+// requestKey canonicalizes a single or batch request with ids and the
+// jsonrpc version removed, and returns the ids in order.
+func requestKey(body []byte) (string, []json.RawMessage, error) {
+	var batch []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		var single map[string]json.RawMessage
+		if err := json.Unmarshal(body, &single); err != nil {
+			return "", nil, err
+		}
+		id := single["id"]
+		key, err := canonical(single)
+		return key, []json.RawMessage{id}, err
+	}
+	ids := make([]json.RawMessage, len(batch))
+	keys := make([]json.RawMessage, len(batch))
+	for i, m := range batch {
+		ids[i] = m["id"]
+		key, err := canonical(m)
+		if err != nil {
+			return "", nil, err
+		}
+		keys[i] = json.RawMessage(key)
+	}
+	data, err := json.Marshal(keys)
+	return string(data), ids, err
+}
+
+func canonical(m map[string]json.RawMessage) (string, error) {
+	var params any
+	if raw, ok := m["params"]; ok {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return "", err
+		}
+	}
+	var method string
+	json.Unmarshal(m["method"], &method)
+	data, err := json.Marshal(map[string]any{"method": method, "params": params})
+	return string(data), err
+}
+
+// rewriteIDs replaces the recorded ids in a response with the new request's
+// ids, matched by position in the request.
+func rewriteIDs(response []byte, recorded, current []json.RawMessage) ([]byte, error) {
+	mapping := map[string]json.RawMessage{}
+	for i, id := range recorded {
+		if i < len(current) && id != nil {
+			mapping[string(bytes.TrimSpace(id))] = current[i]
+		}
+	}
+	rewrite := func(m map[string]json.RawMessage) {
+		if id, ok := mapping[string(bytes.TrimSpace(m["id"]))]; ok {
+			m["id"] = id
+		}
+	}
+
+	var batch []map[string]json.RawMessage
+	if err := json.Unmarshal(response, &batch); err == nil {
+		for _, m := range batch {
+			rewrite(m)
+		}
+		return json.Marshal(batch)
+	}
+	var single map[string]json.RawMessage
+	if err := json.Unmarshal(response, &single); err != nil {
+		return nil, err
+	}
+	rewrite(single)
+	return json.Marshal(single)
+}
+
+func methodsOf(body []byte) string {
+	var single struct {
+		Method string `json:"method"`
+	}
+	if json.Unmarshal(body, &single) == nil && single.Method != "" {
+		return single.Method
+	}
+	return "batch"
+}
+
+// writeMiss answers an unrecorded request with a JSON-RPC error per call.
+func writeMiss(w http.ResponseWriter, body []byte, ids []json.RawMessage) {
+	errorFor := func(id json.RawMessage) map[string]any {
+		if id == nil {
+			id = json.RawMessage("null")
+		}
+		return map[string]any{
+			"jsonrpc": "2.0",
+			"id":      id,
+			"error":   map[string]any{"code": -32000, "message": "replay: request not in capture"},
+		}
+	}
+	var out any
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		var batch []any
+		for _, id := range ids {
+			batch = append(batch, errorFor(id))
+		}
+		out = batch
+	} else {
+		out = errorFor(ids[0])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,92 @@
+// Package cases calls precompiles through the PrecompileCases contract
+// (contracts/PrecompileCases.sol), which feeds them the same input from
+// calldata, from freshly written memory or from storage. A precompile must
+// answer identically whichever path the input took through the executor.
+package cases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Source is where the contract takes the precompile input from.
+type Source string
+
+const (
+	Calldata Source = "calldata"
+	Memory   Source = "memory"
+	Storage  Source = "storage"
+)
+
+// Sources lists every source, calldata first as the baseline.
+var Sources = []Source{Calldata, Memory, Storage}
+
+// Method is the contract function reading from s.
+func (s Source) Method() string {
+	return "from" + strings.ToUpper(string(s[:1])) + string(s[1:])
+}
+
+// abiJSON is the ABI of contracts/PrecompileCases.sol.
+const abiJSON = `[
+{"type":"function","name":"fromCalldata","stateMutability":"view",
+"inputs":[{"name":"precompile","type":"address"},{"name":"input","type":"bytes"}],
+"outputs":[{"name":"success","type":"bool"},{"name":"output","type":"bytes"}]},
+{"type":"function","name":"fromMemory","stateMutability":"view",
+"inputs":[{"name":"precompile","type":"address"},{"name":"input","type":"bytes"}],
+"outputs":[{"name":"success","type":"bool"},{"name":"output","type":"bytes"}]},
+{"type":"function","name":"fromStorage","stateMutability":"nonpayable",
+"inputs":[{"name":"precompile","type":"address"},{"name":"input","type":"bytes"}],
+"outputs":[{"name":"success","type":"bool"},{"name":"output","type":"bytes"}]}]`
+
+// ABI is the parsed PrecompileCases ABI.
+var ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Result is what the precompile answered inside the contract.
+type Result struct {
+	Success bool
+	Output  []byte
+}
+
+// Pack encodes a call of precompile with input from src.
+func Pack(src Source, precompile common.Address, input []byte) ([]byte, error) {
+	data, err := ABI.Pack(src.Method(), precompile, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", src.Method(), err)
+	}
+	return data, nil
+}
+
+// Unpack decodes the answer of a src call.
+func Unpack(src Source, out []byte) (Result, error) {
+	values, err := ABI.Unpack(src.Method(), out)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to unpack %s: %w", src.Method(), err)
+	}
+	return Result{Success: values[0].(bool), Output: values[1].([]byte)}, nil
+}
+
+// Call has the contract at address call precompile with input from src,
+// in an eth_call.
+func Call(ctx context.Context, client *ethclient.Client, address common.Address, src Source, precompile common.Address, input []byte) (Result, error) {
+	data, err := Pack(src, precompile, input)
+	if err != nil {
+		return Result{}, err
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: data}, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s call failed: %w", src.Method(), err)
+	}
+	return Unpack(src, out)
+}
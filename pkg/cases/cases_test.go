@@ -0,0 +1,78 @@
+package cases
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestCall(t *testing.T) {
+	contract := common.Address{0xca}
+	s := mockrpc.New()
+	defer s.Close()
+	// The contract answers sha256 of the input for 0x02, and a failed call
+	// with no output for anything else
+	s.Handle("eth_call", func(call mockrpc.Call) (any, error) {
+		var msg struct {
+			To    common.Address
+			Input hexutil.Bytes
+		}
+		if err := call.Param(0, &msg); err != nil || msg.To != contract {
+			return nil, &mockrpc.Error{Code: -32000, Message: "unexpected call"}
+		}
+		method, err := ABI.MethodById(msg.Input[:4])
+		if err != nil {
+			return nil, err
+		}
+		args, err := method.Inputs.Unpack(msg.Input[4:])
+		if err != nil {
+			return nil, err
+		}
+		if args[0].(common.Address) != common.BytesToAddress([]byte{2}) {
+			out, _ := method.Outputs.Pack(false, []byte{})
+			return hexutil.Bytes(out), nil
+		}
+		sum := sha256.Sum256(args[1].([]byte))
+		out, _ := method.Outputs.Pack(true, sum[:])
+		return hexutil.Bytes(out), nil
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	input := []byte("provenance")
+	want := sha256.Sum256(input)
+	for _, src := range Sources {
+		got, err := Call(context.Background(), client, contract, src, common.BytesToAddress([]byte{2}), input)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if !got.Success || !bytes.Equal(got.Output, want[:]) {
+			t.Errorf("%s: %+v, want success with %x", src, got, want)
+		}
+		failed, err := Call(context.Background(), client, contract, src, common.BytesToAddress([]byte{0x0b}), input)
+		if err != nil || failed.Success || len(failed.Output) != 0 {
+			t.Errorf("%s of an undefined address: %+v, %v", src, failed, err)
+		}
+	}
+	if got := s.Calls("eth_call"); got != 2*len(Sources) {
+		t.Errorf("%d eth_calls, want %d", got, 2*len(Sources))
+	}
+}
+
+func TestMethods(t *testing.T) {
+	for _, src := range Sources {
+		if _, ok := ABI.Methods[src.Method()]; !ok {
+			t.Errorf("no ABI method %s for source %s", src.Method(), src)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+
+	"cdk-erigon-precompile/pkg/skip"
+)
+
+// DeploymentState describes a contract creation to check.
+type DeploymentState struct {
+	Deployer common.Address
+	Contract common.Address
+	Receipt  *types.Receipt
+	// GasPrice is the price the transaction was signed with, used when the
+	// receipt carries no effectiveGasPrice.
+	GasPrice *big.Int
+	// ProveStorage enables the eth_getProof based codehash and storage root
+	// checks, which only make sense on chains with a keccak MPT state.
+	ProveStorage bool
+}
+
+// CheckDeployment compares account state in the parent block with the block
+// that included the deployment: the deployer nonce must advance by exactly
+// one and its balance drop by exactly gasUsed×price, and the new contract
+// must start at nonce 1 (EIP-161) with a codehash matching its code and, when
+// provable, an empty storage root. Checks that need state the node can't
+// serve (e.g. pruned parent state) are reported as skipped.
+func CheckDeployment(ctx context.Context, client *ethclient.Client, d DeploymentState) []Check {
+	block := d.Receipt.BlockNumber
+	parent := new(big.Int).Sub(block, big.NewInt(1))
+	var checks []Check
+
+	// Deployer nonce
+	check := Check{Name: "deployer nonce +1"}
+	before, errBefore := client.NonceAt(ctx, d.Deployer, parent)
+	after, errAfter := client.NonceAt(ctx, d.Deployer, block)
+	if errBefore != nil || errAfter != nil {
+		check.Skipped, check.Note = true, fmt.Sprintf("nonce unavailable: %v", firstErr(errBefore, errAfter))
+	} else {
+		check.Expected = fmt.Sprint(before + 1)
+		check.Actual = fmt.Sprint(after)
+		check.Passed = after == before+1
+	}
+	checks = append(checks, check)
+
+	// Deployer balance
+	check = Check{Name: "deployer balance -= gasUsed*price"}
+	price := d.Receipt.EffectiveGasPrice
+	if price == nil {
+		price = d.GasPrice
+	}
+	balBefore, errBefore := client.BalanceAt(ctx, d.Deployer, parent)
+	balAfter, errAfter := client.BalanceAt(ctx, d.Deployer, block)
+	if errBefore != nil || errAfter != nil || price == nil {
+		check.Skipped, check.Note = true, fmt.Sprintf("balance unavailable: %v", firstErr(errBefore, errAfter))
+	} else {
+		cost := new(big.Int).Mul(new(big.Int).SetUint64(d.Receipt.GasUsed), price)
+		spent := new(big.Int).Sub(balBefore, balAfter)
+		check.Expected = cost.String()
+		check.Actual = spent.String()
+		check.Passed = spent.Cmp(cost) == 0
+	}
+	checks = append(checks, check)
+
+	// Contract nonce
+	check = Check{Name: "contract nonce = 1", Expected: "1"}
+	contractNonce, err := client.NonceAt(ctx, d.Contract, block)
+	if err != nil {
+		check.Skipped, check.Note = true, fmt.Sprintf("nonce unavailable: %v", err)
+	} else {
+		check.Actual = fmt.Sprint(contractNonce)
+		check.Passed = contractNonce == 1
+	}
+	checks = append(checks, check)
+
+	if !d.ProveStorage {
+		checks = append(checks,
+			skippedCheck("contract codehash", skip.NotApplicable, "not provable on this chain's state trie"),
+			skippedCheck("contract storage root empty", skip.NotApplicable, "not provable on this chain's state trie"),
+		)
+		return checks
+	}
+
+	// Codehash and storage root from the account proof
+	codeCheck := Check{Name: "contract codehash"}
+	rootCheck := Check{Name: "contract storage root empty", Expected: types.EmptyRootHash.Hex()}
+	code, errCode := client.CodeAt(ctx, d.Contract, block)
+	account, errProof := gethclient.New(client.Client()).GetProof(ctx, d.Contract, nil, block)
+	if errCode != nil || errProof != nil {
+		note := fmt.Sprintf("proof unavailable: %v", firstErr(errCode, errProof))
+		codeCheck.Skipped, codeCheck.Note = true, note
+		rootCheck.Skipped, rootCheck.Note = true, note
+	} else {
+		codeCheck.Expected = crypto.Keccak256Hash(code).Hex()
+		codeCheck.Actual = account.CodeHash.Hex()
+		codeCheck.Passed = account.CodeHash == crypto.Keccak256Hash(code)
+		rootCheck.Actual = account.StorageHash.Hex()
+		rootCheck.Passed = account.StorageHash == types.EmptyRootHash
+	}
+	return append(checks, codeCheck, rootCheck)
+}
@@ -0,0 +1,68 @@
+package chain
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Contract addresses are recomputed here from the yellow paper and EIP-1014
+// formulas, with the RLP encoding spelled out, rather than taken from
+// go-ethereum's crypto.CreateAddress: a node and the library agreeing on a
+// wrong derivation would otherwise pass unnoticed.
+
+// CreateAddress is the address CREATE gives the nonce-th contract of
+// sender: the last 20 bytes of keccak256(rlp([sender, nonce])).
+func CreateAddress(sender common.Address, nonce uint64) common.Address {
+	// The list is at most 1+20+1+8 bytes, so its prefix is a single byte
+	payload := append([]byte{0x80 + common.AddressLength}, sender.Bytes()...)
+	switch {
+	case nonce == 0:
+		payload = append(payload, 0x80)
+	case nonce < 0x80:
+		payload = append(payload, byte(nonce))
+	default:
+		var be [8]byte
+		binary.BigEndian.PutUint64(be[:], nonce)
+		i := 0
+		for be[i] == 0 {
+			i++
+		}
+		payload = append(payload, 0x80+byte(8-i))
+		payload = append(payload, be[i:]...)
+	}
+	return addressOf(append([]byte{0xc0 + byte(len(payload))}, payload...))
+}
+
+// Create2Address is the address CREATE2 gives initCode deployed by sender
+// with salt: the last 20 bytes of
+// keccak256(0xff ++ sender ++ salt ++ keccak256(initCode)).
+func Create2Address(sender common.Address, salt common.Hash, initCode []byte) common.Address {
+	data := append([]byte{0xff}, sender.Bytes()...)
+	data = append(data, salt.Bytes()...)
+	return addressOf(append(data, crypto.Keccak256(initCode)...))
+}
+
+func addressOf(data []byte) common.Address {
+	return common.BytesToAddress(crypto.Keccak256(data)[12:])
+}
+
+// VerifyCreation compares the contractAddress the node reported for a
+// contract creation by from with the address derived from from and the
+// transaction's nonce. Calls, and failed creations the node reports no
+// address for, have nothing to compare. A mismatch is an
+// *AddressDerivationError.
+func VerifyCreation(from common.Address, tx *types.Transaction, receipt *types.Receipt) error {
+	if tx.To() != nil {
+		return nil
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful && receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	if derived := CreateAddress(from, tx.Nonce()); receipt.ContractAddress != derived {
+		return &AddressDerivationError{Opcode: "CREATE", Derived: derived, Reported: receipt.ContractAddress}
+	}
+	return nil
+}
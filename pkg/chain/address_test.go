@@ -0,0 +1,87 @@
+package chain
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCreateAddress(t *testing.T) {
+	sender := common.HexToAddress("0x6ac7ea33f8831ea9dcc53393aaa88b25a785dbf0")
+	for nonce, want := range []string{
+		"0xcd234a471b72ba2f1ccf0a70fcaba648a5eecd8d",
+		"0x343c43a37d37dff08ae8c4a11544c718abb4fcf8",
+		"0xf778b86fa74e846c4f0a1fbd1335fe81c00a0c91",
+		"0xfffd933a0bc612844eaf0c6fe3e5b8e9b6c1d19c",
+	} {
+		if got := CreateAddress(sender, uint64(nonce)); got != common.HexToAddress(want) {
+			t.Errorf("nonce %d: %s, want %s", nonce, got.Hex(), want)
+		}
+	}
+	// Every RLP length boundary of the nonce agrees with go-ethereum
+	for _, nonce := range []uint64{0x7f, 0x80, 0xff, 0x100, 0xffff, 0x10000, 1 << 32, 1 << 56, math.MaxUint64} {
+		if got, want := CreateAddress(sender, nonce), crypto.CreateAddress(sender, nonce); got != want {
+			t.Errorf("nonce %#x: %s, go-ethereum %s", nonce, got.Hex(), want.Hex())
+		}
+	}
+}
+
+func TestCreate2Address(t *testing.T) {
+	// The examples of EIP-1014
+	for _, tc := range []struct {
+		sender, salt, initCode, want string
+	}{
+		{"0x0000000000000000000000000000000000000000", "0x00", "0x00", "0x4D1A2e2bB4F88F0250f26Ffff098B0b30B26BF38"},
+		{"0xdeadbeef00000000000000000000000000000000", "0x00", "0x00", "0xB928f69Bb1D91Cd65274e3c79d8986362984fDA3"},
+		{"0xdeadbeef00000000000000000000000000000000", "0x000000000000000000000000feed000000000000000000000000000000000000", "0x00", "0xD04116cDd17beBE565EB2422F2497E06cC1C9833"},
+		{"0x0000000000000000000000000000000000000000", "0x00", "0xdeadbeef", "0x70f2b2914A2a4b783FaEFb75f459A580616Fcb5e"},
+		{"0x00000000000000000000000000000000deadbeef", "0xcafebabe", "0xdeadbeef", "0x60f3f640a8508fC6a86d45DF051962668E1e8AC7"},
+		{"0x00000000000000000000000000000000deadbeef", "0xcafebabe", "0xdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef", "0x1d8bfDC5D46DC4f61D6b6115972536eBE6A8854C"},
+		{"0x0000000000000000000000000000000000000000", "0x00", "0x", "0xE33C0C7F7df4809055C3ebA6c09CFe4BaF1BD9e0"},
+	} {
+		got := Create2Address(common.HexToAddress(tc.sender), common.HexToHash(tc.salt), common.FromHex(tc.initCode))
+		if got != common.HexToAddress(tc.want) {
+			t.Errorf("%s, salt %s, init code %s: %s, want %s", tc.sender, tc.salt, tc.initCode, got.Hex(), tc.want)
+		}
+	}
+}
+
+func TestVerifyCreation(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	creation := types.NewTx(&types.LegacyTx{Nonce: 5, Gas: 100_000, GasPrice: big.NewInt(1)})
+	derived := CreateAddress(from, 5)
+
+	ok := &types.Receipt{Status: types.ReceiptStatusSuccessful, ContractAddress: derived}
+	if err := VerifyCreation(from, creation, ok); err != nil {
+		t.Errorf("derived address: %v", err)
+	}
+	// A failed creation without an address has nothing to compare
+	if err := VerifyCreation(from, creation, &types.Receipt{Status: types.ReceiptStatusFailed}); err != nil {
+		t.Errorf("failed creation: %v", err)
+	}
+	to := common.HexToAddress("0x1234")
+	call := types.NewTx(&types.LegacyTx{Nonce: 5, To: &to, Gas: 100_000, GasPrice: big.NewInt(1)})
+	if err := VerifyCreation(from, call, &types.Receipt{Status: types.ReceiptStatusSuccessful}); err != nil {
+		t.Errorf("call: %v", err)
+	}
+
+	for name, reported := range map[string]common.Address{
+		"off by one nonce": CreateAddress(from, 4),
+		"missing":          {},
+	} {
+		err := VerifyCreation(from, creation, &types.Receipt{Status: types.ReceiptStatusSuccessful, ContractAddress: reported})
+		var mismatch *AddressDerivationError
+		if !errors.Is(err, ErrAddressMismatch) || !errors.As(err, &mismatch) || mismatch.Derived != derived || mismatch.Reported != reported {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/trie"
+
+	"cdk-erigon-precompile/pkg/skip"
+)
+
+// CheckBlock validates the block that included a test transaction: fetched
+// by number and by hash it must be the same block, the transaction must sit
+// at the receipt's index, the header's gasUsed, logsBloom, transactionsRoot
+// and receiptsRoot must match what is recomputed locally from the block body
+// and its receipts.
+func CheckBlock(ctx context.Context, client *ethclient.Client, tx *types.Transaction, receipt *types.Receipt) []Check {
+	byNumber, err := client.BlockByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return []Check{{Name: "block by number", Note: err.Error()}}
+	}
+	byHash, err := client.BlockByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return []Check{{Name: "block by hash", Note: err.Error()}}
+	}
+
+	var checks []Check
+	checks = append(checks, Check{
+		Name:     "block by number = block by hash",
+		Expected: receipt.BlockHash.Hex(),
+		Actual:   byNumber.Hash().Hex(),
+		Passed:   byNumber.Hash() == receipt.BlockHash && byHash.Hash() == receipt.BlockHash,
+	})
+
+	block := byHash
+	txs := block.Transactions()
+
+	check := Check{Name: "transaction at receipt index", Expected: tx.Hash().Hex()}
+	if int(receipt.TransactionIndex) < len(txs) {
+		check.Actual = txs[receipt.TransactionIndex].Hash().Hex()
+		check.Passed = txs[receipt.TransactionIndex].Hash() == tx.Hash()
+	} else {
+		check.Note = fmt.Sprintf("index %d out of range, block has %d transactions", receipt.TransactionIndex, len(txs))
+	}
+	checks = append(checks, check)
+
+	receipts, err := BlockReceipts(ctx, client, receipt.BlockHash)
+	if err != nil {
+		receipts = nil
+		checks = append(checks, skippedCheck("block receipts", skip.Capability, err.Error()))
+	}
+	return append(checks, VerifyBlock(block, receipts)...)
+}
+
+// VerifyBlock re-derives what a block's header commits to from its body and
+// receipts: transactionsRoot, gasUsed, receiptsRoot and logsBloom. It also
+// checks each receipt's bloom against its logs, cumulativeGasUsed, and that
+// every log carries its block-wide index and the transaction and block it
+// came from. Nothing in it assumes the block holds a test transaction, so it
+// verifies any block. Without receipts only the transactions are checked.
+func VerifyBlock(block *types.Block, receipts []*types.Receipt) []Check {
+	txs := block.Transactions()
+	txRoot := types.DeriveSha(txs, trie.NewStackTrie(nil))
+	checks := []Check{{
+		Name:     "transactionsRoot recomputed",
+		Expected: block.TxHash().Hex(),
+		Actual:   txRoot.Hex(),
+		Passed:   txRoot == block.TxHash(),
+	}}
+	if receipts == nil {
+		return checks
+	}
+
+	check := Check{Name: "receipt count = transaction count", Expected: fmt.Sprint(len(txs)), Actual: fmt.Sprint(len(receipts))}
+	check.Passed = len(txs) == len(receipts)
+	checks = append(checks, check)
+
+	var gasUsed uint64
+	for _, r := range receipts {
+		gasUsed += r.GasUsed
+	}
+	checks = append(checks, Check{
+		Name:     "gasUsed = sum of receipts",
+		Expected: fmt.Sprint(block.GasUsed()),
+		Actual:   fmt.Sprint(gasUsed),
+		Passed:   block.GasUsed() == gasUsed,
+	})
+
+	root := types.DeriveSha(types.Receipts(receipts), trie.NewStackTrie(nil))
+	checks = append(checks, Check{
+		Name:     "receiptsRoot recomputed",
+		Expected: block.ReceiptHash().Hex(),
+		Actual:   root.Hex(),
+		Passed:   root == block.ReceiptHash(),
+	})
+
+	bloom := types.MergeBloom(receipts)
+	checks = append(checks, Check{
+		Name:   "logsBloom = merged receipt blooms",
+		Passed: bloom == block.Bloom(),
+	})
+
+	blooms := Check{Name: "receipt blooms match their logs", Passed: true}
+	cumulative := Check{Name: "cumulativeGasUsed monotonic", Passed: true}
+	logs := Check{Name: "log indexes and references", Passed: true}
+	var prev uint64
+	var index uint
+	for i, r := range receipts {
+		if blooms.Passed && types.CreateBloom(r) != r.Bloom {
+			blooms.Passed = false
+			blooms.Note = fmt.Sprintf("receipt %d (%s)", i, r.TxHash.Hex())
+		}
+		if cumulative.Passed && (r.CumulativeGasUsed < prev || r.CumulativeGasUsed-prev != r.GasUsed) {
+			cumulative.Passed = false
+			cumulative.Note = fmt.Sprintf("receipt %d: cumulative %d after %d with gasUsed %d", i, r.CumulativeGasUsed, prev, r.GasUsed)
+		}
+		prev = r.CumulativeGasUsed
+		for _, l := range r.Logs {
+			if logs.Passed {
+				switch {
+				case l.Index != index:
+					logs.Note = fmt.Sprintf("receipt %d: log index %d, want %d", i, l.Index, index)
+				case i < len(txs) && l.TxHash != txs[i].Hash():
+					logs.Note = fmt.Sprintf("receipt %d: log of transaction %s, want %s", i, l.TxHash.Hex(), txs[i].Hash().Hex())
+				case l.TxIndex != uint(i):
+					logs.Note = fmt.Sprintf("receipt %d: log transaction index %d", i, l.TxIndex)
+				case l.BlockHash != block.Hash() || l.BlockNumber != block.NumberU64():
+					logs.Note = fmt.Sprintf("receipt %d: log of block %d %s", i, l.BlockNumber, l.BlockHash.Hex())
+				}
+				logs.Passed = logs.Note == ""
+			}
+			index++
+		}
+	}
+	logs.Actual = fmt.Sprintf("%d logs", index)
+	return append(checks, blooms, cumulative, logs)
+}
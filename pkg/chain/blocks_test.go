@@ -0,0 +1,111 @@
+package chain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testBlock builds a consistent block of three transactions, the second
+// emitting two logs and the third one.
+func testBlock(t *testing.T) (*types.Block, []*types.Receipt) {
+	t.Helper()
+	var (
+		txs      []*types.Transaction
+		receipts []*types.Receipt
+		gas      uint64
+	)
+	to := common.HexToAddress("0x02")
+	for i, nlogs := range []int{0, 2, 1} {
+		tx := types.NewTx(&types.LegacyTx{Nonce: uint64(i), To: &to, Gas: 50_000, GasPrice: big.NewInt(1)})
+		gas += 21_000 + uint64(i)
+		r := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: gas, GasUsed: 21_000 + uint64(i), TxHash: tx.Hash()}
+		for j := range nlogs {
+			r.Logs = append(r.Logs, &types.Log{Address: to, Topics: []common.Hash{common.BigToHash(big.NewInt(int64(i*10 + j)))}})
+		}
+		r.Bloom = types.CreateBloom(r)
+		txs = append(txs, tx)
+		receipts = append(receipts, r)
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(7), GasUsed: gas}, &types.Body{Transactions: txs}, receipts, trie.NewStackTrie(nil))
+
+	// Positions are filled in as a node returns them
+	var index uint
+	for i, r := range receipts {
+		r.BlockHash, r.BlockNumber, r.TransactionIndex = block.Hash(), block.Number(), uint(i)
+		for _, l := range r.Logs {
+			l.Index, l.TxIndex, l.TxHash, l.BlockHash, l.BlockNumber = index, uint(i), r.TxHash, block.Hash(), block.NumberU64()
+			index++
+		}
+	}
+	return block, receipts
+}
+
+func failed(checks []Check) []string {
+	var names []string
+	for _, c := range checks {
+		if !c.Skipped && !c.Passed {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func TestVerifyBlock(t *testing.T) {
+	block, receipts := testBlock(t)
+	checks := VerifyBlock(block, receipts)
+	if f := failed(checks); len(f) > 0 {
+		t.Fatalf("consistent block failed %v", f)
+	}
+	if len(checks) != 8 {
+		t.Errorf("%d checks, want 8", len(checks))
+	}
+	if only := VerifyBlock(block, nil); len(only) != 1 || !only[0].Passed {
+		t.Errorf("without receipts: %+v", only)
+	}
+}
+
+func TestVerifyBlockCatches(t *testing.T) {
+	for name, c := range map[string]struct {
+		tamper func([]*types.Receipt)
+		want   []string
+	}{
+		"log index gap": {
+			func(r []*types.Receipt) { r[2].Logs[0].Index = 3 },
+			[]string{"log indexes and references"},
+		},
+		"log of another transaction": {
+			func(r []*types.Receipt) { r[1].Logs[1].TxHash = r[2].TxHash },
+			[]string{"log indexes and references"},
+		},
+		"receipt bloom missing a log": {
+			func(r []*types.Receipt) { r[1].Bloom = types.Bloom{} },
+			[]string{"receiptsRoot recomputed", "logsBloom = merged receipt blooms", "receipt blooms match their logs"},
+		},
+		"gas": {
+			func(r []*types.Receipt) { r[0].GasUsed++ },
+			[]string{"gasUsed = sum of receipts", "cumulativeGasUsed monotonic"},
+		},
+		"status": {
+			func(r []*types.Receipt) { r[0].Status = types.ReceiptStatusFailed },
+			[]string{"receiptsRoot recomputed"},
+		},
+	} {
+		block, receipts := testBlock(t)
+		c.tamper(receipts)
+		got := failed(VerifyBlock(block, receipts))
+		if len(got) != len(c.want) {
+			t.Errorf("%s: failed %v, want %v", name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: failed %v, want %v", name, got, c.want)
+				break
+			}
+		}
+	}
+}
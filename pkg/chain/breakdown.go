@@ -0,0 +1,228 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// EffectiveGasPriceMethod is cdk-erigon's RPC for the price the sequencer
+// charged a transaction after applying its effective gas price percentage.
+const EffectiveGasPriceMethod = "zkevm_getEffectiveGasPrice"
+
+// l1ReceiptFields are the receipt extensions through which rollups expose
+// the cost of posting a transaction to L1: OP Stack's l1* fields and
+// Arbitrum's gasUsedForL1.
+var l1ReceiptFields = []string{
+	"l1Fee", "l1GasUsed", "l1GasPrice", "l1FeeScalar",
+	"l1BaseFeeScalar", "l1BlobBaseFee", "l1BlobBaseFeeScalar",
+	"gasUsedForL1", "l1BlockNumber",
+}
+
+// FeeBreakdown splits what a mined transaction paid into its L2 execution
+// part and whatever L1 data or posting part the node reports. Amounts are
+// decimal wei.
+type FeeBreakdown struct {
+	Transaction string `json:"transaction"`
+	Type        uint64 `json:"type"`
+	Block       uint64 `json:"block"`
+	GasUsed     uint64 `json:"gasUsed"`
+	// GasPrice is what the sender offered: the gas price, or the fee cap
+	// of a dynamic fee transaction.
+	GasPrice          string `json:"gasPrice"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	BaseFee           string `json:"baseFee,omitempty"`
+	// SequencerGasPrice is the zkEVM sequencer's effective price, and
+	// EffectivePercentage the byte it encodes:
+	// price = gasPrice × (percentage+1) / 256.
+	SequencerGasPrice   string `json:"sequencerGasPrice,omitempty"`
+	EffectivePercentage *int   `json:"effectivePercentage,omitempty"`
+	// L2Gas is the gas spent executing on L2; for Arbitrum it excludes the
+	// gasUsedForL1 that pays for posting.
+	L2Gas         uint64 `json:"l2Gas"`
+	L2BaseFee     string `json:"l2BaseFee"`
+	L2PriorityFee string `json:"l2PriorityFee"`
+	L2Fee         string `json:"l2Fee"`
+	L1Fee         string `json:"l1Fee,omitempty"`
+	Total         string `json:"total"`
+	// L1Fields holds the L1 receipt extensions as the node sent them.
+	L1Fields map[string]string `json:"l1Fields,omitempty"`
+	Notes    []string          `json:"notes,omitempty"`
+}
+
+type feeReceipt struct {
+	BlockNumber       hexutil.Uint64 `json:"blockNumber"`
+	GasUsed           hexutil.Uint64 `json:"gasUsed"`
+	EffectiveGasPrice *hexutil.Big   `json:"effectiveGasPrice"`
+	Type              hexutil.Uint64 `json:"type"`
+}
+
+type feeTransaction struct {
+	GasPrice     *hexutil.Big `json:"gasPrice"`
+	MaxFeePerGas *hexutil.Big `json:"maxFeePerGas"`
+}
+
+// BreakDownFee fetches the receipt, transaction and including block of hash
+// and splits its fee. The L2 fee is gasUsed × effectiveGasPrice, divided at
+// the block's baseFee into the burnt and priority parts. An OP Stack l1Fee
+// is added on top; Arbitrum's gasUsedForL1 is carved out of gasUsed.
+func BreakDownFee(ctx context.Context, client *ethclient.Client, hash common.Hash) (FeeBreakdown, error) {
+	b := FeeBreakdown{Transaction: hash.Hex()}
+
+	var data json.RawMessage
+	if err := client.Client().CallContext(ctx, &data, "eth_getTransactionReceipt", hash); err != nil {
+		return b, fmt.Errorf("failed to get receipt: %w", err)
+	}
+	if len(data) == 0 || string(data) == "null" {
+		return b, fmt.Errorf("transaction %s is not mined", hash.Hex())
+	}
+	var receipt feeReceipt
+	var raw map[string]json.RawMessage
+	if err := firstErr(json.Unmarshal(data, &receipt), json.Unmarshal(data, &raw)); err != nil {
+		return b, fmt.Errorf("failed to decode receipt: %w", err)
+	}
+	var tx feeTransaction
+	if err := client.Client().CallContext(ctx, &tx, "eth_getTransactionByHash", hash); err != nil {
+		return b, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(uint64(receipt.BlockNumber)))
+	if err != nil {
+		return b, fmt.Errorf("failed to get block %d: %w", receipt.BlockNumber, err)
+	}
+
+	b.Type, b.Block, b.GasUsed = uint64(receipt.Type), uint64(receipt.BlockNumber), uint64(receipt.GasUsed)
+	offered := tx.GasPrice
+	if tx.MaxFeePerGas != nil {
+		offered = tx.MaxFeePerGas
+	}
+	if offered != nil {
+		b.GasPrice = offered.ToInt().String()
+	}
+	price := new(big.Int)
+	switch {
+	case receipt.EffectiveGasPrice != nil:
+		price = receipt.EffectiveGasPrice.ToInt()
+	case tx.GasPrice != nil:
+		price = tx.GasPrice.ToInt()
+		b.Notes = append(b.Notes, "receipt has no effectiveGasPrice, using the transaction's gasPrice")
+	}
+	b.EffectiveGasPrice = price.String()
+
+	b.L1Fields = l1Fields(raw)
+	l1Fee := new(big.Int)
+	b.L2Gas = b.GasUsed
+	if s, ok := b.L1Fields["gasUsedForL1"]; ok {
+		l1Gas, err := hexutil.DecodeUint64(s)
+		if err != nil || l1Gas > b.GasUsed {
+			b.Notes = append(b.Notes, fmt.Sprintf("ignoring gasUsedForL1 %s", s))
+		} else {
+			b.L2Gas -= l1Gas
+			l1Fee.Mul(new(big.Int).SetUint64(l1Gas), price)
+		}
+	}
+	if s, ok := b.L1Fields["l1Fee"]; ok {
+		fee, err := hexutil.DecodeBig(s)
+		if err != nil {
+			b.Notes = append(b.Notes, fmt.Sprintf("ignoring l1Fee %s", s))
+		} else {
+			l1Fee.Add(l1Fee, fee)
+		}
+	}
+
+	l2Gas := new(big.Int).SetUint64(b.L2Gas)
+	l2Fee := new(big.Int).Mul(l2Gas, price)
+	burnt := new(big.Int)
+	if header.BaseFee != nil {
+		b.BaseFee = header.BaseFee.String()
+		burnt.Mul(l2Gas, header.BaseFee)
+		if burnt.Cmp(l2Fee) > 0 {
+			burnt.Set(l2Fee)
+		}
+	}
+	b.L2BaseFee = burnt.String()
+	b.L2PriorityFee = new(big.Int).Sub(l2Fee, burnt).String()
+	b.L2Fee = l2Fee.String()
+	if len(b.L1Fields) > 0 {
+		b.L1Fee = l1Fee.String()
+	}
+	b.Total = new(big.Int).Add(l2Fee, l1Fee).String()
+
+	sequencer, err := sequencerGasPrice(ctx, client, hash)
+	switch {
+	case err != nil:
+		b.Notes = append(b.Notes, err.Error())
+	case sequencer != nil:
+		b.SequencerGasPrice = sequencer.String()
+		if offered != nil && offered.ToInt().Sign() > 0 {
+			// Invert price = gasPrice × (pct+1) / 256, rounding up
+			scaled := new(big.Int).Mul(sequencer, big.NewInt(256))
+			scaled.Add(scaled, new(big.Int).Sub(offered.ToInt(), big.NewInt(1)))
+			pct := int(scaled.Div(scaled, offered.ToInt()).Int64()) - 1
+			if pct >= 0 && pct <= 255 {
+				b.EffectivePercentage = &pct
+			}
+		}
+		if sequencer.Cmp(price) != 0 {
+			b.Notes = append(b.Notes, fmt.Sprintf("%s reports %s, the receipt %s", EffectiveGasPriceMethod, sequencer, price))
+		}
+	}
+	return b, nil
+}
+
+// sequencerGasPrice asks a zkEVM node for the effective gas price of hash.
+// Nodes without the method give nil and no error.
+func sequencerGasPrice(ctx context.Context, client *ethclient.Client, hash common.Hash) (*big.Int, error) {
+	var price *hexutil.Big
+	err := client.Client().CallContext(ctx, &price, EffectiveGasPriceMethod, hash)
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", EffectiveGasPriceMethod, err)
+	}
+	if price == nil {
+		return nil, nil
+	}
+	return price.ToInt(), nil
+}
+
+// l1Fields keeps the L1 receipt extensions present in raw, unquoted.
+func l1Fields(raw map[string]json.RawMessage) map[string]string {
+	fields := map[string]string{}
+	for _, name := range l1ReceiptFields {
+		v, ok := raw[name]
+		if !ok || string(v) == "null" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			s = string(v)
+		}
+		fields[name] = s
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// String summarizes the breakdown on one line.
+func (b FeeBreakdown) String() string {
+	parts := []string{fmt.Sprintf("L2 %s (%d gas × %s)", b.L2Fee, b.L2Gas, b.EffectiveGasPrice)}
+	if b.L1Fee != "" {
+		parts = append(parts, "L1 "+b.L1Fee)
+	}
+	if b.EffectivePercentage != nil {
+		parts = append(parts, fmt.Sprintf("effective percentage %d", *b.EffectivePercentage))
+	}
+	return strings.Join(parts, ", ") + " = " + b.Total
+}
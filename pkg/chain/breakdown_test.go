@@ -0,0 +1,60 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestBreakDownFee(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_getBlockByNumber", &types.Header{Number: big.NewInt(5), Difficulty: new(big.Int), BaseFee: big.NewInt(7)})
+	s.Result("eth_getTransactionByHash", map[string]any{"gasPrice": "0xa", "maxFeePerGas": "0x14"})
+	receipt := map[string]any{"blockNumber": "0x5", "gasUsed": "0x64", "effectiveGasPrice": "0xa", "type": "0x2"}
+	s.Handle("eth_getTransactionReceipt", func(mockrpc.Call) (any, error) { return receipt, nil })
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	// Plain L2 transaction: 100 gas at 10 wei, 7 of which are burnt
+	b, err := BreakDownFee(ctx, client, common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.GasPrice != "20" || b.L2Fee != "1000" || b.L2BaseFee != "700" || b.L2PriorityFee != "300" || b.L1Fee != "" || b.Total != "1000" {
+		t.Errorf("L2 only: %+v", b)
+	}
+
+	// OP Stack receipt: l1Fee on top
+	receipt["l1Fee"], receipt["l1GasUsed"], receipt["l1FeeScalar"] = "0x1f4", "0x640", "0.684"
+	if b, err = BreakDownFee(ctx, client, common.Hash{1}); err != nil || b.L1Fee != "500" || b.Total != "1500" || b.L1Fields["l1FeeScalar"] != "0.684" {
+		t.Errorf("OP Stack: %+v, %v", b, err)
+	}
+
+	// Arbitrum receipt: gasUsedForL1 carved out of gasUsed
+	delete(receipt, "l1Fee")
+	delete(receipt, "l1GasUsed")
+	delete(receipt, "l1FeeScalar")
+	receipt["gasUsedForL1"] = "0x28"
+	if b, err = BreakDownFee(ctx, client, common.Hash{1}); err != nil || b.L2Gas != 60 || b.L2Fee != "600" || b.L1Fee != "400" || b.Total != "1000" {
+		t.Errorf("Arbitrum: %+v, %v", b, err)
+	}
+
+	// zkEVM sequencer charging half of the offered 20 wei
+	delete(receipt, "gasUsedForL1")
+	s.Result(EffectiveGasPriceMethod, "0xa")
+	b, err = BreakDownFee(ctx, client, common.Hash{1})
+	if err != nil || b.SequencerGasPrice != "10" || b.EffectivePercentage == nil || *b.EffectivePercentage != 127 || len(b.Notes) != 0 {
+		t.Errorf("zkEVM: %+v, %v", b, err)
+	}
+}
@@ -0,0 +1,309 @@
+// Package chain holds the node interactions shared by the transactional
+// stages: loading the deployer key, signing and sending transactions and
+// waiting for their receipts. Failures callers need to tell apart are
+// reported as sentinel errors (ErrChainMismatch, ErrReceiptTimeout, ...) for
+// errors.Is, with typed errors carrying the details for errors.As.
+package chain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+
+	"cdk-erigon-precompile/internal/hooks"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+// DefaultGasPrice is the legacy gas price used for test transactions (1 Gwei).
+var DefaultGasPrice = big.NewInt(1e9)
+
+// PollInterval is how often WaitForReceipt asks for a receipt.
+var PollInterval = 2 * time.Second
+
+// LoadPrivateKey parses a hex private key (with or without 0x) and derives
+// its address.
+func LoadPrivateKey(privateKeyHex string) (*ecdsa.PrivateKey, common.Address, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(privateKeyHex), "0x"))
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	publicKeyECDSA, ok := privateKey.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, common.Address{}, fmt.Errorf("failed to cast public key to ECDSA")
+	}
+	return privateKey, crypto.PubkeyToAddress(*publicKeyECDSA), nil
+}
+
+// TxTypeEnv names the variable selecting the transaction type senders
+// build, as accepted by signer.ParseTxType, e.g. TX_TYPE=dynamic-fee.
+// Unset, transactions are legacy.
+const TxTypeEnv = "TX_TYPE"
+
+// EnvTxType returns the transaction type TX_TYPE selects.
+func EnvTxType() (signer.TxType, error) {
+	t, err := signer.ParseTxType(os.Getenv(TxTypeEnv))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", TxTypeEnv, err)
+	}
+	return t, nil
+}
+
+// Sender signs and submits transactions from a single account. The signer
+// may be remote, in which case only the digest leaves the host. With a
+// Role, every transaction is charged against the role's spend limit.
+type Sender struct {
+	Client   *ethclient.Client
+	Signer   signer.Signer
+	From     common.Address
+	ChainID  *big.Int
+	GasPrice *big.Int
+	Role     Role
+	// Type is the transaction type sent; nil takes it from TX_TYPE. For
+	// fee market types GasPrice is both the fee cap and the tip.
+	Type signer.TxType
+	// ReceiptTimeout is how long to wait for a receipt; zero waits
+	// three minutes.
+	ReceiptTimeout time.Duration
+}
+
+// NewRoleSender loads the role's key from the environment and returns a
+// sender for it on the client's chain.
+func NewRoleSender(ctx context.Context, client *ethclient.Client, r Role) (*Sender, error) {
+	s, err := RoleSigner(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := RoleLimit(r); err != nil {
+		return nil, err
+	}
+	txType, err := EnvTxType()
+	if err != nil {
+		return nil, err
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	return &Sender{Client: client, Signer: s, From: s.Address(), ChainID: chainID, Role: r, Type: txType}, nil
+}
+
+// Send signs a transaction calling to (or creating a contract when to is
+// nil), submits it and waits for the receipt. A node reporting the
+// transaction as already known is treated as a successful submission;
+// other rejections are classified with ClassifySend. In read-only mode it
+// fails with ErrReadOnly before touching the node, and past the role's
+// spend limit with a *SpendLimitError. The node's eth_estimateGas answer
+// is recorded next to the receipt's gas in EstimatesFile. A creation whose
+// receipt names another contractAddress than CreateAddress derives fails
+// with an *AddressDerivationError, alongside the receipt.
+func (s *Sender) Send(ctx context.Context, to *common.Address, data []byte, gas uint64) (*types.Transaction, *types.Receipt, error) {
+	return s.send(ctx, s.Type, signer.TxFields{To: to, Data: data, Gas: gas})
+}
+
+// Transfer sends value wei to an account.
+func (s *Sender) Transfer(ctx context.Context, to common.Address, value *big.Int) (*types.Transaction, *types.Receipt, error) {
+	return s.send(ctx, s.Type, signer.TxFields{To: &to, Value: value, Gas: params.TxGas})
+}
+
+// SendBlobs sends a blob transaction calling to that carries the blobs of
+// sidecar, whatever the sender's Type. blobHashes, when set, replace the
+// versioned hashes derived from the sidecar so nodes can be offered
+// inconsistent transactions. blobFeeCap bounds the blob base fee paid.
+func (s *Sender) SendBlobs(ctx context.Context, to common.Address, data []byte, gas uint64, sidecar *types.BlobTxSidecar, blobHashes []common.Hash, blobFeeCap *big.Int) (*types.Transaction, *types.Receipt, error) {
+	return s.send(ctx, signer.Blob, signer.TxFields{To: &to, Data: data, Gas: gas, Sidecar: sidecar, BlobHashes: blobHashes, BlobFeeCap: blobFeeCap})
+}
+
+// send fills in the chain ID, nonce and gas price of f and sends it as a
+// transaction of txType, or of the TX_TYPE type when txType is nil.
+func (s *Sender) send(ctx context.Context, txType signer.TxType, f signer.TxFields) (*types.Transaction, *types.Receipt, error) {
+	if err := CheckWritable(); err != nil {
+		return nil, nil, err
+	}
+	if err := s.beforeDeploy(ctx, f); err != nil {
+		return nil, nil, err
+	}
+	nonce, err := s.Client.PendingNonceAt(ctx, s.From)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	if f.Value == nil {
+		f.Value = new(big.Int)
+	}
+	// Estimated up front so the record compares against the same state the
+	// transaction executes on, barring blocks mined in between
+	estimate, estimateErr := s.estimate(ctx, f)
+	if estimateErr != nil {
+		output.Logf(output.ModuleDeploy, output.Verbose, "eth_estimateGas failed: %v", estimateErr)
+	}
+	f.Nonce = nonce
+	signedTx, err := s.sign(ctx, txType, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.Submit(ctx, signedTx); err != nil {
+		return nil, nil, err
+	}
+	receipt, err := s.Await(ctx, signedTx)
+	if err != nil {
+		return signedTx, nil, err
+	}
+	s.recordEstimate(ctx, signedTx, receipt, estimate, estimateErr)
+	// A contract the node placed elsewhere than derived isn't recorded
+	if err := VerifyCreation(s.From, signedTx, receipt); err != nil {
+		return signedTx, receipt, err
+	}
+	s.recordCreation(ctx, signedTx, receipt)
+	return signedTx, receipt, nil
+}
+
+// SignAt signs a transaction calling to (or creating a contract when to is
+// nil) at the given nonce without sending it, for callers choosing the
+// order transactions reach the node in. Submit sends it and Await waits for
+// its receipt.
+func (s *Sender) SignAt(ctx context.Context, nonce uint64, to *common.Address, data []byte, gas uint64) (*types.Transaction, error) {
+	if err := CheckWritable(); err != nil {
+		return nil, err
+	}
+	f := signer.TxFields{To: to, Data: data, Gas: gas, Nonce: nonce, Value: new(big.Int)}
+	if err := s.beforeDeploy(ctx, f); err != nil {
+		return nil, err
+	}
+	return s.sign(ctx, s.Type, f)
+}
+
+// beforeDeploy runs the BeforeDeploy hooks when f creates a contract,
+// before anything is asked of the node.
+func (s *Sender) beforeDeploy(ctx context.Context, f signer.TxFields) error {
+	if f.To != nil {
+		return nil
+	}
+	return hooks.BeforeDeploy(ctx, hooks.Deployment{Contract: ContractName(ctx), From: s.From, Data: f.Data, Gas: f.Gas})
+}
+
+// sign fills in the chain ID and gas price of f and signs it as a
+// transaction of txType, or of the TX_TYPE type when txType is nil.
+func (s *Sender) sign(ctx context.Context, txType signer.TxType, f signer.TxFields) (*types.Transaction, error) {
+	gasPrice := s.GasPrice
+	if gasPrice == nil {
+		gasPrice = DefaultGasPrice
+	}
+	if txType == nil {
+		var err error
+		if txType, err = EnvTxType(); err != nil {
+			return nil, err
+		}
+	}
+	f.ChainID, f.GasPrice = s.ChainID, gasPrice
+	txData, err := txType.Build(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s transaction: %w", txType.Name(), err)
+	}
+	signedTx, err := signer.SignTx(ctx, s.Signer, types.NewTx(txData), s.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	output.Logf(output.ModuleDeploy, output.Verbose, "signed %s %s: nonce %d, gas %d, %d bytes of data", txType.Name(), signedTx.Hash().Hex(), f.Nonce, f.Gas, len(f.Data))
+	return signedTx, nil
+}
+
+// Submit sends a signed transaction of the sender's without waiting for it
+// to be mined. Like Send, it charges the role's spend limit, tolerates a
+// node reporting the transaction as already known and journals it.
+func (s *Sender) Submit(ctx context.Context, tx *types.Transaction) error {
+	if err := CheckWritable(); err != nil {
+		return err
+	}
+	if output.V(output.ModuleDeploy, output.Debug) {
+		raw, _ := tx.MarshalBinary()
+		output.Logf(output.ModuleDeploy, output.Debug, "raw %s", hexutil.Encode(raw))
+	}
+	// The gas price is both the fee cap and the tip, so this is the most
+	// the transaction can cost
+	maxCost := tx.Cost()
+	if s.Role != "" {
+		if err := Charge(s.Role, maxCost); err != nil {
+			return err
+		}
+	}
+	if err := ClassifySend(s.Client.SendTransaction(ctx, tx)); err != nil {
+		if !errors.Is(err, ErrAlreadyKnown) {
+			if s.Role != "" {
+				Refund(s.Role, maxCost)
+			}
+			return fmt.Errorf("failed to send transaction: %w", err)
+		}
+		output.Logf(output.ModuleDeploy, output.Verbose, "%s already known by node", tx.Hash().Hex())
+	}
+	s.journal(ctx, tx)
+	return nil
+}
+
+// Await waits up to ReceiptTimeout for the receipt of a transaction Submit
+// sent, then drops it from the journal and refunds the role the gas it
+// didn't use.
+func (s *Sender) Await(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	timeout := s.ReceiptTimeout
+	if timeout == 0 {
+		timeout = 3 * time.Minute
+	}
+	receipt, err := WaitForReceipt(ctx, s.Client, tx.Hash(), timeout)
+	if err != nil {
+		// It may still be mined, so the charge stands and the journal
+		// keeps it for ResumePending
+		return nil, fmt.Errorf("failed to get receipt: %w", err)
+	}
+	if err := clearPending(tx.Hash()); err != nil {
+		output.Logf(output.ModuleDeploy, output.Normal, "%s not cleared from %s: %v", tx.Hash().Hex(), PendingFile, err)
+	}
+	if s.Role != "" && receipt.GasUsed <= tx.Gas() {
+		unused := new(big.Int).SetUint64(tx.Gas() - receipt.GasUsed)
+		Refund(s.Role, unused.Mul(unused, tx.GasFeeCap()))
+	}
+	return receipt, nil
+}
+
+// WaitForReceipt polls for the receipt of txHash until it appears. When the
+// timeout elapses it fails with a *ReceiptTimeoutError; cancellation of ctx
+// itself is returned as is.
+func WaitForReceipt(ctx context.Context, client *ethclient.Client, txHash common.Hash, timeout time.Duration) (*types.Receipt, error) {
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for polls := 1; ; polls++ {
+		select {
+		case <-ctx.Done():
+			output.Logf(output.ModuleDeploy, output.Verbose, "gave up on receipt of %s after %d polls", txHash.Hex(), polls-1)
+			if parent.Err() != nil {
+				return nil, parent.Err()
+			}
+			return nil, &ReceiptTimeoutError{TxHash: txHash, Timeout: timeout, Polls: polls - 1}
+		case <-ticker.C:
+			receipt, err := client.TransactionReceipt(ctx, txHash)
+			if err == nil && receipt != nil {
+				output.Logf(output.ModuleDeploy, output.Verbose, "receipt of %s after %d polls (%s): block %d, status %d, gas %d",
+					txHash.Hex(), polls, time.Since(start).Round(time.Millisecond), receipt.BlockNumber.Uint64(), receipt.Status, receipt.GasUsed)
+				return receipt, nil
+			}
+			output.Logf(output.ModuleDeploy, output.Debug, "poll %d for %s: %v", polls, txHash.Hex(), err)
+		}
+	}
+}
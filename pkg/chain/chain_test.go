@@ -0,0 +1,360 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/internal/hooks"
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+func newSender(t *testing.T) (*Sender, *mockrpc.Server, *mockrpc.Chain) {
+	t.Helper()
+	PollInterval = 5 * time.Millisecond
+	t.Setenv("WORK_DIR", t.TempDir())
+
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	c := mockrpc.NewChain(s, big.NewInt(10101))
+
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := signer.NewLocal(key)
+	return &Sender{Client: client, Signer: local, From: local.Address(), ChainID: c.ChainID}, s, c
+}
+
+func TestSendWaitsForReceipt(t *testing.T) {
+	sender, s, c := newSender(t)
+	c.ReceiptDelay = 3
+	c.SetNonce(sender.From, 7)
+
+	tx, receipt, err := sender.Send(WithContract(context.Background(), "Empty"), nil, []byte{0x60, 0x00}, 100_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Nonce() != 7 {
+		t.Errorf("nonce %d, want 7", tx.Nonce())
+	}
+	if receipt.TxHash != tx.Hash() || receipt.Status != 1 {
+		t.Errorf("receipt for %s status %d", receipt.TxHash.Hex(), receipt.Status)
+	}
+	if want := crypto.CreateAddress(sender.From, 7); receipt.ContractAddress != want {
+		t.Errorf("contract address %s, want %s", receipt.ContractAddress.Hex(), want.Hex())
+	}
+	if n := s.Calls("eth_getTransactionReceipt"); n != 4 {
+		t.Errorf("polled %d times, want 4", n)
+	}
+
+	ledger, err := LoadLedger(paths.Work(DeploymentsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := ledger.Active("10101")
+	if len(active) != 1 || active[0].Address != receipt.ContractAddress.Hex() || active[0].Contract != "Empty" || active[0].Tx != tx.Hash().Hex() {
+		t.Errorf("ledger %+v", ledger.Deployments)
+	}
+}
+
+func TestSendToleratesAlreadyKnown(t *testing.T) {
+	sender, s, _ := newSender(t)
+
+	// The node accepted the transaction earlier but answers the resend
+	// with "already known"
+	s.Handle("eth_sendRawTransaction", mockrpc.Fail(&mockrpc.Error{Code: -32000, Message: "already known"}))
+	s.Handle("eth_getTransactionReceipt", mockrpc.AfterPolls(1, map[string]any{
+		"transactionHash":   common.Hash{1},
+		"blockHash":         common.Hash{2},
+		"blockNumber":       "0x1",
+		"transactionIndex":  "0x0",
+		"cumulativeGasUsed": "0x5208",
+		"gasUsed":           "0x5208",
+		"logs":              []any{},
+		"logsBloom":         "0x" + strings.Repeat("0", 512),
+		"status":            "0x1",
+		"type":              "0x0",
+	}))
+
+	to := common.Address{0xaa}
+	_, receipt, err := sender.Send(context.Background(), &to, nil, 21_000)
+	if err != nil {
+		t.Fatalf("already known should not fail the send: %v", err)
+	}
+	if receipt.GasUsed != 21_000 {
+		t.Errorf("gas used %d", receipt.GasUsed)
+	}
+}
+
+func TestSendReportsRejection(t *testing.T) {
+	sender, s, _ := newSender(t)
+	s.Handle("eth_sendRawTransaction", mockrpc.Fail(&mockrpc.Error{Code: -32000, Message: "insufficient funds for gas * price + value"}))
+
+	to := common.Address{0xaa}
+	_, _, err := sender.Send(context.Background(), &to, nil, 21_000)
+	if !errors.Is(err, ErrInsufficientFund) {
+		t.Fatalf("got %v, want ErrInsufficientFund", err)
+	}
+	if s.Calls("eth_getTransactionReceipt") != 0 {
+		t.Error("polled for a receipt of a rejected transaction")
+	}
+}
+
+func TestSendTxType(t *testing.T) {
+	sender, _, _ := newSender(t)
+	t.Setenv(TxTypeEnv, "dynamic-fee")
+
+	to := common.Address{0xaa}
+	tx, receipt, err := sender.Send(context.Background(), &to, nil, 21_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type() != types.DynamicFeeTxType || receipt.Type != types.DynamicFeeTxType {
+		t.Errorf("sent type %d, receipt type %d, want dynamic fee", tx.Type(), receipt.Type)
+	}
+	if tx.GasFeeCap().Cmp(DefaultGasPrice) != 0 || tx.GasTipCap().Cmp(DefaultGasPrice) != 0 {
+		t.Errorf("fee cap %s, tip %s", tx.GasFeeCap(), tx.GasTipCap())
+	}
+
+	// An explicit type wins over the environment
+	sender.Type = signer.Legacy
+	if tx, _, err = sender.Send(context.Background(), &to, nil, 21_000); err != nil || tx.Type() != types.LegacyTxType {
+		t.Errorf("sent type %d, %v, want legacy", tx.Type(), err)
+	}
+
+	sender.Type = nil
+	t.Setenv(TxTypeEnv, "type-99")
+	if _, _, err := sender.Send(context.Background(), &to, nil, 21_000); err == nil || !strings.Contains(err.Error(), TxTypeEnv) {
+		t.Errorf("got %v, want a %s error", err, TxTypeEnv)
+	}
+}
+
+func TestSendBlobs(t *testing.T) {
+	sender, _, _ := newSender(t)
+	// The mock node verifies neither commitments nor proofs
+	sidecar := &types.BlobTxSidecar{Blobs: make([]kzg4844.Blob, 2), Commitments: make([]kzg4844.Commitment, 2), Proofs: make([]kzg4844.Proof, 2)}
+
+	tx, receipt, err := sender.SendBlobs(context.Background(), common.Address{0xaa}, nil, 21_000, sidecar, nil, big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Type() != types.BlobTxType || receipt.Type != types.BlobTxType {
+		t.Errorf("sent type %d, receipt type %d, want blob", tx.Type(), receipt.Type)
+	}
+	if hashes := tx.BlobHashes(); len(hashes) != 2 || hashes[0] != sidecar.BlobHashes()[0] || tx.BlobGasFeeCap().Int64() != 7 {
+		t.Errorf("blob hashes %v, fee cap %s", hashes, tx.BlobGasFeeCap())
+	}
+
+	if _, _, err := sender.SendBlobs(context.Background(), common.Address{}, nil, 21_000, nil, nil, nil); err == nil {
+		t.Error("sent a blob transaction without blobs")
+	}
+}
+
+func TestSignAtSubmitAwait(t *testing.T) {
+	sender, _, c := newSender(t)
+	c.SetNonce(sender.From, 3)
+
+	// Both are signed before either reaches the node
+	to := common.Address{0xaa}
+	var txs []*types.Transaction
+	for _, nonce := range []uint64{3, 4} {
+		tx, err := sender.SignAt(context.Background(), nonce, &to, nil, 21_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Nonce() != nonce {
+			t.Errorf("signed nonce %d, want %d", tx.Nonce(), nonce)
+		}
+		txs = append(txs, tx)
+	}
+	for _, tx := range txs {
+		if err := sender.Submit(context.Background(), tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	journal, err := LoadJournal(paths.Work(PendingFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal.Pending) != 2 {
+		t.Errorf("journaled %d transactions, want 2", len(journal.Pending))
+	}
+
+	for _, tx := range txs {
+		receipt, err := sender.Await(context.Background(), tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipt.TxHash != tx.Hash() {
+			t.Errorf("receipt of %s for %s", receipt.TxHash.Hex(), tx.Hash().Hex())
+		}
+	}
+	if journal, err = LoadJournal(paths.Work(PendingFile)); err != nil || len(journal.Pending) != 0 {
+		t.Errorf("journal %+v, %v, want empty", journal, err)
+	}
+
+	// Resending a mined transaction is tolerated like any already known one
+	if err := sender.Submit(context.Background(), txs[0]); err != nil {
+		t.Errorf("resubmission: %v", err)
+	}
+}
+
+func TestSendReadOnly(t *testing.T) {
+	sender, s, _ := newSender(t)
+	t.Setenv(ReadOnlyEnv, "true")
+
+	to := common.Address{0xaa}
+	if _, _, err := sender.Send(context.Background(), &to, nil, 21_000); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got %v, want ErrReadOnly", err)
+	}
+	if n := len(s.Log()); n != 0 {
+		t.Errorf("made %d requests in read-only mode", n)
+	}
+}
+
+func TestSendBeforeDeploy(t *testing.T) {
+	sender, s, _ := newSender(t)
+	refuse := errors.New("not on a Friday")
+	var got hooks.Deployment
+	defer hooks.Register(hooks.Hooks{BeforeDeploy: func(_ context.Context, d hooks.Deployment) error {
+		got = d
+		return refuse
+	}})()
+
+	ctx := WithContract(context.Background(), "Sha256Wrapper")
+	if _, _, err := sender.Send(ctx, nil, []byte{0x60, 0x00}, 100_000); !errors.Is(err, hooks.ErrHook) || !errors.Is(err, refuse) {
+		t.Fatalf("got %v, want the hook's error", err)
+	}
+	if got.Contract != "Sha256Wrapper" || got.From != sender.From || got.Gas != 100_000 {
+		t.Errorf("hook saw %+v", got)
+	}
+	if n := len(s.Log()); n != 0 {
+		t.Errorf("made %d requests for a refused deployment", n)
+	}
+	// Calls aren't deployments
+	to := common.Address{0xaa}
+	if _, err := sender.SignAt(ctx, 0, &to, nil, 21_000); err != nil {
+		t.Errorf("call refused: %v", err)
+	}
+}
+
+func TestWaitForReceiptTimeout(t *testing.T) {
+	sender, _, _ := newSender(t)
+
+	_, err := WaitForReceipt(context.Background(), sender.Client, common.Hash{0xde, 0xad}, 30*time.Millisecond)
+	if !errors.Is(err, ErrReceiptTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a receipt timeout", err)
+	}
+	var timeout *ReceiptTimeoutError
+	if !errors.As(err, &timeout) || timeout.TxHash != (common.Hash{0xde, 0xad}) || timeout.Polls == 0 {
+		t.Errorf("got %+v", timeout)
+	}
+}
+
+func TestWaitForReceiptCanceled(t *testing.T) {
+	sender, _, _ := newSender(t)
+
+	// Canceling the caller's context is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := WaitForReceipt(ctx, sender.Client, common.Hash{0xde, 0xad}, time.Minute)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrReceiptTimeout) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestClassifySend(t *testing.T) {
+	for msg, want := range map[string]error{
+		"already known":                       ErrAlreadyKnown,
+		"known transaction: 0xabc":            ErrAlreadyKnown,
+		"nonce too low: next nonce 5":         ErrNonceTooLow,
+		"replacement transaction underpriced": ErrUnderpriced,
+		"insufficient funds for transfer":     ErrInsufficientFund,
+	} {
+		err := ClassifySend(&mockrpc.Error{Code: -32000, Message: msg})
+		if !errors.Is(err, want) {
+			t.Errorf("%q: got %v, want %v", msg, err, want)
+		}
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("%q: node message lost in %q", msg, err)
+		}
+	}
+	other := errors.New("connection refused")
+	if ClassifySend(other) != other || ClassifySend(nil) != nil {
+		t.Error("unrelated errors should pass through unchanged")
+	}
+}
+
+func TestCheckChainID(t *testing.T) {
+	sender, _, _ := newSender(t)
+	if err := CheckChainID(context.Background(), sender.Client, big.NewInt(10101)); err != nil {
+		t.Fatal(err)
+	}
+	err := CheckChainID(context.Background(), sender.Client, big.NewInt(1101))
+	var mismatch *ChainMismatchError
+	if !errors.Is(err, ErrChainMismatch) || !errors.As(err, &mismatch) || mismatch.Actual.Int64() != 10101 {
+		t.Fatalf("got %v, want a chain mismatch", err)
+	}
+}
+
+func TestSendRecordsEstimate(t *testing.T) {
+	sender, s, _ := newSender(t)
+	// The mock chain uses half the gas limit
+	estimates := []uint64{60_000, 40_000}
+	s.Handle("eth_estimateGas", func(mockrpc.Call) (any, error) {
+		e := estimates[0]
+		estimates = estimates[1:]
+		return hexutil.Uint64(e), nil
+	})
+
+	to := common.Address{0xaa}
+	for range 2 {
+		if _, _, err := sender.Send(context.Background(), &to, nil, 100_000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Handle("eth_estimateGas", mockrpc.Fail(&mockrpc.Error{Code: 3, Message: "execution reverted"}))
+	if _, _, err := sender.Send(context.Background(), &to, nil, 100_000); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := LoadEstimates(paths.Work(EstimatesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Transactions) != 3 {
+		t.Fatalf("recorded %d transactions, want 3", len(e.Transactions))
+	}
+	for i, want := range []GasEstimate{
+		{Estimate: 60_000, GasUsed: 50_000},
+		{Estimate: 40_000, GasUsed: 50_000, Underestimated: true},
+		{GasUsed: 50_000, EstimateError: "execution reverted"},
+	} {
+		g := e.Transactions[i]
+		if g.Estimate != want.Estimate || g.GasUsed != want.GasUsed || g.Underestimated != want.Underestimated || g.EstimateError != want.EstimateError {
+			t.Errorf("transaction %d: %+v", i, g)
+		}
+	}
+	if under := e.Underestimated(); len(under) != 1 || under[0].Estimate != 40_000 {
+		t.Errorf("underestimated %+v", under)
+	}
+}
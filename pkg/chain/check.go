@@ -0,0 +1,48 @@
+package chain
+
+import "cdk-erigon-precompile/pkg/skip"
+
+// Check is one assertion about chain state around a transaction: account
+// accounting, fee fields, receipt shape or block consistency. A skipped
+// check has the reason code in Skip, explained by Note.
+type Check struct {
+	Name     string       `json:"name"`
+	Expected string       `json:"expected,omitempty"`
+	Actual   string       `json:"actual,omitempty"`
+	Passed   bool         `json:"passed"`
+	Skipped  bool         `json:"skipped,omitempty"`
+	Skip     *skip.Reason `json:"skip,omitempty"`
+	Note     string       `json:"note,omitempty"`
+}
+
+// MarkSkipped records that c wasn't checked, for code, with note saying
+// why.
+func (c *Check) MarkSkipped(code skip.Code, note string) {
+	c.Skipped, c.Skip, c.Note = true, skip.New(code, ""), note
+}
+
+// skippedCheck is a check named name that wasn't checked.
+func skippedCheck(name string, code skip.Code, note string) Check {
+	c := Check{Name: name}
+	c.MarkSkipped(code, note)
+	return c
+}
+
+// AllPassed reports whether every non-skipped check passed.
+func AllPassed(checks []Check) bool {
+	for _, c := range checks {
+		if !c.Skipped && !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
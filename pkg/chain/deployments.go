@@ -0,0 +1,140 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// DeploymentsFile is the ledger of every contract the tool deployed, kept in
+// the work directory so shared testnets can be tidied up later.
+const DeploymentsFile = "deployments.json"
+
+// Deployment is one contract the tool created.
+type Deployment struct {
+	Address string `json:"address"`
+	// Contract names what was deployed, when the caller said so.
+	Contract   string `json:"contract,omitempty"`
+	ChainID    string `json:"chainId"`
+	Deployer   string `json:"deployer"`
+	Role       Role   `json:"role,omitempty"`
+	Tx         string `json:"tx"`
+	Block      uint64 `json:"block"`
+	DeployedAt string `json:"deployedAt"`
+	// RetiredAt is set once cleanup retired the contract: the tool no
+	// longer uses it, whether or not its code is gone.
+	RetiredAt string `json:"retiredAt,omitempty"`
+	Destroyed bool   `json:"destroyed,omitempty"`
+}
+
+// Retired reports whether cleanup has retired d.
+func (d Deployment) Retired() bool { return d.RetiredAt != "" }
+
+// Ledger is the content of DeploymentsFile.
+type Ledger struct {
+	Deployments []Deployment `json:"deployments"`
+}
+
+// LoadLedger reads the ledger at path; a missing file is an empty ledger.
+func LoadLedger(path string) (*Ledger, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Ledger{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployments: %w", err)
+	}
+	var l Ledger
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &l, nil
+}
+
+// Save writes the ledger to path.
+func (l *Ledger) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return paths.WriteFile(path, data)
+}
+
+// Active returns the deployments on chainID that aren't retired yet.
+func (l *Ledger) Active(chainID string) []*Deployment {
+	var out []*Deployment
+	for i := range l.Deployments {
+		if d := &l.Deployments[i]; d.ChainID == chainID && !d.Retired() {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// ledgerMu serializes the load-append-save of RecordDeployment within a
+// process.
+var ledgerMu sync.Mutex
+
+// RecordDeployment appends d to the ledger in the work directory.
+func RecordDeployment(d Deployment) error {
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+	path := paths.Work(DeploymentsFile)
+	l, err := LoadLedger(path)
+	if err != nil {
+		return err
+	}
+	if d.DeployedAt == "" {
+		d.DeployedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	l.Deployments = append(l.Deployments, d)
+	return l.Save(path)
+}
+
+type contractKey struct{}
+
+// WithContract names the contracts Sender deploys with ctx in the ledger.
+func WithContract(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contractKey{}, name)
+}
+
+// ContractName is the name WithContract attached to ctx, or "".
+func ContractName(ctx context.Context) string {
+	name, _ := ctx.Value(contractKey{}).(string)
+	return name
+}
+
+// recordCreation adds a successful contract creation by s to the ledger.
+// The deployment stands whether or not it can be recorded, so a failure is
+// only logged.
+func (s *Sender) recordCreation(ctx context.Context, tx *types.Transaction, receipt *types.Receipt) {
+	if tx.To() != nil || receipt.Status != types.ReceiptStatusSuccessful || receipt.ContractAddress == (common.Address{}) {
+		return
+	}
+	var chainID string
+	if s.ChainID != nil {
+		chainID = s.ChainID.String()
+	}
+	err := RecordDeployment(Deployment{
+		Address:  receipt.ContractAddress.Hex(),
+		Contract: ContractName(ctx),
+		ChainID:  chainID,
+		Deployer: s.From.Hex(),
+		Role:     s.Role,
+		Tx:       tx.Hash().Hex(),
+		Block:    receipt.BlockNumber.Uint64(),
+	})
+	if err != nil {
+		output.Logf(output.ModuleDeploy, output.Normal, "deployment of %s not recorded in %s: %v", receipt.ContractAddress.Hex(), DeploymentsFile, err)
+	}
+}
@@ -0,0 +1,100 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+// GenerateAccount creates a fresh key that only lives in memory, returned
+// with its hex encoding for handing to child processes through their
+// environment. Parallel runs each using their own account never contend
+// for nonces.
+func GenerateAccount() (*signer.Local, string, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return signer.NewLocal(key), "0x" + common.Bytes2Hex(crypto.FromECDSA(key)), nil
+}
+
+// RequestFaucet asks a faucet to fund addr by posting {"address": addr} to
+// faucetURL. Any 2xx answer counts as accepted; wait for the funds with
+// WaitForBalance.
+func RequestFaucet(ctx context.Context, faucetURL string, addr common.Address) error {
+	body, err := json.Marshal(map[string]string{"address": addr.Hex()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, faucetURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid faucet URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("faucet request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("faucet returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// WaitForBalance polls addr until it holds at least min wei, returning the
+// balance, or fails once timeout elapses.
+func WaitForBalance(ctx context.Context, client *ethclient.Client, addr common.Address, min *big.Int, timeout time.Duration) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		balance, err := client.BalanceAt(ctx, addr, nil)
+		if err == nil && balance.Cmp(min) >= 0 {
+			return balance, nil
+		}
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return nil, fmt.Errorf("balance of %s not available after %s: %w", addr.Hex(), timeout, err)
+			}
+			return nil, fmt.Errorf("%s holds %s wei after %s, want %s", addr.Hex(), balance, timeout, min)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep transfers everything s.From holds, less the transfer's own fee, to
+// the given account. Balances too small to pay for the transfer are left
+// behind and reported with a nil transaction.
+func Sweep(ctx context.Context, s *Sender, to common.Address) (*types.Transaction, *types.Receipt, error) {
+	balance, err := s.Client.BalanceAt(ctx, s.From, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get balance of %s: %w", s.From.Hex(), err)
+	}
+	gasPrice := s.GasPrice
+	if gasPrice == nil {
+		gasPrice = DefaultGasPrice
+	}
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(params.TxGas))
+	if balance.Cmp(fee) <= 0 {
+		return nil, nil, nil
+	}
+	return s.Transfer(ctx, to, balance.Sub(balance, fee))
+}
@@ -0,0 +1,59 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestSweep(t *testing.T) {
+	sender, s, c := newSender(t)
+	balance := big.NewInt(params.Ether)
+	s.Handle("eth_getBalance", func(mockrpc.Call) (any, error) { return (*hexutil.Big)(balance), nil })
+
+	to := common.Address{0xf0}
+	if _, _, err := Sweep(context.Background(), sender, to); err != nil {
+		t.Fatal(err)
+	}
+	sent := c.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d transactions, want 1", len(sent))
+	}
+	want := new(big.Int).Sub(balance, new(big.Int).Mul(DefaultGasPrice, new(big.Int).SetUint64(params.TxGas)))
+	if *sent[0].To() != to || sent[0].Value().Cmp(want) != 0 {
+		t.Errorf("swept %s to %s, want %s to %s", sent[0].Value(), sent[0].To().Hex(), want, to.Hex())
+	}
+
+	// Dust that can't pay for the transfer stays
+	balance = big.NewInt(1000)
+	if tx, _, err := Sweep(context.Background(), sender, to); tx != nil || err != nil {
+		t.Errorf("swept dust: %v, %v", tx, err)
+	}
+}
+
+func TestRequestFaucet(t *testing.T) {
+	addr := common.Address{0xab}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Address string }
+		if json.NewDecoder(r.Body).Decode(&in) != nil || common.HexToAddress(in.Address) != addr {
+			http.Error(w, "bad address", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	if err := RequestFaucet(context.Background(), srv.URL, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := RequestFaucet(context.Background(), srv.URL, common.Address{1}); err == nil {
+		t.Error("faucet rejection not reported")
+	}
+}
@@ -0,0 +1,123 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Sentinel errors for the failures callers branch on. Functions return them
+// wrapped, or as the typed errors below which match them with errors.Is and
+// carry the details for errors.As.
+var (
+	ErrChainMismatch    = errors.New("chain ID mismatch")
+	ErrNoCodeAtAddress  = errors.New("no contract code at address")
+	ErrReceiptTimeout   = errors.New("timed out waiting for receipt")
+	ErrReverted         = errors.New("transaction reverted")
+	ErrAlreadyKnown     = errors.New("transaction already known")
+	ErrNonceTooLow      = errors.New("nonce too low")
+	ErrUnderpriced      = errors.New("transaction underpriced")
+	ErrInsufficientFund = errors.New("insufficient funds for gas * price + value")
+	ErrAddressMismatch  = errors.New("contract address not derived as specified")
+)
+
+// ChainMismatchError reports a node on a different chain than expected.
+type ChainMismatchError struct {
+	Expected, Actual *big.Int
+}
+
+func (e *ChainMismatchError) Error() string {
+	return fmt.Sprintf("chain ID mismatch: expected %d, node is on %d", e.Expected, e.Actual)
+}
+
+func (e *ChainMismatchError) Is(target error) bool { return target == ErrChainMismatch }
+
+// NoCodeError reports an address without contract code.
+type NoCodeError struct {
+	Address common.Address
+}
+
+func (e *NoCodeError) Error() string {
+	return fmt.Sprintf("no contract code found at address %s", e.Address.Hex())
+}
+
+func (e *NoCodeError) Is(target error) bool { return target == ErrNoCodeAtAddress }
+
+// AddressDerivationError reports a node placing a contract elsewhere than
+// the CREATE or CREATE2 formula derives.
+type AddressDerivationError struct {
+	Opcode            string
+	Derived, Reported common.Address
+}
+
+func (e *AddressDerivationError) Error() string {
+	return fmt.Sprintf("%s address mismatch: derived %s, node reports %s", e.Opcode, e.Derived.Hex(), e.Reported.Hex())
+}
+
+func (e *AddressDerivationError) Is(target error) bool { return target == ErrAddressMismatch }
+
+// ReceiptTimeoutError reports a transaction that wasn't mined in time. It
+// unwraps to context.DeadlineExceeded.
+type ReceiptTimeoutError struct {
+	TxHash  common.Hash
+	Timeout time.Duration
+	Polls   int
+}
+
+func (e *ReceiptTimeoutError) Error() string {
+	return fmt.Sprintf("no receipt for %s after %s (%d polls)", e.TxHash.Hex(), e.Timeout, e.Polls)
+}
+
+func (e *ReceiptTimeoutError) Is(target error) bool { return target == ErrReceiptTimeout }
+
+func (e *ReceiptTimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+// sendErrors maps the messages nodes use for rejected transactions to the
+// sentinels. Geth and erigon say "already known", older clients "known
+// transaction".
+var sendErrors = []struct {
+	substrings []string
+	err        error
+}{
+	{[]string{"already known", "known transaction"}, ErrAlreadyKnown},
+	{[]string{"nonce too low"}, ErrNonceTooLow},
+	{[]string{"underpriced"}, ErrUnderpriced},
+	{[]string{"insufficient funds"}, ErrInsufficientFund},
+}
+
+// ClassifySend wraps a transaction submission error with the matching
+// sentinel, keeping the node's message, so callers can use errors.Is. It
+// returns other errors unchanged, and nil for nil.
+func ClassifySend(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	for _, e := range sendErrors {
+		for _, s := range e.substrings {
+			if strings.Contains(msg, s) {
+				return fmt.Errorf("%w: %w", e.err, err)
+			}
+		}
+	}
+	return err
+}
+
+// CheckChainID fails with a *ChainMismatchError unless the node is on
+// chain expected.
+func CheckChainID(ctx context.Context, client *ethclient.Client, expected *big.Int) error {
+	actual, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	if actual.Cmp(expected) != 0 {
+		return &ChainMismatchError{Expected: expected, Actual: actual}
+	}
+	return nil
+}
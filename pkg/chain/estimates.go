@@ -0,0 +1,159 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+// EstimatesFile records, for every transaction Sender mined, the node's
+// eth_estimateGas answer next to the gas the receipt reports, checking the
+// node's estimation path against its execution path.
+const EstimatesFile = "gas_estimates.json"
+
+// GasEstimate is one transaction's estimate and actual gas use.
+type GasEstimate struct {
+	Tx       string `json:"tx"`
+	ChainID  string `json:"chainId"`
+	From     string `json:"from"`
+	To       string `json:"to,omitempty"`
+	Contract string `json:"contract,omitempty"`
+	Block    uint64 `json:"block"`
+	Status   uint64 `json:"status"`
+	GasLimit uint64 `json:"gasLimit"`
+	// Estimate is zero when EstimateError is set.
+	Estimate      uint64 `json:"estimate"`
+	EstimateError string `json:"estimateError,omitempty"`
+	GasUsed       uint64 `json:"gasUsed"`
+	// Underestimated is set when the estimate is below the gas used, so a
+	// wallet sending with the estimate as its limit would run out of gas.
+	Underestimated bool   `json:"underestimated"`
+	RecordedAt     string `json:"recordedAt"`
+}
+
+// Estimates is the content of EstimatesFile.
+type Estimates struct {
+	Transactions []GasEstimate `json:"transactions"`
+}
+
+// LoadEstimates reads the estimates at path; a missing file has none.
+func LoadEstimates(path string) (*Estimates, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Estimates{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gas estimates: %w", err)
+	}
+	var e Estimates
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &e, nil
+}
+
+// Save writes the estimates to path.
+func (e *Estimates) Save(path string) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return paths.WriteFile(path, data)
+}
+
+// Underestimated returns the transactions whose estimate fell short.
+func (e *Estimates) Underestimated() []GasEstimate {
+	var out []GasEstimate
+	for _, g := range e.Transactions {
+		if g.Underestimated {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// Since returns the estimates recorded at or after t, to the second.
+func (e *Estimates) Since(t time.Time) *Estimates {
+	out := &Estimates{}
+	for _, g := range e.Transactions {
+		at, err := time.Parse(time.RFC3339, g.RecordedAt)
+		if err == nil && !at.Before(t.Truncate(time.Second)) {
+			out.Transactions = append(out.Transactions, g)
+		}
+	}
+	return out
+}
+
+// estimatesMu serializes the load-append-save of RecordEstimate within a
+// process.
+var estimatesMu sync.Mutex
+
+// RecordEstimate appends g to the estimates in the work directory.
+func RecordEstimate(g GasEstimate) error {
+	estimatesMu.Lock()
+	defer estimatesMu.Unlock()
+	path := paths.Work(EstimatesFile)
+	e, err := LoadEstimates(path)
+	if err != nil {
+		return err
+	}
+	if g.RecordedAt == "" {
+		g.RecordedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	e.Transactions = append(e.Transactions, g)
+	return e.Save(path)
+}
+
+// estimate asks the node what f would cost. The fee fields are left out:
+// they don't change the gas, and a zero price skips the balance check.
+func (s *Sender) estimate(ctx context.Context, f signer.TxFields) (uint64, error) {
+	return s.Client.EstimateGas(ctx, ethereum.CallMsg{
+		From:       s.From,
+		To:         f.To,
+		Value:      f.Value,
+		Data:       f.Data,
+		BlobHashes: f.BlobHashes,
+	})
+}
+
+// recordEstimate adds the estimate taken before sending tx, or the reason
+// there is none, next to the gas its receipt reports. The transaction is
+// mined whether or not it can be recorded, so a failure is only logged.
+func (s *Sender) recordEstimate(ctx context.Context, tx *types.Transaction, receipt *types.Receipt, estimate uint64, estimateErr error) {
+	g := GasEstimate{
+		Tx:       tx.Hash().Hex(),
+		From:     s.From.Hex(),
+		Contract: ContractName(ctx),
+		Block:    receipt.BlockNumber.Uint64(),
+		Status:   receipt.Status,
+		GasLimit: tx.Gas(),
+		Estimate: estimate,
+		GasUsed:  receipt.GasUsed,
+	}
+	if s.ChainID != nil {
+		g.ChainID = s.ChainID.String()
+	}
+	if tx.To() != nil {
+		g.To = tx.To().Hex()
+	}
+	if estimateErr != nil {
+		g.EstimateError = estimateErr.Error()
+	} else if estimate < receipt.GasUsed {
+		g.Underestimated = true
+		output.Logf(output.ModuleDeploy, output.Normal, "⚠️  %s used %d gas, more than the node's estimate of %d", g.Tx, receipt.GasUsed, estimate)
+	}
+	if err := RecordEstimate(g); err != nil {
+		output.Logf(output.ModuleDeploy, output.Normal, "estimate of %s not recorded in %s: %v", g.Tx, EstimatesFile, err)
+	}
+}
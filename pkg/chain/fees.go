@@ -0,0 +1,161 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// gasUsedRatioTolerance absorbs float rounding in eth_feeHistory.
+const gasUsedRatioTolerance = 1e-9
+
+// CheckFees validates the fee fields around a mined transaction for internal
+// consistency: the receipt's effectiveGasPrice against the transaction type
+// and block baseFee, eth_feeHistory for the including block against its
+// header, and eth_maxPriorityFeePerGas / eth_gasPrice against the latest
+// baseFee. Blocks without a baseFee (pre-London or zkEVM chains that don't
+// set one) are treated as a zero baseFee where the RPC spec allows it.
+func CheckFees(ctx context.Context, client *ethclient.Client, tx *types.Transaction, receipt *types.Receipt) []Check {
+	var checks []Check
+
+	header, err := client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return []Check{{Name: "including block header", Note: err.Error()}}
+	}
+
+	// effectiveGasPrice
+	check := Check{Name: "receipt effectiveGasPrice"}
+	expected := effectiveGasPrice(tx, header.BaseFee)
+	switch {
+	case receipt.EffectiveGasPrice == nil:
+		check.Skipped, check.Note = true, "receipt has no effectiveGasPrice"
+	case expected == nil:
+		check.Skipped, check.Note = true, "dynamic fee transaction in a block without baseFee"
+	default:
+		check.Expected = expected.String()
+		check.Actual = receipt.EffectiveGasPrice.String()
+		check.Passed = expected.Cmp(receipt.EffectiveGasPrice) == 0
+	}
+	checks = append(checks, check)
+
+	// Fee cap must cover the baseFee of the including block
+	check = Check{Name: "fee cap >= block baseFee"}
+	if header.BaseFee == nil {
+		check.Skipped, check.Note = true, "block has no baseFee"
+	} else {
+		check.Expected = ">= " + header.BaseFee.String()
+		check.Actual = tx.GasFeeCap().String()
+		check.Passed = tx.GasFeeCap().Cmp(header.BaseFee) >= 0
+	}
+	checks = append(checks, check)
+
+	checks = append(checks, checkFeeHistory(ctx, client, header)...)
+
+	// Fee suggestions must be usable against the current baseFee
+	latest, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return append(checks, Check{Name: "latest block header", Note: err.Error()})
+	}
+	latestBaseFee := baseFeeOrZero(latest)
+
+	check = Check{Name: "eth_maxPriorityFeePerGas", Expected: ">= 0"}
+	if tip, err := client.SuggestGasTipCap(ctx); err != nil {
+		check.Note = err.Error()
+	} else {
+		check.Actual = tip.String()
+		check.Passed = tip.Sign() >= 0
+	}
+	checks = append(checks, check)
+
+	check = Check{Name: "eth_gasPrice >= latest baseFee", Expected: ">= " + latestBaseFee.String()}
+	if price, err := client.SuggestGasPrice(ctx); err != nil {
+		check.Note = err.Error()
+	} else {
+		check.Actual = price.String()
+		check.Passed = price.Cmp(latestBaseFee) >= 0
+	}
+	return append(checks, check)
+}
+
+// checkFeeHistory asks eth_feeHistory for the single including block and
+// compares it with the header; the following block's baseFee is compared
+// when that block already exists.
+func checkFeeHistory(ctx context.Context, client *ethclient.Client, header *types.Header) []Check {
+	history, err := client.FeeHistory(ctx, 1, header.Number, []float64{50})
+	if err != nil {
+		return []Check{{Name: "eth_feeHistory", Note: err.Error()}}
+	}
+
+	var checks []Check
+	check := Check{Name: "feeHistory oldestBlock", Expected: header.Number.String()}
+	if history.OldestBlock != nil {
+		check.Actual = history.OldestBlock.String()
+		check.Passed = history.OldestBlock.Cmp(header.Number) == 0
+	}
+	checks = append(checks, check)
+
+	check = Check{Name: "feeHistory baseFee = header baseFee", Expected: baseFeeOrZero(header).String()}
+	if len(history.BaseFee) > 0 {
+		check.Actual = history.BaseFee[0].String()
+		check.Passed = history.BaseFee[0].Cmp(baseFeeOrZero(header)) == 0
+	} else {
+		check.Note = "no baseFeePerGas entries"
+	}
+	checks = append(checks, check)
+
+	check = Check{Name: "feeHistory gasUsedRatio = gasUsed/gasLimit"}
+	if header.GasLimit == 0 {
+		check.Skipped, check.Note = true, "block gasLimit is 0"
+	} else if len(history.GasUsedRatio) == 0 {
+		check.Note = "no gasUsedRatio entries"
+	} else {
+		ratio := float64(header.GasUsed) / float64(header.GasLimit)
+		check.Expected = fmt.Sprintf("%.9f", ratio)
+		check.Actual = fmt.Sprintf("%.9f", history.GasUsedRatio[0])
+		check.Passed = math.Abs(ratio-history.GasUsedRatio[0]) <= gasUsedRatioTolerance
+	}
+	checks = append(checks, check)
+
+	// baseFeePerGas has one extra entry: the baseFee of the next block
+	check = Check{Name: "feeHistory next baseFee = next header baseFee"}
+	next, err := client.HeaderByNumber(ctx, new(big.Int).Add(header.Number, big.NewInt(1)))
+	switch {
+	case err != nil:
+		check.Skipped, check.Note = true, "next block not mined yet"
+	case len(history.BaseFee) < 2:
+		check.Note = "baseFeePerGas is missing the next-block entry"
+	default:
+		check.Expected = baseFeeOrZero(next).String()
+		check.Actual = history.BaseFee[1].String()
+		check.Passed = history.BaseFee[1].Cmp(baseFeeOrZero(next)) == 0
+	}
+	return append(checks, check)
+}
+
+// effectiveGasPrice is the price a transaction pays in a block: its gas
+// price for legacy and access-list transactions, min(feeCap, baseFee+tip)
+// for dynamic fee ones. It returns nil when that can't be determined.
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+		return tx.GasPrice()
+	}
+	if baseFee == nil {
+		return nil
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		price.Set(tx.GasFeeCap())
+	}
+	return price
+}
+
+func baseFeeOrZero(h *types.Header) *big.Int {
+	if h.BaseFee == nil {
+		return new(big.Int)
+	}
+	return h.BaseFee
+}
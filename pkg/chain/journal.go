@@ -0,0 +1,202 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// PendingFile journals the transactions Sender has submitted but not yet
+// seen mined, so a run cut short by a dropped connection can pick their
+// receipts up again instead of losing track of them.
+const PendingFile = "pending_txs.json"
+
+// PendingTx is a submitted transaction still waiting for its receipt.
+type PendingTx struct {
+	Hash    string `json:"hash"`
+	ChainID string `json:"chainId"`
+	From    string `json:"from"`
+	Nonce   uint64 `json:"nonce"`
+	Role    Role   `json:"role,omitempty"`
+	// Create is set for contract creations, recorded in the deployment
+	// ledger once mined; Contract is their name, if any.
+	Create   bool   `json:"create,omitempty"`
+	Contract string `json:"contract,omitempty"`
+	SentAt   string `json:"sentAt"`
+}
+
+// Journal is the content of PendingFile.
+type Journal struct {
+	Pending []PendingTx `json:"pending"`
+}
+
+// LoadJournal reads the journal at path; a missing file is an empty journal.
+func LoadJournal(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Journal{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending transactions: %w", err)
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &j, nil
+}
+
+// Save writes the journal to path.
+func (j *Journal) Save(path string) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return paths.WriteFile(path, data)
+}
+
+// OnChain returns the pending transactions of chainID.
+func (j *Journal) OnChain(chainID string) []PendingTx {
+	var out []PendingTx
+	for _, p := range j.Pending {
+		if p.ChainID == chainID {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// journalMu serializes the load-modify-save of the journal within a process.
+var journalMu sync.Mutex
+
+// updateJournal applies fn to the journal in the work directory.
+func updateJournal(fn func(*Journal)) error {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	path := paths.Work(PendingFile)
+	j, err := LoadJournal(path)
+	if err != nil {
+		return err
+	}
+	fn(j)
+	return j.Save(path)
+}
+
+// addPending adds p to the journal.
+func addPending(p PendingTx) error {
+	if p.SentAt == "" {
+		p.SentAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	return updateJournal(func(j *Journal) {
+		j.Pending = append(j.Pending, p)
+	})
+}
+
+// journal adds tx, just submitted by s, to the journal. The
+// transaction is out whether or not it can be journaled, so a failure is
+// only logged.
+func (s *Sender) journal(ctx context.Context, tx *types.Transaction) {
+	var chainID string
+	if s.ChainID != nil {
+		chainID = s.ChainID.String()
+	}
+	err := addPending(PendingTx{
+		Hash:     tx.Hash().Hex(),
+		ChainID:  chainID,
+		From:     s.From.Hex(),
+		Nonce:    tx.Nonce(),
+		Role:     s.Role,
+		Create:   tx.To() == nil,
+		Contract: ContractName(ctx),
+	})
+	if err != nil {
+		output.Logf(output.ModuleDeploy, output.Normal, "%s not journaled in %s: %v", tx.Hash().Hex(), PendingFile, err)
+	}
+}
+
+// clearPending drops hash from the journal.
+func clearPending(hash common.Hash) error {
+	return updateJournal(func(j *Journal) {
+		kept := j.Pending[:0]
+		for _, p := range j.Pending {
+			if p.Hash != hash.Hex() {
+				kept = append(kept, p)
+			}
+		}
+		j.Pending = kept
+	})
+}
+
+// ResumedTx is the outcome of waiting again for a journaled transaction.
+type ResumedTx struct {
+	PendingTx
+	Receipt *types.Receipt `json:"-"`
+	Block   uint64         `json:"block,omitempty"`
+	Status  uint64         `json:"status,omitempty"`
+	// Error is set when the receipt still didn't appear; the transaction
+	// stays in the journal.
+	Error string `json:"error,omitempty"`
+}
+
+// ResumePending waits up to timeout for the receipt of each transaction the
+// journal holds for the client's chain, dropping the mined ones from it and
+// recording mined contract creations in the deployment ledger. Cancelling
+// ctx stops early, with the remaining transactions left in the journal.
+func ResumePending(ctx context.Context, client *ethclient.Client, timeout time.Duration) ([]ResumedTx, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	journalMu.Lock()
+	j, err := LoadJournal(paths.Work(PendingFile))
+	journalMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var resumed []ResumedTx
+	for _, p := range j.OnChain(chainID.String()) {
+		r := ResumedTx{PendingTx: p}
+		hash := common.HexToHash(p.Hash)
+		receipt, err := WaitForReceipt(ctx, client, hash, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return resumed, ctx.Err()
+			}
+			r.Error = err.Error()
+			resumed = append(resumed, r)
+			continue
+		}
+		r.Receipt, r.Block, r.Status = receipt, receipt.BlockNumber.Uint64(), receipt.Status
+		if p.Create && receipt.Status == types.ReceiptStatusSuccessful {
+			err = RecordDeployment(Deployment{
+				Address:  receipt.ContractAddress.Hex(),
+				Contract: p.Contract,
+				ChainID:  p.ChainID,
+				Deployer: p.From,
+				Role:     p.Role,
+				Tx:       p.Hash,
+				Block:    r.Block,
+			})
+			if err != nil {
+				return resumed, err
+			}
+		}
+		if err := clearPending(hash); err != nil {
+			return resumed, err
+		}
+		resumed = append(resumed, r)
+	}
+	return resumed, nil
+}
@@ -0,0 +1,62 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+func TestResumePending(t *testing.T) {
+	sender, _, c := newSender(t)
+	sender.ReceiptTimeout = 20 * time.Millisecond
+	c.ReceiptDelay = 10
+
+	// The receipt doesn't show up in time, as when the node drops mid-wait
+	tx, _, err := sender.Send(WithContract(context.Background(), "Empty"), nil, []byte{0x60, 0x00}, 100_000)
+	if !errors.Is(err, ErrReceiptTimeout) {
+		t.Fatalf("got %v, want a receipt timeout", err)
+	}
+	journal, err := LoadJournal(paths.Work(PendingFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := journal.OnChain("10101")
+	if len(pending) != 1 || pending[0].Hash != tx.Hash().Hex() || !pending[0].Create || pending[0].Contract != "Empty" {
+		t.Fatalf("journal %+v", journal.Pending)
+	}
+
+	resumed, err := ResumePending(context.Background(), sender.Client, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed) != 1 || resumed[0].Error != "" || resumed[0].Status != 1 {
+		t.Fatalf("resumed %+v", resumed)
+	}
+	if journal, _ = LoadJournal(paths.Work(PendingFile)); len(journal.Pending) != 0 {
+		t.Errorf("journal still holds %+v", journal.Pending)
+	}
+	ledger, err := LoadLedger(paths.Work(DeploymentsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active := ledger.Active("10101"); len(active) != 1 || active[0].Tx != tx.Hash().Hex() || active[0].Contract != "Empty" {
+		t.Errorf("ledger %+v", ledger.Deployments)
+	}
+}
+
+func TestSendClearsJournal(t *testing.T) {
+	sender, _, _ := newSender(t)
+	if _, _, err := sender.Send(context.Background(), nil, []byte{0x60, 0x00}, 100_000); err != nil {
+		t.Fatal(err)
+	}
+	journal, err := LoadJournal(paths.Work(PendingFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal.Pending) != 0 {
+		t.Errorf("journal holds a mined transaction: %+v", journal.Pending)
+	}
+}
@@ -0,0 +1,31 @@
+package chain
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// ReadOnlyEnv names the variable that forbids signing and sending
+// transactions, e.g. READ_ONLY=true in the env file of a mainnet-like
+// endpoint. run.go --read-only sets it for every stage it runs.
+const ReadOnlyEnv = "READ_ONLY"
+
+// ErrReadOnly is returned instead of signing a transaction in read-only
+// mode.
+var ErrReadOnly = errors.New("read-only mode: refusing to sign or send a transaction")
+
+// ReadOnly reports whether READ_ONLY is set to a true value.
+func ReadOnly() bool {
+	ro, _ := strconv.ParseBool(os.Getenv(ReadOnlyEnv))
+	return ro
+}
+
+// CheckWritable fails with ErrReadOnly in read-only mode. Everything that
+// signs or broadcasts a transaction calls it first.
+func CheckWritable() error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
+	return nil
+}
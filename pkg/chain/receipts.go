@@ -0,0 +1,147 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/skip"
+)
+
+// CheckReceipt validates the full shape of a mined transaction's receipt:
+// the type and effectiveGasPrice fields, contractAddress presence (set only
+// for creations, to the address derived from sender and nonce), logsBloom
+// against the logs, log positions, and cumulativeGasUsed across the block.
+func CheckReceipt(ctx context.Context, client *ethclient.Client, tx *types.Transaction, receipt *types.Receipt) []Check {
+	var checks []Check
+
+	// The typed receipt can't tell a missing field from a zero one, so the
+	// presence rules are checked on the raw JSON
+	var raw map[string]any
+	if err := client.Client().CallContext(ctx, &raw, "eth_getTransactionReceipt", tx.Hash()); err != nil || raw == nil {
+		checks = append(checks, Check{Name: "raw receipt", Note: fmt.Sprintf("unavailable: %v", err)})
+	} else {
+		checks = append(checks, rawReceiptChecks(tx, raw)...)
+	}
+
+	check := Check{Name: "logsBloom matches logs"}
+	bloom := types.CreateBloom(receipt)
+	check.Expected = hexutil.Encode(bloom[:])
+	check.Actual = hexutil.Encode(receipt.Bloom[:])
+	check.Passed = bloom == receipt.Bloom
+	checks = append(checks, check)
+
+	check = Check{Name: "logs reference the transaction", Passed: true}
+	for i, l := range receipt.Logs {
+		if l.TxHash != tx.Hash() || l.BlockHash != receipt.BlockHash || l.TxIndex != receipt.TransactionIndex {
+			check.Passed = false
+			check.Note = fmt.Sprintf("log %d: tx %s index %d block %s", i, l.TxHash.Hex(), l.TxIndex, l.BlockHash.Hex())
+			break
+		}
+	}
+	checks = append(checks, check)
+
+	return append(checks, cumulativeGasChecks(ctx, client, receipt)...)
+}
+
+func rawReceiptChecks(tx *types.Transaction, raw map[string]any) []Check {
+	var checks []Check
+
+	check := Check{Name: "receipt type = tx type", Expected: hexutil.EncodeUint64(uint64(tx.Type()))}
+	if t, ok := raw["type"].(string); ok {
+		check.Actual = t
+		check.Passed = t == check.Expected
+	} else {
+		check.Note = "type field missing"
+	}
+	checks = append(checks, check)
+
+	check = Check{Name: "effectiveGasPrice present"}
+	if p, ok := raw["effectiveGasPrice"].(string); ok {
+		check.Actual = p
+		check.Passed = true
+	} else {
+		check.Note = "effectiveGasPrice field missing"
+	}
+	checks = append(checks, check)
+
+	check = Check{Name: "contractAddress presence"}
+	addr, _ := raw["contractAddress"].(string)
+	if tx.To() != nil {
+		check.Expected = "null"
+		check.Actual = addr
+		if addr == "" {
+			check.Actual = "null"
+		}
+		check.Passed = addr == ""
+	} else {
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			check.Note = fmt.Sprintf("can't recover sender: %v", err)
+		} else {
+			want := CreateAddress(from, tx.Nonce())
+			check.Expected = want.Hex()
+			check.Actual = addr
+			check.Passed = addr != "" && common.HexToAddress(addr) == want
+		}
+	}
+	return append(checks, check)
+}
+
+// cumulativeGasChecks compares the receipt with the other receipts of its
+// block: cumulativeGasUsed must grow by exactly each receipt's gasUsed.
+func cumulativeGasChecks(ctx context.Context, client *ethclient.Client, receipt *types.Receipt) []Check {
+	receipts, err := BlockReceipts(ctx, client, receipt.BlockHash)
+	if err != nil {
+		return []Check{skippedCheck("cumulativeGasUsed monotonic", skip.Capability, err.Error())}
+	}
+
+	monotonic := Check{Name: "cumulativeGasUsed monotonic", Passed: true}
+	delta := Check{Name: "gasUsed = cumulativeGasUsed delta"}
+	var prev uint64
+	for i, r := range receipts {
+		if r.CumulativeGasUsed < prev || r.CumulativeGasUsed-prev != r.GasUsed {
+			monotonic.Passed = false
+			monotonic.Note = fmt.Sprintf("receipt %d: cumulative %d after %d with gasUsed %d", i, r.CumulativeGasUsed, prev, r.GasUsed)
+		}
+		if r.TxHash == receipt.TxHash {
+			delta.Expected = fmt.Sprint(r.CumulativeGasUsed - prev)
+			delta.Actual = fmt.Sprint(receipt.GasUsed)
+			delta.Passed = r.CumulativeGasUsed-prev == receipt.GasUsed && r.CumulativeGasUsed == receipt.CumulativeGasUsed
+		}
+		prev = r.CumulativeGasUsed
+	}
+	if delta.Actual == "" {
+		delta.Note = "transaction not among the block's receipts"
+	}
+	return []Check{monotonic, delta}
+}
+
+// BlockReceipts returns the receipts of a block in transaction order, using
+// eth_getBlockReceipts and falling back to one eth_getTransactionReceipt per
+// transaction on nodes without it.
+func BlockReceipts(ctx context.Context, client *ethclient.Client, blockHash common.Hash) ([]*types.Receipt, error) {
+	receipts, err := client.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(blockHash, false))
+	if err == nil {
+		return receipts, nil
+	}
+
+	block, err := client.BlockByHash(ctx, blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %s: %w", blockHash.Hex(), err)
+	}
+	receipts = make([]*types.Receipt, 0, len(block.Transactions()))
+	for _, tx := range block.Transactions() {
+		r, err := client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get receipt of %s: %w", tx.Hash().Hex(), err)
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, nil
+}
@@ -0,0 +1,209 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+// Role is what an account is used for. Each role can have its own key and
+// spend limit, so a leaked invocation key can't drain the funding account.
+type Role string
+
+// The roles, with their key variables, each holding a hex private key or a
+// vault://, awskms:// or gcpkms:// URI:
+//
+//	deploy  DEPLOYER_PRIVATE_KEY  contract deployments
+//	invoke  INVOKER_PRIVATE_KEY   state-changing calls, DEPLOYER_PRIVATE_KEY if unset
+//	fund    FUNDER_PRIVATE_KEY    topping up the other roles, never shared with them
+const (
+	RoleDeploy Role = "deploy"
+	RoleInvoke Role = "invoke"
+	RoleFund   Role = "fund"
+)
+
+// Roles lists every role.
+var Roles = []Role{RoleDeploy, RoleInvoke, RoleFund}
+
+// Sentinel errors of role configuration and spending.
+var (
+	ErrSpendLimit = errors.New("role spend limit exceeded")
+	ErrSharedKey  = errors.New("funding key shared with another role")
+)
+
+// KeyEnv names the variable holding the role's private key.
+func (r Role) KeyEnv() string {
+	switch r {
+	case RoleInvoke:
+		return "INVOKER_PRIVATE_KEY"
+	case RoleFund:
+		return "FUNDER_PRIVATE_KEY"
+	}
+	return "DEPLOYER_PRIVATE_KEY"
+}
+
+// AddressEnv names the variable holding the role's address, for hosts
+// that fund or prepare transactions without holding the key.
+func (r Role) AddressEnv() string {
+	return strings.TrimSuffix(r.KeyEnv(), "_PRIVATE_KEY") + "_ADDRESS"
+}
+
+// LimitEnv names the variable capping what the role may spend per run,
+// e.g. DEPLOY_SPEND_LIMIT=0.05eth.
+func (r Role) LimitEnv() string {
+	return strings.ToUpper(string(r)) + "_SPEND_LIMIT"
+}
+
+// SpendLimitError reports a transaction whose worst-case cost would take a
+// role past its limit.
+type SpendLimitError struct {
+	Role               Role
+	Limit, Spent, Cost *big.Int
+}
+
+func (e *SpendLimitError) Error() string {
+	return fmt.Sprintf("%s role spend limit of %s wei exceeded: spent %s, transaction costs up to %s (raise %s)",
+		e.Role, e.Limit, e.Spent, e.Cost, e.Role.LimitEnv())
+}
+
+func (e *SpendLimitError) Is(target error) bool { return target == ErrSpendLimit }
+
+// RoleSigner loads the role's key from the environment: a hex private key
+// or a secret backend URI (see package signer). The invoke role falls back
+// to the deployer key; the funding key must be set explicitly and belong to
+// a different account than the others, or it fails with ErrSharedKey.
+func RoleSigner(ctx context.Context, r Role) (signer.Signer, error) {
+	value := os.Getenv(r.KeyEnv())
+	if value == "" && r == RoleInvoke {
+		value = os.Getenv(RoleDeploy.KeyEnv())
+	}
+	if value == "" {
+		return nil, fmt.Errorf("%s not set", r.KeyEnv())
+	}
+	s, err := signer.Load(ctx, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.KeyEnv(), err)
+	}
+	if r == RoleFund {
+		for _, other := range []Role{RoleDeploy, RoleInvoke} {
+			otherValue := os.Getenv(other.KeyEnv())
+			if otherValue == "" {
+				continue
+			}
+			if otherValue == value {
+				return nil, fmt.Errorf("%w: %s is also %s", ErrSharedKey, r.KeyEnv(), other.KeyEnv())
+			}
+			if o, err := signer.Load(ctx, otherValue); err == nil && o.Address() == s.Address() {
+				return nil, fmt.Errorf("%w: %s is also %s", ErrSharedKey, r.KeyEnv(), other.KeyEnv())
+			}
+		}
+	}
+	return s, nil
+}
+
+// RoleAddress is the role's address: the one of its key, or its address
+// variable when the key isn't available.
+func RoleAddress(ctx context.Context, r Role) (common.Address, error) {
+	if s, err := RoleSigner(ctx, r); err == nil {
+		return s.Address(), nil
+	}
+	s := os.Getenv(r.AddressEnv())
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("neither %s nor a valid %s is set", r.KeyEnv(), r.AddressEnv())
+	}
+	return common.HexToAddress(s), nil
+}
+
+// RoleLimit is the role's spend limit, or nil if it has none.
+func RoleLimit(r Role) (*big.Int, error) {
+	s := os.Getenv(r.LimitEnv())
+	if s == "" {
+		return nil, nil
+	}
+	limit, err := ParseAmount(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.LimitEnv(), err)
+	}
+	return limit, nil
+}
+
+// spent is what each role has been charged in this process.
+var spent = struct {
+	sync.Mutex
+	m map[Role]*big.Int
+}{m: map[Role]*big.Int{}}
+
+// Charge records a transaction's worst-case cost against the role, failing
+// with a *SpendLimitError instead if that would exceed its limit. Refund
+// the difference once the actual cost is known.
+func Charge(r Role, cost *big.Int) error {
+	limit, err := RoleLimit(r)
+	if err != nil {
+		return err
+	}
+	spent.Lock()
+	defer spent.Unlock()
+	total := new(big.Int)
+	if s := spent.m[r]; s != nil {
+		total.Set(s)
+	}
+	if limit != nil && new(big.Int).Add(total, cost).Cmp(limit) > 0 {
+		return &SpendLimitError{Role: r, Limit: limit, Spent: total, Cost: new(big.Int).Set(cost)}
+	}
+	spent.m[r] = total.Add(total, cost)
+	return nil
+}
+
+// Refund returns part of an earlier charge.
+func Refund(r Role, amount *big.Int) {
+	spent.Lock()
+	defer spent.Unlock()
+	if s := spent.m[r]; s != nil {
+		s.Sub(s, amount)
+	}
+}
+
+// Spent is what the role has been charged in this process.
+func Spent(r Role) *big.Int {
+	spent.Lock()
+	defer spent.Unlock()
+	if s := spent.m[r]; s != nil {
+		return new(big.Int).Set(s)
+	}
+	return new(big.Int)
+}
+
+// ParseAmount parses a wei amount, or a decimal one with an eth or gwei
+// suffix, e.g. "0.05eth", "2000000gwei" or "1000000000".
+func ParseAmount(s string) (*big.Int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	unit := big.NewInt(params.Wei)
+	// gwei before wei and ether before eth, so the longer suffix wins
+	for _, u := range []struct {
+		suffix string
+		wei    int64
+	}{{"gwei", params.GWei}, {"wei", params.Wei}, {"ether", params.Ether}, {"eth", params.Ether}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), big.NewInt(u.wei)
+			break
+		}
+	}
+	amount, ok := new(big.Rat).SetString(s)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(unit))
+	if !amount.IsInt() {
+		return nil, fmt.Errorf("amount %q is not a whole number of wei", s)
+	}
+	return amount.Num(), nil
+}
@@ -0,0 +1,92 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestParseAmount(t *testing.T) {
+	for in, want := range map[string]*big.Int{
+		"1000":        big.NewInt(1000),
+		"0.05eth":     new(big.Int).Mul(big.NewInt(5), big.NewInt(params.Ether/100)),
+		"1 ether":     big.NewInt(params.Ether),
+		"2000000gwei": new(big.Int).Mul(big.NewInt(2_000_000), big.NewInt(params.GWei)),
+		"1.5gwei":     big.NewInt(1_500_000_000),
+		"7wei":        big.NewInt(7),
+	} {
+		got, err := ParseAmount(in)
+		if err != nil || got.Cmp(want) != 0 {
+			t.Errorf("ParseAmount(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "-1", "0.5", "1.5wei", "lots"} {
+		if _, err := ParseAmount(in); err == nil {
+			t.Errorf("ParseAmount(%q) succeeded", in)
+		}
+	}
+}
+
+func TestRoleSigner(t *testing.T) {
+	const deployer = "0x59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d"
+	const funder = "0x5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab365a"
+	t.Setenv(RoleDeploy.KeyEnv(), deployer)
+	t.Setenv(RoleInvoke.KeyEnv(), "")
+	t.Setenv(RoleFund.KeyEnv(), "")
+
+	ctx := context.Background()
+	deploy, err := RoleSigner(ctx, RoleDeploy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoke, err := RoleSigner(ctx, RoleInvoke); err != nil || invoke.Address() != deploy.Address() {
+		t.Errorf("invoke role: %v, %v, want the deployer %s", invoke, err, deploy.Address().Hex())
+	}
+	if _, err := RoleSigner(ctx, RoleFund); err == nil {
+		t.Error("fund role loaded without FUNDER_PRIVATE_KEY")
+	}
+
+	t.Setenv(RoleFund.KeyEnv(), deployer)
+	if _, err := RoleSigner(ctx, RoleFund); !errors.Is(err, ErrSharedKey) {
+		t.Errorf("funder sharing the deployer key: %v, want ErrSharedKey", err)
+	}
+	t.Setenv(RoleFund.KeyEnv(), deployer[2:])
+	if _, err := RoleSigner(ctx, RoleFund); !errors.Is(err, ErrSharedKey) {
+		t.Errorf("funder sharing the deployer key spelled differently: %v, want ErrSharedKey", err)
+	}
+	t.Setenv(RoleFund.KeyEnv(), funder)
+	if fund, err := RoleSigner(ctx, RoleFund); err != nil || fund.Address() == deploy.Address() {
+		t.Errorf("fund role: %v, %v", fund, err)
+	}
+}
+
+func TestSendSpendLimit(t *testing.T) {
+	sender, _, c := newSender(t)
+	sender.Role = RoleInvoke
+	spent.m = map[Role]*big.Int{}
+	// Sends of 100k gas at 1 gwei use half their gas, so only two fit
+	t.Setenv(RoleInvoke.LimitEnv(), "150000gwei")
+
+	to := common.Address{0xaa}
+	for i := 0; i < 2; i++ {
+		if _, _, err := sender.Send(context.Background(), &to, nil, 100_000); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	if want := big.NewInt(100_000 * params.GWei); Spent(RoleInvoke).Cmp(want) != 0 {
+		t.Errorf("spent %s, want %s", Spent(RoleInvoke), want)
+	}
+
+	_, _, err := sender.Send(context.Background(), &to, nil, 100_000)
+	var limitErr *SpendLimitError
+	if !errors.Is(err, ErrSpendLimit) || !errors.As(err, &limitErr) || limitErr.Role != RoleInvoke {
+		t.Fatalf("third send: %v, want a spend limit error", err)
+	}
+	if n := len(c.Sent()); n != 2 {
+		t.Errorf("%d transactions sent, want 2", n)
+	}
+}
@@ -0,0 +1,171 @@
+// Package chaos is a fault-injecting HTTP proxy placed between the harness
+// and the node. It adds latency, drops connections, returns 429s and
+// truncates JSON bodies at configurable rates so the retry layer and error
+// classification can be exercised without a misbehaving node.
+package chaos
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Fault names, also used as keys in Stats.
+const (
+	FaultNone        = "none"
+	FaultDrop        = "drop"
+	FaultRateLimit   = "rate_limit"
+	FaultMalformed   = "malformed"
+	FaultServerError = "server_error"
+)
+
+// Config sets the probability (0..1) of each fault per request. Latency is
+// added to every forwarded request, plus up to Jitter at random.
+type Config struct {
+	Latency         time.Duration
+	Jitter          time.Duration
+	DropRate        float64
+	RateLimitRate   float64
+	MalformedRate   float64
+	ServerErrorRate float64
+	Seed            int64
+}
+
+// Stats counts the faults injected so far.
+type Stats struct {
+	Requests int64            `json:"requests"`
+	Faults   map[string]int64 `json:"faults"`
+}
+
+// Proxy is a running fault-injection proxy.
+type Proxy struct {
+	URL string
+
+	cfg      Config
+	upstream *httputil.ReverseProxy
+	server   *http.Server
+	listener net.Listener
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	requests atomic.Int64
+	faults   sync.Map // fault name -> *atomic.Int64
+}
+
+// Start listens on addr (":0" for a random port) and forwards to target.
+func Start(addr, target string, cfg Config) (*Proxy, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream URL %q: %w", target, err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	p := &Proxy{
+		URL:      "http://" + ln.Addr().String(),
+		cfg:      cfg,
+		upstream: httputil.NewSingleHostReverseProxy(u),
+		listener: ln,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+	p.server = &http.Server{Handler: p}
+	go p.server.Serve(ln)
+	return p, nil
+}
+
+// Close stops the proxy.
+func (p *Proxy) Close() error {
+	return p.server.Close()
+}
+
+// Stats returns a snapshot of the injected faults.
+func (p *Proxy) Stats() Stats {
+	s := Stats{Requests: p.requests.Load(), Faults: map[string]int64{}}
+	p.faults.Range(func(k, v any) bool {
+		s.Faults[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	return s
+}
+
+func (p *Proxy) count(fault string) {
+	v, _ := p.faults.LoadOrStore(fault, new(atomic.Int64))
+	v.(*atomic.Int64).Add(1)
+}
+
+// pick draws the fault and latency for one request.
+func (p *Proxy) pick() (string, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delay := p.cfg.Latency
+	if p.cfg.Jitter > 0 {
+		delay += time.Duration(p.rng.Int63n(int64(p.cfg.Jitter)))
+	}
+
+	r := p.rng.Float64()
+	for _, f := range []struct {
+		name string
+		rate float64
+	}{
+		{FaultDrop, p.cfg.DropRate},
+		{FaultRateLimit, p.cfg.RateLimitRate},
+		{FaultMalformed, p.cfg.MalformedRate},
+		{FaultServerError, p.cfg.ServerErrorRate},
+	} {
+		if r < f.rate {
+			return f.name, delay
+		}
+		r -= f.rate
+	}
+	return FaultNone, delay
+}
+
+// ServeHTTP implements http.Handler.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.requests.Add(1)
+	fault, delay := p.pick()
+	p.count(fault)
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch fault {
+	case FaultDrop:
+		// Close the connection without writing a response
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		panic(http.ErrAbortHandler)
+	case FaultRateLimit:
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "rate limited by chaos proxy", http.StatusTooManyRequests)
+	case FaultServerError:
+		http.Error(w, "injected server error", http.StatusBadGateway)
+	case FaultMalformed:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x`))
+	default:
+		p.upstream.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,140 @@
+// Package composed calls the ComposedHashes contract
+// (contracts/ComposedHashes.sol), whose view functions feed the SHA-256
+// precompile's output into keccak256 and ABI encoding, and computes what
+// each composition must return. zk executors account for sha256 and
+// keccak256 with separate counters, so compositions catch bugs a lone
+// precompile call doesn't.
+package composed
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// abiJSON is the ABI of contracts/ComposedHashes.sol.
+const abiJSON = `[
+{"type":"function","name":"sha256ThenKeccak","stateMutability":"view",
+"inputs":[{"name":"input","type":"bytes"}],"outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"keccakThenSha256","stateMutability":"view",
+"inputs":[{"name":"input","type":"bytes"}],"outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"pipeline","stateMutability":"view",
+"inputs":[{"name":"input","type":"bytes"}],"outputs":[{"name":"","type":"bytes"}]},
+{"type":"function","name":"alternate","stateMutability":"view",
+"inputs":[{"name":"input","type":"bytes"},{"name":"rounds","type":"uint256"}],"outputs":[{"name":"h","type":"bytes32"}]}]`
+
+// ABI is the parsed ComposedHashes ABI.
+var ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Composition is one of the contract's functions. Rounds only applies to
+// alternate.
+type Composition struct {
+	Method string `json:"method"`
+	Rounds uint64 `json:"rounds,omitempty"`
+}
+
+// Compositions lists every function, alternate with an odd and an even
+// number of rounds so it ends on either hash.
+var Compositions = []Composition{
+	{Method: "sha256ThenKeccak"},
+	{Method: "keccakThenSha256"},
+	{Method: "pipeline"},
+	{Method: "alternate", Rounds: 7},
+	{Method: "alternate", Rounds: 64},
+}
+
+func (c Composition) String() string {
+	if c.Method == "alternate" {
+		return fmt.Sprintf("alternate(%d)", c.Rounds)
+	}
+	return c.Method
+}
+
+// Pack encodes a call of c with input.
+func (c Composition) Pack(input []byte) ([]byte, error) {
+	args := []any{input}
+	if c.Method == "alternate" {
+		args = append(args, new(big.Int).SetUint64(c.Rounds))
+	}
+	data, err := ABI.Pack(c.Method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", c, err)
+	}
+	return data, nil
+}
+
+// Unpack decodes the answer of c: a hash, or the encoded bytes of
+// pipeline.
+func (c Composition) Unpack(out []byte) ([]byte, error) {
+	values, err := ABI.Unpack(c.Method, out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %w", c, err)
+	}
+	switch v := values[0].(type) {
+	case [32]byte:
+		return v[:], nil
+	case []byte:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s: unexpected return type %T", c, values[0])
+}
+
+// pipelineArgs is how pipeline encodes its answer.
+var pipelineArgs = func() abi.Arguments {
+	b32, _ := abi.NewType("bytes32", "", nil)
+	u256, _ := abi.NewType("uint256", "", nil)
+	return abi.Arguments{{Type: b32}, {Type: b32}, {Type: u256}}
+}()
+
+// Expected computes locally what c returns for input.
+func (c Composition) Expected(input []byte) ([]byte, error) {
+	digest := sha256.Sum256(input)
+	switch c.Method {
+	case "sha256ThenKeccak":
+		return crypto.Keccak256(digest[:]), nil
+	case "keccakThenSha256":
+		h := sha256.Sum256(crypto.Keccak256(input))
+		return h[:], nil
+	case "pipeline":
+		return pipelineArgs.Pack(digest, [32]byte(crypto.Keccak256(digest[:])), big.NewInt(int64(len(input))))
+	case "alternate":
+		h := digest[:]
+		for i := uint64(1); i <= c.Rounds; i++ {
+			if i%2 == 1 {
+				h = crypto.Keccak256(h)
+			} else {
+				s := sha256.Sum256(h)
+				h = s[:]
+			}
+		}
+		return h, nil
+	}
+	return nil, fmt.Errorf("unknown composition %s", c.Method)
+}
+
+// Call has the contract at address run c over input, in an eth_call.
+func Call(ctx context.Context, client *ethclient.Client, address common.Address, c Composition, input []byte) ([]byte, error) {
+	data, err := c.Pack(input)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s call failed: %w", c, err)
+	}
+	return c.Unpack(out)
+}
@@ -0,0 +1,100 @@
+package composed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestExpected(t *testing.T) {
+	input := []byte("hello world")
+	digest := sha256.Sum256(input)
+
+	pipeline, err := Composition{Method: "pipeline"}.Expected(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := pipelineArgs.Unpack(pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0].([32]byte) != digest || !bytes.Equal(crypto.Keccak256(digest[:]), common.Hash(values[1].([32]byte)).Bytes()) || values[2].(*big.Int).Int64() != 11 {
+		t.Errorf("pipeline %x", pipeline)
+	}
+
+	// Two rounds: keccak256 of the digest, then sha256 of that
+	twice, err := Composition{Method: "alternate", Rounds: 2}.Expected(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := sha256.Sum256(crypto.Keccak256(digest[:])); !bytes.Equal(twice, want[:]) {
+		t.Errorf("alternate(2) %x, want %x", twice, want)
+	}
+	if none, _ := (Composition{Method: "alternate"}).Expected(input); !bytes.Equal(none, digest[:]) {
+		t.Errorf("alternate(0) %x", none)
+	}
+}
+
+func TestCall(t *testing.T) {
+	contract := common.Address{0xc0}
+	s := mockrpc.New()
+	defer s.Close()
+	// The contract composes correctly, except pipeline, which drops the
+	// input length
+	s.Handle("eth_call", func(call mockrpc.Call) (any, error) {
+		var msg struct {
+			Input hexutil.Bytes
+		}
+		if err := call.Param(0, &msg); err != nil {
+			return nil, err
+		}
+		method, err := ABI.MethodById(msg.Input[:4])
+		if err != nil {
+			return nil, err
+		}
+		args, err := method.Inputs.Unpack(msg.Input[4:])
+		if err != nil {
+			return nil, err
+		}
+		c := Composition{Method: method.Name}
+		if len(args) > 1 {
+			c.Rounds = args[1].(*big.Int).Uint64()
+		}
+		want, err := c.Expected(args[0].([]byte))
+		if err != nil {
+			return nil, err
+		}
+		if c.Method == "pipeline" {
+			out, _ := method.Outputs.Pack(want[:64])
+			return hexutil.Bytes(out), nil
+		}
+		out, _ := method.Outputs.Pack([32]byte(want))
+		return hexutil.Bytes(out), nil
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	input := bytes.Repeat([]byte{0xab}, 33)
+	for _, c := range Compositions {
+		got, err := Call(context.Background(), client, contract, c, input)
+		if err != nil {
+			t.Fatalf("%s: %v", c, err)
+		}
+		want, _ := c.Expected(input)
+		if match := bytes.Equal(got, want); match != (c.Method != "pipeline") {
+			t.Errorf("%s: got %x, want %x", c, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Args are the solc flags selecting c's settings.
+func (c Compiler) Args() []string {
+	var args []string
+	if c.Optimize {
+		args = append(args, "--optimize")
+		if c.Runs > 0 {
+			args = append(args, "--optimize-runs", strconv.Itoa(c.Runs))
+		}
+	}
+	if c.ViaIR {
+		args = append(args, "--via-ir")
+	}
+	if c.EVMVersion != "" {
+		args = append(args, "--evm-version", c.EVMVersion)
+	}
+	return args
+}
+
+// Label names c's optimizer settings, e.g. "runs=200 viaIR".
+func (c Compiler) Label() string {
+	label := "unoptimized"
+	if c.Optimize {
+		label = "runs=" + strconv.Itoa(c.Runs)
+	}
+	if c.ViaIR {
+		label += " viaIR"
+	}
+	return label
+}
+
+// OptimizerMatrix is every combination of the optimizer runs and via-IR
+// settings, ordered by runs and legacy pipeline first. Runs of 0 or less
+// mean unoptimized.
+func OptimizerMatrix(runs []int, viaIR []bool) []Compiler {
+	var out []Compiler
+	for _, r := range runs {
+		for _, ir := range viaIR {
+			out = append(out, Compiler{Optimize: r > 0, Runs: max(r, 0), ViaIR: ir})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Runs < out[j].Runs })
+	return out
+}
+
+// Compiled is one contract out of solc.
+type Compiled struct {
+	Bin []byte
+	ABI json.RawMessage
+	// Solc is the compiler version from the bytecode metadata.
+	Solc string
+}
+
+// Compile compiles contract from source with solc (the binary at solc, or
+// on PATH when empty) and c's settings.
+func Compile(ctx context.Context, solc, source, contract string, c Compiler) (Compiled, error) {
+	if solc == "" {
+		solc = "solc"
+	}
+	args := append([]string{"--combined-json", "bin,abi"}, c.Args()...)
+	cmd := exec.CommandContext(ctx, solc, append(args, source)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return Compiled{}, fmt.Errorf("solc %s failed: %v: %s", c.Label(), err, strings.TrimSpace(stderr.String()))
+	}
+	return ParseCombined(out, contract)
+}
+
+// ParseCombined extracts contract from solc's --combined-json bin,abi
+// output. The ABI is a JSON string before solc 0.8.10 and an array after.
+func ParseCombined(out []byte, contract string) (Compiled, error) {
+	var combined struct {
+		Contracts map[string]struct {
+			Bin string          `json:"bin"`
+			ABI json.RawMessage `json:"abi"`
+		} `json:"contracts"`
+	}
+	if err := json.Unmarshal(out, &combined); err != nil {
+		return Compiled{}, fmt.Errorf("failed to parse solc output: %w", err)
+	}
+	var names []string
+	for key, c := range combined.Contracts {
+		names = append(names, key)
+		// Keys are <source path>:<contract name>
+		if key[strings.LastIndex(key, ":")+1:] != contract {
+			continue
+		}
+		if c.Bin == "" {
+			return Compiled{}, fmt.Errorf("%s is abstract or an interface: solc produced no bytecode", contract)
+		}
+		abi := c.ABI
+		var encoded string
+		if json.Unmarshal(abi, &encoded) == nil {
+			abi = json.RawMessage(encoded)
+		}
+		return Compiled{Bin: common.FromHex(c.Bin), ABI: abi, Solc: SolcVersion(c.Bin)}, nil
+	}
+	sort.Strings(names)
+	return Compiled{}, fmt.Errorf("solc output has no contract %s (has %s)", contract, strings.Join(names, ", "))
+}
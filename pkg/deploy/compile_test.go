@@ -0,0 +1,41 @@
+package deploy
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOptimizerMatrix(t *testing.T) {
+	var labels []string
+	for _, c := range OptimizerMatrix([]int{10000, 1, 0}, []bool{false, true}) {
+		labels = append(labels, c.Label())
+	}
+	want := []string{"unoptimized", "unoptimized viaIR", "runs=1", "runs=1 viaIR", "runs=10000", "runs=10000 viaIR"}
+	if !slices.Equal(labels, want) {
+		t.Errorf("labels %v, want %v", labels, want)
+	}
+	args := strings.Join(Compiler{Optimize: true, Runs: 200, ViaIR: true}.Args(), " ")
+	if args != "--optimize --optimize-runs 200 --via-ir" {
+		t.Errorf("args %q", args)
+	}
+}
+
+func TestParseCombined(t *testing.T) {
+	const bin = "6080604052a264697066735822122000000000000000000000000000000000000000000000000000000000000000000064736f6c634300081e0033"
+	for name, out := range map[string]string{
+		"array abi":  `{"contracts":{"contracts/W.sol:Lib":{"bin":"00","abi":[]},"contracts/W.sol:W":{"bin":"` + bin + `","abi":[{"type":"fallback"}]}},"version":"0.8.30"}`,
+		"string abi": `{"contracts":{"contracts/W.sol:W":{"bin":"` + bin + `","abi":"[{\"type\":\"fallback\"}]"}}}`,
+	} {
+		c, err := ParseCombined([]byte(out), "W")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if c.Solc != "0.8.30" || len(c.Bin) != len(bin)/2 || string(c.ABI) != `[{"type":"fallback"}]` {
+			t.Errorf("%s: %+v", name, c)
+		}
+	}
+	if _, err := ParseCombined([]byte(`{"contracts":{"a.sol:A":{"bin":"00"}}}`), "W"); err == nil || !strings.Contains(err.Error(), "a.sol:A") {
+		t.Errorf("missing contract error %v", err)
+	}
+}
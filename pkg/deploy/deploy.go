@@ -0,0 +1,248 @@
+package deploy
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"cdk-erigon-precompile/pkg/chain"
+)
+
+// DefaultGasLimit is used for manifest entries without an explicit gasLimit.
+const DefaultGasLimit = 2_000_000
+
+// Deployed records one contract deployed from a manifest.
+type Deployed struct {
+	Name            string `json:"name"`
+	Address         string `json:"address"`
+	TransactionHash string `json:"transactionHash"`
+	BlockNumber     uint64 `json:"blockNumber"`
+	GasUsed         uint64 `json:"gasUsed"`
+	Status          uint64 `json:"status"`
+	CodeSize        int    `json:"codeSize"`
+}
+
+// Artifact is a compiled contract. The bytecode is kept as hex because it
+// may still contain library placeholders, which are not valid hex.
+type Artifact struct {
+	BytecodeHex string
+	ABI         abi.ABI
+}
+
+// Bytecode links libraries into the creation code and decodes it.
+func (a *Artifact) Bytecode(libraries map[string]common.Address) ([]byte, error) {
+	linked, err := Link(a.BytecodeHex, libraries)
+	if err != nil {
+		return nil, err
+	}
+	code, err := hex.DecodeString(linked)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytecode hex: %w", err)
+	}
+	return code, nil
+}
+
+// LoadArtifact reads <path>.bin and <path>.abi.
+func LoadArtifact(path string) (*Artifact, error) {
+	bin, err := os.ReadFile(path + ".bin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bytecode: %w", err)
+	}
+	abiBytes, err := os.ReadFile(path + ".abi")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ABI: %w", err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(abiBytes)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI %s.abi: %w", path, err)
+	}
+	return &Artifact{BytecodeHex: strings.TrimSpace(string(bin)), ABI: parsed}, nil
+}
+
+// DeployAll deploys every manifest contract in dependency order, feeding the
+// addresses of earlier deployments into constructor arguments of later ones.
+// Deployments completed before a failure are returned alongside the error.
+func DeployAll(ctx context.Context, sender *chain.Sender, m *Manifest, progress func(Contract)) ([]Deployed, error) {
+	ordered, err := m.Order()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := map[string]common.Address{}
+	var deployed []Deployed
+	for _, c := range ordered {
+		if progress != nil {
+			progress(c)
+		}
+
+		if err := VerifyArtifact(c.Artifact); err != nil {
+			return deployed, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		artifact, err := LoadArtifact(c.Artifact)
+		if err != nil {
+			return deployed, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		data, err := creationData(artifact, c, addresses)
+		if err != nil {
+			return deployed, fmt.Errorf("%s: %w", c.Name, err)
+		}
+
+		gas := c.GasLimit
+		if gas == 0 {
+			gas = DefaultGasLimit
+		}
+		tx, receipt, err := sender.Send(chain.WithContract(ctx, c.Name), nil, data, gas)
+		if err != nil {
+			return deployed, fmt.Errorf("%s: %w", c.Name, err)
+		}
+
+		d := Deployed{
+			Name:            c.Name,
+			Address:         receipt.ContractAddress.Hex(),
+			TransactionHash: tx.Hash().Hex(),
+			BlockNumber:     receipt.BlockNumber.Uint64(),
+			GasUsed:         receipt.GasUsed,
+			Status:          receipt.Status,
+		}
+		if receipt.Status != 1 {
+			deployed = append(deployed, d)
+			return deployed, fmt.Errorf("%s: deployment %w in block %d", c.Name, chain.ErrReverted, d.BlockNumber)
+		}
+
+		code, err := sender.Client.CodeAt(ctx, receipt.ContractAddress, nil)
+		if err != nil {
+			return deployed, fmt.Errorf("%s: failed to get contract code: %w", c.Name, err)
+		}
+		d.CodeSize = len(code)
+		deployed = append(deployed, d)
+		if d.CodeSize == 0 {
+			return deployed, fmt.Errorf("%s: %w", c.Name, &chain.NoCodeError{Address: receipt.ContractAddress})
+		}
+		addresses[c.Name] = receipt.ContractAddress
+	}
+	return deployed, nil
+}
+
+// creationData links libraries into the bytecode and appends the ABI-encoded
+// constructor arguments.
+func creationData(artifact *Artifact, c Contract, addresses map[string]common.Address) ([]byte, error) {
+	libraries := make(map[string]common.Address, len(c.Libraries))
+	for fqn, target := range c.Libraries {
+		addr, ok := addresses[target]
+		if !ok {
+			return nil, fmt.Errorf("library %s references %q which is not deployed", fqn, target)
+		}
+		libraries[fqn] = addr
+	}
+	bytecode, err := artifact.Bytecode(libraries)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := artifact.ABI.Constructor.Inputs
+	if len(inputs) != len(c.ConstructorArgs) {
+		return nil, fmt.Errorf("constructor takes %d arguments, manifest gives %d", len(inputs), len(c.ConstructorArgs))
+	}
+
+	args := make([]any, len(inputs))
+	for i, a := range c.ConstructorArgs {
+		if a.Ref != "" {
+			addr, ok := addresses[a.Ref]
+			if !ok {
+				return nil, fmt.Errorf("argument %d references %q which is not deployed", i, a.Ref)
+			}
+			args[i] = addr
+			continue
+		}
+		v, err := convertArg(inputs[i].Type, a.Value)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s): %w", i, inputs[i].Name, err)
+		}
+		args[i] = v
+	}
+
+	packed, err := artifact.ABI.Pack("", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack constructor arguments: %w", err)
+	}
+	return append(bytecode, packed...), nil
+}
+
+// convertArg turns a JSON literal into the Go value the ABI packer expects
+// for t. Integers may be given as JSON numbers or decimal/0x strings.
+func convertArg(t abi.Type, v any) (any, error) {
+	switch t.T {
+	case abi.AddressTy:
+		s, ok := v.(string)
+		if !ok || !common.IsHexAddress(s) {
+			return nil, fmt.Errorf("want address string, got %v", v)
+		}
+		return common.HexToAddress(s), nil
+	case abi.BoolTy:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("want bool, got %v", v)
+		}
+		return b, nil
+	case abi.StringTy:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("want string, got %v", v)
+		}
+		return s, nil
+	case abi.BytesTy:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("want 0x hex string, got %v", v)
+		}
+		return common.FromHex(s), nil
+	case abi.FixedBytesTy:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("want 0x hex string, got %v", v)
+		}
+		b := common.FromHex(s)
+		if len(b) != t.Size {
+			return nil, fmt.Errorf("want %d bytes, got %d", t.Size, len(b))
+		}
+		return abi.ReadFixedBytes(t, common.RightPadBytes(b, 32))
+	case abi.UintTy, abi.IntTy:
+		n, err := toBig(v)
+		if err != nil {
+			return nil, err
+		}
+		if t.Size > 64 {
+			return n, nil
+		}
+		// Small integers are packed from their exact Go types (uint8, int32...)
+		if t.T == abi.IntTy {
+			return reflect.ValueOf(n.Int64()).Convert(t.GetType()).Interface(), nil
+		}
+		return reflect.ValueOf(n.Uint64()).Convert(t.GetType()).Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported constructor argument type %s", t)
+}
+
+func toBig(v any) (*big.Int, error) {
+	switch x := v.(type) {
+	case float64:
+		if x != float64(int64(x)) {
+			return nil, fmt.Errorf("want integer, got %v", x)
+		}
+		return big.NewInt(int64(x)), nil
+	case string:
+		n, ok := new(big.Int).SetString(x, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", x)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("want integer, got %v", v)
+}
@@ -0,0 +1,175 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/output"
+)
+
+// factoryABI declares the interface of contracts/Sha256WrapperFactory.sol,
+// so the factory can be called before its ABI is compiled.
+const factoryABI = `[
+	{"type":"function","name":"deploy","stateMutability":"nonpayable","inputs":[{"name":"initCode","type":"bytes"}],"outputs":[{"name":"deployed","type":"address"}]},
+	{"type":"function","name":"deploy2","stateMutability":"nonpayable","inputs":[{"name":"initCode","type":"bytes"},{"name":"salt","type":"bytes32"}],"outputs":[{"name":"deployed","type":"address"}]},
+	{"type":"event","name":"Deployed","anonymous":false,"inputs":[{"name":"deployed","type":"address","indexed":true}]}
+]`
+
+// FactoryGasLimit covers the factory call and the wrapper's creation.
+const FactoryGasLimit = 2_000_000
+
+// FactoryDeployment records a contract created by the factory's CREATE or
+// CREATE2.
+type FactoryDeployment struct {
+	Factory string `json:"factory"`
+	Opcode  string `json:"opcode"`
+	// Salt is the CREATE2 salt; empty for CREATE.
+	Salt string `json:"salt,omitempty"`
+	// FactoryNonce is the factory's nonce before the call, from which a
+	// CREATE address derives.
+	FactoryNonce    uint64        `json:"factoryNonce"`
+	Address         string        `json:"address"`
+	TransactionHash string        `json:"transactionHash"`
+	BlockNumber     uint64        `json:"blockNumber"`
+	GasUsed         uint64        `json:"gasUsed"`
+	Status          uint64        `json:"status"`
+	CodeSize        int           `json:"codeSize"`
+	Checks          []chain.Check `json:"checks,omitempty"`
+}
+
+// DeployViaFactory has the factory contract create initCode, with CREATE,
+// or with CREATE2 when salt is set. It checks the created contract sits at
+// the address derived from the factory and its nonce (CREATE) or from the
+// factory, salt and init code (CREATE2). It also checks that the factory's
+// nonce advanced by one and that the new contract starts at nonce 1 with
+// code. The factory must not be used by anyone else meanwhile. A
+// successful creation is recorded in the ledger with the factory as
+// deployer, under the contract name ctx carries.
+func DeployViaFactory(ctx context.Context, sender *chain.Sender, factory common.Address, initCode []byte, salt *common.Hash, gas uint64) (*FactoryDeployment, error) {
+	parsed, err := abi.JSON(strings.NewReader(factoryABI))
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if salt == nil {
+		data, err = parsed.Pack("deploy", initCode)
+	} else {
+		data, err = parsed.Pack("deploy2", initCode, *salt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack factory call: %w", err)
+	}
+	nonce, err := sender.Client.NonceAt(ctx, factory, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get factory nonce: %w", err)
+	}
+
+	tx, receipt, err := sender.Send(ctx, &factory, data, gas)
+	if err != nil {
+		return nil, fmt.Errorf("factory call failed: %w", err)
+	}
+	d := &FactoryDeployment{
+		Factory:         factory.Hex(),
+		Opcode:          "CREATE",
+		FactoryNonce:    nonce,
+		TransactionHash: tx.Hash().Hex(),
+		BlockNumber:     receipt.BlockNumber.Uint64(),
+		GasUsed:         receipt.GasUsed,
+		Status:          receipt.Status,
+	}
+	derived := chain.CreateAddress(factory, nonce)
+	formula := "created address is derived from the factory and its nonce"
+	if salt != nil {
+		d.Opcode, d.Salt = "CREATE2", salt.Hex()
+		derived = chain.Create2Address(factory, *salt, initCode)
+		formula = "created address is derived from the factory, salt and init code"
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return d, fmt.Errorf("factory call %w in block %d", chain.ErrReverted, d.BlockNumber)
+	}
+	created, err := FactoryCreated(receipt, factory)
+	if err != nil {
+		return d, err
+	}
+	d.Address = created.Hex()
+
+	d.Checks = append(d.Checks, chain.Check{
+		Name:     formula,
+		Expected: derived.Hex(),
+		Actual:   created.Hex(),
+		Passed:   created == derived,
+	})
+	// CREATE2 bumps the creator's nonce too, and by EIP-161 contracts
+	// start at nonce 1 whoever creates them
+	d.Checks = append(d.Checks, nonceCheck(ctx, sender, "factory nonce advanced by one", factory, receipt, nonce+1))
+	d.Checks = append(d.Checks, nonceCheck(ctx, sender, "created contract has nonce 1", created, receipt, 1))
+
+	code, err := sender.Client.CodeAt(ctx, created, receipt.BlockNumber)
+	if err != nil {
+		return d, fmt.Errorf("failed to get created contract code: %w", err)
+	}
+	d.CodeSize = len(code)
+	d.Checks = append(d.Checks, chain.Check{
+		Name:     "created contract has code",
+		Expected: "non-empty code",
+		Actual:   fmt.Sprintf("%d bytes", len(code)),
+		Passed:   len(code) > 0,
+	})
+
+	err = chain.RecordDeployment(chain.Deployment{
+		Address:  created.Hex(),
+		Contract: chain.ContractName(ctx),
+		ChainID:  chainID(sender),
+		Deployer: factory.Hex(),
+		Role:     sender.Role,
+		Tx:       d.TransactionHash,
+		Block:    d.BlockNumber,
+	})
+	if err != nil {
+		output.Logf(output.ModuleDeploy, output.Normal, "deployment of %s not recorded in %s: %v", created.Hex(), chain.DeploymentsFile, err)
+	}
+	return d, nil
+}
+
+// FactoryCreated returns the address the factory's Deployed event in
+// receipt names. Events of other contracts are ignored.
+func FactoryCreated(receipt *types.Receipt, factory common.Address) (common.Address, error) {
+	parsed, err := abi.JSON(strings.NewReader(factoryABI))
+	if err != nil {
+		return common.Address{}, err
+	}
+	event := parsed.Events["Deployed"].ID
+	for _, l := range receipt.Logs {
+		if l.Address == factory && len(l.Topics) == 2 && l.Topics[0] == event {
+			return common.BytesToAddress(l.Topics[1].Bytes()), nil
+		}
+	}
+	return common.Address{}, fmt.Errorf("no Deployed event from factory %s in transaction %s", factory.Hex(), receipt.TxHash.Hex())
+}
+
+// nonceCheck compares the nonce of addr as of the receipt's block with
+// want.
+func nonceCheck(ctx context.Context, sender *chain.Sender, name string, addr common.Address, receipt *types.Receipt, want uint64) chain.Check {
+	c := chain.Check{Name: name, Expected: fmt.Sprint(want)}
+	nonce, err := sender.Client.NonceAt(ctx, addr, receipt.BlockNumber)
+	if err != nil {
+		c.Note = fmt.Sprintf("eth_getTransactionCount failed: %v", err)
+		return c
+	}
+	c.Actual = fmt.Sprint(nonce)
+	c.Passed = nonce == want
+	return c
+}
+
+func chainID(sender *chain.Sender) string {
+	if sender.ChainID == nil {
+		return ""
+	}
+	return sender.ChainID.String()
+}
@@ -0,0 +1,180 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+func TestFactoryCreated(t *testing.T) {
+	factory := common.HexToAddress("0xfac")
+	created := common.HexToAddress("0xc0ffee")
+	event := crypto.Keccak256Hash([]byte("Deployed(address)"))
+	topics := []common.Hash{event, common.BytesToHash(created.Bytes())}
+
+	receipt := &types.Receipt{Logs: []*types.Log{
+		// The same event from another contract isn't the factory's
+		{Address: common.HexToAddress("0xbad"), Topics: []common.Hash{event, common.BytesToHash([]byte{1})}},
+		{Address: factory, Topics: topics},
+	}}
+	if got, err := FactoryCreated(receipt, factory); err != nil || got != created {
+		t.Errorf("created %s, %v; want %s", got.Hex(), err, created.Hex())
+	}
+
+	receipt.Logs = receipt.Logs[:1]
+	if _, err := FactoryCreated(receipt, factory); err == nil {
+		t.Error("found a creation without the factory's event")
+	}
+}
+
+func TestDeployViaFactory(t *testing.T) {
+	factory := common.HexToAddress("0xfac")
+	initCode := []byte{0x60, 0x00}
+	runtime := []byte{0x00}
+	salt := common.HexToHash("0x5a17")
+	elsewhere := common.HexToAddress("0xe15e")
+
+	for _, tc := range []struct {
+		name string
+		salt *common.Hash
+		// place overrides where the factory creates the contract
+		place        *common.Address
+		frozenNonce  bool   // the factory's nonce doesn't advance
+		createdNonce uint64 // nonce the created contract starts at
+		noCode       bool
+		noEvent      bool
+		revert       bool
+		// wantErr is the error the deployment fails with, matched with
+		// errors.Is, or by message for an error without a sentinel
+		wantErr    error
+		wantFailed []string
+	}{
+		{name: "CREATE", createdNonce: 1},
+		{name: "CREATE2", salt: &salt, createdNonce: 1},
+		{name: "address not derived", place: &elsewhere, createdNonce: 1,
+			wantFailed: []string{"created address is derived from the factory and its nonce"}},
+		{name: "CREATE2 address not derived", salt: &salt, place: &elsewhere, createdNonce: 1,
+			wantFailed: []string{"created address is derived from the factory, salt and init code"}},
+		{name: "factory nonce unchanged", frozenNonce: true, createdNonce: 1,
+			wantFailed: []string{"factory nonce advanced by one"}},
+		{name: "created at nonce 0", createdNonce: 0,
+			wantFailed: []string{"created contract has nonce 1"}},
+		{name: "no code", noCode: true, createdNonce: 1,
+			wantFailed: []string{"created contract has code"}},
+		{name: "no event", noEvent: true, wantErr: errors.New("no Deployed event")},
+		{name: "reverted", revert: true, wantErr: chain.ErrReverted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sender, c := newFactorySender(t)
+			const nonce = 7
+			c.SetNonce(factory, nonce)
+			c.Fail = func(*types.Transaction) bool { return tc.revert }
+
+			var created common.Address
+			c.Execute = func(_ common.Address, tx *types.Transaction, r *types.Receipt) {
+				if r.Status != types.ReceiptStatusSuccessful || *tx.To() != factory {
+					return
+				}
+				created = chain.CreateAddress(factory, nonce)
+				if tc.salt != nil {
+					created = chain.Create2Address(factory, *tc.salt, initCode)
+				}
+				if tc.place != nil {
+					created = *tc.place
+				}
+				if !tc.frozenNonce {
+					c.SetNonce(factory, nonce+1)
+				}
+				c.SetNonce(created, tc.createdNonce)
+				if !tc.noCode {
+					c.SetCode(created, runtime)
+				}
+				if !tc.noEvent {
+					r.Logs = append(r.Logs, &types.Log{
+						Address: factory,
+						Topics:  []common.Hash{crypto.Keccak256Hash([]byte("Deployed(address)")), common.BytesToHash(created.Bytes())},
+					})
+				}
+			}
+
+			ctx := chain.WithContract(context.Background(), "Sha256Wrapper")
+			d, err := DeployViaFactory(ctx, sender, factory, initCode, tc.salt, FactoryGasLimit)
+			switch {
+			case tc.wantErr != nil:
+				if err == nil || !errors.Is(err, tc.wantErr) && !strings.Contains(err.Error(), tc.wantErr.Error()) {
+					t.Fatalf("err %v, want %v", err, tc.wantErr)
+				}
+				if d == nil || d.Address != "" {
+					t.Errorf("deployment %+v, want the call recorded without an address", d)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			wantOpcode, wantSalt := "CREATE", ""
+			if tc.salt != nil {
+				wantOpcode, wantSalt = "CREATE2", tc.salt.Hex()
+			}
+			if d.Opcode != wantOpcode || d.Salt != wantSalt || d.FactoryNonce != nonce || d.Address != created.Hex() {
+				t.Errorf("deployment %+v", d)
+			}
+			var failed []string
+			for _, check := range d.Checks {
+				if !check.Passed {
+					failed = append(failed, check.Name)
+				}
+			}
+			if len(d.Checks) != 4 || !slices.Equal(failed, tc.wantFailed) {
+				t.Errorf("%d checks, failed %q, want %q", len(d.Checks), failed, tc.wantFailed)
+			}
+
+			ledger, err := chain.LoadLedger(paths.Work(chain.DeploymentsFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ledger.Deployments) != 1 {
+				t.Fatalf("%d deployments recorded, want 1", len(ledger.Deployments))
+			}
+			if r := ledger.Deployments[0]; r.Address != created.Hex() || r.Deployer != factory.Hex() || r.Contract != "Sha256Wrapper" {
+				t.Errorf("recorded %+v", r)
+			}
+		})
+	}
+}
+
+func newFactorySender(t *testing.T) (*chain.Sender, *mockrpc.Chain) {
+	t.Helper()
+	chain.PollInterval = 5 * time.Millisecond
+	t.Setenv("WORK_DIR", t.TempDir())
+
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	c := mockrpc.NewChain(s, big.NewInt(10101))
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := signer.NewLocal(key)
+	return &chain.Sender{Client: client, Signer: local, From: local.Address(), ChainID: c.ChainID, Role: chain.RoleDeploy}, c
+}
@@ -0,0 +1,64 @@
+package deploy
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// solc >=0.5 marks library call sites with __$<34 hex chars>$__, the hex being
+// the first 17 bytes of keccak256 of the library's fully qualified name
+// ("contracts/Lib.sol:Lib"). Older compilers used the name itself, padded
+// with underscores to 40 characters. Both forms are 40 characters starting
+// and ending with "__", which can never occur in real hex.
+var placeholderPattern = regexp.MustCompile(`__.{36}__`)
+
+// Placeholder returns the solc >=0.5 link placeholder for a library.
+func Placeholder(fullyQualifiedName string) string {
+	hash := crypto.Keccak256([]byte(fullyQualifiedName))
+	return "__$" + hex.EncodeToString(hash)[:34] + "$__"
+}
+
+// legacyPlaceholder returns the pre-0.5 placeholder for a library.
+func legacyPlaceholder(fullyQualifiedName string) string {
+	name := fullyQualifiedName
+	if len(name) > 36 {
+		name = name[:36]
+	}
+	return "__" + name + strings.Repeat("_", 38-len(name))
+}
+
+// Link substitutes library addresses into hex bytecode. libraries maps each
+// fully qualified library name to its deployed address. The result still
+// needs hex decoding; unresolved placeholders are an error.
+func Link(bytecodeHex string, libraries map[string]common.Address) (string, error) {
+	linked := strings.TrimPrefix(strings.TrimSpace(bytecodeHex), "0x")
+	for name, address := range libraries {
+		addr := strings.ToLower(strings.TrimPrefix(address.Hex(), "0x"))
+		linked = strings.ReplaceAll(linked, Placeholder(name), addr)
+		linked = strings.ReplaceAll(linked, legacyPlaceholder(name), addr)
+	}
+	if missing := Unlinked(linked); len(missing) > 0 {
+		return "", fmt.Errorf("bytecode has unresolved library placeholders %v", missing)
+	}
+	return linked, nil
+}
+
+// Unlinked lists the distinct library placeholders remaining in bytecode.
+func Unlinked(bytecodeHex string) []string {
+	seen := map[string]bool{}
+	for _, m := range placeholderPattern.FindAllString(bytecodeHex, -1) {
+		seen[m] = true
+	}
+	out := make([]string, 0, len(seen))
+	for m := range seen {
+		out = append(out, m)
+	}
+	sort.Strings(out)
+	return out
+}
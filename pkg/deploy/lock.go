@@ -0,0 +1,229 @@
+package deploy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// LockPath is the checksum manifest of the compiled artifacts, relative to
+// the project root.
+const LockPath = "artifacts.lock"
+
+// Sentinel errors of artifact verification, matched by ArtifactError.
+var (
+	ErrArtifactModified = errors.New("artifact modified since artifacts.lock was generated")
+	ErrArtifactStale    = errors.New("contract source changed since the artifact was compiled")
+	ErrArtifactUnlocked = errors.New("artifact not listed in artifacts.lock")
+)
+
+// Compiler records the settings the artifacts were compiled with.
+type Compiler struct {
+	Version    string `json:"version"`
+	Optimize   bool   `json:"optimize"`
+	Runs       int    `json:"runs,omitempty"`
+	EVMVersion string `json:"evmVersion,omitempty"`
+	ViaIR      bool   `json:"viaIR,omitempty"`
+}
+
+// LockEntry holds the checksums of one artifact and of its source.
+type LockEntry struct {
+	Bin    string `json:"bin"`
+	ABI    string `json:"abi"`
+	Source string `json:"source,omitempty"`
+	// SourceSHA256 is empty when no contracts/<Name>.sol was found.
+	SourceSHA256 string `json:"sourceSha256,omitempty"`
+	// Solc is the compiler version embedded in the bytecode metadata.
+	Solc string `json:"solc,omitempty"`
+}
+
+// Lock maps artifact paths without extension, e.g. artifacts/Sha256Wrapper,
+// to their checksums.
+type Lock struct {
+	Compiler  Compiler             `json:"compiler"`
+	Artifacts map[string]LockEntry `json:"artifacts"`
+}
+
+// ArtifactError reports a file whose checksum differs from the lock, or an
+// artifact the lock doesn't list.
+type ArtifactError struct {
+	Artifact string
+	// File is the mismatching .bin, .abi or .sol file, empty when the
+	// artifact isn't locked.
+	File           string
+	Locked, Actual string
+}
+
+func (e *ArtifactError) Error() string {
+	switch {
+	case e.File == "":
+		return fmt.Sprintf("%s is not listed in %s", e.Artifact, LockPath)
+	case strings.HasSuffix(e.File, ".sol"):
+		return fmt.Sprintf("%s changed since %s was compiled (sha256 %s, locked %s)", e.File, e.Artifact, e.Actual, e.Locked)
+	}
+	return fmt.Sprintf("%s does not match %s (sha256 %s, locked %s)", e.File, LockPath, e.Actual, e.Locked)
+}
+
+func (e *ArtifactError) Is(target error) bool {
+	switch {
+	case e.File == "":
+		return target == ErrArtifactUnlocked
+	case strings.HasSuffix(e.File, ".sol"):
+		return target == ErrArtifactStale
+	}
+	return target == ErrArtifactModified
+}
+
+// GenerateLock checksums every <name>.bin and <name>.abi pair in dir, and
+// contracts/<name>.sol under sourceDir when it exists.
+func GenerateLock(dir, sourceDir string, compiler Compiler) (*Lock, error) {
+	bins, err := filepath.Glob(filepath.Join(dir, "*.bin"))
+	if err != nil {
+		return nil, err
+	}
+	if len(bins) == 0 {
+		return nil, fmt.Errorf("no .bin artifacts in %s", dir)
+	}
+	l := &Lock{Compiler: compiler, Artifacts: map[string]LockEntry{}}
+	for _, bin := range bins {
+		artifact := filepath.ToSlash(strings.TrimSuffix(bin, ".bin"))
+		var e LockEntry
+		code, err := os.ReadFile(bin)
+		if err != nil {
+			return nil, err
+		}
+		e.Bin = checksum(code)
+		e.Solc = SolcVersion(string(code))
+		abiBytes, err := os.ReadFile(artifact + ".abi")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", artifact, err)
+		}
+		e.ABI = checksum(abiBytes)
+
+		source := filepath.ToSlash(filepath.Join(sourceDir, filepath.Base(artifact)+".sol"))
+		if data, err := os.ReadFile(source); err == nil {
+			e.Source, e.SourceSHA256 = source, checksum(data)
+		}
+		l.Artifacts[artifact] = e
+	}
+	return l, nil
+}
+
+// LoadLock reads a lock file.
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var l Lock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &l, nil
+}
+
+// Save writes the lock with artifacts in sorted order.
+func (l *Lock) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock: %w", err)
+	}
+	if err := paths.WriteFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return nil
+}
+
+// Names lists the locked artifacts in sorted order.
+func (l *Lock) Names() []string {
+	names := make([]string, 0, len(l.Artifacts))
+	for name := range l.Artifacts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Verify checks the artifact's .bin and .abi, and its source if locked,
+// against the recorded checksums.
+func (l *Lock) Verify(artifact string) error {
+	artifact = filepath.ToSlash(filepath.Clean(artifact))
+	e, ok := l.lookup(artifact)
+	if !ok {
+		return &ArtifactError{Artifact: artifact}
+	}
+	files := [][2]string{{artifact + ".bin", e.Bin}, {artifact + ".abi", e.ABI}}
+	if e.Source != "" {
+		files = append(files, [2]string{e.Source, e.SourceSHA256})
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f[0])
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", artifact, err)
+		}
+		if sum := checksum(data); sum != f[1] {
+			return &ArtifactError{Artifact: artifact, File: f[0], Locked: f[1], Actual: sum}
+		}
+	}
+	return nil
+}
+
+// lookup finds the entry of artifact, falling back to the one of the same
+// name when the artifacts were moved to another directory (ARTIFACTS_DIR).
+func (l *Lock) lookup(artifact string) (LockEntry, bool) {
+	if e, ok := l.Artifacts[artifact]; ok {
+		return e, true
+	}
+	for name, e := range l.Artifacts {
+		if path.Base(name) == path.Base(artifact) {
+			return e, true
+		}
+	}
+	return LockEntry{}, false
+}
+
+// VerifyArtifact checks an artifact against the project's artifacts.lock
+// before it is deployed.
+func VerifyArtifact(artifact string) error {
+	l, err := LoadLock(LockPath)
+	if err != nil {
+		return err
+	}
+	return l.Verify(artifact)
+}
+
+// SolcVersion extracts the compiler version solc appends to the bytecode in
+// its CBOR metadata ("solc" followed by major, minor and patch bytes), or
+// returns "" if there is none.
+func SolcVersion(bytecodeHex string) string {
+	// Library placeholders aren't hex; the metadata is never inside one
+	unlinked := placeholderPattern.ReplaceAllString(strings.TrimSpace(bytecodeHex), strings.Repeat("0", 40))
+	code, err := hex.DecodeString(strings.TrimPrefix(unlinked, "0x"))
+	if err != nil {
+		return ""
+	}
+	marker := []byte{0x64, 's', 'o', 'l', 'c', 0x43}
+	i := bytes.LastIndex(code, marker)
+	if i < 0 || i+len(marker)+3 > len(code) {
+		return ""
+	}
+	v := code[i+len(marker) : i+len(marker)+3]
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// checksum hashes data with CRLF line endings normalised, so a checkout
+// with core.autocrlf matches the lock generated from an LF one.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")))
+	return hex.EncodeToString(sum[:])
+}
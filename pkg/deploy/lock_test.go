@@ -0,0 +1,87 @@
+package deploy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	artifacts, contracts := filepath.Join(dir, "artifacts"), filepath.Join(dir, "contracts")
+	for _, d := range []string{artifacts, contracts} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bin, abi, sol := filepath.Join(artifacts, "Wrapper.bin"), filepath.Join(artifacts, "Wrapper.abi"), filepath.Join(contracts, "Wrapper.sol")
+	// Ends in solc 0.8.30 metadata
+	write(bin, "6080604052a264697066735822122000000000000000000000000000000000000000000000000000000000000000000064736f6c634300081e0033")
+	write(abi, "[\n]\n")
+	write(sol, "contract Wrapper {}")
+
+	lock, err := GenerateLock(artifacts, contracts, Compiler{Version: "0.8.30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.ToSlash(filepath.Join(artifacts, "Wrapper"))
+	if e := lock.Artifacts[name]; e.Solc != "0.8.30" || e.Source == "" {
+		t.Fatalf("entry %+v", e)
+	}
+	path := filepath.Join(dir, LockPath)
+	if err := lock.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if lock, err = LoadLock(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Verify(name); err != nil {
+		t.Fatalf("fresh artifact: %v", err)
+	}
+
+	// A CRLF checkout, or a copy in another ARTIFACTS_DIR, still matches
+	write(abi, "[\r\n]\r\n")
+	moved := filepath.Join(dir, "moved")
+	if err := os.Mkdir(moved, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{bin, abi} {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(filepath.Join(moved, filepath.Base(f)), string(data))
+	}
+	if err := lock.Verify(filepath.Join(moved, "Wrapper")); err != nil {
+		t.Fatalf("moved CRLF artifact: %v", err)
+	}
+
+	write(sol, "contract Wrapper { uint x; }")
+	if err := lock.Verify(name); !errors.Is(err, ErrArtifactStale) {
+		t.Errorf("edited source: %v, want stale", err)
+	}
+	write(bin, "6080")
+	var artifactErr *ArtifactError
+	if err := lock.Verify(name); !errors.Is(err, ErrArtifactModified) || !errors.As(err, &artifactErr) || artifactErr.File != bin {
+		t.Errorf("edited bytecode: %v, want modified", err)
+	}
+	if err := lock.Verify(filepath.Join(artifacts, "Other")); !errors.Is(err, ErrArtifactUnlocked) {
+		t.Errorf("unknown artifact: %v, want unlocked", err)
+	}
+}
+
+func TestSolcVersion(t *testing.T) {
+	linked := "73__$0123456789abcdef0123456789abcdef01$__6364736f6c6343000814"
+	if v := SolcVersion(linked); v != "0.8.20" {
+		t.Errorf("version %q, want 0.8.20 despite the library placeholder", v)
+	}
+	if v := SolcVersion("6080604052"); v != "" {
+		t.Errorf("version %q without metadata", v)
+	}
+}
@@ -0,0 +1,144 @@
+// Package deploy deploys suites of contracts described by a manifest,
+// resolving the deployment order from the dependencies each contract
+// declares.
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Arg is one constructor argument: either a literal value or a reference to
+// the address of another contract in the same manifest.
+type Arg struct {
+	Ref   string `json:"ref,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Contract is one deployable entry of a manifest.
+type Contract struct {
+	Name string `json:"name"`
+	// Artifact is the solc output path without extension; <Artifact>.bin and
+	// <Artifact>.abi are loaded.
+	Artifact        string   `json:"artifact"`
+	ConstructorArgs []Arg    `json:"constructorArgs,omitempty"`
+	DependsOn       []string `json:"dependsOn,omitempty"`
+	GasLimit        uint64   `json:"gasLimit,omitempty"`
+	// Libraries maps fully qualified library names used in the bytecode
+	// ("contracts/Lib.sol:Lib") to the manifest entry deploying them.
+	Libraries map[string]string `json:"libraries,omitempty"`
+}
+
+// Manifest lists the contracts of a suite.
+type Manifest struct {
+	Contracts []Contract `json:"contracts"`
+}
+
+// LoadManifest reads a JSON manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Dependencies returns every contract name c needs deployed first: explicit
+// dependsOn entries, linked libraries and constructor arguments referencing
+// other contracts.
+func (c Contract) Dependencies() []string {
+	seen := map[string]bool{}
+	var deps []string
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			deps = append(deps, name)
+		}
+	}
+	for _, d := range c.DependsOn {
+		add(d)
+	}
+	libs := make([]string, 0, len(c.Libraries))
+	for _, target := range c.Libraries {
+		libs = append(libs, target)
+	}
+	sort.Strings(libs)
+	for _, target := range libs {
+		add(target)
+	}
+	for _, a := range c.ConstructorArgs {
+		add(a.Ref)
+	}
+	return deps
+}
+
+// Order returns the contracts in an order where every contract follows its
+// dependencies. Independent contracts keep their manifest order so runs are
+// reproducible. Unknown references and cycles are errors.
+func (m *Manifest) Order() ([]Contract, error) {
+	index := make(map[string]int, len(m.Contracts))
+	for i, c := range m.Contracts {
+		if c.Name == "" {
+			return nil, fmt.Errorf("manifest entry %d has no name", i)
+		}
+		if _, dup := index[c.Name]; dup {
+			return nil, fmt.Errorf("contract %q declared twice", c.Name)
+		}
+		index[c.Name] = i
+	}
+
+	// Kahn's algorithm over manifest positions
+	pending := make([]int, len(m.Contracts))
+	dependents := make([][]int, len(m.Contracts))
+	for i, c := range m.Contracts {
+		for _, dep := range c.Dependencies() {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("contract %q depends on unknown contract %q", c.Name, dep)
+			}
+			if j == i {
+				return nil, fmt.Errorf("contract %q depends on itself", c.Name)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var ready []int
+	for i := range m.Contracts {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	ordered := make([]Contract, 0, len(m.Contracts))
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, m.Contracts[i])
+		for _, d := range dependents[i] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if len(ordered) != len(m.Contracts) {
+		var stuck []string
+		for i, c := range m.Contracts {
+			if pending[i] > 0 {
+				stuck = append(stuck, c.Name)
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle between %v", stuck)
+	}
+	return ordered, nil
+}
@@ -0,0 +1,79 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"cdk-erigon-precompile/pkg/chain"
+)
+
+// EIP-1167 minimal proxy: the runtime forwards every call to the
+// implementation with DELEGATECALL and bubbles up the return data.
+var (
+	proxyInit          = common.FromHex("3d602d80600a3d3981f3")
+	proxyRuntimePrefix = common.FromHex("363d3d373d3d3d363d73")
+	proxyRuntimeSuffix = common.FromHex("5af43d82803e903d91602b57fd5bf3")
+)
+
+// ProxyGasLimit comfortably covers the 55-byte clone deployment.
+const ProxyGasLimit = 100_000
+
+// MinimalProxyRuntime returns the deployed code of a clone of implementation.
+func MinimalProxyRuntime(implementation common.Address) []byte {
+	code := append([]byte{}, proxyRuntimePrefix...)
+	code = append(code, implementation.Bytes()...)
+	return append(code, proxyRuntimeSuffix...)
+}
+
+// MinimalProxyCreationCode returns init code that deploys a clone of
+// implementation, so an EOA can create clones without a factory contract.
+func MinimalProxyCreationCode(implementation common.Address) []byte {
+	return append(append([]byte{}, proxyInit...), MinimalProxyRuntime(implementation)...)
+}
+
+// MinimalProxyTarget reports the implementation a clone forwards to.
+func MinimalProxyTarget(code []byte) (common.Address, bool) {
+	if len(code) != len(proxyRuntimePrefix)+common.AddressLength+len(proxyRuntimeSuffix) ||
+		!bytes.HasPrefix(code, proxyRuntimePrefix) || !bytes.HasSuffix(code, proxyRuntimeSuffix) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(code[len(proxyRuntimePrefix) : len(proxyRuntimePrefix)+common.AddressLength]), true
+}
+
+// DeployProxies creates n clones of implementation and checks each clone's
+// code is exactly the EIP-1167 runtime pointing at it.
+func DeployProxies(ctx context.Context, sender *chain.Sender, implementation common.Address, n int) ([]Deployed, error) {
+	var deployed []Deployed
+	for i := 0; i < n; i++ {
+		tx, receipt, err := sender.Send(chain.WithContract(ctx, "MinimalProxy"), nil, MinimalProxyCreationCode(implementation), ProxyGasLimit)
+		if err != nil {
+			return deployed, fmt.Errorf("proxy %d: %w", i, err)
+		}
+		d := Deployed{
+			Name:            fmt.Sprintf("proxy-%d", i),
+			Address:         receipt.ContractAddress.Hex(),
+			TransactionHash: tx.Hash().Hex(),
+			BlockNumber:     receipt.BlockNumber.Uint64(),
+			GasUsed:         receipt.GasUsed,
+			Status:          receipt.Status,
+		}
+		if receipt.Status != 1 {
+			deployed = append(deployed, d)
+			return deployed, fmt.Errorf("proxy %d: deployment reverted in block %d", i, d.BlockNumber)
+		}
+
+		code, err := sender.Client.CodeAt(ctx, receipt.ContractAddress, nil)
+		if err != nil {
+			return deployed, fmt.Errorf("proxy %d: failed to get code: %w", i, err)
+		}
+		d.CodeSize = len(code)
+		deployed = append(deployed, d)
+		if target, ok := MinimalProxyTarget(code); !ok || target != implementation {
+			return deployed, fmt.Errorf("proxy %d at %s has unexpected code %x", i, d.Address, code)
+		}
+	}
+	return deployed, nil
+}
@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+)
+
+// ErrABIMismatch is matched by ABIMismatchError.
+var ErrABIMismatch = errors.New("ABI does not match deployed contract")
+
+// ABIMismatchError reports ABI functions whose selectors the deployed code
+// doesn't dispatch on.
+type ABIMismatchError struct {
+	Address common.Address
+	// Missing are the signatures and selectors, e.g. "sha256Hash(bytes) 0xe7f1e7c5".
+	Missing []string
+	// Found is how many selectors the code dispatches on.
+	Found int
+}
+
+func (e *ABIMismatchError) Error() string {
+	return fmt.Sprintf("ABI does not match deployed contract at %s: no dispatch for %s (code has %d selectors)",
+		e.Address.Hex(), strings.Join(e.Missing, ", "), e.Found)
+}
+
+func (e *ABIMismatchError) Is(target error) bool { return target == ErrABIMismatch }
+
+// Selectors extracts the function selectors from the dispatch table of solc
+// runtime code: a PUSH of up to four bytes compared with EQ, or a PUSH4
+// compared with GT where solc splits large tables. Push data is skipped so
+// bytes inside it are never mistaken for opcodes.
+func Selectors(code []byte) map[[4]byte]bool {
+	selectors := map[[4]byte]bool{}
+	for pc := 0; pc < len(code); pc++ {
+		op := vm.OpCode(code[pc])
+		if op < vm.PUSH1 || op > vm.PUSH32 {
+			continue
+		}
+		size := int(op-vm.PUSH1) + 1
+		end := pc + 1 + size
+		if end < len(code) && size <= 4 {
+			next := vm.OpCode(code[end])
+			if next == vm.EQ || (next == vm.GT && size == 4) {
+				var sel [4]byte
+				copy(sel[4-size:], code[pc+1:end])
+				selectors[sel] = true
+			}
+		}
+		pc += size
+	}
+	return selectors
+}
+
+// CheckABI verifies the code at address dispatches on every function of
+// parsedABI, failing with an *ABIMismatchError otherwise. Calling a
+// contract through the wrong ABI would otherwise surface as an opaque
+// revert or unpack failure.
+func CheckABI(ctx context.Context, client *ethclient.Client, address common.Address, parsedABI *abi.ABI) error {
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get contract code: %w", err)
+	}
+	if len(code) == 0 {
+		return &chain.NoCodeError{Address: address}
+	}
+	selectors := Selectors(code)
+	var missing []string
+	for _, m := range parsedABI.Methods {
+		if !selectors[[4]byte(m.ID)] {
+			missing = append(missing, m.Sig+" 0x"+hex.EncodeToString(m.ID))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return &ABIMismatchError{Address: address, Missing: missing, Found: len(selectors)}
+	}
+	return nil
+}
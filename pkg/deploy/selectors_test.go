@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestCheckABI(t *testing.T) {
+	artifact, err := LoadArtifact("../../artifacts/Sha256Wrapper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The creation code embeds the runtime code and its dispatch table
+	code, err := artifact.Bytecode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_getCode", func(c mockrpc.Call) (any, error) {
+		var addr common.Address
+		if err := c.Param(0, &addr); err != nil {
+			return nil, err
+		}
+		if addr == wrapper {
+			return hexutil.Bytes(code), nil
+		}
+		return hexutil.Bytes{}, nil
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if err := CheckABI(ctx, client, wrapper, &artifact.ABI); err != nil {
+		t.Errorf("wrapper ABI: %v", err)
+	}
+
+	store, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"store","inputs":[{"name":"input","type":"bytes"}],"outputs":[{"type":"bytes32"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckABI(ctx, client, wrapper, &store)
+	var mismatch *ABIMismatchError
+	if !errors.Is(err, ErrABIMismatch) || !errors.As(err, &mismatch) || len(mismatch.Missing) != 1 || !strings.HasPrefix(mismatch.Missing[0], "store(bytes) 0x") {
+		t.Errorf("store ABI against the wrapper: %v", err)
+	}
+
+	if err := CheckABI(ctx, client, common.HexToAddress("0x01"), &store); !errors.Is(err, chain.ErrNoCodeAtAddress) {
+		t.Errorf("empty account: %v", err)
+	}
+}
+
+func TestSelectorsSkipPushData(t *testing.T) {
+	// PUSH5 carrying what looks like PUSH4 <sel> EQ, then a real PUSH3 <sel> EQ
+	code := []byte{0x64, 0x63, 0xaa, 0xbb, 0xcc, 0x14, 0x80, 0x62, 0x01, 0x02, 0x03, 0x14}
+	sels := Selectors(code)
+	if len(sels) != 1 || !sels[[4]byte{0x00, 0x01, 0x02, 0x03}] {
+		t.Errorf("selectors %v", sels)
+	}
+}
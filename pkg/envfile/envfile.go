@@ -0,0 +1,86 @@
+// Package envfile loads the dotenv files the scripts read their
+// configuration from, so one checkout can target several networks, e.g.
+// --env-file .env.cardona, and containers can rely on the environment alone.
+package envfile
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// Default is loaded when no --env-file is given. Unlike files named on the
+// command line it may be missing.
+const Default = ".env"
+
+// Options are the dotenv flags shared by the scripts.
+type Options struct {
+	Files    []string
+	Disabled bool
+}
+
+// Flags registers --env-file and --no-env-file on the default flag set.
+func Flags() *Options {
+	return FlagsOn(flag.CommandLine)
+}
+
+// FlagsOn registers --env-file and --no-env-file on fs, for scripts with
+// subcommands.
+func FlagsOn(fs *flag.FlagSet) *Options {
+	o := &Options{}
+	fs.Func("env-file", "dotenv file to load instead of .env; repeat to layer files, later ones overriding earlier ones", func(s string) error {
+		o.Files = append(o.Files, s)
+		return nil
+	})
+	fs.BoolVar(&o.Disabled, "no-env-file", false, "load no dotenv file and use only the environment")
+	return o
+}
+
+// Load sets the variables of the files that aren't set in the environment
+// already, so exported variables always win. It does nothing when
+// --no-env-file is set, and tolerates a missing .env when no file is named.
+func (o *Options) Load() error {
+	if o.Disabled {
+		return nil
+	}
+	files, optional := o.Files, len(o.Files) == 0
+	if optional {
+		files = []string{Default}
+	}
+	vars := map[string]string{}
+	for _, path := range files {
+		layer, err := godotenv.Read(path)
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load env file %s: %w", path, err)
+		}
+		maps.Copy(vars, layer)
+	}
+	for k, v := range vars {
+		if _, set := os.LookupEnv(k); !set {
+			if err := os.Setenv(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Args renders the options as flags for a child script.
+func (o *Options) Args() []string {
+	var args []string
+	if o.Disabled {
+		return append(args, "--no-env-file")
+	}
+	for _, path := range o.Files {
+		args = append(args, "--env-file", path)
+	}
+	return args
+}
@@ -0,0 +1,54 @@
+package envfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	base, network := filepath.Join(dir, ".env"), filepath.Join(dir, ".env.cardona")
+	if err := os.WriteFile(base, []byte("ENVFILE_HOST=localhost\nENVFILE_PORT=8545\nENVFILE_KEY=base\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(network, []byte("ENVFILE_HOST=rpc.cardona\r\nENVFILE_KEY=network\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"ENVFILE_HOST", "ENVFILE_PORT"} {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+	t.Setenv("ENVFILE_KEY", "exported")
+
+	o := &Options{Files: []string{base, network}}
+	if err := o.Load(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"ENVFILE_HOST": "rpc.cardona", "ENVFILE_PORT": "8545", "ENVFILE_KEY": "exported"}
+	for k, v := range want {
+		if got := os.Getenv(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	if err := (&Options{Files: []string{filepath.Join(dir, ".env.missing")}}).Load(); err == nil {
+		t.Error("missing named file loaded")
+	}
+	if err := (&Options{Files: []string{filepath.Join(dir, ".env.missing")}, Disabled: true}).Load(); err != nil {
+		t.Errorf("disabled: %v", err)
+	}
+
+	// A missing default .env is fine
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := (&Options{}).Load(); err != nil {
+		t.Errorf("missing .env: %v", err)
+	}
+}
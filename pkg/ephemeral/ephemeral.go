@@ -0,0 +1,192 @@
+// Package ephemeral spawns a throwaway local dev node, anvil or a hardhat
+// node, so the pipeline can run against a reference EVM with no infra
+// setup, for example as the baseline of a differential check against
+// cdk-erigon.
+package ephemeral
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DevKey is the private key of the first account anvil and hardhat fund at
+// startup, derived from their shared default mnemonic. It is public and
+// must never hold real funds.
+const DevKey = "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+// StartTimeout bounds how long Start waits for the node to answer.
+var StartTimeout = 30 * time.Second
+
+// Commands builds the command line of each supported node for a port.
+var Commands = map[string]func(port int) []string{
+	"anvil": func(port int) []string {
+		return []string{"anvil", "--host", "127.0.0.1", "--port", strconv.Itoa(port), "--silent"}
+	},
+	"hardhat": func(port int) []string {
+		return []string{"npx", "hardhat", "node", "--hostname", "127.0.0.1", "--port", strconv.Itoa(port)}
+	},
+}
+
+// Kinds lists the supported nodes in sorted order.
+func Kinds() []string {
+	kinds := make([]string, 0, len(Commands))
+	for k := range Commands {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Node is a running dev node.
+type Node struct {
+	Kind    string
+	Host    string
+	Port    int
+	URL     string
+	ChainID uint64
+
+	cmd    *exec.Cmd
+	output *syncBuffer
+	exited chan struct{}
+	err    error
+}
+
+// Start launches the node on a free local port and waits until it answers
+// eth_chainId. The node is killed if ctx is cancelled; call Stop to shut it
+// down.
+func Start(ctx context.Context, kind string) (*Node, error) {
+	command, ok := Commands[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown ephemeral node %q (want %s)", kind, strings.Join(Kinds(), ", "))
+	}
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	argv := command(port)
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		return nil, fmt.Errorf("%s is not installed: %w", argv[0], err)
+	}
+
+	n := &Node{
+		Kind:   kind,
+		Host:   "127.0.0.1",
+		Port:   port,
+		URL:    fmt.Sprintf("http://127.0.0.1:%d", port),
+		cmd:    exec.CommandContext(ctx, argv[0], argv[1:]...),
+		output: &syncBuffer{},
+		exited: make(chan struct{}),
+	}
+	n.cmd.Stdout = n.output
+	n.cmd.Stderr = n.output
+	// npx leaves the node in a child process that may hold the output
+	// pipes after the parent exits
+	n.cmd.WaitDelay = 5 * time.Second
+	if err := n.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", kind, err)
+	}
+	go func() {
+		n.err = n.cmd.Wait()
+		close(n.exited)
+	}()
+
+	if err := n.waitReady(ctx); err != nil {
+		n.Stop()
+		return nil, err
+	}
+	return n, nil
+}
+
+// waitReady polls eth_chainId until the node answers, exits or the start
+// timeout passes.
+func (n *Node) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, StartTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		client, err := ethclient.DialContext(ctx, n.URL)
+		if err == nil {
+			chainID, err := client.ChainID(ctx)
+			client.Close()
+			if err == nil {
+				n.ChainID = chainID.Uint64()
+				return nil
+			}
+		}
+		select {
+		case <-n.exited:
+			return fmt.Errorf("%s exited before it was ready (%v): %s", n.Kind, n.err, n.Output())
+		case <-ctx.Done():
+			return fmt.Errorf("%s did not answer on %s: %w", n.Kind, n.URL, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop interrupts the node and waits for it to exit, killing it if it
+// doesn't within five seconds, or right away where processes can't be
+// interrupted (Windows).
+func (n *Node) Stop() {
+	select {
+	case <-n.exited:
+		return
+	default:
+	}
+	if err := n.cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = n.cmd.Process.Kill()
+		<-n.exited
+		return
+	}
+	select {
+	case <-n.exited:
+	case <-time.After(5 * time.Second):
+		_ = n.cmd.Process.Kill()
+		<-n.exited
+	}
+}
+
+// Output is everything the node printed so far.
+func (n *Node) Output() string {
+	return strings.TrimSpace(n.output.String())
+}
+
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// syncBuffer collects the process output, which exec writes from its own
+// goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
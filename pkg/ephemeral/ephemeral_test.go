@@ -0,0 +1,89 @@
+package ephemeral
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess is the fake node started by the tests below: a JSON-RPC
+// server answering eth_chainId like anvil does.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("EPHEMERAL_HELPER") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if args[1] == "crash" {
+		fmt.Println("error: address already in use")
+		os.Exit(1)
+	}
+	port, _ := strconv.Atoi(args[1])
+	http.ListenAndServe("127.0.0.1:"+strconv.Itoa(port), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x7a69"}`, req.ID)
+	}))
+	os.Exit(0)
+}
+
+func fakeNode(t *testing.T, arg func(port int) string) {
+	t.Helper()
+	t.Setenv("EPHEMERAL_HELPER", "1")
+	Commands["fake"] = func(port int) []string {
+		return []string{os.Args[0], "-test.run=TestHelperProcess", "--", arg(port)}
+	}
+	t.Cleanup(func() { delete(Commands, "fake") })
+}
+
+func TestStart(t *testing.T) {
+	fakeNode(t, strconv.Itoa)
+
+	n, err := Start(context.Background(), "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+	if n.ChainID != 31337 {
+		t.Errorf("chain ID %d, want 31337", n.ChainID)
+	}
+	if n.URL != fmt.Sprintf("http://127.0.0.1:%d", n.Port) {
+		t.Errorf("URL %s doesn't match port %d", n.URL, n.Port)
+	}
+
+	n.Stop()
+	select {
+	case <-n.exited:
+	default:
+		t.Error("node still running after Stop")
+	}
+}
+
+func TestStartReportsEarlyExit(t *testing.T) {
+	fakeNode(t, func(int) string { return "crash" })
+
+	start := time.Now()
+	_, err := Start(context.Background(), "fake")
+	if err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatalf("got %v, want the node's output in the error", err)
+	}
+	if time.Since(start) > StartTimeout/2 {
+		t.Error("waited for the timeout instead of noticing the exit")
+	}
+}
+
+func TestStartUnknownKind(t *testing.T) {
+	if _, err := Start(context.Background(), "ganache"); err == nil || !strings.Contains(err.Error(), "anvil, hardhat") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Package erc1271 checks signatures through ERC1271Wallet
+// (contracts/ERC1271Wallet.sol), a minimal smart wallet whose
+// isValidSignature recovers its owner with the ecrecover precompile or, when
+// owned by another wallet, asks that wallet. Smart accounts validate every
+// signature this way, so a faulty ecrecover breaks them on any L2.
+package erc1271
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/typeddata"
+)
+
+// MagicValue is what isValidSignature returns for a valid signature, the
+// selector of isValidSignature(bytes32,bytes). Invalid is what the wallet
+// returns otherwise.
+var (
+	MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+	Invalid    = [4]byte{0xff, 0xff, 0xff, 0xff}
+)
+
+// abiJSON is the ABI of contracts/ERC1271Wallet.sol.
+const abiJSON = `[
+{"type":"constructor","inputs":[{"name":"_owner","type":"address"}]},
+{"type":"function","name":"owner","stateMutability":"view","inputs":[],
+"outputs":[{"name":"","type":"address"}]},
+{"type":"function","name":"isValidSignature","stateMutability":"view",
+"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],
+"outputs":[{"name":"","type":"bytes4"}]}]`
+
+// ABI is the parsed ERC1271Wallet ABI.
+var ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// DeployData is the creation code of a wallet owned by owner: the compiled
+// bytecode followed by the encoded constructor argument.
+func DeployData(bytecode []byte, owner common.Address) ([]byte, error) {
+	args, err := ABI.Pack("", owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack constructor: %w", err)
+	}
+	return append(append([]byte{}, bytecode...), args...), nil
+}
+
+// Encode packs sig as the wallet takes it: r, s, then v.
+func Encode(sig typeddata.Signature) []byte {
+	out := make([]byte, 0, 65)
+	out = append(out, sig.R[:]...)
+	out = append(out, sig.S[:]...)
+	return append(out, sig.V)
+}
+
+// Owner is the wallet's owner.
+func Owner(ctx context.Context, client *ethclient.Client, wallet common.Address) (common.Address, error) {
+	out, err := call(ctx, client, wallet, "owner")
+	if err != nil {
+		return common.Address{}, err
+	}
+	return out[0].(common.Address), nil
+}
+
+// IsValidSignature is the wallet's answer for signature over hash:
+// MagicValue if it accepts it.
+func IsValidSignature(ctx context.Context, client *ethclient.Client, wallet common.Address, hash common.Hash, signature []byte) ([4]byte, error) {
+	out, err := call(ctx, client, wallet, "isValidSignature", hash, signature)
+	if err != nil {
+		return [4]byte{}, err
+	}
+	return out[0].([4]byte), nil
+}
+
+func call(ctx context.Context, client *ethclient.Client, wallet common.Address, method string, args ...any) ([]any, error) {
+	data, err := ABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &wallet, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s call failed: %w", method, err)
+	}
+	values, err := ABI.Unpack(method, out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %w", method, err)
+	}
+	return values, nil
+}
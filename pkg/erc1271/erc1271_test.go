@@ -0,0 +1,95 @@
+package erc1271
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/signer"
+	"cdk-erigon-precompile/pkg/typeddata"
+)
+
+func TestDeployData(t *testing.T) {
+	owner := common.Address{0xaa}
+	data, err := DeployData([]byte{0x60, 0x80}, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 34 || !bytes.Equal(data[:2], []byte{0x60, 0x80}) || common.BytesToAddress(data[2:]) != owner {
+		t.Errorf("deploy data %x", data)
+	}
+}
+
+func TestIsValidSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	wallet := common.Address{0x12}
+
+	// The mock wallet recovers the owner as the contract does
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_call", func(call mockrpc.Call) (any, error) {
+		var msg struct {
+			Input hexutil.Bytes `json:"input"`
+			Data  hexutil.Bytes `json:"data"`
+		}
+		if err := call.Param(0, &msg); err != nil {
+			return nil, err
+		}
+		data := msg.Input
+		if len(data) == 0 {
+			data = msg.Data
+		}
+		method, err := ABI.MethodById(data[:4])
+		if err != nil {
+			return nil, err
+		}
+		if method.Name == "owner" {
+			return hexutil.Bytes(common.LeftPadBytes(owner.Bytes(), 32)), nil
+		}
+		args, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		hash, sig := args[0].([32]byte), args[1].([]byte)
+		result := Invalid
+		if len(sig) == 65 {
+			sig = append(append([]byte{}, sig[:64]...), sig[64]-27)
+			if pub, err := crypto.SigToPub(hash[:], sig); err == nil && crypto.PubkeyToAddress(*pub) == owner {
+				result = MagicValue
+			}
+		}
+		out, _ := method.Outputs.Pack(result)
+		return hexutil.Bytes(out), nil
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if got, err := Owner(ctx, client, wallet); err != nil || got != owner {
+		t.Errorf("owner %s, %v", got.Hex(), err)
+	}
+	hash := common.Hash{0x42}
+	sig, err := typeddata.Sign(ctx, signer.NewLocal(key), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := IsValidSignature(ctx, client, wallet, hash, Encode(sig)); err != nil || got != MagicValue {
+		t.Errorf("owner's signature: %x, %v", got, err)
+	}
+	if got, err := IsValidSignature(ctx, client, wallet, common.Hash{0x43}, Encode(sig)); err != nil || got != Invalid {
+		t.Errorf("other hash: %x, %v", got, err)
+	}
+}
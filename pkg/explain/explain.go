@@ -0,0 +1,262 @@
+// Package explain takes a single test case apart for debugging: the raw
+// JSON-RPC exchanges it caused, its calldata word by word, the steps of the
+// local reference computation and the trace around the precompile call,
+// laid out next to what the node answered. It backs the --explain flag of
+// the stage scripts, which otherwise only report a mismatch.
+package explain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"cdk-erigon-precompile/pkg/tracediff"
+)
+
+// Exchange is one raw JSON-RPC request and the node's response.
+type Exchange struct {
+	Request  []byte
+	Response []byte
+	Status   int
+	Error    string
+}
+
+// Recorder is an http.RoundTripper that keeps every exchange in memory
+// until Take is called.
+type Recorder struct {
+	Base http.RoundTripper
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	e := Exchange{Request: body}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+		r.add(e)
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	e.Response, e.Status = data, resp.StatusCode
+	if err != nil {
+		e.Error = err.Error()
+	}
+	r.add(e)
+	return resp, nil
+}
+
+func (r *Recorder) add(e Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges = append(r.exchanges, e)
+}
+
+// Take returns the exchanges recorded since the last call.
+func (r *Recorder) Take() []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	taken := r.exchanges
+	r.exchanges = nil
+	return taken
+}
+
+// Indent pretty-prints a JSON body, returning it unchanged when it isn't
+// valid JSON.
+func Indent(body []byte) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(body), "", "  "); err != nil {
+		return string(body)
+	}
+	return buf.String()
+}
+
+// Word is one piece of ABI calldata: the 4-byte selector or a 32-byte word.
+type Word struct {
+	Offset  int
+	Data    []byte
+	Meaning string
+}
+
+// Calldata splits data, a call of method, into its selector and words and
+// labels each with its role in the encoding of the top-level arguments:
+// static values, offsets of dynamic ones, and the lengths and contents
+// they point at. Words it can't attribute are left unlabelled.
+func Calldata(method abi.Method, data []byte) []Word {
+	if len(data) < 4 {
+		return []Word{{Data: data, Meaning: "short calldata, no selector"}}
+	}
+	words := []Word{{Offset: 0, Data: data[:4], Meaning: fmt.Sprintf("selector of %s", method.Sig)}}
+	if !bytes.Equal(data[:4], method.ID) {
+		words[0].Meaning = fmt.Sprintf("selector, not %s (%x)", method.Sig, method.ID)
+	}
+	args := data[4:]
+	meanings := map[int]string{}
+	for i, in := range method.Inputs {
+		name := in.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		head := 32 * i
+		switch in.Type.T {
+		case abi.BytesTy, abi.StringTy, abi.SliceTy:
+			meanings[head] = fmt.Sprintf("offset of %s (%s)", name, in.Type)
+			offset, ok := wordInt(args, head)
+			if !ok || offset%32 != 0 || offset >= len(args) {
+				continue
+			}
+			meanings[offset] = fmt.Sprintf("length of %s", name)
+			length, ok := wordInt(args, offset)
+			if !ok {
+				continue
+			}
+			n, part := (length+31)/32, "word"
+			if in.Type.T == abi.SliceTy {
+				n, part = length, "element"
+			}
+			for w := 0; w < n && offset+32*(w+1) < len(args); w++ {
+				meanings[offset+32*(w+1)] = fmt.Sprintf("%s %s %d", name, part, w)
+			}
+		default:
+			meanings[head] = fmt.Sprintf("%s (%s)", name, in.Type)
+		}
+	}
+	for off := 0; off < len(args); off += 32 {
+		end := min(off+32, len(args))
+		m := meanings[off]
+		if end-off < 32 {
+			m = strings.TrimSpace(m + " (truncated)")
+		}
+		words = append(words, Word{Offset: 4 + off, Data: args[off:end], Meaning: m})
+	}
+	return words
+}
+
+// wordInt reads the word at offset as an int, failing when it is missing
+// or too large to be an offset or length.
+func wordInt(data []byte, offset int) (int, bool) {
+	if offset+32 > len(data) {
+		return 0, false
+	}
+	n := new(big.Int).SetBytes(data[offset : offset+32])
+	if !n.IsInt64() || n.Int64() > int64(len(data)) {
+		return 0, false
+	}
+	return int(n.Int64()), true
+}
+
+// Step is one step of a local reference computation.
+type Step struct {
+	Name  string
+	Value string
+}
+
+// maxBlocks bounds how many message blocks SHA256Steps lists.
+const maxBlocks = 4
+
+// SHA256Steps spells out how the digest of input is computed: the
+// padding FIPS 180-4 appends, the 64-byte blocks the compression function
+// consumes, the digest, and the gas the precompile charges for it.
+func SHA256Steps(input []byte) []Step {
+	bits := uint64(len(input)) * 8
+	zeros := (55 - len(input)%64 + 64) % 64
+	padded := make([]byte, 0, len(input)+1+zeros+8)
+	padded = append(padded, input...)
+	padded = append(padded, 0x80)
+	padded = append(padded, make([]byte, zeros)...)
+	padded = binary.BigEndian.AppendUint64(padded, bits)
+	blocks := len(padded) / 64
+
+	steps := []Step{
+		{"input length", fmt.Sprintf("%d bytes (%d bits)", len(input), bits)},
+		{"padding", fmt.Sprintf("0x80, %d zero bytes, bit length 0x%016x", zeros, bits)},
+		{"padded message", fmt.Sprintf("%d bytes = %d blocks of 64", len(padded), blocks)},
+	}
+	for i := 0; i < blocks && i < maxBlocks; i++ {
+		steps = append(steps, Step{fmt.Sprintf("block %d", i), fmt.Sprintf("%x", padded[64*i:64*(i+1)])})
+	}
+	if blocks > maxBlocks {
+		steps = append(steps, Step{"…", fmt.Sprintf("%d more blocks", blocks-maxBlocks)})
+	}
+	sum := sha256.Sum256(input)
+	words := (uint64(len(input)) + 31) / 32
+	steps = append(steps,
+		Step{"digest", fmt.Sprintf("%x", sum)},
+		Step{"precompile gas", fmt.Sprintf("60 + 12 × %d words = %d", words, 60+12*words)},
+	)
+	return steps
+}
+
+// TraceWindow returns the steps of t from before to after steps around the
+// first call to target, with the indexes in t of the window's first step
+// and of the call. The call index is -1, and the window empty, when the
+// trace never calls target.
+func TraceWindow(t tracediff.Trace, target common.Address, before, after int) (window []tracediff.Step, start, call int) {
+	for i, s := range t.StructLogs {
+		// Every call opcode takes the gas, then the address, from the top
+		// of the stack
+		switch s.Op {
+		case "CALL", "CALLCODE", "DELEGATECALL", "STATICCALL":
+		default:
+			continue
+		}
+		if len(s.Stack) < 2 || common.HexToAddress(s.Stack[len(s.Stack)-2]) != target {
+			continue
+		}
+		start = max(0, i-before)
+		end := min(len(t.StructLogs), i+after+1)
+		return t.StructLogs[start:end], start, i
+	}
+	return nil, 0, -1
+}
+
+// Row is one line of a side-by-side comparison.
+type Row struct {
+	Label string
+	Left  string
+	Right string
+}
+
+// SideBySide writes rows as columns headed left and right, marking each row
+// with whether both sides agree.
+func SideBySide(w io.Writer, left, right string, rows []Row) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  \t%s\t%s\n", left, right)
+	for _, r := range rows {
+		mark := "✅"
+		if r.Left != r.Right {
+			mark = "❌"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", r.Label, r.Left, r.Right, mark)
+	}
+	return tw.Flush()
+}
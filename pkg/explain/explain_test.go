@@ -0,0 +1,135 @@
+package explain
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/tracediff"
+)
+
+const wrapperABI = `[{"type":"function","name":"sha256Hash","stateMutability":"view",
+"inputs":[{"name":"input","type":"bytes"}],"outputs":[{"name":"result","type":"bytes32"}]}]`
+
+func TestCalldata(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(wrapperABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := bytes.Repeat([]byte{0xab}, 40)
+	data, err := parsed.Pack("sha256Hash", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, w := range Calldata(parsed.Methods["sha256Hash"], data) {
+		got = append(got, w.Meaning)
+	}
+	want := []string{
+		"selector of sha256Hash(bytes)",
+		"offset of input (bytes)",
+		"length of input",
+		"input word 0",
+		"input word 1",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("meanings %q, want %q", got, want)
+	}
+
+	// A truncated call keeps its words but can't follow the offset
+	words := Calldata(parsed.Methods["sha256Hash"], data[:4+40])
+	if last := words[len(words)-1]; len(last.Data) != 8 || !strings.Contains(last.Meaning, "truncated") {
+		t.Errorf("truncated word %+v", last)
+	}
+}
+
+func TestSHA256Steps(t *testing.T) {
+	for _, c := range []struct {
+		n      int
+		blocks string
+		gas    string
+	}{
+		{0, "64 bytes = 1 blocks", "= 60"},
+		{55, "64 bytes = 1 blocks", "= 84"},
+		{56, "128 bytes = 2 blocks", "= 84"},
+		{300, "320 bytes = 5 blocks", "= 180"},
+	} {
+		steps := SHA256Steps(make([]byte, c.n))
+		values := map[string]string{}
+		for _, s := range steps {
+			values[s.Name] = s.Value
+		}
+		if !strings.HasPrefix(values["padded message"], c.blocks) {
+			t.Errorf("%d bytes: padded message %q, want %q", c.n, values["padded message"], c.blocks)
+		}
+		if !strings.HasSuffix(values["precompile gas"], c.gas) {
+			t.Errorf("%d bytes: gas %q, want %q", c.n, values["precompile gas"], c.gas)
+		}
+		if c.n == 300 && values["…"] != "1 more blocks" {
+			t.Errorf("300 bytes: elided %q", values["…"])
+		}
+	}
+	if steps := SHA256Steps(nil); steps[len(steps)-2].Value != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("empty digest %q", steps[len(steps)-2].Value)
+	}
+}
+
+func TestTraceWindow(t *testing.T) {
+	trace := tracediff.Trace{StructLogs: []tracediff.Step{
+		{Op: "PUSH1"},
+		{Op: "CALL", Stack: []string{"0x0", "0x5", "0xffff"}},
+		{Op: "POP"},
+		{Op: "STATICCALL", Stack: []string{"0x40", "0x2", "0xffff"}},
+		{Op: "ISZERO"},
+		{Op: "JUMPI"},
+	}}
+	window, start, at := TraceWindow(trace, common.HexToAddress("0x02"), 1, 1)
+	if start != 2 || at != 3 || len(window) != 3 || window[0].Op != "POP" || window[2].Op != "ISZERO" {
+		t.Errorf("window %+v from %d, call at %d", window, start, at)
+	}
+	if _, _, at := TraceWindow(trace, common.HexToAddress("0x08"), 1, 1); at != -1 {
+		t.Errorf("found a call to 0x08 at %d", at)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_chainId", "0x7a69")
+	rec := &Recorder{}
+	client, err := rpc.DialOptions(context.Background(), s.URL, rpc.WithHTTPClient(&http.Client{Transport: rec}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var id string
+	if err := client.Call(&id, "eth_chainId"); err != nil {
+		t.Fatal(err)
+	}
+	taken := rec.Take()
+	if len(taken) != 1 || !strings.Contains(string(taken[0].Request), `"eth_chainId"`) || !strings.Contains(Indent(taken[0].Response), `"result": "0x7a69"`) {
+		t.Errorf("recorded %+v", taken)
+	}
+	if again := rec.Take(); len(again) != 0 {
+		t.Errorf("%d exchanges left after Take", len(again))
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SideBySide(&buf, "local", "node", []Row{{"digest", "aa", "aa"}, {"length", "32", "0"}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "✅") || !strings.Contains(lines[2], "❌") {
+		t.Errorf("output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,101 @@
+// Package failfast decides whether a run stops at its first failed check,
+// after a number of them, or runs every check and reports every failure,
+// with --fail-fast and --max-failures. Running everything is the default.
+package failfast
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables setting the policy, so the groups started by the
+// suite runner follow the runner's.
+const (
+	EnvFailFast    = "FAIL_FAST"
+	EnvMaxFailures = "MAX_FAILURES"
+)
+
+// Policy counts failures and says when to stop.
+type Policy struct {
+	FailFast bool
+	// MaxFailures is how many failures stop the run; 0 never stops it.
+	MaxFailures int
+	failures    int
+}
+
+// Flags registers --fail-fast and --max-failures on the default flag set,
+// defaulting to FAIL_FAST and MAX_FAILURES.
+func Flags() *Policy {
+	return FlagsOn(flag.CommandLine)
+}
+
+// FlagsOn registers --fail-fast and --max-failures on fs, for scripts with
+// subcommands.
+func FlagsOn(fs *flag.FlagSet) *Policy {
+	p := &Policy{}
+	p.FailFast, _ = strconv.ParseBool(os.Getenv(EnvFailFast))
+	if n, err := strconv.Atoi(os.Getenv(EnvMaxFailures)); err == nil && n > 0 {
+		p.MaxFailures = n
+	}
+	fs.BoolVar(&p.FailFast, "fail-fast", p.FailFast, "stop at the first failed check instead of running every check (same as --max-failures 1)")
+	fs.Func("max-failures", "stop after this many failed checks (0 runs every check)", func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return fmt.Errorf("want a count of zero or more, got %q", s)
+		}
+		p.MaxFailures = n
+		return nil
+	})
+	return p
+}
+
+// Limit is how many failures stop the run, 0 for none.
+func (p *Policy) Limit() int {
+	if p == nil {
+		return 0
+	}
+	if p.FailFast {
+		return 1
+	}
+	return p.MaxFailures
+}
+
+// Fail counts a failure and reports whether the run must stop now.
+func (p *Policy) Fail() bool {
+	if p == nil {
+		return false
+	}
+	p.failures++
+	return p.Stopped()
+}
+
+// Failures is how many failures were counted.
+func (p *Policy) Failures() int {
+	if p == nil {
+		return 0
+	}
+	return p.failures
+}
+
+// Stopped reports whether the failures reached the limit.
+func (p *Policy) Stopped() bool {
+	limit := p.Limit()
+	return limit > 0 && p.failures >= limit
+}
+
+// Reason says why the run stopped, for the checks it never ran.
+func (p *Policy) Reason() string {
+	if p.FailFast {
+		return "stopped at the first failure (--fail-fast)"
+	}
+	return fmt.Sprintf("stopped after %d failures (--max-failures %d)", p.failures, p.MaxFailures)
+}
+
+// Export sets FAIL_FAST and MAX_FAILURES to the policy, so child processes
+// follow it.
+func (p *Policy) Export() {
+	os.Setenv(EnvFailFast, strconv.FormatBool(p.FailFast))
+	os.Setenv(EnvMaxFailures, strconv.Itoa(p.MaxFailures))
+}
@@ -0,0 +1,68 @@
+package failfast
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func parse(t *testing.T, args ...string) (*Policy, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	p := FlagsOn(fs)
+	return p, fs.Parse(args)
+}
+
+func TestPolicy(t *testing.T) {
+	t.Setenv(EnvFailFast, "")
+	t.Setenv(EnvMaxFailures, "")
+
+	p, err := parse(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if p.Fail() {
+			t.Fatalf("stopped after %d failures with no limit", p.Failures())
+		}
+	}
+
+	p, _ = parse(t, "--fail-fast")
+	if !p.Fail() || p.Limit() != 1 {
+		t.Errorf("--fail-fast didn't stop at the first failure")
+	}
+
+	p, _ = parse(t, "--max-failures", "3")
+	if p.Fail() || p.Fail() || !p.Fail() {
+		t.Errorf("--max-failures 3 didn't stop at the third failure")
+	}
+	if want := "stopped after 3 failures (--max-failures 3)"; p.Reason() != want {
+		t.Errorf("Reason = %q, want %q", p.Reason(), want)
+	}
+
+	if _, err := parse(t, "--max-failures", "-1"); err == nil {
+		t.Error("accepted a negative --max-failures")
+	}
+
+	var none *Policy
+	if none.Fail() || none.Stopped() {
+		t.Error("a nil policy stopped")
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Setenv(EnvFailFast, "")
+	t.Setenv(EnvMaxFailures, "")
+	p, _ := parse(t, "--max-failures", "5")
+	p.Export()
+
+	child, _ := parse(t)
+	if child.Limit() != 5 {
+		t.Errorf("child limit %d, want 5", child.Limit())
+	}
+	child, _ = parse(t, "--fail-fast")
+	if child.Limit() != 1 {
+		t.Errorf("--fail-fast on top of MAX_FAILURES: limit %d, want 1", child.Limit())
+	}
+}
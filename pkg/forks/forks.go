@@ -0,0 +1,335 @@
+// Package forks finds, on a node with historical state, the block at which
+// each precompile and each precompile repricing became active. Every
+// behavior is observed at a series of heights, usually either side of the
+// configured fork blocks, and the switch between the last height where it
+// was missing and the first where it was present is then bisected down to
+// a single block.
+package forks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+// Fork names, in activation order.
+const (
+	Frontier  = "frontier"
+	Byzantium = "byzantium"
+	Istanbul  = "istanbul"
+	Berlin    = "berlin"
+	Cancun    = "cancun"
+	Prague    = "prague"
+)
+
+// Names lists the forks in activation order.
+var Names = []string{Frontier, Byzantium, Istanbul, Berlin, Cancun, Prague}
+
+// Blocks maps fork names to the block they activated at.
+type Blocks map[string]uint64
+
+// ParseBlocks parses a comma-separated list of name=block pairs.
+func ParseBlocks(spec string) (Blocks, error) {
+	b := Blocks{}
+	for _, pair := range strings.Split(spec, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, block, ok := strings.Cut(pair, "=")
+		n, err := strconv.ParseUint(strings.TrimSpace(block), 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid fork %q (want name=block)", pair)
+		}
+		b[strings.ToLower(strings.TrimSpace(name))] = n
+	}
+	return b, nil
+}
+
+// Observation is what a behavior looked like at one height.
+type Observation struct {
+	Block  uint64 `json:"block"`
+	Active bool   `json:"active"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Behavior is something a fork switched on: a precompile answering, or a
+// precompile charging its new price.
+type Behavior struct {
+	Name string
+	Fork string
+	EIP  int
+	// Observe reports whether the behavior is active at block. An error
+	// means the node couldn't be asked, not that the behavior is missing.
+	Observe func(ctx context.Context, client *ethclient.Client, block *big.Int) (active bool, detail string, err error)
+}
+
+// probes are inputs each precompile answers with something other than the
+// empty output of an account without code.
+var probes = map[string][]byte{
+	"ecrecover":        common.FromHex("18c547e4f7b0f325ad1e56f57e26c745b09a3e503d86e00e5255ff7f715d3d1c000000000000000000000000000000000000000000000000000000000000001c73b1693892219d736caba55bdb67216e485557ea6b6af75f37096c9aa6a5a75feeb940b1d03b21e36b0e47e79769f095fe2ab855bd91e3a38756b7d75a9c4549"),
+	"sha256":           []byte("abc"),
+	"ripemd160":        []byte("abc"),
+	"identity":         []byte("abc"),
+	"modexp":           modexpProbe,
+	"bn256add":         common.FromHex("0000000000000000000000000000000000000000000000000000000000000001000000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000002"),
+	"bn256mul":         common.FromHex("000000000000000000000000000000000000000000000000000000000000000100000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000002"),
+	"bn256pairing":     nil,
+	"blake2f":          common.FromHex("0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001"),
+	"point_evaluation": common.FromHex("01e798154708fe7789429634053cbf9f99b619f9f084048927333fce637f549b564c0a11a0f704f4fc3e8acfe0f8245f0ad1347b378fbf96e206da11a5d3630624d25032e67a7e6a4910df5834b8fe70e6bcfeeac0352434196bdf4b2485d5a18f59a8d2a1a625a17f3fea0fe5eb8c896db3764f3185481bc22f91b4aaffcca25f26936857bc3a7c2539ea8ec3a952b7873033e038326e87ed3e1276fd140253fa08e9fc25fb2d9a98527fc22a2c9612fbeafdad446cbc7bcdbdcd780af2c16a"),
+}
+
+// modexpProbe is EIP-198's first example, 3^(p-1) mod p for the secp256k1
+// field prime, whose price differs by an order of magnitude either side of
+// EIP-2565.
+var modexpProbe = common.FromHex("00000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000002003fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2efffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f")
+
+// introduced names the fork and EIP that added each precompile.
+var introduced = map[string]struct {
+	fork string
+	eip  int
+}{
+	"ecrecover":        {Frontier, 0},
+	"sha256":           {Frontier, 0},
+	"ripemd160":        {Frontier, 0},
+	"identity":         {Frontier, 0},
+	"modexp":           {Byzantium, 198},
+	"bn256add":         {Byzantium, 196},
+	"bn256mul":         {Byzantium, 196},
+	"bn256pairing":     {Byzantium, 197},
+	"blake2f":          {Istanbul, 152},
+	"point_evaluation": {Cancun, 4844},
+}
+
+// Behaviors lists the availability of every precompile up to Prague, then
+// the repricings of EIP-1108 and EIP-2565.
+func Behaviors() []Behavior {
+	bls := map[common.Address][]byte{}
+	for _, v := range precompile.BLSVectors() {
+		if _, ok := bls[v.Precompile.Address]; !ok {
+			bls[v.Precompile.Address] = v.Input
+		}
+	}
+
+	var out []Behavior
+	for _, p := range reference.Precompiles {
+		input, ok := probes[p.Name]
+		fork, eip := Prague, 2537
+		if ok {
+			fork, eip = introduced[p.Name].fork, introduced[p.Name].eip
+		} else {
+			input = bls[p.Address]
+		}
+		out = append(out, Behavior{Name: p.Name, Fork: fork, EIP: eip, Observe: answers(p, input)})
+	}
+	return append(out,
+		repricing("bn256add price", Istanbul, 1108, "bn256add", vm.PrecompiledContractsByzantium, vm.PrecompiledContractsIstanbul),
+		repricing("modexp price", Berlin, 2565, "modexp", vm.PrecompiledContractsIstanbul, vm.PrecompiledContractsBerlin),
+	)
+}
+
+// answers is active once p returns the reference answer to input.
+func answers(p reference.Precompile, input []byte) func(context.Context, *ethclient.Client, *big.Int) (bool, string, error) {
+	expected, err := p.Run(input)
+	if err != nil {
+		panic(fmt.Sprintf("probe of %s: %v", p.Name, err))
+	}
+	address := p.Address
+	return func(ctx context.Context, client *ethclient.Client, block *big.Int) (bool, string, error) {
+		out, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: input}, block)
+		// An account without code never fails a call, so a failure is
+		// either missing state or a broken precompile; neither dates it
+		switch {
+		case err != nil:
+			return false, "", err
+		case len(out) == 0:
+			return false, "no output", nil
+		case !bytes.Equal(out, expected):
+			return false, fmt.Sprintf("returned %x", out), nil
+		}
+		return true, "", nil
+	}
+}
+
+// repricing is active once the precompile called name charges what after
+// does for its probe, rather than what before does. The price is the
+// difference between the gas estimates of calling it and calling the
+// identity precompile, whose price never changed, with the same input: the
+// intrinsic cost, calldata included, cancels out.
+func repricing(behavior, fork string, eip int, name string, before, after map[common.Address]vm.PrecompiledContract) Behavior {
+	p, err := reference.ByName(name)
+	if err != nil {
+		panic(err)
+	}
+	input := probes[name]
+	identity := common.BytesToAddress([]byte{0x04})
+	oldGas, newGas := before[p.Address].RequiredGas(input), after[p.Address].RequiredGas(input)
+	identityGas := vm.PrecompiledContractsByzantium[identity].RequiredGas(input)
+	return Behavior{Name: behavior, Fork: fork, EIP: eip, Observe: func(ctx context.Context, client *ethclient.Client, block *big.Int) (bool, string, error) {
+		gas, err := EstimateAt(ctx, client, p.Address, input, block)
+		if err != nil {
+			return false, "", err
+		}
+		base, err := EstimateAt(ctx, client, identity, input, block)
+		if err != nil {
+			return false, "", err
+		}
+		price := int64(gas) - int64(base) + int64(identityGas)
+		switch price {
+		case int64(newGas):
+			return true, fmt.Sprintf("%d gas", price), nil
+		case int64(oldGas):
+			return false, fmt.Sprintf("%d gas", price), nil
+		}
+		return false, fmt.Sprintf("%d gas, expected %d before or %d after", price, oldGas, newGas), nil
+	}}
+}
+
+// EstimateAt is eth_estimateGas of a call to address with input, in the
+// state of block.
+func EstimateAt(ctx context.Context, client *ethclient.Client, address common.Address, input []byte, block *big.Int) (uint64, error) {
+	var gas hexutil.Uint64
+	args := map[string]any{"to": address, "input": hexutil.Bytes(input)}
+	if err := client.Client().CallContext(ctx, &gas, "eth_estimateGas", args, hexutil.EncodeBig(block)); err != nil {
+		return 0, err
+	}
+	return uint64(gas), nil
+}
+
+// Heights are the blocks to observe: the genesis, the head, and window
+// blocks either side of each fork block up to the head. Without fork
+// blocks, samples heights are spread evenly instead.
+func Heights(blocks Blocks, head, window uint64, samples int) []uint64 {
+	set := map[uint64]bool{0: true, head: true}
+	for _, b := range blocks {
+		lo := uint64(0)
+		if b > window {
+			lo = b - window
+		}
+		for h := lo; h <= b+window && h <= head; h++ {
+			set[h] = true
+		}
+	}
+	if len(blocks) == 0 {
+		for i := 1; i < samples; i++ {
+			set[head*uint64(i)/uint64(samples)] = true
+		}
+	}
+	heights := make([]uint64, 0, len(set))
+	for h := range set {
+		heights = append(heights, h)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	return heights
+}
+
+// Activation is where a behavior was found to switch on.
+type Activation struct {
+	Behavior string `json:"behavior"`
+	Fork     string `json:"fork"`
+	EIP      int    `json:"eip,omitempty"`
+	// ForkBlock is the configured block of Fork, if any.
+	ForkBlock *uint64 `json:"forkBlock,omitempty"`
+	// Block is the first block the behavior is active at, or without
+	// bisecting the first height observed active. It is nil when the
+	// behavior isn't active at the head, and 0 when it is active from the
+	// genesis.
+	Block *uint64 `json:"block,omitempty"`
+	// Inconsistent is set when the behavior was active at some height and
+	// missing at a later one, so there is no single activation block.
+	Inconsistent bool          `json:"inconsistent,omitempty"`
+	Observations []Observation `json:"observations"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// Agrees reports whether a configured fork block matches the observation:
+// the behavior switched on exactly there, or the fork isn't reached yet and
+// the behavior is missing. It is true when no block is configured.
+func (a Activation) Agrees(head uint64) bool {
+	switch {
+	case a.Error != "" || a.Inconsistent:
+		return false
+	case a.ForkBlock == nil:
+		return true
+	case *a.ForkBlock > head:
+		return a.Block == nil
+	}
+	return a.Block != nil && *a.Block == *a.ForkBlock
+}
+
+// Find observes b at every height and, with bisect, narrows the switch from
+// missing to active down to one block. heights must be sorted.
+func Find(ctx context.Context, client *ethclient.Client, b Behavior, blocks Blocks, heights []uint64, bisect bool) Activation {
+	a := Activation{Behavior: b.Name, Fork: b.Fork, EIP: b.EIP}
+	if n, ok := blocks[b.Fork]; ok {
+		a.ForkBlock = &n
+	}
+	observe := func(h uint64) (Observation, error) {
+		active, detail, err := b.Observe(ctx, client, new(big.Int).SetUint64(h))
+		o := Observation{Block: h, Active: active, Detail: detail}
+		if err != nil {
+			o.Error = err.Error()
+		}
+		return o, err
+	}
+
+	for _, h := range heights {
+		o, err := observe(h)
+		a.Observations = append(a.Observations, o)
+		if err != nil {
+			a.Error = fmt.Sprintf("block %d: %v", h, err)
+			return a
+		}
+	}
+
+	// The last missing observation before the first active one
+	lo, hi := -1, -1
+	for i, o := range a.Observations {
+		if o.Active && hi < 0 {
+			hi = i
+		}
+		if !o.Active && hi >= 0 {
+			a.Inconsistent = true
+			return a
+		}
+		if !o.Active {
+			lo = i
+		}
+	}
+	if hi < 0 {
+		return a
+	}
+	first := a.Observations[hi].Block
+	if lo >= 0 && bisect {
+		missing := a.Observations[lo].Block
+		for first-missing > 1 {
+			mid := missing + (first-missing)/2
+			o, err := observe(mid)
+			a.Observations = append(a.Observations, o)
+			if err != nil {
+				a.Error = fmt.Sprintf("block %d: %v", mid, err)
+				return a
+			}
+			if o.Active {
+				first = mid
+			} else {
+				missing = mid
+			}
+		}
+	}
+	a.Block = &first
+	return a
+}
@@ -0,0 +1,177 @@
+package forks
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+// forkedNode answers eth_call with the reference implementation once the
+// precompile's fork is reached, and eth_estimateGas with the intrinsic cost
+// plus the price of the precompile set in force at the block.
+func forkedNode(t *testing.T, blocks Blocks) (*ethclient.Client, *mockrpc.Server) {
+	t.Helper()
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	introducedBy := map[common.Address]string{}
+	for _, b := range behaviors {
+		if p, err := reference.ByName(b.Name); err == nil {
+			introducedBy[p.Address] = b.Fork
+		}
+	}
+	active := func(c mockrpc.Call, address common.Address) (uint64, bool) {
+		var block hexutil.Big
+		if err := c.Param(1, &block); err != nil {
+			t.Fatal(err)
+		}
+		n := (*big.Int)(&block).Uint64()
+		fork, ok := blocks[introducedBy[address]]
+		return n, introducedBy[address] == Frontier || ok && n >= fork
+	}
+	type args struct {
+		To    common.Address `json:"to"`
+		Input hexutil.Bytes  `json:"input"`
+	}
+	s.Handle("eth_call", func(c mockrpc.Call) (any, error) {
+		var a args
+		if err := c.Param(0, &a); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := active(c, a.To); !ok {
+			return hexutil.Bytes{}, nil
+		}
+		out, err := reference.Run(a.To, a.Input)
+		return hexutil.Bytes(out), err
+	})
+	s.Handle("eth_estimateGas", func(c mockrpc.Call) (any, error) {
+		var a args
+		if err := c.Param(0, &a); err != nil {
+			t.Fatal(err)
+		}
+		gas := uint64(21000 + 16*len(a.Input))
+		n, ok := active(c, a.To)
+		if !ok {
+			return hexutil.Uint64(gas), nil
+		}
+		set := vm.PrecompiledContractsByzantium
+		if b, ok := blocks[Berlin]; ok && n >= b {
+			set = vm.PrecompiledContractsBerlin
+		} else if b, ok := blocks[Istanbul]; ok && n >= b {
+			set = vm.PrecompiledContractsIstanbul
+		}
+		return hexutil.Uint64(gas + set[a.To].RequiredGas(a.Input)), nil
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client, s
+}
+
+// behaviors is built once; building it runs every probe through the
+// reference implementations.
+var behaviors = Behaviors()
+
+func behavior(t *testing.T, name string) Behavior {
+	t.Helper()
+	for _, b := range behaviors {
+		if b.Name == name {
+			return b
+		}
+	}
+	t.Fatalf("no behavior %q", name)
+	return Behavior{}
+}
+
+func TestFindBisectsToTheForkBlock(t *testing.T) {
+	blocks := Blocks{Byzantium: 370, Istanbul: 906, Berlin: 1224}
+	client, _ := forkedNode(t, blocks)
+	heights := Heights(nil, 2000, 0, 4)
+
+	for name, want := range map[string]uint64{
+		"sha256":         0,
+		"modexp":         370,
+		"blake2f":        906,
+		"bn256add":       370,
+		"bn256add price": 906,
+		"modexp price":   1224,
+	} {
+		a := Find(context.Background(), client, behavior(t, name), blocks, heights, true)
+		if a.Error != "" || a.Inconsistent || a.Block == nil || *a.Block != want {
+			t.Errorf("%s: %+v, want activation at %d", name, a, want)
+			continue
+		}
+		if !a.Agrees(2000) {
+			t.Errorf("%s disagrees with its fork block", name)
+		}
+	}
+
+	a := Find(context.Background(), client, behavior(t, "point_evaluation"), blocks, heights, true)
+	if a.Block != nil || !a.Agrees(2000) {
+		t.Errorf("point_evaluation without cancun: %+v", a)
+	}
+}
+
+func TestFindWithoutBisecting(t *testing.T) {
+	client, _ := forkedNode(t, Blocks{Istanbul: 906})
+	a := Find(context.Background(), client, behavior(t, "blake2f"), Blocks{Istanbul: 900}, []uint64{0, 500, 1000, 2000}, false)
+	if a.Block == nil || *a.Block != 1000 || len(a.Observations) != 4 {
+		t.Errorf("%+v, want the first active height without extra calls", a)
+	}
+	if a.Agrees(2000) {
+		t.Error("activation at 1000 agrees with a configured 900")
+	}
+}
+
+func TestFindInconsistent(t *testing.T) {
+	flip := Behavior{Name: "flip", Observe: func(_ context.Context, _ *ethclient.Client, block *big.Int) (bool, string, error) {
+		return block.Uint64() == 10, "", nil
+	}}
+	a := Find(context.Background(), nil, flip, nil, []uint64{0, 10, 20}, true)
+	if !a.Inconsistent || a.Block != nil || a.Agrees(20) {
+		t.Errorf("%+v, want an inconsistent activation", a)
+	}
+}
+
+func TestHeights(t *testing.T) {
+	got := Heights(Blocks{Byzantium: 10, Istanbul: 99}, 100, 1, 0)
+	if want := []uint64{0, 9, 10, 11, 98, 99, 100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("around forks: %v, want %v", got, want)
+	}
+	got = Heights(nil, 100, 1, 4)
+	if want := []uint64{0, 25, 50, 75, 100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("evenly spread: %v, want %v", got, want)
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	b, err := ParseBlocks("Byzantium=4370000, istanbul=9069000")
+	if err != nil || !reflect.DeepEqual(b, Blocks{Byzantium: 4370000, Istanbul: 9069000}) {
+		t.Errorf("%v, %v", b, err)
+	}
+	if _, err := ParseBlocks("berlin"); err == nil {
+		t.Error("fork without a block accepted")
+	}
+}
+
+func TestBehaviorsCoverEveryPrecompile(t *testing.T) {
+	names := map[string]bool{}
+	for _, b := range behaviors {
+		names[b.Name] = true
+	}
+	for _, p := range reference.Precompiles {
+		if !names[p.Name] {
+			t.Errorf("no behavior for %s", p.Name)
+		}
+	}
+}
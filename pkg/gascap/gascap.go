@@ -0,0 +1,191 @@
+// Package gascap discovers how large an input a node's eth_call accepts for
+// a precompile. Calls are bounded by the node's RPC gas cap, and very large
+// ones by its request size limit; vectors beyond what the node accepts are
+// skipped with a reason rather than reported as failures.
+package gascap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// DefaultPath is where the probe saves its report for the other scripts.
+const DefaultPath = "results_gascap.json"
+
+// Target is a precompile whose cost grows with its input.
+type Target struct {
+	Name    string
+	Address common.Address
+	// Gas is the precompile's own cost for an input of n bytes.
+	Gas func(n int) uint64
+}
+
+func words(n int) uint64 { return uint64(n+31) / 32 }
+
+// Identity and SHA256 are the precompiles probed by default: both accept
+// any input and are cheap per byte, so calldata dominates their cost.
+var (
+	Identity = Target{Name: "identity", Address: common.HexToAddress("0x04"), Gas: func(n int) uint64 {
+		return params.IdentityBaseGas + params.IdentityPerWordGas*words(n)
+	}}
+	SHA256 = Target{Name: "sha256", Address: common.HexToAddress("0x02"), Gas: func(n int) uint64 {
+		return params.Sha256BaseGas + params.Sha256PerWordGas*words(n)
+	}}
+)
+
+// Targets lists the probeable precompiles by name.
+var Targets = map[string]Target{Identity.Name: Identity, SHA256.Name: SHA256}
+
+// CallGas is the gas of a top-level eth_call of t with input: the intrinsic
+// transaction cost, calldata included, plus the precompile's.
+func (t Target) CallGas(input []byte) uint64 {
+	gas := params.TxGas
+	for _, b := range input {
+		if b == 0 {
+			gas += params.TxDataZeroGas
+		} else {
+			gas += params.TxDataNonZeroGasEIP2028
+		}
+	}
+	return gas + t.Gas(len(input))
+}
+
+// Result is what the probe found for one target.
+type Result struct {
+	Target string `json:"target"`
+	// MaxInput is the largest input the node answered, in bytes, and
+	// MaxGas the gas of that call; the cap lies between MaxGas and the gas
+	// of FailedInput.
+	MaxInput int    `json:"maxInputBytes"`
+	MaxGas   uint64 `json:"maxGas"`
+	// FailedInput is the smallest input found to fail, 0 if every input up
+	// to the limit was answered; Reason is the node's error for it.
+	FailedInput int    `json:"failedInputBytes,omitempty"`
+	FailedGas   uint64 `json:"failedGas,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Calls       int    `json:"calls"`
+}
+
+// Capped reports whether the probe hit a limit at all.
+func (r Result) Capped() bool { return r.FailedInput > 0 }
+
+// SkipReason explains why input to the target would exceed what the node
+// accepts, or returns "" if it fits.
+func (r Result) SkipReason(input []byte) string {
+	if !r.Capped() || len(input) <= r.MaxInput {
+		return ""
+	}
+	return fmt.Sprintf("%d-byte input exceeds the largest the node accepts for %s (%d bytes, %d gas): %s",
+		len(input), r.Target, r.MaxInput, r.MaxGas, r.Reason)
+}
+
+// Probe doubles an input of 0xff bytes, the most expensive calldata, from
+// start until the node fails the call or limit is reached, then bisects
+// down to resolution bytes. The context being cancelled is an error, any
+// other call failure is taken as the limit.
+func Probe(ctx context.Context, client *ethclient.Client, t Target, start, limit, resolution int) (Result, error) {
+	r := Result{Target: t.Name}
+	call := func(n int) (bool, error) {
+		input := make([]byte, n)
+		for i := range input {
+			input[i] = 0xff
+		}
+		r.Calls++
+		to := t.Address
+		_, err := client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: input}, nil)
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		if err != nil {
+			r.FailedInput, r.FailedGas, r.Reason = n, t.CallGas(input), err.Error()
+			return false, nil
+		}
+		r.MaxInput, r.MaxGas = n, t.CallGas(input)
+		return true, nil
+	}
+
+	if start <= 0 || limit < start {
+		return r, errors.New("probe start must be positive and at most the limit")
+	}
+	// The failure recorded last is always hi, the smallest known to fail
+	lo, hi := 0, 0
+	for n := start; ; n *= 2 {
+		if n > limit {
+			n = limit
+		}
+		ok, err := call(n)
+		if err != nil {
+			return r, err
+		}
+		if !ok {
+			hi = n
+			break
+		}
+		lo = n
+		if n == limit {
+			return r, nil
+		}
+	}
+	for hi-lo > max(resolution, 1) {
+		mid := lo + (hi-lo)/2
+		ok, err := call(mid)
+		if err != nil {
+			return r, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return r, nil
+}
+
+// Report is the saved outcome of a probe run.
+type Report struct {
+	RPCURL    string            `json:"rpcUrl"`
+	Targets   map[string]Result `json:"targets"`
+	Timestamp string            `json:"timestamp"`
+}
+
+// Load reads the report at path. A missing file, or one probed against
+// another node, gives nil: nothing is known and nothing is skipped.
+func Load(path, rpcURL string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gas cap report: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse gas cap report %s: %w", path, err)
+	}
+	if r.RPCURL != rpcURL {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+// SkipReason explains why input to the named target exceeds what the node
+// accepts, or returns "" if it fits or the target wasn't probed. A nil
+// report skips nothing.
+func (r *Report) SkipReason(target string, input []byte) string {
+	if r == nil {
+		return ""
+	}
+	res, ok := r.Targets[target]
+	if !ok {
+		return ""
+	}
+	return res.SkipReason(input)
+}
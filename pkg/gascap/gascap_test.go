@@ -0,0 +1,111 @@
+package gascap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+// capped answers calls of up to maxInput bytes and fails larger ones.
+func capped(t *testing.T, maxInput int) *ethclient.Client {
+	t.Helper()
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	s.Handle("eth_call", func(c mockrpc.Call) (any, error) {
+		var args struct {
+			Input hexutil.Bytes `json:"input"`
+		}
+		if err := c.Param(0, &args); err != nil {
+			return nil, err
+		}
+		if len(args.Input) > maxInput {
+			return nil, errors.New("gas required exceeds allowance (50000000)")
+		}
+		return args.Input, nil
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestProbe(t *testing.T) {
+	client := capped(t, 5000)
+	r, err := Probe(context.Background(), client, Identity, 1024, 1<<20, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MaxInput > 5000 || r.MaxInput < 5000-32 || r.FailedInput <= 5000 || r.FailedInput-r.MaxInput > 32 {
+		t.Errorf("max %d, failed at %d, want a 32-byte bracket around 5000", r.MaxInput, r.FailedInput)
+	}
+	if !strings.Contains(r.Reason, "exceeds allowance") || r.MaxGas >= r.FailedGas {
+		t.Errorf("result %+v", r)
+	}
+
+	if reason := r.SkipReason(make([]byte, 6000)); !strings.Contains(reason, "6000-byte input") {
+		t.Errorf("oversized input not skipped: %q", reason)
+	}
+	if reason := r.SkipReason(make([]byte, 100)); reason != "" {
+		t.Errorf("small input skipped: %q", reason)
+	}
+}
+
+func TestProbeUncapped(t *testing.T) {
+	client := capped(t, 1<<30)
+	r, err := Probe(context.Background(), client, SHA256, 1024, 10000, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Capped() || r.MaxInput != 10000 || r.Calls != 5 {
+		t.Errorf("result %+v, want every size up to the limit answered in 5 calls", r)
+	}
+	if reason := r.SkipReason(make([]byte, 20000)); reason != "" {
+		t.Errorf("input skipped without a known cap: %q", reason)
+	}
+}
+
+func TestCallGas(t *testing.T) {
+	// 21000 intrinsic, 4 zero bytes at 4 and 28 at 16, 60+12 for one word
+	input := make([]byte, 32)
+	for i := 4; i < 32; i++ {
+		input[i] = 1
+	}
+	if got, want := SHA256.CallGas(input), uint64(21000+4*4+28*16+72); got != want {
+		t.Errorf("gas %d, want %d", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gascap.json")
+	if r, err := Load(path, "http://node:8545"); r != nil || err != nil {
+		t.Fatalf("missing file: %v, %v", r, err)
+	}
+
+	data, _ := json.Marshal(Report{RPCURL: "http://node:8545", Targets: map[string]Result{
+		"sha256": {Target: "sha256", MaxInput: 100, FailedInput: 101, Reason: "out of gas"},
+	}})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Load(path, "http://node:8545")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SkipReason("sha256", make([]byte, 200)) == "" || r.SkipReason("identity", make([]byte, 200)) != "" {
+		t.Error("skip reasons don't follow the probed targets")
+	}
+	if other, _ := Load(path, "http://other:8545"); other != nil {
+		t.Error("report of another node was used")
+	}
+}
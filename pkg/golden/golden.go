@@ -0,0 +1,143 @@
+// Package golden stores the expected gas of canonical vectors per cdk-erigon
+// fork ID, so gas repricing between node releases shows up as a golden file
+// mismatch instead of passing silently.
+package golden
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// Check outcomes.
+const (
+	StatusMatch    = "match"
+	StatusMismatch = "mismatch"
+	StatusNew      = "new"
+)
+
+// File is the golden gas table of one fork.
+type File struct {
+	// Label identifies the fork, "fork<ID>" on zkEVM nodes or "chain<ID>"
+	// when the node has no fork ID.
+	Label   string            `json:"label"`
+	Updated string            `json:"updated"`
+	Gas     map[string]uint64 `json:"gas"`
+
+	path    string
+	changed bool
+}
+
+// Check is the comparison of one observed gas value with its golden value.
+type Check struct {
+	Key      string `json:"key"`
+	Expected uint64 `json:"expected,omitempty"`
+	Observed uint64 `json:"observed"`
+	Status   string `json:"status"`
+}
+
+// ForkLabel asks the node for its zkEVM fork ID (zkevm_getForkId) and falls
+// back to the chain ID for nodes that don't expose one.
+func ForkLabel(ctx context.Context, client *ethclient.Client) (string, error) {
+	var forkID hexutil.Uint64
+	if err := client.Client().CallContext(ctx, &forkID, "zkevm_getForkId"); err == nil {
+		return fmt.Sprintf("fork%d", uint64(forkID)), nil
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get fork ID or chain ID: %w", err)
+	}
+	return fmt.Sprintf("chain%d", chainID), nil
+}
+
+// Load reads <dir>/gas_<label>.json, returning an empty table if it doesn't
+// exist yet.
+func Load(dir, label string) (*File, error) {
+	path := filepath.Join(dir, "gas_"+label+".json")
+	f := &File{Label: label, Gas: map[string]uint64{}, path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden file: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse golden file %s: %w", path, err)
+	}
+	if f.Gas == nil {
+		f.Gas = map[string]uint64{}
+	}
+	return f, nil
+}
+
+// Path returns the file the table is loaded from and saved to.
+func (f *File) Path() string { return f.path }
+
+// Key names the golden value of calling method with input, e.g.
+// "sha256Hash:0x68656c6c6f". The input is hex so binary inputs give
+// readable, stable keys.
+func Key(method string, input []byte) string {
+	return method + ":" + hexutil.Encode(input)
+}
+
+// Check compares observed with the golden value for key. With update set the
+// observed value is recorded as the new golden value.
+func (f *File) Check(key string, observed uint64, update bool) Check {
+	c := Check{Key: key, Observed: observed}
+	expected, ok := f.Gas[key]
+	switch {
+	case !ok:
+		c.Status = StatusNew
+	case expected == observed:
+		c.Status = StatusMatch
+		c.Expected = expected
+	default:
+		c.Status = StatusMismatch
+		c.Expected = expected
+	}
+
+	if update && c.Status != StatusMatch {
+		f.Gas[key] = observed
+		f.changed = true
+	}
+	return c
+}
+
+// Save writes the table back if Check recorded any change.
+func (f *File) Save() error {
+	if !f.changed {
+		return nil
+	}
+	f.Updated = time.Now().UTC().Format(time.RFC3339)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal golden file: %w", err)
+	}
+	if err := paths.WriteFile(f.path, data); err != nil {
+		return fmt.Errorf("failed to save golden file: %w", err)
+	}
+	f.changed = false
+	return nil
+}
+
+// Keys lists the golden keys in sorted order.
+func (f *File) Keys() []string {
+	keys := make([]string, 0, len(f.Gas))
+	for k := range f.Gas {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,79 @@
+package golden
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestKey(t *testing.T) {
+	for input, want := range map[string]string{
+		"":           "sha256Hash:0x",
+		"hello":      "sha256Hash:0x68656c6c6f",
+		"\x00\xff\"": "sha256Hash:0x00ff22",
+	} {
+		if got := Key("sha256Hash", []byte(input)); got != want {
+			t.Errorf("Key(%q) = %s, want %s", input, got, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f, err := Load(t.TempDir(), "fork12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := f.Check("a", 100, false); c.Status != StatusNew {
+		t.Errorf("unknown key without update: %+v", c)
+	}
+	if c := f.Check("a", 100, true); c.Status != StatusNew {
+		t.Errorf("unknown key with update: %+v", c)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = Load(filepath.Dir(f.Path()), "fork12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := f.Check("a", 100, false); c.Status != StatusMatch || c.Expected != 100 {
+		t.Errorf("recorded key: %+v", c)
+	}
+	if c := f.Check("a", 101, false); c.Status != StatusMismatch || c.Expected != 100 {
+		t.Errorf("repriced key: %+v", c)
+	}
+	if f.Gas["a"] != 100 {
+		t.Errorf("a mismatch without update changed the golden value to %d", f.Gas["a"])
+	}
+}
+
+// The committed golden files must load under their own label and use hex
+// keys.
+func TestCommittedFiles(t *testing.T) {
+	files, err := filepath.Glob("../../golden/gas_*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no golden files committed")
+	}
+	for _, path := range files {
+		label := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "gas_"), ".json")
+		f, err := Load(filepath.Dir(path), label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Label != label || len(f.Gas) == 0 {
+			t.Errorf("%s: label %q with %d values", path, f.Label, len(f.Gas))
+		}
+		for key, gas := range f.Gas {
+			method, input, ok := strings.Cut(key, ":")
+			if _, err := hexutil.Decode(input); !ok || method == "" || err != nil || gas < 21000 {
+				t.Errorf("%s: invalid entry %s = %d", path, key, gas)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package groth16
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Circuit is a rank-1 constraint system: each constraint requires
+// <A, w> * <B, w> = <C, w> of the witness w. Variable 0 is the constant
+// one, followed by the public inputs and then the private variables.
+type Circuit struct {
+	Name        string
+	Vars        int
+	Public      int
+	Constraints []Constraint
+}
+
+// Constraint holds the coefficients of one constraint, by variable.
+type Constraint struct {
+	A, B, C map[int]int64
+}
+
+// Cubic is the toy circuit "I know x with x³ + x + 5 = out", out public.
+// Its variables are one, out, x, x², x³ and x³ + x.
+var Cubic = Circuit{
+	Name:   "x^3 + x + 5 = out",
+	Vars:   6,
+	Public: 1,
+	Constraints: []Constraint{
+		{A: map[int]int64{2: 1}, B: map[int]int64{2: 1}, C: map[int]int64{3: 1}},
+		{A: map[int]int64{3: 1}, B: map[int]int64{2: 1}, C: map[int]int64{4: 1}},
+		{A: map[int]int64{4: 1, 2: 1}, B: map[int]int64{0: 1}, C: map[int]int64{5: 1}},
+		{A: map[int]int64{5: 1, 0: 5}, B: map[int]int64{0: 1}, C: map[int]int64{1: 1}},
+	},
+}
+
+// CubicWitness is the witness of Cubic for x.
+func CubicWitness(x *big.Int) []*big.Int {
+	x = mod(x)
+	x2 := mod(new(big.Int).Mul(x, x))
+	x3 := mod(new(big.Int).Mul(x2, x))
+	sum := mod(new(big.Int).Add(x3, x))
+	out := mod(new(big.Int).Add(sum, big.NewInt(5)))
+	return []*big.Int{big.NewInt(1), out, x, x2, x3, sum}
+}
+
+// Check reports the first constraint w doesn't satisfy.
+func (c Circuit) Check(w []*big.Int) error {
+	if len(w) != c.Vars {
+		return fmt.Errorf("witness has %d variables, circuit %d", len(w), c.Vars)
+	}
+	for i, k := range c.Constraints {
+		a, b := dot(k.A, w), dot(k.B, w)
+		if mod(a.Mul(a, b)).Cmp(dot(k.C, w)) != 0 {
+			return fmt.Errorf("constraint %d not satisfied", i)
+		}
+	}
+	return nil
+}
+
+// columns returns, for each variable, its coefficients in the A, B and C
+// of every constraint.
+func (c Circuit) columns() (a, b, cc [][]*big.Int) {
+	column := func(pick func(Constraint) map[int]int64) [][]*big.Int {
+		cols := make([][]*big.Int, c.Vars)
+		for i := range cols {
+			cols[i] = make([]*big.Int, len(c.Constraints))
+			for j, k := range c.Constraints {
+				cols[i][j] = mod(big.NewInt(pick(k)[i]))
+			}
+		}
+		return cols
+	}
+	return column(func(k Constraint) map[int]int64 { return k.A }),
+		column(func(k Constraint) map[int]int64 { return k.B }),
+		column(func(k Constraint) map[int]int64 { return k.C })
+}
+
+func dot(coeffs map[int]int64, w []*big.Int) *big.Int {
+	sum := new(big.Int)
+	for i, c := range coeffs {
+		sum.Add(sum, new(big.Int).Mul(big.NewInt(c), w[i]))
+	}
+	return mod(sum)
+}
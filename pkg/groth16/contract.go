@@ -0,0 +1,102 @@
+package groth16
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// abiJSON is the ABI of contracts/Groth16Verifier.sol, whose circuit has
+// one public input.
+const abiJSON = `[
+{"type":"function","name":"verifyProof","stateMutability":"view",
+"inputs":[{"name":"a","type":"uint256[2]"},{"name":"b","type":"uint256[2][2]"},{"name":"c","type":"uint256[2]"},{"name":"input","type":"uint256[1]"}],
+"outputs":[{"name":"","type":"bool"}]},
+{"type":"function","name":"verifyBatch","stateMutability":"view",
+"inputs":[{"name":"a","type":"uint256[2][]"},{"name":"b","type":"uint256[2][2][]"},{"name":"c","type":"uint256[2][]"},{"name":"input","type":"uint256[1][]"}],
+"outputs":[{"name":"valid","type":"bool[]"}]}]`
+
+// ABI is the parsed Groth16Verifier ABI.
+var ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// PackVerify returns the verifyProof calldata of d.
+func PackVerify(d Calldata) ([]byte, error) {
+	input, err := input1(d)
+	if err != nil {
+		return nil, err
+	}
+	return ABI.Pack("verifyProof", d.A, d.B, d.C, input)
+}
+
+// PackBatch returns the verifyBatch calldata of ds.
+func PackBatch(ds []Calldata) ([]byte, error) {
+	as := make([][2]*big.Int, len(ds))
+	bs := make([][2][2]*big.Int, len(ds))
+	cs := make([][2]*big.Int, len(ds))
+	inputs := make([][1]*big.Int, len(ds))
+	for i, d := range ds {
+		input, err := input1(d)
+		if err != nil {
+			return nil, fmt.Errorf("proof %d: %w", i, err)
+		}
+		as[i], bs[i], cs[i], inputs[i] = d.A, d.B, d.C, input
+	}
+	return ABI.Pack("verifyBatch", as, bs, cs, inputs)
+}
+
+func input1(d Calldata) ([1]*big.Int, error) {
+	if len(d.Input) != 1 {
+		return [1]*big.Int{}, fmt.Errorf("the verifier takes 1 public input, got %d", len(d.Input))
+	}
+	return [1]*big.Int{d.Input[0]}, nil
+}
+
+// Call asks the verifier at contract whether d verifies.
+func Call(ctx context.Context, client *ethclient.Client, contract common.Address, d Calldata) (bool, error) {
+	data, err := PackVerify(d)
+	if err != nil {
+		return false, err
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return false, err
+	}
+	values, err := ABI.Unpack("verifyProof", out)
+	if err != nil {
+		return false, fmt.Errorf("unexpected verifyProof output %x: %v", out, err)
+	}
+	return values[0].(bool), nil
+}
+
+// CallBatch asks the verifier at contract which of ds verify, in one call.
+func CallBatch(ctx context.Context, client *ethclient.Client, contract common.Address, ds []Calldata) ([]bool, error) {
+	data, err := PackBatch(ds)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := ABI.Unpack("verifyBatch", out)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected verifyBatch output %x: %v", out, err)
+	}
+	valid := values[0].([]bool)
+	if len(valid) != len(ds) {
+		return nil, fmt.Errorf("verifyBatch answered %d verdicts for %d proofs", len(valid), len(ds))
+	}
+	return valid, nil
+}
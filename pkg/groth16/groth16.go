@@ -0,0 +1,282 @@
+// Package groth16 proves and verifies statements of a toy circuit with
+// Groth16 over bn256, the curve of the ecAdd, ecMul and ecPairing
+// precompiles, and renders the Solidity verifier of its key
+// (contracts/Groth16Verifier.sol). Verifying a proof on-chain runs the
+// three precompiles together the way rollup and privacy verifiers do.
+//
+// The setup derives its trapdoor from a public seed, so anyone can forge
+// proofs: it tests the EVM, not the statement.
+package groth16
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/bn256"
+)
+
+// ToySeed is the seed of the setup behind contracts/Groth16Verifier.sol.
+const ToySeed = "cdk-erigon-precompile groth16 toy setup"
+
+// ProvingKey is what the prover needs of the setup.
+type ProvingKey struct {
+	Alpha1, Beta1, Delta1 *bn256.G1
+	Beta2, Delta2         *bn256.G2
+	// A, B1 and B2 are each variable's A and B polynomials at the
+	// trapdoor, in G1 and G2.
+	A, B1 []*bn256.G1
+	B2    []*bn256.G2
+	// K combines the polynomials of each private variable, over delta.
+	K []*bn256.G1
+	// Z are the powers of the trapdoor times the vanishing polynomial, over
+	// delta, for the quotient.
+	Z []*bn256.G1
+}
+
+// VerifyingKey is what the verifier needs of the setup. IC has one point
+// for the constant one and one per public input.
+type VerifyingKey struct {
+	Alpha1                *bn256.G1
+	Beta2, Gamma2, Delta2 *bn256.G2
+	IC                    []*bn256.G1
+}
+
+// Proof is a Groth16 proof.
+type Proof struct {
+	A *bn256.G1
+	B *bn256.G2
+	C *bn256.G1
+}
+
+// Setup generates the keys of c with a trapdoor derived from seed.
+func Setup(c Circuit, seed string) (*ProvingKey, *VerifyingKey) {
+	scalar := func(label string) *big.Int {
+		sum := sha256.Sum256([]byte(seed + "/" + label))
+		k := mod(new(big.Int).SetBytes(sum[:]))
+		if k.Sign() == 0 {
+			k.SetInt64(1)
+		}
+		return k
+	}
+	tau, alpha, beta, gamma, delta := scalar("tau"), scalar("alpha"), scalar("beta"), scalar("gamma"), scalar("delta")
+	gammaInv := new(big.Int).ModInverse(gamma, Order)
+	deltaInv := new(big.Int).ModInverse(delta, Order)
+
+	pk := &ProvingKey{
+		Alpha1: g1(alpha), Beta1: g1(beta), Delta1: g1(delta),
+		Beta2: g2(beta), Delta2: g2(delta),
+	}
+	vk := &VerifyingKey{Alpha1: pk.Alpha1, Beta2: pk.Beta2, Gamma2: g2(gamma), Delta2: pk.Delta2}
+
+	as, bs, cs := c.columns()
+	for i := 0; i < c.Vars; i++ {
+		u, v, w := interpolate(as[i]).eval(tau), interpolate(bs[i]).eval(tau), interpolate(cs[i]).eval(tau)
+		pk.A = append(pk.A, g1(u))
+		pk.B1 = append(pk.B1, g1(v))
+		pk.B2 = append(pk.B2, g2(v))
+
+		combined := new(big.Int).Mul(beta, u)
+		combined.Add(combined, new(big.Int).Mul(alpha, v))
+		combined = mod(combined.Add(combined, w))
+		if i <= c.Public {
+			vk.IC = append(vk.IC, g1(mod(combined.Mul(combined, gammaInv))))
+		} else {
+			pk.K = append(pk.K, g1(mod(combined.Mul(combined, deltaInv))))
+		}
+	}
+
+	n := len(c.Constraints)
+	z := mod(new(big.Int).Mul(vanishing(n).eval(tau), deltaInv))
+	for j := 0; j <= n-2; j++ {
+		pk.Z = append(pk.Z, g1(z))
+		z = mod(z.Mul(z, tau))
+	}
+	return pk, vk
+}
+
+// Toy returns the keys of Cubic from ToySeed, those of the verifier
+// contract.
+func Toy() (*ProvingKey, *VerifyingKey) {
+	return Setup(Cubic, ToySeed)
+}
+
+// Prove proves that w satisfies c, blinding the proof with randomness
+// from random (crypto/rand if nil).
+func Prove(pk *ProvingKey, c Circuit, w []*big.Int, random io.Reader) (*Proof, error) {
+	if err := c.Check(w); err != nil {
+		return nil, err
+	}
+	if random == nil {
+		random = rand.Reader
+	}
+
+	// The quotient of A*B - C by the vanishing polynomial
+	as, bs, cs := c.columns()
+	combine := func(cols [][]*big.Int) poly {
+		values := make([]*big.Int, len(c.Constraints))
+		for j := range values {
+			values[j] = new(big.Int)
+			for i, col := range cols {
+				values[j].Add(values[j], new(big.Int).Mul(w[i], col[j]))
+			}
+			mod(values[j])
+		}
+		return interpolate(values)
+	}
+	h, err := combine(as).mul(combine(bs)).sub(combine(cs)).div(vanishing(len(c.Constraints)))
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := rand.Int(random, Order)
+	if err != nil {
+		return nil, err
+	}
+	s, err := rand.Int(random, Order)
+	if err != nil {
+		return nil, err
+	}
+
+	a := new(bn256.G1).Set(pk.Alpha1)
+	b2 := new(bn256.G2).Set(pk.Beta2)
+	b1 := new(bn256.G1).Set(pk.Beta1)
+	for i := range w {
+		a.Add(a, new(bn256.G1).ScalarMult(pk.A[i], w[i]))
+		b2.Add(b2, new(bn256.G2).ScalarMult(pk.B2[i], w[i]))
+		b1.Add(b1, new(bn256.G1).ScalarMult(pk.B1[i], w[i]))
+	}
+	a.Add(a, new(bn256.G1).ScalarMult(pk.Delta1, r))
+	b2.Add(b2, new(bn256.G2).ScalarMult(pk.Delta2, s))
+	b1.Add(b1, new(bn256.G1).ScalarMult(pk.Delta1, s))
+
+	cp := new(bn256.G1).ScalarMult(a, s)
+	cp.Add(cp, new(bn256.G1).ScalarMult(b1, r))
+	cp.Add(cp, new(bn256.G1).ScalarMult(pk.Delta1, mod(new(big.Int).Neg(new(big.Int).Mul(r, s)))))
+	for i, k := range pk.K {
+		cp.Add(cp, new(bn256.G1).ScalarMult(k, w[c.Public+1+i]))
+	}
+	for j, z := range pk.Z {
+		if j < len(h) {
+			cp.Add(cp, new(bn256.G1).ScalarMult(z, h[j]))
+		}
+	}
+	return &Proof{A: a, B: b2, C: cp}, nil
+}
+
+// PublicInputs returns the public inputs of witness w of c.
+func (c Circuit) PublicInputs(w []*big.Int) []*big.Int {
+	return w[1 : c.Public+1]
+}
+
+// Calldata is a proof and its public inputs as the verifier contract takes
+// them: points as coordinates, G2 with the imaginary part first.
+type Calldata struct {
+	A     [2]*big.Int
+	B     [2][2]*big.Int
+	C     [2]*big.Int
+	Input []*big.Int
+}
+
+// Calldata returns the proof with public inputs input.
+func (p *Proof) Calldata(input []*big.Int) Calldata {
+	a, b, c := words(p.A.Marshal()), words(p.B.Marshal()), words(p.C.Marshal())
+	return Calldata{
+		A:     [2]*big.Int{a[0], a[1]},
+		B:     [2][2]*big.Int{{b[0], b[1]}, {b[2], b[3]}},
+		C:     [2]*big.Int{c[0], c[1]},
+		Input: append([]*big.Int(nil), input...),
+	}
+}
+
+// Copy returns a deep copy of d, to tamper with.
+func (d Calldata) Copy() Calldata {
+	cp := func(x *big.Int) *big.Int { return new(big.Int).Set(x) }
+	out := Calldata{
+		A: [2]*big.Int{cp(d.A[0]), cp(d.A[1])},
+		B: [2][2]*big.Int{{cp(d.B[0][0]), cp(d.B[0][1])}, {cp(d.B[1][0]), cp(d.B[1][1])}},
+		C: [2]*big.Int{cp(d.C[0]), cp(d.C[1])},
+	}
+	for _, x := range d.Input {
+		out.Input = append(out.Input, cp(x))
+	}
+	return out
+}
+
+// ErrInvalid is returned by Verify for a proof that doesn't verify.
+var ErrInvalid = errors.New("proof does not verify")
+
+// Verify checks d against vk as the verifier contract does: coordinates
+// and public inputs out of their fields are rejected, as are points off
+// the curve.
+func Verify(vk *VerifyingKey, d Calldata) error {
+	if len(d.Input) != len(vk.IC)-1 {
+		return fmt.Errorf("%d public inputs, key takes %d", len(d.Input), len(vk.IC)-1)
+	}
+	a, err := point1(d.A)
+	if err != nil {
+		return fmt.Errorf("A: %w", err)
+	}
+	c, err := point1(d.C)
+	if err != nil {
+		return fmt.Errorf("C: %w", err)
+	}
+	b := new(bn256.G2)
+	if _, err := b.Unmarshal(marshal(d.B[0][0], d.B[0][1], d.B[1][0], d.B[1][1])); err != nil {
+		return fmt.Errorf("B: %w", err)
+	}
+
+	x := new(bn256.G1).Set(vk.IC[0])
+	for i, in := range d.Input {
+		if in.Sign() < 0 || in.Cmp(Order) >= 0 {
+			return fmt.Errorf("public input %d out of the field", i)
+		}
+		x.Add(x, new(bn256.G1).ScalarMult(vk.IC[i+1], in))
+	}
+	if !bn256.PairingCheck(
+		[]*bn256.G1{new(bn256.G1).Neg(a), vk.Alpha1, x, c},
+		[]*bn256.G2{b, vk.Beta2, vk.Gamma2, vk.Delta2},
+	) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func point1(xy [2]*big.Int) (*bn256.G1, error) {
+	p := new(bn256.G1)
+	_, err := p.Unmarshal(marshal(xy[0], xy[1]))
+	return p, err
+}
+
+func g1(k *big.Int) *bn256.G1 {
+	return new(bn256.G1).ScalarBaseMult(k)
+}
+
+func g2(k *big.Int) *bn256.G2 {
+	return new(bn256.G2).ScalarBaseMult(k)
+}
+
+// words splits a marshalled point into its 32-byte coordinates.
+func words(data []byte) []*big.Int {
+	var out []*big.Int
+	for i := 0; i+32 <= len(data); i += 32 {
+		out = append(out, new(big.Int).SetBytes(data[i:i+32]))
+	}
+	return out
+}
+
+// marshal encodes coordinates as 32-byte words, failing unmarshalling for
+// any too large to fit.
+func marshal(ws ...*big.Int) []byte {
+	out := make([]byte, 0, 32*len(ws))
+	for _, w := range ws {
+		if w.Sign() < 0 || w.BitLen() > 256 {
+			return nil
+		}
+		out = append(out, w.FillBytes(make([]byte, 32))...)
+	}
+	return out
+}
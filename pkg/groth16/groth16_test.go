@@ -0,0 +1,139 @@
+package groth16
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestProveVerify(t *testing.T) {
+	pk, vk := Toy()
+	w := CubicWitness(big.NewInt(3))
+	if got := Cubic.PublicInputs(w)[0].Int64(); got != 35 {
+		t.Fatalf("out = %d, want 35", got)
+	}
+	proof, err := Prove(pk, Cubic, w, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := proof.Calldata(Cubic.PublicInputs(w))
+	if err := Verify(vk, valid); err != nil {
+		t.Fatalf("valid proof: %v", err)
+	}
+
+	wrongInput := valid.Copy()
+	wrongInput.Input[0].SetInt64(36)
+	// The same input plus the group order is the same scalar to ecMul
+	aliased := valid.Copy()
+	aliased.Input[0].Add(aliased.Input[0], Order)
+	tampered := valid.Copy()
+	tampered.C[0], tampered.C[1] = big.NewInt(1), big.NewInt(2)
+	offCurve := valid.Copy()
+	offCurve.A[1].Add(offCurve.A[1], big.NewInt(1))
+	for name, d := range map[string]Calldata{"wrong input": wrongInput, "aliased input": aliased, "tampered C": tampered, "A off the curve": offCurve} {
+		if err := Verify(vk, d); err == nil {
+			t.Errorf("%s verified", name)
+		}
+	}
+	if err := Verify(vk, tampered); !errors.Is(err, ErrInvalid) {
+		t.Errorf("tampered C: %v, want ErrInvalid", err)
+	}
+
+	bad := CubicWitness(big.NewInt(3))
+	bad[1].SetInt64(36)
+	if _, err := Prove(pk, Cubic, bad, nil); err == nil {
+		t.Error("proved an unsatisfied witness")
+	}
+}
+
+// The committed contract must be the one of the toy setup, or the proofs
+// the scripts make won't verify on-chain.
+func TestSolidity(t *testing.T) {
+	_, vk := Toy()
+	got, err := Solidity(vk, Cubic, ToySeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := os.ReadFile("../../contracts/Groth16Verifier.sol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, committed) {
+		t.Error("contracts/Groth16Verifier.sol is stale: regenerate it with scripts/groth16.go --write-verifier")
+	}
+}
+
+func TestCall(t *testing.T) {
+	pk, vk := Toy()
+	w := CubicWitness(big.NewInt(5))
+	proof, err := Prove(pk, Cubic, w, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := proof.Calldata(Cubic.PublicInputs(w))
+	invalid := valid.Copy()
+	invalid.Input[0].SetInt64(1)
+
+	// The contract answers what Verify does
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_call", func(call mockrpc.Call) (any, error) {
+		var msg struct{ Input hexutil.Bytes }
+		if err := call.Param(0, &msg); err != nil {
+			return nil, err
+		}
+		method, err := ABI.MethodById(msg.Input[:4])
+		if err != nil {
+			return nil, err
+		}
+		args, err := method.Inputs.Unpack(msg.Input[4:])
+		if err != nil {
+			return nil, err
+		}
+		verify := func(a [2]*big.Int, b [2][2]*big.Int, c [2]*big.Int, in [1]*big.Int) bool {
+			return Verify(vk, Calldata{A: a, B: b, C: c, Input: in[:]}) == nil
+		}
+		var out []byte
+		if method.Name == "verifyProof" {
+			out, err = method.Outputs.Pack(verify(args[0].([2]*big.Int), args[1].([2][2]*big.Int), args[2].([2]*big.Int), args[3].([1]*big.Int)))
+		} else {
+			as, bs, cs, ins := args[0].([][2]*big.Int), args[1].([][2][2]*big.Int), args[2].([][2]*big.Int), args[3].([][1]*big.Int)
+			verdicts := make([]bool, len(as))
+			for i := range as {
+				verdicts[i] = verify(as[i], bs[i], cs[i], ins[i])
+			}
+			out, err = method.Outputs.Pack(verdicts)
+		}
+		return hexutil.Bytes(out), err
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+	contract := common.Address{0x16}
+
+	if ok, err := Call(ctx, client, contract, valid); err != nil || !ok {
+		t.Errorf("valid proof: %t, %v", ok, err)
+	}
+	if ok, err := Call(ctx, client, contract, invalid); err != nil || ok {
+		t.Errorf("invalid proof: %t, %v", ok, err)
+	}
+	verdicts, err := CallBatch(ctx, client, contract, []Calldata{valid, invalid, valid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verdicts[0] || verdicts[1] || !verdicts[2] {
+		t.Errorf("batch verdicts %v, want [true false true]", verdicts)
+	}
+}
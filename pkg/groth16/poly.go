@@ -0,0 +1,117 @@
+package groth16
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Order is the order of the bn256 groups, the field of the circuit.
+var Order, _ = new(big.Int).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+
+// FieldModulus is the modulus of the field the bn256 points lie over.
+var FieldModulus, _ = new(big.Int).SetString("21888242871839275222246405745257275088696311157297823662689037894645226208583", 10)
+
+func mod(x *big.Int) *big.Int {
+	return x.Mod(x, Order)
+}
+
+// poly is a polynomial over the circuit's field, lowest coefficient first.
+type poly []*big.Int
+
+// interpolate returns the polynomial taking ys[j] at j+1.
+func interpolate(ys []*big.Int) poly {
+	result := make(poly, len(ys))
+	for i := range result {
+		result[i] = new(big.Int)
+	}
+	for j, y := range ys {
+		if y.Sign() == 0 {
+			continue
+		}
+		// The Lagrange basis polynomial: one at j+1, zero at the others
+		basis := poly{big.NewInt(1)}
+		denom := big.NewInt(1)
+		for k := range ys {
+			if k == j {
+				continue
+			}
+			basis = basis.mul(poly{mod(big.NewInt(int64(-(k + 1)))), big.NewInt(1)})
+			denom = mod(denom.Mul(denom, big.NewInt(int64(j-k))))
+		}
+		scale := mod(new(big.Int).Mul(y, new(big.Int).ModInverse(denom, Order)))
+		for i, c := range basis {
+			result[i] = mod(result[i].Add(result[i], new(big.Int).Mul(c, scale)))
+		}
+	}
+	return result
+}
+
+func (p poly) mul(q poly) poly {
+	out := make(poly, len(p)+len(q)-1)
+	for i := range out {
+		out[i] = new(big.Int)
+	}
+	for i, a := range p {
+		for j, b := range q {
+			out[i+j] = mod(out[i+j].Add(out[i+j], new(big.Int).Mul(a, b)))
+		}
+	}
+	return out
+}
+
+func (p poly) sub(q poly) poly {
+	out := make(poly, max(len(p), len(q)))
+	for i := range out {
+		out[i] = new(big.Int)
+		if i < len(p) {
+			out[i].Add(out[i], p[i])
+		}
+		if i < len(q) {
+			out[i].Sub(out[i], q[i])
+		}
+		mod(out[i])
+	}
+	return out
+}
+
+// div divides p by the monic d, failing unless it divides exactly.
+func (p poly) div(d poly) (poly, error) {
+	rem := append(poly(nil), p...)
+	for i := range rem {
+		rem[i] = new(big.Int).Set(p[i])
+	}
+	if len(rem) < len(d) {
+		return poly{new(big.Int)}, nil
+	}
+	quo := make(poly, len(rem)-len(d)+1)
+	for i := len(quo) - 1; i >= 0; i-- {
+		c := new(big.Int).Set(rem[i+len(d)-1])
+		quo[i] = c
+		for j, dc := range d {
+			rem[i+j] = mod(rem[i+j].Sub(rem[i+j], new(big.Int).Mul(c, dc)))
+		}
+	}
+	for _, c := range rem {
+		if c.Sign() != 0 {
+			return nil, errors.New("witness doesn't satisfy the circuit")
+		}
+	}
+	return quo, nil
+}
+
+func (p poly) eval(x *big.Int) *big.Int {
+	y := new(big.Int)
+	for i := len(p) - 1; i >= 0; i-- {
+		y = mod(y.Add(y.Mul(y, x), p[i]))
+	}
+	return y
+}
+
+// vanishing is the polynomial that is zero at 1 to n.
+func vanishing(n int) poly {
+	z := poly{big.NewInt(1)}
+	for k := 1; k <= n; k++ {
+		z = z.mul(poly{mod(big.NewInt(int64(-k))), big.NewInt(1)})
+	}
+	return z
+}
@@ -0,0 +1,146 @@
+package groth16
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"text/template"
+)
+
+// verifierTemplate is the verifier contract of a key. Public inputs are
+// folded into the key's IC point with ecMul and ecAdd, and the proof is
+// checked with a single ecPairing of four pairs, as generated verifiers
+// do. Every failed precompile call makes the proof invalid rather than
+// reverting, so tampered proofs can be told apart from broken calls.
+var verifierTemplate = template.Must(template.New("verifier").Parse(`// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+// Code generated by pkg/groth16 from the toy setup of the circuit
+// "{{.Circuit}}", seeded with "{{.Seed}}". DO NOT EDIT.
+// Regenerate with: go run scripts/groth16.go --write-verifier contracts/Groth16Verifier.sol
+//
+// Verifies Groth16 proofs over bn256 with the ecAdd (0x06), ecMul (0x07)
+// and ecPairing (0x08) precompiles. The setup's trapdoor is public, so
+// anyone can forge proofs: the contract tests the EVM, not the statement.
+contract Groth16Verifier {
+    address constant EC_ADD = address(uint160(0x06));
+    address constant EC_MUL = address(uint160(0x07));
+    address constant EC_PAIRING = address(uint160(0x08));
+
+    // Order of the bn256 groups: public inputs must be below it.
+    uint256 constant R = {{.R}};
+    // Modulus of the field the points lie over.
+    uint256 constant Q = {{.Q}};
+
+    // Verifying key. G2 points have the imaginary part of each coordinate
+    // first, as ecPairing takes them.
+    uint256 constant ALPHA_X = {{index .Alpha 0}};
+    uint256 constant ALPHA_Y = {{index .Alpha 1}};
+{{- range .G2}}
+    uint256 constant {{.Name}}_X1 = {{index .Words 0}};
+    uint256 constant {{.Name}}_X0 = {{index .Words 1}};
+    uint256 constant {{.Name}}_Y1 = {{index .Words 2}};
+    uint256 constant {{.Name}}_Y0 = {{index .Words 3}};
+{{- end}}
+{{- range $i, $p := .IC}}
+    uint256 constant IC{{$i}}_X = {{index $p 0}};
+    uint256 constant IC{{$i}}_Y = {{index $p 1}};
+{{- end}}
+
+    // Checks one proof of the public inputs.
+    function verifyProof(uint256[2] calldata a, uint256[2][2] calldata b, uint256[2] calldata c, uint256[{{.Inputs}}] calldata input) public view returns (bool) {
+        // vk_x = IC0 + input[0] * IC1 + ...
+        uint256[2] memory x = [IC0_X, IC0_Y];
+        bool ok;
+{{- range $i, $p := .Terms}}
+        if (input[{{$i}}] >= R) {
+            return false;
+        }
+        (ok, x) = ecMulAdd(x, [IC{{$p}}_X, IC{{$p}}_Y], input[{{$i}}]);
+        if (!ok) {
+            return false;
+        }
+{{- end}}
+        if (a[1] >= Q) {
+            return false;
+        }
+
+        // e(-A, B) * e(alpha, beta) * e(vk_x, gamma) * e(C, delta) == 1
+        bytes memory pairs = abi.encode(a[0], (Q - a[1]) % Q, b[0][0], b[0][1], b[1][0], b[1][1]);
+        pairs = bytes.concat(pairs, abi.encode(ALPHA_X, ALPHA_Y, BETA_X1, BETA_X0, BETA_Y1, BETA_Y0));
+        pairs = bytes.concat(pairs, abi.encode(x[0], x[1], GAMMA_X1, GAMMA_X0, GAMMA_Y1, GAMMA_Y0));
+        pairs = bytes.concat(pairs, abi.encode(c[0], c[1], DELTA_X1, DELTA_X0, DELTA_Y1, DELTA_Y0));
+        (bool success, bytes memory out) = EC_PAIRING.staticcall(pairs);
+        return success && out.length == 32 && abi.decode(out, (uint256)) == 1;
+    }
+
+    // Checks a batch of proofs in one call, each on its own.
+    function verifyBatch(uint256[2][] calldata a, uint256[2][2][] calldata b, uint256[2][] calldata c, uint256[{{.Inputs}}][] calldata input) external view returns (bool[] memory valid) {
+        require(b.length == a.length && c.length == a.length && input.length == a.length, "batch length mismatch");
+        valid = new bool[](a.length);
+        for (uint256 i = 0; i < a.length; i++) {
+            valid[i] = verifyProof(a[i], b[i], c[i], input[i]);
+        }
+    }
+
+    // Returns sum + s * p with ecMul and ecAdd.
+    function ecMulAdd(uint256[2] memory sum, uint256[2] memory p, uint256 s) internal view returns (bool, uint256[2] memory) {
+        (bool ok, bytes memory out) = EC_MUL.staticcall(abi.encode(p[0], p[1], s));
+        if (!ok || out.length != 64) {
+            return (false, sum);
+        }
+        (uint256 x, uint256 y) = abi.decode(out, (uint256, uint256));
+        (ok, out) = EC_ADD.staticcall(abi.encode(sum[0], sum[1], x, y));
+        if (!ok || out.length != 64) {
+            return (false, sum);
+        }
+        (x, y) = abi.decode(out, (uint256, uint256));
+        return (true, [x, y]);
+    }
+}
+`))
+
+type g2Constant struct {
+	Name  string
+	Words []*big.Int
+}
+
+// Solidity renders the verifier contract of vk, generated by the setup
+// from seed of circuit c.
+func Solidity(vk *VerifyingKey, c Circuit, seed string) ([]byte, error) {
+	if len(vk.IC) != c.Public+1 {
+		return nil, fmt.Errorf("key has %d IC points, circuit %d public inputs", len(vk.IC), c.Public)
+	}
+	data := struct {
+		Seed, Circuit string
+		R, Q          *big.Int
+		Alpha         []*big.Int
+		G2            []g2Constant
+		IC            [][]*big.Int
+		Terms         []int
+		Inputs        int
+	}{
+		Seed:    seed,
+		Circuit: c.Name,
+		R:       Order,
+		Q:       FieldModulus,
+		Alpha:   words(vk.Alpha1.Marshal()),
+		G2: []g2Constant{
+			{"BETA", words(vk.Beta2.Marshal())},
+			{"GAMMA", words(vk.Gamma2.Marshal())},
+			{"DELTA", words(vk.Delta2.Marshal())},
+		},
+		Inputs: c.Public,
+	}
+	for i, p := range vk.IC {
+		data.IC = append(data.IC, words(p.Marshal()))
+		if i > 0 {
+			data.Terms = append(data.Terms, i)
+		}
+	}
+	var out bytes.Buffer
+	if err := verifierTemplate.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,98 @@
+// Package hashref computes the local SHA-256 answers that precompile results
+// are checked against. The implementation is pluggable so that very large
+// corpora can use an assembly-optimised hasher, and Digest spreads the work
+// over several cores so the harness isn't the bottleneck.
+package hashref
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	sha256simd "github.com/minio/sha256-simd"
+)
+
+// Func returns the SHA-256 digest of its input.
+type Func func([]byte) [32]byte
+
+// Implementation names accepted by Lookup.
+const (
+	// Stdlib is crypto/sha256, the default.
+	Stdlib = "stdlib"
+	// SIMD is github.com/minio/sha256-simd, which uses the SHA extensions on
+	// amd64 and arm64 when the CPU has them and falls back to Go otherwise.
+	SIMD = "simd"
+)
+
+var impls = map[string]Func{
+	Stdlib: sha256.Sum256,
+	SIMD:   sha256simd.Sum256,
+}
+
+// Names lists the registered implementations in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(impls))
+	for name := range impls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the named implementation. An empty name selects Stdlib.
+func Lookup(name string) (Func, error) {
+	if name == "" {
+		name = Stdlib
+	}
+	fn, ok := impls[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash implementation %q (want %s)", name, strings.Join(Names(), ", "))
+	}
+	return fn, nil
+}
+
+// chunk is how many inputs a worker claims at a time, keeping contention on
+// the shared counter low for small inputs.
+const chunk = 64
+
+// Digest hashes every input with fn on workers goroutines; the result at i
+// is the digest of inputs[i]. workers <= 0 uses GOMAXPROCS. It stops early
+// and returns ctx's error if ctx is cancelled.
+func Digest(ctx context.Context, fn Func, inputs [][]byte, workers int) ([][32]byte, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if batches := (len(inputs) + chunk - 1) / chunk; workers > batches {
+		workers = batches
+	}
+
+	out := make([][32]byte, len(inputs))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				start := int(next.Add(chunk)) - chunk
+				if start >= len(inputs) {
+					return
+				}
+				for i := start; i < min(start+chunk, len(inputs)); i++ {
+					out[i] = fn(inputs[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package hashref
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func corpus(n int) [][]byte {
+	rng := rand.New(rand.NewSource(1))
+	inputs := make([][]byte, n)
+	for i := range inputs {
+		inputs[i] = make([]byte, rng.Intn(300))
+		rng.Read(inputs[i])
+	}
+	return inputs
+}
+
+func TestImplementationsAgree(t *testing.T) {
+	for _, name := range Names() {
+		fn, err := Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Lengths around the 64-byte block and padding boundaries
+		for n := 0; n <= 200; n++ {
+			input := make([]byte, n)
+			for i := range input {
+				input[i] = byte(i * 7)
+			}
+			if got, want := fn(input), sha256.Sum256(input); got != want {
+				t.Fatalf("%s: %d-byte input hashed to %x, want %x", name, n, got, want)
+			}
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if _, err := Lookup(""); err != nil {
+		t.Errorf("default implementation: %v", err)
+	}
+	if _, err := Lookup("SIMD"); err != nil {
+		t.Errorf("names should be case-insensitive: %v", err)
+	}
+	if _, err := Lookup("md5"); err == nil {
+		t.Error("unknown implementation accepted")
+	}
+}
+
+func TestDigest(t *testing.T) {
+	inputs := corpus(1000)
+	for _, workers := range []int{0, 1, 3, 100} {
+		got, err := Digest(context.Background(), sha256.Sum256, inputs, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, in := range inputs {
+			if got[i] != sha256.Sum256(in) {
+				t.Fatalf("%d workers: digest %d is wrong", workers, i)
+			}
+		}
+	}
+
+	if got, err := Digest(context.Background(), sha256.Sum256, nil, 4); err != nil || len(got) != 0 {
+		t.Errorf("empty corpus: %v, %v", got, err)
+	}
+}
+
+func TestDigestCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Digest(ctx, sha256.Sum256, corpus(10), 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+func BenchmarkDigest(b *testing.B) {
+	inputs := corpus(10000)
+	for _, name := range Names() {
+		fn, _ := Lookup(name)
+		for _, workers := range []int{1, 0} {
+			label := name + "/serial"
+			if workers == 0 {
+				label = name + "/parallel"
+			}
+			b.Run(label, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := Digest(context.Background(), fn, inputs, workers); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
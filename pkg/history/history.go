@@ -0,0 +1,207 @@
+// Package history re-executes a canary call at a series of heights since a
+// contract was deployed, on a node with historical state. The answer must
+// not depend on the height: every change between two sampled heights is
+// bisected down to the block that introduced it, and heights sampled by an
+// earlier run are checked for a different state root or answer, which
+// points at a re-executed or rewritten history.
+package history
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+)
+
+// Call makes the canary call in the state of block.
+type Call func(ctx context.Context, block *big.Int) ([]byte, error)
+
+// DeploymentBlock bisects the first block at which address has code, up to
+// head. It fails with a *chain.NoCodeError when there is no code at head,
+// and with the node's error when it has no state for the heights asked.
+func DeploymentBlock(ctx context.Context, client *ethclient.Client, address common.Address, head uint64) (uint64, error) {
+	hasCode := func(n uint64) (bool, error) {
+		code, err := client.CodeAt(ctx, address, new(big.Int).SetUint64(n))
+		if err != nil {
+			return false, fmt.Errorf("failed to get code at block %d: %w", n, err)
+		}
+		return len(code) > 0, nil
+	}
+	if ok, err := hasCode(head); err != nil || !ok {
+		if err == nil {
+			err = &chain.NoCodeError{Address: address}
+		}
+		return 0, err
+	}
+	if ok, err := hasCode(0); err != nil || ok {
+		return 0, err
+	}
+	missing, present := uint64(0), head
+	for present-missing > 1 {
+		mid := missing + (present-missing)/2
+		ok, err := hasCode(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			present = mid
+		} else {
+			missing = mid
+		}
+	}
+	return present, nil
+}
+
+// Heights are the blocks to sample: from, every every-th block after it,
+// and head.
+func Heights(from, head, every uint64) []uint64 {
+	if every == 0 {
+		every = 1
+	}
+	var heights []uint64
+	for h := from; h < head; h += every {
+		heights = append(heights, h)
+	}
+	return append(heights, head)
+}
+
+// Sample is the canary call's outcome at one height.
+type Sample struct {
+	Block     uint64 `json:"block"`
+	StateRoot string `json:"stateRoot,omitempty"`
+	Output    string `json:"output,omitempty"`
+	Match     bool   `json:"match"`
+	Error     string `json:"error,omitempty"`
+}
+
+// outcome is what consecutive samples are compared by.
+func (s Sample) outcome() string {
+	if s.Error != "" {
+		return "error: " + s.Error
+	}
+	return s.Output
+}
+
+// Transition is a change of the answer between two consecutive sampled
+// heights.
+type Transition struct {
+	// Block is the first block with the new answer, or without bisecting
+	// the first sampled height with it.
+	Block  uint64 `json:"block"`
+	Before Sample `json:"before"`
+	After  Sample `json:"after"`
+	// Regression is set when the answer went from right to wrong or to an
+	// error.
+	Regression bool   `json:"regression"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is the outcome of Replay.
+type Report struct {
+	Samples     []Sample     `json:"samples"`
+	Transitions []Transition `json:"transitions,omitempty"`
+	// Mismatches counts samples whose answer is wrong or an error.
+	Mismatches int `json:"mismatches"`
+}
+
+// Consistent reports whether the answer was right at every height.
+func (r Report) Consistent() bool {
+	return r.Mismatches == 0 && len(r.Transitions) == 0
+}
+
+// Replay makes call at every height, comparing the answer with expected,
+// and with bisect narrows every change between consecutive heights down to
+// one block. heights must be sorted.
+func Replay(ctx context.Context, client *ethclient.Client, call Call, expected []byte, heights []uint64, bisect bool) Report {
+	sample := func(n uint64) Sample {
+		block := new(big.Int).SetUint64(n)
+		s := Sample{Block: n}
+		if header, err := client.HeaderByNumber(ctx, block); err == nil {
+			s.StateRoot = header.Root.Hex()
+		}
+		out, err := call(ctx, block)
+		if err != nil {
+			s.Error = err.Error()
+			return s
+		}
+		s.Output = hexutil.Encode(out)
+		s.Match = bytes.Equal(out, expected)
+		return s
+	}
+
+	var r Report
+	for _, h := range heights {
+		if ctx.Err() != nil {
+			break
+		}
+		s := sample(h)
+		if !s.Match {
+			r.Mismatches++
+		}
+		if n := len(r.Samples); n > 0 && r.Samples[n-1].outcome() != s.outcome() {
+			r.Transitions = append(r.Transitions, transition(ctx, sample, r.Samples[n-1], s, bisect))
+		}
+		r.Samples = append(r.Samples, s)
+	}
+	return r
+}
+
+// transition bisects between before and after for the first block answering
+// like after.
+func transition(ctx context.Context, sample func(uint64) Sample, before, after Sample, bisect bool) Transition {
+	t := Transition{Block: after.Block, Before: before, After: after, Regression: before.Match && !after.Match}
+	for lo, hi := before.Block, after.Block; bisect && hi-lo > 1; {
+		if ctx.Err() != nil {
+			t.Error = ctx.Err().Error()
+			break
+		}
+		mid := lo + (hi-lo)/2
+		switch s := sample(mid); s.outcome() {
+		case after.outcome():
+			hi, t.Block, t.After = mid, mid, s
+		case before.outcome():
+			lo, t.Before = mid, s
+		default:
+			// A third answer in between: report the first change found
+			t.Block, t.After = mid, s
+			t.Error = fmt.Sprintf("block %d answers differently from both ends", mid)
+			return t
+		}
+	}
+	return t
+}
+
+// Change is a height sampled by two runs whose state root or answer is
+// different in the second.
+type Change struct {
+	Block    uint64 `json:"block"`
+	Previous Sample `json:"previous"`
+	Current  Sample `json:"current"`
+}
+
+// Changes compares the heights sampled by both r and an earlier run.
+// Samples without a state root on either side are compared by answer only.
+func (r Report) Changes(previous Report) []Change {
+	before := map[uint64]Sample{}
+	for _, s := range previous.Samples {
+		before[s.Block] = s
+	}
+	var changes []Change
+	for _, s := range r.Samples {
+		p, ok := before[s.Block]
+		if !ok {
+			continue
+		}
+		rootChanged := p.StateRoot != "" && s.StateRoot != "" && p.StateRoot != s.StateRoot
+		if rootChanged || p.outcome() != s.outcome() {
+			changes = append(changes, Change{Block: s.Block, Previous: p, Current: s})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,129 @@
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+var canary = sha256.Sum256([]byte("hello world"))
+
+// node has the wrapper's code from deployed on, and answers the canary
+// with the right digest until broken, a wrong one from there.
+func node(t *testing.T, deployed, broken uint64) (*ethclient.Client, *mockrpc.Server) {
+	t.Helper()
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	height := func(c mockrpc.Call, i int) uint64 {
+		var block hexutil.Big
+		if err := c.Param(i, &block); err != nil {
+			t.Fatal(err)
+		}
+		return (*big.Int)(&block).Uint64()
+	}
+	s.Handle("eth_getCode", func(c mockrpc.Call) (any, error) {
+		if height(c, 1) < deployed {
+			return hexutil.Bytes{}, nil
+		}
+		return hexutil.Bytes{0x60, 0x00}, nil
+	})
+	s.Handle("eth_getBlockByNumber", func(c mockrpc.Call) (any, error) {
+		n := height(c, 0)
+		return &types.Header{Number: new(big.Int).SetUint64(n), Difficulty: new(big.Int), Root: common.BigToHash(new(big.Int).SetUint64(n + 1))}, nil
+	})
+	s.Handle("eth_call", func(c mockrpc.Call) (any, error) {
+		if height(c, 1) >= broken {
+			return hexutil.Bytes(make([]byte, 32)), nil
+		}
+		return hexutil.Bytes(canary[:]), nil
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client, s
+}
+
+func call(client *ethclient.Client) Call {
+	return func(ctx context.Context, block *big.Int) ([]byte, error) {
+		var out hexutil.Bytes
+		args := map[string]any{"to": common.HexToAddress("0x1234")}
+		err := client.Client().CallContext(ctx, &out, "eth_call", args, hexutil.EncodeBig(block))
+		return out, err
+	}
+}
+
+func TestDeploymentBlock(t *testing.T) {
+	client, _ := node(t, 37, 1000)
+	n, err := DeploymentBlock(context.Background(), client, common.HexToAddress("0x1234"), 200)
+	if err != nil || n != 37 {
+		t.Errorf("got %d, %v", n, err)
+	}
+	if _, err := DeploymentBlock(context.Background(), client, common.HexToAddress("0x1234"), 20); !errors.Is(err, chain.ErrNoCodeAtAddress) {
+		t.Errorf("before deployment: got %v", err)
+	}
+}
+
+func TestHeights(t *testing.T) {
+	if got, want := Heights(37, 100, 25), []uint64{37, 62, 87, 100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("heights %v, want %v", got, want)
+	}
+	if got := Heights(100, 100, 25); !reflect.DeepEqual(got, []uint64{100}) {
+		t.Errorf("deployed at the head: %v", got)
+	}
+}
+
+func TestReplayBisectsRegression(t *testing.T) {
+	client, s := node(t, 0, 73)
+	heights := Heights(10, 110, 20)
+	r := Replay(context.Background(), client, call(client), canary[:], heights, true)
+	if len(r.Samples) != len(heights) || r.Mismatches != 2 || r.Consistent() {
+		t.Fatalf("%d samples, %d mismatches", len(r.Samples), r.Mismatches)
+	}
+	if len(r.Transitions) != 1 {
+		t.Fatalf("transitions %+v", r.Transitions)
+	}
+	tr := r.Transitions[0]
+	if tr.Block != 73 || !tr.Regression || tr.Before.Block != 72 || tr.After.Block != 73 || tr.Error != "" {
+		t.Errorf("transition %+v", tr)
+	}
+	if r.Samples[0].StateRoot == "" || r.Samples[0].Output != hexutil.Encode(canary[:]) {
+		t.Errorf("first sample %+v", r.Samples[0])
+	}
+	before := s.Calls("eth_call")
+
+	r = Replay(context.Background(), client, call(client), canary[:], heights, false)
+	if r.Transitions[0].Block != 90 || s.Calls("eth_call")-before != len(heights) {
+		t.Errorf("without bisecting: %+v", r.Transitions)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	previous := Report{Samples: []Sample{
+		{Block: 10, StateRoot: "0x01", Output: "0xaa", Match: true},
+		{Block: 20, StateRoot: "0x02", Output: "0xaa", Match: true},
+		{Block: 30, Output: "0xaa", Match: true},
+	}}
+	current := Report{Samples: []Sample{
+		{Block: 10, StateRoot: "0x01", Output: "0xaa", Match: true},
+		{Block: 20, StateRoot: "0x22", Output: "0xaa", Match: true},
+		{Block: 30, StateRoot: "0x03", Output: "0xaa", Match: true},
+		{Block: 40, StateRoot: "0x04", Error: "missing trie node"},
+	}}
+	changes := current.Changes(previous)
+	if len(changes) != 1 || changes[0].Block != 20 {
+		t.Errorf("changes %+v", changes)
+	}
+}
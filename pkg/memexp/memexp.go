@@ -0,0 +1,278 @@
+// Package memexp checks precompile calls whose input and output buffers sit
+// at extreme memory offsets. Several EVM implementations mispriced or
+// mishandled the memory expansion a CALL to a precompile triggers, so each
+// case is run on the node and in go-ethereum's EVM, and the two must agree
+// on the result and on the gas the call cost.
+//
+// A case is a small EVM program assembled here rather than a Solidity
+// function: solc can't place buffers at arbitrary offsets without inline
+// assembly, and only hand-placed opcodes make the gas between the two GAS
+// readings exact. The program runs as the init code of a creation eth_call,
+// so nothing is deployed.
+package memexp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CallOverhead is the gas between the two GAS readings besides the
+// STATICCALL's memory expansion and the precompile's own cost: five pushes,
+// the GAS forwarding all gas, the warm STATICCALL and the closing GAS.
+const CallOverhead = 5*3 + 2 + 100 + 2
+
+// LimitedCallOverhead is CallOverhead for a Limited case, where pushing
+// CallGas costs one gas more than GAS.
+const LimitedCallOverhead = CallOverhead + 1
+
+// headerSize is the success flag, gas used and return data size words the
+// program returns before the output buffer.
+const headerSize = 96
+
+// Case is one precompile call with its buffers placed in memory.
+type Case struct {
+	Name       string
+	Precompile common.Address
+	Input      []byte
+	// InOffset is where the input is copied to before the call; with an
+	// empty input it must not expand memory.
+	InOffset uint64
+	// OutOffset and OutSize are the output buffer handed to the call; with
+	// a zero size the offset must not expand memory.
+	OutOffset uint64
+	OutSize   uint64
+	// Limited hands the precompile CallGas, which may be zero, instead of
+	// all available gas.
+	Limited bool
+	CallGas uint64
+}
+
+// Outcome is what running a case produced.
+type Outcome struct {
+	// Failed is set when the whole program failed, typically out of gas
+	// for the expansion, with the node's or the EVM's message in Error.
+	Failed bool
+	Error  string
+	// Success is the precompile call's own success flag.
+	Success bool
+	// GasUsed is the gas between the readings around the STATICCALL.
+	GasUsed uint64
+	// ReturnSize is RETURNDATASIZE after the call.
+	ReturnSize uint64
+	// Output is the output buffer after the call.
+	Output []byte
+}
+
+// Equal reports whether two outcomes agree. Failure messages differ
+// between clients and aren't compared.
+func (o Outcome) Equal(other Outcome) bool {
+	if o.Failed || other.Failed {
+		return o.Failed == other.Failed
+	}
+	return o.Success == other.Success && o.GasUsed == other.GasUsed && o.ReturnSize == other.ReturnSize &&
+		string(o.Output) == string(other.Output)
+}
+
+// Code assembles the program for c. It copies the input, which follows the
+// program, to InOffset, reads GAS, calls the precompile, reads GAS again and
+// returns the call's success flag, gas used and return data size followed
+// by the output buffer, copied one word at a time above every buffer of the
+// call.
+func (c Case) Code() []byte {
+	header := align(max(end(c.InOffset, uint64(len(c.Input))), end(c.OutOffset, c.OutSize)))
+	words := (c.OutSize + 31) / 32
+
+	var a asm
+	a.push(uint64(len(c.Input)))
+	dataOffset := a.placeholder()
+	a.push(c.InOffset)
+	a.op(vm.CODECOPY)
+	a.op(vm.GAS)
+	a.push(c.OutSize)
+	a.push(c.OutOffset)
+	a.push(uint64(len(c.Input)))
+	a.push(c.InOffset)
+	a.code = append(append(a.code, byte(vm.PUSH20)), c.Precompile.Bytes()...)
+	if c.Limited {
+		a.push(c.CallGas)
+	} else {
+		a.op(vm.GAS)
+	}
+	a.op(vm.STATICCALL)
+	a.op(vm.GAS)
+	a.op(vm.SWAP1)
+	a.push(header)
+	a.op(vm.MSTORE)
+	a.op(vm.SWAP1)
+	a.op(vm.SUB)
+	a.push(header + 32)
+	a.op(vm.MSTORE)
+	a.op(vm.RETURNDATASIZE)
+	a.push(header + 64)
+	a.op(vm.MSTORE)
+	for i := uint64(0); i < words; i++ {
+		a.push(c.OutOffset + 32*i)
+		a.op(vm.MLOAD)
+		a.push(header + headerSize + 32*i)
+		a.op(vm.MSTORE)
+	}
+	a.push(headerSize + 32*words)
+	a.push(header)
+	a.op(vm.RETURN)
+
+	binary.BigEndian.PutUint64(a.code[dataOffset:], uint64(len(a.code)))
+	return append(a.code, c.Input...)
+}
+
+// Decode reads the program's return data.
+func (c Case) Decode(ret []byte) (Outcome, error) {
+	if want := headerSize + 32*((c.OutSize+31)/32); uint64(len(ret)) != want {
+		return Outcome{}, fmt.Errorf("%s: returned %d bytes, want %d", c.Name, len(ret), want)
+	}
+	return Outcome{
+		Success:    ret[31] == 1,
+		GasUsed:    binary.BigEndian.Uint64(ret[56:64]),
+		ReturnSize: binary.BigEndian.Uint64(ret[88:96]),
+		Output:     ret[headerSize : headerSize+c.OutSize],
+	}, nil
+}
+
+// Reference runs c in go-ethereum's EVM with gas available.
+func Reference(c Case, gas uint64) (Outcome, error) {
+	ret, _, err := runtime.Execute(c.Code(), nil, &runtime.Config{GasLimit: gas})
+	if err != nil {
+		return Outcome{Failed: true, Error: err.Error()}, nil
+	}
+	return c.Decode(ret)
+}
+
+// Call runs c on the node in a creation eth_call with gas available. An
+// execution error is reported as a failed outcome; any other error, such as
+// a transport failure, is returned.
+func Call(ctx context.Context, client *ethclient.Client, c Case, gas uint64) (Outcome, error) {
+	ret, err := client.CallContract(ctx, ethereum.CallMsg{Gas: gas, Data: c.Code()}, nil)
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return Outcome{Failed: true, Error: err.Error()}, nil
+	}
+	if err != nil {
+		return Outcome{}, fmt.Errorf("%s: %w", c.Name, err)
+	}
+	return c.Decode(ret)
+}
+
+// Layout places the buffers of a call; the output size is derived from the
+// length of the precompile's natural output.
+type Layout struct {
+	Name      string
+	InOffset  uint64
+	OutOffset uint64
+	OutSize   func(natural uint64) uint64
+}
+
+func natural(n uint64) uint64 { return n }
+
+// Layouts are the default buffer placements: unaligned and word-boundary
+// offsets, overlapping buffers, outputs shorter and longer than the
+// precompile's, zero-sized buffers at huge offsets, which must not expand
+// memory, and expansions too costly to pay for or past what a 64-bit size
+// can describe, which must fail.
+var Layouts = []Layout{
+	{Name: "zero", OutSize: natural},
+	{Name: "unaligned", InOffset: 1, OutOffset: 67, OutSize: natural},
+	{Name: "word-boundary", InOffset: 31, OutOffset: 96, OutSize: natural},
+	{Name: "overlapping", InOffset: 64, OutOffset: 80, OutSize: natural},
+	{Name: "short-output", InOffset: 0, OutOffset: 64, OutSize: func(uint64) uint64 { return 5 }},
+	{Name: "long-output", InOffset: 0, OutOffset: 64, OutSize: func(n uint64) uint64 { return n + 33 }},
+	{Name: "out-64k", InOffset: 0, OutOffset: 1 << 16, OutSize: natural},
+	{Name: "in-64k", InOffset: 1 << 16, OutOffset: 0, OutSize: natural},
+	{Name: "out-1m", InOffset: 0, OutOffset: 1 << 20, OutSize: natural},
+	{Name: "out-3m", InOffset: 0, OutOffset: 3 << 20, OutSize: natural},
+	{Name: "empty-output-at-2^40", InOffset: 0, OutOffset: 1 << 40, OutSize: func(uint64) uint64 { return 0 }},
+	{Name: "out-2^40", InOffset: 0, OutOffset: 1 << 40, OutSize: natural},
+	{Name: "out-2^63", InOffset: 0, OutOffset: 1 << 63, OutSize: natural},
+}
+
+// Cases applies every layout to the identity (0x04) and SHA-256 (0x02)
+// precompiles with input, and adds for each an empty input at a huge
+// offset, which the call must ignore.
+func Cases(input []byte) []Case {
+	targets := []struct {
+		name    string
+		address common.Address
+		natural uint64
+	}{
+		{"identity", common.BytesToAddress([]byte{4}), uint64(len(input))},
+		{"sha256", common.BytesToAddress([]byte{2}), 32},
+	}
+	var cases []Case
+	for _, t := range targets {
+		for _, l := range Layouts {
+			cases = append(cases, Case{
+				Name:       t.name + "/" + l.Name,
+				Precompile: t.address,
+				Input:      input,
+				InOffset:   l.InOffset,
+				OutOffset:  l.OutOffset,
+				OutSize:    l.OutSize(t.natural),
+			})
+		}
+		cases = append(cases, Case{
+			Name:       t.name + "/empty-input-at-2^40",
+			Precompile: t.address,
+			InOffset:   1 << 40,
+			OutOffset:  0,
+			OutSize:    32,
+		})
+	}
+	return cases
+}
+
+// end is the first byte past a buffer, or 0 for an empty one, which takes
+// no memory. Offsets past any real memory size saturate.
+func end(offset, size uint64) uint64 {
+	if size == 0 {
+		return 0
+	}
+	if offset+size < offset {
+		return ^uint64(0) &^ 31
+	}
+	return offset + size
+}
+
+func align(n uint64) uint64 {
+	if n > ^uint64(0)-31 {
+		return ^uint64(0) &^ 31
+	}
+	return (n + 31) &^ 31
+}
+
+// asm builds a program. Every push is 8 bytes wide, so the layout doesn't
+// depend on the values pushed.
+type asm struct {
+	code []byte
+}
+
+func (a *asm) op(op vm.OpCode) {
+	a.code = append(a.code, byte(op))
+}
+
+func (a *asm) push(v uint64) {
+	a.code = append(a.code, byte(vm.PUSH8))
+	a.code = binary.BigEndian.AppendUint64(a.code, v)
+}
+
+// placeholder pushes a value patched in later and returns its position.
+func (a *asm) placeholder() int {
+	a.push(0)
+	return len(a.code) - 8
+}
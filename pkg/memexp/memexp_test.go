@@ -0,0 +1,162 @@
+package memexp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+var input = []byte("precompile memory expansion boundary!!!!")
+
+// memoryCost is the yellow paper's cost of words of memory.
+func memoryCost(words uint64) uint64 {
+	return params.MemoryGas*words + words*words/params.QuadCoeffDiv
+}
+
+func TestReference(t *testing.T) {
+	byName := map[string]Case{}
+	for _, c := range Cases(input) {
+		byName[c.Name] = c
+	}
+	sum := sha256.Sum256(input)
+	identityGas := params.IdentityBaseGas + params.IdentityPerWordGas*2
+	inWords := uint64(2)
+	for _, tc := range []struct {
+		name   string
+		output []byte
+		// returned is the precompile's output length, whatever the buffer
+		returned uint64
+		// gas is the call's cost besides CallOverhead
+		gas uint64
+	}{
+		{"identity/zero", input, 40, identityGas},
+		{"sha256/zero", sum[:], 32, params.Sha256BaseGas + params.Sha256PerWordGas*2},
+		{"sha256/out-64k", sum[:], 32, params.Sha256BaseGas + params.Sha256PerWordGas*2 + memoryCost(1<<11+1) - memoryCost(inWords)},
+		{"identity/out-1m", input, 40, identityGas + memoryCost(1<<15+2) - memoryCost(inWords)},
+		{"identity/short-output", input[:5], 40, identityGas + memoryCost(3) - memoryCost(inWords)},
+		{"identity/long-output", append(append([]byte{}, input...), make([]byte, 33)...), 40, identityGas + memoryCost(5) - memoryCost(inWords)},
+		{"identity/empty-output-at-2^40", []byte{}, 40, identityGas},
+		{"identity/empty-input-at-2^40", make([]byte, 32), 0, params.IdentityBaseGas + memoryCost(1)},
+	} {
+		c, ok := byName[tc.name]
+		if !ok {
+			t.Fatalf("no case %s", tc.name)
+		}
+		got, err := Reference(c, 30_000_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Failed || !got.Success || !bytes.Equal(got.Output, tc.output) || got.ReturnSize != tc.returned {
+			t.Errorf("%s: %+v, want success with %x of %d bytes", tc.name, got, tc.output, tc.returned)
+		}
+		if got.GasUsed != CallOverhead+tc.gas {
+			t.Errorf("%s: gas %d, want %d", tc.name, got.GasUsed, CallOverhead+tc.gas)
+		}
+	}
+
+	// An expansion nobody can pay for fails the whole program
+	for _, name := range []string{"sha256/out-2^40", "identity/out-2^63"} {
+		if got, err := Reference(byName[name], 30_000_000); err != nil || !got.Failed {
+			t.Errorf("%s: %+v, %v, want a failed run", name, got, err)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	// The node executes like the reference, except that it charges the 3
+	// MiB expansion one gas short
+	s.Handle("eth_call", func(call mockrpc.Call) (any, error) {
+		var msg struct {
+			Input hexutil.Bytes
+		}
+		if err := call.Param(0, &msg); err != nil {
+			return nil, err
+		}
+		for _, c := range Cases(input) {
+			if !bytes.Equal(c.Code(), msg.Input) {
+				continue
+			}
+			o, err := Reference(c, 30_000_000)
+			if err != nil {
+				return nil, err
+			}
+			if o.Failed {
+				return nil, &mockrpc.Error{Code: -32000, Message: "out of gas"}
+			}
+			if c.Name == "identity/out-3m" {
+				o.GasUsed--
+			}
+			ret := make([]byte, headerSize, headerSize+len(o.Output))
+			if o.Success {
+				ret[31] = 1
+			}
+			binary.BigEndian.PutUint64(ret[56:], o.GasUsed)
+			binary.BigEndian.PutUint64(ret[88:], o.ReturnSize)
+			ret = append(ret, o.Output...)
+			ret = append(ret, make([]byte, 32*((c.OutSize+31)/32)-c.OutSize)...)
+			return hexutil.Bytes(ret), nil
+		}
+		return nil, &mockrpc.Error{Code: -32000, Message: "unknown program"}
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var mismatched []string
+	for _, c := range Cases(input) {
+		got, err := Call(context.Background(), client, c, 30_000_000)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		want, _ := Reference(c, 30_000_000)
+		if !got.Equal(want) {
+			mismatched = append(mismatched, c.Name)
+		}
+	}
+	if len(mismatched) != 1 || mismatched[0] != "identity/out-3m" {
+		t.Errorf("mismatched %v, want only identity/out-3m", mismatched)
+	}
+}
+
+func TestLimitedGas(t *testing.T) {
+	cost := params.Sha256BaseGas + params.Sha256PerWordGas*2
+	// The output buffer is the input's second word, which a failed call
+	// leaves as it was
+	untouched := append(append([]byte{}, input[32:]...), make([]byte, 24)...)
+	for _, tc := range []struct {
+		gas     uint64
+		success bool
+		used    uint64
+	}{
+		// A failing precompile consumes all the gas it was handed
+		{0, false, LimitedCallOverhead},
+		{cost - 1, false, LimitedCallOverhead + cost - 1},
+		{cost, true, LimitedCallOverhead + cost},
+		{cost + 1000, true, LimitedCallOverhead + cost},
+	} {
+		c := Case{Name: "sha256", Precompile: common.BytesToAddress([]byte{2}), Input: input, OutOffset: 32, OutSize: 32, Limited: true, CallGas: tc.gas}
+		got, err := Reference(c, 1_000_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Failed || got.Success != tc.success || got.GasUsed != tc.used {
+			t.Errorf("%d gas: %+v, want success=%t using %d", tc.gas, got, tc.success, tc.used)
+		}
+		if !tc.success && (got.ReturnSize != 0 || !bytes.Equal(got.Output, untouched)) {
+			t.Errorf("%d gas: a failed call left output %x, return data %d", tc.gas, got.Output, got.ReturnSize)
+		}
+	}
+}
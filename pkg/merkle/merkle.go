@@ -0,0 +1,179 @@
+// Package merkle builds SHA-256 Merkle trees locally and through the
+// Sha256Merkle contract (contracts/Sha256Merkle.sol), which calls the
+// SHA-256 precompile once per node in a loop, as bridges and light clients
+// do. Leaves are padded with zero to a power of two and a node is
+// sha256(left ++ right), as in SSZ.
+package merkle
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// abiJSON is the ABI of contracts/Sha256Merkle.sol.
+const abiJSON = `[
+{"type":"function","name":"root","stateMutability":"view",
+"inputs":[{"name":"leaves","type":"bytes32[]"}],"outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"verify","stateMutability":"view",
+"inputs":[{"name":"leaf","type":"bytes32"},{"name":"branch","type":"bytes32[]"},{"name":"index","type":"uint256"},{"name":"expected","type":"bytes32"}],
+"outputs":[{"name":"","type":"bool"}]}]`
+
+// ABI is the parsed Sha256Merkle ABI.
+var ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Width is the number of leaves the tree of n leaves is padded to.
+func Width(n int) int {
+	width := 1
+	for width < n {
+		width <<= 1
+	}
+	return width
+}
+
+// Hashes is the number of sha256 calls building the root of n leaves
+// takes.
+func Hashes(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return Width(n) - 1
+}
+
+func node(left, right common.Hash) common.Hash {
+	return sha256.Sum256(append(left.Bytes(), right.Bytes()...))
+}
+
+// levels returns every level of the tree of leaves, the padded leaves
+// first and the root last.
+func levels(leaves []common.Hash) [][]common.Hash {
+	level := make([]common.Hash, Width(len(leaves)))
+	copy(level, leaves)
+	out := [][]common.Hash{level}
+	for len(level) > 1 {
+		next := make([]common.Hash, len(level)/2)
+		for i := range next {
+			next[i] = node(level[2*i], level[2*i+1])
+		}
+		out = append(out, next)
+		level = next
+	}
+	return out
+}
+
+// Root is the root of leaves: zero for none, the leaf itself for one.
+func Root(leaves []common.Hash) common.Hash {
+	if len(leaves) == 0 {
+		return common.Hash{}
+	}
+	ls := levels(leaves)
+	return ls[len(ls)-1][0]
+}
+
+// Branch returns the siblings of leaf index from the leaves up.
+func Branch(leaves []common.Hash, index int) ([]common.Hash, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf %d of %d", index, len(leaves))
+	}
+	ls := levels(leaves)
+	branch := make([]common.Hash, 0, len(ls)-1)
+	for _, level := range ls[:len(ls)-1] {
+		branch = append(branch, level[index^1])
+		index >>= 1
+	}
+	return branch, nil
+}
+
+// Verify checks that leaf is at index under root, as the contract's verify
+// does.
+func Verify(leaf common.Hash, branch []common.Hash, index uint64, root common.Hash) bool {
+	if index>>len(branch) != 0 {
+		return false
+	}
+	n := leaf
+	for i, sibling := range branch {
+		if (index>>i)&1 == 1 {
+			n = node(sibling, n)
+		} else {
+			n = node(n, sibling)
+		}
+	}
+	return n == root
+}
+
+// Contract is a deployed Sha256Merkle.
+type Contract struct {
+	Client  *ethclient.Client
+	Address common.Address
+}
+
+// Root has the contract build the root of leaves.
+func (c Contract) Root(ctx context.Context, leaves []common.Hash) (common.Hash, error) {
+	values, err := c.call(ctx, "root", hashes(leaves))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return values[0].([32]byte), nil
+}
+
+// Verify has the contract check a branch.
+func (c Contract) Verify(ctx context.Context, leaf common.Hash, branch []common.Hash, index uint64, root common.Hash) (bool, error) {
+	values, err := c.call(ctx, "verify", leaf, hashes(branch), new(big.Int).SetUint64(index), root)
+	if err != nil {
+		return false, err
+	}
+	return values[0].(bool), nil
+}
+
+// PackRoot is the calldata of building the root of leaves, for sending it
+// in a transaction.
+func PackRoot(leaves []common.Hash) ([]byte, error) {
+	return ABI.Pack("root", hashes(leaves))
+}
+
+// EstimateRoot is the gas of building the root of leaves in a transaction.
+func (c Contract) EstimateRoot(ctx context.Context, leaves []common.Hash) (uint64, error) {
+	data, err := PackRoot(leaves)
+	if err != nil {
+		return 0, err
+	}
+	return c.Client.EstimateGas(ctx, ethereum.CallMsg{To: &c.Address, Data: data})
+}
+
+func (c Contract) call(ctx context.Context, method string, args ...any) ([]any, error) {
+	data, err := ABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+	}
+	out, err := c.Client.CallContract(ctx, ethereum.CallMsg{To: &c.Address, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s call failed: %w", method, err)
+	}
+	values, err := ABI.Unpack(method, out)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected %s output %x: %v", method, out, err)
+	}
+	return values, nil
+}
+
+// hashes converts to what the ABI packs as bytes32[].
+func hashes(hs []common.Hash) [][32]byte {
+	out := make([][32]byte, len(hs))
+	for i, h := range hs {
+		out[i] = h
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package merkle
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func leaves(n int) []common.Hash {
+	out := make([]common.Hash, n)
+	for i := range out {
+		out[i] = sha256.Sum256([]byte{byte(i)})
+	}
+	return out
+}
+
+func TestRoot(t *testing.T) {
+	ls := leaves(3)
+	if Root(nil) != (common.Hash{}) || Root(ls[:1]) != ls[0] {
+		t.Error("root of no leaves or one leaf")
+	}
+	// The third leaf is paired with a zero leaf
+	want := node(node(ls[0], ls[1]), node(ls[2], common.Hash{}))
+	if got := Root(ls); got != want {
+		t.Errorf("root of 3 leaves %s, want %s", got, want)
+	}
+	// SSZ's zero hash of depth 2
+	if got := Root(make([]common.Hash, 4)); got != common.HexToHash("0xdb56114e00fdd4c1f85c892bf35ac9a89289aaecb1ebd0a96cde606a748b5d71") {
+		t.Errorf("root of 4 zero leaves %s", got)
+	}
+	if Hashes(5) != 7 || Hashes(8) != 7 || Hashes(1) != 0 {
+		t.Errorf("hashes %d %d %d", Hashes(5), Hashes(8), Hashes(1))
+	}
+}
+
+func TestBranch(t *testing.T) {
+	ls := leaves(5)
+	root := Root(ls)
+	for i, leaf := range ls {
+		branch, err := Branch(ls, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(branch) != 3 || !Verify(leaf, branch, uint64(i), root) {
+			t.Errorf("leaf %d: branch %v doesn't verify", i, branch)
+		}
+		if Verify(leaf, branch, uint64(i^1), root) || Verify(leaf, branch, uint64(i+8), root) {
+			t.Errorf("leaf %d verified at another index", i)
+		}
+	}
+	if _, err := Branch(ls, 5); err == nil {
+		t.Error("branch of a missing leaf")
+	}
+}
+
+func TestContract(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	// The contract answers what the local tree does
+	s.Handle("eth_call", func(call mockrpc.Call) (any, error) {
+		var msg struct{ Input hexutil.Bytes }
+		if err := call.Param(0, &msg); err != nil {
+			return nil, err
+		}
+		method, err := ABI.MethodById(msg.Input[:4])
+		if err != nil {
+			return nil, err
+		}
+		args, err := method.Inputs.Unpack(msg.Input[4:])
+		if err != nil {
+			return nil, err
+		}
+		toHashes := func(v any) []common.Hash {
+			var hs []common.Hash
+			for _, h := range v.([][32]byte) {
+				hs = append(hs, h)
+			}
+			return hs
+		}
+		var out []byte
+		if method.Name == "root" {
+			out, err = method.Outputs.Pack([32]byte(Root(toHashes(args[0]))))
+		} else {
+			index := args[2].(*big.Int).Uint64()
+			out, err = method.Outputs.Pack(Verify(args[0].([32]byte), toHashes(args[1]), index, args[3].([32]byte)))
+		}
+		return hexutil.Bytes(out), err
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+	c := Contract{Client: client, Address: common.Address{0x3e}}
+
+	ls := leaves(6)
+	root, err := c.Root(ctx, ls)
+	if err != nil || root != Root(ls) {
+		t.Errorf("Root = %s, %v", root, err)
+	}
+	branch, _ := Branch(ls, 4)
+	if ok, err := c.Verify(ctx, ls[4], branch, 4, root); !ok || err != nil {
+		t.Errorf("Verify = %t, %v", ok, err)
+	}
+	if ok, err := c.Verify(ctx, ls[3], branch, 4, root); ok || err != nil {
+		t.Errorf("Verify of the wrong leaf = %t, %v", ok, err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package metrics writes run outcomes as OpenMetrics text, into files the
+// node_exporter textfile collector picks up, so monitoring stacks without a
+// Pushgateway can still scrape them. Every metric is a gauge: a file holds
+// the outcome of the last run, not a running count.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// EnvTextfileDir names the directory metrics files are written to, the one
+// node_exporter's --collector.textfile.directory reads.
+const EnvTextfileDir = "METRICS_TEXTFILE_DIR"
+
+// Labels are a sample's label names and values.
+type Labels map[string]string
+
+// Sample is one value of a metric.
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// Family is a gauge and its samples.
+type Family struct {
+	Name    string
+	Help    string
+	Samples []Sample
+}
+
+// Gauge returns a family of the samples.
+func Gauge(name, help string, samples ...Sample) Family {
+	return Family{Name: name, Help: help, Samples: samples}
+}
+
+// Encode writes the families in the OpenMetrics text format, ending with
+// the # EOF marker. Labels are written in name order.
+func Encode(w io.Writer, families []Family) error {
+	var b strings.Builder
+	for _, f := range families {
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", f.Name)
+		if f.Help != "" {
+			fmt.Fprintf(&b, "# HELP %s %s\n", f.Name, escape(f.Help, false))
+		}
+		for _, s := range f.Samples {
+			b.WriteString(f.Name)
+			if len(s.Labels) > 0 {
+				names := make([]string, 0, len(s.Labels))
+				for name := range s.Labels {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				b.WriteByte('{')
+				for i, name := range names {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", name, escape(s.Labels[name], true))
+				}
+				b.WriteByte('}')
+			}
+			fmt.Fprintf(&b, " %s\n", value(s.Value))
+		}
+	}
+	b.WriteString("# EOF\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escape escapes backslashes and newlines, and double quotes in label
+// values.
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+func value(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// WriteTextfile writes the families to name.prom in dir. The file is
+// written under a name the collector ignores and renamed into place, so a
+// scrape never reads half of it.
+func WriteTextfile(dir, name string, families []Family) (string, error) {
+	if err := os.MkdirAll(dir, paths.DirMode); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+".prom")
+	tmp, err := os.CreateTemp(dir, "."+name+".prom.*")
+	if err != nil {
+		return "", err
+	}
+	err = Encode(tmp, families)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// CreateTemp makes it owner-only, and node_exporter runs as
+		// another user
+		err = os.Chmod(tmp.Name(), paths.FileMode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write metrics to %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	var b strings.Builder
+	err := Encode(&b, []Family{
+		Gauge("suite_score", "Weighted conformance score.", Sample{Labels: Labels{"target": "configured"}, Value: 0.975}),
+		Gauge("suite_groups", "", Sample{Labels: Labels{"status": "failed", "target": `a "b"\c`}, Value: 2}, Sample{Value: math.NaN()}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE suite_score gauge
+# HELP suite_score Weighted conformance score.
+suite_score{target="configured"} 0.975
+# TYPE suite_groups gauge
+suite_groups{status="failed",target="a \"b\"\\c"} 2
+suite_groups NaN
+# EOF
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "textfile")
+	path, err := WriteTextfile(dir, "suite", []Family{Gauge("suite_duration_seconds", "", Sample{Value: 12.5})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dir, "suite.prom") {
+		t.Errorf("wrote %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "suite_duration_seconds 12.5\n") {
+		t.Errorf("file %q, %v", data, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory holds %v, %v; want only the .prom file", entries, err)
+	}
+}
+
+func TestGroupURL(t *testing.T) {
+	got, err := GroupURL("http://user:pw@pushgateway:9091/", "precompile_suite", Labels{
+		"node_version": "erigon/2.61.0/linux-amd64",
+		"network":      "cdk devnet",
+		"target":       "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "http://user:pw@pushgateway:9091/metrics/job/precompile_suite/network/cdk%20devnet" +
+		"/node_version@base64/ZXJpZ29uLzIuNjEuMC9saW51eC1hbWQ2NA/target@base64/="
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if _, err := GroupURL("pushgateway:9091", "job", nil); err == nil {
+		t.Error("accepted a URL without a scheme")
+	}
+}
+
+func TestPush(t *testing.T) {
+	var method, path, contentType, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, contentType, body = r.Method, r.URL.Path, r.Header.Get("Content-Type"), string(data)
+	}))
+	defer srv.Close()
+
+	families := []Family{Gauge("suite_duration_seconds", "", Sample{Labels: Labels{"network": "devnet"}, Value: 3})}
+	if err := Push(context.Background(), srv.URL, "suite", Labels{"network": "devnet"}, families); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/suite/network/devnet" || !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("%s %s as %s", method, path, contentType)
+	}
+	if !strings.Contains(body, `suite_duration_seconds{network="devnet"} 3`) {
+		t.Errorf("pushed %q", body)
+	}
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "inconsistent labels", http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	if err := Push(context.Background(), rejecting.URL, "suite", nil, families); err == nil || !strings.Contains(err.Error(), "inconsistent labels") {
+		t.Errorf("got %v, want the gateway's error", err)
+	}
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// EnvPushgateway names the Pushgateway runs push their metrics to, e.g.
+// http://pushgateway:9091. Credentials in the URL are sent as basic auth.
+const EnvPushgateway = "PUSHGATEWAY_URL"
+
+// Push replaces the metrics of a group on the Pushgateway at baseURL with
+// families. The group is the job and the grouping labels, which every
+// sample carrying one of them must match.
+func Push(ctx context.Context, baseURL, job string, grouping Labels, families []Family) error {
+	u, err := GroupURL(baseURL, job, grouping)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	if err := Encode(&body, families); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, &body)
+	if err != nil {
+		return err
+	}
+	// The Pushgateway takes the Prometheus text format, which reads the
+	// # EOF marker as a comment
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to push metrics: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// GroupURL is the URL of a group: /metrics/job/<job> and a /<name>/<value>
+// pair per grouping label, in name order. Values that can't be a path
+// segment, empty ones and those with a slash, are base64-encoded the way
+// the Pushgateway accepts them.
+func GroupURL(baseURL, job string, grouping Labels) (string, error) {
+	if job == "" {
+		return "", fmt.Errorf("pushgateway job name is empty")
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid pushgateway URL %q", baseURL)
+	}
+	var path strings.Builder
+	path.WriteString(strings.TrimSuffix(u.Path, "/"))
+	path.WriteString("/metrics")
+	names := make([]string, 0, len(grouping))
+	for name := range grouping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	segment(&path, "job", job)
+	for _, name := range names {
+		segment(&path, name, grouping[name])
+	}
+	u.Path, u.RawPath = path.String(), ""
+	return u.String(), nil
+}
+
+func segment(path *strings.Builder, name, value string) {
+	switch {
+	case value == "":
+		fmt.Fprintf(path, "/%s@base64/=", name)
+	case strings.Contains(value, "/"):
+		fmt.Fprintf(path, "/%s@base64/%s", name, base64.RawURLEncoding.EncodeToString([]byte(value)))
+	default:
+		fmt.Fprintf(path, "/%s/%s", name, value)
+	}
+}
@@ -0,0 +1,213 @@
+package mockrpc
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Chain is a minimal node behind a Server: it accepts signed transactions,
+// tracks nonces and mines each transaction into its own block once its
+// receipt has been polled ReceiptDelay times. Contract creations deploy
+// Code (or the init code itself when Code is nil) at the derived address.
+type Chain struct {
+	ChainID  *big.Int
+	GasPrice *big.Int
+	// ReceiptDelay is how many receipt polls return null before a sent
+	// transaction is mined.
+	ReceiptDelay int
+	// Code, if set, is the runtime code of every created contract.
+	Code []byte
+	// Fail, if set, reverts transactions it returns true for.
+	Fail func(*types.Transaction) bool
+	// Execute, if set, runs once a transaction is mined, standing in for
+	// the contract it calls: it may add logs to the receipt and change
+	// state with SetNonce and SetCode. The bloom is recomputed after it.
+	Execute func(from common.Address, tx *types.Transaction, r *types.Receipt)
+
+	// mining serializes receipt polls, so each transaction is mined and
+	// executed once while Execute can still take mu
+	mining sync.Mutex
+	mu     sync.Mutex
+	nonces map[common.Address]uint64
+	txs    map[common.Hash]*pending
+	code   map[common.Address][]byte
+	head   uint64
+}
+
+type pending struct {
+	tx      *types.Transaction
+	from    common.Address
+	polls   int
+	receipt *types.Receipt
+}
+
+// NewChain installs the chain's methods on s: eth_chainId, net_version,
+// eth_gasPrice, eth_blockNumber, eth_getTransactionCount,
+// eth_sendRawTransaction, eth_getTransactionReceipt and eth_getCode.
+// Individual methods can still be overridden with s.Handle afterwards.
+func NewChain(s *Server, chainID *big.Int) *Chain {
+	c := &Chain{
+		ChainID:  chainID,
+		GasPrice: big.NewInt(1e9),
+		nonces:   map[common.Address]uint64{},
+		txs:      map[common.Hash]*pending{},
+		code:     map[common.Address][]byte{},
+	}
+	s.Handle("eth_chainId", func(Call) (any, error) { return (*hexutil.Big)(c.ChainID), nil })
+	s.Handle("net_version", func(Call) (any, error) { return c.ChainID.String(), nil })
+	s.Handle("eth_gasPrice", func(Call) (any, error) { return (*hexutil.Big)(c.GasPrice), nil })
+	s.Handle("eth_blockNumber", func(Call) (any, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return hexutil.Uint64(c.head), nil
+	})
+	s.Handle("eth_getTransactionCount", c.transactionCount)
+	s.Handle("eth_sendRawTransaction", c.sendRawTransaction)
+	s.Handle("eth_getTransactionReceipt", c.transactionReceipt)
+	s.Handle("eth_getCode", c.getCode)
+	return c
+}
+
+// SetNonce sets the next nonce of addr.
+func (c *Chain) SetNonce(addr common.Address, nonce uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nonces[addr] = nonce
+}
+
+// SetCode places code at addr.
+func (c *Chain) SetCode(addr common.Address, code []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.code[addr] = code
+}
+
+// Sent returns the transactions received so far.
+func (c *Chain) Sent() []*types.Transaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var txs []*types.Transaction
+	for _, p := range c.txs {
+		txs = append(txs, p.tx)
+	}
+	return txs
+}
+
+func (c *Chain) transactionCount(call Call) (any, error) {
+	var addr common.Address
+	if err := call.Param(0, &addr); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return hexutil.Uint64(c.nonces[addr]), nil
+}
+
+func (c *Chain) sendRawTransaction(call Call) (any, error) {
+	var raw hexutil.Bytes
+	if err := call.Param(0, &raw); err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, &Error{Code: -32000, Message: fmt.Sprintf("rlp: %v", err)}
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(c.ChainID), tx)
+	if err != nil {
+		return nil, &Error{Code: -32000, Message: fmt.Sprintf("invalid sender: %v", err)}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.txs[tx.Hash()]; ok {
+		return nil, &Error{Code: -32000, Message: "already known"}
+	}
+	if want := c.nonces[from]; tx.Nonce() != want {
+		return nil, &Error{Code: -32000, Message: fmt.Sprintf("invalid nonce: have %d, want %d", tx.Nonce(), want)}
+	}
+	c.nonces[from]++
+	c.txs[tx.Hash()] = &pending{tx: tx, from: from}
+	return tx.Hash(), nil
+}
+
+func (c *Chain) transactionReceipt(call Call) (any, error) {
+	var hash common.Hash
+	if err := call.Param(0, &hash); err != nil {
+		return nil, err
+	}
+	c.mining.Lock()
+	defer c.mining.Unlock()
+	c.mu.Lock()
+	p, ok := c.txs[hash]
+	c.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	if p.receipt != nil {
+		return p.receipt, nil
+	}
+	p.polls++
+	if p.polls <= c.ReceiptDelay {
+		return nil, nil
+	}
+	c.mu.Lock()
+	r := c.mine(p)
+	c.mu.Unlock()
+
+	if c.Execute != nil {
+		c.Execute(p.from, p.tx, r)
+		r.Bloom = types.CreateBloom(r)
+	}
+	p.receipt = r
+	return r, nil
+}
+
+// mine puts p alone into the next block.
+func (c *Chain) mine(p *pending) *types.Receipt {
+	c.head++
+	status := types.ReceiptStatusSuccessful
+	if c.Fail != nil && c.Fail(p.tx) {
+		status = types.ReceiptStatusFailed
+	}
+	gasUsed := p.tx.Gas() / 2
+	r := &types.Receipt{
+		Type:              p.tx.Type(),
+		Status:            status,
+		CumulativeGasUsed: gasUsed,
+		Logs:              []*types.Log{},
+		TxHash:            p.tx.Hash(),
+		GasUsed:           gasUsed,
+		EffectiveGasPrice: p.tx.GasPrice(),
+		BlockHash:         crypto.Keccak256Hash(new(big.Int).SetUint64(c.head).Bytes()),
+		BlockNumber:       new(big.Int).SetUint64(c.head),
+		TransactionIndex:  0,
+	}
+	r.Bloom = types.CreateBloom(r)
+	if p.tx.To() == nil {
+		r.ContractAddress = crypto.CreateAddress(p.from, p.tx.Nonce())
+		if status == types.ReceiptStatusSuccessful {
+			code := c.Code
+			if code == nil {
+				code = p.tx.Data()
+			}
+			c.code[r.ContractAddress] = code
+		}
+	}
+	return r
+}
+
+func (c *Chain) getCode(call Call) (any, error) {
+	var addr common.Address
+	if err := call.Param(0, &addr); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return hexutil.Bytes(c.code[addr]), nil
+}
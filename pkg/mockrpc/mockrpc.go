@@ -0,0 +1,236 @@
+// Package mockrpc is an httptest-based Ethereum JSON-RPC server with
+// scriptable responses, for unit-testing the harness without a devnet.
+// Handlers are registered per method and see how many times the method has
+// been called, so tests can script receipts appearing after N polls, flaky
+// errors, HTTP failures and wrong answers. Chain provides a minimal account
+// and transaction model on top for the transactional paths.
+package mockrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Call is one JSON-RPC request as seen by a handler.
+type Call struct {
+	Method string
+	Params []json.RawMessage
+	// N counts the calls of Method so far, starting at 1.
+	N int
+}
+
+// Param decodes the i-th parameter into v.
+func (c Call) Param(i int, v any) error {
+	if i >= len(c.Params) {
+		return fmt.Errorf("%s: missing param %d", c.Method, i)
+	}
+	return json.Unmarshal(c.Params[i], v)
+}
+
+// Handler answers a call. A nil result is sent as JSON null. Returning an
+// *Error sends a JSON-RPC error, an HTTPError fails the whole HTTP request
+// with that status, ErrDrop closes the connection without answering, and
+// any other error becomes a -32000 JSON-RPC error.
+type Handler func(Call) (any, error)
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%d: %s", e.Code, e.Message) }
+
+// HTTPError makes the server answer with a bare HTTP status.
+type HTTPError int
+
+func (e HTTPError) Error() string { return fmt.Sprintf("HTTP %d", int(e)) }
+
+// ErrDrop makes the server close the connection without answering, like a
+// node going away mid-request.
+var ErrDrop = errors.New("connection dropped")
+
+// Server is a mock JSON-RPC endpoint.
+type Server struct {
+	URL string
+
+	srv      *httptest.Server
+	mu       sync.Mutex
+	handlers map[string]Handler
+	counts   map[string]int
+	log      []Call
+}
+
+// New starts a server. Unhandled methods answer with -32601.
+func New() *Server {
+	s := &Server{handlers: map[string]Handler{}, counts: map[string]int{}}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() { s.srv.Close() }
+
+// Handle registers h for method, replacing any previous handler.
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = h
+}
+
+// Result answers method with v on every call.
+func (s *Server) Result(method string, v any) { s.Handle(method, Static(v)) }
+
+// Calls returns how many times method was called.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[method]
+}
+
+// Log returns every call received, in order.
+func (s *Server) Log() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.log...)
+}
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// MarshalJSON omits result on errors, as the spec requires.
+func (r response) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+			Error   *Error          `json:"error"`
+		}{r.JSONRPC, r.ID, r.Error})
+	}
+	type plain response
+	return json.Marshal(plain(r))
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var batch []request
+	isBatch := json.Unmarshal(body, &batch) == nil
+	if !isBatch {
+		var single request
+		if err := json.Unmarshal(body, &single); err != nil {
+			http.Error(w, "invalid JSON-RPC request", http.StatusBadRequest)
+			return
+		}
+		batch = []request{single}
+	}
+
+	responses := make([]response, len(batch))
+	for i, req := range batch {
+		result, err := s.dispatch(req)
+		if errors.Is(err, ErrDrop) {
+			if conn, _, herr := http.NewResponseController(w).Hijack(); herr == nil {
+				conn.Close()
+			}
+			return
+		}
+		var status HTTPError
+		if errors.As(err, &status) {
+			w.WriteHeader(int(status))
+			return
+		}
+		responses[i] = response{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if err != nil {
+			var rpcErr *Error
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &Error{Code: -32000, Message: err.Error()}
+			}
+			responses[i].Error = rpcErr
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if isBatch {
+		json.NewEncoder(w).Encode(responses)
+	} else {
+		json.NewEncoder(w).Encode(responses[0])
+	}
+}
+
+func (s *Server) dispatch(req request) (any, error) {
+	s.mu.Lock()
+	s.counts[req.Method]++
+	call := Call{Method: req.Method, Params: req.Params, N: s.counts[req.Method]}
+	s.log = append(s.log, call)
+	h, ok := s.handlers[req.Method]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, &Error{Code: -32601, Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method)}
+	}
+	return h(call)
+}
+
+// Static answers every call with v.
+func Static(v any) Handler {
+	return func(Call) (any, error) { return v, nil }
+}
+
+// Fail answers every call with err.
+func Fail(err error) Handler {
+	return func(Call) (any, error) { return nil, err }
+}
+
+// AfterPolls answers null for the first n calls and v afterwards, like a
+// receipt that appears once the transaction is mined.
+func AfterPolls(n int, v any) Handler {
+	return func(c Call) (any, error) {
+		if c.N <= n {
+			return nil, nil
+		}
+		return v, nil
+	}
+}
+
+// FailFirst answers the first n calls with err and delegates the rest to h.
+func FailFirst(n int, err error, h Handler) Handler {
+	return func(c Call) (any, error) {
+		if c.N <= n {
+			return nil, err
+		}
+		return h(c)
+	}
+}
+
+// Sequence answers the i-th call with the i-th handler; the last handler
+// answers every call after that.
+func Sequence(hs ...Handler) Handler {
+	return func(c Call) (any, error) {
+		i := c.N - 1
+		if i >= len(hs) {
+			i = len(hs) - 1
+		}
+		return hs[i](c)
+	}
+}
@@ -0,0 +1,112 @@
+package mockrpc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func dial(t *testing.T, s *Server) *ethclient.Client {
+	t.Helper()
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestUnhandledMethod(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	_, err := dial(t, s).BlockNumber(context.Background())
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != -32601 {
+		t.Fatalf("got %v, want a -32601 error", err)
+	}
+	if n := s.Calls("eth_blockNumber"); n != 1 {
+		t.Errorf("recorded %d calls, want 1", n)
+	}
+}
+
+func TestAfterPolls(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.Handle("eth_blockNumber", AfterPolls(2, "0x10"))
+
+	client := dial(t, s)
+	for i := 0; i < 2; i++ {
+		if _, err := client.BlockNumber(context.Background()); err == nil {
+			t.Fatalf("poll %d: expected a null result to fail decoding", i+1)
+		}
+	}
+	n, err := client.BlockNumber(context.Background())
+	if err != nil || n != 16 {
+		t.Fatalf("third poll: got %d, %v", n, err)
+	}
+}
+
+func TestFailFirstAndHTTPError(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.Handle("eth_chainId", FailFirst(1, HTTPError(503), Static("0x2775")))
+
+	client := dial(t, s)
+	_, err := client.ChainID(context.Background())
+	var httpErr rpc.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 503 {
+		t.Fatalf("first call: got %v, want HTTP 503", err)
+	}
+	id, err := client.ChainID(context.Background())
+	if err != nil || id.Cmp(big.NewInt(10101)) != 0 {
+		t.Fatalf("second call: got %v, %v", id, err)
+	}
+}
+
+func TestSequenceAndJSONRPCError(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.Handle("eth_getCode", Sequence(
+		Fail(&Error{Code: -32005, Message: "limit exceeded"}),
+		Static("0x6001"),
+	))
+
+	client := dial(t, s)
+	_, err := client.CodeAt(context.Background(), common.Address{}, nil)
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("first call: got %v, want -32005", err)
+	}
+	for i := 0; i < 2; i++ {
+		code, err := client.CodeAt(context.Background(), common.Address{}, nil)
+		if err != nil || len(code) != 2 {
+			t.Fatalf("call %d: got %x, %v", i+2, code, err)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.Result("eth_blockNumber", "0x1")
+	s.Result("eth_chainId", "0x2")
+
+	var a, b string
+	batch := []rpc.BatchElem{
+		{Method: "eth_blockNumber", Result: &a},
+		{Method: "eth_chainId", Result: &b},
+		{Method: "eth_missing", Result: new(string)},
+	}
+	if err := dial(t, s).Client().BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if a != "0x1" || b != "0x2" || batch[0].Error != nil || batch[2].Error == nil {
+		t.Fatalf("got %q %q, errors %v", a, b, []error{batch[0].Error, batch[1].Error, batch[2].Error})
+	}
+}
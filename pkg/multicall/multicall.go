@@ -0,0 +1,93 @@
+// Package multicall batches calls through a Multicall3 aggregator, which is
+// how indexers and frontends usually reach precompiles: many calls in one
+// eth_call or transaction, each answered with its own success flag.
+package multicall
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// CanonicalAddress is where Multicall3 lives on most chains, deployed by
+// the same presigned transaction everywhere.
+var CanonicalAddress = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// abiJSON is the aggregate3 subset of Multicall3 that contracts/Multicall3.sol
+// implements.
+const abiJSON = `[{"type":"function","name":"aggregate3","stateMutability":"payable",
+"inputs":[{"name":"calls","type":"tuple[]","components":[
+	{"name":"target","type":"address"},
+	{"name":"allowFailure","type":"bool"},
+	{"name":"callData","type":"bytes"}]}],
+"outputs":[{"name":"returnData","type":"tuple[]","components":[
+	{"name":"success","type":"bool"},
+	{"name":"returnData","type":"bytes"}]}]}]`
+
+// ABI is the parsed aggregate3 ABI.
+var ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Call is one call of a batch. A failing call reverts the whole batch
+// unless AllowFailure is set.
+type Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// Result is the outcome of one call of a batch.
+type Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// Pack encodes an aggregate3 call of calls.
+func Pack(calls []Call) ([]byte, error) {
+	data, err := ABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3: %w", err)
+	}
+	return data, nil
+}
+
+// Unpack decodes the results of an aggregate3 call.
+func Unpack(out []byte) ([]Result, error) {
+	values, err := ABI.Unpack("aggregate3", out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack aggregate3 results: %w", err)
+	}
+	results := *abi.ConvertType(values[0], new([]Result)).(*[]Result)
+	return results, nil
+}
+
+// Aggregate3 sends calls through the aggregator at address in a single
+// eth_call. A batch reverted by a call without AllowFailure is an error.
+func Aggregate3(ctx context.Context, client *ethclient.Client, address common.Address, calls []Call) ([]Result, error) {
+	data, err := Pack(calls)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate3 call failed: %w", err)
+	}
+	results, err := Unpack(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(results), len(calls))
+	}
+	return results, nil
+}
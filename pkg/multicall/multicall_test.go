@@ -0,0 +1,92 @@
+package multicall
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+// aggregator answers aggregate3 like the contract would, with sha256 at
+// 0x02 and every other target failing.
+func aggregator(c mockrpc.Call) (any, error) {
+	var args struct {
+		Input hexutil.Bytes `json:"input"`
+	}
+	if err := c.Param(0, &args); err != nil {
+		return nil, err
+	}
+	method := ABI.Methods["aggregate3"]
+	values, err := method.Inputs.Unpack(args.Input[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := *abi.ConvertType(values[0], new([]Call)).(*[]Call)
+
+	results := make([]Result, len(calls))
+	for i, call := range calls {
+		if call.Target == common.HexToAddress("0x02") {
+			sum := sha256.Sum256(call.CallData)
+			results[i] = Result{Success: true, ReturnData: sum[:]}
+			continue
+		}
+		if !call.AllowFailure {
+			return nil, &mockrpc.Error{Code: 3, Message: "execution reverted: Multicall3: call failed"}
+		}
+	}
+	out, err := method.Outputs.Pack(results)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(out), nil
+}
+
+func TestAggregate3(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_call", aggregator)
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	calls := []Call{
+		{Target: common.HexToAddress("0x02"), CallData: []byte("hello")},
+		{Target: common.HexToAddress("0x08"), AllowFailure: true, CallData: []byte{1}},
+		{Target: common.HexToAddress("0x02")},
+	}
+	results, err := Aggregate3(ctx, client, CanonicalAddress, calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("%d results, want 3", len(results))
+	}
+	hello, empty := sha256.Sum256([]byte("hello")), sha256.Sum256(nil)
+	if !results[0].Success || string(results[0].ReturnData) != string(hello[:]) {
+		t.Errorf("result 0: %+v", results[0])
+	}
+	if results[1].Success || len(results[1].ReturnData) != 0 {
+		t.Errorf("allowed failure reported as %+v", results[1])
+	}
+	if !results[2].Success || string(results[2].ReturnData) != string(empty[:]) {
+		t.Errorf("result 2: %+v", results[2])
+	}
+
+	// Without allowFailure the failing call reverts the batch
+	calls[1].AllowFailure = false
+	if _, err := Aggregate3(ctx, client, CanonicalAddress, calls); err == nil {
+		t.Error("batch with a disallowed failure didn't revert")
+	}
+	if n := s.Calls("eth_call"); n != 2 {
+		t.Errorf("%d eth_calls, want one per batch", n)
+	}
+}
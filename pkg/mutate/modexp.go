@@ -0,0 +1,98 @@
+package mutate
+
+import (
+	"math/big"
+
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+// ModExp is the precompile at 0x05, whose input starts with the base,
+// exponent and modulus lengths as 32-byte words. The reference follows
+// EIP-198 with EIP-2565 pricing: missing input bytes read as zero, and
+// a call fails when its gas exceeds gasCap less the intrinsic cost.
+func ModExp(gasCap uint64) Target {
+	return Target{
+		Name:   "modexp",
+		Encode: clone,
+		Prefixes: []Span{
+			{Name: "base-length", Offset: 0, Len: 32},
+			{Name: "exp-length", Offset: 32, Len: 32},
+			{Name: "mod-length", Offset: 64, Len: 32},
+		},
+		Reference: func(input []byte) Outcome {
+			return modexpReference(input, gasCap)
+		},
+	}
+}
+
+func modexpReference(input []byte, gasCap uint64) Outcome {
+	baseLen := word(input, big.NewInt(0))
+	expLen := word(input, big.NewInt(32))
+	modLen := word(input, big.NewInt(64))
+
+	available := gasCap - min(gasCap, intrinsicGas(input))
+	if modexpGas(input, baseLen, expLen, modLen).Cmp(new(big.Int).SetUint64(available)) > 0 {
+		return Outcome{Fails: true}
+	}
+	// Within the gas cap every length is small
+	out, err := reference.ModExp(input)
+	if err != nil {
+		return Outcome{Fails: true}
+	}
+	return Outcome{Output: out}
+}
+
+// modexpGas prices lengths of any size, which a flipped prefix easily
+// makes too large for precompile.ModExp.Gas to handle.
+func modexpGas(input []byte, baseLen, expLen, modLen *big.Int) *big.Int {
+	maxLen := baseLen
+	if modLen.Cmp(maxLen) > 0 {
+		maxLen = modLen
+	}
+	words := new(big.Int).Add(maxLen, big.NewInt(7))
+	words.Rsh(words, 3)
+	complexity := new(big.Int).Mul(words, words)
+
+	// Only the first 32 bytes of the exponent count by value
+	headLen := uint64(32)
+	if expLen.IsUint64() && expLen.Uint64() < 32 {
+		headLen = expLen.Uint64()
+	}
+	var head *big.Int
+	if baseLen.IsUint64() && baseLen.Uint64() <= uint64(len(input)) {
+		head = new(big.Int).SetBytes(read(input, 96+baseLen.Uint64(), headLen))
+	} else {
+		head = new(big.Int)
+	}
+	iterations := big.NewInt(0)
+	if bits := head.BitLen(); bits > 0 {
+		iterations.SetInt64(int64(bits - 1))
+	}
+	if expLen.Cmp(big.NewInt(32)) > 0 {
+		extra := new(big.Int).Sub(expLen, big.NewInt(32))
+		iterations.Add(iterations, extra.Lsh(extra, 3))
+	}
+	if iterations.Sign() == 0 {
+		iterations.SetInt64(1)
+	}
+
+	gas := new(big.Int).Mul(complexity, iterations)
+	gas.Div(gas, big.NewInt(3))
+	if gas.Cmp(big.NewInt(200)) < 0 {
+		gas.SetInt64(200)
+	}
+	return gas
+}
+
+// intrinsicGas is the cost of a call transaction carrying input.
+func intrinsicGas(input []byte) uint64 {
+	gas := uint64(21000)
+	for _, b := range input {
+		if b == 0 {
+			gas += 4
+		} else {
+			gas += 16
+		}
+	}
+	return gas
+}
@@ -0,0 +1,101 @@
+// Package mutate derives deterministic mutations from known-answer vectors
+// — one byte truncated, one byte appended, each byte of a length prefix
+// flipped — and predicts what a conforming node answers for each, so
+// off-by-one parsing bugs show up as a mismatch against the local
+// reference instead of depending on random fuzzing to hit them.
+package mutate
+
+import (
+	"fmt"
+	"math/big"
+
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+// Span is a length prefix inside an encoded input.
+type Span struct {
+	Name   string
+	Offset int
+	Len    int
+}
+
+// Mutation is one mutated input.
+type Mutation struct {
+	Name  string
+	Input []byte
+}
+
+// Outcome is what a call answers: Output, or a failure (a revert or an
+// error) when Fails is set.
+type Outcome struct {
+	Output []byte
+	Fails  bool
+}
+
+// Target describes how a precompile or contract reads its input.
+type Target struct {
+	Name string
+	// Encode turns a vector into the call input.
+	Encode func(vector []byte) []byte
+	// Prefixes are the length prefixes of an encoded input.
+	Prefixes []Span
+	// Reference predicts the outcome of any input, well-formed or not.
+	Reference func(input []byte) Outcome
+}
+
+// Matrix returns the mutations of an encoded input: truncated by one byte
+// (unless empty), extended by one zero byte, and every byte of each prefix
+// inverted.
+func Matrix(input []byte, prefixes []Span) []Mutation {
+	var ms []Mutation
+	if len(input) > 0 {
+		ms = append(ms, Mutation{Name: "truncate-1", Input: clone(input[:len(input)-1])})
+	}
+	ms = append(ms, Mutation{Name: "append-1", Input: append(clone(input), 0x00)})
+	for _, p := range prefixes {
+		for i := 0; i < p.Len && p.Offset+i < len(input); i++ {
+			flipped := clone(input)
+			flipped[p.Offset+i] ^= 0xff
+			ms = append(ms, Mutation{Name: fmt.Sprintf("flip-%s-%d", p.Name, i), Input: flipped})
+		}
+	}
+	return ms
+}
+
+func clone(b []byte) []byte {
+	return append([]byte{}, b...)
+}
+
+// SHA256 is the precompile at 0x02, which hashes its input as is: there is
+// no prefix, and truncation or extension simply hashes different bytes.
+func SHA256() Target {
+	return Target{
+		Name:   "sha256",
+		Encode: clone,
+		Reference: func(input []byte) Outcome {
+			out, _ := reference.SHA256(input)
+			return Outcome{Output: out}
+		},
+	}
+}
+
+// word reads the 32-byte big-endian word at offset, zero-padding past the
+// end of data like CALLDATALOAD.
+func word(data []byte, offset *big.Int) *big.Int {
+	buf := make([]byte, 32)
+	if offset.IsInt64() && offset.Int64() < int64(len(data)) {
+		copy(buf, data[offset.Int64():])
+	}
+	return new(big.Int).SetBytes(buf)
+}
+
+// read returns n bytes of data at offset, zero-padded past its end.
+func read(data []byte, offset, n uint64) []byte {
+	buf := make([]byte, n)
+	if offset < uint64(len(data)) {
+		copy(buf, data[offset:])
+	}
+	return buf
+}
+
+var maxUint64 = new(big.Int).SetUint64(^uint64(0))
@@ -0,0 +1,157 @@
+package mutate
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+
+	"cdk-erigon-precompile/pkg/precompile"
+)
+
+var vectors = [][]byte{
+	[]byte("hello world"),
+	{},
+	[]byte("The quick brown fox jumps over the lazy dog"),
+	bytes.Repeat([]byte{0xab}, 32),
+}
+
+func TestMatrix(t *testing.T) {
+	ms := Matrix([]byte{1, 2, 3, 4}, []Span{{Name: "len", Offset: 1, Len: 2}})
+	var names []string
+	for _, m := range ms {
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, ","); got != "truncate-1,append-1,flip-len-0,flip-len-1" {
+		t.Fatalf("mutations %s", got)
+	}
+	if !bytes.Equal(ms[0].Input, []byte{1, 2, 3}) || !bytes.Equal(ms[1].Input, []byte{1, 2, 3, 4, 0}) ||
+		!bytes.Equal(ms[3].Input, []byte{1, 2, 0xfc, 4}) {
+		t.Errorf("inputs %x", ms)
+	}
+	if len(Matrix(nil, nil)) != 1 {
+		t.Error("an empty input can't be truncated")
+	}
+}
+
+// deployWrapper runs the compiled wrapper in go-ethereum's EVM, so the
+// reference is checked against what solc actually generated.
+func deployWrapper(t *testing.T) (*abi.ABI, func([]byte) Outcome) {
+	t.Helper()
+	abiData, err := os.ReadFile("../../artifacts/Sha256Wrapper.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(abiData)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, err := os.ReadFile("../../artifacts/Sha256Wrapper.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := new(runtime.Config)
+	_, address, _, err := runtime.Create(common.FromHex(strings.TrimSpace(string(bin))), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &parsed, func(input []byte) Outcome {
+		out, _, err := runtime.Call(address, input, cfg)
+		if err != nil {
+			return Outcome{Fails: true}
+		}
+		return Outcome{Output: out}
+	}
+}
+
+func TestWrapperReferenceMatchesEVM(t *testing.T) {
+	parsed, call := deployWrapper(t)
+	target := Wrapper(parsed)
+	for _, v := range vectors {
+		input := target.Encode(v)
+		for _, m := range append([]Mutation{{Name: "original", Input: input}}, Matrix(input, target.Prefixes)...) {
+			want, got := target.Reference(m.Input), call(m.Input)
+			if want.Fails != got.Fails || !bytes.Equal(want.Output, got.Output) {
+				t.Errorf("%q %s: reference %+v, EVM %+v", v, m.Name, want, got)
+			}
+		}
+	}
+}
+
+func TestWrapperReference(t *testing.T) {
+	parsed, _ := deployWrapper(t)
+	target := Wrapper(parsed)
+	input := target.Encode([]byte("hello world"))
+	original := target.Reference(input)
+
+	// Dropping a padding byte or adding one past the data changes nothing;
+	// a length pointing past the data reverts
+	for name, want := range map[string]bool{"truncate-1": false, "append-1": false, "flip-length-31": true} {
+		for _, m := range Matrix(input, target.Prefixes) {
+			if m.Name != name {
+				continue
+			}
+			got := target.Reference(m.Input)
+			if got.Fails != want || (!want && !bytes.Equal(got.Output, original.Output)) {
+				t.Errorf("%s: %+v", name, got)
+			}
+		}
+	}
+}
+
+func TestModExpReference(t *testing.T) {
+	target := ModExp(50_000_000)
+	m := precompile.ModExp{Base: []byte{3}, Exp: []byte{5}, Mod: []byte{0, 100}}
+	input := target.Encode(m.Input())
+	if got := target.Reference(input); got.Fails || !bytes.Equal(got.Output, m.Expected()) {
+		t.Fatalf("original: %+v, want %x", got, m.Expected())
+	}
+
+	outcomes := map[string]Outcome{}
+	for _, mut := range Matrix(input, target.Prefixes) {
+		outcomes[mut.Name] = target.Reference(mut.Input)
+	}
+	// The last modulus byte is missing and reads as zero: 3^5 % 0x0000 = 0
+	if got := outcomes["truncate-1"]; got.Fails || !bytes.Equal(got.Output, []byte{0, 0}) {
+		t.Errorf("truncate-1: %+v", got)
+	}
+	if got := outcomes["append-1"]; got.Fails || !bytes.Equal(got.Output, m.Expected()) {
+		t.Errorf("append-1: %+v", got)
+	}
+	// A flipped high byte makes the modulus length astronomically large
+	if got := outcomes["flip-mod-length-0"]; !got.Fails {
+		t.Errorf("flip-mod-length-0: %+v", got)
+	}
+	// 0xfe base bytes, mostly read past the input as zeros
+	if got := outcomes["flip-base-length-31"]; got.Fails {
+		t.Errorf("flip-base-length-31: %+v", got)
+	}
+
+	// Well-formed probes agree with the precompile package's gas and result
+	for _, p := range precompile.ModExpProbes([]int{1024}) {
+		if got := target.Reference(p.Input()); got.Fails || !bytes.Equal(got.Output, p.Expected()) {
+			t.Errorf("%s: reference %+v", p.Name, got)
+		}
+		input := p.Input()
+		gas := modexpGas(input, word(input, big.NewInt(0)), word(input, big.NewInt(32)), word(input, big.NewInt(64)))
+		if gas.Uint64() != p.Gas() {
+			t.Errorf("%s: gas %s, precompile.ModExp.Gas %d", p.Name, gas, p.Gas())
+		}
+	}
+}
+
+func TestSHA256Reference(t *testing.T) {
+	target := SHA256()
+	for _, v := range vectors {
+		for _, m := range Matrix(target.Encode(v), target.Prefixes) {
+			if got := target.Reference(m.Input); got.Fails || len(got.Output) != 32 {
+				t.Errorf("%s: %+v", m.Name, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package mutate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Wrapper is Sha256Wrapper.sha256Hash(bytes), whose calldata carries the
+// input's ABI length word at offset 36. The reference follows the checks
+// Solidity's ABI decoder makes before the function runs, so it predicts a
+// revert for a length pointing past the calldata and a digest of exactly
+// the declared bytes otherwise, however much padding follows.
+func Wrapper(parsedABI *abi.ABI) Target {
+	method := parsedABI.Methods["sha256Hash"]
+	return Target{
+		Name: "wrapper",
+		Encode: func(vector []byte) []byte {
+			// Packing bytes can't fail
+			input, _ := parsedABI.Pack("sha256Hash", vector)
+			return input
+		},
+		Prefixes: []Span{{Name: "length", Offset: 36, Len: 32}},
+		Reference: func(input []byte) Outcome {
+			if len(input) < 4 || !bytes.Equal(input[:4], method.ID) {
+				return Outcome{Fails: true}
+			}
+			data, ok := decodeBytes(input[4:])
+			if !ok {
+				return Outcome{Fails: true}
+			}
+			sum := sha256.Sum256(data)
+			return Outcome{Output: sum[:]}
+		},
+	}
+}
+
+// decodeBytes decodes a single dynamic bytes argument the way solc's
+// decoder does, reporting false where it would revert.
+func decodeBytes(args []byte) ([]byte, bool) {
+	end := big.NewInt(int64(len(args)))
+	if len(args) < 32 {
+		return nil, false
+	}
+	offset := word(args, big.NewInt(0))
+	if offset.Cmp(maxUint64) > 0 {
+		return nil, false
+	}
+	// The length word must start inside the calldata
+	if new(big.Int).Add(offset, big.NewInt(0x1f)).Cmp(end) >= 0 {
+		return nil, false
+	}
+	length := word(args, offset)
+	if length.Cmp(maxUint64) > 0 {
+		return nil, false
+	}
+	src := new(big.Int).Add(offset, big.NewInt(32))
+	if new(big.Int).Add(src, length).Cmp(end) > 0 {
+		return nil, false
+	}
+	return args[src.Int64() : src.Int64()+length.Int64()], true
+}
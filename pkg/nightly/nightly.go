@@ -0,0 +1,300 @@
+// Package nightly runs the suite against several networks, the shape of a
+// nightly conformance job, and consolidates what each run left in its own
+// work directory into one cross-network report.
+//
+// The networks are listed in a JSON file:
+//
+//	{
+//	  "args": ["--exclude-tags", "slow"],
+//	  "networks": [
+//	    {"name": "cardona", "envFiles": [".env.cardona"], "profile": "cardona"},
+//	    {"name": "devnet", "envFiles": [".env", ".env.devnet"], "deploy": true}
+//	  ]
+//	}
+package nightly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+
+	"cdk-erigon-precompile/pkg/score"
+	"cdk-erigon-precompile/pkg/skip"
+)
+
+// Network is one network the suite runs against.
+type Network struct {
+	// Name names the network's work directory, so it is restricted to
+	// characters safe in a path.
+	Name string `json:"name"`
+	// EnvFiles are passed to the run as --env-file, layered in order.
+	EnvFiles []string `json:"envFiles"`
+	// Profile sets CHAIN_PROFILE, and Env any other variable, for the
+	// network's run; both win over its env files.
+	Profile string            `json:"profile,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	// Args are run.go flags for this network only, after the shared ones.
+	Args []string `json:"args,omitempty"`
+	// Deploy runs stage 2 first when the work directory has no deployed
+	// wrapper yet.
+	Deploy bool `json:"deploy,omitempty"`
+}
+
+// Config is the content of the networks file.
+type Config struct {
+	// Args are run.go flags for every network.
+	Args     []string  `json:"args,omitempty"`
+	Networks []Network `json:"networks"`
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Load reads and validates a networks file.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Config{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(c.Networks) == 0 {
+		return Config{}, fmt.Errorf("%s lists no networks", path)
+	}
+	seen := map[string]bool{}
+	for _, n := range c.Networks {
+		if !validName.MatchString(n.Name) {
+			return Config{}, fmt.Errorf("%s: invalid network name %q", path, n.Name)
+		}
+		if seen[n.Name] {
+			return Config{}, fmt.Errorf("%s: network %q listed twice", path, n.Name)
+		}
+		seen[n.Name] = true
+	}
+	return c, nil
+}
+
+// Select keeps the named networks, in the file's order. No names keeps
+// them all.
+func (c Config) Select(names []string) ([]Network, error) {
+	if len(names) == 0 {
+		return c.Networks, nil
+	}
+	var out []Network
+	for _, name := range names {
+		if !slices.ContainsFunc(c.Networks, func(n Network) bool { return n.Name == name }) {
+			return nil, fmt.Errorf("unknown network %q", name)
+		}
+	}
+	for _, n := range c.Networks {
+		if slices.Contains(names, n.Name) {
+			out = append(out, n)
+		}
+	}
+	return out, nil
+}
+
+// RunArgs are the run.go flags for the network: its env files, then the
+// shared flags, then its own.
+func (n Network) RunArgs(shared []string) []string {
+	args := []string{"--yes"}
+	for _, f := range n.EnvFiles {
+		args = append(args, "--env-file", f)
+	}
+	args = append(args, shared...)
+	return append(args, n.Args...)
+}
+
+// Environ is the environment the network's run adds to the inherited one,
+// pinning WORK_DIR to workDir. Variables set here win over the env files,
+// which only fill in what is unset.
+func (n Network) Environ(workDir string) []string {
+	env := []string{"WORK_DIR=" + workDir}
+	if n.Profile != "" {
+		env = append(env, "CHAIN_PROFILE="+n.Profile)
+	}
+	keys := make([]string, 0, len(n.Env))
+	for k := range n.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+n.Env[k])
+	}
+	return env
+}
+
+// Statuses of a network's run.
+const (
+	Passed = "passed"
+	Failed = "failed"
+	// Error is a run that left no results_run.json, such as one that
+	// couldn't start.
+	Error = "error"
+)
+
+// GroupStatus is how one group of a network's run ended.
+type GroupStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// NetworkResult is the outcome of the suite on one network, read back from
+// its work directory.
+type NetworkResult struct {
+	Name      string        `json:"name"`
+	Profile   string        `json:"profile,omitempty"`
+	WorkDir   string        `json:"workDir"`
+	Log       string        `json:"log"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	DurationS float64       `json:"durationSeconds"`
+	Passed    int           `json:"passed"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	SkippedBy skip.Counts   `json:"skippedBy,omitempty"`
+	Groups    []GroupStatus `json:"groups,omitempty"`
+	// Score and Categories are the run's conformance score, when it
+	// produced one.
+	Score      *float64     `json:"score,omitempty"`
+	Percent    string       `json:"percent,omitempty"`
+	Categories []score.Part `json:"categories,omitempty"`
+}
+
+// Collect reads the results_run.json and conformance.json the run of n
+// left in dir. runErr is how the run exited; a run with failed groups
+// exits non-zero too, so it only decides the status when no results were
+// written.
+func Collect(n Network, dir, log string, runErr error) NetworkResult {
+	r := NetworkResult{Name: n.Name, Profile: n.Profile, WorkDir: dir, Log: log}
+	var run struct {
+		Passed    int           `json:"passed"`
+		Failed    int           `json:"failed"`
+		Skipped   int           `json:"skipped"`
+		DurationS float64       `json:"durationSeconds"`
+		SkippedBy skip.Counts   `json:"skippedBy"`
+		Groups    []GroupStatus `json:"groups"`
+	}
+	if err := readJSON(filepath.Join(dir, "results_run.json"), &run); err != nil {
+		r.Status, r.Error = Error, err.Error()
+		if runErr != nil {
+			r.Error = runErr.Error()
+		}
+		return r
+	}
+	r.Passed, r.Failed, r.Skipped, r.DurationS, r.SkippedBy, r.Groups = run.Passed, run.Failed, run.Skipped, run.DurationS, run.SkippedBy, run.Groups
+	r.Status = Passed
+	if run.Failed > 0 {
+		r.Status = Failed
+	} else if runErr != nil {
+		r.Status, r.Error = Failed, runErr.Error()
+	}
+
+	var report score.Report
+	switch err := readJSON(filepath.Join(dir, "conformance.json"), &report); {
+	case err == nil:
+		r.Score, r.Percent, r.Categories = &report.Score, report.Percent, report.Categories
+	case !errors.Is(err, fs.ErrNotExist):
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// GroupRow is a group's status on every network. It diverges when it
+// passed on one network and failed on another.
+type GroupRow struct {
+	Name     string            `json:"name"`
+	Status   map[string]string `json:"status"`
+	Diverges bool              `json:"diverges"`
+}
+
+// CategoryRow is a score category's percentage on every network that
+// scored it.
+type CategoryRow struct {
+	Name    string            `json:"name"`
+	Percent map[string]string `json:"percent"`
+}
+
+// Report is the consolidated cross-network report.
+type Report struct {
+	Stage      string          `json:"stage"`
+	Networks   []NetworkResult `json:"networks"`
+	Groups     []GroupRow      `json:"groups"`
+	Categories []CategoryRow   `json:"categories"`
+	// Passed and Failed count networks; Divergent counts groups.
+	Passed    int     `json:"passed"`
+	Failed    int     `json:"failed"`
+	Divergent int     `json:"divergent"`
+	DurationS float64 `json:"durationSeconds"`
+	Timestamp string  `json:"timestamp"`
+}
+
+// Consolidate lines the networks' results up by group and score category,
+// in the order they first appear.
+func Consolidate(results []NetworkResult) Report {
+	r := Report{Stage: "Nightly - Suite Across Networks", Networks: results}
+	groups := map[string]*GroupRow{}
+	var groupOrder []string
+	categories := map[string]*CategoryRow{}
+	var categoryOrder []string
+	for _, n := range results {
+		if n.Status == Passed {
+			r.Passed++
+		} else {
+			r.Failed++
+		}
+		for _, g := range n.Groups {
+			row, ok := groups[g.Name]
+			if !ok {
+				row = &GroupRow{Name: g.Name, Status: map[string]string{}}
+				groups[g.Name] = row
+				groupOrder = append(groupOrder, g.Name)
+			}
+			row.Status[n.Name] = g.Status
+		}
+		for _, c := range n.Categories {
+			row, ok := categories[c.Name]
+			if !ok {
+				row = &CategoryRow{Name: c.Name, Percent: map[string]string{}}
+				categories[c.Name] = row
+				categoryOrder = append(categoryOrder, c.Name)
+			}
+			row.Percent[n.Name] = c.Percent
+		}
+	}
+	for _, name := range groupOrder {
+		row := groups[name]
+		var passed, failed bool
+		for _, s := range row.Status {
+			passed = passed || s == Passed
+			failed = failed || s == Failed
+		}
+		row.Diverges = passed && failed
+		if row.Diverges {
+			r.Divergent++
+		}
+		r.Groups = append(r.Groups, *row)
+	}
+	for _, name := range categoryOrder {
+		r.Categories = append(r.Categories, *categories[name])
+	}
+	return r
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
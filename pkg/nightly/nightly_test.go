@@ -0,0 +1,106 @@
+package nightly
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "networks.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	c, err := Load(write(`{"args": ["--exclude-tags", "slow"], "networks": [
+		{"name": "cardona", "envFiles": [".env.cardona"], "profile": "cardona", "env": {"RPC_PORT": "8545", "B": "2"}},
+		{"name": "devnet", "envFiles": [".env", ".env.devnet"], "args": ["--time-budget", "10m"], "deploy": true}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cardona, devnet := c.Networks[0], c.Networks[1]
+	if got, want := devnet.RunArgs(c.Args), []string{"--yes", "--env-file", ".env", "--env-file", ".env.devnet", "--exclude-tags", "slow", "--time-budget", "10m"}; !slices.Equal(got, want) {
+		t.Errorf("run args %q, want %q", got, want)
+	}
+	if got, want := cardona.Environ("/runs/cardona"), []string{"WORK_DIR=/runs/cardona", "CHAIN_PROFILE=cardona", "B=2", "RPC_PORT=8545"}; !slices.Equal(got, want) {
+		t.Errorf("environment %q, want %q", got, want)
+	}
+
+	selected, err := c.Select([]string{"devnet"})
+	if err != nil || len(selected) != 1 || selected[0].Name != "devnet" {
+		t.Errorf("selected %v, %v", selected, err)
+	}
+	if _, err := c.Select([]string{"mainnet"}); err == nil {
+		t.Error("selected an unknown network")
+	}
+
+	for name, content := range map[string]string{
+		"no networks":   `{"networks": []}`,
+		"path in name":  `{"networks": [{"name": "../up"}]}`,
+		"repeated name": `{"networks": [{"name": "a"}, {"name": "a"}]}`,
+	} {
+		if _, err := Load(write(content)); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+}
+
+func TestConsolidate(t *testing.T) {
+	dir := t.TempDir()
+	save := func(network, name, content string) {
+		if err := os.MkdirAll(filepath.Join(dir, network), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, network, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("a", "results_run.json", `{"passed": 2, "failed": 0, "skipped": 1, "skippedBy": {"tag-filter": 1},
+		"groups": [{"name": "canary", "status": "passed"}, {"name": "wrapper", "status": "passed"}, {"name": "fuzz", "status": "skipped"}]}`)
+	save("a", "conformance.json", `{"score": 1, "percent": "100.0%", "categories": [{"name": "wrapper", "percent": "100.0%"}]}`)
+	save("b", "results_run.json", `{"passed": 1, "failed": 1,
+		"groups": [{"name": "canary", "status": "passed"}, {"name": "wrapper", "status": "failed"}]}`)
+	save("b", "conformance.json", `{"score": 0.5, "percent": "50.0%", "categories": [{"name": "wrapper", "percent": "50.0%"}]}`)
+
+	exit := errors.New("exit status 1")
+	results := []NetworkResult{
+		Collect(Network{Name: "a"}, filepath.Join(dir, "a"), "", nil),
+		Collect(Network{Name: "b"}, filepath.Join(dir, "b"), "", exit),
+		Collect(Network{Name: "c"}, filepath.Join(dir, "c"), "", exit),
+	}
+	for i, want := range []string{Passed, Failed, Error} {
+		if results[i].Status != want {
+			t.Errorf("%s: status %s, want %s", results[i].Name, results[i].Status, want)
+		}
+	}
+	if results[2].Error != exit.Error() {
+		t.Errorf("error %q, want the run's", results[2].Error)
+	}
+	if results[1].Score == nil || *results[1].Score != 0.5 {
+		t.Errorf("score %v, want 0.5", results[1].Score)
+	}
+
+	r := Consolidate(results)
+	if r.Passed != 1 || r.Failed != 2 || r.Divergent != 1 {
+		t.Errorf("passed %d, failed %d, divergent %d; want 1, 2, 1", r.Passed, r.Failed, r.Divergent)
+	}
+	var names []string
+	for _, g := range r.Groups {
+		names = append(names, g.Name)
+		if g.Diverges != (g.Name == "wrapper") {
+			t.Errorf("%s diverges: %t", g.Name, g.Diverges)
+		}
+	}
+	if want := []string{"canary", "wrapper", "fuzz"}; !slices.Equal(names, want) {
+		t.Errorf("groups %v, want %v", names, want)
+	}
+	if len(r.Categories) != 1 || r.Categories[0].Percent["b"] != "50.0%" {
+		t.Errorf("categories %+v", r.Categories)
+	}
+}
@@ -0,0 +1,177 @@
+// Package offline splits sending a transaction into prepare, sign and
+// broadcast steps that exchange JSON files, so the signing key can stay on
+// an airgapped machine: the online host prepares an unsigned transaction
+// with the current nonce, the offline host signs it, and the online host
+// broadcasts the result.
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+// UnsignedTx is a legacy (EIP-155) transaction awaiting a signature.
+type UnsignedTx struct {
+	Description string          `json:"description,omitempty"`
+	ChainID     *hexutil.Big    `json:"chainId"`
+	From        common.Address  `json:"from"`
+	Nonce       hexutil.Uint64  `json:"nonce"`
+	GasPrice    *hexutil.Big    `json:"gasPrice"`
+	Gas         hexutil.Uint64  `json:"gas"`
+	To          *common.Address `json:"to"`
+	Value       *hexutil.Big    `json:"value"`
+	Data        hexutil.Bytes   `json:"data"`
+	// ContractAddress is where a contract creation will deploy to.
+	ContractAddress *common.Address `json:"contractAddress,omitempty"`
+}
+
+// SignedTx is an UnsignedTx together with its signed encoding.
+type SignedTx struct {
+	UnsignedTx
+	Raw  hexutil.Bytes `json:"raw"`
+	Hash common.Hash   `json:"hash"`
+}
+
+// NewUnsigned builds an unsigned transaction; to is nil for a contract
+// creation, in which case the deployment address is filled in.
+func NewUnsigned(chainID *big.Int, from common.Address, nonce uint64, gasPrice *big.Int, gas uint64, to *common.Address, value *big.Int, data []byte) *UnsignedTx {
+	if value == nil {
+		value = new(big.Int)
+	}
+	u := &UnsignedTx{
+		ChainID:  (*hexutil.Big)(chainID),
+		From:     from,
+		Nonce:    hexutil.Uint64(nonce),
+		GasPrice: (*hexutil.Big)(gasPrice),
+		Gas:      hexutil.Uint64(gas),
+		To:       to,
+		Value:    (*hexutil.Big)(value),
+		Data:     data,
+	}
+	if to == nil {
+		addr := chain.CreateAddress(from, nonce)
+		u.ContractAddress = &addr
+	}
+	return u
+}
+
+// Transaction returns the unsigned go-ethereum transaction.
+func (u *UnsignedTx) Transaction() *types.Transaction {
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    uint64(u.Nonce),
+		GasPrice: u.GasPrice.ToInt(),
+		Gas:      uint64(u.Gas),
+		To:       u.To,
+		Value:    u.Value.ToInt(),
+		Data:     u.Data,
+	})
+}
+
+// SigningHash is the hash the signer signs, shown on both machines so the
+// operator can confirm they are signing what was prepared.
+func (u *UnsignedTx) SigningHash() common.Hash {
+	return signer.ChainSigner(u.ChainID.ToInt()).Hash(u.Transaction())
+}
+
+// Sign signs u with s, which must belong to u.From. It fails with
+// chain.ErrReadOnly in read-only mode.
+func Sign(ctx context.Context, u *UnsignedTx, s signer.Signer) (*SignedTx, error) {
+	if err := chain.CheckWritable(); err != nil {
+		return nil, err
+	}
+	if addr := s.Address(); addr != u.From {
+		return nil, fmt.Errorf("key belongs to %s, transaction is from %s", addr.Hex(), u.From.Hex())
+	}
+	tx, err := signer.SignTx(ctx, s, u.Transaction(), u.ChainID.ToInt())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	return &SignedTx{UnsignedTx: *u, Raw: raw, Hash: tx.Hash()}, nil
+}
+
+// Decode parses the signed encoding and checks that it is exactly the
+// described transaction, signed by From, so an edited file is rejected
+// before broadcast.
+func (s *SignedTx) Decode() (*types.Transaction, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(s.Raw); err != nil {
+		return nil, fmt.Errorf("invalid signed transaction: %w", err)
+	}
+	if tx.Hash() != s.Hash {
+		return nil, fmt.Errorf("signed transaction hash %s doesn't match recorded %s", tx.Hash().Hex(), s.Hash.Hex())
+	}
+	txSigner := signer.ChainSigner(s.ChainID.ToInt())
+	if txSigner.Hash(tx) != s.SigningHash() {
+		return nil, fmt.Errorf("signed transaction differs from the prepared fields")
+	}
+	from, err := types.Sender(txSigner, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	if from != s.From {
+		return nil, fmt.Errorf("transaction signed by %s, expected %s", from.Hex(), s.From.Hex())
+	}
+	return tx, nil
+}
+
+// Write saves v as indented JSON.
+func Write(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+	if err := paths.WriteFile(path, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadUnsigned loads an unsigned transaction file.
+func ReadUnsigned(path string) (*UnsignedTx, error) {
+	u := new(UnsignedTx)
+	if err := read(path, u); err != nil {
+		return nil, err
+	}
+	if u.ChainID == nil || u.GasPrice == nil || u.Value == nil {
+		return nil, fmt.Errorf("%s is missing chainId, gasPrice or value", path)
+	}
+	return u, nil
+}
+
+// ReadSigned loads a signed transaction file.
+func ReadSigned(path string) (*SignedTx, error) {
+	s := new(SignedTx)
+	if err := read(path, s); err != nil {
+		return nil, err
+	}
+	if s.ChainID == nil || len(s.Raw) == 0 {
+		return nil, fmt.Errorf("%s is not a signed transaction file", path)
+	}
+	return s, nil
+}
+
+func read(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
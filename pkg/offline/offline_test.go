@@ -0,0 +1,120 @@
+package offline
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+func TestPrepareSignBroadcast(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	dir := t.TempDir()
+
+	// Prepare: only the address is known
+	u := NewUnsigned(big.NewInt(10101), from, 5, big.NewInt(1e9), 2_000_000, nil, nil, []byte{0x60, 0x00})
+	if want := crypto.CreateAddress(from, 5); *u.ContractAddress != want {
+		t.Fatalf("contract address %s, want %s", u.ContractAddress.Hex(), want.Hex())
+	}
+	if err := Write(filepath.Join(dir, "unsigned.json"), u); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sign from the file, as the offline host does
+	loaded, err := ReadUnsigned(filepath.Join(dir, "unsigned.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.SigningHash() != u.SigningHash() {
+		t.Fatal("signing hash changed through the file")
+	}
+	signed, err := Sign(context.Background(), loaded, signer.NewLocal(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(filepath.Join(dir, "signed.json"), signed); err != nil {
+		t.Fatal(err)
+	}
+
+	// Broadcast from the file
+	signed, err = ReadSigned(filepath.Join(dir, "signed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := signed.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := mockrpc.New()
+	defer s.Close()
+	chain := mockrpc.NewChain(s, big.NewInt(10101))
+	chain.SetNonce(from, 5)
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := client.TransactionReceipt(context.Background(), signed.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.ContractAddress != *signed.ContractAddress {
+		t.Errorf("deployed to %s, prepared for %s", receipt.ContractAddress.Hex(), signed.ContractAddress.Hex())
+	}
+}
+
+func TestSignRejectsWrongKey(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	u := NewUnsigned(big.NewInt(1), crypto.PubkeyToAddress(key.PublicKey), 0, big.NewInt(1), 21_000, &common.Address{1}, nil, nil)
+	if _, err := Sign(context.Background(), u, signer.NewLocal(other)); err == nil {
+		t.Fatal("signed with a key that doesn't own the sender address")
+	}
+}
+
+func TestDecodeRejectsEditedFile(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	u := NewUnsigned(big.NewInt(1), crypto.PubkeyToAddress(key.PublicKey), 0, big.NewInt(1), 21_000, &common.Address{1}, big.NewInt(5), nil)
+	signed, err := Sign(context.Background(), u, signer.NewLocal(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, edit := range map[string]func(*SignedTx){
+		"value":     func(s *SignedTx) { s.Value.ToInt().SetInt64(500) },
+		"nonce":     func(s *SignedTx) { s.Nonce++ },
+		"chain":     func(s *SignedTx) { s.ChainID.ToInt().SetInt64(2) },
+		"from":      func(s *SignedTx) { s.From = common.Address{2} },
+		"hash":      func(s *SignedTx) { s.Hash = common.Hash{3} },
+		"raw":       func(s *SignedTx) { s.Raw = s.Raw[:len(s.Raw)-1] },
+		"recipient": func(s *SignedTx) { to := common.Address{9}; s.To = &to },
+	} {
+		edited := *signed
+		edited.Value = (*hexutil.Big)(new(big.Int).Set(signed.Value.ToInt()))
+		edited.ChainID = (*hexutil.Big)(new(big.Int).Set(signed.ChainID.ToInt()))
+		edited.Raw = append([]byte(nil), signed.Raw...)
+		edit(&edited)
+		if _, err := edited.Decode(); err == nil {
+			t.Errorf("%s: edited file accepted", name)
+		}
+	}
+	if _, err := signed.Decode(); err != nil {
+		t.Errorf("original rejected: %v", err)
+	}
+}
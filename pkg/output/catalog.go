@@ -0,0 +1,81 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// EnvLocales overrides the directory message catalogs are loaded from.
+const EnvLocales = "OUTPUT_LOCALES"
+
+// DefaultLocalesDir is where catalogs live relative to the repository root.
+const DefaultLocalesDir = "locales"
+
+// Catalog translates the fixed parts of the scripts' messages. Messages map
+// English phrases to their translation and are replaced wherever they occur
+// in a line, longest first, so formatted values around them survive.
+// Markers, when set, replace DefaultMarkers in plain mode, and Numbers
+// replaces English number formatting.
+type Catalog struct {
+	Language string            `json:"language"`
+	Markers  map[string]string `json:"markers,omitempty"`
+	Numbers  *NumberFormat     `json:"numbers,omitempty"`
+	Messages map[string]string `json:"messages"`
+
+	phrases []string
+}
+
+// LoadCatalog reads locales/<lang>.json.
+func LoadCatalog(lang string) (*Catalog, error) {
+	dir := os.Getenv(EnvLocales)
+	if dir == "" {
+		dir = DefaultLocalesDir
+	}
+	path := filepath.Join(dir, lang+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no message catalog for %q: %w", lang, err)
+	}
+	c := new(Catalog)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for phrase := range c.Messages {
+		if phrase != "" {
+			c.phrases = append(c.phrases, phrase)
+		}
+	}
+	sort.Slice(c.phrases, func(i, j int) bool {
+		if len(c.phrases[i]) != len(c.phrases[j]) {
+			return len(c.phrases[i]) > len(c.phrases[j])
+		}
+		return c.phrases[i] < c.phrases[j]
+	})
+	return c, nil
+}
+
+// translate replaces every catalog phrase in s. Replacements are applied in
+// a single pass so a translation is never translated again.
+func (c *Catalog) translate(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		matched := false
+		for _, phrase := range c.phrases {
+			if strings.HasPrefix(s, phrase) {
+				b.WriteString(c.Messages[phrase])
+				s = s[len(phrase):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	return b.String()
+}
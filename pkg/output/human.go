@@ -0,0 +1,164 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvRaw makes the console show exact values instead of humanized ones. The
+// results files always hold exact values.
+const EnvRaw = "OUTPUT_RAW"
+
+// Raw reports whether --raw-numbers or OUTPUT_RAW is in effect.
+func Raw() bool {
+	return isTrue(os.Getenv(EnvRaw))
+}
+
+// NumberFormat is how a language writes numbers: the separator between
+// groups of three digits and the decimal separator.
+type NumberFormat struct {
+	Group   string `json:"group"`
+	Decimal string `json:"decimal"`
+}
+
+// English is the number format of untranslated output.
+var English = NumberFormat{Group: ",", Decimal: "."}
+
+var (
+	numbersOnce sync.Once
+	numbers     NumberFormat
+)
+
+// numberFormat is the format of the OUTPUT_LANG catalog, English when it
+// has none.
+func numberFormat() NumberFormat {
+	numbersOnce.Do(func() {
+		numbers = English
+		if lang := os.Getenv(EnvLang); lang != "" {
+			if c, err := LoadCatalog(lang); err == nil && c.Numbers != nil {
+				numbers = *c.Numbers
+			}
+		}
+	})
+	return numbers
+}
+
+// Integer is what Count formats.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// Count formats n with its digits grouped in threes, 1,234,567 in English,
+// or as is in raw mode.
+func Count[T Integer](n T) string {
+	s := fmt.Sprint(n)
+	if Raw() {
+		return s
+	}
+	return numberFormat().group(s)
+}
+
+// group inserts the group separator into a string of digits with an
+// optional sign.
+func (f NumberFormat) group(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= 3 {
+		return sign + s
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	first := len(s) % 3
+	if first == 0 {
+		first = 3
+	}
+	b.WriteString(s[:first])
+	for i := first; i < len(s); i += 3 {
+		b.WriteString(f.Group)
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
+
+// decimal formats v, at least 1, to three significant digits without
+// trailing zeros, with the language's decimal separator.
+func (f NumberFormat) decimal(v float64) string {
+	decimals := 0
+	switch {
+	case v < 10:
+		decimals = 2
+	case v < 100:
+		decimals = 1
+	}
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if decimals > 0 {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	return strings.Replace(s, ".", f.Decimal, 1)
+}
+
+// Bytes formats a size in binary units, 1.2 MiB, or as an exact byte count
+// in raw mode.
+func Bytes[T Integer](n T) string {
+	if Raw() {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	f := numberFormat()
+	v := float64(n)
+	if v < 1024 && v > -1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	units := []string{"KiB", "MiB", "GiB", "TiB"}
+	unit := ""
+	for _, unit = range units {
+		v /= 1024
+		if v < 1024 && v > -1024 || unit == units[len(units)-1] {
+			break
+		}
+	}
+	return strings.Replace(strconv.FormatFloat(v, 'f', 1, 64), ".", f.Decimal, 1) + " " + unit
+}
+
+// Duration formats d to three significant digits in the largest fitting
+// unit, 3.4s or 850ms, with minutes and hours as 2m05s and 1h02m, or as
+// time.Duration prints it in raw mode.
+func Duration(d time.Duration) string {
+	if Raw() {
+		return d.String()
+	}
+	f := numberFormat()
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	switch {
+	case d < time.Microsecond:
+		return fmt.Sprintf("%s%dns", sign, d.Nanoseconds())
+	case d < time.Millisecond:
+		return sign + f.decimal(float64(d)/float64(time.Microsecond)) + "µs"
+	case d < time.Second:
+		return sign + f.decimal(float64(d)/float64(time.Millisecond)) + "ms"
+	case d < time.Minute:
+		return sign + f.decimal(d.Seconds()) + "s"
+	case d < time.Hour:
+		d = d.Round(time.Second)
+		return fmt.Sprintf("%s%dm%02ds", sign, int(d.Minutes()), int(d.Seconds())%60)
+	}
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%s%dh%02dm", sign, int(d.Hours()), int(d.Minutes())%60)
+}
+
+// Millis formats a latency measured in float milliseconds like Duration,
+// or with every digit of the measurement in raw mode.
+func Millis(ms float64) string {
+	if Raw() {
+		return strconv.FormatFloat(ms, 'f', -1, 64) + "ms"
+	}
+	return Duration(time.Duration(ms * float64(time.Millisecond)))
+}
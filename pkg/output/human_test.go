@@ -0,0 +1,60 @@
+package output
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanize(t *testing.T) {
+	t.Setenv(EnvRaw, "")
+	t.Setenv(EnvLang, "")
+	for _, tc := range []struct {
+		got, want string
+	}{
+		{Count(0), "0"},
+		{Count(999), "999"},
+		{Count(1000), "1,000"},
+		{Count(-1234567), "-1,234,567"},
+		{Count(uint64(30_000_000)), "30,000,000"},
+		{Bytes(512), "512 B"},
+		{Bytes(24576), "24.0 KiB"},
+		{Bytes(1258291), "1.2 MiB"},
+		{Duration(850 * time.Millisecond), "850ms"},
+		{Duration(3400 * time.Millisecond), "3.4s"},
+		{Duration(12500 * time.Microsecond), "12.5ms"},
+		{Duration(125 * time.Second), "2m05s"},
+		{Duration(62 * time.Minute), "1h02m"},
+		{Duration(999960 * time.Microsecond), "1000ms"},
+		{Millis(1.5), "1.5ms"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %q, want %q", tc.got, tc.want)
+		}
+	}
+}
+
+func TestHumanizeRaw(t *testing.T) {
+	t.Setenv(EnvRaw, "1")
+	if got := Count(1234567); got != "1234567" {
+		t.Errorf("Count = %q", got)
+	}
+	if got := Bytes(1258291); got != "1258291 bytes" {
+		t.Errorf("Bytes = %q", got)
+	}
+	if got := Duration(3400 * time.Millisecond); got != "3.4s" {
+		t.Errorf("Duration = %q", got)
+	}
+	if got := Millis(1.23456); got != "1.23456ms" {
+		t.Errorf("Millis = %q", got)
+	}
+}
+
+func TestNumberFormat(t *testing.T) {
+	de := NumberFormat{Group: ".", Decimal: ","}
+	if got := de.group("1234567"); got != "1.234.567" {
+		t.Errorf("group = %q", got)
+	}
+	if got := de.decimal(3.4); got != "3,4" {
+		t.Errorf("decimal = %q", got)
+	}
+}
@@ -0,0 +1,352 @@
+// Package output post-processes the scripts' console output for
+// environments that can't cope with it: --plain replaces status emoji with
+// ASCII markers and strips the remaining symbols, since emoji break some log
+// aggregation systems and CI terminals, and --lang translates fixed messages
+// through a message catalog, and -q, -v and -vv pick how much each module
+// (rpc, deploy, vectors, report) prints.
+//
+// Scripts print with fmt and log directly, so rather than threading a
+// printer through every call, Setup re-runs the script as a child process
+// and filters its stdout and stderr line by line. This keeps log.Fatal and
+// os.Exit paths intact: the parent only exits once the child's output has
+// been fully drained, with the child's exit code.
+package output
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"cdk-erigon-precompile/pkg/capture"
+)
+
+// Environment variables controlling the output mode. They are inherited by
+// scripts started from a script, such as the suite runner's groups.
+const (
+	EnvPlain = "PLAIN_OUTPUT"
+	EnvLang  = "OUTPUT_LANG"
+
+	// envChild marks the filtered child process.
+	envChild = "OUTPUT_FILTERED"
+
+	// envCache and envJWTSecret are rpccache.EnvCache and
+	// rpcclient.EnvJWTSecret, which import this package.
+	envCache     = "RPC_CACHE"
+	envJWTSecret = "RPC_JWT_SECRET"
+)
+
+// Setup applies --plain and --lang (or PLAIN_OUTPUT and OUTPUT_LANG) and the
+// verbosity flags -q, -v, -vv and --verbosity=<spec> (or VERBOSITY), and
+// --capture-rpc=<file> (or RPC_CAPTURE) to record RPC traffic,
+// --rpc-cache=<dir> (or RPC_CACHE) to cache immutable queries and
+// --jwt-secret=<file> (or RPC_JWT_SECRET) to authenticate them, and
+// --raw-numbers (or OUTPUT_RAW) for exact values in place of humanized
+// ones, and removes
+// those flags from os.Args, so it must run before flag parsing. When
+// filtering is needed it doesn't return: it runs the script as a child and
+// exits with the child's status.
+func Setup() {
+	a := parseArgs()
+	if a.plain {
+		os.Setenv(EnvPlain, "1")
+	}
+	if a.lang != "" {
+		os.Setenv(EnvLang, a.lang)
+	}
+	lv, err := ParseLevels(os.Getenv(EnvVerbosity))
+	if err == nil && a.verbosity != "" {
+		var flagLevels Levels
+		if flagLevels, err = ParseLevels(a.verbosity); err == nil {
+			lv.Default = flagLevels.Default
+			for m, l := range flagLevels.Modules {
+				lv.Modules[m] = l
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "output: %v\n", err)
+		os.Exit(2)
+	}
+	os.Setenv(EnvVerbosity, lv.String())
+	if os.Getenv(envChild) != "" {
+		return
+	}
+
+	t, err := NewTranslator(isTrue(os.Getenv(EnvPlain)), os.Getenv(EnvLang))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "output: %v\n", err)
+		os.Exit(2)
+	}
+	t.quiet = lv.Of(ModuleReport) == Quiet
+	if t.Identity() {
+		return
+	}
+	os.Exit(runFiltered(t))
+}
+
+// Plain reports whether --plain or PLAIN_OUTPUT is in effect, for output
+// whose layout depends on the width of the status markers.
+func Plain() bool {
+	return isTrue(os.Getenv(EnvPlain))
+}
+
+type args struct {
+	plain     bool
+	lang      string
+	verbosity string
+}
+
+// parseArgs extracts --plain[=bool], --lang=x / --lang x, -q, -v, -vv,
+// --verbosity=spec / --verbosity spec, --capture-rpc=file, --rpc-cache=dir,
+// --jwt-secret=file and --raw-numbers[=bool] from os.Args. Verbosity flags
+// are combined into a single spec, later ones overriding earlier ones.
+func parseArgs() args {
+	var a args
+	var specs []string
+	kept := []string{os.Args[0]}
+	rest := os.Args[1:]
+	for i := 0; i < len(rest); i++ {
+		arg := rest[i]
+		if arg == "--" {
+			kept = append(kept, rest[i:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			kept = append(kept, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !hasValue && (name == "lang" || name == "verbosity" || name == "capture-rpc" || name == "rpc-cache" || name == "jwt-secret") && i+1 < len(rest) {
+			i++
+			value = rest[i]
+		}
+		switch name {
+		case "plain":
+			a.plain = !hasValue || isTrue(value)
+		case "raw-numbers":
+			if !hasValue || isTrue(value) {
+				os.Setenv(EnvRaw, "1")
+			}
+		case "lang":
+			a.lang = value
+		case "q", "v", "vv":
+			specs = append(specs, name)
+		case "verbosity":
+			specs = append(specs, value)
+		case "capture-rpc":
+			os.Setenv(capture.EnvCapture, value)
+		case "rpc-cache":
+			os.Setenv(envCache, value)
+		case "jwt-secret":
+			os.Setenv(envJWTSecret, value)
+		default:
+			kept = append(kept, arg)
+		}
+	}
+	os.Args = kept
+	a.verbosity = strings.Join(specs, ",")
+	return a
+}
+
+func isTrue(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "t", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// runFiltered re-executes the running binary with its output piped through
+// t and returns the child's exit code.
+func runFiltered(t *Translator) int {
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), envChild+"=1")
+	cmd.Stdin = os.Stdin
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "output: %v\n", err)
+		return 2
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "output: %v\n", err)
+		return 2
+	}
+
+	// Ctrl-C reaches the child through the process group; the parent keeps
+	// draining its output until it exits
+	signal.Ignore(os.Interrupt)
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "output: failed to start %s: %v\n", os.Args[0], err)
+		return 2
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); t.Copy(os.Stdout, stdout, t.quiet) }()
+	go func() { defer wg.Done(); t.Copy(os.Stderr, stderr, false) }()
+	wg.Wait()
+
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "output: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// Copy translates r line by line into w. With quiet set only lines that
+// report failures, warnings or summaries are kept.
+func (t *Translator) Copy(w io.Writer, r io.Reader, quiet bool) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" && (!quiet || important(line)) {
+			io.WriteString(w, t.Line(line))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// important reports whether a line survives quiet mode.
+func important(line string) bool {
+	for _, symbol := range []string{"❌", "⚠", "↩", "🛑", "📊"} {
+		if strings.Contains(line, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// Translator rewrites output lines.
+type Translator struct {
+	plain   bool
+	quiet   bool
+	catalog *Catalog
+}
+
+// NewTranslator returns a translator for the given mode and language; an
+// empty language or "en" leaves the messages untranslated.
+func NewTranslator(plain bool, lang string) (*Translator, error) {
+	t := &Translator{plain: plain}
+	if lang != "" && lang != "en" {
+		c, err := LoadCatalog(lang)
+		if err != nil {
+			return nil, err
+		}
+		t.catalog = c
+	}
+	return t, nil
+}
+
+// Identity reports whether the translator leaves output unchanged.
+func (t *Translator) Identity() bool {
+	return !t.plain && !t.quiet && t.catalog == nil
+}
+
+// Line translates one line of output.
+func (t *Translator) Line(s string) string {
+	if t.catalog != nil {
+		s = t.catalog.translate(s)
+	}
+	if t.plain {
+		s = t.ascii(s)
+	}
+	return s
+}
+
+// ascii replaces status emoji with their markers and drops every other
+// non-ASCII symbol along with the space following it. Letters are kept so
+// translated messages stay readable.
+func (t *Translator) ascii(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		if m, n := t.marker(s); n > 0 {
+			b.WriteString(m)
+			s = s[n:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		switch {
+		case r < 0x80:
+			b.WriteByte(s[0])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteString(s[:size])
+		case unicode.IsSpace(r):
+			b.WriteByte(' ')
+		default:
+			// Symbol: drop it, its variation selector and the padding after it
+			size += skipDecoration(s[size:], true)
+		}
+		s = s[size:]
+	}
+	return b.String()
+}
+
+// marker matches a status symbol at the start of s and returns its marker
+// and the number of bytes consumed. Bracketed markers absorb the padding
+// after the symbol and add their own.
+func (t *Translator) marker(s string) (string, int) {
+	markers := DefaultMarkers
+	if t.catalog != nil && len(t.catalog.Markers) > 0 {
+		markers = t.catalog.Markers
+	}
+	for symbol, m := range markers {
+		if !strings.HasPrefix(s, symbol) {
+			continue
+		}
+		bracketed := strings.HasSuffix(m, "]")
+		n := len(symbol) + skipDecoration(s[len(symbol):], bracketed)
+		if bracketed {
+			m += " "
+		}
+		return m, n
+	}
+	return "", 0
+}
+
+// skipDecoration returns the length of variation selectors and zero-width
+// joiners, and optionally spaces, at the start of s.
+func skipDecoration(s string, spaces bool) int {
+	n := 0
+	for n < len(s) {
+		if s[n] == ' ' && spaces {
+			n++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if r != '\uFE0F' && r != '\u200D' {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// DefaultMarkers are the ASCII replacements for status symbols.
+var DefaultMarkers = map[string]string{
+	"✅":  "[OK]",
+	"❌":  "[FAIL]",
+	"⚠️": "[WARN]",
+	"⚠":  "[WARN]",
+	"⏭️": "[SKIP]",
+	"⏭":  "[SKIP]",
+	"↩️": "[REVERT]",
+	"↩":  "[REVERT]",
+	"🛑":  "[STOP]",
+	"⚪":  "[--]",
+	"🚀":  "[DONE]",
+	"→":  "->",
+}
@@ -0,0 +1,155 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Level is how much a module reports.
+type Level int
+
+// Verbosity levels, selected with -q, -v and -vv.
+const (
+	Quiet   Level = -1
+	Normal  Level = 0
+	Verbose Level = 1
+	Debug   Level = 2
+)
+
+// Modules with their own verbosity.
+const (
+	// ModuleRPC logs JSON-RPC calls at Verbose and raw request and
+	// response bodies at Debug.
+	ModuleRPC = "rpc"
+	// ModuleDeploy logs transaction submission and receipt polling.
+	ModuleDeploy = "deploy"
+	// ModuleVectors logs loaded vector sets and each vector's input.
+	ModuleVectors = "vectors"
+	// ModuleReport is the scripts' regular console output; at Quiet only
+	// failures and warnings are printed.
+	ModuleReport = "report"
+)
+
+// Modules lists the modules accepted in a verbosity spec.
+var Modules = []string{ModuleRPC, ModuleDeploy, ModuleVectors, ModuleReport}
+
+// EnvVerbosity carries the verbosity spec to the script and its children.
+const EnvVerbosity = "VERBOSITY"
+
+// Levels is a default level with per-module overrides.
+type Levels struct {
+	Default Level
+	Modules map[string]Level
+}
+
+// Of returns the level of module.
+func (l Levels) Of(module string) Level {
+	if lvl, ok := l.Modules[module]; ok {
+		return lvl
+	}
+	return l.Default
+}
+
+// String formats l as a spec ParseLevels accepts.
+func (l Levels) String() string {
+	parts := []string{strconv.Itoa(int(l.Default))}
+	for _, m := range Modules {
+		if lvl, ok := l.Modules[m]; ok {
+			parts = append(parts, fmt.Sprintf("%s=%d", m, lvl))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseLevels parses a spec such as "1" or "quiet,rpc=debug,deploy=1": an
+// optional default level followed by module=level overrides. Levels are
+// quiet, normal, verbose, debug or -1 to 2.
+func ParseLevels(spec string) (Levels, error) {
+	l := Levels{Modules: map[string]Level{}}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		module, value, ok := strings.Cut(part, "=")
+		if !ok {
+			lvl, err := parseLevel(part)
+			if err != nil {
+				return Levels{}, err
+			}
+			l.Default = lvl
+			continue
+		}
+		if !knownModule(module) {
+			return Levels{}, fmt.Errorf("unknown verbosity module %q (have %s)", module, strings.Join(Modules, ", "))
+		}
+		lvl, err := parseLevel(value)
+		if err != nil {
+			return Levels{}, err
+		}
+		l.Modules[module] = lvl
+	}
+	return l, nil
+}
+
+func parseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "quiet", "q":
+		return Quiet, nil
+	case "normal":
+		return Normal, nil
+	case "verbose", "v":
+		return Verbose, nil
+	case "debug", "vv":
+		return Debug, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < int(Quiet) || n > int(Debug) {
+		return 0, fmt.Errorf("invalid verbosity level %q", s)
+	}
+	return Level(n), nil
+}
+
+func knownModule(m string) bool {
+	for _, known := range Modules {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	levelsOnce sync.Once
+	levels     Levels
+)
+
+// current returns the levels from VERBOSITY, which Setup fills in from the
+// command line.
+func current() Levels {
+	levelsOnce.Do(func() {
+		var err error
+		if levels, err = ParseLevels(os.Getenv(EnvVerbosity)); err != nil {
+			fmt.Fprintf(os.Stderr, "output: %v\n", err)
+			os.Exit(2)
+		}
+	})
+	return levels
+}
+
+// V reports whether module logs at lvl.
+func V(module string, lvl Level) bool {
+	return current().Of(module) >= lvl
+}
+
+// Logf prints a message for module to stderr if it is enabled at lvl.
+func Logf(module string, lvl Level, format string, args ...any) {
+	if !V(module, lvl) {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintf(os.Stderr, "[%s] %s\n", module, strings.TrimSuffix(msg, "\n"))
+}
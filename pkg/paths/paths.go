@@ -0,0 +1,117 @@
+// Package paths resolves where the scripts read and write their files and
+// reads the small text files they exchange, so the tool behaves the same
+// from any directory and on Windows.
+//
+// Compiled contracts are read from ARTIFACTS_DIR (default "artifacts").
+// Deployment state such as deployed_address.txt and the results files go
+// to WORK_DIR (default the current directory). Either may be absolute or
+// relative to the working directory. Both are read from the environment,
+// not .env, since flag defaults are resolved before .env is loaded.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// FileMode and DirMode are used for everything the scripts write. Windows
+// ignores all but the owner write bit.
+const (
+	FileMode os.FileMode = 0o644
+	DirMode  os.FileMode = 0o755
+)
+
+// ArtifactsDir is the directory of the compiled .bin and .abi files.
+func ArtifactsDir() string {
+	if dir := os.Getenv("ARTIFACTS_DIR"); dir != "" {
+		return filepath.Clean(dir)
+	}
+	return "artifacts"
+}
+
+// WorkDir is the directory of deployment state and results.
+func WorkDir() string {
+	if dir := os.Getenv("WORK_DIR"); dir != "" {
+		return filepath.Clean(dir)
+	}
+	return "."
+}
+
+// Artifact is the path of a file in the artifacts directory, e.g.
+// Artifact("Sha256Wrapper.bin").
+func Artifact(name string) string { return filepath.Join(ArtifactsDir(), name) }
+
+// Work is the path of a file in the work directory.
+func Work(name string) string { return filepath.Join(WorkDir(), name) }
+
+// Under resolves p against base unless it is absolute.
+func Under(base, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(base, p)
+}
+
+// WriteFile writes data to path, creating its directory if needed.
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), DirMode); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, FileMode)
+}
+
+// Clean strips a UTF-8 byte order mark, which Windows editors like to add,
+// and surrounding whitespace including CRLF line endings.
+func Clean(data []byte) string {
+	return strings.TrimSpace(strings.TrimPrefix(string(data), "\ufeff"))
+}
+
+// ReadAddress reads a file holding one address, failing if it holds
+// anything else rather than silently decoding garbage.
+func ReadAddress(path string) (common.Address, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	s := Clean(data)
+	if !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("%s does not hold an address: %q", path, s)
+	}
+	return common.HexToAddress(s), nil
+}
+
+// ReadAddresses reads a file listing one address per line, skipping blank
+// lines.
+func ReadAddresses(path string) ([]common.Address, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []common.Address
+	for i, line := range strings.Split(Clean(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if !common.IsHexAddress(line) {
+			return nil, fmt.Errorf("%s:%d is not an address: %q", path, i+1, line)
+		}
+		addrs = append(addrs, common.HexToAddress(line))
+	}
+	return addrs, nil
+}
+
+// ReadHex reads a hex file such as a .bin artifact, dropping the BOM, any
+// 0x prefix and all whitespace, so wrapped or CRLF files decode the same.
+// Library placeholders are kept, so the result isn't necessarily valid hex.
+func ReadHex(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	s := strings.Join(strings.Fields(Clean(data)), "")
+	return strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"), nil
+}
@@ -0,0 +1,60 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestReadFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := WriteFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	want := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+
+	// As saved by Notepad: BOM and CRLF
+	addr, err := ReadAddress(write("deployed_address.txt", "\ufeff0x5FbDB2315678afecb367f032d93F642f64180aa3\r\n"))
+	if err != nil || addr != want {
+		t.Errorf("address %s, %v", addr.Hex(), err)
+	}
+	if _, err := ReadAddress(write("bad.txt", "0x5FbDB23\r\n")); err == nil {
+		t.Error("truncated address accepted")
+	}
+
+	addrs, err := ReadAddresses(write("proxies.txt", "0x5FbDB2315678afecb367f032d93F642f64180aa3\r\n\r\n0x0000000000000000000000000000000000000001\r\n"))
+	if err != nil || len(addrs) != 2 || addrs[0] != want {
+		t.Errorf("addresses %v, %v", addrs, err)
+	}
+
+	code, err := ReadHex(write(filepath.Join("nested", "Wrapper.bin"), "0x6080\r\n6040 52\r\n"))
+	if err != nil || code != "6080604052" {
+		t.Errorf("hex %q, %v", code, err)
+	}
+}
+
+func TestDirs(t *testing.T) {
+	t.Setenv("ARTIFACTS_DIR", "")
+	t.Setenv("WORK_DIR", "")
+	if Artifact("X.bin") != filepath.Join("artifacts", "X.bin") || Work("results.json") != "results.json" {
+		t.Errorf("defaults %s, %s", Artifact("X.bin"), Work("results.json"))
+	}
+
+	work := filepath.Join(t.TempDir(), "out")
+	t.Setenv("WORK_DIR", work)
+	if Work("results.json") != filepath.Join(work, "results.json") {
+		t.Errorf("work path %s", Work("results.json"))
+	}
+	if Under("base", work) != work || Under("base", "rel") != filepath.Join("base", "rel") {
+		t.Error("Under doesn't keep absolute paths")
+	}
+	if _, err := os.Stat(work); !os.IsNotExist(err) {
+		t.Error("work dir created before anything was written")
+	}
+}
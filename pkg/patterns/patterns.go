@@ -0,0 +1,186 @@
+// Package patterns calls precompiles through the PrecompilePatterns
+// contract (contracts/PrecompilePatterns.sol), which makes the same call
+// with the compiler-generated staticcall and with hand-written assembly,
+// and directly, with no contract in between. A wrong answer from every
+// pattern and the direct call points at the EVM; one from some patterns
+// only points at how they call.
+package patterns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Pattern is how the contract calls the precompile.
+type Pattern string
+
+const (
+	// Solidity is the compiler-generated high-level staticcall.
+	Solidity Pattern = "solidity"
+	// Assembly is a hand-written staticcall copying the return data by
+	// RETURNDATASIZE.
+	Assembly Pattern = "assembly"
+	// Fixed is a hand-written staticcall into an output area of a fixed
+	// size, leaving the size check to the caller.
+	Fixed Pattern = "fixed"
+	// Checked is Fixed with the size check done by the contract.
+	Checked Pattern = "checked"
+)
+
+// Patterns lists every pattern, the compiler-generated one first.
+var Patterns = []Pattern{Solidity, Assembly, Fixed, Checked}
+
+// Method is the contract function calling with p.
+func (p Pattern) Method() string {
+	return string(p) + "Call"
+}
+
+// sized reports whether p takes an output size.
+func (p Pattern) sized() bool {
+	return p == Fixed || p == Checked
+}
+
+// abiJSON is the ABI of contracts/PrecompilePatterns.sol.
+const abiJSON = `[
+{"type":"function","name":"solidityCall","stateMutability":"view",
+"inputs":[{"name":"precompile","type":"address"},{"name":"input","type":"bytes"}],
+"outputs":[{"name":"success","type":"bool"},{"name":"output","type":"bytes"}]},
+{"type":"function","name":"assemblyCall","stateMutability":"view",
+"inputs":[{"name":"precompile","type":"address"},{"name":"input","type":"bytes"}],
+"outputs":[{"name":"success","type":"bool"},{"name":"output","type":"bytes"}]},
+{"type":"function","name":"fixedCall","stateMutability":"view",
+"inputs":[{"name":"precompile","type":"address"},{"name":"input","type":"bytes"},{"name":"outSize","type":"uint256"}],
+"outputs":[{"name":"success","type":"bool"},{"name":"returnSize","type":"uint256"},{"name":"output","type":"bytes"}]},
+{"type":"function","name":"checkedCall","stateMutability":"view",
+"inputs":[{"name":"precompile","type":"address"},{"name":"input","type":"bytes"},{"name":"outSize","type":"uint256"}],
+"outputs":[{"name":"output","type":"bytes"}]},
+{"type":"error","name":"ReturnSize",
+"inputs":[{"name":"success","type":"bool"},{"name":"size","type":"uint256"}]}]`
+
+// ABI is the parsed PrecompilePatterns ABI.
+var ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Result is what the precompile answered, as a pattern saw it. Output is
+// what was copied out: for Fixed the part of the output area the return
+// data covered, and nothing when Checked reverted.
+type Result struct {
+	Success    bool          `json:"success"`
+	ReturnSize uint64        `json:"returnSize"`
+	Output     hexutil.Bytes `json:"output,omitempty"`
+}
+
+// Equal reports whether r and o are the same answer.
+func (r Result) Equal(o Result) bool {
+	return r.Success == o.Success && r.ReturnSize == o.ReturnSize && string(r.Output) == string(o.Output)
+}
+
+// Expect is the answer a precompile must give: its success and output.
+func Expect(success bool, output []byte) Result {
+	return Result{Success: success, ReturnSize: uint64(len(output)), Output: output}
+}
+
+// Pack encodes a call of precompile with input through p. outSize is the
+// output area of the sized patterns and ignored by the others.
+func Pack(p Pattern, precompile common.Address, input []byte, outSize uint64) ([]byte, error) {
+	args := []any{precompile, input}
+	if p.sized() {
+		args = append(args, new(big.Int).SetUint64(outSize))
+	}
+	data, err := ABI.Pack(p.Method(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", p.Method(), err)
+	}
+	return data, nil
+}
+
+// Unpack decodes the answer of a p call with an output area of outSize.
+func Unpack(p Pattern, out []byte, outSize uint64) (Result, error) {
+	values, err := ABI.Unpack(p.Method(), out)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to unpack %s: %w", p.Method(), err)
+	}
+	switch p {
+	case Fixed:
+		r := Result{Success: values[0].(bool), ReturnSize: values[1].(*big.Int).Uint64()}
+		area := values[2].([]byte)
+		r.Output = area[:min(r.ReturnSize, uint64(len(area)))]
+		return r, nil
+	case Checked:
+		output := values[0].([]byte)
+		return Result{Success: true, ReturnSize: uint64(len(output)), Output: output}, nil
+	}
+	output := values[1].([]byte)
+	return Result{Success: values[0].(bool), ReturnSize: uint64(len(output)), Output: output}, nil
+}
+
+// Call has the contract at address call precompile with input through p,
+// in an eth_call.
+func Call(ctx context.Context, client *ethclient.Client, address common.Address, p Pattern, precompile common.Address, input []byte, outSize uint64) (Result, error) {
+	data, err := Pack(p, precompile, input, outSize)
+	if err != nil {
+		return Result{}, err
+	}
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: data}, nil)
+	if err != nil {
+		if p == Checked {
+			if r, ok := returnSize(err); ok {
+				return r, nil
+			}
+		}
+		return Result{}, fmt.Errorf("%s call failed: %w", p.Method(), err)
+	}
+	return Unpack(p, out, outSize)
+}
+
+// returnSize decodes the ReturnSize revert of checkedCall from err.
+func returnSize(err error) (Result, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return Result{}, false
+	}
+	hex, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return Result{}, false
+	}
+	data, decodeErr := hexutil.Decode(hex)
+	revert := ABI.Errors["ReturnSize"]
+	if decodeErr != nil || len(data) < 4 || string(data[:4]) != string(revert.ID[:4]) {
+		return Result{}, false
+	}
+	values, unpackErr := revert.Inputs.Unpack(data[4:])
+	if unpackErr != nil {
+		return Result{}, false
+	}
+	return Result{Success: values[0].(bool), ReturnSize: values[1].(*big.Int).Uint64()}, true
+}
+
+// Direct calls precompile with input in an eth_call of its own. A call the
+// node answers with a JSON-RPC error is a failed call, as the precompile
+// failing makes the whole eth_call fail.
+func Direct(ctx context.Context, client *ethclient.Client, precompile common.Address, input []byte) (Result, error) {
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &precompile, Data: input}, nil)
+	if err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			return Result{}, nil
+		}
+		return Result{}, fmt.Errorf("direct call failed: %w", err)
+	}
+	return Expect(true, out), nil
+}
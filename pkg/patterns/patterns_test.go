@@ -0,0 +1,120 @@
+package patterns
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestCall(t *testing.T) {
+	contract := common.Address{0xca}
+	sha := common.BytesToAddress([]byte{2})
+	s := mockrpc.New()
+	defer s.Close()
+	// The contract answers sha256 of the input for 0x02 and an empty
+	// successful call for anything else; the precompile itself fails on
+	// anything but a non-empty input
+	s.Handle("eth_call", func(call mockrpc.Call) (any, error) {
+		var msg struct {
+			To    common.Address
+			Input hexutil.Bytes
+		}
+		if err := call.Param(0, &msg); err != nil {
+			return nil, err
+		}
+		if msg.To == sha {
+			if len(msg.Input) == 0 {
+				return nil, &mockrpc.Error{Code: -32000, Message: "out of gas"}
+			}
+			sum := sha256.Sum256(msg.Input)
+			return hexutil.Bytes(sum[:]), nil
+		}
+		method, err := ABI.MethodById(msg.Input[:4])
+		if err != nil {
+			return nil, err
+		}
+		args, err := method.Inputs.Unpack(msg.Input[4:])
+		if err != nil {
+			return nil, err
+		}
+		var answer []byte
+		if args[0].(common.Address) == sha {
+			sum := sha256.Sum256(args[1].([]byte))
+			answer = sum[:]
+		}
+		var out []byte
+		switch method.Name {
+		case "fixedCall":
+			area := make([]byte, args[2].(*big.Int).Uint64())
+			copy(area, answer)
+			out, err = method.Outputs.Pack(true, big.NewInt(int64(len(answer))), area)
+		case "checkedCall":
+			if uint64(len(answer)) != args[2].(*big.Int).Uint64() {
+				revert := ABI.Errors["ReturnSize"]
+				data, _ := revert.Inputs.Pack(true, big.NewInt(int64(len(answer))))
+				data = append(append([]byte{}, revert.ID[:4]...), data...)
+				return nil, &mockrpc.Error{Code: 3, Message: "execution reverted", Data: hexutil.Encode(data)}
+			}
+			out, err = method.Outputs.Pack(answer)
+		default:
+			out, err = method.Outputs.Pack(true, answer)
+		}
+		return hexutil.Bytes(out), err
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	input := []byte("patterns")
+	sum := sha256.Sum256(input)
+	want := Expect(true, sum[:])
+	for _, p := range Patterns {
+		got, err := Call(ctx, client, contract, p, sha, input, 32)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("%s: %+v, want %+v", p, got, want)
+		}
+	}
+
+	// An empty answer leaves the fixed area untouched, and checkedCall
+	// reverts with the size
+	identity := common.BytesToAddress([]byte{4})
+	empty := Expect(true, nil)
+	for _, p := range Patterns {
+		got, err := Call(ctx, client, contract, p, identity, input, 32)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		if p == Checked {
+			if got.ReturnSize != 0 || len(got.Output) != 0 {
+				t.Errorf("%s: %+v, want a size-0 revert", p, got)
+			}
+			continue
+		}
+		if !got.Equal(empty) {
+			t.Errorf("%s: %+v, want %+v", p, got, empty)
+		}
+	}
+
+	direct, err := Direct(ctx, client, sha, input)
+	if err != nil || !bytes.Equal(direct.Output, sum[:]) || !direct.Success {
+		t.Errorf("Direct = %+v, %v", direct, err)
+	}
+	failed, err := Direct(ctx, client, sha, nil)
+	if err != nil || failed.Success {
+		t.Errorf("Direct of a failing call = %+v, %v", failed, err)
+	}
+}
@@ -0,0 +1,305 @@
+// Package plugin runs validators written in any language, such as a
+// reference implementation in Rust, as subprocesses judging each case of
+// the stages that report their cases. A plugin is an executable that reads
+// one request as JSON on stdin and writes one verdict as JSON on stdout:
+//
+//	{"version": 1, "stage": "Stage 3 - Invoke Solidity Wrapper",
+//	 "case": {"id": "3a6e…", "precompile": "0x…02", "input": "0x68656c6c6f", "target": "0x…"},
+//	 "outcome": {"passed": true, "output": "0x2cf2…"}}
+//
+//	{"verdict": "fail", "message": "reference answers 0xb94d…"}
+//
+// The verdict is pass, fail or skip. A plugin's fail fails the case, and
+// every verdict is saved next to the stage's results, so the score counts
+// them under the plugin category.
+//
+// Plugins are listed in plugins.json, or the file PLUGINS_FILE names:
+//
+//	{"plugins": [
+//	  {"name": "rust-reference", "command": ["./plugins/rust-ref", "--strict"], "timeout": "10s"}
+//	]}
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"cdk-erigon-precompile/internal/hooks"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// Version is the version of the protocol sent in every request.
+const Version = 1
+
+// DefaultPath is the plugins file read when PLUGINS_FILE is unset.
+const DefaultPath = "plugins.json"
+
+// DefaultTimeout bounds a plugin's answer to one case when it sets none.
+const DefaultTimeout = 30 * time.Second
+
+// Verdicts a plugin answers with.
+const (
+	Pass = "pass"
+	Fail = "fail"
+	Skip = "skip"
+)
+
+// Plugin is one external validator.
+type Plugin struct {
+	Name string `json:"name"`
+	// Command is the executable and its arguments. A relative executable
+	// path is taken from the directory of the plugins file, where the
+	// plugin also runs.
+	Command []string `json:"command"`
+	// Timeout is a duration such as "10s"; DefaultTimeout when empty.
+	Timeout string `json:"timeout,omitempty"`
+
+	dir     string
+	timeout time.Duration
+}
+
+// Load reads and validates a plugins file.
+func Load(path string) ([]Plugin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Plugins []Plugin `json:"plugins"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for i := range file.Plugins {
+		p := &file.Plugins[i]
+		if p.Name == "" || len(p.Command) == 0 {
+			return nil, fmt.Errorf("%s: plugin %d needs a name and a command", path, i+1)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("%s: plugin %q listed twice", path, p.Name)
+		}
+		seen[p.Name] = true
+		p.dir = filepath.Dir(path)
+		p.timeout = DefaultTimeout
+		if p.Timeout != "" {
+			if p.timeout, err = time.ParseDuration(p.Timeout); err != nil || p.timeout <= 0 {
+				return nil, fmt.Errorf("%s: plugin %q: invalid timeout %q", path, p.Name, p.Timeout)
+			}
+		}
+	}
+	return file.Plugins, nil
+}
+
+// FromEnv loads the plugins file PLUGINS_FILE names, or plugins.json. No
+// plugins.json means no plugins; a PLUGINS_FILE that is missing is an
+// error.
+func FromEnv() ([]Plugin, error) {
+	path := os.Getenv("PLUGINS_FILE")
+	if path == "" {
+		plugins, err := Load(DefaultPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return plugins, err
+	}
+	return Load(path)
+}
+
+// Case is the case in a request.
+type Case struct {
+	ID         string        `json:"id"`
+	Precompile string        `json:"precompile"`
+	Input      hexutil.Bytes `json:"input"`
+	Target     string        `json:"target,omitempty"`
+}
+
+// Outcome is how the stage judged the case.
+type Outcome struct {
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+	// Output is what the node answered, when the stage has it.
+	Output hexutil.Bytes `json:"output,omitempty"`
+	TxHash string        `json:"txHash,omitempty"`
+	Block  uint64        `json:"block,omitempty"`
+}
+
+// Request is what a plugin reads on stdin.
+type Request struct {
+	Version int     `json:"version"`
+	Stage   string  `json:"stage"`
+	Case    Case    `json:"case"`
+	Outcome Outcome `json:"outcome"`
+}
+
+// NewRequest is the request for a case's outcome.
+func NewRequest(o hooks.Outcome) Request {
+	return Request{
+		Version: Version,
+		Stage:   o.Stage,
+		Case:    Case{ID: o.ID, Precompile: o.Precompile, Input: o.Input, Target: o.Target},
+		Outcome: Outcome{Passed: o.Passed, Error: o.Error, Output: o.Output, TxHash: o.TxHash, Block: o.Block},
+	}
+}
+
+// Response is what a plugin writes on stdout.
+type Response struct {
+	Verdict string `json:"verdict"`
+	Message string `json:"message,omitempty"`
+}
+
+// Check runs the plugin on one request. A plugin that exits non-zero, runs
+// out of time or answers anything but a verdict is an error, quoting what
+// it wrote to stderr.
+func (p Plugin) Check(ctx context.Context, req Request) (Response, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+	timeout := p.timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Dir = p.dir
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Children left holding stdout don't keep a killed plugin waiting
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("no verdict within %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return Response{}, fmt.Errorf("answered no verdict: %w", err)
+	}
+	switch resp.Verdict {
+	case Pass, Fail, Skip:
+		return resp, nil
+	}
+	return Response{}, fmt.Errorf("answered unknown verdict %q", resp.Verdict)
+}
+
+// Verdict is a plugin's judgement of one case, as saved.
+type Verdict struct {
+	Plugin     string `json:"plugin"`
+	Case       string `json:"case"`
+	Precompile string `json:"precompile"`
+	Target     string `json:"target,omitempty"`
+	// Verdict is empty when the plugin failed to answer, with Error
+	// saying why.
+	Verdict string `json:"verdict,omitempty"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Results are the verdicts of one stage run.
+type Results struct {
+	Stage     string    `json:"stage"`
+	Plugins   []string  `json:"plugins"`
+	Verdicts  []Verdict `json:"verdicts"`
+	Passed    int       `json:"passed"`
+	Failed    int       `json:"failed"`
+	Skipped   int       `json:"skipped"`
+	Errors    int       `json:"errors"`
+	Timestamp string    `json:"timestamp"`
+}
+
+// ResultsPath is where the verdicts of the stage saving its results to
+// stagePath are saved: results_stage3.json's to
+// results_stage3_plugins.json.
+func ResultsPath(stagePath string) string {
+	return strings.TrimSuffix(stagePath, ".json") + "_plugins.json"
+}
+
+// Register runs the plugins after every case with an AfterCase hook, and
+// saves their verdicts when the stage's run ends with an AfterRun hook.
+// A case any plugin fails, or fails to answer for, fails; the errors
+// name the plugin. Without plugins nothing is registered.
+func Register(plugins []Plugin) (unregister func()) {
+	if len(plugins) == 0 {
+		return func() {}
+	}
+	var (
+		mu       sync.Mutex
+		verdicts []Verdict
+	)
+	return hooks.Register(hooks.Hooks{
+		AfterCase: func(ctx context.Context, o hooks.Outcome) error {
+			req := NewRequest(o)
+			var errs []error
+			for _, p := range plugins {
+				v := Verdict{Plugin: p.Name, Case: o.ID, Precompile: o.Precompile, Target: o.Target}
+				resp, err := p.Check(ctx, req)
+				switch {
+				case err != nil:
+					v.Error = err.Error()
+					errs = append(errs, fmt.Errorf("plugin %s: %w", p.Name, err))
+				case resp.Verdict == Fail:
+					errs = append(errs, fmt.Errorf("plugin %s: %s", p.Name, orDefault(resp.Message, "case failed")))
+				}
+				v.Verdict, v.Message = resp.Verdict, resp.Message
+				mu.Lock()
+				verdicts = append(verdicts, v)
+				mu.Unlock()
+			}
+			return errors.Join(errs...)
+		},
+		AfterRun: func(_ context.Context, r hooks.Run) error {
+			mu.Lock()
+			saved := Results{Stage: r.Stage, Verdicts: verdicts, Timestamp: time.Now().UTC().Format(time.RFC3339)}
+			verdicts = nil
+			mu.Unlock()
+			for _, p := range plugins {
+				saved.Plugins = append(saved.Plugins, p.Name)
+			}
+			for _, v := range saved.Verdicts {
+				switch v.Verdict {
+				case Pass:
+					saved.Passed++
+				case Fail:
+					saved.Failed++
+				case Skip:
+					saved.Skipped++
+				default:
+					saved.Errors++
+				}
+			}
+			data, err := json.MarshalIndent(saved, "", "  ")
+			if err != nil {
+				return err
+			}
+			return paths.WriteFile(ResultsPath(r.Results), data)
+		},
+	})
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
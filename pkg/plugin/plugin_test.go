@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cdk-erigon-precompile/internal/hooks"
+)
+
+// writePlugins writes the scripts and a plugins file listing them, and
+// loads it.
+func writePlugins(t *testing.T, scripts map[string]string, list string) []Plugin {
+	t.Helper()
+	dir := t.TempDir()
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "plugins.json")
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plugins, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plugins
+}
+
+func TestCheck(t *testing.T) {
+	plugins := writePlugins(t, map[string]string{
+		// Passes the cases the node answered 0xabcd for
+		"reference": `read -r request
+case "$request" in
+*'"output":"0xabcd"'*) echo '{"verdict": "pass"}' ;;
+*) echo '{"verdict": "fail", "message": "reference answers 0xabcd"}' ;;
+esac
+`,
+		"crash":   "echo 'no reference for this input' >&2; exit 3\n",
+		"garbage": "echo 'ok'\n",
+		"slow":    "sleep 5\n",
+	}, `{"plugins": [
+		{"name": "reference", "command": ["./reference"]},
+		{"name": "crash", "command": ["./crash"]},
+		{"name": "garbage", "command": ["./garbage"]},
+		{"name": "slow", "command": ["./slow"], "timeout": "100ms"}]}`)
+	ctx := context.Background()
+
+	resp, err := plugins[0].Check(ctx, NewRequest(hooks.Outcome{Passed: true, Output: []byte{0xab, 0xcd}}))
+	if err != nil || resp.Verdict != Pass {
+		t.Errorf("matching output: %+v, %v", resp, err)
+	}
+	resp, err = plugins[0].Check(ctx, NewRequest(hooks.Outcome{Output: []byte{0x01}}))
+	if err != nil || resp.Verdict != Fail || resp.Message != "reference answers 0xabcd" {
+		t.Errorf("other output: %+v, %v", resp, err)
+	}
+	if _, err := plugins[1].Check(ctx, Request{}); err == nil || !strings.Contains(err.Error(), "no reference for this input") {
+		t.Errorf("crashing plugin: %v", err)
+	}
+	if _, err := plugins[2].Check(ctx, Request{}); err == nil {
+		t.Error("garbage accepted as a verdict")
+	}
+	if _, err := plugins[3].Check(ctx, Request{}); err == nil || !strings.Contains(err.Error(), "no verdict within 100ms") {
+		t.Errorf("slow plugin: %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	plugins := writePlugins(t, map[string]string{
+		"pass": "echo '{\"verdict\": \"pass\"}'\n",
+		"fail": "echo '{\"verdict\": \"fail\", \"message\": \"wrong digest\"}'\n",
+	}, `{"plugins": [{"name": "lenient", "command": ["./pass"]}, {"name": "strict", "command": ["./fail"]}]}`)
+	defer Register(plugins)()
+	ctx := context.Background()
+
+	err := hooks.AfterCase(ctx, hooks.Outcome{Case: hooks.Case{ID: "a", Precompile: "0x02"}, Passed: true})
+	if !errors.Is(err, hooks.ErrHook) || !strings.Contains(err.Error(), "plugin strict: wrong digest") {
+		t.Errorf("AfterCase: %v", err)
+	}
+	results := filepath.Join(t.TempDir(), "results_stage3.json")
+	if err := hooks.AfterRun(ctx, hooks.Run{Stage: "Stage 3", Results: results}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(ResultsPath(results))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Results
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Stage != "Stage 3" || len(saved.Verdicts) != 2 || saved.Passed != 1 || saved.Failed != 1 {
+		t.Errorf("saved %+v", saved)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"no command":      `{"plugins": [{"name": "a"}]}`,
+		"repeated name":   `{"plugins": [{"name": "a", "command": ["x"]}, {"name": "a", "command": ["y"]}]}`,
+		"invalid timeout": `{"plugins": [{"name": "a", "command": ["x"], "timeout": "soon"}]}`,
+	} {
+		path := filepath.Join(dir, "plugins.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+
+	// Only the default plugins file is optional
+	t.Setenv("PLUGINS_FILE", filepath.Join(dir, "missing.json"))
+	if _, err := FromEnv(); err == nil {
+		t.Error("missing PLUGINS_FILE accepted")
+	}
+}
@@ -0,0 +1,244 @@
+package precompile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+// BLSPrecompile is one of the BLS12-381 precompiles of EIP-2537. Address is
+// where Prague puts it; DraftAddress is where earlier drafts of the EIP,
+// which some pre-Pectra builds still follow, put the same operation. The
+// drafts had separate single-point multiplications at 0x0c and 0x0f, and
+// shifted everything after them.
+type BLSPrecompile struct {
+	Name         string
+	Address      common.Address
+	DraftAddress common.Address
+}
+
+var (
+	BLSG1Add   = BLSPrecompile{"g1add", common.HexToAddress("0x0b"), common.HexToAddress("0x0b")}
+	BLSG1MSM   = BLSPrecompile{"g1msm", common.HexToAddress("0x0c"), common.HexToAddress("0x0d")}
+	BLSG2Add   = BLSPrecompile{"g2add", common.HexToAddress("0x0d"), common.HexToAddress("0x0e")}
+	BLSG2MSM   = BLSPrecompile{"g2msm", common.HexToAddress("0x0e"), common.HexToAddress("0x10")}
+	BLSPairing = BLSPrecompile{"pairing", common.HexToAddress("0x0f"), common.HexToAddress("0x11")}
+	BLSMapG1   = BLSPrecompile{"map_fp_to_g1", common.HexToAddress("0x10"), common.HexToAddress("0x12")}
+	BLSMapG2   = BLSPrecompile{"map_fp2_to_g2", common.HexToAddress("0x11"), common.HexToAddress("0x13")}
+)
+
+// BLSPrecompiles lists the precompiles in address order.
+var BLSPrecompiles = []BLSPrecompile{BLSG1Add, BLSG1MSM, BLSG2Add, BLSG2MSM, BLSPairing, BLSMapG1, BLSMapG2}
+
+// blsP is the BLS12-381 base field modulus and blsR the group order.
+var (
+	blsP, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+	blsR, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+)
+
+// fp encodes a field element as EIP-2537 does: 64 bytes, the top 16 zero.
+func fp(n *big.Int) []byte { return common.LeftPadBytes(n.Bytes(), 64) }
+
+func hexInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 16)
+	return n
+}
+
+// The generators, and points on the curves but outside the prime-order
+// subgroup, from go-ethereum's EIP-2537 failure vectors.
+var (
+	g1x  = hexInt("17f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb")
+	g1y  = hexInt("08b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1")
+	g2x0 = hexInt("024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8")
+	g2x1 = hexInt("13e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e")
+	g2y0 = hexInt("0ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801")
+	g2y1 = hexInt("0606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be")
+
+	badG1 = concat(
+		fp(hexInt("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")),
+		fp(hexInt("193fb7cedb32b2c3adc06ec11a96bc0d661869316f5e4a577a9f7c179593987beb4fb2ee424dbb2f5dd891e228b46c4a")))
+	badG2 = concat(fp(big.NewInt(0)), fp(big.NewInt(2)),
+		fp(hexInt("013a59858b6809fca4d9a3b6539246a70051a3c88899964a42bc9a69cf9acdd9dd387cfa9086b894185b9a46a402be73")),
+		fp(hexInt("02d27e0ec3356299a346a09ad7dc4ef68a483c3aed53f9139d2f929a3eecebf72082e5e58c6da24ee32e03040c406d4f")))
+)
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func neg(y *big.Int) *big.Int { return new(big.Int).Sub(blsP, y) }
+
+func scalar(n *big.Int) []byte { return common.LeftPadBytes(n.Bytes(), 32) }
+
+// BLSVector is an input to a BLS12-381 precompile. Fail marks inputs the
+// precompile must reject.
+type BLSVector struct {
+	Name       string
+	Precompile BLSPrecompile
+	Input      []byte
+	Fail       bool
+}
+
+// Reference is the answer of the reference implementation for v.
+func (v BLSVector) Reference() ([]byte, error) {
+	return reference.Run(v.Precompile.Address, v.Input)
+}
+
+// Gas is the precompile's cost for v under EIP-2537.
+func (v BLSVector) Gas() uint64 {
+	return vm.PrecompiledContractsPrague[v.Precompile.Address].RequiredGas(v.Input)
+}
+
+// BLSVectors covers every BLS12-381 precompile with identities whose
+// answers are known (adding the point at infinity, P + -P, multiplying by
+// zero and by the group order, pairings that multiply to one) and with the
+// encodings the EIP requires them to reject: wrong lengths, coordinates
+// not below p, set top bytes, points off the curve and, for the
+// multiplications and the pairing, points outside the subgroup. The first
+// vector of each precompile is a valid input fit for probing it.
+func BLSVectors() []BLSVector {
+	g1 := concat(fp(g1x), fp(g1y))
+	negG1 := concat(fp(g1x), fp(neg(g1y)))
+	infG1 := make([]byte, 128)
+	offCurveG1 := concat(fp(g1x), fp(new(big.Int).Add(g1y, big.NewInt(1))))
+	g2 := concat(fp(g2x0), fp(g2x1), fp(g2y0), fp(g2y1))
+	negG2 := concat(fp(g2x0), fp(g2x1), fp(neg(g2y0)), fp(neg(g2y1)))
+	infG2 := make([]byte, 256)
+	offCurveG2 := concat(fp(g2x0), fp(g2x1), fp(new(big.Int).Add(g2y0, big.NewInt(1))), fp(g2y1))
+	one, two := scalar(big.NewInt(1)), scalar(big.NewInt(2))
+
+	topByte := concat(g1, infG1)
+	topByte[0] = 1
+
+	return []BLSVector{
+		{Name: "g1 + g1", Precompile: BLSG1Add, Input: concat(g1, g1)},
+		{Name: "g1 + inf = g1", Precompile: BLSG1Add, Input: concat(g1, infG1)},
+		{Name: "inf + inf = inf", Precompile: BLSG1Add, Input: concat(infG1, infG1)},
+		{Name: "g1 + -g1 = inf", Precompile: BLSG1Add, Input: concat(g1, negG1)},
+		{Name: "points outside the subgroup add without a check", Precompile: BLSG1Add, Input: concat(badG1, badG1)},
+		{Name: "short input", Precompile: BLSG1Add, Input: concat(g1, infG1)[:255], Fail: true},
+		{Name: "long input", Precompile: BLSG1Add, Input: concat(g1, infG1, []byte{0}), Fail: true},
+		{Name: "point off the curve", Precompile: BLSG1Add, Input: concat(offCurveG1, g1), Fail: true},
+		{Name: "coordinate equal to p", Precompile: BLSG1Add, Input: concat(fp(blsP), fp(g1y), g1), Fail: true},
+		{Name: "top byte set", Precompile: BLSG1Add, Input: topByte, Fail: true},
+
+		{Name: "2·g1", Precompile: BLSG1MSM, Input: concat(g1, two)},
+		{Name: "1·g1 + 2·g1", Precompile: BLSG1MSM, Input: concat(g1, one, g1, two)},
+		{Name: "0·g1 = inf", Precompile: BLSG1MSM, Input: concat(g1, scalar(big.NewInt(0)))},
+		{Name: "r·g1 = inf", Precompile: BLSG1MSM, Input: concat(g1, scalar(blsR))},
+		{Name: "empty input", Precompile: BLSG1MSM, Input: nil, Fail: true},
+		{Name: "point outside the subgroup", Precompile: BLSG1MSM, Input: concat(badG1, one), Fail: true},
+		{Name: "trailing byte", Precompile: BLSG1MSM, Input: concat(g1, two, []byte{0}), Fail: true},
+
+		{Name: "g2 + g2", Precompile: BLSG2Add, Input: concat(g2, g2)},
+		{Name: "g2 + inf = g2", Precompile: BLSG2Add, Input: concat(g2, infG2)},
+		{Name: "g2 + -g2 = inf", Precompile: BLSG2Add, Input: concat(g2, negG2)},
+		{Name: "short input", Precompile: BLSG2Add, Input: concat(g2, g2)[:511], Fail: true},
+		{Name: "point off the curve", Precompile: BLSG2Add, Input: concat(offCurveG2, g2), Fail: true},
+
+		{Name: "2·g2", Precompile: BLSG2MSM, Input: concat(g2, two)},
+		{Name: "1·g2 + 2·g2", Precompile: BLSG2MSM, Input: concat(g2, one, g2, two)},
+		{Name: "0·g2 = inf", Precompile: BLSG2MSM, Input: concat(g2, scalar(big.NewInt(0)))},
+		{Name: "empty input", Precompile: BLSG2MSM, Input: nil, Fail: true},
+		{Name: "point outside the subgroup", Precompile: BLSG2MSM, Input: concat(badG2, one), Fail: true},
+
+		{Name: "e(g1, g2)·e(-g1, g2) = 1", Precompile: BLSPairing, Input: concat(g1, g2, negG1, g2)},
+		{Name: "e(g1, g2) ≠ 1", Precompile: BLSPairing, Input: concat(g1, g2)},
+		{Name: "e(inf, g2) = 1", Precompile: BLSPairing, Input: concat(infG1, g2)},
+		{Name: "empty input", Precompile: BLSPairing, Input: nil, Fail: true},
+		{Name: "extra byte", Precompile: BLSPairing, Input: concat(g1, g2, []byte{0}), Fail: true},
+		{Name: "g1 outside the subgroup", Precompile: BLSPairing, Input: concat(badG1, g2), Fail: true},
+		{Name: "g2 outside the subgroup", Precompile: BLSPairing, Input: concat(g1, badG2), Fail: true},
+
+		{Name: "fp 1", Precompile: BLSMapG1, Input: fp(big.NewInt(1))},
+		{Name: "fp 0", Precompile: BLSMapG1, Input: fp(big.NewInt(0))},
+		{Name: "fp p-1", Precompile: BLSMapG1, Input: fp(new(big.Int).Sub(blsP, big.NewInt(1)))},
+		{Name: "fp equal to p", Precompile: BLSMapG1, Input: fp(blsP), Fail: true},
+		{Name: "short input", Precompile: BLSMapG1, Input: fp(big.NewInt(1))[:63], Fail: true},
+
+		{Name: "fp2 (1, 2)", Precompile: BLSMapG2, Input: concat(fp(big.NewInt(1)), fp(big.NewInt(2)))},
+		{Name: "fp2 (0, 0)", Precompile: BLSMapG2, Input: make([]byte, 128)},
+		{Name: "fp2 c1 equal to p", Precompile: BLSMapG2, Input: concat(fp(big.NewInt(1)), fp(blsP)), Fail: true},
+		{Name: "short input", Precompile: BLSMapG2, Input: make([]byte, 127), Fail: true},
+	}
+}
+
+// CallBLS calls the precompile at address with input.
+func CallBLS(ctx context.Context, client *ethclient.Client, address common.Address, input []byte) ([]byte, error) {
+	return client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: input}, nil)
+}
+
+// States of a BLS precompile as found by ProbeBLS.
+const (
+	BLSSupported     = "supported"
+	BLSAbsent        = "absent"
+	BLSNonconforming = "nonconforming"
+	BLSProbeError    = "error"
+)
+
+// BLSCapability is how the node exposes one BLS precompile.
+type BLSCapability struct {
+	Name string `json:"name"`
+	// Status is supported when the probe returned go-ethereum's answer,
+	// absent when it returned nothing like an empty account, nonconforming
+	// when it returned something else, and error when the call failed.
+	Status string `json:"status"`
+	// Address is where something answered the probe, the Prague or the
+	// draft address; it is empty when the precompile is absent.
+	Address string `json:"address,omitempty"`
+	Draft   bool   `json:"draft,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// ProbeBLS looks for p at its Prague address and, failing that, at its
+// draft address, calling it with its first vector. An empty account
+// answers a call with nothing, so an empty output means no precompile is
+// there.
+func ProbeBLS(ctx context.Context, client *ethclient.Client, p BLSPrecompile) BLSCapability {
+	var v BLSVector
+	for _, v = range BLSVectors() {
+		if v.Precompile == p {
+			break
+		}
+	}
+	expected, err := v.Reference()
+	if err != nil {
+		panic(fmt.Sprintf("probe vector %s %s: %v", p.Name, v.Name, err))
+	}
+	try := func(address common.Address, draft bool) BLSCapability {
+		c := BLSCapability{Name: p.Name, Address: address.Hex(), Draft: draft}
+		out, err := CallBLS(ctx, client, address, v.Input)
+		switch {
+		case err != nil:
+			c.Status, c.Detail = BLSProbeError, fmt.Sprintf("at %s: %v", address.Hex(), err)
+		case len(out) == 0:
+			return BLSCapability{Name: p.Name, Status: BLSAbsent}
+		case !bytes.Equal(out, expected):
+			c.Status, c.Detail = BLSNonconforming, fmt.Sprintf("%s returned %x for %s", address.Hex(), out, v.Name)
+		default:
+			c.Status = BLSSupported
+		}
+		return c
+	}
+	prague := try(p.Address, false)
+	if prague.Status == BLSSupported || p.DraftAddress == p.Address {
+		return prague
+	}
+	// Keep the Prague verdict unless the draft address does better
+	if draft := try(p.DraftAddress, true); draft.Status == BLSSupported || prague.Status == BLSAbsent {
+		return draft
+	}
+	return prague
+}
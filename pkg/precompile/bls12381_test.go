@@ -0,0 +1,123 @@
+package precompile
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+func TestBLSVectors(t *testing.T) {
+	seen := map[string]bool{}
+	for _, v := range BLSVectors() {
+		out, err := v.Reference()
+		if v.Fail != (err != nil) {
+			t.Errorf("%s %s: fail=%t, reference returned %x, %v", v.Precompile.Name, v.Name, v.Fail, out, err)
+		}
+		// The first vector of each precompile probes it and must be valid
+		if !seen[v.Precompile.Name] && v.Fail {
+			t.Errorf("%s: first vector %q is invalid", v.Precompile.Name, v.Name)
+		}
+		seen[v.Precompile.Name] = true
+		if v.Gas() == 0 && !v.Fail {
+			t.Errorf("%s %s: zero gas", v.Precompile.Name, v.Name)
+		}
+	}
+	if len(seen) != len(BLSPrecompiles) {
+		t.Errorf("vectors cover %d of %d precompiles", len(seen), len(BLSPrecompiles))
+	}
+}
+
+// Different operations on the same points must agree with each other, not
+// only with the reference.
+func TestBLSIdentities(t *testing.T) {
+	answers := map[string][]byte{}
+	for _, v := range BLSVectors() {
+		if !v.Fail {
+			out, _ := v.Reference()
+			answers[v.Precompile.Name+" "+v.Name] = out
+		}
+	}
+	for _, pair := range [][2]string{
+		{"g1add g1 + g1", "g1msm 2·g1"},
+		{"g2add g2 + g2", "g2msm 2·g2"},
+		{"g1add g1 + -g1 = inf", "g1msm r·g1 = inf"},
+		{"g1add inf + inf = inf", "g1msm 0·g1 = inf"},
+	} {
+		if !bytes.Equal(answers[pair[0]], answers[pair[1]]) {
+			t.Errorf("%s = %x, %s = %x", pair[0], answers[pair[0]], pair[1], answers[pair[1]])
+		}
+	}
+	if out := answers["pairing e(g1, g2)·e(-g1, g2) = 1"]; len(out) != 32 || out[31] != 1 {
+		t.Errorf("pairing product %x, want true", out)
+	}
+	if out := answers["pairing e(g1, g2) ≠ 1"]; len(out) != 32 || out[31] != 0 {
+		t.Errorf("single pairing %x, want false", out)
+	}
+	if out := answers["g1add g1 + inf = g1"]; !bytes.Equal(out[:128], BLSVectors()[0].Input[:128]) {
+		t.Errorf("g1 + inf = %x", out)
+	}
+}
+
+// blsNode answers eth_call like a node defining each precompile at the
+// address layout picks, and like an empty account everywhere else.
+func blsNode(layout func(BLSPrecompile) common.Address) mockrpc.Handler {
+	return func(c mockrpc.Call) (any, error) {
+		var args callArgs
+		if err := c.Param(0, &args); err != nil {
+			return nil, err
+		}
+		for _, p := range BLSPrecompiles {
+			if layout(p) == args.To {
+				out, err := reference.Run(p.Address, args.payload())
+				if err != nil {
+					return nil, err
+				}
+				return hexutil.Bytes(out), nil
+			}
+		}
+		return hexutil.Bytes{}, nil
+	}
+}
+
+func TestProbeBLS(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		layout func(BLSPrecompile) common.Address
+		status string
+		draft  bool
+	}{
+		{"prague", func(p BLSPrecompile) common.Address { return p.Address }, BLSSupported, false},
+		{"draft", func(p BLSPrecompile) common.Address { return p.DraftAddress }, BLSSupported, true},
+		{"none", func(BLSPrecompile) common.Address { return common.Address{} }, BLSAbsent, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, _ := setup(t, blsNode(tc.layout))
+			for _, p := range BLSPrecompiles {
+				c := ProbeBLS(context.Background(), client, p)
+				want := ""
+				if tc.status != BLSAbsent {
+					want = tc.layout(p).Hex()
+				}
+				if c.Status != tc.status || c.Address != want || (c.Draft != tc.draft && p.Address != p.DraftAddress) {
+					t.Errorf("%s: got %+v, want %s at %q", p.Name, c, tc.status, want)
+				}
+			}
+		})
+	}
+}
+
+// Whatever answers at an address is reported there, so callers can tell
+// it isn't an empty account.
+func TestProbeBLSNonconforming(t *testing.T) {
+	client, _ := setup(t, mockrpc.Static(hexutil.Bytes{0x01}))
+	c := ProbeBLS(context.Background(), client, BLSG1MSM)
+	if c.Status != BLSNonconforming || c.Address != BLSG1MSM.Address.Hex() || c.Draft {
+		t.Errorf("got %+v, want nonconforming at the Prague address", c)
+	}
+}
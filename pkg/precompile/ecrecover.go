@@ -0,0 +1,74 @@
+package precompile
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ECRecoverAddress is the address of the ecrecover precompile.
+var ECRecoverAddress = common.HexToAddress("0x01")
+
+// Signature is a message hash signed locally, with the address the
+// precompile is expected to recover.
+type Signature struct {
+	Hash common.Hash
+	// Sig is [R || S || V] with V in {0, 1}, as crypto.Sign returns it.
+	Sig    []byte
+	Signer common.Address
+}
+
+// Input is the 128-byte precompile input: hash, v (27 or 28), r and s.
+func (s Signature) Input() []byte {
+	input := make([]byte, 128)
+	copy(input[0:32], s.Hash[:])
+	input[63] = s.Sig[64] + 27
+	copy(input[64:128], s.Sig[:64])
+	return input
+}
+
+// RandomSignatures generates n fresh keys and signs the keccak256 hash of
+// a random 32-byte message with each.
+func RandomSignatures(n int) ([]Signature, error) {
+	sigs := make([]Signature, n)
+	msg := make([]byte, 32)
+	for i := range sigs {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate key: %w", err)
+		}
+		if _, err := rand.Read(msg); err != nil {
+			return nil, fmt.Errorf("failed to generate message: %w", err)
+		}
+		hash := crypto.Keccak256Hash(msg)
+		sig, err := crypto.Sign(hash[:], key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign: %w", err)
+		}
+		sigs[i] = Signature{Hash: hash, Sig: sig, Signer: crypto.PubkeyToAddress(key.PublicKey)}
+	}
+	return sigs, nil
+}
+
+// CallECRecover calls the precompile with s and returns the recovered
+// address. The precompile answers nothing for signatures it can't recover,
+// which is returned as the zero address.
+func CallECRecover(ctx context.Context, client *ethclient.Client, s Signature) (common.Address, error) {
+	to := ECRecoverAddress
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: s.Input()}, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	switch len(out) {
+	case 0:
+		return common.Address{}, nil
+	case 32:
+		return common.BytesToAddress(out[12:]), nil
+	}
+	return common.Address{}, fmt.Errorf("unexpected ecrecover output of %d bytes", len(out))
+}
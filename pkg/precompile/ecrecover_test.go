@@ -0,0 +1,78 @@
+package precompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+// ecrecover answers like the precompile: the left-padded signer, or nothing
+// for an unrecoverable signature.
+func ecrecover(c mockrpc.Call) (any, error) {
+	var args callArgs
+	if err := c.Param(0, &args); err != nil {
+		return nil, err
+	}
+	input := args.payload()
+	sig := append(append([]byte(nil), input[64:128]...), input[63]-27)
+	pub, err := crypto.SigToPub(input[:32], sig)
+	if err != nil {
+		return hexutil.Bytes{}, nil
+	}
+	return hexutil.Bytes(common.LeftPadBytes(crypto.PubkeyToAddress(*pub).Bytes(), 32)), nil
+}
+
+func TestCallECRecover(t *testing.T) {
+	client, s := setup(t, ecrecover)
+	sigs, err := RandomSignatures(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sig := range sigs {
+		got, err := CallECRecover(context.Background(), client, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != sig.Signer {
+			t.Errorf("recovered %s, want %s", got.Hex(), sig.Signer.Hex())
+		}
+	}
+	if n := s.Calls("eth_call"); n != len(sigs) {
+		t.Errorf("%d calls, want %d", n, len(sigs))
+	}
+}
+
+func TestCallECRecoverInvalid(t *testing.T) {
+	client, _ := setup(t, ecrecover)
+	sigs, err := RandomSignatures(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// r = 0 is never a valid signature
+	bad := sigs[0]
+	bad.Sig = append([]byte(nil), bad.Sig...)
+	copy(bad.Sig[:32], make([]byte, 32))
+	got, err := CallECRecover(context.Background(), client, bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (common.Address{}) {
+		t.Errorf("recovered %s from an invalid signature", got.Hex())
+	}
+}
+
+func TestCallECRecoverMalformed(t *testing.T) {
+	client, _ := setup(t, mockrpc.Static(hexutil.Bytes{0x01}))
+	sigs, err := RandomSignatures(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CallECRecover(context.Background(), client, sigs[0]); err == nil {
+		t.Error("expected an error for a 1-byte answer")
+	}
+}
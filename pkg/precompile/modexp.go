@@ -0,0 +1,151 @@
+package precompile
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/reference"
+)
+
+// ModExpAddress is the address of the modexp precompile.
+var ModExpAddress = common.HexToAddress("0x05")
+
+// ModExp is one base^exp % mod computation, operands big-endian and of the
+// lengths given to the precompile.
+type ModExp struct {
+	Base, Exp, Mod []byte
+}
+
+// Input encodes m as the precompile expects: the three operand lengths as
+// 32-byte words followed by the operands.
+func (m ModExp) Input() []byte {
+	input := make([]byte, 0, 96+len(m.Base)+len(m.Exp)+len(m.Mod))
+	for _, n := range []int{len(m.Base), len(m.Exp), len(m.Mod)} {
+		input = append(input, common.LeftPadBytes(big.NewInt(int64(n)).Bytes(), 32)...)
+	}
+	input = append(input, m.Base...)
+	input = append(input, m.Exp...)
+	return append(input, m.Mod...)
+}
+
+// Expected is the result of the reference implementation, padded to the
+// modulus length. A zero modulus gives zero.
+func (m ModExp) Expected() []byte {
+	out, err := reference.ModExp(m.Input())
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// Gas is the precompile's cost under EIP-2565.
+func (m ModExp) Gas() uint64 {
+	maxLen := uint64(max(len(m.Base), len(m.Mod)))
+	words := (maxLen + 7) / 8
+	complexity := words * words
+
+	// The iteration count depends on the bit length of the exponent's
+	// leading 32 bytes and on how far the exponent extends past them
+	head := m.Exp
+	if len(head) > 32 {
+		head = head[:32]
+	}
+	var iterations uint64
+	if bits := new(big.Int).SetBytes(head).BitLen(); bits > 0 {
+		iterations = uint64(bits - 1)
+	}
+	if len(m.Exp) > 32 {
+		iterations += 8 * uint64(len(m.Exp)-32)
+	}
+	iterations = max(iterations, 1)
+
+	return max(200, complexity*iterations/3)
+}
+
+// CallModExp calls the precompile with m and returns its answer.
+func CallModExp(ctx context.Context, client *ethclient.Client, m ModExp) ([]byte, error) {
+	to := ModExpAddress
+	return client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: m.Input()}, nil)
+}
+
+// ModExpProbe is a named input chosen to stress the implementation.
+type ModExpProbe struct {
+	Name string
+	// Bits is the operand size.
+	Bits int
+	ModExp
+}
+
+// Carmichael numbers pass the Fermat test for every coprime base, which
+// makes exponents next to them a classic edge case for shortcuts in
+// modular exponentiation.
+var carmichael = []int64{561, 41041, 825265, 321197185}
+
+// ModExpProbes returns the worst-case probes: a cheap 256-bit reference
+// first, then for each operand size a full-length all-ones exponent with an
+// odd and with an even modulus (which rules out Montgomery multiplication),
+// and short exponents next to Carmichael numbers, which are charged the
+// minimum for their length but still multiply full-size operands.
+func ModExpProbes(sizes []int) []ModExpProbe {
+	probes := []ModExpProbe{{
+		Name: "reference-256",
+		Bits: 256,
+		ModExp: ModExp{
+			Base: ones(256),
+			Exp:  ones(256),
+			Mod:  oddModulus(256),
+		},
+	}}
+	for _, bits := range sizes {
+		probes = append(probes,
+			ModExpProbe{
+				Name:   fmt.Sprintf("odd-modulus-%d", bits),
+				Bits:   bits,
+				ModExp: ModExp{Base: ones(bits), Exp: ones(bits), Mod: oddModulus(bits)},
+			},
+			ModExpProbe{
+				Name:   fmt.Sprintf("even-modulus-%d", bits),
+				Bits:   bits,
+				ModExp: ModExp{Base: ones(bits), Exp: ones(bits), Mod: evenModulus(bits)},
+			},
+		)
+		for _, c := range carmichael {
+			for _, delta := range []int64{-1, 1} {
+				exp := big.NewInt(c + delta)
+				probes = append(probes, ModExpProbe{
+					Name:   fmt.Sprintf("carmichael-%d%+d-%d", c, delta, bits),
+					Bits:   bits,
+					ModExp: ModExp{Base: ones(bits), Exp: exp.Bytes(), Mod: evenModulus(bits)},
+				})
+			}
+		}
+	}
+	return probes
+}
+
+// ones is a bits-long operand with every bit set.
+func ones(bits int) []byte {
+	b := make([]byte, bits/8)
+	for i := range b {
+		b[i] = 0xff
+	}
+	return b
+}
+
+// oddModulus is 2^bits - 3; evenModulus is 2^bits - 2.
+func oddModulus(bits int) []byte {
+	b := ones(bits)
+	b[len(b)-1] = 0xfd
+	return b
+}
+
+func evenModulus(bits int) []byte {
+	b := ones(bits)
+	b[len(b)-1] = 0xfe
+	return b
+}
@@ -0,0 +1,71 @@
+package precompile
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestModExpGas(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		m    ModExp
+		want uint64
+	}{
+		// EIP-2565 example: 32-byte operands, 256-bit exponent
+		{"reference", ModExp{Base: ones(256), Exp: ones(256), Mod: oddModulus(256)}, 1360},
+		{"1024-bit", ModExp{Base: ones(1024), Exp: ones(1024), Mod: oddModulus(1024)}, 87296},
+		{"short exponent", ModExp{Base: ones(4096), Exp: big.NewInt(560).Bytes(), Mod: evenModulus(4096)}, 12288},
+		{"minimum", ModExp{Base: []byte{3}, Exp: []byte{0}, Mod: []byte{7}}, 200},
+	} {
+		if got := tt.m.Gas(); got != tt.want {
+			t.Errorf("%s: gas %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestModExpExpected(t *testing.T) {
+	m := ModExp{Base: []byte{3}, Exp: []byte{5}, Mod: []byte{0, 0, 100}}
+	if got := m.Expected(); !bytes.Equal(got, []byte{0, 0, 43}) {
+		t.Errorf("3^5 %% 100 = %x, want 00002b", got)
+	}
+	if got := (ModExp{Base: []byte{3}, Exp: []byte{5}, Mod: []byte{0, 0}}).Expected(); !bytes.Equal(got, []byte{0, 0}) {
+		t.Errorf("zero modulus gave %x", got)
+	}
+}
+
+func TestCallModExp(t *testing.T) {
+	client, _ := setup(t, func(c mockrpc.Call) (any, error) {
+		var args callArgs
+		if err := c.Param(0, &args); err != nil {
+			return nil, err
+		}
+		if args.To != ModExpAddress {
+			t.Errorf("called %s, want the precompile", args.To.Hex())
+		}
+		// Decode the lengths back out of the input
+		input := args.payload()
+		lens := make([]int, 3)
+		for i := range lens {
+			lens[i] = int(new(big.Int).SetBytes(input[32*i : 32*i+32]).Int64())
+		}
+		data := input[96:]
+		m := ModExp{Base: data[:lens[0]], Exp: data[lens[0] : lens[0]+lens[1]], Mod: data[lens[0]+lens[1]:]}
+		return hexutil.Bytes(m.Expected()), nil
+	})
+
+	for _, p := range ModExpProbes([]int{1024}) {
+		got, err := CallModExp(context.Background(), client, p.ModExp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, p.Expected()) {
+			t.Errorf("%s: got %x", p.Name, got)
+		}
+	}
+}
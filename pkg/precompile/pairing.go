@@ -0,0 +1,67 @@
+package precompile
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/bn256"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// PairingAddress is the address of the bn256 pairing check precompile.
+var PairingAddress = common.HexToAddress("0x08")
+
+// PairSize is the input size of one (G1, G2) pair.
+const PairSize = 192
+
+// PairingGas is the precompile's cost for n pairs under EIP-1108.
+func PairingGas(n int) uint64 {
+	return 45000 + 34000*uint64(n)
+}
+
+// PairingInput returns n pairs whose pairing product is one, so the
+// precompile must answer true for every n of two or more. All pairs use
+// non-trivial points so no implementation can shortcut them:
+// e(G1, G2) * e(-G1, G2) = 1 repeated, with e(2G1, G2) * e(-G1, G2)^2 for
+// an odd count. A single pair can't multiply to one with non-trivial
+// points; it is e(G1, G2), which must answer false.
+func PairingInput(n int) (input []byte, expected bool) {
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	negG1 := new(bn256.G1).Neg(g1)
+	twoG1 := new(bn256.G1).ScalarBaseMult(big.NewInt(2))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1)).Marshal()
+
+	pair := func(p *bn256.G1) []byte { return append(p.Marshal(), g2...) }
+	if n == 1 {
+		return pair(g1), false
+	}
+
+	input = make([]byte, 0, n*PairSize)
+	if n%2 == 1 {
+		input = append(input, pair(twoG1)...)
+		input = append(input, pair(negG1)...)
+		input = append(input, pair(negG1)...)
+		n -= 3
+	}
+	for i := 0; i < n; i += 2 {
+		input = append(input, pair(g1)...)
+		input = append(input, pair(negG1)...)
+	}
+	return input, true
+}
+
+// CallPairing calls the precompile with input and returns its verdict.
+func CallPairing(ctx context.Context, client *ethclient.Client, input []byte) (bool, error) {
+	to := PairingAddress
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: input}, nil)
+	if err != nil {
+		return false, err
+	}
+	if len(out) != 32 {
+		return false, fmt.Errorf("unexpected pairing output of %d bytes", len(out))
+	}
+	return new(big.Int).SetBytes(out).Cmp(big.NewInt(1)) == 0, nil
+}
@@ -0,0 +1,68 @@
+package precompile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/bn256"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+// pairingCheck answers like the precompile.
+func pairingCheck(c mockrpc.Call) (any, error) {
+	var args callArgs
+	if err := c.Param(0, &args); err != nil {
+		return nil, err
+	}
+	input := args.payload()
+	var g1s []*bn256.G1
+	var g2s []*bn256.G2
+	for i := 0; i < len(input); i += PairSize {
+		g1, g2 := new(bn256.G1), new(bn256.G2)
+		if _, err := g1.Unmarshal(input[i : i+64]); err != nil {
+			return nil, err
+		}
+		if _, err := g2.Unmarshal(input[i+64 : i+PairSize]); err != nil {
+			return nil, err
+		}
+		g1s, g2s = append(g1s, g1), append(g2s, g2)
+	}
+	out := make([]byte, 32)
+	if bn256.PairingCheck(g1s, g2s) {
+		out[31] = 1
+	}
+	return hexutil.Bytes(out), nil
+}
+
+func TestPairingInput(t *testing.T) {
+	client, _ := setup(t, pairingCheck)
+	for n := 1; n <= 6; n++ {
+		input, expected := PairingInput(n)
+		if len(input) != n*PairSize {
+			t.Fatalf("%d pairs: %d bytes", n, len(input))
+		}
+		got, err := CallPairing(context.Background(), client, input)
+		if err != nil {
+			t.Fatalf("%d pairs: %v", n, err)
+		}
+		if got != expected {
+			t.Errorf("%d pairs: got %v, want %v", n, got, expected)
+		}
+	}
+}
+
+func TestPairingGas(t *testing.T) {
+	if got := PairingGas(2); got != 113000 {
+		t.Errorf("2 pairs: %d gas, want 113000", got)
+	}
+}
+
+func TestCallPairingMalformed(t *testing.T) {
+	client, _ := setup(t, mockrpc.Static(hexutil.Bytes{}))
+	input, _ := PairingInput(2)
+	if _, err := CallPairing(context.Background(), client, input); err == nil {
+		t.Error("expected an error for an empty answer")
+	}
+}
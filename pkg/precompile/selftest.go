@@ -0,0 +1,75 @@
+package precompile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+)
+
+// SelfTestChecks names the known-answer checks of the wrapper's
+// selftest(); check i passed when bit i of its answer is set.
+var SelfTestChecks = []string{
+	"sha256 of empty input",
+	`sha256 of "abc"`,
+	`sha256 of "hello world"`,
+	"sha256 of the 56-byte two-block message",
+	"sha256 of 64 zero bytes",
+	"32-byte answer",
+}
+
+// selfTestABI declares selftest() on its own, so wrappers compiled before
+// it was added still resolve with the current artifacts.
+const selfTestABI = `[{"type":"function","name":"selftest","stateMutability":"view","inputs":[],"outputs":[{"name":"passed","type":"uint256"}]}]`
+
+// ErrNoSelfTest is returned for wrappers whose code has no selftest(), as
+// those compiled before it was added.
+var ErrNoSelfTest = errors.New("wrapper has no selftest()")
+
+// WrapperSelfTest calls selftest() on the wrapper at address and returns
+// one check per known-answer check it ran. It fails with ErrNoSelfTest
+// when the wrapper's dispatch table has no selftest().
+func WrapperSelfTest(ctx context.Context, client *ethclient.Client, address common.Address) ([]chain.Check, error) {
+	parsed, err := abi.JSON(strings.NewReader(selfTestABI))
+	if err != nil {
+		return nil, err
+	}
+	method := parsed.Methods["selftest"]
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract code: %w", err)
+	}
+	if len(code) == 0 {
+		return nil, &chain.NoCodeError{Address: address}
+	}
+	if !deploy.Selectors(code)[[4]byte(method.ID)] {
+		return nil, ErrNoSelfTest
+	}
+
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: method.ID}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("selftest() call failed: %w", err)
+	}
+	unpacked, err := parsed.Unpack("selftest", out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode selftest() answer %x: %w", out, err)
+	}
+	passed := unpacked[0].(*big.Int)
+	checks := make([]chain.Check, len(SelfTestChecks))
+	for i, name := range SelfTestChecks {
+		checks[i] = chain.Check{Name: name, Passed: passed.Bit(i) == 1}
+		if !checks[i].Passed {
+			checks[i].Note = fmt.Sprintf("bit %d of selftest() answer 0x%x is clear", i, passed)
+		}
+	}
+	return checks, nil
+}
@@ -0,0 +1,50 @@
+package precompile
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestWrapperSelfTest(t *testing.T) {
+	// Every check but the two-block message passed
+	bitmap := big.NewInt(0b110111)
+	client, s := setup(t, func(c mockrpc.Call) (any, error) {
+		return hexutil.Bytes(math.U256Bytes(bitmap)), nil
+	})
+	selector := crypto.Keccak256([]byte("selftest()"))[:4]
+	s.Result("eth_getCode", hexutil.Bytes(append(append([]byte{byte(vm.PUSH4)}, selector...), byte(vm.EQ))))
+
+	ctx := context.Background()
+	wrapper := common.HexToAddress("0x1234")
+	checks, err := WrapperSelfTest(ctx, client, wrapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != len(SelfTestChecks) {
+		t.Fatalf("%d checks, want %d", len(checks), len(SelfTestChecks))
+	}
+	for i, c := range checks {
+		if c.Passed != (i != 3) {
+			t.Errorf("%s: passed %t", c.Name, c.Passed)
+		}
+	}
+
+	// Wrappers compiled before selftest() was added
+	s.Result("eth_getCode", hexutil.Bytes{byte(vm.PUSH4), 0xe7, 0xf1, 0xe7, 0xc5, byte(vm.EQ)})
+	if _, err := WrapperSelfTest(ctx, client, wrapper); !errors.Is(err, ErrNoSelfTest) {
+		t.Errorf("old wrapper: %v", err)
+	}
+	if s.Calls("eth_call") != 1 {
+		t.Errorf("%d calls, want only the one to the wrapper with selftest()", s.Calls("eth_call"))
+	}
+}
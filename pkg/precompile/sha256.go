@@ -0,0 +1,91 @@
+// Package precompile calls the precompiles the harness exercises — SHA-256
+// at 0x02, directly and through the Sha256Wrapper contract, ecrecover at
+// 0x01, modexp at 0x05 and the bn256 pairing check at 0x08 — and compares
+// their answers with locally computed ones. The stage scripts share it so the call logic can
+// be unit-tested against a mock node.
+package precompile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+)
+
+// SHA256Address is the address of the SHA-256 precompile.
+var SHA256Address = common.HexToAddress("0x02")
+
+// Outcome is one digest comparison.
+type Outcome struct {
+	Expected [32]byte
+	// Returned is what the node answered; a correct answer is 32 bytes.
+	Returned []byte
+}
+
+// Match reports whether the node returned the expected digest.
+func (o Outcome) Match() bool {
+	return bytes.Equal(o.Expected[:], o.Returned)
+}
+
+// CallSHA256 calls the precompile directly with input.
+func CallSHA256(ctx context.Context, client *ethclient.Client, input []byte) (Outcome, error) {
+	out := Outcome{Expected: sha256.Sum256(input)}
+	to := SHA256Address
+	returned, err := client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: input}, nil)
+	if err != nil {
+		return out, err
+	}
+	out.Returned = returned
+	return out, nil
+}
+
+// CallWrapper calls sha256Hash(input) on the wrapper contract at address.
+func CallWrapper(ctx context.Context, client *ethclient.Client, parsedABI *abi.ABI, address common.Address, input []byte) (Outcome, error) {
+	return CallWrapperFrom(ctx, client, parsedABI, address, common.Address{}, input)
+}
+
+// CallWrapperFrom is CallWrapper with the call made from from. The digest
+// must not depend on it.
+func CallWrapperFrom(ctx context.Context, client *ethclient.Client, parsedABI *abi.ABI, address, from common.Address, input []byte) (Outcome, error) {
+	out := Outcome{Expected: sha256.Sum256(input)}
+
+	callData, err := parsedABI.Pack("sha256Hash", input)
+	if err != nil {
+		return out, fmt.Errorf("failed to pack ABI call: %w", err)
+	}
+	result, err := client.CallContract(ctx, ethereum.CallMsg{From: from, To: &address, Data: callData}, nil)
+	if err != nil {
+		return out, fmt.Errorf("contract call failed: %w", err)
+	}
+
+	unpacked, err := parsedABI.Unpack("sha256Hash", result)
+	if err != nil {
+		return out, fmt.Errorf("failed to unpack result: %w", err)
+	}
+	hash, ok := unpacked[0].([32]byte)
+	if !ok {
+		return out, fmt.Errorf("unexpected return type: %T", unpacked[0])
+	}
+	out.Returned = hash[:]
+	return out, nil
+}
+
+// CodeSize returns the size of the code at address, failing with a
+// *chain.NoCodeError if there is none.
+func CodeSize(ctx context.Context, client *ethclient.Client, address common.Address) (int, error) {
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get contract code: %w", err)
+	}
+	if len(code) == 0 {
+		return 0, &chain.NoCodeError{Address: address}
+	}
+	return len(code), nil
+}
@@ -0,0 +1,182 @@
+package precompile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+// callArgs is the eth_call transaction object; go-ethereum sends the data
+// as "input", older clients as "data".
+type callArgs struct {
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Input hexutil.Bytes  `json:"input"`
+	Data  hexutil.Bytes  `json:"data"`
+}
+
+func (a callArgs) payload() []byte {
+	if a.Input != nil {
+		return a.Input
+	}
+	return a.Data
+}
+
+func setup(t *testing.T, h mockrpc.Handler) (*ethclient.Client, *mockrpc.Server) {
+	t.Helper()
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	s.Handle("eth_call", h)
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client, s
+}
+
+func loadABI(t *testing.T) *abi.ABI {
+	t.Helper()
+	data, err := os.ReadFile("../../artifacts/Sha256Wrapper.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &parsed
+}
+
+func TestCallSHA256(t *testing.T) {
+	client, _ := setup(t, func(c mockrpc.Call) (any, error) {
+		var args callArgs
+		if err := c.Param(0, &args); err != nil {
+			return nil, err
+		}
+		if args.To != SHA256Address {
+			t.Errorf("called %s, want the precompile", args.To.Hex())
+		}
+		sum := sha256.Sum256(args.payload())
+		return hexutil.Bytes(sum[:]), nil
+	})
+
+	for _, input := range [][]byte{[]byte("hello world"), {}, {0x00, 0xff}} {
+		out, err := CallSHA256(context.Background(), client, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !out.Match() {
+			t.Errorf("%x: returned %x, expected %x", input, out.Returned, out.Expected)
+		}
+	}
+}
+
+func TestCallSHA256Mismatch(t *testing.T) {
+	// A node answering with the digest of different data, or a truncated one
+	wrong := sha256.Sum256([]byte("something else"))
+	for name, answer := range map[string]hexutil.Bytes{
+		"wrong digest": wrong[:],
+		"truncated":    wrong[:31],
+		"empty":        {},
+	} {
+		client, _ := setup(t, mockrpc.Static(answer))
+		out, err := CallSHA256(context.Background(), client, []byte("hello world"))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if out.Match() {
+			t.Errorf("%s: reported a match", name)
+		}
+	}
+}
+
+func TestCallWrapper(t *testing.T) {
+	parsed := loadABI(t)
+	wrapper := common.HexToAddress("0x1234")
+	client, _ := setup(t, func(c mockrpc.Call) (any, error) {
+		var args callArgs
+		if err := c.Param(0, &args); err != nil {
+			return nil, err
+		}
+		values, err := parsed.Methods["sha256Hash"].Inputs.Unpack(args.payload()[4:])
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(values[0].([]byte))
+		out, err := parsed.Methods["sha256Hash"].Outputs.Pack(sum)
+		return hexutil.Bytes(out), err
+	})
+
+	out, err := CallWrapper(context.Background(), client, parsed, wrapper, []byte("cdk-erigon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Match() {
+		t.Errorf("returned %x, expected %x", out.Returned, out.Expected)
+	}
+}
+
+func TestCallWrapperFrom(t *testing.T) {
+	parsed := loadABI(t)
+	from := common.HexToAddress("0x02")
+	client, _ := setup(t, func(c mockrpc.Call) (any, error) {
+		var args callArgs
+		if err := c.Param(0, &args); err != nil {
+			return nil, err
+		}
+		values, err := parsed.Methods["sha256Hash"].Inputs.Unpack(args.payload()[4:])
+		if err != nil {
+			return nil, err
+		}
+		// A node whose answer depends on the caller
+		sum := sha256.Sum256(append(values[0].([]byte), args.From.Bytes()...))
+		out, err := parsed.Methods["sha256Hash"].Outputs.Pack(sum)
+		return hexutil.Bytes(out), err
+	})
+
+	out, err := CallWrapperFrom(context.Background(), client, parsed, common.HexToAddress("0x1234"), from, []byte("cdk-erigon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := sha256.Sum256(append([]byte("cdk-erigon"), from.Bytes()...)); out.Match() || !bytes.Equal(out.Returned, want[:]) {
+		t.Errorf("returned %x, the call was not made from %s", out.Returned, from.Hex())
+	}
+}
+
+func TestCallWrapperRevert(t *testing.T) {
+	client, _ := setup(t, mockrpc.Fail(&mockrpc.Error{Code: 3, Message: "execution reverted"}))
+	if _, err := CallWrapper(context.Background(), client, loadABI(t), common.Address{1}, nil); err == nil {
+		t.Fatal("expected the revert to be reported")
+	}
+}
+
+func TestCodeSize(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_getCode", mockrpc.Sequence(mockrpc.Static("0x"), mockrpc.Static("0x600160005260206000f3")))
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := CodeSize(context.Background(), client, common.Address{1}); !errors.Is(err, chain.ErrNoCodeAtAddress) {
+		t.Errorf("got %v, want ErrNoCodeAtAddress", err)
+	}
+	if n, err := CodeSize(context.Background(), client, common.Address{1}); err != nil || n != 10 {
+		t.Errorf("got %d, %v; want 10 bytes", n, err)
+	}
+}
@@ -0,0 +1,226 @@
+package precompile
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// WrapperMethod is the name the wrapper's hashing function has in the
+// current artifacts, and the name every detected version is driven by.
+const WrapperMethod = "sha256Hash"
+
+// WrapperABIDir holds the ABIs of wrappers deployed from older artifacts,
+// one <version>.abi file each, kept there when the artifacts are upgraded.
+const WrapperABIDir = "wrapper_abis"
+
+// commonWrapperABI declares the other names SHA-256 wrappers are commonly
+// deployed with, tried after the artifacts.
+const commonWrapperABI = `[
+{"type":"function","name":"sha256","stateMutability":"view","inputs":[{"name":"input","type":"bytes"}],"outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"hash","stateMutability":"view","inputs":[{"name":"input","type":"bytes"}],"outputs":[{"name":"","type":"bytes32"}]}
+]`
+
+// WrapperVersion is one interface a deployed wrapper may have: a function
+// taking the input as bytes and returning its digest as bytes32.
+type WrapperVersion struct {
+	// Name is "current" for the artifacts' ABI, the file name for those in
+	// WrapperABIDir and the signature for the common ones.
+	Name   string
+	Method abi.Method
+}
+
+// ABI returns an ABI holding the version's function under WrapperMethod,
+// so code written against the current artifacts drives any version: Pack
+// encodes calls with the version's selector and Unpack decodes its digest.
+func (v WrapperVersion) ABI() *abi.ABI {
+	return &abi.ABI{Methods: map[string]abi.Method{WrapperMethod: v.Method}}
+}
+
+// Current reports whether v is the interface of the current artifacts.
+func (v WrapperVersion) Current() bool { return v.Name == "current" }
+
+// String names the version and its signature, e.g. "v0: digest(bytes)".
+func (v WrapperVersion) String() string {
+	if v.Name == v.Method.Sig {
+		return v.Name
+	}
+	return v.Name + ": " + v.Method.Sig
+}
+
+// ErrUnknownWrapper is matched by UnknownWrapperError.
+var ErrUnknownWrapper = errors.New("deployed wrapper matches no known ABI version")
+
+// UnknownWrapperError reports a wrapper that neither dispatches on the
+// selector of any known version nor answers one with the right digest.
+type UnknownWrapperError struct {
+	Address common.Address
+	// Tried are the signatures probed, e.g. "sha256Hash(bytes)".
+	Tried []string
+}
+
+func (e *UnknownWrapperError) Error() string {
+	return fmt.Sprintf("wrapper at %s matches no known ABI version (tried %s)", e.Address.Hex(), strings.Join(e.Tried, ", "))
+}
+
+func (e *UnknownWrapperError) Is(target error) bool { return target == ErrUnknownWrapper }
+
+// WrapperVersions returns the known versions in the order they are tried:
+// the current artifact, the older ones in WrapperABIDir by name, then the
+// common ones. Versions with a selector already listed are left out.
+func WrapperVersions() ([]WrapperVersion, error) {
+	var versions []WrapperVersion
+	seen := map[string]bool{}
+	add := func(name string, parsed abi.ABI, method string) error {
+		m, ok := parsed.Methods[method]
+		if !ok {
+			return fmt.Errorf("%s: no %s function", name, method)
+		}
+		if len(m.Inputs) != 1 || m.Inputs[0].Type.T != abi.BytesTy || len(m.Outputs) != 1 || m.Outputs[0].Type.String() != "bytes32" {
+			return fmt.Errorf("%s: %s must take bytes and return bytes32", name, m.Sig)
+		}
+		if !seen[string(m.ID)] {
+			seen[string(m.ID)] = true
+			versions = append(versions, WrapperVersion{Name: name, Method: m})
+		}
+		return nil
+	}
+
+	current, err := readABI(paths.Artifact("Sha256Wrapper.abi"))
+	if err != nil {
+		return nil, err
+	}
+	if err := add("current", current, WrapperMethod); err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(paths.ArtifactsDir(), WrapperABIDir, "*.abi"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		parsed, err := readABI(f)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(f), ".abi")
+		if len(parsed.Methods) != 1 {
+			return nil, fmt.Errorf("%s: want exactly one function, found %d", f, len(parsed.Methods))
+		}
+		for method := range parsed.Methods {
+			if err := add(name, parsed, method); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	known, err := abi.JSON(strings.NewReader(commonWrapperABI))
+	if err != nil {
+		return nil, err
+	}
+	for _, method := range []string{"sha256", "hash"} {
+		if err := add(known.Methods[method].Sig, known, method); err != nil {
+			return nil, err
+		}
+	}
+	return versions, nil
+}
+
+func readABI(path string) (abi.ABI, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("failed to read ABI: %w", err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(data)))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return parsed, nil
+}
+
+// probeInput is hashed by the wrapper when detection falls back to calls.
+var probeInput = []byte("abc")
+
+// DetectWrapper finds which of versions the wrapper at address implements.
+// The first version whose selector the code's dispatch table holds wins;
+// code without a readable table, such as a proxy's, is probed instead by
+// calling each version and keeping the first to return the right digest.
+// It fails with a *chain.NoCodeError without code and an
+// *UnknownWrapperError when nothing matches.
+func DetectWrapper(ctx context.Context, client *ethclient.Client, address common.Address, versions []WrapperVersion) (WrapperVersion, error) {
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return WrapperVersion{}, fmt.Errorf("failed to get contract code: %w", err)
+	}
+	if len(code) == 0 {
+		return WrapperVersion{}, &chain.NoCodeError{Address: address}
+	}
+	selectors := deploy.Selectors(code)
+	for _, v := range versions {
+		if selectors[[4]byte(v.Method.ID)] {
+			return v, nil
+		}
+	}
+
+	want := sha256.Sum256(probeInput)
+	tried := make([]string, len(versions))
+	for i, v := range versions {
+		tried[i] = v.Method.Sig
+		data, err := v.ABI().Pack(WrapperMethod, probeInput)
+		if err != nil {
+			return WrapperVersion{}, err
+		}
+		out, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: data}, nil)
+		if err != nil {
+			continue
+		}
+		if unpacked, err := v.ABI().Unpack(WrapperMethod, out); err == nil && unpacked[0] == want {
+			return v, nil
+		}
+	}
+	return WrapperVersion{}, &UnknownWrapperError{Address: address, Tried: tried}
+}
+
+// ResolveWrapper loads the known versions and detects the one of the
+// wrapper at address.
+func ResolveWrapper(ctx context.Context, client *ethclient.Client, address common.Address) (WrapperVersion, error) {
+	versions, err := WrapperVersions()
+	if err != nil {
+		return WrapperVersion{}, err
+	}
+	return DetectWrapper(ctx, client, address, versions)
+}
+
+// LoadWrapper reads the address stage 2 recorded in the work directory
+// and detects the ABI version of the wrapper there, warning when it
+// predates the current artifacts.
+func LoadWrapper(ctx context.Context, client *ethclient.Client) (common.Address, WrapperVersion, error) {
+	address, err := paths.ReadAddress(paths.Work("deployed_address.txt"))
+	if err != nil {
+		return common.Address{}, WrapperVersion{}, fmt.Errorf("failed to read deployed address: %w", err)
+	}
+	version, err := ResolveWrapper(ctx, client, address)
+	if err != nil {
+		return common.Address{}, WrapperVersion{}, err
+	}
+	if !version.Current() {
+		output.Logf(output.ModuleReport, output.Quiet, "⚠️  Wrapper at %s predates the current artifacts, using ABI %s", address.Hex(), version)
+	}
+	return address, version, nil
+}
@@ -0,0 +1,141 @@
+package precompile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+const digestABI = `[{"type":"function","name":"digest","stateMutability":"view",
+"inputs":[{"name":"data","type":"bytes"}],"outputs":[{"name":"","type":"bytes32"}]}]`
+
+// artifactsWith points ARTIFACTS_DIR at a copy of the artifacts plus the
+// given older wrapper ABIs.
+func artifactsWith(t *testing.T, older map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	current, err := os.ReadFile("../../artifacts/Sha256Wrapper.abi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Sha256Wrapper.abi"), current, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, WrapperABIDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range older {
+		if err := os.WriteFile(filepath.Join(dir, WrapperABIDir, name+".abi"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("ARTIFACTS_DIR", dir)
+}
+
+func TestWrapperVersions(t *testing.T) {
+	artifactsWith(t, map[string]string{"v0": digestABI})
+	versions, err := WrapperVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Name+"="+v.Method.Sig)
+	}
+	want := "current=sha256Hash(bytes) v0=digest(bytes) sha256(bytes)=sha256(bytes) hash(bytes)=hash(bytes)"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("versions %s, want %s", s, want)
+	}
+	if !versions[0].Current() || versions[1].Current() {
+		t.Error("only the artifact's version is current")
+	}
+
+	// An older version is driven under the current name
+	data, err := versions[1].ABI().Pack(WrapperMethod, []byte("abc"))
+	if err != nil || !bytes.Equal(data[:4], versions[1].Method.ID) {
+		t.Errorf("packed %x, %v", data, err)
+	}
+
+	artifactsWith(t, map[string]string{"bad": `[{"type":"function","name":"digest","inputs":[{"name":"d","type":"bytes"}],"outputs":[{"name":"","type":"bytes"}]}]`})
+	if _, err := WrapperVersions(); err == nil {
+		t.Error("accepted a version returning bytes")
+	}
+}
+
+// detect runs DetectWrapper against a wrapper whose code is built by code
+// and which answers the probe only through the version answering picks,
+// if any.
+func detect(t *testing.T, code func([]WrapperVersion) []byte, answering func([]WrapperVersion) *WrapperVersion) (WrapperVersion, *mockrpc.Server, error) {
+	t.Helper()
+	artifactsWith(t, map[string]string{"v0": digestABI})
+	versions, err := WrapperVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	answers := answering(versions)
+	client, s := setup(t, func(c mockrpc.Call) (any, error) {
+		var args callArgs
+		if err := c.Param(0, &args); err != nil {
+			return nil, err
+		}
+		if answers == nil || !bytes.Equal(args.payload()[:4], answers.Method.ID) {
+			return nil, &mockrpc.Error{Code: 3, Message: "execution reverted"}
+		}
+		sum := sha256.Sum256(probeInput)
+		return hexutil.Bytes(sum[:]), nil
+	})
+	s.Result("eth_getCode", hexutil.Bytes(code(versions)))
+	v, err := DetectWrapper(context.Background(), client, common.HexToAddress("0x1234"), versions)
+	return v, s, err
+}
+
+func none([]WrapperVersion) *WrapperVersion { return nil }
+
+func TestDetectWrapperBySelector(t *testing.T) {
+	// PUSH4 <digest(bytes)> EQ, as in a solc dispatch table
+	dispatch := func(vs []WrapperVersion) []byte {
+		return append(append([]byte{byte(vm.PUSH4)}, vs[1].Method.ID...), byte(vm.EQ))
+	}
+	v, s, err := detect(t, dispatch, none)
+	if err != nil || v.Name != "v0" {
+		t.Fatalf("detected %q, %v", v.Name, err)
+	}
+	if s.Calls("eth_call") != 0 {
+		t.Error("probed despite a selector match")
+	}
+}
+
+func TestDetectWrapperByProbe(t *testing.T) {
+	// A proxy dispatches nothing itself
+	proxy := func([]WrapperVersion) []byte { return []byte{byte(vm.CALLDATASIZE), byte(vm.DELEGATECALL)} }
+	v, s, err := detect(t, proxy, func(vs []WrapperVersion) *WrapperVersion { return &vs[2] })
+	if err != nil || v.Method.Sig != "sha256(bytes)" {
+		t.Fatalf("detected %q, %v", v.Name, err)
+	}
+	if n := s.Calls("eth_call"); n != 3 {
+		t.Errorf("%d probes, want 3", n)
+	}
+
+	_, _, err = detect(t, proxy, none)
+	var unknown *UnknownWrapperError
+	if !errors.Is(err, ErrUnknownWrapper) || !errors.As(err, &unknown) || len(unknown.Tried) != 4 {
+		t.Errorf("got %v", err)
+	}
+
+	_, _, err = detect(t, func([]WrapperVersion) []byte { return nil }, none)
+	if !errors.Is(err, chain.ErrNoCodeAtAddress) {
+		t.Errorf("no code: got %v", err)
+	}
+}
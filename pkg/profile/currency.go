@@ -0,0 +1,65 @@
+package profile
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Currency is a chain's native currency. CDK chains with a custom gas token
+// name it and may use fewer decimals than ETH.
+type Currency struct {
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// ETH is the native currency unless a profile says otherwise.
+var ETH = Currency{Symbol: "ETH", Decimals: 18}
+
+// MaxDecimals bounds Currency.Decimals; anything larger is a typo.
+const MaxDecimals = 36
+
+// Validate checks the currency can be formatted and parsed.
+func (c Currency) Validate() error {
+	if strings.TrimSpace(c.Symbol) == "" || strings.ContainsAny(c.Symbol, " \t0123456789.") {
+		return fmt.Errorf("invalid native currency symbol %q", c.Symbol)
+	}
+	if c.Decimals < 0 || c.Decimals > MaxDecimals {
+		return fmt.Errorf("native currency %s has %d decimals, want 0 to %d", c.Symbol, c.Decimals, MaxDecimals)
+	}
+	return nil
+}
+
+func (c Currency) unit() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.Decimals)), nil)
+}
+
+// Format renders an amount of base units (wei for ETH) in whole units with
+// up to six decimals, e.g. "0.100000 ETH".
+func (c Currency) Format(amount *big.Int) string {
+	places := min(c.Decimals, 6)
+	return new(big.Rat).SetFrac(amount, c.unit()).FloatString(places) + " " + c.Symbol
+}
+
+// Parse parses an amount suffixed with the currency symbol in any case,
+// e.g. "0.05eth" or "2.5 USDC", or else a whole number of base units,
+// optionally suffixed with "wei".
+func (c Currency) Parse(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	unit := big.NewInt(1)
+	lower := strings.ToLower(s)
+	if symbol := strings.ToLower(c.Symbol); symbol != "" && strings.HasSuffix(lower, symbol) {
+		s, unit = strings.TrimSpace(s[:len(s)-len(symbol)]), c.unit()
+	} else if strings.HasSuffix(lower, "wei") {
+		s = strings.TrimSpace(s[:len(s)-len("wei")])
+	}
+	amount, ok := new(big.Rat).SetString(s)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q (want base units, or a decimal suffixed with %s)", s, c.Symbol)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(unit))
+	if !amount.IsInt() {
+		return nil, fmt.Errorf("amount %q is finer than the %d decimals of %s", s, c.Decimals, c.Symbol)
+	}
+	return amount.Num(), nil
+}
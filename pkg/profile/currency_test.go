@@ -0,0 +1,67 @@
+package profile
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCurrency(t *testing.T) {
+	usdc := Currency{Symbol: "USDC", Decimals: 6}
+	for _, tc := range []struct {
+		c    Currency
+		in   string
+		want int64
+		text string
+	}{
+		{ETH, "0.05eth", 5e16, "0.050000 ETH"},
+		{ETH, "1 ETH", 1e18, "1.000000 ETH"},
+		{ETH, "1000wei", 1000, "0.000000 ETH"},
+		{usdc, "2.5usdc", 2_500_000, "2.500000 USDC"},
+		{usdc, "7", 7, "0.000007 USDC"},
+		{Currency{Symbol: "PTS", Decimals: 2}, "1.25 pts", 125, "1.25 PTS"},
+	} {
+		got, err := tc.c.Parse(tc.in)
+		if err != nil || got.Cmp(big.NewInt(tc.want)) != 0 {
+			t.Errorf("%s.Parse(%q) = %v, %v, want %d", tc.c.Symbol, tc.in, got, err, tc.want)
+			continue
+		}
+		if text := tc.c.Format(got); text != tc.text {
+			t.Errorf("%s.Format(%d) = %q, want %q", tc.c.Symbol, tc.want, text, tc.text)
+		}
+	}
+
+	// ETH amounts aren't silently reinterpreted on a custom token chain
+	for _, in := range []string{"0.1eth", "0.0000001usdc", "-1", "lots"} {
+		if _, err := usdc.Parse(in); err == nil {
+			t.Errorf("USDC.Parse(%q) succeeded", in)
+		}
+	}
+}
+
+func TestLoadValidatesCurrency(t *testing.T) {
+	Dir = t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(Dir, name+".json"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("token", `{"chainId": 7, "nativeCurrency": {"symbol": "TOK", "decimals": 6}}`)
+	write("broken", `{"chainId": 7, "nativeCurrency": {"symbol": "TOK", "decimals": 77}}`)
+
+	p, err := Load("token")
+	if err != nil || p.NativeCurrency != (Currency{"TOK", 6}) {
+		t.Errorf("token profile: %+v, %v", p, err)
+	}
+	if err := p.CheckChain(8); err == nil {
+		t.Error("profile for chain 7 accepted on chain 8")
+	}
+	if _, err := Load("broken"); err == nil {
+		t.Error("loaded a currency with 77 decimals")
+	}
+	if p, _ := Load("cdk-erigon"); p.NativeCurrency != ETH {
+		t.Errorf("built-in profile currency %+v, want ETH", p.NativeCurrency)
+	}
+}
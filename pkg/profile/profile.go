@@ -0,0 +1,122 @@
+// Package profile describes the chains the harness runs against. A profile
+// captures the properties that change how results must be interpreted, such
+// as which state trie the chain commits to and the currency balances and
+// costs are reported in.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// TrieMPT is the keccak Merkle-Patricia trie used by Ethereum L1 and
+	// standard EVM nodes.
+	TrieMPT = "mpt"
+	// TrieSMT is the Poseidon sparse Merkle tree used by Polygon zkEVM and
+	// CDK chains running cdk-erigon.
+	TrieSMT = "smt"
+)
+
+// Profile is the set of chain properties the stages depend on.
+type Profile struct {
+	Name      string `json:"name"`
+	ChainID   uint64 `json:"chainId"`
+	StateTrie string `json:"stateTrie"`
+	// ProofMethod is the RPC method returning state proofs for StateTrie.
+	ProofMethod string `json:"proofMethod"`
+	// NativeCurrency is what gas is paid in, ETH unless the chain uses a
+	// custom gas token.
+	NativeCurrency Currency `json:"nativeCurrency"`
+	// Forks maps fork names (byzantium, istanbul, ...) to their activation
+	// blocks, for the fork activation probe to check.
+	Forks map[string]uint64 `json:"forks,omitempty"`
+}
+
+var builtin = []Profile{
+	{Name: "ethereum", ChainID: 1, StateTrie: TrieMPT, ProofMethod: "eth_getProof", Forks: map[string]uint64{
+		"byzantium": 4370000, "istanbul": 9069000, "berlin": 12244000, "cancun": 19426587, "prague": 22431084,
+	}},
+	{Name: "sepolia", ChainID: 11155111, StateTrie: TrieMPT, ProofMethod: "eth_getProof"},
+	{Name: "anvil", ChainID: 31337, StateTrie: TrieMPT, ProofMethod: "eth_getProof"},
+	{Name: "cdk-erigon", ChainID: 10101, StateTrie: TrieSMT, ProofMethod: "zkevm_getProof"},
+	{Name: "cardona", ChainID: 2442, StateTrie: TrieSMT, ProofMethod: "zkevm_getProof"},
+	{Name: "zkevm-mainnet", ChainID: 1101, StateTrie: TrieSMT, ProofMethod: "zkevm_getProof"},
+}
+
+// Dir is where custom profiles are looked up as <name>.json.
+var Dir = "profiles"
+
+// Load returns the named profile, preferring a JSON file in Dir over the
+// built-in definitions so operators can override them.
+func Load(name string) (Profile, error) {
+	path := filepath.Join(Dir, name+".json")
+	if data, err := os.ReadFile(path); err == nil {
+		var p Profile
+		if err := json.Unmarshal(data, &p); err != nil {
+			return Profile{}, fmt.Errorf("failed to parse profile %s: %w", path, err)
+		}
+		if p.Name == "" {
+			p.Name = name
+		}
+		p = p.withDefaults()
+		if err := p.NativeCurrency.Validate(); err != nil {
+			return Profile{}, fmt.Errorf("profile %s: %w", path, err)
+		}
+		return p, nil
+	}
+
+	for _, p := range builtin {
+		if p.Name == name {
+			return p.withDefaults(), nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown chain profile %q", name)
+}
+
+// Detect picks the built-in profile matching chainID, falling back to a
+// generic MPT profile for unknown chains.
+func Detect(chainID uint64) Profile {
+	for _, p := range builtin {
+		if p.ChainID == chainID {
+			return p.withDefaults()
+		}
+	}
+	return Profile{Name: "generic", ChainID: chainID}.withDefaults()
+}
+
+// Resolve loads name when set, otherwise detects the profile from chainID.
+func Resolve(name string, chainID uint64) (Profile, error) {
+	if name != "" {
+		return Load(name)
+	}
+	return Detect(chainID), nil
+}
+
+// CheckChain fails when the profile describes another chain than the one
+// with chainID, whose balances it would misreport. Profiles without a chain
+// ID fit any chain.
+func (p Profile) CheckChain(chainID uint64) error {
+	if p.ChainID != 0 && p.ChainID != chainID {
+		return fmt.Errorf("chain profile %s is for chain %d, but the node is on chain %d", p.Name, p.ChainID, chainID)
+	}
+	return nil
+}
+
+func (p Profile) withDefaults() Profile {
+	if p.NativeCurrency == (Currency{}) {
+		p.NativeCurrency = ETH
+	}
+	if p.StateTrie == "" {
+		p.StateTrie = TrieMPT
+	}
+	if p.ProofMethod == "" {
+		p.ProofMethod = "eth_getProof"
+		if p.StateTrie == TrieSMT {
+			p.ProofMethod = "zkevm_getProof"
+		}
+	}
+	return p
+}
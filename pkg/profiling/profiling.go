@@ -0,0 +1,109 @@
+// Package profiling exposes pprof endpoints and runtime statistics for the
+// harness itself, so long runs can show whether time is spent in the node or
+// in client-side code.
+package profiling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// Server serves the standard pprof handlers on a dedicated listener.
+type Server struct {
+	Addr string
+	srv  *http.Server
+}
+
+// Start listens on addr (e.g. "127.0.0.1:6060") and serves /debug/pprof/ in
+// the background. The handlers are mounted on a private mux rather than
+// http.DefaultServeMux so nothing else leaks onto the port.
+func Start(addr string) (*Server, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for pprof on %s: %w", addr, err)
+	}
+
+	s := &Server{Addr: ln.Addr().String(), srv: &http.Server{Handler: mux}}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("pprof server stopped: %v\n", err)
+		}
+	}()
+	return s, nil
+}
+
+// Shutdown stops the pprof server, waiting for in-flight requests until ctx
+// is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// Close shuts the pprof server down, giving in-flight requests two seconds.
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Stats is a snapshot of the harness's own resource usage.
+type Stats struct {
+	Timestamp    string `json:"timestamp"`
+	Goroutines   int    `json:"goroutines"`
+	HeapAlloc    uint64 `json:"heapAllocBytes"`
+	HeapInuse    uint64 `json:"heapInuseBytes"`
+	Sys          uint64 `json:"sysBytes"`
+	TotalAlloc   uint64 `json:"totalAllocBytes"`
+	NumGC        uint32 `json:"numGC"`
+	PauseTotalNs uint64 `json:"gcPauseTotalNs"`
+}
+
+// Snapshot reads the current runtime statistics.
+func Snapshot() Stats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return Stats{
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    m.HeapAlloc,
+		HeapInuse:    m.HeapInuse,
+		Sys:          m.Sys,
+		TotalAlloc:   m.TotalAlloc,
+		NumGC:        m.NumGC,
+		PauseTotalNs: m.PauseTotalNs,
+	}
+}
+
+// Monitor calls report with a fresh snapshot every interval until ctx is
+// cancelled.
+func Monitor(ctx context.Context, interval time.Duration, report func(Stats)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report(Snapshot())
+		}
+	}
+}
+
+// String renders a one-line human summary of s.
+func (s Stats) String() string {
+	return fmt.Sprintf("goroutines=%d heap=%.1fMiB sys=%.1fMiB gc=%d",
+		s.Goroutines, float64(s.HeapAlloc)/(1<<20), float64(s.Sys)/(1<<20), s.NumGC)
+}
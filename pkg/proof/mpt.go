@@ -0,0 +1,194 @@
+package proof
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+
+	"cdk-erigon-precompile/pkg/profile"
+)
+
+// MPTVerifier checks eth_getProof responses against a keccak Merkle-Patricia
+// state trie: the account proof against the block's state root and each
+// storage proof against the proven storage root.
+type MPTVerifier struct {
+	// Method is the proof RPC, eth_getProof when empty.
+	Method string
+}
+
+// Trie implements Verifier.
+func (MPTVerifier) Trie() string { return profile.TrieMPT }
+
+// VerifyStorage implements Verifier.
+func (v MPTVerifier) VerifyStorage(ctx context.Context, client *ethclient.Client, address common.Address, slots []common.Hash, expected []common.Hash, blockNumber *big.Int) (*Result, error) {
+	header, err := client.HeaderByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch header: %w", err)
+	}
+
+	keys := make([]string, len(slots))
+	for i, slot := range slots {
+		keys[i] = slot.Hex()
+	}
+	var resp *gethclient.AccountResult
+	if v.Method == "" || v.Method == "eth_getProof" {
+		resp, err = gethclient.New(client.Client()).GetProof(ctx, address, keys, header.Number)
+	} else {
+		resp, err = getProofVia(ctx, client, v.Method, address, keys, header.Number)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", v.methodName(), err)
+	}
+
+	result := &Result{
+		Trie:        profile.TrieMPT,
+		Address:     address.Hex(),
+		BlockNumber: header.Number.Uint64(),
+		StateRoot:   header.Root.Hex(),
+		StorageRoot: resp.StorageHash.Hex(),
+	}
+
+	if err := verifyAccount(header.Root, address, resp); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.AccountProven = true
+	}
+
+	answered := make([]common.Hash, len(resp.StorageProof))
+	for i, sp := range resp.StorageProof {
+		answered[i] = common.HexToHash(sp.Key)
+	}
+	slotResults, err := pairSlots(slots, answered, expected)
+	if err != nil {
+		result.addError(err)
+	}
+	for i := range slotResults {
+		slot := &slotResults[i]
+		if slot.Error != "" {
+			continue
+		}
+		sp := resp.StorageProof[i]
+		slot.Value = common.BigToHash(sp.Value).Hex()
+		if err := verifySlot(resp.StorageHash, sp); err != nil {
+			slot.Error = err.Error()
+		} else if slot.Expected != "" && slot.Expected != slot.Value {
+			slot.Error = "proven value differs from expected"
+		} else {
+			slot.Verified = true
+		}
+	}
+	result.Slots = slotResults
+	return result, nil
+}
+
+func verifyAccount(stateRoot common.Hash, address common.Address, resp *gethclient.AccountResult) error {
+	value, err := trie.VerifyProof(stateRoot, crypto.Keccak256(address.Bytes()), proofDB(resp.AccountProof))
+	if err != nil {
+		return fmt.Errorf("account proof invalid: %w", err)
+	}
+
+	balance, overflow := uint256.FromBig(resp.Balance)
+	if overflow {
+		return fmt.Errorf("account balance overflows 256 bits")
+	}
+	want, err := rlp.EncodeToBytes(&types.StateAccount{
+		Nonce:    resp.Nonce,
+		Balance:  balance,
+		Root:     resp.StorageHash,
+		CodeHash: resp.CodeHash.Bytes(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode account: %w", err)
+	}
+	if !bytes.Equal(value, want) {
+		return fmt.Errorf("account proof leaf does not match returned nonce/balance/storageHash/codeHash")
+	}
+	return nil
+}
+
+func verifySlot(storageRoot common.Hash, sp gethclient.StorageResult) error {
+	key := common.HexToHash(sp.Key)
+	value, err := trie.VerifyProof(storageRoot, crypto.Keccak256(key.Bytes()), proofDB(sp.Proof))
+	if err != nil {
+		return fmt.Errorf("storage proof invalid: %w", err)
+	}
+
+	// Zero values are absent from the trie; a valid exclusion proof yields nil.
+	if sp.Value.Sign() == 0 {
+		if len(value) != 0 {
+			return fmt.Errorf("proof shows a value for a slot reported as zero")
+		}
+		return nil
+	}
+	want, err := rlp.EncodeToBytes(sp.Value.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to encode slot value: %w", err)
+	}
+	if !bytes.Equal(value, want) {
+		return fmt.Errorf("proof leaf %x does not match returned value %s", value, hexutil.EncodeBig(sp.Value))
+	}
+	return nil
+}
+
+func (v MPTVerifier) methodName() string {
+	if v.Method == "" {
+		return "eth_getProof"
+	}
+	return v.Method
+}
+
+// getProofVia calls an eth_getProof-compatible method exposed under another
+// name by some nodes.
+func getProofVia(ctx context.Context, client *ethclient.Client, method string, address common.Address, keys []string, blockNumber *big.Int) (*gethclient.AccountResult, error) {
+	var raw struct {
+		AccountProof []string       `json:"accountProof"`
+		Balance      hexutil.Big    `json:"balance"`
+		CodeHash     common.Hash    `json:"codeHash"`
+		Nonce        hexutil.Uint64 `json:"nonce"`
+		StorageHash  common.Hash    `json:"storageHash"`
+		StorageProof []struct {
+			Key   string      `json:"key"`
+			Value hexutil.Big `json:"value"`
+			Proof []string    `json:"proof"`
+		} `json:"storageProof"`
+	}
+	if err := client.Client().CallContext(ctx, &raw, method, address, keys, hexutil.EncodeBig(blockNumber)); err != nil {
+		return nil, err
+	}
+
+	res := &gethclient.AccountResult{
+		Address:      address,
+		AccountProof: raw.AccountProof,
+		Balance:      raw.Balance.ToInt(),
+		CodeHash:     raw.CodeHash,
+		Nonce:        uint64(raw.Nonce),
+		StorageHash:  raw.StorageHash,
+	}
+	for _, sp := range raw.StorageProof {
+		res.StorageProof = append(res.StorageProof, gethclient.StorageResult{Key: sp.Key, Value: sp.Value.ToInt(), Proof: sp.Proof})
+	}
+	return res, nil
+}
+
+// proofDB loads proof nodes into a key-value store keyed by node hash, the
+// shape trie.VerifyProof expects.
+func proofDB(nodes []string) *memorydb.Database {
+	db := memorydb.New()
+	for _, n := range nodes {
+		blob := common.FromHex(n)
+		db.Put(crypto.Keccak256(blob), blob)
+	}
+	return db
+}
@@ -0,0 +1,187 @@
+package proof
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+// fixture is a proof response saved in testdata, with the block whose
+// state root it proves against and the request it answers.
+type fixture struct {
+	Block struct {
+		Number    hexutil.Big `json:"number"`
+		StateRoot common.Hash `json:"stateRoot"`
+	} `json:"block"`
+	Address common.Address  `json:"address"`
+	Slots   []common.Hash   `json:"slots"`
+	Result  json.RawMessage `json:"result"`
+}
+
+func loadFixture(t *testing.T, name string, result any) fixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(f.Result, result); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// serveProof answers the block's header, and method with result.
+func serveProof(t *testing.T, f fixture, method string, result any) *ethclient.Client {
+	t.Helper()
+	s := mockrpc.New()
+	t.Cleanup(s.Close)
+	s.Result("eth_getBlockByNumber", &types.Header{Number: f.Block.Number.ToInt(), Root: f.Block.StateRoot, Difficulty: new(big.Int)})
+	s.Result(method, result)
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// proofCase tampers with a known-good response and names what must fail.
+type proofCase[R any] struct {
+	name   string
+	tamper func(*R)
+	// expected changes the slots' values the verifier is given;
+	// unexpected gives it none, leaving the proofs alone to judge
+	expected   func([]common.Hash)
+	unexpected bool
+	// accountFails is set when the account proof must fail, failed lists
+	// the slots that must, and wantErr is part of the result's error.
+	accountFails bool
+	failed       []int
+	wantErr      string
+}
+
+func checkProofCase[R any](t *testing.T, v Verifier, f fixture, method string, good []byte, values []common.Hash, tc proofCase[R]) {
+	t.Helper()
+	var resp R
+	if err := json.Unmarshal(good, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if tc.tamper != nil {
+		tc.tamper(&resp)
+	}
+	expected := slices.Clone(values)
+	if tc.expected != nil {
+		tc.expected(expected)
+	}
+	if tc.unexpected {
+		expected = nil
+	}
+	client := serveProof(t, f, method, resp)
+	res, err := v.VerifyStorage(context.Background(), client, f.Address, f.Slots, expected, f.Block.Number.ToInt())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StateRoot != f.Block.StateRoot.Hex() || res.BlockNumber != f.Block.Number.ToInt().Uint64() {
+		t.Errorf("proved against block %d, root %s", res.BlockNumber, res.StateRoot)
+	}
+	if res.AccountProven == tc.accountFails {
+		t.Errorf("account proven %v: %s", res.AccountProven, res.Error)
+	}
+	if len(res.Slots) != len(f.Slots) {
+		t.Fatalf("%d slot results for %d slots", len(res.Slots), len(f.Slots))
+	}
+	var failed []int
+	for i, slot := range res.Slots {
+		if slot.Slot != f.Slots[i].Hex() {
+			t.Errorf("slot %d reported as %s", i, slot.Slot)
+		}
+		if !slot.Verified {
+			failed = append(failed, i)
+		} else if slot.Value != values[i].Hex() {
+			t.Errorf("slot %d verified with value %s, want %s", i, slot.Value, values[i].Hex())
+		}
+	}
+	if !slices.Equal(failed, tc.failed) {
+		t.Errorf("failed slots %v, want %v: %+v", failed, tc.failed, res.Slots)
+	}
+	if tc.wantErr == "" && !tc.accountFails && res.Error != "" || !strings.Contains(res.Error, tc.wantErr) {
+		t.Errorf("error %q, want %q", res.Error, tc.wantErr)
+	}
+	if pass := !tc.accountFails && len(tc.failed) == 0 && tc.wantErr == ""; res.Passed() != pass {
+		t.Errorf("passed %v, want %v", res.Passed(), pass)
+	}
+}
+
+// mptResponse is an eth_getProof answer.
+type mptResponse struct {
+	Address      common.Address `json:"address"`
+	AccountProof []string       `json:"accountProof"`
+	Balance      *hexutil.Big   `json:"balance"`
+	CodeHash     common.Hash    `json:"codeHash"`
+	Nonce        hexutil.Uint64 `json:"nonce"`
+	StorageHash  common.Hash    `json:"storageHash"`
+	StorageProof []struct {
+		Key   string       `json:"key"`
+		Value *hexutil.Big `json:"value"`
+		Proof []string     `json:"proof"`
+	} `json:"storageProof"`
+}
+
+func TestMPTVerifyStorage(t *testing.T) {
+	var good mptResponse
+	f := loadFixture(t, "eth_getProof.json", &good)
+	values := make([]common.Hash, len(good.StorageProof))
+	for i, sp := range good.StorageProof {
+		values[i] = common.BigToHash(sp.Value.ToInt())
+	}
+	// flip corrupts the last byte of a hex proof node
+	flip := func(node string) string {
+		b := common.FromHex(node)
+		b[len(b)-1] ^= 1
+		return hexutil.Encode(b)
+	}
+
+	for _, tc := range []proofCase[mptResponse]{
+		{name: "valid"},
+		{name: "unexpected value", expected: func(e []common.Hash) { e[1] = common.BigToHash(big.NewInt(43)) }, failed: []int{1}},
+		{name: "balance", tamper: func(r *mptResponse) { r.Balance = (*hexutil.Big)(big.NewInt(1)) }, accountFails: true,
+			wantErr: "account proof leaf does not match"},
+		{name: "account proof node", tamper: func(r *mptResponse) { r.AccountProof[1] = flip(r.AccountProof[1]) }, accountFails: true,
+			wantErr: "account proof invalid"},
+		{name: "slot value", tamper: func(r *mptResponse) { r.StorageProof[1].Value = (*hexutil.Big)(big.NewInt(43)) }, failed: []int{1}},
+		{name: "set slot reported as zero", tamper: func(r *mptResponse) { r.StorageProof[0].Value = (*hexutil.Big)(new(big.Int)) }, failed: []int{0}},
+		{name: "absent slot reported as set", tamper: func(r *mptResponse) { r.StorageProof[2].Value = (*hexutil.Big)(big.NewInt(1)) }, failed: []int{2}},
+		{name: "storage proof node", tamper: func(r *mptResponse) {
+			p := r.StorageProof[0].Proof
+			p[len(p)-1] = flip(p[len(p)-1])
+		}, failed: []int{0}},
+		{name: "missing proof", tamper: func(r *mptResponse) { r.StorageProof = r.StorageProof[:2] }, failed: []int{2},
+			wantErr: "2 storage proofs returned for 3 slots"},
+		{name: "extra proof", tamper: func(r *mptResponse) { r.StorageProof = append(r.StorageProof, r.StorageProof[0]) },
+			wantErr: "4 storage proofs returned for 3 slots"},
+		{name: "proof for another key", unexpected: true, tamper: func(r *mptResponse) {
+			r.StorageProof[0], r.StorageProof[1] = r.StorageProof[1], r.StorageProof[0]
+		}, failed: []int{0, 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, _ := json.Marshal(good)
+			checkProofCase(t, MPTVerifier{}, f, "eth_getProof", raw, values, tc)
+		})
+	}
+}
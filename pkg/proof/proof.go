@@ -0,0 +1,115 @@
+// Package proof verifies state proofs against a block's state root, so
+// storage values written by the tests can be checked independently of what
+// eth_getStorageAt reports. Standard EVM chains commit to a keccak
+// Merkle-Patricia trie while zkEVM/CDK chains commit to a Poseidon sparse
+// Merkle tree; the Verifier for a chain is selected from its profile.
+package proof
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/profile"
+)
+
+// Verifier proves account storage against a block's state root.
+type Verifier interface {
+	// Trie names the commitment scheme, one of the profile.Trie* constants.
+	Trie() string
+	// VerifyStorage fetches a proof for address and slots at blockNumber and
+	// checks it against the block's state root. If expected is non-nil it
+	// must hold one value per slot that the proven value is compared with.
+	// The result has one SlotResult per slot, in order; a slot the node
+	// returned no proof for, or a proof of another key, fails.
+	VerifyStorage(ctx context.Context, client *ethclient.Client, address common.Address, slots []common.Hash, expected []common.Hash, blockNumber *big.Int) (*Result, error)
+}
+
+// ForProfile returns the verifier matching the chain's state trie.
+func ForProfile(p profile.Profile) (Verifier, error) {
+	switch p.StateTrie {
+	case profile.TrieMPT, "":
+		return MPTVerifier{Method: p.ProofMethod}, nil
+	case profile.TrieSMT:
+		return SMTVerifier{Method: p.ProofMethod}, nil
+	}
+	return nil, fmt.Errorf("no proof verifier for state trie %q", p.StateTrie)
+}
+
+// SlotResult is the verification outcome for one storage slot.
+type SlotResult struct {
+	Slot     string `json:"slot"`
+	Value    string `json:"value"`
+	Expected string `json:"expected,omitempty"`
+	Verified bool   `json:"verified"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Result is the verification outcome for one account at one block.
+type Result struct {
+	Trie          string       `json:"trie"`
+	Address       string       `json:"address"`
+	BlockNumber   uint64       `json:"blockNumber"`
+	StateRoot     string       `json:"stateRoot"`
+	StorageRoot   string       `json:"storageRoot"`
+	AccountProven bool         `json:"accountProven"`
+	Slots         []SlotResult `json:"slots"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// Passed reports whether the account and every slot verified.
+func (r *Result) Passed() bool {
+	if !r.AccountProven || r.Error != "" {
+		return false
+	}
+	for _, s := range r.Slots {
+		if !s.Verified {
+			return false
+		}
+	}
+	return true
+}
+
+// addError records err as the result's error, after any already there.
+func (r *Result) addError(err error) {
+	if r.Error != "" {
+		r.Error += "; "
+	}
+	r.Error += err.Error()
+}
+
+// pairSlots matches the storage proofs of a response, answering for the
+// keys answered, with the slots requested: a node must return one proof
+// per slot, in order. It returns a result per requested slot with Slot and
+// Expected filled in, failing those whose proof is missing or answers for
+// another key, and an error when the node returned more or fewer proofs
+// than slots. The caller verifies the proofs of the others.
+func pairSlots(slots, answered, expected []common.Hash) ([]SlotResult, error) {
+	results := make([]SlotResult, len(slots))
+	for i, slot := range slots {
+		results[i].Slot = slot.Hex()
+		if expected != nil && i < len(expected) {
+			results[i].Expected = expected[i].Hex()
+		}
+		switch {
+		case i >= len(answered):
+			results[i].Error = "no storage proof returned for the slot"
+		case answered[i] != slot:
+			results[i].Error = fmt.Sprintf("storage proof answers for slot %s", answered[i].Hex())
+		}
+	}
+	if len(answered) != len(slots) {
+		return results, fmt.Errorf("%d storage proofs returned for %d slots", len(answered), len(slots))
+	}
+	return results, nil
+}
+
+// MappingSlot returns the storage slot of mapping[key] for a Solidity
+// mapping declared at slot base.
+func MappingSlot(key common.Hash, base uint64) common.Hash {
+	return crypto.Keccak256Hash(key.Bytes(), common.BigToHash(new(big.Int).SetUint64(base)).Bytes())
+}
@@ -0,0 +1,293 @@
+package proof
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	poseidon "github.com/iden3/go-iden3-crypto/goldenposeidon"
+
+	"cdk-erigon-precompile/pkg/profile"
+)
+
+// Leaf types of the zkEVM state tree; each account field lives under its
+// own key rather than in an RLP account leaf.
+const (
+	smtKeyBalance = 0
+	smtKeyNonce   = 1
+	smtKeyStorage = 3
+)
+
+// Proof nodes as returned by cdk-erigon's zkevm_getProof: intermediate
+// nodes are the 64-byte concatenation of both children, the leaf is 65 bytes
+// (remaining key, value hash, a trailing flag byte) and is followed by the
+// raw 32-byte value.
+const (
+	smtBranchLen = 64
+	smtLeafLen   = 65
+)
+
+var (
+	branchCapacity = [4]uint64{0, 0, 0, 0}
+	leafCapacity   = [4]uint64{1, 0, 0, 0}
+)
+
+// nodeKey is a Poseidon digest as four Goldilocks field elements, least
+// significant limb first.
+type nodeKey [4]uint64
+
+func (k nodeKey) isZero() bool { return k == nodeKey{} }
+
+// SMTVerifier checks zkevm_getProof responses against the Poseidon sparse
+// Merkle tree that Polygon zkEVM and cdk-erigon use as state root. Balance,
+// nonce and every storage slot are separate leaves keyed by Poseidon hashes
+// of the address, so there is no per-account storage root to report.
+type SMTVerifier struct {
+	// Method is the proof RPC, zkevm_getProof when empty.
+	Method string
+}
+
+type smtStorageProof struct {
+	Key   common.Hash     `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+type smtProofResult struct {
+	Balance      *hexutil.Big      `json:"balance"`
+	Nonce        hexutil.Uint64    `json:"nonce"`
+	BalanceProof []hexutil.Bytes   `json:"balanceProof"`
+	NonceProof   []hexutil.Bytes   `json:"nonceProof"`
+	StorageProof []smtStorageProof `json:"storageProof"`
+}
+
+// Trie implements Verifier.
+func (SMTVerifier) Trie() string { return profile.TrieSMT }
+
+// VerifyStorage implements Verifier.
+func (v SMTVerifier) VerifyStorage(ctx context.Context, client *ethclient.Client, address common.Address, slots []common.Hash, expected []common.Hash, blockNumber *big.Int) (*Result, error) {
+	header, err := client.HeaderByNumber(ctx, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch header: %w", err)
+	}
+
+	method := v.Method
+	if method == "" {
+		method = "zkevm_getProof"
+	}
+	keys := make([]string, len(slots))
+	for i, slot := range slots {
+		keys[i] = slot.Hex()
+	}
+	var resp smtProofResult
+	if err := client.Client().CallContext(ctx, &resp, method, address, keys, hexutil.EncodeBig(header.Number)); err != nil {
+		return nil, fmt.Errorf("%s failed: %w", method, err)
+	}
+
+	root := bytesToKey(header.Root.Bytes())
+	result := &Result{
+		Trie:        profile.TrieSMT,
+		Address:     address.Hex(),
+		BlockNumber: header.Number.Uint64(),
+		StateRoot:   header.Root.Hex(),
+	}
+
+	if err := verifySMTLeaf(root, addressKey(address, smtKeyBalance), resp.BalanceProof, bigOrZero(resp.Balance)); err != nil {
+		result.Error = fmt.Sprintf("balance proof invalid: %v", err)
+	} else if err := verifySMTLeaf(root, addressKey(address, smtKeyNonce), resp.NonceProof, new(big.Int).SetUint64(uint64(resp.Nonce))); err != nil {
+		result.Error = fmt.Sprintf("nonce proof invalid: %v", err)
+	} else {
+		result.AccountProven = true
+	}
+
+	answered := make([]common.Hash, len(resp.StorageProof))
+	for i, sp := range resp.StorageProof {
+		answered[i] = sp.Key
+	}
+	slotResults, err := pairSlots(slots, answered, expected)
+	if err != nil {
+		result.addError(err)
+	}
+	for i := range slotResults {
+		slot := &slotResults[i]
+		if slot.Error != "" {
+			continue
+		}
+		sp := resp.StorageProof[i]
+		value := bigOrZero(sp.Value)
+		slot.Value = common.BigToHash(value).Hex()
+		if err := verifySMTLeaf(root, storageKey(address, sp.Key), sp.Proof, value); err != nil {
+			slot.Error = fmt.Sprintf("storage proof invalid: %v", err)
+		} else if slot.Expected != "" && slot.Expected != slot.Value {
+			slot.Error = "proven value differs from expected"
+		} else {
+			slot.Verified = true
+		}
+	}
+	result.Slots = slotResults
+	return result, nil
+}
+
+// verifySMTLeaf walks proof from root along the path of key and checks that
+// the tree commits to value under key. A zero value is proven by reaching an
+// empty subtree or a leaf belonging to a different key.
+func verifySMTLeaf(root, key nodeKey, proof []hexutil.Bytes, value *big.Int) error {
+	if root.isZero() {
+		if value.Sign() != 0 {
+			return fmt.Errorf("empty tree cannot hold a non-zero value")
+		}
+		return nil
+	}
+
+	path := keyPath(key)
+	current := root
+	for level, node := range proof {
+		if len(node) != smtBranchLen && len(node) != smtLeafLen {
+			return fmt.Errorf("node %d has unexpected length %d", level, len(node))
+		}
+		left := bytesToKey(node[:32])
+		right := bytesToKey(node[32:64])
+
+		isLeaf := len(node) == smtLeafLen
+		capacity := branchCapacity
+		if isLeaf {
+			capacity = leafCapacity
+		}
+		if h := hashNode(left, right, capacity); h != current {
+			return fmt.Errorf("node %d does not hash to its parent", level)
+		}
+
+		if !isLeaf {
+			if path[level] == 0 {
+				current = left
+			} else {
+				current = right
+			}
+			if current.isZero() {
+				if value.Sign() != 0 {
+					return fmt.Errorf("path ends in an empty subtree at level %d but value is non-zero", level)
+				}
+				return nil
+			}
+			continue
+		}
+
+		// Leaf: the left child is the key remainder below this level
+		if joinKey(path[:level], left) != key {
+			if value.Sign() != 0 {
+				return fmt.Errorf("leaf at level %d belongs to another key", level)
+			}
+			return nil
+		}
+		if level+1 >= len(proof) {
+			return fmt.Errorf("proof is missing the leaf value")
+		}
+		proven := new(big.Int).SetBytes(proof[level+1])
+		if hashValue(proven) != right {
+			return fmt.Errorf("leaf value does not match its hash")
+		}
+		if proven.Cmp(value) != 0 {
+			return fmt.Errorf("proven value %s differs from returned %s", proven, value)
+		}
+		return nil
+	}
+	return fmt.Errorf("proof ended before reaching a leaf")
+}
+
+func hashNode(left, right nodeKey, capacity [4]uint64) nodeKey {
+	in := [8]uint64{left[0], left[1], left[2], left[3], right[0], right[1], right[2], right[3]}
+	return hash(in, capacity)
+}
+
+func hashValue(v *big.Int) nodeKey {
+	return hash(scalarToLimbs(v), branchCapacity)
+}
+
+func hash(in [8]uint64, capacity [4]uint64) nodeKey {
+	// Hash only fails on inputs outside the Goldilocks field; 32-bit limbs
+	// and child digests are always in range.
+	out, err := poseidon.Hash(in, capacity)
+	if err != nil {
+		panic(fmt.Sprintf("poseidon: %v", err))
+	}
+	return nodeKey(out)
+}
+
+// addressKey derives the tree key of an account field.
+func addressKey(address common.Address, leafType uint64) nodeKey {
+	a := scalarToLimbs(new(big.Int).SetBytes(address.Bytes()))
+	in := [8]uint64{a[0], a[1], a[2], a[3], a[4], a[5], leafType, 0}
+	return hash(in, hash([8]uint64{}, [4]uint64{}))
+}
+
+// storageKey derives the tree key of a contract storage slot; the slot's own
+// hash is used as capacity.
+func storageKey(address common.Address, slot common.Hash) nodeKey {
+	a := scalarToLimbs(new(big.Int).SetBytes(address.Bytes()))
+	in := [8]uint64{a[0], a[1], a[2], a[3], a[4], a[5], smtKeyStorage, 0}
+	return hash(in, hash(scalarToLimbs(slot.Big()), branchCapacity))
+}
+
+// scalarToLimbs splits a 256-bit value into eight 32-bit limbs, least
+// significant first.
+func scalarToLimbs(v *big.Int) [8]uint64 {
+	var out [8]uint64
+	b := common.BigToHash(v).Bytes()
+	for i := 0; i < 8; i++ {
+		chunk := b[32-4*(i+1) : 32-4*i]
+		out[i] = uint64(chunk[0])<<24 | uint64(chunk[1])<<16 | uint64(chunk[2])<<8 | uint64(chunk[3])
+	}
+	return out
+}
+
+// bytesToKey splits a big-endian 32-byte digest into four 64-bit limbs,
+// least significant first.
+func bytesToKey(b []byte) nodeKey {
+	var k nodeKey
+	for i := 0; i < 4; i++ {
+		chunk := b[32-8*(i+1) : 32-8*i]
+		for _, c := range chunk {
+			k[i] = k[i]<<8 | uint64(c)
+		}
+	}
+	return k
+}
+
+// keyPath lists the branch directions of key: level n takes bit n/4 of
+// limb n%4.
+func keyPath(key nodeKey) []int {
+	path := make([]int, 0, 256)
+	for bit := 0; bit < 64; bit++ {
+		for limb := 0; limb < 4; limb++ {
+			path = append(path, int((key[limb]>>bit)&1))
+		}
+	}
+	return path
+}
+
+// joinKey rebuilds a full key from the directions already taken and the
+// remainder stored in a leaf.
+func joinKey(used []int, remaining nodeKey) nodeKey {
+	var acc, shift [4]uint64
+	for i, bit := range used {
+		if bit == 1 {
+			acc[i%4] |= 1 << shift[i%4]
+		}
+		shift[i%4]++
+	}
+	var key nodeKey
+	for i := range key {
+		key[i] = remaining[i]<<shift[i] | acc[i]
+	}
+	return key
+}
+
+func bigOrZero(v *hexutil.Big) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v.ToInt()
+}
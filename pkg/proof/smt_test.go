@@ -0,0 +1,58 @@
+package proof
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestSMTVerifyStorage(t *testing.T) {
+	var good smtProofResult
+	f := loadFixture(t, "zkevm_getProof.json", &good)
+	values := make([]common.Hash, len(good.StorageProof))
+	for i, sp := range good.StorageProof {
+		values[i] = common.BigToHash(bigOrZero(sp.Value))
+	}
+	// flip corrupts the last byte of a proof node
+	flip := func(node hexutil.Bytes) hexutil.Bytes {
+		b := append(hexutil.Bytes(nil), node...)
+		b[len(b)-1] ^= 1
+		return b
+	}
+
+	for _, tc := range []proofCase[smtProofResult]{
+		{name: "valid"},
+		{name: "unexpected value", expected: func(e []common.Hash) { e[1] = common.BigToHash(big.NewInt(43)) }, failed: []int{1}},
+		{name: "balance", tamper: func(r *smtProofResult) { r.Balance = (*hexutil.Big)(big.NewInt(1)) }, accountFails: true,
+			wantErr: "balance proof invalid"},
+		{name: "nonce", tamper: func(r *smtProofResult) { r.Nonce = 2 }, accountFails: true,
+			wantErr: "nonce proof invalid"},
+		{name: "balance proof node", tamper: func(r *smtProofResult) { r.BalanceProof[1] = flip(r.BalanceProof[1]) }, accountFails: true,
+			wantErr: "balance proof invalid"},
+		{name: "slot value", tamper: func(r *smtProofResult) { r.StorageProof[1].Value = (*hexutil.Big)(big.NewInt(43)) }, failed: []int{1}},
+		{name: "set slot reported as zero", tamper: func(r *smtProofResult) { r.StorageProof[0].Value = nil }, failed: []int{0}},
+		{name: "absent slot reported as set", tamper: func(r *smtProofResult) { r.StorageProof[2].Value = (*hexutil.Big)(big.NewInt(1)) }, failed: []int{2}},
+		{name: "leaf value", tamper: func(r *smtProofResult) {
+			p := r.StorageProof[1].Proof
+			p[len(p)-1] = flip(p[len(p)-1])
+		}, failed: []int{1}},
+		{name: "truncated proof", tamper: func(r *smtProofResult) {
+			r.StorageProof[0].Proof = r.StorageProof[0].Proof[:2]
+		}, failed: []int{0}},
+		{name: "missing proof", tamper: func(r *smtProofResult) { r.StorageProof = r.StorageProof[:2] }, failed: []int{2},
+			wantErr: "2 storage proofs returned for 3 slots"},
+		{name: "extra proof", tamper: func(r *smtProofResult) { r.StorageProof = append(r.StorageProof, r.StorageProof[0]) },
+			wantErr: "4 storage proofs returned for 3 slots"},
+		{name: "proof for another key", unexpected: true, tamper: func(r *smtProofResult) {
+			r.StorageProof[0], r.StorageProof[1] = r.StorageProof[1], r.StorageProof[0]
+		}, failed: []int{0, 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, _ := json.Marshal(good)
+			checkProofCase(t, SMTVerifier{}, f, "zkevm_getProof", raw, values, tc)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package reference
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var secp256k1N = crypto.S256().Params().N
+
+// ECRecover recovers the signer of a (hash, v, r, s) input, each a 32-byte
+// word, as a left-padded address. Missing input counts as zeros. It answers
+// nothing when v isn't 27 or 28 in a word that is otherwise zero, when r or
+// s is zero or not below the curve order, or when no key recovers; unlike
+// transactions, the high-s half of the signatures is accepted.
+func ECRecover(input []byte) ([]byte, error) {
+	in := padded(input, 0, 128)
+	r := new(big.Int).SetBytes(in[64:96])
+	s := new(big.Int).SetBytes(in[96:128])
+	v := new(big.Int).SetBytes(in[32:64])
+	if (v.Uint64() != 27 && v.Uint64() != 28) || v.BitLen() > 8 ||
+		r.Sign() == 0 || s.Sign() == 0 || r.Cmp(secp256k1N) >= 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, nil
+	}
+	sig := make([]byte, 65)
+	copy(sig, in[64:128])
+	sig[64] = byte(v.Uint64() - 27)
+	pub, err := crypto.Ecrecover(in[:32], sig)
+	if err != nil {
+		return nil, nil
+	}
+	return common.LeftPadBytes(crypto.Keccak256(pub[1:])[12:], 32), nil
+}
+
+// MaxModExpLength bounds the operand lengths ModExp accepts. Longer operands
+// cost more gas than any block holds, so no node ever computes them.
+const MaxModExpLength = 1 << 20
+
+// ErrModExpLength is returned for operands longer than MaxModExpLength.
+var ErrModExpLength = errors.New("modexp operand too long")
+
+// ModExp computes base^exp % mod as EIP-198 specifies. The input starts with
+// the three operand lengths as 32-byte words; the operands follow,
+// zero-filled past the end of the input. The result has the modulus length
+// and is zero for a zero modulus.
+func ModExp(input []byte) ([]byte, error) {
+	var lengths [3]*big.Int
+	for i := range lengths {
+		lengths[i] = new(big.Int).SetBytes(padded(input, uint64(32*i), 32))
+	}
+	// Nothing is computed for a zero-length modulus, however long the
+	// other operands claim to be
+	if lengths[2].Sign() == 0 {
+		return []byte{}, nil
+	}
+	for _, n := range lengths {
+		if !n.IsUint64() || n.Uint64() > MaxModExpLength {
+			return nil, ErrModExpLength
+		}
+	}
+	baseLen, expLen, modLen := lengths[0].Uint64(), lengths[1].Uint64(), lengths[2].Uint64()
+	base := new(big.Int).SetBytes(padded(input, 96, baseLen))
+	exp := new(big.Int).SetBytes(padded(input, 96+baseLen, expLen))
+	mod := new(big.Int).SetBytes(padded(input, 96+baseLen+expLen, modLen))
+	if mod.Sign() == 0 {
+		return make([]byte, modLen), nil
+	}
+	return common.LeftPadBytes(new(big.Int).Exp(base, exp, mod).Bytes(), int(modLen)), nil
+}
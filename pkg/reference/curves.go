@@ -0,0 +1,128 @@
+package reference
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto/bn256"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// BN256 errors.
+var ErrPairingLength = errors.New("bn256 pairing input must be a multiple of 192 bytes")
+
+func g1(blob []byte) (*bn256.G1, error) {
+	p := new(bn256.G1)
+	if _, err := p.Unmarshal(blob); err != nil {
+		return nil, fmt.Errorf("invalid G1 point: %w", err)
+	}
+	return p, nil
+}
+
+func g2(blob []byte) (*bn256.G2, error) {
+	p := new(bn256.G2)
+	if _, err := p.Unmarshal(blob); err != nil {
+		return nil, fmt.Errorf("invalid G2 point: %w", err)
+	}
+	return p, nil
+}
+
+// BN256Add adds two alt_bn128 points (EIP-196), each 64 bytes of x and y;
+// missing input counts as zeros, which encode the point at infinity.
+func BN256Add(input []byte) ([]byte, error) {
+	a, err := g1(padded(input, 0, 64))
+	if err != nil {
+		return nil, err
+	}
+	b, err := g1(padded(input, 64, 64))
+	if err != nil {
+		return nil, err
+	}
+	return new(bn256.G1).Add(a, b).Marshal(), nil
+}
+
+// BN256Mul multiplies an alt_bn128 point by a 32-byte scalar (EIP-196).
+func BN256Mul(input []byte) ([]byte, error) {
+	p, err := g1(padded(input, 0, 64))
+	if err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(padded(input, 64, 32))
+	return new(bn256.G1).ScalarMult(p, k).Marshal(), nil
+}
+
+// BN256Pairing checks that the product of the pairings of its (G1, G2)
+// pairs is one (EIP-197), answering a 32-byte 1 or 0. The empty input is
+// the empty product, one.
+func BN256Pairing(input []byte) ([]byte, error) {
+	if len(input)%192 != 0 {
+		return nil, ErrPairingLength
+	}
+	var (
+		ps []*bn256.G1
+		qs []*bn256.G2
+	)
+	for i := 0; i < len(input); i += 192 {
+		p, err := g1(input[i : i+64])
+		if err != nil {
+			return nil, err
+		}
+		q, err := g2(input[i+64 : i+192])
+		if err != nil {
+			return nil, err
+		}
+		ps, qs = append(ps, p), append(qs, q)
+	}
+	out := make([]byte, 32)
+	if bn256.PairingCheck(ps, qs) {
+		out[31] = 1
+	}
+	return out, nil
+}
+
+// Point evaluation errors.
+var (
+	ErrPointEvaluationLength  = errors.New("point evaluation input must be 192 bytes")
+	ErrPointEvaluationVersion = errors.New("versioned hash doesn't match the commitment")
+)
+
+// PointEvaluationOutput is what a valid proof answers: the number of field
+// elements in a blob and the BLS12-381 scalar field modulus.
+var PointEvaluationOutput = common.FromHex("0x" +
+	"0000000000000000000000000000000000000000000000000000000000001000" +
+	"73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001")
+
+// PointEvaluation verifies a KZG proof that the blob committed to evaluates
+// to y at z (EIP-4844). The input is the versioned hash, z, y, the 48-byte
+// commitment and the 48-byte proof.
+func PointEvaluation(input []byte) ([]byte, error) {
+	if len(input) != 192 {
+		return nil, ErrPointEvaluationLength
+	}
+	var (
+		point      kzg4844.Point
+		claim      kzg4844.Claim
+		commitment kzg4844.Commitment
+		proof      kzg4844.Proof
+	)
+	copy(point[:], input[32:64])
+	copy(claim[:], input[64:96])
+	copy(commitment[:], input[96:144])
+	copy(proof[:], input[144:192])
+	if kzg4844.CalcBlobHashV1(sha256.New(), &commitment) != [32]byte(input[:32]) {
+		return nil, ErrPointEvaluationVersion
+	}
+	if err := kzg4844.VerifyProof(commitment, point, claim, proof); err != nil {
+		return nil, fmt.Errorf("invalid proof: %w", err)
+	}
+	return common.CopyBytes(PointEvaluationOutput), nil
+}
+
+// prague wraps go-ethereum's EIP-2537 precompile at address.
+func prague(address byte) Func {
+	return vm.PrecompiledContractsPrague[common.BytesToAddress([]byte{address})].Run
+}
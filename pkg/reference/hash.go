@@ -0,0 +1,68 @@
+package reference
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/blake2b"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// SHA256 is the SHA-256 digest of input.
+func SHA256(input []byte) ([]byte, error) {
+	sum := sha256.Sum256(input)
+	return sum[:], nil
+}
+
+// RIPEMD160 is the RIPEMD-160 digest of input, left-padded to 32 bytes.
+func RIPEMD160(input []byte) ([]byte, error) {
+	h := ripemd160.New()
+	h.Write(input)
+	return common.LeftPadBytes(h.Sum(nil), 32), nil
+}
+
+// Identity returns a copy of input.
+func Identity(input []byte) ([]byte, error) {
+	return common.CopyBytes(input), nil
+}
+
+// Blake2F errors.
+var (
+	ErrBlake2FLength = errors.New("blake2f input must be 213 bytes")
+	ErrBlake2FFinal  = errors.New("blake2f final block flag must be 0 or 1")
+)
+
+// Blake2F runs the BLAKE2b compression function F as EIP-152 specifies:
+// rounds as a big-endian uint32, then the state h, the message block m and
+// the offset counters t as little-endian words, then the final block flag.
+func Blake2F(input []byte) ([]byte, error) {
+	if len(input) != 213 {
+		return nil, ErrBlake2FLength
+	}
+	if input[212] > 1 {
+		return nil, ErrBlake2FFinal
+	}
+	var (
+		rounds = binary.BigEndian.Uint32(input[0:4])
+		h      [8]uint64
+		m      [16]uint64
+		t      [2]uint64
+	)
+	for i := range h {
+		h[i] = binary.LittleEndian.Uint64(input[4+8*i:])
+	}
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(input[68+8*i:])
+	}
+	t[0] = binary.LittleEndian.Uint64(input[196:])
+	t[1] = binary.LittleEndian.Uint64(input[204:])
+	blake2b.F(&h, m, t, input[212] == 1, rounds)
+
+	out := make([]byte, 64)
+	for i := range h {
+		binary.LittleEndian.PutUint64(out[8*i:], h[i])
+	}
+	return out, nil
+}
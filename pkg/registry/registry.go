@@ -0,0 +1,210 @@
+// Package registry fetches vector sets and contract artifacts from a central
+// location over HTTP. Every download can be pinned to a sha256 checksum and
+// is cached by content hash, so a pinned corpus is fetched once and can't
+// change underneath a run.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// MaxSize caps a single download.
+const MaxSize = 64 << 20
+
+// Source is a location plus an optional pinned checksum.
+type Source struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// ParseSource reads "<url-or-path>[#sha256=<hex>]".
+func ParseSource(s string) Source {
+	src := Source{URL: s}
+	if i := strings.LastIndex(s, "#sha256="); i >= 0 {
+		src.URL = s[:i]
+		src.SHA256 = strings.ToLower(s[i+len("#sha256="):])
+	}
+	return src
+}
+
+// Remote reports whether the source is fetched over HTTP(S).
+func (s Source) Remote() bool {
+	return strings.HasPrefix(s.URL, "http://") || strings.HasPrefix(s.URL, "https://")
+}
+
+// Pinned reports whether the source carries a checksum.
+func (s Source) Pinned() bool { return s.SHA256 != "" }
+
+// DefaultCacheDir is the per-user cache directory, falling back to a
+// directory in the project when the user cache isn't available.
+func DefaultCacheDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cdk-erigon-precompile", "registry")
+	}
+	return filepath.Join(".cache", "registry")
+}
+
+// Fetcher downloads sources into a content-addressed cache.
+type Fetcher struct {
+	CacheDir string
+	Client   *http.Client
+}
+
+// NewFetcher returns a fetcher caching in dir (DefaultCacheDir if empty).
+func NewFetcher(dir string) *Fetcher {
+	if dir == "" {
+		dir = DefaultCacheDir()
+	}
+	return &Fetcher{CacheDir: dir, Client: &http.Client{Timeout: time.Minute}}
+}
+
+// Fetch returns the content of src and its sha256. Pinned sources are served
+// from the cache when present; a checksum mismatch is always an error.
+func (f *Fetcher) Fetch(ctx context.Context, src Source) ([]byte, string, error) {
+	if src.Pinned() {
+		if data, err := os.ReadFile(f.cachePath(src.SHA256)); err == nil && checksum(data) == src.SHA256 {
+			return data, src.SHA256, nil
+		}
+	}
+
+	var data []byte
+	var err error
+	if src.Remote() {
+		data, err = f.download(ctx, src.URL)
+	} else {
+		data, err = os.ReadFile(src.URL)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := checksum(data)
+	if src.Pinned() && sum != src.SHA256 {
+		return nil, "", fmt.Errorf("checksum mismatch for %s: pinned %s, got %s", src.URL, src.SHA256, sum)
+	}
+	if src.Remote() {
+		if err := f.store(sum, data); err != nil {
+			return nil, "", err
+		}
+	}
+	return data, sum, nil
+}
+
+func (f *Fetcher) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry URL %s: %w", url, err)
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, MaxSize)
+	}
+	return data, nil
+}
+
+func (f *Fetcher) cachePath(sum string) string {
+	return filepath.Join(f.CacheDir, sum)
+}
+
+// store writes data into the cache atomically, so concurrent runs never see
+// a partial file.
+func (f *Fetcher) store(sum string, data []byte) error {
+	if err := os.MkdirAll(f.CacheDir, paths.DirMode); err != nil {
+		return fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(f.CacheDir, sum+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return os.Rename(tmp.Name(), f.cachePath(sum))
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Entry is one file listed in a registry index.
+type Entry struct {
+	Name string `json:"name"`
+	Source
+	// Dest is where the file is installed relative to the project root,
+	// e.g. artifacts/Sha256Wrapper.bin or vectors/conformance.json.
+	Dest string `json:"dest"`
+}
+
+// Index lists the files a team publishes.
+type Index struct {
+	Entries []Entry `json:"entries"`
+}
+
+// LoadIndex reads an index from a local path or URL.
+func (f *Fetcher) LoadIndex(ctx context.Context, src Source) (*Index, error) {
+	data, _, err := f.Fetch(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("invalid registry index %s: %w", src.URL, err)
+	}
+	for _, e := range idx.Entries {
+		if e.Dest == "" || filepath.IsAbs(e.Dest) || strings.HasPrefix(filepath.Clean(e.Dest), "..") {
+			return nil, fmt.Errorf("registry entry %q has invalid dest %q", e.Name, e.Dest)
+		}
+	}
+	return &idx, nil
+}
+
+// LoadVectors reads the vector set at src and returns its name and vectors.
+// An unpinned remote set is loaded with a warning, since it may change
+// between runs.
+func (f *Fetcher) LoadVectors(ctx context.Context, src Source) (string, []vector.Vector, error) {
+	data, sum, err := f.Fetch(ctx, src)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load vectors: %w", err)
+	}
+	name, vectors, err := vector.ParseSet(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", src.URL, err)
+	}
+	output.Logf(output.ModuleVectors, output.Verbose, "vector set %s: %d bytes, sha256 %s", src.URL, len(data), sum)
+	if src.Remote() && !src.Pinned() {
+		output.Logf(output.ModuleVectors, output.Quiet, "⚠️  Vector set %s is not pinned (sha256 %s)", src.URL, sum)
+	}
+	return name, vectors, nil
+}
@@ -0,0 +1,47 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadVectors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "set.json")
+	data := []byte(`{"name":"basic","vectors":[{"input":"abc","tags":["smoke"]},{"input":"0x00ff"}]}`)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFetcher(filepath.Join(dir, "cache"))
+
+	name, vectors, err := f.LoadVectors(context.Background(), ParseSource(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "basic" || len(vectors) != 2 {
+		t.Fatalf("got %q with %d vectors, want basic with 2", name, len(vectors))
+	}
+	if string(vectors[0].Input) != "abc" || vectors[0].Tags[0] != "smoke" || vectors[1].Input[1] != 0xff {
+		t.Errorf("vectors = %+v", vectors)
+	}
+
+	if _, _, err := f.LoadVectors(context.Background(), ParseSource(path+"#sha256="+checksum(data))); err != nil {
+		t.Errorf("pinned to its checksum: %v", err)
+	}
+	if _, _, err := f.LoadVectors(context.Background(), ParseSource(path+"#sha256="+checksum([]byte("other")))); err == nil {
+		t.Error("pinned to another checksum: no error")
+	}
+	if _, _, err := f.LoadVectors(context.Background(), ParseSource(filepath.Join(dir, "missing.json"))); err == nil {
+		t.Error("missing file: no error")
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"vectors":[{"input":"0xzz"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.LoadVectors(context.Background(), ParseSource(bad)); err == nil {
+		t.Error("invalid vector: no error")
+	}
+}
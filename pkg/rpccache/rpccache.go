@@ -0,0 +1,225 @@
+// Package rpccache answers repeated immutable JSON-RPC queries from a cache
+// on disk, so differential runs replaying the same historical calls against
+// a slow reference endpoint only reach it once. A query is immutable when
+// it names its block by number or hash, or needs none (eth_chainId); queries
+// against latest, pending, safe or finalized, and errors, always go to the
+// node.
+//
+// Entries are keyed by endpoint, method and params, one file each in the
+// cache directory, so the cache survives across runs and scripts and can be
+// dropped by removing the directory.
+package rpccache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// EnvCache names the directory every RPC client of a script caches into.
+const EnvCache = "RPC_CACHE"
+
+// blockParam is the position of the block parameter of the methods whose
+// answer is fixed once that block is. Methods at -1 take no block and are
+// fixed per endpoint.
+var blockParam = map[string]int{
+	"eth_chainId":             -1,
+	"eth_getBlockByHash":      -1,
+	"eth_getBlockByNumber":    0,
+	"eth_call":                1,
+	"eth_estimateGas":         1,
+	"eth_getBalance":          1,
+	"eth_getCode":             1,
+	"eth_getTransactionCount": 1,
+	"eth_getStorageAt":        2,
+	"eth_getProof":            2,
+	"debug_traceCall":         1,
+}
+
+// Stats counts what the cache saw. Bypassed requests weren't cacheable.
+type Stats struct {
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+	Bypassed int64 `json:"bypassed"`
+}
+
+// Transport is an http.RoundTripper answering cacheable requests from Dir
+// and storing the node's successful answers to them.
+type Transport struct {
+	Base http.RoundTripper
+	Dir  string
+
+	hits, misses, bypassed atomic.Int64
+}
+
+// Stats returns a snapshot of the cache counters.
+func (t *Transport) Stats() Stats {
+	return Stats{Hits: t.hits.Load(), Misses: t.misses.Load(), Bypassed: t.bypassed.Load()}
+}
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// RoundTrip implements http.RoundTripper. Batches pass through uncached.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	var r request
+	if json.Unmarshal(body, &r) != nil || !Cacheable(r.Method, r.Params) {
+		t.bypassed.Add(1)
+		return base.RoundTrip(req)
+	}
+
+	path := filepath.Join(t.Dir, Key(req.URL.String(), r.Method, r.Params)+".json")
+	if result, err := os.ReadFile(path); err == nil {
+		t.hits.Add(1)
+		output.Logf(output.ModuleRPC, output.Verbose, "%s: answered from the cache", r.Method)
+		return reply(req, r.ID, result)
+	}
+	t.misses.Add(1)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return resp, nil
+	}
+	var answer response
+	// A null result, such as a block not mined yet, may still change
+	if json.Unmarshal(data, &answer) == nil && answer.Error == nil && answer.Result != nil && string(answer.Result) != "null" {
+		if err := store(path, answer.Result); err != nil {
+			fmt.Fprintf(os.Stderr, "rpccache: %v\n", err)
+		}
+	}
+	return resp, nil
+}
+
+// store writes an entry through a temporary file, so concurrent runs never
+// read half of one.
+func store(path string, result []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), paths.DirMode); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(result)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	return nil
+}
+
+// reply answers req with a cached result under the request's id.
+func reply(req *http.Request, id json.RawMessage, result []byte) (*http.Response, error) {
+	data, err := json.Marshal(response{JSONRPC: "2.0", ID: id, Result: result})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// Cacheable reports whether the answer to method with params can't change:
+// the method is known and its block parameter is a number, a hash or
+// earliest.
+func Cacheable(method string, params []json.RawMessage) bool {
+	i, ok := blockParam[method]
+	switch {
+	case !ok:
+		return false
+	case i < 0:
+		return true
+	case i >= len(params):
+		// An omitted block defaults to latest
+		return false
+	}
+	return fixedBlock(params[i])
+}
+
+// fixedBlock reports whether a block parameter names one block for good.
+func fixedBlock(param json.RawMessage) bool {
+	var tag string
+	if json.Unmarshal(param, &tag) == nil {
+		return tag == "earliest" || strings.HasPrefix(tag, "0x")
+	}
+	// EIP-1898: {"blockNumber": ...} or {"blockHash": ...}
+	var ref struct {
+		BlockNumber string `json:"blockNumber"`
+		BlockHash   string `json:"blockHash"`
+	}
+	if json.Unmarshal(param, &ref) != nil {
+		return false
+	}
+	return ref.BlockHash != "" || strings.HasPrefix(ref.BlockNumber, "0x")
+}
+
+// Key identifies a query: the endpoint, the method and the params compacted,
+// so requests differing only in whitespace or id share an entry.
+func Key(endpoint, method string, params []json.RawMessage) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", endpoint, method)
+	for _, p := range params {
+		var compact bytes.Buffer
+		if json.Compact(&compact, p) != nil {
+			compact.Write(p)
+		}
+		h.Write(compact.Bytes())
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,116 @@
+package rpccache
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func dial(t *testing.T, url string, transport http.RoundTripper) *ethclient.Client {
+	t.Helper()
+	c, err := rpc.DialOptions(context.Background(), url, rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ethclient.NewClient(c)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestCachesImmutableQueries(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_call", hexutil.Bytes{0xab})
+	s.Result("eth_chainId", "0x2775")
+	dir := t.TempDir()
+
+	to := common.HexToAddress("0x02")
+	msg := ethereum.CallMsg{To: &to, Data: []byte("abc")}
+	for run := 0; run < 2; run++ {
+		// A new transport per run, as a later script would have
+		cache := &Transport{Dir: dir}
+		client := dial(t, s.URL, cache)
+		for i := 0; i < 2; i++ {
+			out, err := client.CallContract(context.Background(), msg, big.NewInt(7))
+			if err != nil || len(out) != 1 || out[0] != 0xab {
+				t.Fatalf("run %d: got %x, %v", run, out, err)
+			}
+			if _, err := client.CallContract(context.Background(), msg, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := client.ChainID(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		want := Stats{Hits: 1, Misses: 2, Bypassed: 2}
+		if run == 1 {
+			want = Stats{Hits: 3, Bypassed: 2}
+		}
+		if got := cache.Stats(); got != want {
+			t.Errorf("run %d: stats %+v, want %+v", run, got, want)
+		}
+	}
+	// The call at block 7 reached the node once, those at latest every time
+	if n := s.Calls("eth_call"); n != 5 {
+		t.Errorf("%d eth_calls reached the node, want 5", n)
+	}
+	if n := s.Calls("eth_chainId"); n != 1 {
+		t.Errorf("%d eth_chainIds reached the node, want 1", n)
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_getCode", mockrpc.FailFirst(1, &mockrpc.Error{Code: -32000, Message: "missing trie node"}, mockrpc.Static(hexutil.Bytes{0x60})))
+	client := dial(t, s.URL, &Transport{Dir: t.TempDir()})
+
+	addr := common.HexToAddress("0x1234")
+	if _, err := client.CodeAt(context.Background(), addr, big.NewInt(3)); err == nil {
+		t.Fatal("expected the first call to fail")
+	}
+	for i := 0; i < 2; i++ {
+		if code, err := client.CodeAt(context.Background(), addr, big.NewInt(3)); err != nil || len(code) != 1 {
+			t.Fatalf("got %x, %v", code, err)
+		}
+	}
+	if n := s.Calls("eth_getCode"); n != 2 {
+		t.Errorf("%d calls reached the node, want 2", n)
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	for _, c := range []struct {
+		method string
+		params string
+		want   bool
+	}{
+		{"eth_call", `[{}, "0x10"]`, true},
+		{"eth_call", `[{}, "latest"]`, false},
+		{"eth_call", `[{}]`, false},
+		{"eth_call", `[{}, {"blockHash": "0xabc"}]`, true},
+		{"eth_call", `[{}, {"blockNumber": "finalized"}]`, false},
+		{"eth_getStorageAt", `["0x1", "0x0", "earliest"]`, true},
+		{"eth_getBlockByNumber", `["pending", false]`, false},
+		{"eth_sendRawTransaction", `["0x00"]`, false},
+		{"eth_chainId", `[]`, true},
+	} {
+		var params []json.RawMessage
+		if err := json.Unmarshal([]byte(c.params), &params); err != nil {
+			t.Fatal(err)
+		}
+		if got := Cacheable(c.method, params); got != c.want {
+			t.Errorf("%s %s: %v, want %v", c.method, c.params, got, c.want)
+		}
+	}
+}
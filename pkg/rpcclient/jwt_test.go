@@ -0,0 +1,111 @@
+package rpcclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+// checkToken verifies a bearer token the way the node's auth port does: an
+// HS256 signature with secret and an iat within a minute of now.
+func checkToken(secret [32]byte, auth string) bool {
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	parts := strings.Split(token, ".")
+	if !ok || len(parts) != 3 {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		IAT int64 `json:"iat"`
+	}
+	if err != nil || json.Unmarshal(data, &claims) != nil {
+		return false
+	}
+	return time.Since(time.Unix(claims.IAT, 0)).Abs() < time.Minute
+}
+
+func TestJWTAuth(t *testing.T) {
+	secret := [32]byte{1, 2, 3}
+	path := t.TempDir() + "/jwt.hex"
+	if err := os.WriteFile(path, []byte("0x0102030000000000000000000000000000000000000000000000000000000000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_chainId", "0x2775")
+	target, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	authed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkToken(secret, r.Header.Get("Authorization")) {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer authed.Close()
+
+	client, err := Connect(context.Background(), authed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ChainID(context.Background()); err == nil {
+		t.Error("unauthenticated call succeeded")
+	}
+	client.Close()
+
+	t.Setenv(EnvJWTSecret, path)
+	client, err = Connect(context.Background(), authed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.ChainID(context.Background()); err != nil {
+		t.Errorf("Connect: %v", err)
+	}
+	retrying, _, err := Dial(context.Background(), authed.URL, fastRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer retrying.Close()
+	if _, err := retrying.ChainID(context.Background()); err != nil {
+		t.Errorf("Dial: %v", err)
+	}
+	// DialConn asks for the chain ID itself
+	conn, err := DialConn(context.Background(), authed.URL, ReconnectOptions{})
+	if err != nil {
+		t.Fatalf("DialConn: %v", err)
+	}
+	conn.Close()
+	if n := s.Calls("eth_chainId"); n != 3 {
+		t.Errorf("%d authenticated calls, want 3", n)
+	}
+
+	if err := os.WriteFile(path, []byte("0x0102"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Connect(context.Background(), authed.URL); err == nil {
+		t.Error("short secret accepted")
+	}
+}
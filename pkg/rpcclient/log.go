@@ -0,0 +1,142 @@
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/capture"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/rpccache"
+)
+
+// LogTransport is an http.RoundTripper that logs JSON-RPC traffic through
+// the rpc output module: method names, status and latency at Verbose, and
+// the raw request and response bodies at Debug.
+type LogTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *LogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !output.V(output.ModuleRPC, output.Verbose) {
+		return base.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	methods := requestMethods(body)
+	output.Logf(output.ModuleRPC, output.Debug, "--> %s", body)
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Microsecond)
+	if err != nil {
+		output.Logf(output.ModuleRPC, output.Verbose, "%s failed after %s: %v", methods, elapsed, err)
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		output.Logf(output.ModuleRPC, output.Verbose, "%s: reading response failed after %s: %v", methods, elapsed, err)
+		return resp, nil
+	}
+	output.Logf(output.ModuleRPC, output.Verbose, "%s: HTTP %d in %s%s", methods, resp.StatusCode, elapsed, responseErrors(data))
+	output.Logf(output.ModuleRPC, output.Debug, "<-- %s", data)
+	return resp, nil
+}
+
+// requestMethods names the methods of a single or batch request.
+func requestMethods(body []byte) string {
+	var single struct {
+		Method string `json:"method"`
+	}
+	if json.Unmarshal(body, &single) == nil && single.Method != "" {
+		return single.Method
+	}
+	var batch []struct {
+		Method string `json:"method"`
+	}
+	if json.Unmarshal(body, &batch) == nil && len(batch) > 0 {
+		names := make([]string, len(batch))
+		for i, m := range batch {
+			names[i] = m.Method
+		}
+		return "batch[" + strings.Join(names, ",") + "]"
+	}
+	return "request"
+}
+
+// responseErrors summarizes JSON-RPC errors in a response body.
+func responseErrors(data []byte) string {
+	var single struct {
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &single) == nil && single.Error != nil {
+		return fmt.Sprintf(", error %d: %s", single.Error.Code, single.Error.Message)
+	}
+	return ""
+}
+
+// Connect dials rpcURL without retries, logging traffic according to the rpc
+// verbosity, recording it when RPC_CAPTURE is set and authenticating when
+// RPC_JWT_SECRET is. It is the drop-in
+// replacement for ethclient.DialContext.
+func Connect(ctx context.Context, rpcURL string) (*ethclient.Client, error) {
+	base, err := baseTransport()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := AuthOptions()
+	if err != nil {
+		return nil, err
+	}
+	c, err := rpc.DialOptions(ctx, rpcURL, append(opts, rpc.WithHTTPClient(&http.Client{Transport: base}))...)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(c), nil
+}
+
+// baseTransport is the logging transport, answering immutable queries from
+// the RPC_CACHE directory and recording into the RPC_CAPTURE file if set.
+// Only exchanges that reach the node are recorded.
+func baseTransport() (http.RoundTripper, error) {
+	var base http.RoundTripper
+	if path := os.Getenv(capture.EnvCapture); path != "" {
+		recorder, err := capture.NewRecorder(http.DefaultTransport, path)
+		if err != nil {
+			return nil, err
+		}
+		base = recorder
+	}
+	if dir := os.Getenv(rpccache.EnvCache); dir != "" {
+		base = &rpccache.Transport{Base: base, Dir: dir}
+	}
+	return &LogTransport{Base: base}, nil
+}
@@ -0,0 +1,271 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/output"
+)
+
+// ReconnectOptions tunes a Conn.
+type ReconnectOptions struct {
+	// Backoff is the delay before the first redial; it doubles per
+	// attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// PollInterval is how often FollowHeads asks for the latest head when
+	// the connection can't subscribe.
+	PollInterval time.Duration
+	// OnReconnect, if set, is called once the node is reachable again with
+	// the number of redials it took and the error that triggered them.
+	OnReconnect func(attempts int, cause error)
+}
+
+// DefaultReconnectOptions redials after 1s, backing off to a minute, and
+// polls heads every 2s.
+var DefaultReconnectOptions = ReconnectOptions{Backoff: time.Second, MaxBackoff: time.Minute, PollInterval: 2 * time.Second}
+
+// Dropped reports whether err means the connection to the node is gone, as
+// opposed to the node answering with an error.
+func Dropped(err error) bool {
+	return errors.Is(err, rpc.ErrClientQuit) || Classify(err) == ClassConnection
+}
+
+// Conn is a client that survives the node going away: calls made through
+// Do that fail because the connection dropped are retried on a fresh
+// client, redialled with backoff until the node answers again. It lets
+// watch and benchmark runs ride out node restarts instead of aborting.
+type Conn struct {
+	URL  string
+	Opts ReconnectOptions
+
+	mu         sync.Mutex
+	client     *ethclient.Client
+	reconnects atomic.Int64
+}
+
+// DialConn connects to rpcURL, which may be an http(s) or ws(s) URL, and
+// checks the node answers.
+func DialConn(ctx context.Context, rpcURL string, opts ReconnectOptions) (*Conn, error) {
+	c := &Conn{URL: rpcURL, Opts: opts}
+	client, err := c.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", rpcURL, err)
+	}
+	c.client = client
+	return c, nil
+}
+
+// dial opens a client and asks for the chain ID, as HTTP dials don't
+// connect by themselves.
+func (c *Conn) dial(ctx context.Context) (*ethclient.Client, error) {
+	base, err := baseTransport()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := AuthOptions()
+	if err != nil {
+		return nil, err
+	}
+	rc, err := rpc.DialOptions(ctx, c.URL, append(opts, rpc.WithHTTPClient(&http.Client{Transport: base}))...)
+	if err != nil {
+		return nil, err
+	}
+	client := ethclient.NewClient(rc)
+	if _, err := client.ChainID(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Client returns the current client. It may drop at any time; prefer Do.
+func (c *Conn) Client() *ethclient.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client
+}
+
+// Reconnects is how many times the connection has been re-established.
+func (c *Conn) Reconnects() int64 { return c.reconnects.Load() }
+
+// Close closes the current client.
+func (c *Conn) Close() { c.Client().Close() }
+
+// Do calls fn with the current client, redialling and calling it again for
+// as long as it fails because the connection dropped. Other errors are
+// returned as is, as is the last drop once ctx is done.
+func (c *Conn) Do(ctx context.Context, fn func(*ethclient.Client) error) error {
+	for {
+		client := c.Client()
+		err := fn(client)
+		if err == nil || !Dropped(err) || ctx.Err() != nil {
+			return err
+		}
+		if _, rerr := c.redial(ctx, client, err); rerr != nil {
+			return err
+		}
+	}
+}
+
+// redial replaces stale with a working client, unless another caller
+// already did. It only fails when ctx is done.
+func (c *Conn) redial(ctx context.Context, stale *ethclient.Client, cause error) (*ethclient.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != stale {
+		return c.client, nil
+	}
+	output.Logf(output.ModuleRPC, output.Verbose, "connection to %s dropped: %v", c.URL, cause)
+	delay := c.Opts.Backoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		client, err := c.dial(ctx)
+		if err == nil {
+			stale.Close()
+			c.client = client
+			c.reconnects.Add(1)
+			if c.Opts.OnReconnect != nil {
+				c.Opts.OnReconnect(attempt, cause)
+			}
+			return client, nil
+		}
+		output.Logf(output.ModuleRPC, output.Verbose, "redial %d of %s failed: %v", attempt, c.URL, err)
+		if delay *= 2; c.Opts.MaxBackoff > 0 && delay > c.Opts.MaxBackoff {
+			delay = c.Opts.MaxBackoff
+		}
+	}
+}
+
+// FollowHeads sends every new head to ch until ctx is done, through a
+// subscription when the connection supports one and by polling otherwise.
+// A dropped subscription is resubscribed on a redialled client and the
+// heads mined meanwhile are fetched, so ch sees each block number once and
+// in order; heads replacing an already sent number after a reorg are
+// skipped. It returns ctx's error, or the first error that isn't a drop.
+func (c *Conn) FollowHeads(ctx context.Context, ch chan<- *types.Header) error {
+	f := &follower{conn: c, ch: ch}
+	for {
+		client := c.Client()
+		heads := make(chan *types.Header)
+		sub, err := client.SubscribeNewHead(ctx, heads)
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			return f.poll(ctx)
+		}
+		if err == nil {
+			var fatal error
+			err, fatal = f.consume(ctx, sub, heads)
+			sub.Unsubscribe()
+			if fatal != nil {
+				return fatal
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Any subscription error means the connection went away
+		if _, rerr := c.redial(ctx, client, err); rerr != nil {
+			return rerr
+		}
+	}
+}
+
+type follower struct {
+	conn *Conn
+	ch   chan<- *types.Header
+	// next is the number of the next head to send, nil until the first.
+	next *big.Int
+}
+
+// consume delivers the heads of sub until it fails, returning its error as
+// dropped, or until delivering fails, returning that error as fatal.
+func (f *follower) consume(ctx context.Context, sub ethereum.Subscription, heads <-chan *types.Header) (dropped, fatal error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err := <-sub.Err():
+			if err == nil {
+				err = rpc.ErrClientQuit
+			}
+			return err, nil
+		case h := <-heads:
+			if err := f.deliver(ctx, h); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (f *follower) poll(ctx context.Context) error {
+	ticker := time.NewTicker(f.conn.Opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		var h *types.Header
+		err := f.conn.Do(ctx, func(client *ethclient.Client) error {
+			var err error
+			h, err = client.HeaderByNumber(ctx, nil)
+			return err
+		})
+		if err == nil {
+			err = f.deliver(ctx, h)
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver sends the heads between the last one sent and h, then h.
+func (f *follower) deliver(ctx context.Context, h *types.Header) error {
+	if f.next != nil {
+		if h.Number.Cmp(f.next) < 0 {
+			return nil
+		}
+		for n := new(big.Int).Set(f.next); n.Cmp(h.Number) < 0; n.Add(n, big.NewInt(1)) {
+			var missed *types.Header
+			err := f.conn.Do(ctx, func(client *ethclient.Client) error {
+				var err error
+				missed, err = client.HeaderByNumber(ctx, n)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to fetch missed head %s: %w", n, err)
+			}
+			if err := f.send(ctx, missed); err != nil {
+				return err
+			}
+		}
+	}
+	return f.send(ctx, h)
+}
+
+func (f *follower) send(ctx context.Context, h *types.Header) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case f.ch <- h:
+		f.next = new(big.Int).Add(h.Number, big.NewInt(1))
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+var fastReconnects = ReconnectOptions{Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, PollInterval: time.Millisecond}
+
+func TestConnRedialsAfterDrop(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_chainId", "0x2775")
+	s.Handle("eth_blockNumber", mockrpc.FailFirst(2, mockrpc.ErrDrop, mockrpc.Static("0x10")))
+
+	opts := fastReconnects
+	var causes []error
+	opts.OnReconnect = func(_ int, cause error) { causes = append(causes, cause) }
+	conn, err := DialConn(context.Background(), s.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var n uint64
+	err = conn.Do(context.Background(), func(client *ethclient.Client) error {
+		var err error
+		n, err = client.BlockNumber(context.Background())
+		return err
+	})
+	if err != nil || n != 16 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if conn.Reconnects() != 2 || len(causes) != 2 || !Dropped(causes[0]) {
+		t.Errorf("%d reconnects, causes %v", conn.Reconnects(), causes)
+	}
+}
+
+func TestConnKeepsNodeErrors(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_chainId", "0x2775")
+	s.Handle("eth_blockNumber", mockrpc.Fail(&mockrpc.Error{Code: -32000, Message: "header not found"}))
+
+	conn, err := DialConn(context.Background(), s.URL, fastReconnects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = conn.Do(context.Background(), func(client *ethclient.Client) error {
+		_, err := client.BlockNumber(context.Background())
+		return err
+	})
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) || Dropped(err) || conn.Reconnects() != 0 {
+		t.Errorf("got %v after %d reconnects", err, conn.Reconnects())
+	}
+}
+
+func TestConnGivesUpWithContext(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_chainId", mockrpc.Sequence(mockrpc.Static("0x2775"), mockrpc.Fail(mockrpc.ErrDrop)))
+	s.Handle("eth_blockNumber", mockrpc.Fail(mockrpc.ErrDrop))
+
+	conn, err := DialConn(context.Background(), s.URL, fastReconnects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The node never comes back
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = conn.Do(ctx, func(client *ethclient.Client) error {
+		_, err := client.BlockNumber(ctx)
+		return err
+	})
+	if !Dropped(err) || conn.Reconnects() != 0 {
+		t.Errorf("got %v after %d reconnects", err, conn.Reconnects())
+	}
+}
+
+func TestFollowHeadsBackfills(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_chainId", "0x2775")
+	header := func(n int64) mockrpc.Handler {
+		return mockrpc.Static(&types.Header{Number: big.NewInt(n), Difficulty: new(big.Int)})
+	}
+	// The node drops once between the polls seeing head 5 and head 8
+	latest := mockrpc.Sequence(header(5), mockrpc.Fail(mockrpc.ErrDrop), header(5), header(8))
+	polls := 0
+	s.Handle("eth_getBlockByNumber", func(c mockrpc.Call) (any, error) {
+		var tag string
+		if err := c.Param(0, &tag); err != nil {
+			return nil, err
+		}
+		if tag == "latest" {
+			polls++
+			return latest(mockrpc.Call{N: polls})
+		}
+		var n big.Int
+		n.SetString(tag[2:], 16)
+		return header(n.Int64())(c)
+	})
+
+	conn, err := DialConn(context.Background(), s.URL, fastReconnects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	heads := make(chan *types.Header)
+	done := make(chan error, 1)
+	go func() { done <- conn.FollowHeads(ctx, heads) }()
+
+	var got []uint64
+	for len(got) < 4 {
+		select {
+		case h := <-heads:
+			got = append(got, h.Number.Uint64())
+		case err := <-done:
+			t.Fatalf("stopped after %v: %v", got, err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out after %v", got)
+		}
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("returned %v", err)
+	}
+	if got[0] != 5 || got[1] != 6 || got[2] != 7 || got[3] != 8 {
+		t.Errorf("heads %v, want 5 6 7 8", got)
+	}
+	if conn.Reconnects() != 1 {
+		t.Errorf("%d reconnects", conn.Reconnects())
+	}
+}
@@ -0,0 +1,224 @@
+// Package rpcclient dials the node through an HTTP transport that retries
+// transient failures (dropped connections, 429/5xx responses, truncated or
+// malformed JSON) with backoff, and classifies the errors that remain so
+// reports can tell node faults from transport noise.
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrMalformedResponse is returned when the node keeps answering with a body
+// that isn't valid JSON after all retries.
+var ErrMalformedResponse = errors.New("malformed JSON-RPC response")
+
+// Options tunes the retrying transport.
+type Options struct {
+	MaxRetries int
+	// Backoff is the delay before the first retry; it doubles per attempt.
+	Backoff time.Duration
+	// OnRetry, if set, is called before every retry with the reason.
+	OnRetry func(attempt int, reason string)
+}
+
+// DefaultOptions retries three times starting at 200ms.
+var DefaultOptions = Options{MaxRetries: 3, Backoff: 200 * time.Millisecond}
+
+// Stats counts what the transport saw.
+type Stats struct {
+	Requests  int64 `json:"requests"`
+	Retries   int64 `json:"retries"`
+	GaveUp    int64 `json:"gaveUp"`
+	Recovered int64 `json:"recovered"`
+}
+
+// Transport is an http.RoundTripper that retries transient JSON-RPC failures.
+type Transport struct {
+	Base http.RoundTripper
+	Opts Options
+
+	requests, retries, gaveUp, recovered atomic.Int64
+}
+
+// Stats returns a snapshot of the transport counters.
+func (t *Transport) Stats() Stats {
+	return Stats{
+		Requests:  t.requests.Load(),
+		Retries:   t.retries.Load(),
+		GaveUp:    t.gaveUp.Load(),
+		Recovered: t.recovered.Load(),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	delay := t.Opts.Backoff
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+		if body != nil {
+			attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, reason, err := t.try(base, attemptReq)
+		if reason == "" {
+			if attempt > 0 {
+				t.recovered.Add(1)
+			}
+			return resp, err
+		}
+
+		if attempt >= t.Opts.MaxRetries {
+			t.gaveUp.Add(1)
+			if err == nil && reason == "malformed" {
+				err = ErrMalformedResponse
+			}
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			if ra := retryAfter(resp); ra > delay {
+				delay = ra
+			}
+		}
+
+		t.retries.Add(1)
+		if t.Opts.OnRetry != nil {
+			t.Opts.OnRetry(attempt+1, reason)
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// try performs one attempt and returns a non-empty reason if it should be
+// retried. Retryable responses still carry a readable body.
+func (t *Transport) try(base http.RoundTripper, req *http.Request) (*http.Response, string, error) {
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, "", err
+		}
+		return nil, "connection", err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return resp, "connection", err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return resp, "rate_limited", nil
+	case resp.StatusCode >= 500:
+		return resp, fmt.Sprintf("http_%d", resp.StatusCode), nil
+	case resp.StatusCode == http.StatusOK && !json.Valid(data):
+		return resp, "malformed", nil
+	}
+	return resp, "", nil
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+// Dial connects to rpcURL through a retrying transport.
+func Dial(ctx context.Context, rpcURL string, opts Options) (*ethclient.Client, *Transport, error) {
+	base, err := baseTransport()
+	if err != nil {
+		return nil, nil, err
+	}
+	auth, err := AuthOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+	transport := &Transport{Base: base, Opts: opts}
+	c, err := rpc.DialOptions(ctx, rpcURL, append(auth, rpc.WithHTTPClient(&http.Client{Transport: transport}))...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to dial %s: %w", rpcURL, err)
+	}
+	return ethclient.NewClient(c), transport, nil
+}
+
+// Error classes reported by Classify.
+const (
+	ClassOK          = "ok"
+	ClassTimeout     = "timeout"
+	ClassRateLimited = "rate_limited"
+	ClassHTTP5xx     = "http_5xx"
+	ClassMalformed   = "malformed_response"
+	ClassConnection  = "connection"
+	ClassRPCError    = "rpc_error"
+	ClassUnknown     = "unknown"
+)
+
+// Classify maps an RPC error to a stable class name for reports.
+func Classify(err error) string {
+	if err == nil {
+		return ClassOK
+	}
+
+	var httpErr rpc.HTTPError
+	var rpcErr rpc.Error
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case errors.As(err, &httpErr):
+		if httpErr.StatusCode == http.StatusTooManyRequests {
+			return ClassRateLimited
+		}
+		if httpErr.StatusCode >= 500 {
+			return ClassHTTP5xx
+		}
+		return ClassUnknown
+	case errors.Is(err, ErrMalformedResponse), errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return ClassMalformed
+	case errors.As(err, &rpcErr):
+		return ClassRPCError
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.As(err, &netErr), errors.Is(err, io.EOF):
+		return ClassConnection
+	}
+	return ClassUnknown
+}
@@ -0,0 +1,121 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/capture"
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+var fastRetries = Options{MaxRetries: 3, Backoff: time.Millisecond}
+
+func TestRetriesTransientFailures(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_chainId", mockrpc.FailFirst(2, mockrpc.HTTPError(503), mockrpc.Static("0x2775")))
+
+	client, transport, err := Dial(context.Background(), s.URL, fastRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	id, err := client.ChainID(context.Background())
+	if err != nil || id.Uint64() != 10101 {
+		t.Fatalf("got %v, %v", id, err)
+	}
+	stats := transport.Stats()
+	if stats.Retries != 2 || stats.Recovered != 1 || stats.GaveUp != 0 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestGivesUpAndClassifies(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_chainId", mockrpc.Fail(mockrpc.HTTPError(429)))
+	s.Handle("eth_blockNumber", mockrpc.Fail(&mockrpc.Error{Code: -32000, Message: "header not found"}))
+
+	client, transport, err := Dial(context.Background(), s.URL, fastRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	_, err = client.ChainID(context.Background())
+	if class := Classify(err); class != ClassRateLimited {
+		t.Errorf("429: class %q (%v)", class, err)
+	}
+	if n := s.Calls("eth_chainId"); n != 4 {
+		t.Errorf("429: %d attempts, want 4", n)
+	}
+	if transport.Stats().GaveUp != 1 {
+		t.Errorf("stats %+v", transport.Stats())
+	}
+
+	// JSON-RPC errors are answers, not transport faults: no retries
+	_, err = client.BlockNumber(context.Background())
+	if class := Classify(err); class != ClassRPCError {
+		t.Errorf("rpc error: class %q (%v)", class, err)
+	}
+	if n := s.Calls("eth_blockNumber"); n != 1 {
+		t.Errorf("rpc error: %d attempts, want 1", n)
+	}
+}
+
+func TestClassifyTimeout(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("eth_chainId", func(mockrpc.Call) (any, error) {
+		time.Sleep(100 * time.Millisecond)
+		return "0x1", nil
+	})
+
+	client, err := Connect(context.Background(), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.ChainID(ctx)
+	if class := Classify(err); class != ClassTimeout {
+		t.Errorf("class %q (%v)", class, err)
+	}
+}
+
+func TestConnectRecordsCapture(t *testing.T) {
+	path := t.TempDir() + "/capture.har"
+	t.Setenv("RPC_CAPTURE", path)
+
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("eth_blockNumber", "0x2a")
+
+	client, err := Connect(context.Background(), s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.BlockNumber(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var rpcErr rpc.Error
+	if _, err := client.ChainID(context.Background()); !errors.As(err, &rpcErr) {
+		t.Fatalf("expected the unhandled method to fail, got %v", err)
+	}
+
+	data, err := capture.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Log.Entries) != 2 {
+		t.Fatalf("captured %d exchanges, want 2", len(data.Log.Entries))
+	}
+}
@@ -0,0 +1,79 @@
+package score
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+)
+
+// DefaultLabel is the badge's left-hand text.
+const DefaultLabel = "precompile conformance"
+
+// Color picks the badge color for a score.
+func Color(score float64) string {
+	switch {
+	case score >= 0.99:
+		return "brightgreen"
+	case score >= 0.95:
+		return "green"
+	case score >= 0.90:
+		return "yellowgreen"
+	case score >= 0.75:
+		return "yellow"
+	case score >= 0.50:
+		return "orange"
+	}
+	return "red"
+}
+
+var colors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+}
+
+// BadgeJSON renders the report in the shields.io endpoint format, so a
+// published file can back a https://img.shields.io/endpoint?url=... badge.
+func BadgeJSON(label string, r Report) ([]byte, error) {
+	return json.MarshalIndent(map[string]any{
+		"schemaVersion": 1,
+		"label":         label,
+		"message":       r.Percent,
+		"color":         Color(r.Score),
+	}, "", "  ")
+}
+
+// BadgeSVG renders a flat badge.
+func BadgeSVG(label string, r Report) []byte {
+	// Verdana 11px averages close to 6.5px per character
+	labelWidth := textWidth(label)
+	valueWidth := textWidth(r.Percent)
+	width := labelWidth + valueWidth
+	label = html.EscapeString(label)
+	value := html.EscapeString(r.Percent)
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">
+  <title>%[2]s: %[3]s</title>
+  <linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+  <clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[4]d" height="20" fill="#555"/>
+    <rect x="%[4]d" width="%[5]d" height="20" fill="%[6]s"/>
+    <rect width="%[1]d" height="20" fill="url(#s)"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[2]s</text>
+    <text x="%[7]d" y="14">%[2]s</text>
+    <text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[3]s</text>
+    <text x="%[8]d" y="14">%[3]s</text>
+  </g>
+</svg>
+`, width, label, value, labelWidth, valueWidth, colors[Color(r.Score)], labelWidth/2, labelWidth+valueWidth/2))
+}
+
+func textWidth(s string) int {
+	return len(s)*13/2 + 10
+}
@@ -0,0 +1,105 @@
+package score
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Latency summarizes the call latencies one results file measured for a
+// precompile.
+type Latency struct {
+	Precompile string  `json:"precompile"`
+	Source     string  `json:"source"`
+	Samples    int     `json:"samples"`
+	MeanMs     float64 `json:"meanMs"`
+	MinMs      float64 `json:"minMs"`
+	MaxMs      float64 `json:"maxMs"`
+}
+
+var latencyCollectors = []struct {
+	file    string
+	collect func([]byte) ([]Latency, error)
+}{
+	{"results_benchmark.json", latencyBenchmark},
+	{"results_ecrecover.json", latencyECRecover},
+	{"results_fuzz.json", latencyFuzz},
+}
+
+// CollectLatency reads the latency summaries of the results files in dir
+// written at or after since (zero for all). Files without one are skipped.
+func CollectLatency(dir string, since time.Time) ([]Latency, error) {
+	var out []Latency
+	for _, col := range latencyCollectors {
+		data, err := readFresh(dir, col.file, since)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		ls, err := col.collect(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", col.file, err)
+		}
+		for _, l := range ls {
+			if l.Samples > 0 {
+				out = append(out, l)
+			}
+		}
+	}
+	return out, nil
+}
+
+// latencySummary is the shape of bench.Summary.
+type latencySummary struct {
+	Samples int     `json:"samples"`
+	Mean    float64 `json:"meanMs"`
+	Min     float64 `json:"minMs"`
+	Max     float64 `json:"maxMs"`
+}
+
+func latencyBenchmark(data []byte) ([]Latency, error) {
+	var r struct {
+		Target  string         `json:"target"`
+		Summary latencySummary `json:"summary"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	s := r.Summary
+	return []Latency{{Precompile: sha256Precompile, Source: "benchmark-" + r.Target, Samples: s.Samples, MeanMs: s.Mean, MinMs: s.Min, MaxMs: s.Max}}, nil
+}
+
+func latencyECRecover(data []byte) ([]Latency, error) {
+	var r struct {
+		Precompile string         `json:"precompile"`
+		Latency    latencySummary `json:"latency"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Precompile == "" {
+		r.Precompile = "0x01"
+	}
+	s := r.Latency
+	return []Latency{{Precompile: r.Precompile, Source: "ecrecover", Samples: s.Samples, MeanMs: s.Mean, MinMs: s.Min, MaxMs: s.Max}}, nil
+}
+
+func latencyFuzz(data []byte) ([]Latency, error) {
+	var r struct {
+		Precompile string `json:"precompile"`
+		// The shape of bench.Running
+		Latency struct {
+			Count int     `json:"count"`
+			Min   float64 `json:"min"`
+			Max   float64 `json:"max"`
+			Mean  float64 `json:"mean"`
+		} `json:"latencyMs"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	l := r.Latency
+	return []Latency{{Precompile: orDefault(r.Precompile), Source: "fuzz", Samples: l.Count, MeanMs: l.Mean, MinMs: l.Min, MaxMs: l.Max}}, nil
+}
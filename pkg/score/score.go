@@ -0,0 +1,619 @@
+// Package score turns the results files of a run into a weighted
+// precompile conformance score, per precompile and per category, and renders
+// it as a badge node operators can publish.
+//
+// Each category's pass rate is weighted and averaged over the categories
+// that produced results, so a run that skipped the archive tests is scored
+// on what it did run rather than penalized for it.
+package score
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cdk-erigon-precompile/pkg/skip"
+)
+
+// Categories scored from the results files.
+const (
+	RawCall      = "raw-call"
+	Wrapper      = "wrapper"
+	StorageProof = "storage-proof"
+	Conformance  = "node-conformance"
+	Archive      = "archive"
+	Fuzz         = "fuzz"
+	Mutation     = "mutation"
+	Multicall    = "multicall"
+	Provenance   = "provenance"
+	CallPattern  = "call-pattern"
+	Composition  = "composition"
+	MemExp       = "memory-expansion"
+	Undefined    = "undefined-address"
+	ZeroGas      = "zero-gas"
+	EmptyInput   = "empty-input"
+	EIP712       = "eip712"
+	ERC1271      = "erc1271"
+	BLS          = "bls12-381"
+	Groth16      = "groth16"
+	Merkle       = "merkle"
+	Plugin       = "plugin"
+)
+
+// DefaultWeights favors the known-answer checks over the broader ones.
+var DefaultWeights = map[string]float64{
+	RawCall:      3,
+	Wrapper:      3,
+	StorageProof: 2,
+	Fuzz:         2,
+	Mutation:     2,
+	Multicall:    2,
+	Provenance:   2,
+	CallPattern:  2,
+	Composition:  2,
+	MemExp:       2,
+	Undefined:    1,
+	ZeroGas:      2,
+	EmptyInput:   2,
+	EIP712:       2,
+	ERC1271:      2,
+	BLS:          2,
+	Groth16:      2,
+	Merkle:       2,
+	Conformance:  1,
+	Archive:      1,
+	Plugin:       1,
+}
+
+// Tally counts passed and failed checks of one precompile in one category,
+// and the skipped ones by reason. Skipped checks aren't scored.
+type Tally struct {
+	Precompile string      `json:"precompile"`
+	Category   string      `json:"category"`
+	Passed     int         `json:"passed"`
+	Failed     int         `json:"failed"`
+	Skipped    skip.Counts `json:"skipped,omitempty"`
+}
+
+// Rate is the pass rate in [0, 1].
+func (t Tally) Rate() float64 {
+	if t.Passed+t.Failed == 0 {
+		return 0
+	}
+	return float64(t.Passed) / float64(t.Passed+t.Failed)
+}
+
+// Part is a scored slice of the results.
+type Part struct {
+	Name    string  `json:"name"`
+	Score   float64 `json:"score"`
+	Passed  int     `json:"passed"`
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped,omitempty"`
+	Weight  float64 `json:"weight,omitempty"`
+	Percent string  `json:"percent"`
+}
+
+// Report is the overall score with its breakdowns.
+type Report struct {
+	Score       float64            `json:"score"`
+	Percent     string             `json:"percent"`
+	Precompiles []Part             `json:"precompiles"`
+	Categories  []Part             `json:"categories"`
+	Weights     map[string]float64 `json:"weights"`
+	Tallies     []Tally            `json:"tallies"`
+	// Skipped counts the checks not run, by reason, so "not run" is never
+	// mistaken for "passed".
+	Skipped   skip.Counts `json:"skipped,omitempty"`
+	Sources   []string    `json:"sources"`
+	Missing   []string    `json:"missing,omitempty"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// ParseWeights parses "wrapper=2,fuzz=0.5" on top of DefaultWeights.
+func ParseWeights(spec string) (map[string]float64, error) {
+	weights := map[string]float64{}
+	for k, v := range DefaultWeights {
+		weights[k] = v
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if _, known := DefaultWeights[name]; !ok || !known {
+			return nil, fmt.Errorf("invalid weight %q (categories: %s)", part, strings.Join(categoryNames(), ", "))
+		}
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q", part)
+		}
+		weights[name] = w
+	}
+	return weights, nil
+}
+
+func categoryNames() []string {
+	names := make([]string, 0, len(DefaultWeights))
+	for name := range DefaultWeights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Compute scores the tallies.
+func Compute(tallies []Tally, weights map[string]float64) Report {
+	r := Report{Weights: weights, Tallies: tallies, Timestamp: time.Now().UTC().Format(time.RFC3339)}
+
+	r.Categories = group(tallies, func(t Tally) string { return t.Category }, weights)
+	r.Precompiles = group(tallies, func(t Tally) string { return t.Precompile }, weights)
+	for _, t := range tallies {
+		if len(t.Skipped) > 0 {
+			if r.Skipped == nil {
+				r.Skipped = skip.Counts{}
+			}
+			r.Skipped.Merge(t.Skipped)
+		}
+	}
+
+	var sum, total float64
+	for _, c := range r.Categories {
+		sum += c.Weight * c.Score
+		total += c.Weight
+	}
+	if total > 0 {
+		r.Score = sum / total
+	}
+	r.Percent = percent(r.Score)
+	return r
+}
+
+// group scores tallies sharing a key: within the group, categories are
+// combined with their weights.
+func group(tallies []Tally, key func(Tally) string, weights map[string]float64) []Part {
+	byKey := map[string][]Tally{}
+	for _, t := range tallies {
+		byKey[key(t)] = append(byKey[key(t)], t)
+	}
+
+	var parts []Part
+	for name, ts := range byKey {
+		// Merge per category first so a category split over several
+		// tallies counts once
+		perCategory := map[string]*Tally{}
+		p := Part{Name: name}
+		for _, t := range ts {
+			c, ok := perCategory[t.Category]
+			if !ok {
+				c = &Tally{Category: t.Category}
+				perCategory[t.Category] = c
+			}
+			c.Passed += t.Passed
+			c.Failed += t.Failed
+			p.Passed += t.Passed
+			p.Failed += t.Failed
+			for _, n := range t.Skipped {
+				p.Skipped += n
+			}
+		}
+		var sum, total float64
+		for cat, c := range perCategory {
+			if c.Passed+c.Failed == 0 {
+				continue
+			}
+			w := weights[cat]
+			sum += w * c.Rate()
+			total += w
+		}
+		if total > 0 {
+			p.Score = sum / total
+			p.Weight = total
+		} else if p.Passed+p.Failed > 0 {
+			// Only zero-weight categories: show the plain pass rate
+			p.Score = float64(p.Passed) / float64(p.Passed+p.Failed)
+		}
+		p.Percent = percent(p.Score)
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Name < parts[j].Name })
+	return parts
+}
+
+// percent formats a score the way the badge shows it, never rounding a
+// score short of perfect up to 100%.
+func percent(score float64) string {
+	p := math.Floor(score*1000) / 10
+	if p == math.Trunc(p) {
+		return fmt.Sprintf("%.0f%%", p)
+	}
+	return fmt.Sprintf("%.1f%%", p)
+}
+
+// Collection is what Collect found.
+type Collection struct {
+	Tallies []Tally
+	// Sources are the results files read, Missing the ones absent or
+	// older than the run.
+	Sources []string
+	Missing []string
+}
+
+// Score computes the report of the collected tallies.
+func (c *Collection) Score(weights map[string]float64) Report {
+	r := Compute(c.Tallies, weights)
+	r.Sources = c.Sources
+	r.Missing = c.Missing
+	return r
+}
+
+// Collect reads the results files in dir written at or after since (zero
+// for all) and tallies them.
+func Collect(dir string, since time.Time) (*Collection, error) {
+	c := new(Collection)
+	for _, col := range collectors {
+		data, err := readFresh(dir, col.file, since)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			c.Missing = append(c.Missing, col.file)
+			continue
+		}
+		ts, err := col.collect(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", col.file, err)
+		}
+		c.Tallies = append(c.Tallies, ts...)
+		c.Sources = append(c.Sources, col.file)
+	}
+	return c, nil
+}
+
+// readFresh reads the results file name in dir, or returns nil if it is
+// missing or older than since.
+func readFresh(dir, name string, since time.Time) ([]byte, error) {
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.ModTime().Before(since)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+type collector struct {
+	file    string
+	collect func([]byte) ([]Tally, error)
+}
+
+// sha256Precompile is what the stages exercise; results written before
+// files recorded their precompile default to it.
+const sha256Precompile = "0x02"
+
+var collectors = []collector{
+	{"results_stage1.json", collectStage1},
+	{"results_stage3.json", collectStage3},
+	{"results_stage4.json", collectStage4},
+	{"results_fuzz.json", collectFuzz},
+	{"results_archive.json", collectArchive},
+	{"results_ecrecover.json", collectECRecover},
+	{"results_modexp.json", collectModExp},
+	{"results_pairing.json", collectPairing},
+	{"results_mutation.json", collectMutation},
+	{"results_multicall.json", collectMulticall},
+	{"results_provenance.json", collectCases(Provenance)},
+	{"results_call_patterns.json", collectCases(CallPattern)},
+	{"results_composed.json", collectCases(Composition)},
+	{"results_memexp.json", collectCases(MemExp)},
+	{"results_undefined.json", collectUndefined},
+	{"results_zerogas.json", collectCases(ZeroGas)},
+	{"results_empty_input.json", collectCases(EmptyInput)},
+	{"results_eip712.json", collectCases(EIP712)},
+	{"results_erc1271.json", collectCases(ERC1271)},
+	{"results_bls.json", collectCases(BLS)},
+	{"results_groth16.json", collectCases(Groth16)},
+	{"results_merkle.json", collectCases(Merkle)},
+	{"results_stage1_plugins.json", collectPlugins},
+	{"results_stage3_plugins.json", collectPlugins},
+	{"results_stage4_plugins.json", collectPlugins},
+}
+
+func collectStage1(data []byte) ([]Tally, error) {
+	var r struct {
+		Precompile string `json:"precompile"`
+		Match      bool   `json:"match"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	t := Tally{Precompile: orDefault(r.Precompile), Category: RawCall}
+	count(&t, r.Match)
+	return []Tally{t}, nil
+}
+
+func collectStage3(data []byte) ([]Tally, error) {
+	var rs []struct {
+		Match   bool         `json:"match"`
+		Skipped bool         `json:"skipped"`
+		Skip    *skip.Reason `json:"skip"`
+	}
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, err
+	}
+	// Vectors beyond the node's eth_call cap were never sent
+	t := Tally{Precompile: sha256Precompile, Category: Wrapper}
+	for _, r := range rs {
+		if r.Skipped {
+			countSkipped(&t, r.Skip)
+		} else {
+			count(&t, r.Match)
+		}
+	}
+	return []Tally{t}, nil
+}
+
+type check struct {
+	Passed  bool         `json:"passed"`
+	Skipped bool         `json:"skipped"`
+	Skip    *skip.Reason `json:"skip"`
+}
+
+func collectStage4(data []byte) ([]Tally, error) {
+	var rs []struct {
+		Passed        bool    `json:"passed"`
+		FeeChecks     []check `json:"feeChecks"`
+		ReceiptChecks []check `json:"receiptChecks"`
+		BlockChecks   []check `json:"blockChecks"`
+	}
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, err
+	}
+	proofs := Tally{Precompile: sha256Precompile, Category: StorageProof}
+	node := Tally{Precompile: sha256Precompile, Category: Conformance}
+	for _, r := range rs {
+		count(&proofs, r.Passed)
+		for _, group := range [][]check{r.FeeChecks, r.ReceiptChecks, r.BlockChecks} {
+			for _, c := range group {
+				if c.Skipped {
+					countSkipped(&node, c.Skip)
+				} else {
+					count(&node, c.Passed)
+				}
+			}
+		}
+	}
+	return []Tally{proofs, node}, nil
+}
+
+func collectFuzz(data []byte) ([]Tally, error) {
+	var r struct {
+		Precompile string `json:"precompile"`
+		Matches    int    `json:"matches"`
+		Mismatches int    `json:"mismatches"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	// Transport errors say nothing about conformance and aren't scored
+	return []Tally{{Precompile: orDefault(r.Precompile), Category: Fuzz, Passed: r.Matches, Failed: r.Mismatches}}, nil
+}
+
+func collectArchive(data []byte) ([]Tally, error) {
+	var r struct {
+		Groups []struct {
+			Checks []check `json:"checks"`
+		} `json:"groups"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	t := Tally{Precompile: sha256Precompile, Category: Archive}
+	for _, g := range r.Groups {
+		for _, c := range g.Checks {
+			count(&t, c.Passed)
+		}
+	}
+	return []Tally{t}, nil
+}
+
+func collectECRecover(data []byte) ([]Tally, error) {
+	var r struct {
+		Precompile string `json:"precompile"`
+		Recoveries int    `json:"recoveries"`
+		Mismatches int    `json:"mismatches"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return []Tally{{Precompile: r.Precompile, Category: RawCall, Passed: r.Recoveries, Failed: r.Mismatches}}, nil
+}
+
+// collectModExp scores correctness only; probes slower than their gas are
+// a performance finding, not a conformance failure.
+func collectModExp(data []byte) ([]Tally, error) {
+	var r struct {
+		Precompile string `json:"precompile"`
+		Matches    int    `json:"matches"`
+		Mismatches int    `json:"mismatches"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return []Tally{{Precompile: r.Precompile, Category: RawCall, Passed: r.Matches, Failed: r.Mismatches}}, nil
+}
+
+// collectPairing scores each measured pair count by its result; counts the
+// node rejected are a capacity finding and pass unless they answered wrong.
+func collectPairing(data []byte) ([]Tally, error) {
+	var r struct {
+		Precompile   string            `json:"precompile"`
+		Steps        []json.RawMessage `json:"steps"`
+		WrongResults int               `json:"wrongResults"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return []Tally{{Precompile: r.Precompile, Category: RawCall, Passed: len(r.Steps) - r.WrongResults, Failed: r.WrongResults}}, nil
+}
+
+func collectMutation(data []byte) ([]Tally, error) {
+	var r struct {
+		Precompiles map[string]struct {
+			Matches    int `json:"matches"`
+			Mismatches int `json:"mismatches"`
+		} `json:"precompiles"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	var ts []Tally
+	for p, c := range r.Precompiles {
+		ts = append(ts, Tally{Precompile: p, Category: Mutation, Passed: c.Matches, Failed: c.Mismatches})
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Precompile < ts[j].Precompile })
+	return ts, nil
+}
+
+func collectMulticall(data []byte) ([]Tally, error) {
+	var r struct {
+		Calls []struct {
+			Precompile string `json:"precompile"`
+			Match      bool   `json:"match"`
+		} `json:"calls"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	tallies := map[string]*Tally{}
+	var ts []Tally
+	for _, c := range r.Calls {
+		t := tallies[c.Precompile]
+		if t == nil {
+			t = &Tally{Precompile: c.Precompile, Category: Multicall}
+			tallies[c.Precompile] = t
+		}
+		count(t, c.Match)
+	}
+	for _, t := range tallies {
+		ts = append(ts, *t)
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Precompile < ts[j].Precompile })
+	return ts, nil
+}
+
+// collectCases tallies a results file listing "cases", each with its
+// precompile and match, per precompile under category.
+func collectCases(category string) func([]byte) ([]Tally, error) {
+	return func(data []byte) ([]Tally, error) {
+		var r struct {
+			Cases []struct {
+				Precompile string `json:"precompile"`
+				Match      bool   `json:"match"`
+			} `json:"cases"`
+		}
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, err
+		}
+		tallies := map[string]*Tally{}
+		var ts []Tally
+		for _, c := range r.Cases {
+			t := tallies[c.Precompile]
+			if t == nil {
+				t = &Tally{Precompile: c.Precompile, Category: category}
+				tallies[c.Precompile] = t
+			}
+			count(t, c.Match)
+		}
+		for _, t := range tallies {
+			ts = append(ts, *t)
+		}
+		sort.Slice(ts, func(i, j int) bool { return ts[i].Precompile < ts[j].Precompile })
+		return ts, nil
+	}
+}
+
+// collectUndefined tallies every undefined address under one "undefined"
+// entry rather than one per address.
+func collectUndefined(data []byte) ([]Tally, error) {
+	var r struct {
+		Matches    int `json:"matches"`
+		Mismatches int `json:"mismatches"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return []Tally{{Precompile: "undefined", Category: Undefined, Passed: r.Matches, Failed: r.Mismatches}}, nil
+}
+
+// collectPlugins tallies the verdicts of external plugins per precompile.
+// A plugin that failed to answer says nothing about the node and isn't
+// scored.
+func collectPlugins(data []byte) ([]Tally, error) {
+	var r struct {
+		Verdicts []struct {
+			Precompile string `json:"precompile"`
+			Verdict    string `json:"verdict"`
+		} `json:"verdicts"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	tallies := map[string]*Tally{}
+	var ts []Tally
+	for _, v := range r.Verdicts {
+		t := tallies[v.Precompile]
+		if t == nil {
+			t = &Tally{Precompile: orDefault(v.Precompile), Category: Plugin}
+			tallies[v.Precompile] = t
+		}
+		switch v.Verdict {
+		case "pass":
+			count(t, true)
+		case "fail":
+			count(t, false)
+		case "skip":
+			countSkipped(t, skip.New(skip.NotApplicable, ""))
+		}
+	}
+	for _, t := range tallies {
+		ts = append(ts, *t)
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Precompile < ts[j].Precompile })
+	return ts, nil
+}
+
+func count(t *Tally, passed bool) {
+	if passed {
+		t.Passed++
+	} else {
+		t.Failed++
+	}
+}
+
+// countSkipped counts a check skipped for r; results written before
+// reasons were recorded count as skip.Unspecified.
+func countSkipped(t *Tally, r *skip.Reason) {
+	if t.Skipped == nil {
+		t.Skipped = skip.Counts{}
+	}
+	t.Skipped.Add(r)
+}
+
+func orDefault(precompile string) string {
+	if precompile == "" {
+		return sha256Precompile
+	}
+	return precompile
+}
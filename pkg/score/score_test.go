@@ -0,0 +1,199 @@
+package score
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cdk-erigon-precompile/pkg/skip"
+)
+
+func TestComputeWeightsCategories(t *testing.T) {
+	weights := map[string]float64{RawCall: 3, Fuzz: 1}
+	r := Compute([]Tally{
+		{Precompile: "0x02", Category: RawCall, Passed: 1},
+		{Precompile: "0x02", Category: Fuzz, Passed: 50, Failed: 50},
+		// Categories without weight don't move the overall score
+		{Precompile: "0x02", Category: Archive, Failed: 10},
+	}, weights)
+
+	if want := (3*1.0 + 1*0.5) / 4; r.Score != want {
+		t.Errorf("score %v, want %v", r.Score, want)
+	}
+	if r.Percent != "87.5%" {
+		t.Errorf("percent %q", r.Percent)
+	}
+	if len(r.Precompiles) != 1 || r.Precompiles[0].Score != r.Score {
+		t.Errorf("precompiles %+v", r.Precompiles)
+	}
+}
+
+func TestComputeSkipsEmptyCategories(t *testing.T) {
+	r := Compute([]Tally{
+		{Precompile: "0x02", Category: RawCall, Passed: 1},
+		{Precompile: "0x02", Category: Wrapper},
+	}, DefaultWeights)
+	if r.Score != 1 || r.Percent != "100%" {
+		t.Errorf("score %v (%s), want a perfect score", r.Score, r.Percent)
+	}
+}
+
+func TestPercentNeverRoundsUpToPerfect(t *testing.T) {
+	if p := percent(0.9999); p != "99.9%" {
+		t.Errorf("got %s", p)
+	}
+	if p := percent(0.98); p != "98%" {
+		t.Errorf("got %s", p)
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	w, err := ParseWeights("wrapper=5, fuzz=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w[Wrapper] != 5 || w[Fuzz] != 0 || w[RawCall] != DefaultWeights[RawCall] {
+		t.Errorf("weights %v", w)
+	}
+	for _, bad := range []string{"nope=1", "wrapper", "wrapper=-1", "wrapper=x"} {
+		if _, err := ParseWeights(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("results_stage1.json", `{"precompile":"0x02","match":true}`)
+	write("results_stage3.json", `[{"match":true},{"match":false},{"skipped":true,"skip":{"code":"gas-cap"}}]`)
+	write("results_fuzz.json", `{"precompile":"0x02","matches":9,"mismatches":1,"errors":5}`)
+	write("results_stage4.json", `[{"passed":true,"feeChecks":[{"passed":true},{"passed":false,"skipped":true}]}]`)
+	write("results_ecrecover.json", `{"precompile":"0x01","recoveries":4,"mismatches":0,"errors":2}`)
+	write("results_mutation.json", `{"precompiles":{"0x02":{"matches":70,"mismatches":2},"0x05":{"matches":30,"mismatches":0}}}`)
+	write("results_multicall.json", `{"calls":[{"precompile":"0x02","match":true},{"precompile":"0x08","match":true},{"precompile":"0x02","match":false}]}`)
+	write("results_provenance.json", `{"cases":[{"precompile":"0x04","match":true},{"precompile":"0x04","match":false}]}`)
+	write("results_call_patterns.json", `{"cases":[{"precompile":"0x01","match":true},{"precompile":"0x01","match":false}]}`)
+	write("results_composed.json", `{"cases":[{"precompile":"0x02","match":true},{"precompile":"0x02","match":true}]}`)
+	write("results_memexp.json", `{"cases":[{"precompile":"0x02","match":false},{"precompile":"0x04","match":true}]}`)
+	write("results_undefined.json", `{"matches":28,"mismatches":2}`)
+	write("results_zerogas.json", `{"cases":[{"precompile":"0x09","match":true},{"precompile":"0x09","match":true}]}`)
+	write("results_empty_input.json", `{"cases":[{"precompile":"0x0a","match":true},{"precompile":"0x09","match":false}]}`)
+	write("results_eip712.json", `{"cases":[{"precompile":"0x01","match":true},{"precompile":"0x01","match":false}]}`)
+	write("results_erc1271.json", `{"cases":[{"precompile":"0x01","match":true},{"precompile":"0x01","match":true}]}`)
+	write("results_bls.json", `{"cases":[{"precompile":"0x0b","match":true},{"precompile":"0x0f","match":false}]}`)
+	write("results_groth16.json", `{"cases":[{"precompile":"0x08","match":true},{"precompile":"0x08","match":true},{"precompile":"0x08","match":false}]}`)
+	write("results_merkle.json", `{"cases":[{"precompile":"0x02","match":true},{"precompile":"0x02","match":false}]}`)
+	write("results_pairing.json", `{"precompile":"0x08","steps":[{},{},{}],"wrongResults":1}`)
+	write("results_stage1_plugins.json", `{"verdicts":[{"precompile":"0x02","verdict":"pass"}]}`)
+	write("results_stage3_plugins.json", `{"verdicts":[{"precompile":"0x02","verdict":"fail"},{"precompile":"0x02","verdict":"skip"},{"precompile":"0x02","error":"exit status 2"}]}`)
+	write("results_stage4_plugins.json", `{"verdicts":[{"precompile":"0x02","verdict":"pass"}]}`)
+	write("results_modexp.json", `{"precompile":"0x05","matches":10,"mismatches":1,"slow":3}`)
+
+	// A results file from an earlier run is ignored
+	write("results_archive.json", `{"groups":[{"checks":[{"passed":false}]}]}`)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "results_archive.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Collect(dir, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(c.Missing, ",") != "results_archive.json" {
+		t.Errorf("missing %v", c.Missing)
+	}
+	got := map[string]Tally{}
+	for _, tally := range c.Tallies {
+		got[tally.Precompile+" "+tally.Category] = tally
+	}
+	for cat, want := range map[string][2]int{
+		"0x02 " + RawCall: {1, 0}, "0x02 " + Wrapper: {1, 1}, "0x02 " + Fuzz: {9, 1},
+		"0x02 " + StorageProof: {1, 0}, "0x02 " + Conformance: {1, 0}, "0x01 " + RawCall: {4, 0},
+		"0x05 " + RawCall: {10, 1}, "0x08 " + RawCall: {2, 1},
+		"0x02 " + Mutation: {70, 2}, "0x05 " + Mutation: {30, 0},
+		"0x02 " + Multicall: {1, 1}, "0x08 " + Multicall: {1, 0},
+		"0x04 " + Provenance: {1, 1}, "0x01 " + CallPattern: {1, 1}, "0x02 " + Composition: {2, 0},
+		"0x02 " + MemExp: {0, 1}, "0x04 " + MemExp: {1, 0},
+		"0x0a " + EmptyInput: {1, 0}, "0x09 " + EmptyInput: {0, 1},
+		"0x01 " + EIP712: {1, 1}, "0x01 " + ERC1271: {2, 0},
+		"0x0b " + BLS: {1, 0}, "0x0f " + BLS: {0, 1}, "0x08 " + Groth16: {2, 1},
+		"0x02 " + Merkle: {1, 1},
+	} {
+		if got[cat].Passed != want[0] || got[cat].Failed != want[1] {
+			t.Errorf("%s: %+v, want %v", cat, got[cat], want)
+		}
+	}
+	// Skipped checks are counted by reason, not scored
+	if n := got["0x02 "+Wrapper].Skipped[skip.GasCap]; n != 1 {
+		t.Errorf("wrapper skipped for gas cap: %d, want 1", n)
+	}
+	if n := got["0x02 "+Conformance].Skipped[skip.Unspecified]; n != 1 {
+		t.Errorf("conformance skipped without a reason: %d, want 1", n)
+	}
+	r := c.Score(DefaultWeights)
+	if r.Skipped[skip.GasCap] != 1 || r.Skipped[skip.Unspecified] != 1 || r.Skipped[skip.NotApplicable] != 1 {
+		t.Errorf("report skipped %v", r.Skipped)
+	}
+	// Every stage's plugin verdicts add up; unanswered ones aren't scored
+	var plugins Part
+	for _, part := range r.Categories {
+		if part.Name == Plugin {
+			plugins = part
+		}
+	}
+	if plugins.Passed != 2 || plugins.Failed != 1 {
+		t.Errorf("plugin category %+v, want 2 passed and 1 failed", plugins)
+	}
+}
+
+func TestBadges(t *testing.T) {
+	r := Report{Score: 0.98, Percent: "98%"}
+	data, err := BadgeJSON(DefaultLabel, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"message": "98%"`) || !strings.Contains(string(data), `"color": "green"`) {
+		t.Errorf("badge JSON %s", data)
+	}
+	svg := string(BadgeSVG("a<b", r))
+	if !strings.Contains(svg, "a&lt;b: 98%") || !strings.HasPrefix(svg, "<svg") {
+		t.Errorf("badge SVG %s", svg)
+	}
+}
+
+func TestCollectLatency(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("results_benchmark.json", `{"target":"raw","summary":{"samples":50,"meanMs":2.5,"minMs":1,"maxMs":9}}`)
+	write("results_ecrecover.json", `{"precompile":"0x01","latency":{"samples":0}}`)
+	write("results_fuzz.json", `{"precompile":"0x02","latencyMs":{"count":300,"min":0.5,"max":12,"mean":3}}`)
+
+	got, err := CollectLatency(dir, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Latency{
+		{Precompile: "0x02", Source: "benchmark-raw", Samples: 50, MeanMs: 2.5, MinMs: 1, MaxMs: 9},
+		{Precompile: "0x02", Source: "fuzz", Samples: 300, MeanMs: 3, MinMs: 0.5, MaxMs: 12},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("latency %d: %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
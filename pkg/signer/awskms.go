@@ -0,0 +1,214 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// awsKMS signs with an asymmetric ECC_SECG_P256K1 key in AWS KMS. It reads
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, optionally AWS_SESSION_TOKEN,
+// and AWS_REGION (or the region of a key ARN). AWS_KMS_ENDPOINT overrides
+// the endpoint, e.g. for localstack.
+type awsKMS struct {
+	keyID    string
+	region   string
+	endpoint string
+	creds    awsCredentials
+	pub      *ecdsa.PublicKey
+	addr     common.Address
+}
+
+type awsCredentials struct {
+	accessKey, secretKey, sessionToken string
+}
+
+func openAWSKMS(ctx context.Context, keyID string) (Signer, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	// arn:aws:kms:<region>:<account>:key/<id>
+	if parts := strings.Split(keyID, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return nil, fmt.Errorf("awskms signer needs AWS_REGION")
+	}
+	k := &awsKMS{
+		keyID:    keyID,
+		region:   region,
+		endpoint: os.Getenv("AWS_KMS_ENDPOINT"),
+		creds:    awsCredentials{os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN")},
+	}
+	if k.creds.accessKey == "" || k.creds.secretKey == "" {
+		return nil, fmt.Errorf("awskms signer needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if k.endpoint == "" {
+		k.endpoint = "https://kms." + region + ".amazonaws.com"
+	}
+
+	var out struct {
+		KeySpec   string
+		PublicKey []byte
+	}
+	if err := k.call(ctx, "GetPublicKey", map[string]any{"KeyId": keyID}, &out); err != nil {
+		return nil, err
+	}
+	if out.KeySpec != "" && out.KeySpec != "ECC_SECG_P256K1" {
+		return nil, fmt.Errorf("KMS key %s is %s, not ECC_SECG_P256K1", keyID, out.KeySpec)
+	}
+	pub, err := parsePublicKey(out.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("KMS key %s: %w", keyID, err)
+	}
+	k.pub, k.addr = pub, crypto.PubkeyToAddress(*pub)
+	return k, nil
+}
+
+func (k *awsKMS) Address() common.Address { return k.addr }
+
+func (k *awsKMS) SignHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	var out struct{ Signature []byte }
+	in := map[string]any{
+		"KeyId":            k.keyID,
+		"Message":          hash[:],
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": "ECDSA_SHA_256",
+	}
+	if err := k.call(ctx, "Sign", in, &out); err != nil {
+		return nil, err
+	}
+	return fromDER(hash, out.Signature, k.pub)
+}
+
+// call invokes a KMS JSON API action.
+func (k *awsKMS) call(ctx context.Context, action string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	signV4(req, body, k.creds, k.region, "kms", time.Now())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("KMS %s failed: %w", action, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read KMS response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &e)
+		return fmt.Errorf("KMS %s returned %s: %s %s", action, resp.Status, e.Type, e.Message)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse KMS %s response: %w", action, err)
+	}
+	return nil
+}
+
+// signV4 adds AWS Signature Version 4 headers to req, signing every header
+// already set plus host and x-amz-date.
+func signV4(req *http.Request, body []byte, creds awsCredentials, region, service string, t time.Time) {
+	amzDate, date := t.UTC().Format("20060102T150405Z"), t.UTC().Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, key := range keys {
+		for _, v := range query[key] {
+			params = append(params, awsEscape(key)+"="+awsEscape(v))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, strings.Join(params, "&"), canonicalHeaders.String(), signedHeaders, sha256Hex(body),
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// awsEscape percent-encodes everything but RFC 3986 unreserved characters.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,60 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// secp256k1N is the curve order; signatures with S above half of it are
+// rejected by Ethereum (EIP-2).
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// parsePublicKey decodes a DER SubjectPublicKeyInfo holding a secp256k1
+// key, which crypto/x509 can't parse.
+func parsePublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var spki struct {
+		Algorithm asn1.RawValue
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	pub, err := crypto.UnmarshalPubkey(spki.PublicKey.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("not a secp256k1 public key: %w", err)
+	}
+	return pub, nil
+}
+
+// fromDER converts a DER ECDSA signature of hash by pub to the [R || S || V]
+// form: S is normalised to the lower half of the order and V found by
+// recovering the public key.
+func fromDER(hash common.Hash, der []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	var rs struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(der, &rs); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("invalid DER signature: %v", err)
+	}
+	if rs.S.Cmp(secp256k1HalfN) > 0 {
+		rs.S = new(big.Int).Sub(secp256k1N, rs.S)
+	}
+	sig := make([]byte, 65)
+	rs.R.FillBytes(sig[:32])
+	rs.S.FillBytes(sig[32:64])
+	want := crypto.FromECDSAPub(pub)
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		if got, err := crypto.Ecrecover(hash[:], sig); err == nil && string(got) == string(want) {
+			return sig, nil
+		}
+	}
+	return nil, errors.New("signature does not recover to the key's public key")
+}
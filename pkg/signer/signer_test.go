@@ -0,0 +1,190 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The example request of the AWS Signature Version 4 documentation.
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds := awsCredentials{accessKey: "AKIDEXAMPLE", secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(req, nil, creds, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization:\n%s\nwant\n%s", got, want)
+	}
+}
+
+// spki encodes pub as a DER SubjectPublicKeyInfo, as KMS returns it.
+func spki(t *testing.T, pub *ecdsa.PublicKey) []byte {
+	t.Helper()
+	type algorithm struct {
+		Algorithm, Curve asn1.ObjectIdentifier
+	}
+	der, err := asn1.Marshal(struct {
+		Algorithm algorithm
+		PublicKey asn1.BitString
+	}{
+		algorithm{asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}, asn1.ObjectIdentifier{1, 3, 132, 0, 10}},
+		asn1.BitString{Bytes: crypto.FromECDSAPub(pub), BitLength: 65 * 8},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// derSign signs like KMS: DER encoded, and with a high S half the time.
+func derSign(t *testing.T, key *ecdsa.PrivateKey, digest []byte) []byte {
+	t.Helper()
+	sig, err := crypto.Sign(digest, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if digest[0]&1 == 1 {
+		s.Sub(secp256k1N, s)
+	}
+	der, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// checkSigner signs transactions with s and checks they recover to key.
+func checkSigner(t *testing.T, s Signer, key *ecdsa.PrivateKey) {
+	t.Helper()
+	want := crypto.PubkeyToAddress(key.PublicKey)
+	if s.Address() != want {
+		t.Fatalf("address %s, want %s", s.Address().Hex(), want.Hex())
+	}
+	chainID := big.NewInt(10101)
+	for nonce := uint64(0); nonce < 8; nonce++ {
+		tx := types.NewTx(&types.LegacyTx{Nonce: nonce, GasPrice: big.NewInt(1e9), Gas: 21_000, To: &common.Address{1}, Value: big.NewInt(0)})
+		signed, err := SignTx(context.Background(), s, tx, chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, err := types.Sender(types.NewEIP155Signer(chainID), signed)
+		if err != nil || from != want {
+			t.Fatalf("nonce %d: sender %s, %v", nonce, from.Hex(), err)
+		}
+	}
+}
+
+func TestAWSKMS(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			http.Error(w, `{"__type":"AccessDeniedException"}`, http.StatusForbidden)
+			return
+		}
+		var in struct {
+			KeyId       string
+			Message     []byte
+			MessageType string
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			json.NewEncoder(w).Encode(map[string]any{"KeySpec": "ECC_SECG_P256K1", "PublicKey": spki(t, &key.PublicKey)})
+		case "TrentService.Sign":
+			if in.MessageType != "DIGEST" || len(in.Message) != 32 {
+				http.Error(w, `{"__type":"ValidationException"}`, http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"Signature": derSign(t, key, in.Message)})
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_KMS_ENDPOINT", srv.URL)
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	s, err := Open(context.Background(), "awskms://alias/deployer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSigner(t, s, key)
+}
+
+func TestGCPKMS(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	const name = "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/" + name + "/publicKey":
+			pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki(t, &key.PublicKey)})
+			json.NewEncoder(w).Encode(map[string]any{"pem": string(pemKey), "algorithm": "EC_SIGN_SECP256K1_SHA256"})
+		case "/v1/" + name + ":asymmetricSign":
+			var in struct {
+				Digest struct{ SHA256 []byte } `json:"digest"`
+			}
+			json.NewDecoder(r.Body).Decode(&in)
+			json.NewEncoder(w).Encode(map[string]any{"signature": derSign(t, key, in.Digest.SHA256)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("GCP_KMS_ENDPOINT", srv.URL)
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "token")
+
+	s, err := Open(context.Background(), "gcpkms://"+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSigner(t, s, key)
+}
+
+func TestVault(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" || r.URL.Path != "/v1/secret/data/ci/deployer" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": map[string]any{
+			"key": "0x" + common.Bytes2Hex(crypto.FromECDSA(key)),
+		}}})
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "token")
+
+	s, err := Open(context.Background(), "vault://secret/ci/deployer#key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSigner(t, s, key)
+
+	if _, err := Open(context.Background(), "vault://secret/ci/other"); err == nil {
+		t.Error("opened a secret the token can't read")
+	}
+}
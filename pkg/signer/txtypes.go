@@ -0,0 +1,200 @@
+package signer
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+// TxFields are the fields a transaction of any type is built from. Fields
+// a type doesn't carry are ignored.
+type TxFields struct {
+	ChainID *big.Int
+	Nonce   uint64
+	// To is nil for a contract creation.
+	To    *common.Address
+	Value *big.Int
+	Data  []byte
+	Gas   uint64
+	// GasPrice is the gas price of legacy and access-list transactions
+	// and the fee cap of the others.
+	GasPrice *big.Int
+	// TipCap is the priority fee of dynamic fee and blob transactions;
+	// nil tips the whole fee cap.
+	TipCap     *big.Int
+	AccessList types.AccessList
+
+	// BlobFeeCap, BlobHashes and Sidecar are only used by blob
+	// transactions.
+	BlobFeeCap *big.Int
+	BlobHashes []common.Hash
+	Sidecar    *types.BlobTxSidecar
+}
+
+// TxType builds transactions of one EIP-2718 type. Senders take one so a
+// new type only needs a TxType, not changes to the deployment code.
+type TxType interface {
+	// Name is the name ParseTxType accepts, e.g. "dynamic-fee".
+	Name() string
+	// Type is the EIP-2718 type byte.
+	Type() uint8
+	Build(f TxFields) (types.TxData, error)
+}
+
+// Transaction types.
+var (
+	Legacy     TxType = legacyType{}
+	AccessList TxType = accessListType{}
+	DynamicFee TxType = dynamicFeeType{}
+	Blob       TxType = blobType{}
+)
+
+// TxTypes lists the supported types in type byte order.
+var TxTypes = []TxType{Legacy, AccessList, DynamicFee, Blob}
+
+// eips are the EIPs introducing each type, also accepted by ParseTxType.
+var eips = map[uint8]string{
+	types.LegacyTxType:     "eip155",
+	types.AccessListTxType: "eip2930",
+	types.DynamicFeeTxType: "eip1559",
+	types.BlobTxType:       "eip4844",
+}
+
+// ParseTxType returns the type named s: its name (legacy, access-list,
+// dynamic-fee, blob), its EIP (eip155, eip2930, eip1559, eip4844) or its
+// type byte (0 to 3). An empty s is legacy.
+func ParseTxType(s string) (TxType, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Legacy, nil
+	}
+	for _, t := range TxTypes {
+		if s == t.Name() || s == eips[t.Type()] || s == strconv.Itoa(int(t.Type())) {
+			return t, nil
+		}
+	}
+	names := make([]string, len(TxTypes))
+	for i, t := range TxTypes {
+		names[i] = t.Name()
+	}
+	return nil, fmt.Errorf("unknown transaction type %q (want %s, an EIP or a type byte)", s, strings.Join(names, ", "))
+}
+
+// ChainSigner is the signer of every transaction type on chainID, under the
+// latest rules: legacy transactions are replay-protected as in EIP-155.
+func ChainSigner(chainID *big.Int) types.Signer {
+	return types.LatestSignerForChainID(chainID)
+}
+
+type legacyType struct{}
+
+func (legacyType) Name() string { return "legacy" }
+func (legacyType) Type() uint8  { return types.LegacyTxType }
+
+func (legacyType) Build(f TxFields) (types.TxData, error) {
+	return &types.LegacyTx{
+		Nonce:    f.Nonce,
+		GasPrice: f.GasPrice,
+		Gas:      f.Gas,
+		To:       f.To,
+		Value:    orZero(f.Value),
+		Data:     f.Data,
+	}, nil
+}
+
+type accessListType struct{}
+
+func (accessListType) Name() string { return "access-list" }
+func (accessListType) Type() uint8  { return types.AccessListTxType }
+
+func (accessListType) Build(f TxFields) (types.TxData, error) {
+	return &types.AccessListTx{
+		ChainID:    f.ChainID,
+		Nonce:      f.Nonce,
+		GasPrice:   f.GasPrice,
+		Gas:        f.Gas,
+		To:         f.To,
+		Value:      orZero(f.Value),
+		Data:       f.Data,
+		AccessList: f.AccessList,
+	}, nil
+}
+
+type dynamicFeeType struct{}
+
+func (dynamicFeeType) Name() string { return "dynamic-fee" }
+func (dynamicFeeType) Type() uint8  { return types.DynamicFeeTxType }
+
+func (dynamicFeeType) Build(f TxFields) (types.TxData, error) {
+	return &types.DynamicFeeTx{
+		ChainID:    f.ChainID,
+		Nonce:      f.Nonce,
+		GasTipCap:  tipCap(f),
+		GasFeeCap:  f.GasPrice,
+		Gas:        f.Gas,
+		To:         f.To,
+		Value:      orZero(f.Value),
+		Data:       f.Data,
+		AccessList: f.AccessList,
+	}, nil
+}
+
+type blobType struct{}
+
+func (blobType) Name() string { return "blob" }
+func (blobType) Type() uint8  { return types.BlobTxType }
+
+// Build fails for contract creations and transactions without blobs, which
+// blob transactions can't be. Fee fields must fit in 256 bits.
+func (blobType) Build(f TxFields) (types.TxData, error) {
+	if f.To == nil {
+		return nil, fmt.Errorf("blob transactions can't create contracts")
+	}
+	hashes := f.BlobHashes
+	if len(hashes) == 0 && f.Sidecar != nil {
+		hashes = f.Sidecar.BlobHashes()
+	}
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("blob transaction without blobs")
+	}
+	var values [5]*uint256.Int
+	for i, v := range []*big.Int{f.ChainID, tipCap(f), f.GasPrice, orZero(f.Value), orZero(f.BlobFeeCap)} {
+		var overflow bool
+		if values[i], overflow = uint256.FromBig(orZero(v)); overflow {
+			return nil, fmt.Errorf("blob transaction field %d overflows 256 bits", i)
+		}
+	}
+	return &types.BlobTx{
+		ChainID:    values[0],
+		Nonce:      f.Nonce,
+		GasTipCap:  values[1],
+		GasFeeCap:  values[2],
+		Gas:        f.Gas,
+		To:         *f.To,
+		Value:      values[3],
+		Data:       f.Data,
+		AccessList: f.AccessList,
+		BlobFeeCap: values[4],
+		BlobHashes: hashes,
+		Sidecar:    f.Sidecar,
+	}, nil
+}
+
+func tipCap(f TxFields) *big.Int {
+	if f.TipCap != nil {
+		return f.TipCap
+	}
+	return f.GasPrice
+}
+
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestParseTxType(t *testing.T) {
+	for in, want := range map[string]TxType{
+		"":             Legacy,
+		"legacy":       Legacy,
+		"EIP155":       Legacy,
+		"1":            AccessList,
+		"eip2930":      AccessList,
+		" dynamic-fee": DynamicFee,
+		"2":            DynamicFee,
+		"eip4844":      Blob,
+	} {
+		got, err := ParseTxType(in)
+		if err != nil || got != want {
+			t.Errorf("ParseTxType(%q) = %v, %v, want %s", in, got, err, want.Name())
+		}
+	}
+	if _, err := ParseTxType("set-code"); err == nil {
+		t.Error("accepted an unsupported type")
+	}
+}
+
+func TestTxTypesSign(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	s := NewLocal(key)
+	chainID := big.NewInt(10101)
+	to := common.Address{1}
+	for _, txType := range TxTypes {
+		data, err := txType.Build(TxFields{
+			ChainID:    chainID,
+			Nonce:      3,
+			To:         &to,
+			Gas:        50_000,
+			GasPrice:   big.NewInt(2e9),
+			TipCap:     big.NewInt(1e9),
+			BlobFeeCap: big.NewInt(1),
+			BlobHashes: []common.Hash{{0x01}},
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", txType.Name(), err)
+		}
+		signed, err := SignTx(context.Background(), s, types.NewTx(data), chainID)
+		if err != nil {
+			t.Fatalf("%s: %v", txType.Name(), err)
+		}
+		if signed.Type() != txType.Type() {
+			t.Errorf("%s: built type %d", txType.Name(), signed.Type())
+		}
+		from, err := types.Sender(ChainSigner(chainID), signed)
+		if err != nil || from != s.Address() {
+			t.Errorf("%s: sender %s, %v", txType.Name(), from.Hex(), err)
+		}
+		if signed.Type() != types.LegacyTxType && signed.ChainId().Cmp(chainID) != 0 {
+			t.Errorf("%s: chain ID %s", txType.Name(), signed.ChainId())
+		}
+	}
+
+	// A legacy transaction signed under the latest rules is still EIP-155
+	data, _ := Legacy.Build(TxFields{ChainID: chainID, To: &to, Gas: 21_000, GasPrice: big.NewInt(1)})
+	signed, _ := SignTx(context.Background(), s, types.NewTx(data), chainID)
+	if !signed.Protected() || signed.ChainId().Cmp(chainID) != 0 {
+		t.Errorf("legacy transaction not replay-protected: chain ID %s", signed.ChainId())
+	}
+}
+
+func TestBlobBuildRejects(t *testing.T) {
+	to := common.Address{1}
+	if _, err := Blob.Build(TxFields{ChainID: big.NewInt(1), GasPrice: big.NewInt(1), BlobHashes: []common.Hash{{1}}}); err == nil {
+		t.Error("built a blob contract creation")
+	}
+	if _, err := Blob.Build(TxFields{ChainID: big.NewInt(1), GasPrice: big.NewInt(1), To: &to}); err == nil {
+		t.Error("built a blob transaction without blobs")
+	}
+}
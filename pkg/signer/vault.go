@@ -0,0 +1,68 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// openVault reads a hex private key from a Vault KV v2 secret, referenced as
+// <mount>/<path>#<field> (field defaults to private_key), using VAULT_ADDR,
+// VAULT_TOKEN and optionally VAULT_NAMESPACE. The key is only held in
+// memory.
+func openVault(ctx context.Context, ref string) (Signer, error) {
+	addr, token := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return nil, fmt.Errorf("vault signer needs VAULT_ADDR and VAULT_TOKEN")
+	}
+	secret, field, _ := strings.Cut(ref, "#")
+	if field == "" {
+		field = "private_key"
+	}
+	mount, path, ok := strings.Cut(strings.Trim(secret, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("vault reference %q needs a mount and a path", ref)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+mount+"/data/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %s for %s/%s", resp.Status, mount, path)
+	}
+	var kv struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &kv); err != nil {
+		return nil, fmt.Errorf("failed to parse vault response: %w", err)
+	}
+	key, ok := kv.Data.Data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("vault secret %s/%s has no string field %q", mount, path, field)
+	}
+	local, err := ParseLocal(key)
+	if err != nil {
+		return nil, fmt.Errorf("vault secret %s/%s: %w", mount, path, err)
+	}
+	return local, nil
+}
@@ -0,0 +1,137 @@
+// Package skip gives every check that wasn't run, or didn't run to the
+// end, a machine-readable reason, so results and reports tell "not run"
+// apart from "passed" and say why.
+package skip
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// Code is why a check wasn't run.
+type Code string
+
+const (
+	// Capability: the node lacks a method, state or precompile the check
+	// needs.
+	Capability Code = "capability-missing"
+	// TagFilter: --include-tags/--exclude-tags left the check out.
+	TagFilter Code = "tag-filter"
+	// Budget: the suite's --time-budget had no room for it.
+	Budget Code = "budget-exceeded"
+	// XFail: the check is known to fail on this node and isn't held
+	// against it.
+	XFail Code = "xfail"
+	// GasCap: the input is beyond what the node accepts in an eth_call.
+	GasCap Code = "gas-cap"
+	// FailurePolicy: --fail-fast or --max-failures stopped the run first.
+	FailurePolicy Code = "failure-policy"
+	// Interrupted: the run was cancelled first.
+	Interrupted Code = "interrupted"
+	// Prerequisite: there is nothing to check yet, such as a deployment,
+	// transaction or block an earlier step makes.
+	Prerequisite Code = "prerequisite-missing"
+	// NotApplicable: the check doesn't apply to this chain or case.
+	NotApplicable Code = "not-applicable"
+	// Unspecified is counted for results written before reasons were
+	// recorded.
+	Unspecified Code = "unspecified"
+)
+
+// Reason is why a check wasn't run: a Code, and a Detail for people.
+type Reason struct {
+	Code   Code   `json:"code"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// New returns a reason with detail.
+func New(code Code, detail string) *Reason {
+	return &Reason{Code: code, Detail: detail}
+}
+
+// Newf returns a reason with a formatted detail.
+func Newf(code Code, format string, args ...any) *Reason {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+func (r *Reason) String() string {
+	switch {
+	case r == nil:
+		return ""
+	case r.Detail == "":
+		return string(r.Code)
+	}
+	return fmt.Sprintf("%s: %s", r.Code, r.Detail)
+}
+
+// Counts tallies skipped checks by reason code.
+type Counts map[Code]int
+
+// Add counts one check skipped for r; a nil r counts as Unspecified.
+func (c Counts) Add(r *Reason) {
+	if r == nil {
+		c[Unspecified]++
+		return
+	}
+	c[r.Code]++
+}
+
+// Merge adds the counts of o.
+func (c Counts) Merge(o Counts) {
+	for code, n := range o {
+		c[code] += n
+	}
+}
+
+// String lists the counts by code, e.g. "budget-exceeded 2, tag-filter 1".
+func (c Counts) String() string {
+	parts := make([]string, 0, len(c))
+	for code, n := range c {
+		parts = append(parts, fmt.Sprintf("%s %d", code, n))
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ", ")
+}
+
+// EnvReport names the file where a script that skips all of its checks
+// records why. The suite runner sets it for every group, so a group that
+// skipped itself is reported as skipped rather than passed.
+const EnvReport = "SKIP_REPORT"
+
+// Record writes r to the EnvReport file, if one is set.
+func Record(r *Reason) error {
+	path := os.Getenv(EnvReport)
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := paths.WriteFile(path, data); err != nil {
+		return fmt.Errorf("failed to record skip reason: %w", err)
+	}
+	return nil
+}
+
+// ReadReport reads the reason recorded at path, or nil if nothing was.
+func ReadReport(path string) (*Reason, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Reason
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,61 @@
+package skip
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReason(t *testing.T) {
+	if got := Newf(GasCap, "%d bytes", 40000).String(); got != "gas-cap: 40000 bytes" {
+		t.Errorf("String = %q", got)
+	}
+	if got := New(Interrupted, "").String(); got != "interrupted" {
+		t.Errorf("String = %q", got)
+	}
+	var none *Reason
+	if got := none.String(); got != "" {
+		t.Errorf("nil String = %q", got)
+	}
+
+	c := Counts{}
+	c.Add(New(TagFilter, "smoke"))
+	c.Add(New(TagFilter, "gas"))
+	c.Add(nil)
+	c.Merge(Counts{Budget: 2})
+	want := Counts{TagFilter: 2, Unspecified: 1, Budget: 2}
+	if len(c) != len(want) {
+		t.Fatalf("counts %v, want %v", c, want)
+	}
+	for code, n := range want {
+		if c[code] != n {
+			t.Errorf("%s: %d, want %d", code, c[code], n)
+		}
+	}
+	if got := c.String(); got != "budget-exceeded 2, tag-filter 2, unspecified 1" {
+		t.Errorf("String = %q", got)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "skip.json")
+
+	t.Setenv(EnvReport, "")
+	if err := Record(New(TagFilter, "x")); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := ReadReport(path); r != nil || err != nil {
+		t.Fatalf("recorded without %s: %v, %v", EnvReport, r, err)
+	}
+
+	t.Setenv(EnvReport, path)
+	if err := Record(Newf(Capability, "no %s", "debug_traceCall")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ReadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.Code != Capability || r.Detail != "no debug_traceCall" {
+		t.Errorf("read back %+v", r)
+	}
+}
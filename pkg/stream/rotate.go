@@ -0,0 +1,224 @@
+package stream
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// RotateOptions controls when a stream file is rotated and what happens to
+// the rotated segments.
+type RotateOptions struct {
+	// MaxBytes rotates once the active file reaches this size (0 disables).
+	MaxBytes int64
+	// MaxAge rotates once the active file has been open this long (0 disables).
+	MaxAge time.Duration
+	// MaxBackups keeps at most this many rotated segments (0 keeps all).
+	MaxBackups int
+	// Compress gzips rotated segments.
+	Compress bool
+}
+
+// Enabled reports whether any rotation trigger is configured.
+func (o RotateOptions) Enabled() bool {
+	return o.MaxBytes > 0 || o.MaxAge > 0
+}
+
+// RotatingFile is an append-only file that rolls over to a timestamped
+// segment by size or age. It is safe for concurrent use.
+type RotatingFile struct {
+	mu     sync.Mutex
+	path   string
+	opts   RotateOptions
+	file   *os.File
+	size   int64
+	opened time.Time
+	// rotated feeds rotated segments to the maintain goroutine, which
+	// closes done once rotated is closed and drained. Both are nil when
+	// segments are neither compressed nor pruned.
+	rotated chan string
+	done    chan struct{}
+}
+
+// maintainQueue is how many rotated segments may wait for compression and
+// pruning before a rotation blocks the write causing it.
+const maintainQueue = 16
+
+// OpenRotating opens path for appending with rotation.
+func OpenRotating(path string, opts RotateOptions) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, opts: opts}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	if opts.Compress || opts.MaxBackups > 0 {
+		r.rotated = make(chan string, maintainQueue)
+		r.done = make(chan struct{})
+		go r.maintain()
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, paths.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", r.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat %s: %w", r.path, err)
+	}
+	r.file = f
+	r.size = info.Size()
+	r.opened = time.Now()
+	return nil
+}
+
+// Write appends p, rotating first if the active segment is full or too old.
+// Records are never split across segments.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) shouldRotate(next int64) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxBytes > 0 && r.size+next > r.opts.MaxBytes {
+		return true
+	}
+	return r.opts.MaxAge > 0 && time.Since(r.opened) >= r.opts.MaxAge
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", r.path, err)
+	}
+
+	segment := fmt.Sprintf("%s.%s", r.path, time.Now().UTC().Format("20060102T150405.000000000"))
+	if err := os.Rename(r.path, segment); err != nil {
+		return fmt.Errorf("failed to rotate %s: %w", r.path, err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	// Compression and pruning run off the write path so a large segment
+	// doesn't stall the run
+	if r.rotated != nil {
+		r.rotated <- segment
+	}
+	return nil
+}
+
+// maintain compresses and prunes rotated segments one at a time, so
+// pruning never lists or removes a segment while it is being compressed.
+func (r *RotatingFile) maintain() {
+	defer close(r.done)
+	for segment := range r.rotated {
+		if r.opts.Compress {
+			if err := compressFile(segment); err != nil {
+				fmt.Fprintf(os.Stderr, "stream: %v\n", err)
+			}
+		}
+		if r.opts.MaxBackups > 0 {
+			if err := pruneSegments(r.path, r.opts.MaxBackups); err != nil {
+				fmt.Fprintf(os.Stderr, "stream: %v\n", err)
+			}
+		}
+	}
+}
+
+// Sync flushes the active segment to disk.
+func (r *RotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Sync()
+}
+
+// Close closes the active segment and waits for pending compression and
+// pruning.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	err := r.file.Close()
+	rotated := r.rotated
+	r.rotated = nil
+	r.mu.Unlock()
+	if rotated != nil {
+		close(rotated)
+		<-r.done
+	}
+	return err
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open segment %s: %w", path, err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, paths.FileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create %s.gz: %w", path, err)
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close %s.gz: %w", path, err)
+	}
+	return os.Remove(path)
+}
+
+// pruneSegments removes the oldest rotated segments of path beyond keep.
+// Segment names embed a sortable UTC timestamp, so lexical order is age order.
+func pruneSegments(path string, keep int) error {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return err
+	}
+	var segments []string
+	for _, m := range matches {
+		// A segment whose compression failed halfway has both forms on
+		// disk; count it once.
+		if !strings.HasSuffix(m, ".gz") {
+			if _, err := os.Stat(m + ".gz"); err == nil {
+				continue
+			}
+		}
+		segments = append(segments, m)
+	}
+	sort.Strings(segments)
+	for len(segments) > keep {
+		if err := os.Remove(segments[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to prune %s: %w", segments[0], err)
+		}
+		segments = segments[1:]
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package stream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// segments lists the rotated segments of path, oldest first.
+func segments(t *testing.T, path string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// readSegment returns a segment's records, gunzipped if compressed.
+func readSegment(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return string(data)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	plain, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return string(plain)
+}
+
+func writeRecords(t *testing.T, r *RotatingFile, n int) []string {
+	t.Helper()
+	var records []string
+	for i := 0; i < n; i++ {
+		record := fmt.Sprintf("record %02d\n", i)
+		if _, err := r.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRotateBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.ndjson")
+	// Two 10-byte records fit in a segment, a third rolls over
+	r, err := OpenRotating(path, RotateOptions{MaxBytes: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := writeRecords(t, r, 5)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range segments(t, path) {
+		got = append(got, readSegment(t, s))
+	}
+	got = append(got, readSegment(t, path))
+	want := []string{records[0] + records[1], records[2] + records[3], records[4]}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("segments %q, want %q", got, want)
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.ndjson")
+	r, err := OpenRotating(path, RotateOptions{MaxAge: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := writeRecords(t, r, 2)
+	if n := len(segments(t, path)); n != 0 {
+		t.Errorf("%d segments before the age is reached", n)
+	}
+	time.Sleep(30 * time.Millisecond)
+	second := writeRecords(t, r, 1)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := segments(t, path)
+	if len(rotated) != 1 || readSegment(t, rotated[0]) != strings.Join(first, "") || readSegment(t, path) != second[0] {
+		t.Errorf("segments %q after one rotation", rotated)
+	}
+}
+
+func TestRotateCompressAndPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.ndjson")
+	// Every record rotates the one before it out, so compression and
+	// pruning of many segments overlap with the writes
+	r, err := OpenRotating(path, RotateOptions{MaxBytes: 1, MaxBackups: 3, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := writeRecords(t, r, 40)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := segments(t, path)
+	if len(rotated) != 3 {
+		t.Fatalf("kept %q, want the 3 newest", rotated)
+	}
+	for i, s := range rotated {
+		if !strings.HasSuffix(s, ".gz") {
+			t.Errorf("%s left uncompressed", s)
+			continue
+		}
+		if got, want := readSegment(t, s), records[36+i]; got != want {
+			t.Errorf("%s holds %q, want %q", s, got, want)
+		}
+	}
+	if got := readSegment(t, path); got != records[39] {
+		t.Errorf("active file holds %q", got)
+	}
+}
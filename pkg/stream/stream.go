@@ -0,0 +1,83 @@
+// Package stream writes newline-delimited JSON (NDJSON) records as they are
+// produced, so partial results of long runs survive a crash and can be
+// tailed by other tools.
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// Writer appends one JSON document per line. It is safe for concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	syncer interface{ Sync() error }
+}
+
+// Open creates an NDJSON writer for path. "-" streams to stdout; any other
+// path is opened for appending so a restarted run extends the same file.
+func Open(path string) (*Writer, error) {
+	if path == "-" {
+		return &Writer{w: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, paths.FileMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream %s: %w", path, err)
+	}
+	return &Writer{w: f, closer: f, syncer: f}, nil
+}
+
+// OpenRotated is like Open but rolls the file over according to opts, so
+// continuous runs don't grow a single file without bound.
+func OpenRotated(path string, opts RotateOptions) (*Writer, error) {
+	if path == "-" || !opts.Enabled() {
+		return Open(path)
+	}
+	f, err := OpenRotating(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: f, closer: f, syncer: f}, nil
+}
+
+// New wraps an arbitrary writer.
+func New(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write encodes record as a single line. Files are synced after every record
+// so a crash loses at most the line being written.
+func (s *Writer) Write(record any) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write stream record: %w", err)
+	}
+	if s.syncer != nil {
+		if err := s.syncer.Sync(); err != nil {
+			return fmt.Errorf("failed to sync stream: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying file, if any.
+func (s *Writer) Close() error {
+	if s == nil || s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
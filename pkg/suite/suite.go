@@ -0,0 +1,230 @@
+// Package suite plans which test groups to run. Each group is one stage
+// command; durations of past runs are kept in a history file so a run with a
+// time budget can pick the highest-priority groups that fit and report the
+// rest as skipped.
+package suite
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// Group is a test group run as one stage command.
+type Group struct {
+	Name string
+	// Priority orders groups, lower first: canaries before sweeps.
+	Priority int
+	Script   string
+	Args     []string
+	// Estimate is used until the history has a duration for the group.
+	Estimate time.Duration
+	// Tags label the group as a whole; Contains lists the tags of the
+	// vectors inside it, which the stage command filters itself.
+	Tags     []string
+	Contains []string
+	// Requests, Transactions and Gas are rough upper bounds of what one
+	// run of the group costs: RPC requests made, transactions sent and the
+	// gas they are sent with. Transactions and Gas are usually left to
+	// Declare.
+	Requests     int
+	Transactions int
+	Gas          uint64
+}
+
+// Selected reports whether the group should run under filter: none of its
+// own tags may be excluded, and an include list must name one of its own or
+// its vectors' tags.
+func (g Group) Selected(filter *tags.Filter) bool {
+	if !filter.Active() {
+		return true
+	}
+	if !(&tags.Filter{Exclude: filter.Exclude}).Match(g.Tags) {
+		return false
+	}
+	all := append(append([]string(nil), g.Tags...), g.Contains...)
+	return (&tags.Filter{Include: filter.Include}).Match(all)
+}
+
+// WritesDirective is the comment with which a group's script declares that
+// it may send transactions, placed above its package clause with the most
+// one run sends and the gas they are sent with:
+//
+//	//suite:writes transactions=1 gas=2000000
+//
+// Declare reads it, so what --read-only refuses and the cost estimate
+// follow the scripts rather than a list kept beside them.
+const WritesDirective = "//suite:writes"
+
+// Declare returns g with what its script declares: the writes directive
+// tags it tags.Writes and sets its Transactions and Gas.
+func Declare(g Group) (Group, error) {
+	data, err := os.ReadFile(g.Script)
+	if err != nil {
+		return g, fmt.Errorf("failed to read the script of group %s: %w", g.Name, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "package" {
+			break
+		}
+		if fields[0] != WritesDirective {
+			continue
+		}
+		if !slices.Contains(g.Tags, tags.Writes) {
+			g.Tags = append(slices.Clip(g.Tags), tags.Writes)
+		}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			n, err := strconv.ParseUint(value, 10, 64)
+			switch {
+			case err != nil:
+				return g, fmt.Errorf("%s: invalid %s %q: %w", g.Script, WritesDirective, field, err)
+			case key == "transactions":
+				g.Transactions = int(n)
+			case key == "gas":
+				g.Gas = n
+			default:
+				return g, fmt.Errorf("%s: unknown %s field %q", g.Script, WritesDirective, key)
+			}
+		}
+	}
+	return g, nil
+}
+
+// Planned is a group with the duration it is expected to take. Skip is
+// why the plan leaves it out.
+type Planned struct {
+	Group    Group
+	Estimate time.Duration
+	FromRuns int
+	Skip     *skip.Reason
+}
+
+// Cost is what running a set of groups is expected to take.
+type Cost struct {
+	Requests     int
+	Transactions int
+	Gas          uint64
+	Duration     time.Duration
+}
+
+// Total adds up the expected cost of planned groups.
+func Total(planned []Planned) Cost {
+	var c Cost
+	for _, p := range planned {
+		c.Requests += p.Group.Requests
+		c.Transactions += p.Group.Transactions
+		c.Gas += p.Group.Gas
+		c.Duration += p.Estimate
+	}
+	return c
+}
+
+// historyRuns is how many recent durations are kept per group.
+const historyRuns = 10
+
+// History holds the recent durations of each group, in seconds.
+type History struct {
+	Groups map[string][]float64 `json:"groups"`
+
+	path string
+}
+
+// LoadHistory reads the history file, returning an empty history if it
+// doesn't exist yet.
+func LoadHistory(path string) (*History, error) {
+	h := &History{Groups: map[string][]float64{}, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run history: %w", err)
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("failed to parse run history %s: %w", path, err)
+	}
+	if h.Groups == nil {
+		h.Groups = map[string][]float64{}
+	}
+	return h, nil
+}
+
+// Record appends a measured duration for group.
+func (h *History) Record(group string, d time.Duration) {
+	runs := append(h.Groups[group], d.Seconds())
+	if len(runs) > historyRuns {
+		runs = runs[len(runs)-historyRuns:]
+	}
+	h.Groups[group] = runs
+}
+
+// Estimate returns the median of the recorded durations of group, or
+// fallback when none are recorded, and the number of runs it is based on.
+func (h *History) Estimate(group string, fallback time.Duration) (time.Duration, int) {
+	runs := h.Groups[group]
+	if len(runs) == 0 {
+		return fallback, 0
+	}
+	sorted := append([]float64(nil), runs...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	median := sorted[mid]
+	if len(sorted)%2 == 0 {
+		median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return time.Duration(median * float64(time.Second)), len(runs)
+}
+
+// Save writes the history back to the file it was loaded from.
+func (h *History) Save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run history: %w", err)
+	}
+	if err := paths.WriteFile(h.path, data); err != nil {
+		return fmt.Errorf("failed to save run history: %w", err)
+	}
+	return nil
+}
+
+// Plan orders groups by priority, drops those excluded by the tag filter
+// and, with a non-zero budget, greedily selects those whose estimated
+// duration still fits. A group that doesn't fit is skipped, but cheaper
+// lower-priority groups may still be selected.
+func Plan(groups []Group, h *History, budget time.Duration, filter *tags.Filter) (selected, skipped []Planned) {
+	ordered := append([]Group(nil), groups...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Priority < ordered[j].Priority })
+
+	remaining := budget
+	for _, g := range ordered {
+		est, n := h.Estimate(g.Name, g.Estimate)
+		p := Planned{Group: g, Estimate: est, FromRuns: n}
+		if !g.Selected(filter) {
+			p.Skip = skip.Newf(skip.TagFilter, "tag filter (%s)", filter)
+			skipped = append(skipped, p)
+			continue
+		}
+		if budget > 0 && est > remaining {
+			p.Skip = skip.Newf(skip.Budget, "estimated %s exceeds remaining budget %s", est.Round(time.Second), remaining.Round(time.Second))
+			skipped = append(skipped, p)
+			continue
+		}
+		remaining -= est
+		selected = append(selected, p)
+	}
+	return selected, skipped
+}
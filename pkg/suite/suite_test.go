@@ -0,0 +1,61 @@
+package suite
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+func TestTotal(t *testing.T) {
+	groups := []Group{
+		{Name: "canary", Priority: 0, Estimate: 5 * time.Second, Requests: 5},
+		{Name: "store", Priority: 10, Estimate: time.Minute, Requests: 80, Transactions: 6, Gas: 1_500_000, Tags: []string{tags.Writes}},
+		{Name: "sweep", Priority: 20, Estimate: 2 * time.Minute, Requests: 1000},
+	}
+	h := &History{Groups: map[string][]float64{"canary": {3, 9, 7}}}
+
+	selected, _ := Plan(groups, h, 90*time.Second, &tags.Filter{})
+	want := Cost{Requests: 85, Transactions: 6, Gas: 1_500_000, Duration: 67 * time.Second}
+	if got := Total(selected); got != want {
+		t.Errorf("total %+v, want %+v", got, want)
+	}
+}
+
+func TestDeclare(t *testing.T) {
+	dir := t.TempDir()
+	writer := filepath.Join(dir, "writer.go")
+	reader := filepath.Join(dir, "reader.go")
+	if err := os.WriteFile(writer, []byte("//suite:writes transactions=2 gas=2000000\n\npackage main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Only directives above the package clause count
+	if err := os.WriteFile(reader, []byte("package main\n\n//suite:writes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := Declare(Group{Name: "writer", Script: writer, Tags: []string{tags.Smoke}})
+	if err != nil || !slices.Equal(g.Tags, []string{tags.Smoke, tags.Writes}) || g.Transactions != 2 || g.Gas != 2_000_000 {
+		t.Errorf("writer %+v, %v", g, err)
+	}
+	if g, err = Declare(g); err != nil || len(g.Tags) != 2 {
+		t.Errorf("declared twice: tags %q, %v", g.Tags, err)
+	}
+	if g, err := Declare(Group{Name: "reader", Script: reader}); err != nil || len(g.Tags) != 0 {
+		t.Errorf("reader tags %q, %v", g.Tags, err)
+	}
+	for _, directive := range []string{"transactions=two", "fees=1"} {
+		if err := os.WriteFile(writer, []byte("//suite:writes "+directive+"\n\npackage main\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Declare(Group{Name: "writer", Script: writer}); err == nil {
+			t.Errorf("declared %q", directive)
+		}
+	}
+	if _, err := Declare(Group{Name: "missing", Script: filepath.Join(dir, "missing.go")}); err == nil {
+		t.Error("declared a missing script")
+	}
+}
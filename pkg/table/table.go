@@ -0,0 +1,226 @@
+// Package table renders per-case results as an aligned console table. The
+// columns shown and the row order are picked with --columns and --sort, so
+// the output of large runs can be scanned rather than read line by line.
+package table
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"cdk-erigon-precompile/pkg/output"
+)
+
+// Column is one column a table can show.
+type Column struct {
+	Name string
+	// Numeric columns are right-aligned and sorted by value. Cells that
+	// don't parse as a number, such as "-", sort before the others.
+	Numeric bool
+}
+
+// Row is one result. Marker, usually the status emoji, starts the line
+// ahead of the cells. In plain output mode it is replaced with its ASCII
+// marker before the widths are measured, so the columns stay aligned.
+type Row struct {
+	Marker string
+	Cells  map[string]string
+}
+
+// Table is a set of rows with the columns they may be shown with.
+type Table struct {
+	Columns []Column
+	Rows    []Row
+}
+
+// Options select the columns shown, in order, and the column rows are
+// sorted by, descending when prefixed with "-".
+type Options struct {
+	Columns []string
+	Sort    string
+}
+
+// Flags registers --columns and --sort on the default flag set. defaults
+// are the columns shown without --columns; available are listed in the
+// help.
+func Flags(defaults []string, available []Column) *Options {
+	names := make([]string, len(available))
+	for i, c := range available {
+		names[i] = c.Name
+	}
+	o := &Options{Columns: defaults}
+	flag.Func("columns", fmt.Sprintf("comma-separated result table columns, of %s (default %s)", strings.Join(names, ", "), strings.Join(defaults, ",")), func(s string) error {
+		o.Columns = nil
+		for _, c := range strings.Split(s, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				o.Columns = append(o.Columns, c)
+			}
+		}
+		return nil
+	})
+	flag.StringVar(&o.Sort, "sort", "", "sort the result table by this column, descending when prefixed with - (default run order)")
+	return o
+}
+
+// Check validates o against the columns a table will have, so a typo
+// fails before a long run rather than after it.
+func (o *Options) Check(available []Column) error {
+	t := Table{Columns: available}
+	for _, name := range o.Columns {
+		if _, err := t.column(name); err != nil {
+			return fmt.Errorf("--columns: %w", err)
+		}
+	}
+	if o.Sort != "" {
+		if _, err := t.column(strings.TrimPrefix(o.Sort, "-")); err != nil {
+			return fmt.Errorf("--sort: %w", err)
+		}
+	}
+	return nil
+}
+
+// column returns the column called name.
+func (t *Table) column(name string) (Column, error) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return Column{}, fmt.Errorf("unknown column %q (have %s)", name, strings.Join(names, ", "))
+}
+
+// Shorten cuts s to n characters, marking the cut with an ellipsis, for
+// cells such as long inputs that would widen the whole column.
+func Shorten(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+// Add appends a row.
+func (t *Table) Add(marker string, cells map[string]string) {
+	t.Rows = append(t.Rows, Row{Marker: marker, Cells: cells})
+}
+
+// Render writes the header and the rows with the columns and order of o.
+// Rows keep the order they were added in when o sorts nothing, and among
+// equal sort keys.
+func (t *Table) Render(w io.Writer, o Options) error {
+	columns := make([]Column, len(o.Columns))
+	for i, name := range o.Columns {
+		c, err := t.column(name)
+		if err != nil {
+			return err
+		}
+		columns[i] = c
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns selected")
+	}
+
+	rows := append([]Row(nil), t.Rows...)
+	if output.Plain() {
+		for i, r := range rows {
+			if m, ok := output.DefaultMarkers[r.Marker]; ok {
+				rows[i].Marker = m
+			}
+		}
+	}
+	if o.Sort != "" {
+		key, desc := strings.CutPrefix(o.Sort, "-")
+		c, err := t.column(key)
+		if err != nil {
+			return fmt.Errorf("--sort: %w", err)
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i].Cells[key], rows[j].Cells[key]
+			if desc {
+				a, b = b, a
+			}
+			return less(c, a, b)
+		})
+	}
+
+	widths := make([]int, len(columns))
+	markerWidth := 0
+	for i, c := range columns {
+		widths[i] = utf8.RuneCountInString(c.Name)
+		for _, r := range rows {
+			widths[i] = max(widths[i], utf8.RuneCountInString(r.Cells[c.Name]))
+		}
+	}
+	for _, r := range rows {
+		markerWidth = max(markerWidth, width(r.Marker))
+	}
+
+	line := func(marker string, cell func(Column) string) error {
+		var b strings.Builder
+		if markerWidth > 0 {
+			b.WriteString(marker)
+			b.WriteString(strings.Repeat(" ", markerWidth-width(marker)+1))
+		}
+		for i, c := range columns {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			v := cell(c)
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v))
+			if c.Numeric {
+				b.WriteString(pad + v)
+			} else if i < len(columns)-1 {
+				b.WriteString(v + pad)
+			} else {
+				b.WriteString(v)
+			}
+		}
+		_, err := fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+		return err
+	}
+	if err := line("", func(c Column) string { return c.Name }); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := line(r.Marker, func(c Column) string { return r.Cells[c.Name] }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func less(c Column, a, b string) bool {
+	if !c.Numeric {
+		return a < b
+	}
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return errA != nil && errB == nil
+	}
+	return x < y
+}
+
+// width is the number of terminal cells marker takes: emoji are two wide,
+// and the variation selector some of them carry takes none.
+func width(marker string) int {
+	n := 0
+	for _, r := range marker {
+		switch {
+		case r == '\uFE0F':
+		case r >= 0x2190:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+package table
+
+import (
+	"strings"
+	"testing"
+)
+
+func sample() *Table {
+	t := &Table{Columns: []Column{{Name: "case"}, {Name: "result"}, {Name: "gas", Numeric: true}, {Name: "latency", Numeric: true}}}
+	t.Add("✅", map[string]string{"case": "aa11", "result": "match", "gas": "23500", "latency": "4.20"})
+	t.Add("❌", map[string]string{"case": "bb22", "result": "mismatch", "gas": "-", "latency": "12.75"})
+	t.Add("⏭️", map[string]string{"case": "cc33", "result": "skipped", "gas": "-", "latency": "-"})
+	return t
+}
+
+func render(t *testing.T, tbl *Table, o Options) string {
+	t.Helper()
+	var b strings.Builder
+	if err := tbl.Render(&b, o); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestRenderAligns(t *testing.T) {
+	got := render(t, sample(), Options{Columns: []string{"case", "result", "latency"}})
+	want := "" +
+		"   case  result    latency\n" +
+		"✅ aa11  match        4.20\n" +
+		"❌ bb22  mismatch    12.75\n" +
+		"⏭️ cc33  skipped         -\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderSorts(t *testing.T) {
+	got := render(t, sample(), Options{Columns: []string{"case"}, Sort: "-latency"})
+	if want := "   case\n❌ bb22\n✅ aa11\n⏭️ cc33\n"; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if err := sample().Render(&strings.Builder{}, Options{Columns: []string{"case", "nonce"}}); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("unknown column: got %v", err)
+	}
+}
+
+func TestRenderPlainMarkers(t *testing.T) {
+	t.Setenv("PLAIN_OUTPUT", "1")
+	got := render(t, sample(), Options{Columns: []string{"case", "result"}})
+	want := "" +
+		"       case  result\n" +
+		"[OK]   aa11  match\n" +
+		"[FAIL] bb22  mismatch\n" +
+		"[SKIP] cc33  skipped\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCheckAndShorten(t *testing.T) {
+	cols := sample().Columns
+	if err := (&Options{Columns: []string{"case", "gas"}, Sort: "-latency"}).Check(cols); err != nil {
+		t.Error(err)
+	}
+	if err := (&Options{Columns: []string{"case"}, Sort: "speed"}).Check(cols); err == nil {
+		t.Error("accepted an unknown sort column")
+	}
+	if got := Shorten("0x0123456789", 6); got != "0x012…" {
+		t.Errorf("Shorten: %q", got)
+	}
+}
@@ -0,0 +1,112 @@
+// Package tags labels vectors and test groups (smoke, gas, fuzz, ...) and
+// filters them with --include-tags/--exclude-tags, so a run can be narrowed
+// without editing the input lists.
+package tags
+
+import (
+	"flag"
+	"slices"
+	"strings"
+)
+
+// Well-known tags.
+const (
+	Smoke      = "smoke"
+	Gas        = "gas"
+	Fuzz       = "fuzz"
+	ZKCounters = "zk-counters"
+	Slow       = "slow"
+	Binary     = "binary"
+	Archive    = "archive"
+	// Writes marks groups that send transactions, refused by --read-only.
+	Writes = "writes"
+)
+
+// Filter selects tagged items. An item matches when it has at least one
+// included tag (or Include is empty) and none of the excluded tags.
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// Flags registers --include-tags and --exclude-tags on the default flag set.
+func Flags() *Filter {
+	f := &Filter{}
+	flag.Func("include-tags", "only run vectors/groups with one of these comma-separated tags", func(s string) error {
+		f.Include = append(f.Include, Parse(s)...)
+		return nil
+	})
+	flag.Func("exclude-tags", "skip vectors/groups with any of these comma-separated tags", func(s string) error {
+		f.Exclude = append(f.Exclude, Parse(s)...)
+		return nil
+	})
+	return f
+}
+
+// Parse splits a comma-separated tag list, dropping empty entries.
+func Parse(s string) []string {
+	var out []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Active reports whether the filter restricts anything.
+func (f *Filter) Active() bool {
+	return f != nil && (len(f.Include) > 0 || len(f.Exclude) > 0)
+}
+
+// Match reports whether an item with the given tags is selected.
+func (f *Filter) Match(tags []string) bool {
+	if f == nil {
+		return true
+	}
+	for _, t := range f.Exclude {
+		if slices.Contains(tags, t) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, t := range f.Include {
+		if slices.Contains(tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Args renders the filter back into command-line flags, for passing it on
+// to sub-commands.
+func (f *Filter) Args() []string {
+	var args []string
+	if f == nil {
+		return args
+	}
+	if len(f.Include) > 0 {
+		args = append(args, "--include-tags", strings.Join(f.Include, ","))
+	}
+	if len(f.Exclude) > 0 {
+		args = append(args, "--exclude-tags", strings.Join(f.Exclude, ","))
+	}
+	return args
+}
+
+// String renders the filter for log lines.
+func (f *Filter) String() string {
+	if !f.Active() {
+		return "all"
+	}
+	var parts []string
+	if len(f.Include) > 0 {
+		parts = append(parts, "include "+strings.Join(f.Include, ","))
+	}
+	if len(f.Exclude) > 0 {
+		parts = append(parts, "exclude "+strings.Join(f.Exclude, ","))
+	}
+	return strings.Join(parts, ", ")
+}
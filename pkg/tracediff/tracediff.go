@@ -0,0 +1,200 @@
+// Package tracediff compares the opcode-level traces of the same execution
+// on two endpoints. Where the results stages can only say that two nodes
+// returned different outputs, the first step at which their traces part
+// ways, and the steps priced differently before it, point at the opcode or
+// precompile to blame.
+package tracediff
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Step is one struct logger entry.
+type Step struct {
+	PC      uint64   `json:"pc"`
+	Op      string   `json:"op"`
+	Gas     uint64   `json:"gas"`
+	GasCost uint64   `json:"gasCost"`
+	Depth   int      `json:"depth"`
+	Stack   []string `json:"stack,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Trace is a struct logger trace.
+type Trace struct {
+	Gas         uint64 `json:"gas"`
+	Failed      bool   `json:"failed"`
+	ReturnValue string `json:"returnValue"`
+	StructLogs  []Step `json:"structLogs"`
+}
+
+// config asks for the struct logger with the stack, which the diff
+// compares, and without storage and memory, which make traces of even small
+// calls very large.
+var config = map[string]any{"disableStorage": true, "enableMemory": false, "enableReturnData": true}
+
+// Transaction traces a mined transaction with debug_traceTransaction.
+func Transaction(ctx context.Context, c *rpc.Client, hash common.Hash) (Trace, error) {
+	var t Trace
+	if err := c.CallContext(ctx, &t, "debug_traceTransaction", hash, config); err != nil {
+		return Trace{}, fmt.Errorf("debug_traceTransaction failed: %w", err)
+	}
+	return t, nil
+}
+
+// CallArgs is the call traced by Call.
+type CallArgs struct {
+	From *common.Address `json:"from,omitempty"`
+	To   *common.Address `json:"to,omitempty"`
+	Gas  *hexutil.Uint64 `json:"gas,omitempty"`
+	Data hexutil.Bytes   `json:"data"`
+}
+
+// Call traces a call at block, e.g. "latest", with debug_traceCall.
+func Call(ctx context.Context, c *rpc.Client, args CallArgs, block string) (Trace, error) {
+	var t Trace
+	if err := c.CallContext(ctx, &t, "debug_traceCall", args, block, config); err != nil {
+		return Trace{}, fmt.Errorf("debug_traceCall failed: %w", err)
+	}
+	return t, nil
+}
+
+// Divergence is a step at which the traces disagree. A is nil when the
+// first trace ended before the second, and B the other way round.
+type Divergence struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+	A      *Step  `json:"a,omitempty"`
+	B      *Step  `json:"b,omitempty"`
+}
+
+// GasDelta is a step both traces executed but priced differently.
+type GasDelta struct {
+	Index    int    `json:"index"`
+	PC       uint64 `json:"pc"`
+	Op       string `json:"op"`
+	Depth    int    `json:"depth"`
+	GasCostA uint64 `json:"gasCostA"`
+	GasCostB uint64 `json:"gasCostB"`
+}
+
+// Diff is the structured comparison of two traces.
+type Diff struct {
+	StepsA  int    `json:"stepsA"`
+	StepsB  int    `json:"stepsB"`
+	GasA    uint64 `json:"gasA"`
+	GasB    uint64 `json:"gasB"`
+	FailedA bool   `json:"failedA"`
+	FailedB bool   `json:"failedB"`
+	ReturnA string `json:"returnA"`
+	ReturnB string `json:"returnB"`
+	// FirstDivergence is the first step whose pc, opcode or depth differs,
+	// i.e. where control flow split.
+	FirstDivergence *Divergence `json:"firstDivergence,omitempty"`
+	// FirstStackDivergence is the first step, before control flow split,
+	// entered with a different stack: the value computed by the step
+	// before it differed.
+	FirstStackDivergence *Divergence `json:"firstStackDivergence,omitempty"`
+	// GasDeltas are the first differently priced steps before control flow
+	// split, up to the limit given to Compare, of GasDeltaCount in all.
+	GasDeltas     []GasDelta `json:"gasDeltas,omitempty"`
+	GasDeltaCount int        `json:"gasDeltaCount"`
+}
+
+// Identical reports whether the traces agree step for step.
+func (d Diff) Identical() bool {
+	return d.FirstDivergence == nil && d.FirstStackDivergence == nil && d.GasDeltaCount == 0 &&
+		d.GasA == d.GasB && d.FailedA == d.FailedB && sameReturn(d.ReturnA, d.ReturnB)
+}
+
+// Compare walks both traces in step until control flow splits, keeping at
+// most maxDeltas gas deltas.
+func Compare(a, b Trace, maxDeltas int) Diff {
+	d := Diff{
+		StepsA: len(a.StructLogs), StepsB: len(b.StructLogs),
+		GasA: a.Gas, GasB: b.Gas,
+		FailedA: a.Failed, FailedB: b.Failed,
+		ReturnA: a.ReturnValue, ReturnB: b.ReturnValue,
+	}
+	for i := 0; i < max(len(a.StructLogs), len(b.StructLogs)); i++ {
+		if i >= len(a.StructLogs) || i >= len(b.StructLogs) {
+			div := &Divergence{Index: i, Reason: "trace ended"}
+			if i < len(a.StructLogs) {
+				div.A = &a.StructLogs[i]
+			} else {
+				div.B = &b.StructLogs[i]
+			}
+			d.FirstDivergence = div
+			break
+		}
+		sa, sb := &a.StructLogs[i], &b.StructLogs[i]
+		if reason := controlFlow(sa, sb); reason != "" {
+			d.FirstDivergence = &Divergence{Index: i, Reason: reason, A: sa, B: sb}
+			break
+		}
+		if d.FirstStackDivergence == nil && !sameStack(sa.Stack, sb.Stack) {
+			d.FirstStackDivergence = &Divergence{Index: i, Reason: "stack differs", A: sa, B: sb}
+		}
+		if sa.GasCost != sb.GasCost {
+			d.GasDeltaCount++
+			if len(d.GasDeltas) < maxDeltas {
+				d.GasDeltas = append(d.GasDeltas, GasDelta{Index: i, PC: sa.PC, Op: sa.Op, Depth: sa.Depth, GasCostA: sa.GasCost, GasCostB: sb.GasCost})
+			}
+		}
+	}
+	return d
+}
+
+func controlFlow(a, b *Step) string {
+	switch {
+	case a.Depth != b.Depth:
+		return fmt.Sprintf("depth %d vs %d", a.Depth, b.Depth)
+	case a.PC != b.PC:
+		return fmt.Sprintf("pc %d vs %d", a.PC, b.PC)
+	case a.Op != b.Op:
+		return fmt.Sprintf("op %s vs %s", a.Op, b.Op)
+	case (a.Error == "") != (b.Error == ""):
+		return fmt.Sprintf("error %q vs %q", a.Error, b.Error)
+	}
+	return ""
+}
+
+// sameStack compares stack values numerically, since clients differ in
+// padding and in the 0x prefix. A trace without stacks matches any.
+func sameStack(a, b []string) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if normalizeHex(a[i]) != normalizeHex(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameReturn compares return data, which clients print with or without
+// the 0x prefix.
+func sameReturn(a, b string) bool {
+	trim := func(s string) string { return strings.TrimPrefix(strings.ToLower(s), "0x") }
+	return trim(a) == trim(b)
+}
+
+// normalizeHex strips the 0x prefix and leading zeros.
+func normalizeHex(s string) string {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	if n, ok := new(big.Int).SetString(s, 16); ok {
+		return n.Text(16)
+	}
+	return s
+}
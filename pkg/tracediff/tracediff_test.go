@@ -0,0 +1,89 @@
+package tracediff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func steps(ops ...Step) []Step { return ops }
+
+func TestCompare(t *testing.T) {
+	base := Trace{Gas: 21100, ReturnValue: "00ff", StructLogs: steps(
+		Step{PC: 0, Op: "PUSH1", GasCost: 3, Depth: 1, Stack: []string{}},
+		Step{PC: 2, Op: "STATICCALL", GasCost: 100, Depth: 1, Stack: []string{"0x2"}},
+		Step{PC: 3, Op: "RETURN", GasCost: 0, Depth: 1, Stack: []string{"0x1"}},
+	)}
+
+	// Padding and prefixes of stack values and return data don't matter
+	same := Trace{Gas: 21100, ReturnValue: "0x00FF", StructLogs: steps(
+		Step{PC: 0, Op: "PUSH1", GasCost: 3, Depth: 1},
+		Step{PC: 2, Op: "STATICCALL", GasCost: 100, Depth: 1, Stack: []string{"0000000000000000000000000000000000000000000000000000000000000002"}},
+		Step{PC: 3, Op: "RETURN", GasCost: 0, Depth: 1, Stack: []string{"0x01"}},
+	)}
+	if d := Compare(base, same, 10); !d.Identical() {
+		t.Errorf("equivalent traces differ: %+v", d)
+	}
+
+	// The call is priced differently and answers another value, on which
+	// the next step branches
+	other := Trace{Gas: 21160, ReturnValue: "00ff", StructLogs: steps(
+		Step{PC: 0, Op: "PUSH1", GasCost: 3, Depth: 1},
+		Step{PC: 2, Op: "STATICCALL", GasCost: 160, Depth: 1, Stack: []string{"0x2"}},
+		Step{PC: 3, Op: "RETURN", GasCost: 0, Depth: 1, Stack: []string{"0x0"}},
+		Step{PC: 9, Op: "STOP", Depth: 1},
+	)}
+	d := Compare(base, other, 10)
+	if d.Identical() || d.FirstDivergence == nil || d.FirstDivergence.Index != 3 || d.FirstDivergence.A != nil || d.FirstDivergence.B.Op != "STOP" {
+		t.Errorf("first divergence %+v, want the extra STOP at step 3", d.FirstDivergence)
+	}
+	if d.FirstStackDivergence == nil || d.FirstStackDivergence.Index != 2 {
+		t.Errorf("stack divergence %+v, want step 2", d.FirstStackDivergence)
+	}
+	if d.GasDeltaCount != 1 || d.GasDeltas[0].Op != "STATICCALL" || d.GasDeltas[0].GasCostB != 160 {
+		t.Errorf("gas deltas %+v", d.GasDeltas)
+	}
+
+	jump := Trace{Gas: 21100, ReturnValue: "00ff", StructLogs: steps(
+		Step{PC: 0, Op: "PUSH1", GasCost: 3, Depth: 1},
+		Step{PC: 7, Op: "JUMPDEST", GasCost: 1, Depth: 1},
+	)}
+	if d := Compare(base, jump, 0); d.FirstDivergence == nil || d.FirstDivergence.Reason != "pc 2 vs 7" || d.GasDeltaCount != 0 {
+		t.Errorf("divergence %+v, deltas %d", d.FirstDivergence, d.GasDeltaCount)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	hash := common.HexToHash("0xabc")
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle("debug_traceTransaction", func(call mockrpc.Call) (any, error) {
+		var got common.Hash
+		var cfg map[string]any
+		if err := call.Param(0, &got); err != nil || got != hash {
+			return nil, &mockrpc.Error{Code: -32000, Message: "transaction not found"}
+		}
+		if err := call.Param(1, &cfg); err != nil || cfg["disableStorage"] != true {
+			return nil, &mockrpc.Error{Code: -32602, Message: "unexpected config"}
+		}
+		return map[string]any{"gas": 21000, "failed": false, "returnValue": "",
+			"structLogs": []map[string]any{{"pc": 0, "op": "STOP", "gas": 0, "gasCost": 0, "depth": 1}}}, nil
+	})
+	c, err := rpc.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tr, err := Transaction(context.Background(), c, hash)
+	if err != nil || tr.Gas != 21000 || len(tr.StructLogs) != 1 || tr.StructLogs[0].Op != "STOP" {
+		t.Errorf("trace %+v, %v", tr, err)
+	}
+	if _, err := Transaction(context.Background(), c, common.HexToHash("0xdef")); err == nil {
+		t.Error("traced an unknown transaction")
+	}
+}
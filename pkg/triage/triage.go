@@ -0,0 +1,252 @@
+// Package triage tells failures already tracked upstream apart from new
+// regressions. Known divergences are listed with the signature of their
+// failures and the issue tracking them:
+//
+//	{"issues": [
+//	  {"url": "https://github.com/0xPolygonHermez/cdk-erigon/issues/123",
+//	   "title": "sha256 of empty input reverts through the wrapper",
+//	   "precompile": "0x02", "input": "^0x$", "error": "execution reverted"}
+//	]}
+//
+// Precompile is compared as an address, so "0x02" matches the full
+// address. Input and error are regular expressions, matched against the
+// input as lower-case 0x-prefixed hex and against the failure's error; an
+// empty field matches anything.
+package triage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultPath is the known issues file read unless another is given.
+const DefaultPath = "known_issues.json"
+
+// Issue is a tracked divergence and the signature of its failures.
+type Issue struct {
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	Precompile string `json:"precompile,omitempty"`
+	Input      string `json:"input,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	input, err *regexp.Regexp
+}
+
+// Issues are the known issues, in the order they are tried.
+type Issues []Issue
+
+// Load reads and validates a known issues file. A missing file is an
+// error; LoadOptional allows it.
+func Load(path string) (Issues, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Issues Issues `json:"issues"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for i := range file.Issues {
+		is := &file.Issues[i]
+		if is.URL == "" {
+			return nil, fmt.Errorf("%s: issue %d has no url", path, i+1)
+		}
+		if is.Precompile == "" && is.Input == "" && is.Error == "" {
+			return nil, fmt.Errorf("%s: issue %s would match every failure", path, is.URL)
+		}
+		if is.input, err = compile(is.Input); err != nil {
+			return nil, fmt.Errorf("%s: issue %s: input: %w", path, is.URL, err)
+		}
+		if is.err, err = compile(is.Error); err != nil {
+			return nil, fmt.Errorf("%s: issue %s: error: %w", path, is.URL, err)
+		}
+	}
+	return file.Issues, nil
+}
+
+// LoadOptional is Load, returning no issues when the file doesn't exist.
+func LoadOptional(path string) (Issues, error) {
+	issues, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return issues, err
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// Failure is one failed check found in a results file.
+type Failure struct {
+	// Source is the results file.
+	Source     string `json:"source"`
+	Case       string `json:"case,omitempty"`
+	Precompile string `json:"precompile,omitempty"`
+	Input      string `json:"input,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Match is the first issue whose signature f has, or nil for a new
+// regression.
+func (issues Issues) Match(f Failure) *Issue {
+	for i := range issues {
+		if issues[i].matches(f) {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+func (is *Issue) matches(f Failure) bool {
+	if is.Precompile != "" && !sameAddress(is.Precompile, f.Precompile) {
+		return false
+	}
+	if is.input != nil && !is.input.MatchString(strings.ToLower(f.Input)) {
+		return false
+	}
+	return is.err == nil || is.err.MatchString(f.Error)
+}
+
+// sameAddress compares precompile addresses by value, so "0x02" is the
+// full address; anything but hex is compared as written.
+func sameAddress(a, b string) bool {
+	x, okA := new(big.Int).SetString(strings.TrimPrefix(strings.ToLower(a), "0x"), 16)
+	y, okB := new(big.Int).SetString(strings.TrimPrefix(strings.ToLower(b), "0x"), 16)
+	if okA && okB {
+		return x.Cmp(y) == 0
+	}
+	return strings.EqualFold(a, b)
+}
+
+// Annotated is a failure with the issue tracking it, if any.
+type Annotated struct {
+	Failure
+	// Known is the URL of the tracking issue; empty for a new
+	// regression.
+	Known string `json:"known,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// Annotate matches every failure against the issues.
+func (issues Issues) Annotate(failures []Failure) []Annotated {
+	out := make([]Annotated, len(failures))
+	for i, f := range failures {
+		out[i].Failure = f
+		if is := issues.Match(f); is != nil {
+			out[i].Known, out[i].Title = is.URL, is.Title
+		}
+	}
+	return out
+}
+
+// sha256Precompile is what the stages exercise when their results don't
+// name a precompile.
+const sha256Precompile = "0x02"
+
+// notCases are the results files of the suite itself rather than its
+// checks.
+var notCases = map[string]bool{"results_run.json": true, "results_diff.json": true}
+
+// Collect finds the failed checks in the results files in dir written at
+// or after since. A check is any object with "match" or "passed" set
+// to false and not skipped; its precompile is its own or the nearest
+// enclosing one's. Checks inside a failed check aren't listed again.
+func Collect(dir string, since time.Time) ([]Failure, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "results_*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var failures []Failure
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if notCases[name] {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.ModTime().Before(since) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		failures = walk(v, name, sha256Precompile, failures)
+	}
+	return failures, nil
+}
+
+func walk(v any, source, precompile string, failures []Failure) []Failure {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			failures = walk(e, source, precompile, failures)
+		}
+	case map[string]any:
+		if p, ok := v["precompile"].(string); ok && p != "" {
+			precompile = p
+		}
+		if failed(v) {
+			return append(failures, Failure{
+				Source:     source,
+				Case:       firstString(v, "caseId", "id", "name"),
+				Precompile: precompile,
+				Input:      firstString(v, "input"),
+				Error:      firstString(v, "error", "hookError", "note"),
+			})
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			failures = walk(v[k], source, precompile, failures)
+		}
+	}
+	return failures
+}
+
+func failed(v map[string]any) bool {
+	if skipped, _ := v["skipped"].(bool); skipped {
+		return false
+	}
+	for _, k := range []string{"match", "passed"} {
+		if ok, isBool := v[k].(bool); isBool && !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func firstString(v map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := v[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package triage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCollectAnnotate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("results_stage3.json", `[
+		{"caseId": "a", "input": "0x", "match": false, "error": "execution reverted"},
+		{"caseId": "b", "input": "0x68656c6c6f", "match": true},
+		{"caseId": "c", "input": "0xFFFF", "skipped": true, "match": false},
+		{"caseId": "d", "input": "0x00ff", "match": false}]`)
+	write("results_composed.json", `{"cases": [{"precompile": "0x0000000000000000000000000000000000000004", "name": "identity", "match": false,
+		"steps": [{"passed": false}]}]}`)
+	write("results_run.json", `{"groups": [{"passed": false}]}`)
+	// Results of an earlier run are left out
+	write("results_archive.json", `{"groups": [{"checks": [{"passed": false}]}]}`)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "results_archive.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	failures, err := Collect(dir, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sorted by file; the failed step inside the failed case isn't listed
+	want := []Failure{
+		{Source: "results_composed.json", Case: "identity", Precompile: "0x0000000000000000000000000000000000000004"},
+		{Source: "results_stage3.json", Case: "a", Precompile: "0x02", Input: "0x", Error: "execution reverted"},
+		{Source: "results_stage3.json", Case: "d", Precompile: "0x02", Input: "0x00ff"},
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("failures %+v, want %+v", failures, want)
+	}
+	for i := range want {
+		if failures[i] != want[i] {
+			t.Errorf("failure %d: %+v, want %+v", i, failures[i], want[i])
+		}
+	}
+
+	path := filepath.Join(dir, "known_issues.json")
+	if err := os.WriteFile(path, []byte(`{"issues": [
+		{"url": "https://example.com/issues/1", "precompile": "0x02", "input": "^0x$", "error": "reverted"},
+		{"url": "https://example.com/issues/2", "precompile": "0x04"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotated := issues.Annotate(failures)
+	for i, known := range []string{"https://example.com/issues/2", "https://example.com/issues/1", ""} {
+		if annotated[i].Known != known {
+			t.Errorf("%s %s: known %q, want %q", annotated[i].Source, annotated[i].Case, annotated[i].Known, known)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"no url":        `{"issues": [{"precompile": "0x02"}]}`,
+		"no signature":  `{"issues": [{"url": "https://example.com/issues/1"}]}`,
+		"invalid input": `{"issues": [{"url": "https://example.com/issues/1", "input": "("}]}`,
+	} {
+		path := filepath.Join(dir, "known_issues.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+	if issues, err := LoadOptional(filepath.Join(dir, "missing.json")); err != nil || issues != nil {
+		t.Errorf("missing file: %v, %v", issues, err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing file loaded")
+	}
+}
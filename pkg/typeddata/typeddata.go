@@ -0,0 +1,231 @@
+// Package typeddata signs EIP-712 typed data locally and verifies it
+// on-chain through the TypedDataVerifier contract
+// (contracts/TypedDataVerifier.sol), which recovers the signer with the
+// ecrecover precompile. Hashing is done independently on both sides, with
+// go-ethereum's apitypes here and Solidity there, so a node whose keccak,
+// ABI encoding or ecrecover deviates shows up as a mismatch.
+package typeddata
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+// Name and Version are the EIP-712 domain of TypedDataVerifier.
+const (
+	Name    = "PrecompileTest"
+	Version = "1"
+)
+
+// Person and Mail are the example types of EIP-712.
+type Person struct {
+	Name   string
+	Wallet common.Address
+}
+
+type Mail struct {
+	From     Person
+	To       Person
+	Contents string
+}
+
+// Domain identifies where a signature is valid.
+type Domain struct {
+	Name     string
+	Version  string
+	ChainID  *big.Int
+	Contract common.Address
+}
+
+// VerifierDomain is the domain of the TypedDataVerifier at contract.
+func VerifierDomain(chainID *big.Int, contract common.Address) Domain {
+	return Domain{Name: Name, Version: Version, ChainID: chainID, Contract: contract}
+}
+
+var types = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"Person": {
+		{Name: "name", Type: "string"},
+		{Name: "wallet", Type: "address"},
+	},
+	"Mail": {
+		{Name: "from", Type: "Person"},
+		{Name: "to", Type: "Person"},
+		{Name: "contents", Type: "string"},
+	},
+}
+
+// TypedData is mail signed in d, as eth_signTypedData_v4 takes it.
+func TypedData(d Domain, mail Mail) apitypes.TypedData {
+	person := func(p Person) map[string]any {
+		return map[string]any{"name": p.Name, "wallet": p.Wallet.Hex()}
+	}
+	return apitypes.TypedData{
+		Types:       types,
+		PrimaryType: "Mail",
+		Domain: apitypes.TypedDataDomain{
+			Name:              d.Name,
+			Version:           d.Version,
+			ChainId:           (*math.HexOrDecimal256)(d.ChainID),
+			VerifyingContract: d.Contract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"from":     person(mail.From),
+			"to":       person(mail.To),
+			"contents": mail.Contents,
+		},
+	}
+}
+
+// Hash returns the domain separator of d and the digest a signer signs for
+// mail in it.
+func Hash(d Domain, mail Mail) (separator, digest common.Hash, err error) {
+	td := TypedData(d, mail)
+	sep, err := td.HashStruct("EIP712Domain", td.Domain.Map())
+	if err != nil {
+		return common.Hash{}, common.Hash{}, fmt.Errorf("failed to hash domain: %w", err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(td)
+	if err != nil {
+		return common.Hash{}, common.Hash{}, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	return common.BytesToHash(sep), common.BytesToHash(hash), nil
+}
+
+// Signature is an ecrecover-style signature, V being 27 or 28.
+type Signature struct {
+	V    uint8
+	R, S [32]byte
+}
+
+// Sign signs digest with s, which may be a remote signer.
+func Sign(ctx context.Context, s signer.Signer, digest common.Hash) (Signature, error) {
+	sig, err := s.SignHash(ctx, digest)
+	if err != nil {
+		return Signature{}, err
+	}
+	if len(sig) != crypto.SignatureLength {
+		return Signature{}, fmt.Errorf("signer returned %d bytes, want %d", len(sig), crypto.SignatureLength)
+	}
+	var out Signature
+	copy(out.R[:], sig[:32])
+	copy(out.S[:], sig[32:64])
+	out.V = sig[64] + 27
+	return out, nil
+}
+
+// Malleate returns the other valid signature of the same digest and key:
+// s replaced by n - s and the recovery id flipped. ecrecover accepts both;
+// EIP-2 only allows the one with the low s.
+func (sig Signature) Malleate() Signature {
+	s := new(big.Int).Sub(crypto.S256().Params().N, new(big.Int).SetBytes(sig.S[:]))
+	out := Signature{V: 27 + 28 - sig.V, R: sig.R}
+	s.FillBytes(out.S[:])
+	return out
+}
+
+// abiJSON is the ABI of contracts/TypedDataVerifier.sol.
+const abiJSON = `[
+{"type":"function","name":"domainSeparator","stateMutability":"view","inputs":[],
+"outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"digest","stateMutability":"view",
+"inputs":[{"name":"mail","type":"tuple","components":[` + mailComponents + `]}],
+"outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"recover","stateMutability":"view",
+"inputs":[{"name":"mail","type":"tuple","components":[` + mailComponents + `]},
+{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],
+"outputs":[{"name":"","type":"address"}]},
+{"type":"function","name":"verify","stateMutability":"view",
+"inputs":[{"name":"mail","type":"tuple","components":[` + mailComponents + `]},{"name":"signer","type":"address"},
+{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],
+"outputs":[{"name":"","type":"bool"}]}]`
+
+const personComponents = `{"name":"name","type":"string"},{"name":"wallet","type":"address"}`
+
+const mailComponents = `{"name":"from","type":"tuple","components":[` + personComponents + `]},` +
+	`{"name":"to","type":"tuple","components":[` + personComponents + `]},` +
+	`{"name":"contents","type":"string"}`
+
+// ABI is the parsed TypedDataVerifier ABI.
+var ABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Verifier calls a deployed TypedDataVerifier.
+type Verifier struct {
+	Client   *ethclient.Client
+	Contract common.Address
+}
+
+// DomainSeparator is the separator the contract computes.
+func (v Verifier) DomainSeparator(ctx context.Context) (common.Hash, error) {
+	out, err := v.call(ctx, "domainSeparator")
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return out[0].([32]byte), nil
+}
+
+// Digest is the digest the contract computes for mail.
+func (v Verifier) Digest(ctx context.Context, mail Mail) (common.Hash, error) {
+	out, err := v.call(ctx, "digest", mail)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return out[0].([32]byte), nil
+}
+
+// Recover is what ecrecover answers inside the contract for mail and sig.
+func (v Verifier) Recover(ctx context.Context, mail Mail, sig Signature) (common.Address, error) {
+	out, err := v.call(ctx, "recover", mail, sig.V, sig.R, sig.S)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return out[0].(common.Address), nil
+}
+
+// Verify reports whether the contract accepts sig as signer's over mail.
+func (v Verifier) Verify(ctx context.Context, mail Mail, signer common.Address, sig Signature) (bool, error) {
+	out, err := v.call(ctx, "verify", mail, signer, sig.V, sig.R, sig.S)
+	if err != nil {
+		return false, err
+	}
+	return out[0].(bool), nil
+}
+
+func (v Verifier) call(ctx context.Context, method string, args ...any) ([]any, error) {
+	data, err := ABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+	}
+	out, err := v.Client.CallContract(ctx, ethereum.CallMsg{To: &v.Contract, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s call failed: %w", method, err)
+	}
+	values, err := ABI.Unpack(method, out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %w", method, err)
+	}
+	return values, nil
+}
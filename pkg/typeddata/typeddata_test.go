@@ -0,0 +1,131 @@
+package typeddata
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+	"cdk-erigon-precompile/pkg/signer"
+)
+
+// The example of EIP-712 itself, signed by keccak256("cow").
+var (
+	specDomain = Domain{
+		Name:     "Ether Mail",
+		Version:  "1",
+		ChainID:  big.NewInt(1),
+		Contract: common.HexToAddress("0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"),
+	}
+	specMail = Mail{
+		From:     Person{Name: "Cow", Wallet: common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")},
+		To:       Person{Name: "Bob", Wallet: common.HexToAddress("0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB")},
+		Contents: "Hello, Bob!",
+	}
+)
+
+func TestHashAndSign(t *testing.T) {
+	sep, digest, err := Hash(specDomain, specMail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"); sep != want {
+		t.Errorf("domain separator %s, want %s", sep.Hex(), want.Hex())
+	}
+	if want := common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"); digest != want {
+		t.Errorf("digest %s, want %s", digest.Hex(), want.Hex())
+	}
+
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := Sign(context.Background(), signer.NewLocal(key), digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.V != 28 || common.Hash(sig.R) != common.HexToHash("0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d") ||
+		common.Hash(sig.S) != common.HexToHash("0x07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b91562") {
+		t.Errorf("signature v=%d r=%x s=%x", sig.V, sig.R, sig.S)
+	}
+
+	// The malleated signature recovers the same key but breaks EIP-2
+	m := sig.Malleate()
+	if m.V != 27 || crypto.ValidateSignatureValues(m.V-27, new(big.Int).SetBytes(m.R[:]), new(big.Int).SetBytes(m.S[:]), true) {
+		t.Errorf("malleated v=%d s=%x should be a valid high-s signature", m.V, m.S)
+	}
+	pub, err := crypto.Ecrecover(digest[:], append(append(m.R[:], m.S[:]...), m.V-27))
+	if err != nil || !bytes.Equal(pub, crypto.FromECDSAPub(&key.PublicKey)) {
+		t.Errorf("malleated signature recovers %x, %v", pub, err)
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	contract := common.Address{0x71}
+	signerAddr := specMail.From.Wallet
+	s.Handle("eth_call", func(call mockrpc.Call) (any, error) {
+		var msg struct {
+			To    common.Address `json:"to"`
+			Input hexutil.Bytes  `json:"input"`
+			Data  hexutil.Bytes  `json:"data"`
+		}
+		if err := call.Param(0, &msg); err != nil {
+			return nil, err
+		}
+		data := msg.Input
+		if len(data) == 0 {
+			data = msg.Data
+		}
+		method, err := ABI.MethodById(data[:4])
+		if err != nil || msg.To != contract {
+			return nil, &mockrpc.Error{Code: 3, Message: "execution reverted"}
+		}
+		args, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		var out []byte
+		switch method.Name {
+		case "domainSeparator":
+			out, _ = method.Outputs.Pack([32]byte{1})
+		case "digest":
+			out, _ = method.Outputs.Pack([32]byte{2})
+		case "recover":
+			out, _ = method.Outputs.Pack(signerAddr)
+		case "verify":
+			out, _ = method.Outputs.Pack(args[1].(common.Address) == signerAddr && args[2].(uint8) == 28)
+		}
+		return hexutil.Bytes(out), nil
+	})
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	v := Verifier{Client: client, Contract: contract}
+	ctx := context.Background()
+
+	if sep, err := v.DomainSeparator(ctx); err != nil || sep != (common.Hash{1}) {
+		t.Errorf("domain separator %s, %v", sep.Hex(), err)
+	}
+	if d, err := v.Digest(ctx, specMail); err != nil || d != (common.Hash{2}) {
+		t.Errorf("digest %s, %v", d.Hex(), err)
+	}
+	if a, err := v.Recover(ctx, specMail, Signature{V: 28}); err != nil || a != signerAddr {
+		t.Errorf("recover %s, %v", a.Hex(), err)
+	}
+	if ok, err := v.Verify(ctx, specMail, signerAddr, Signature{V: 28}); err != nil || !ok {
+		t.Errorf("verify %t, %v", ok, err)
+	}
+	if ok, err := v.Verify(ctx, specMail, common.Address{}, Signature{V: 28}); err != nil || ok {
+		t.Errorf("verify for another signer %t, %v", ok, err)
+	}
+}
@@ -0,0 +1,235 @@
+// Package vcache remembers which inputs have already been verified against
+// a given node build, so repeated local runs during node development only
+// send what the build hasn't answered correctly before.
+//
+// Entries are keyed by (node build, target, SHA-256 of the input). Only
+// verified answers are recorded; a failure is always retried.
+package vcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/golden"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+// DefaultPath is where scripts keep the cache unless told otherwise.
+const DefaultPath = "verified_cache.json"
+
+// MaxBuilds is how many node builds the cache file keeps; the least
+// recently used are dropped on Save.
+const MaxBuilds = 8
+
+// Build identifies the node build answers were verified against.
+type Build struct {
+	// ClientVersion is web3_clientVersion.
+	ClientVersion string `json:"clientVersion"`
+	// Fork is the zkEVM fork label, see golden.ForkLabel.
+	Fork string `json:"fork"`
+	// Extra distinguishes builds the node reports identically, such as a
+	// local rebuild without a version bump; typically a commit hash.
+	Extra string `json:"extra,omitempty"`
+}
+
+// Key is the build's entry in the cache file.
+func (b Build) Key() string {
+	key := b.ClientVersion + " " + b.Fork
+	if b.Extra != "" {
+		key += " " + b.Extra
+	}
+	return key
+}
+
+// DetectBuild asks the node for its client version and fork.
+func DetectBuild(ctx context.Context, client *ethclient.Client, extra string) (Build, error) {
+	b := Build{Extra: extra}
+	if err := client.Client().CallContext(ctx, &b.ClientVersion, "web3_clientVersion"); err != nil {
+		return Build{}, fmt.Errorf("failed to get client version: %w", err)
+	}
+	fork, err := golden.ForkLabel(ctx, client)
+	if err != nil {
+		return Build{}, err
+	}
+	b.Fork = fork
+	return b, nil
+}
+
+// Options are the cache flags shared by the scripts.
+type Options struct {
+	Disabled  bool
+	Path      string
+	NodeBuild string
+}
+
+// Flags registers --no-cache, --cache-file and --node-build on the default
+// flag set.
+func Flags() *Options {
+	o := &Options{}
+	flag.BoolVar(&o.Disabled, "no-cache", false, "send every vector, even those already verified against this node build")
+	flag.StringVar(&o.Path, "cache-file", paths.Work(DefaultPath), "file remembering vectors verified per node build")
+	flag.StringVar(&o.NodeBuild, "node-build", "", "extra build identity, e.g. a commit hash, for rebuilds that report the same client version")
+	return o
+}
+
+// Open loads the cache for the node's build. It returns nil, a disabled
+// cache, when --no-cache is set.
+func (o *Options) Open(ctx context.Context, client *ethclient.Client) (*Cache, error) {
+	if o.Disabled {
+		return nil, nil
+	}
+	build, err := DetectBuild(ctx, client, o.NodeBuild)
+	if err != nil {
+		return nil, err
+	}
+	return Open(o.Path, build)
+}
+
+// Cache holds the verified inputs of one build. A nil *Cache is a disabled
+// cache: nothing is verified and nothing is recorded.
+type Cache struct {
+	path   string
+	build  Build
+	file   file
+	seen   map[string]bool
+	hits   int
+	added  int
+	loaded int
+}
+
+type file struct {
+	Builds map[string]*buildEntries `json:"builds"`
+}
+
+type buildEntries struct {
+	Build
+	Used string `json:"used"`
+	// Verified maps a target to the hex SHA-256 of its verified inputs.
+	Verified map[string][]string `json:"verified"`
+}
+
+// Open loads the cache at path for build, starting empty if the file
+// doesn't exist yet.
+func Open(path string, build Build) (*Cache, error) {
+	c := &Cache{path: path, build: build, seen: map[string]bool{}}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read vector cache: %w", err)
+	default:
+		if err := json.Unmarshal(data, &c.file); err != nil {
+			return nil, fmt.Errorf("failed to parse vector cache %s: %w", path, err)
+		}
+	}
+	if c.file.Builds == nil {
+		c.file.Builds = map[string]*buildEntries{}
+	}
+	if entries := c.file.Builds[build.Key()]; entries != nil {
+		for target, hashes := range entries.Verified {
+			for _, h := range hashes {
+				c.seen[target+"/"+h] = true
+			}
+		}
+	}
+	c.loaded = len(c.seen)
+	return c, nil
+}
+
+func key(target string, input []byte) string {
+	sum := sha256.Sum256(input)
+	return target + "/" + hex.EncodeToString(sum[:])
+}
+
+// Verified reports whether input to target was verified against this build
+// before, counting a hit if so.
+func (c *Cache) Verified(target string, input []byte) bool {
+	if c == nil || !c.seen[key(target, input)] {
+		return false
+	}
+	c.hits++
+	return true
+}
+
+// Record marks input to target as verified.
+func (c *Cache) Record(target string, input []byte) {
+	if c == nil {
+		return
+	}
+	k := key(target, input)
+	if !c.seen[k] {
+		c.seen[k] = true
+		c.added++
+	}
+}
+
+// Stats returns how many inputs were loaded, skipped as verified and newly
+// recorded.
+func (c *Cache) Stats() (loaded, hits, added int) {
+	if c == nil {
+		return 0, 0, 0
+	}
+	return c.loaded, c.hits, c.added
+}
+
+// Build is the node build the cache is for.
+func (c *Cache) Build() Build { return c.build }
+
+// Save writes the cache back if anything was recorded, dropping the least
+// recently used builds beyond MaxBuilds.
+func (c *Cache) Save() error {
+	if c == nil || c.added == 0 {
+		return nil
+	}
+
+	entries := &buildEntries{
+		Build:    c.build,
+		Used:     time.Now().UTC().Format(time.RFC3339),
+		Verified: map[string][]string{},
+	}
+	for k := range c.seen {
+		// Keys are target/hash; targets may contain slashes, hashes don't
+		i := len(k) - sha256.Size*2 - 1
+		entries.Verified[k[:i]] = append(entries.Verified[k[:i]], k[i+1:])
+	}
+	for _, hashes := range entries.Verified {
+		sort.Strings(hashes)
+	}
+	c.file.Builds[c.build.Key()] = entries
+
+	if len(c.file.Builds) > MaxBuilds {
+		keys := make([]string, 0, len(c.file.Builds))
+		for k := range c.file.Builds {
+			keys = append(keys, k)
+		}
+		// Newest first, with this build ahead of others saved the same second
+		current := c.build.Key()
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i] == current || keys[j] == current {
+				return keys[i] == current
+			}
+			return c.file.Builds[keys[i]].Used > c.file.Builds[keys[j]].Used
+		})
+		for _, k := range keys[MaxBuilds:] {
+			delete(c.file.Builds, k)
+		}
+	}
+
+	data, err := json.Marshal(c.file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vector cache: %w", err)
+	}
+	if err := paths.WriteFile(c.path, data); err != nil {
+		return fmt.Errorf("failed to save vector cache: %w", err)
+	}
+	return nil
+}
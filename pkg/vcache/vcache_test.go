@@ -0,0 +1,109 @@
+package vcache
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestDetectBuild(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result("web3_clientVersion", "erigon/2.61.0/linux-amd64/go1.22.5")
+	s.Result("zkevm_getForkId", "0xc")
+
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	b, err := DetectBuild(context.Background(), client, "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "erigon/2.61.0/linux-amd64/go1.22.5 fork12 abc123"; b.Key() != want {
+		t.Errorf("key %q, want %q", b.Key(), want)
+	}
+}
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	build := Build{ClientVersion: "erigon/2.61.0", Fork: "fork12"}
+
+	c, err := Open(path, build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Verified("0x02", []byte("hello")) {
+		t.Fatal("empty cache reported a hit")
+	}
+	c.Record("0x02", []byte("hello"))
+	c.Record("wrapper@0x5FbDB2315678afecb367f032d93F642f64180aa3/sha256", []byte("hello"))
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = Open(path, build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Verified("0x02", []byte("hello")) || !c.Verified("wrapper@0x5FbDB2315678afecb367f032d93F642f64180aa3/sha256", []byte("hello")) {
+		t.Error("recorded inputs not found after reopening")
+	}
+	if c.Verified("0x02", []byte("world")) || c.Verified("0x05", []byte("hello")) {
+		t.Error("hit for an input or target never recorded")
+	}
+	if loaded, hits, added := c.Stats(); loaded != 2 || hits != 2 || added != 0 {
+		t.Errorf("stats %d/%d/%d, want 2/2/0", loaded, hits, added)
+	}
+
+	// Another build starts from scratch
+	other, err := Open(path, Build{ClientVersion: "erigon/2.61.0", Fork: "fork12", Extra: "dirty"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Verified("0x02", []byte("hello")) {
+		t.Error("entry leaked across builds")
+	}
+}
+
+func TestCacheEvictsOldBuilds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	for i := 0; i <= MaxBuilds; i++ {
+		c, err := Open(path, Build{ClientVersion: fmt.Sprintf("v%d", i), Fork: "fork12"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Record("0x02", []byte{byte(i)})
+		if err := c.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := Open(path, Build{ClientVersion: fmt.Sprintf("v%d", MaxBuilds), Fork: "fork12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(c.file.Builds); n != MaxBuilds {
+		t.Errorf("%d builds kept, want %d", n, MaxBuilds)
+	}
+	if !c.Verified("0x02", []byte{MaxBuilds}) {
+		t.Error("the build saved last was evicted")
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Record("0x02", []byte("hello"))
+	if c.Verified("0x02", []byte("hello")) {
+		t.Error("disabled cache reported a hit")
+	}
+	if err := c.Save(); err != nil {
+		t.Error(err)
+	}
+}
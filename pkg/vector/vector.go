@@ -0,0 +1,120 @@
+// Package vector carries test inputs as raw bytes. Inputs are serialized as
+// 0x-hex so binary data (fuzz inputs, arbitrary preimages) survives JSON
+// round trips unchanged, with an optional UTF-8 preview for readable ones.
+package vector
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// Vector is an input as it appears in results.
+type Vector struct {
+	Input   hexutil.Bytes `json:"input"`
+	Preview string        `json:"inputUtf8,omitempty"`
+	Tags    []string      `json:"tags,omitempty"`
+}
+
+// New wraps input, filling the preview when input is printable UTF-8.
+func New(input []byte, tags ...string) Vector {
+	return Vector{Input: input, Preview: Preview(input), Tags: tags}
+}
+
+// Select returns the vectors matched by filter, preserving order.
+func Select(vectors []Vector, filter *tags.Filter) []Vector {
+	var selected []Vector
+	for _, v := range vectors {
+		match := filter.Match(v.Tags)
+		if match {
+			selected = append(selected, v)
+		}
+		output.Logf(output.ModuleVectors, output.Debug, "%s tags %v selected=%t", v.Input, v.Tags, match)
+	}
+	output.Logf(output.ModuleVectors, output.Verbose, "selected %d of %d vectors (%s)", len(selected), len(vectors), filter)
+	return selected
+}
+
+// Bytes returns the raw input.
+func (v Vector) Bytes() []byte { return v.Input }
+
+// ID names the case by its input: the first four bytes of the input's
+// SHA-256 in hex, the same in every run and vector set.
+func (v Vector) ID() string {
+	sum := sha256.Sum256(v.Input)
+	return fmt.Sprintf("%x", sum[:4])
+}
+
+// Display renders the input for console output: the quoted text when it is
+// printable, the hex encoding otherwise.
+func (v Vector) Display() string {
+	if v.Preview != "" || len(v.Input) == 0 {
+		return fmt.Sprintf("%q", v.Preview)
+	}
+	return v.Input.String()
+}
+
+// Preview returns input as a string if it is valid UTF-8 made of printable
+// characters and whitespace, and "" otherwise.
+func Preview(input []byte) string {
+	if !utf8.Valid(input) {
+		return ""
+	}
+	s := string(input)
+	for _, r := range s {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return ""
+		}
+	}
+	return s
+}
+
+// Parse reads an input given on the command line or in a vector file:
+// "0x"-prefixed values are hex-decoded, anything else is taken as UTF-8 text.
+func Parse(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		b, err := hexutil.Decode("0x" + s[2:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex input %q: %w", s, err)
+		}
+		return b, nil
+	}
+	return []byte(s), nil
+}
+
+// Set is a named list of vectors as stored in a vector file. Inputs in the
+// file follow Parse: 0x-hex or plain text.
+type Set struct {
+	Name    string      `json:"name"`
+	Vectors []setVector `json:"vectors"`
+}
+
+type setVector struct {
+	Input string   `json:"input"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// ParseSet decodes a vector file.
+func ParseSet(data []byte) (string, []Vector, error) {
+	var set Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return "", nil, fmt.Errorf("invalid vector set: %w", err)
+	}
+	vectors := make([]Vector, 0, len(set.Vectors))
+	for i, sv := range set.Vectors {
+		input, err := Parse(sv.Input)
+		if err != nil {
+			return "", nil, fmt.Errorf("vector %d: %w", i, err)
+		}
+		vectors = append(vectors, New(input, sv.Tags...))
+	}
+	return set.Name, vectors, nil
+}
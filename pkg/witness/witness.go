@@ -0,0 +1,53 @@
+// Package witness requests the execution witness of a block, the state a
+// prover needs to re-execute it, and measures how large it is and how long
+// the node takes to produce it. Precompile-heavy blocks can grow the
+// prover's input well beyond what their gas suggests.
+package witness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultMethod is cdk-erigon's witness RPC. Nodes following go-ethereum
+// serve debug_executionWitness, which takes the same block parameter.
+const DefaultMethod = "zkevm_getWitness"
+
+// ErrUnsupported matches a node that doesn't serve the witness method.
+var ErrUnsupported = errors.New("witness method not supported")
+
+// Measurement is one block's witness.
+type Measurement struct {
+	Block uint64 `json:"block"`
+	// Size is the witness in bytes: decoded when the node answers a hex
+	// string, as cdk-erigon does, and the JSON otherwise.
+	Size       int     `json:"size"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// Fetch requests the witness of block with method.
+func Fetch(ctx context.Context, c *rpc.Client, method string, block uint64) (Measurement, error) {
+	var raw json.RawMessage
+	start := time.Now()
+	err := c.CallContext(ctx, &raw, method, hexutil.EncodeUint64(block))
+	elapsed := time.Since(start)
+	if err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+			return Measurement{}, fmt.Errorf("%w: %s: %v", ErrUnsupported, method, err)
+		}
+		return Measurement{}, fmt.Errorf("%s of block %d failed: %w", method, block, err)
+	}
+	m := Measurement{Block: block, Size: len(raw), DurationMs: float64(elapsed.Microseconds()) / 1000}
+	var encoded hexutil.Bytes
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		m.Size = len(encoded)
+	}
+	return m, nil
+}
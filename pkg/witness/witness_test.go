@@ -0,0 +1,45 @@
+package witness
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func TestFetch(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Handle(DefaultMethod, func(call mockrpc.Call) (any, error) {
+		var block string
+		if err := call.Param(0, &block); err != nil || block != "0x10" {
+			return nil, &mockrpc.Error{Code: -32000, Message: "block not found"}
+		}
+		return "0x0102030405", nil
+	})
+	s.Handle("debug_executionWitness", func(mockrpc.Call) (any, error) {
+		return map[string]any{"state": []string{"0xab"}}, nil
+	})
+	c, err := rpc.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	m, err := Fetch(context.Background(), c, DefaultMethod, 16)
+	if err != nil || m.Block != 16 || m.Size != 5 {
+		t.Errorf("hex witness: %+v, %v, want 5 bytes", m, err)
+	}
+	if m, err := Fetch(context.Background(), c, "debug_executionWitness", 16); err != nil || m.Size != len(`{"state":["0xab"]}`) {
+		t.Errorf("JSON witness: %+v, %v", m, err)
+	}
+	if _, err := Fetch(context.Background(), c, DefaultMethod, 17); err == nil || errors.Is(err, ErrUnsupported) {
+		t.Errorf("missing block: %v", err)
+	}
+	if _, err := Fetch(context.Background(), c, "zkevm_getBatchWitness", 16); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("unknown method: %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,66 @@
+package zkcounters
+
+import "sort"
+
+// Point is the counter usage of one call with an input of Bytes bytes.
+type Point struct {
+	Bytes int               `json:"bytes"`
+	Used  map[string]uint64 `json:"used,omitempty"`
+	OOC   string            `json:"oocError,omitempty"`
+	Error string            `json:"error,omitempty"`
+}
+
+// Fit is a least-squares line through one counter's usage against input
+// size: Used ≈ Intercept + PerByte × bytes.
+type Fit struct {
+	Counter   string  `json:"counter"`
+	PerByte   float64 `json:"perByte"`
+	Intercept float64 `json:"intercept"`
+	// Points is how many measurements the fit is drawn through.
+	Points int `json:"points"`
+}
+
+// Fits draws a line through every counter measured at two or more distinct
+// input sizes, leaving out failed points. Fits are sorted by counter name.
+func Fits(points []Point) []Fit {
+	xs := map[string][]float64{}
+	ys := map[string][]float64{}
+	for _, p := range points {
+		if p.Error != "" || p.OOC != "" {
+			continue
+		}
+		for name, used := range p.Used {
+			xs[name] = append(xs[name], float64(p.Bytes))
+			ys[name] = append(ys[name], float64(used))
+		}
+	}
+	var fits []Fit
+	for name := range xs {
+		if f, ok := fit(xs[name], ys[name]); ok {
+			f.Counter = name
+			fits = append(fits, f)
+		}
+	}
+	sort.Slice(fits, func(i, j int) bool { return fits[i].Counter < fits[j].Counter })
+	return fits
+}
+
+func fit(xs, ys []float64) (Fit, bool) {
+	n := float64(len(xs))
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+	mx, my := sx/n, sy/n
+	var cov, varx float64
+	for i := range xs {
+		cov += (xs[i] - mx) * (ys[i] - my)
+		varx += (xs[i] - mx) * (xs[i] - mx)
+	}
+	if varx == 0 {
+		return Fit{}, false
+	}
+	slope := cov / varx
+	return Fit{PerByte: slope, Intercept: my - slope*mx, Points: len(xs)}, true
+}
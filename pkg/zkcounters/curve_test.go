@@ -0,0 +1,26 @@
+package zkcounters
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFits(t *testing.T) {
+	points := []Point{
+		{Bytes: 0, Used: map[string]uint64{"Steps": 100, "KeccakHashes": 1}},
+		{Bytes: 64, Used: map[string]uint64{"Steps": 228, "KeccakHashes": 1}},
+		{Bytes: 128, Used: map[string]uint64{"Steps": 356, "KeccakHashes": 1, "Binaries": 7}},
+		{Bytes: 256, OOC: "not enough steps", Used: map[string]uint64{"Steps": 9999}},
+		{Bytes: 512, Error: "execution reverted"},
+	}
+	fits := Fits(points)
+	if len(fits) != 2 || fits[0].Counter != "KeccakHashes" || fits[1].Counter != "Steps" {
+		t.Fatalf("fits %+v, want KeccakHashes and Steps", fits)
+	}
+	if f := fits[0]; f.PerByte != 0 || f.Intercept != 1 || f.Points != 3 {
+		t.Errorf("flat counter %+v", f)
+	}
+	if f := fits[1]; math.Abs(f.PerByte-2) > 1e-9 || math.Abs(f.Intercept-100) > 1e-9 {
+		t.Errorf("steps %+v, want 100 + 2/byte", f)
+	}
+}
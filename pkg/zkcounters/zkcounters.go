@@ -0,0 +1,151 @@
+// Package zkcounters reads the zkEVM prover counters a call would consume,
+// via cdk-erigon's zkevm_estimateCounters. The prover limits each batch per
+// counter (keccak hashes, arithmetics, steps...), so a call well under the
+// gas limit can still be rejected as out of counters.
+package zkcounters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Method is the RPC reporting the counters.
+const Method = "zkevm_estimateCounters"
+
+// Estimate is the counter usage of one call.
+type Estimate struct {
+	// Used and Limits are keyed by counter name with the "used"/"max"
+	// prefixes dropped, e.g. "Steps" or "KeccakHashes".
+	Used   map[string]uint64 `json:"used"`
+	Limits map[string]uint64 `json:"limits"`
+	// OOC is the node's out-of-counters error, empty if the call fits.
+	OOC string `json:"oocError,omitempty"`
+}
+
+// Quantity is a counter value, sent as a hex string or a plain number
+// depending on the node version.
+type Quantity uint64
+
+// UnmarshalJSON accepts "0x1f", "31" and 31.
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n uint64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid counter value %s", data)
+		}
+		*q = Quantity(n)
+		return nil
+	}
+	var n uint64
+	var err error
+	if strings.HasPrefix(s, "0x") {
+		n, err = hexutil.DecodeUint64(s)
+	} else {
+		n, err = strconv.ParseUint(s, 10, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid counter value %q: %w", s, err)
+	}
+	*q = Quantity(n)
+	return nil
+}
+
+type response struct {
+	Used     map[string]Quantity `json:"countersUsed"`
+	Limits   map[string]Quantity `json:"countersLimits"`
+	OOCError string              `json:"oocError"`
+}
+
+// EstimateCall asks the node for the counters msg would consume.
+func EstimateCall(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg) (*Estimate, error) {
+	args := map[string]any{"data": hexutil.Bytes(msg.Data)}
+	if msg.To != nil {
+		args["to"] = msg.To
+	}
+	if msg.From != ([20]byte{}) {
+		args["from"] = msg.From
+	}
+	if msg.Gas != 0 {
+		args["gas"] = hexutil.Uint64(msg.Gas)
+	}
+
+	var resp response
+	if err := client.Client().CallContext(ctx, &resp, Method, args); err != nil {
+		return nil, fmt.Errorf("%s: %w", Method, err)
+	}
+	e := &Estimate{Used: map[string]uint64{}, Limits: map[string]uint64{}, OOC: resp.OOCError}
+	for k, v := range resp.Used {
+		e.Used[counterName(k)] = uint64(v)
+	}
+	for k, v := range resp.Limits {
+		e.Limits[counterName(k)] = uint64(v)
+	}
+	return e, nil
+}
+
+// counterName strips the prefixes that differ between usage and limits:
+// "usedSteps" and "maxSteps" are both "Steps", "gasUsed" and "maxGasUsed"
+// both "GasUsed".
+func counterName(key string) string {
+	for _, prefix := range []string{"used", "max"} {
+		if rest, ok := strings.CutPrefix(key, prefix); ok && rest != "" {
+			return rest
+		}
+	}
+	return strings.ToUpper(key[:1]) + key[1:]
+}
+
+// Utilization is the fraction of a counter's batch limit a call uses.
+type Utilization struct {
+	Counter  string  `json:"counter"`
+	Used     uint64  `json:"used"`
+	Limit    uint64  `json:"limit"`
+	Fraction float64 `json:"fraction"`
+}
+
+// Utilization returns each counter with a known limit, highest first.
+func (e *Estimate) Utilization() []Utilization {
+	var us []Utilization
+	for name, used := range e.Used {
+		limit, ok := e.Limits[name]
+		if !ok || limit == 0 {
+			continue
+		}
+		us = append(us, Utilization{Counter: name, Used: used, Limit: limit, Fraction: float64(used) / float64(limit)})
+	}
+	sort.Slice(us, func(i, j int) bool {
+		if us[i].Fraction != us[j].Fraction {
+			return us[i].Fraction > us[j].Fraction
+		}
+		return us[i].Counter < us[j].Counter
+	})
+	return us
+}
+
+// PerBatch is how many copies of the call fit in one batch before the
+// scarcest counter runs out, and which counter that is. It is zero with an
+// empty counter when no used counter has a limit.
+func (e *Estimate) PerBatch() (int, string) {
+	best, bottleneck := -1, ""
+	for _, u := range e.Utilization() {
+		if u.Used == 0 {
+			continue
+		}
+		if n := int(u.Limit / u.Used); best < 0 || n < best {
+			best, bottleneck = n, u.Counter
+		}
+	}
+	if best < 0 {
+		return 0, ""
+	}
+	return best, bottleneck
+}
@@ -0,0 +1,77 @@
+package zkcounters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/mockrpc"
+)
+
+func dial(t *testing.T, s *mockrpc.Server) *ethclient.Client {
+	t.Helper()
+	client, err := ethclient.Dial(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestEstimateCall(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	s.Result(Method, map[string]any{
+		"countersUsed": map[string]any{
+			"gasUsed":          "0x1b968",
+			"usedArithmetics":  "0x3e8",
+			"usedSteps":        2000,
+			"usedKeccakHashes": "0",
+		},
+		"countersLimits": map[string]any{
+			"maxGasUsed":          "0x1c9c380",
+			"maxArithmetics":      "0xf4240",
+			"maxSteps":            "7570538",
+			"maxKeccakHashes":     "0x8a5",
+			"maxPoseidonPaddings": "0x3e8",
+		},
+		"oocError": "",
+	})
+
+	to := common.HexToAddress("0x08")
+	e, err := EstimateCall(context.Background(), dial(t, s), ethereum.CallMsg{To: &to, Data: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Used["GasUsed"] != 113000 || e.Used["Steps"] != 2000 || e.Limits["Steps"] != 7570538 {
+		t.Errorf("decoded %+v", e)
+	}
+
+	// Arithmetics: 1000000/1000 = 1000, Steps: 3785, GasUsed: 265
+	n, bottleneck := e.PerBatch()
+	if n != 265 || bottleneck != "GasUsed" {
+		t.Errorf("per batch %d limited by %q, want 265 by GasUsed", n, bottleneck)
+	}
+	if us := e.Utilization(); len(us) != 4 || us[0].Counter != "GasUsed" {
+		t.Errorf("utilization %+v", us)
+	}
+
+	var sent map[string]string
+	if err := s.Log()[0].Param(0, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if common.HexToAddress(sent["to"]) != to || sent["data"] != "0x01" {
+		t.Errorf("sent %v", sent)
+	}
+}
+
+func TestEstimateCallUnsupported(t *testing.T) {
+	s := mockrpc.New()
+	defer s.Close()
+	if _, err := EstimateCall(context.Background(), dial(t, s), ethereum.CallMsg{}); err == nil {
+		t.Error("expected an error from a node without the method")
+	}
+}
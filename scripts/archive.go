@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/capability"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// Group statuses.
+const (
+	statusPassed  = "passed"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// ArchiveCheck is one assertion inside a test group.
+type ArchiveCheck struct {
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+}
+
+// GroupResult is the outcome of one capability-dependent test group.
+type GroupResult struct {
+	Name     string             `json:"name"`
+	Requires []string           `json:"requires"`
+	Tags     []string           `json:"tags"`
+	Status   string             `json:"status"`
+	Gaps     []capability.Probe `json:"gaps,omitempty"`
+	Reason   string             `json:"reason,omitempty"`
+	Checks   []ArchiveCheck     `json:"checks,omitempty"`
+}
+
+type ArchiveResult struct {
+	Stage        string         `json:"stage"`
+	Capabilities capability.Set `json:"capabilities"`
+	Groups       []GroupResult  `json:"groups"`
+	Passed       int            `json:"passed"`
+	Failed       int            `json:"failed"`
+	Skipped      int            `json:"skipped"`
+	Timestamp    string         `json:"timestamp"`
+	RPCURL       string         `json:"rpcUrl"`
+}
+
+// testGroup runs its checks only when every required capability is present.
+type testGroup struct {
+	name     string
+	requires []string
+	tags     []string
+	run      func(ctx context.Context, client *ethclient.Client, head uint64) []ArchiveCheck
+}
+
+var archiveInput = []byte("hello world")
+
+var testGroups = []testGroup{
+	{"historical-call", []string{capability.HistoricalState}, []string{tags.Archive}, runHistoricalCalls},
+	{"debug-trace", []string{capability.Debug}, []string{tags.Archive}, runDebugTrace},
+	{"trace-call", []string{capability.Trace}, []string{tags.Archive}, runTraceCall},
+}
+
+func main() {
+	output.Setup()
+
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_archive.json")
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	caps, err := capability.Detect(ctx, client)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Println("\n🔎 Endpoint capabilities:")
+	for _, name := range []string{capability.HistoricalState, capability.Debug, capability.Trace} {
+		if caps.Has(name) {
+			fmt.Printf("✅ %s\n", name)
+		} else {
+			fmt.Printf("⚪ %s (not supported)\n", name)
+		}
+	}
+
+	result := ArchiveResult{
+		Stage:        "Archive - Capability-Dependent Tests",
+		Capabilities: caps,
+		RPCURL:       rpcURL,
+	}
+
+	for _, g := range testGroups {
+		gr := GroupResult{Name: g.name, Requires: g.requires, Tags: g.tags}
+		if !tagFilter.Match(g.tags) {
+			gr.Status = statusSkipped
+			gr.Reason = fmt.Sprintf("tag filter (%s)", tagFilter)
+			result.Skipped++
+			result.Groups = append(result.Groups, gr)
+			continue
+		}
+		if gaps := caps.Missing(g.requires...); len(gaps) > 0 {
+			gr.Status = statusSkipped
+			gr.Gaps = gaps
+			result.Skipped++
+			result.Groups = append(result.Groups, gr)
+			continue
+		}
+
+		gr.Checks = g.run(ctx, client, caps.Head)
+		gr.Status = statusPassed
+		for _, c := range gr.Checks {
+			if !c.Passed {
+				gr.Status = statusFailed
+			}
+		}
+		if gr.Status == statusPassed {
+			result.Passed++
+		} else {
+			result.Failed++
+		}
+		result.Groups = append(result.Groups, gr)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	// Save results
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_archive.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Test groups:")
+	for _, gr := range result.Groups {
+		switch gr.Status {
+		case statusSkipped:
+			if gr.Reason != "" {
+				fmt.Printf("⏭️  %s skipped (%s)\n", gr.Name, gr.Reason)
+				continue
+			}
+			var missing []string
+			for _, gap := range gr.Gaps {
+				missing = append(missing, gap.Name)
+			}
+			fmt.Printf("⏭️  %s skipped (missing: %s)\n", gr.Name, strings.Join(missing, ", "))
+		case statusPassed:
+			fmt.Printf("✅ %s (%d checks)\n", gr.Name, len(gr.Checks))
+		default:
+			fmt.Printf("❌ %s\n", gr.Name)
+			for _, c := range gr.Checks {
+				if !c.Passed {
+					fmt.Printf("   %s: expected %s, got %s %s\n", c.Name, c.Expected, c.Actual, c.Error)
+				}
+			}
+		}
+	}
+	fmt.Printf("\n📊 Passed: %d, Failed: %d, Skipped: %d\n", result.Passed, result.Failed, result.Skipped)
+	fmt.Println("📝 Results saved to results_archive.json")
+
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runHistoricalCalls calls the precompile at several historical blocks; the
+// result must not depend on the block.
+func runHistoricalCalls(ctx context.Context, client *ethclient.Client, head uint64) []ArchiveCheck {
+	expected := fmt.Sprintf("%x", sha256.Sum256(archiveInput))
+	precompile := common.HexToAddress("0x02")
+
+	var checks []ArchiveCheck
+	for _, block := range []uint64{1, head / 4, head / 2} {
+		if block == 0 {
+			continue
+		}
+		c := ArchiveCheck{Name: fmt.Sprintf("eth_call@%d", block), Expected: expected}
+		out, err := client.CallContract(ctx, ethereum.CallMsg{To: &precompile, Data: archiveInput}, new(big.Int).SetUint64(block))
+		if err != nil {
+			c.Error = err.Error()
+		} else {
+			c.Actual = fmt.Sprintf("%x", out)
+			c.Passed = c.Actual == expected
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// runDebugTrace traces a call to the precompile with the callTracer and
+// checks the recorded output is the hash.
+func runDebugTrace(ctx context.Context, client *ethclient.Client, head uint64) []ArchiveCheck {
+	var frame struct {
+		To     common.Address `json:"to"`
+		Output hexutil.Bytes  `json:"output"`
+	}
+	err := client.Client().CallContext(ctx, &frame, "debug_traceCall",
+		precompileCallArgs(), "latest", map[string]string{"tracer": "callTracer"})
+	return []ArchiveCheck{outputCheck("debug_traceCall", frame.Output, err)}
+}
+
+// runTraceCall does the same through the parity-style trace namespace.
+func runTraceCall(ctx context.Context, client *ethclient.Client, head uint64) []ArchiveCheck {
+	var res struct {
+		Trace []struct {
+			Result *struct {
+				Output hexutil.Bytes `json:"output"`
+			} `json:"result"`
+		} `json:"trace"`
+	}
+	err := client.Client().CallContext(ctx, &res, "trace_call", precompileCallArgs(), []string{"trace"}, "latest")
+	var output []byte
+	if err == nil {
+		if len(res.Trace) == 0 || res.Trace[0].Result == nil {
+			err = fmt.Errorf("trace_call returned no top-level result")
+		} else {
+			output = res.Trace[0].Result.Output
+		}
+	}
+	return []ArchiveCheck{outputCheck("trace_call", output, err)}
+}
+
+func precompileCallArgs() map[string]any {
+	return map[string]any{"to": common.HexToAddress("0x02"), "data": hexutil.Bytes(archiveInput)}
+}
+
+func outputCheck(name string, output []byte, err error) ArchiveCheck {
+	c := ArchiveCheck{Name: name, Expected: fmt.Sprintf("%x", sha256.Sum256(archiveInput))}
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	c.Actual = fmt.Sprintf("%x", output)
+	c.Passed = c.Actual == c.Expected
+	return c
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+)
+
+func main() {
+	output.Setup()
+
+	dir := flag.String("dir", paths.ArtifactsDir(), "directory of the compiled .bin and .abi files")
+	sources := flag.String("contracts", "contracts", "directory of the contract sources")
+	lockPath := flag.String("lock", deploy.LockPath, "lock file to write or check")
+	check := flag.Bool("check", false, "verify every locked artifact instead of regenerating the lock")
+	version := flag.String("solc-version", "", "compiler version (default: solc --version, or the version in the bytecode metadata)")
+	optimize := flag.Bool("optimize", false, "the artifacts were compiled with --optimize")
+	runs := flag.Int("optimize-runs", 0, "value of --optimize-runs, if given")
+	evmVersion := flag.String("evm-version", "", "value of --evm-version, if given")
+	viaIR := flag.Bool("via-ir", false, "the artifacts were compiled with --via-ir")
+	flag.Parse()
+
+	if *check {
+		runCheck(*lockPath)
+		return
+	}
+
+	compiler := deploy.Compiler{Version: *version, Optimize: *optimize, Runs: *runs, EVMVersion: *evmVersion, ViaIR: *viaIR}
+	if compiler.Version == "" {
+		compiler.Version = installedSolc()
+	}
+	lock, err := deploy.GenerateLock(*dir, *sources, compiler)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	for _, name := range lock.Names() {
+		e := lock.Artifacts[name]
+		if lock.Compiler.Version == "" {
+			lock.Compiler.Version = e.Solc
+		}
+		// Artifacts compiled by another solc than declared are stale
+		if e.Solc != "" && e.Solc != lock.Compiler.Version {
+			log.Fatalf("❌ %s was compiled by solc %s, not %s; recompile it or pass --solc-version", name, e.Solc, lock.Compiler.Version)
+		}
+		source := e.Source
+		if source == "" {
+			source = "no source found"
+		}
+		fmt.Printf("🔒 %s: bin %s, abi %s (%s)\n", name, e.Bin[:12], e.ABI[:12], source)
+	}
+	if lock.Compiler.Version == "" {
+		log.Fatal("❌ Could not determine the compiler version; pass --solc-version")
+	}
+
+	if err := lock.Save(*lockPath); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("\n📝 Locked %d artifacts compiled with solc %s to %s\n", len(lock.Artifacts), lock.Compiler.Version, *lockPath)
+}
+
+// runCheck verifies every artifact in the lock, reporting the first
+// mismatch of each, and exits 1 if any doesn't match.
+func runCheck(lockPath string) {
+	lock, err := deploy.LoadLock(lockPath)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	failed := 0
+	for _, name := range lock.Names() {
+		err := lock.Verify(name)
+		switch {
+		case err == nil:
+			fmt.Printf("✅ %s\n", name)
+			continue
+		case errors.Is(err, deploy.ErrArtifactStale):
+			fmt.Printf("🕰️  %s is stale: %v\n", name, err)
+		default:
+			fmt.Printf("❌ %s: %v\n", name, err)
+		}
+		failed++
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d artifacts don't match %s; recompile and rerun go run scripts/artifacts_lock.go\n", failed, len(lock.Artifacts), lockPath)
+		os.Exit(1)
+	}
+}
+
+var solcVersionPattern = regexp.MustCompile(`Version: (\d+\.\d+\.\d+)`)
+
+// installedSolc returns the version of solc on PATH, or "" if there is none.
+func installedSolc() string {
+	out, err := exec.Command("solc", "--version").Output()
+	if err != nil {
+		return ""
+	}
+	if m := solcVersionPattern.FindSubmatch(out); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/bench"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/profiling"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/stream"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+type BenchmarkResult struct {
+	Stage  string `json:"stage"`
+	Target string `json:"target"`
+	vector.Vector
+	Runs       int               `json:"runs"`
+	Warmup     int               `json:"warmup"`
+	OutlierK   float64           `json:"outlierK"`
+	Summary    bench.Summary     `json:"summary"`
+	Comparison *bench.Comparison `json:"comparison,omitempty"`
+	Mismatches int               `json:"mismatches"`
+	// Reconnects counts the times the connection dropped and was
+	// redialled; the calls it interrupted are measured on their retry.
+	Reconnects int64            `json:"reconnects,omitempty"`
+	Runtime    *profiling.Stats `json:"runtime,omitempty"`
+	Timestamp  string           `json:"timestamp"`
+	RPCURL     string           `json:"rpcUrl"`
+}
+
+// BenchmarkSample is one measured call, streamed as NDJSON when --stream is set.
+type BenchmarkSample struct {
+	Seq       int     `json:"seq"`
+	Target    string  `json:"target"`
+	LatencyMs float64 `json:"latencyMs"`
+	Match     bool    `json:"match"`
+	Error     string  `json:"error,omitempty"`
+	Timestamp string  `json:"timestamp"`
+}
+
+func main() {
+	output.Setup()
+
+	target := flag.String("target", "raw", "what to benchmark: raw (precompile 0x02) or wrapper (Sha256Wrapper.sha256Hash)")
+	inputFlag := flag.String("input", "hello world", "input passed to sha256 (0x-prefixed values are hex-decoded)")
+	runs := flag.Int("runs", 50, "number of measured repetitions")
+	warmup := flag.Int("warmup", 5, "number of unmeasured warm-up iterations")
+	outlierK := flag.Float64("outlier-k", 1.5, "Tukey fence multiplier for outlier rejection (0 disables)")
+	baselinePath := flag.String("baseline", "bench_baseline.json", "baseline file to compare against")
+	saveBaseline := flag.Bool("save-baseline", false, "record this run as the new baseline")
+	maxRegression := flag.Float64("max-regression", 0, "fail if median latency regresses by more than this percent vs baseline (0 disables)")
+	pprofAddr := flag.String("pprof", "", "serve pprof endpoints on this address (e.g. 127.0.0.1:6060)")
+	statsInterval := flag.Duration("runtime-stats", 0, "print harness memory/goroutine stats at this interval (0 disables)")
+	streamPath := flag.String("stream", "", "stream per-call samples as NDJSON to this file (- for stdout)")
+	rotateMB := flag.Int64("rotate-size", 0, "rotate the stream file after this many MiB (0 disables)")
+	rotateEvery := flag.Duration("rotate-every", 0, "rotate the stream file after this long (0 disables)")
+	maxBackups := flag.Int("max-backups", 0, "keep at most this many rotated stream segments (0 keeps all)")
+	compress := flag.Bool("compress", true, "gzip rotated stream segments")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Slow}) {
+		fmt.Printf("⏭️  Benchmark skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	if *runs <= 0 {
+		log.Fatal("❌ --runs must be positive")
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	reconnect := rpcclient.DefaultReconnectOptions
+	reconnect.OnReconnect = func(attempts int, cause error) {
+		fmt.Printf("🔌 Reconnected to %s after %d attempts (%v)\n", rpcURL, attempts, cause)
+	}
+	conn, err := rpcclient.DialConn(ctx, rpcURL, reconnect)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer conn.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, conn.Client(), "results_benchmark.json")
+
+	// Profile the harness itself when requested
+	if *pprofAddr != "" {
+		server, err := profiling.Start(*pprofAddr)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		defer server.Close()
+		fmt.Printf("🩺 pprof listening on http://%s/debug/pprof/\n", server.Addr)
+	}
+	if *statsInterval > 0 {
+		monitorCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go profiling.Monitor(monitorCtx, *statsInterval, func(s profiling.Stats) {
+			fmt.Printf("🩺 harness: %s\n", s)
+		})
+	}
+
+	var samplesOut *stream.Writer
+	if *streamPath != "" {
+		samplesOut, err = stream.OpenRotated(*streamPath, stream.RotateOptions{
+			MaxBytes:   *rotateMB << 20,
+			MaxAge:     *rotateEvery,
+			MaxBackups: *maxBackups,
+			Compress:   *compress,
+		})
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		defer samplesOut.Close()
+	}
+
+	input, err := vector.Parse(*inputFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	call, err := buildCall(ctx, conn.Client(), *target, input)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Warm up connections and node caches without recording
+	fmt.Printf("🔥 Warm-up: %d iterations\n", *warmup)
+	for i := 0; i < *warmup; i++ {
+		err := conn.Do(ctx, func(client *ethclient.Client) error {
+			_, err := call(ctx, client)
+			return err
+		})
+		if err != nil {
+			log.Fatalf("❌ Warm-up call failed: %v", err)
+		}
+	}
+
+	// Measured runs
+	fmt.Printf("⏱️  Measuring %d runs against %s target...\n", *runs, *target)
+	expected := sha256.Sum256(input)
+	samples := make([]time.Duration, 0, *runs)
+	mismatches := 0
+	for i := 0; i < *runs; i++ {
+		var got [32]byte
+		var elapsed time.Duration
+		err := conn.Do(ctx, func(client *ethclient.Client) error {
+			start := time.Now()
+			var err error
+			got, err = call(ctx, client)
+			elapsed = time.Since(start)
+			return err
+		})
+		if samplesOut != nil {
+			sample := BenchmarkSample{
+				Seq:       i,
+				Target:    *target,
+				LatencyMs: float64(elapsed) / float64(time.Millisecond),
+				Match:     err == nil && got == expected,
+				Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+			}
+			if err != nil {
+				sample.Error = err.Error()
+			}
+			if werr := samplesOut.Write(sample); werr != nil {
+				log.Printf("⚠️  %v", werr)
+			}
+		}
+		if err != nil {
+			log.Fatalf("❌ Call %d failed: %v", i, err)
+		}
+		if got != expected {
+			mismatches++
+		}
+		samples = append(samples, elapsed)
+	}
+
+	summary := bench.Summarize(bench.Millis(samples), *outlierK)
+	result := BenchmarkResult{
+		Stage:      "Benchmark",
+		Target:     *target,
+		Vector:     vector.New(input),
+		Runs:       *runs,
+		Warmup:     *warmup,
+		OutlierK:   *outlierK,
+		Summary:    summary,
+		Mismatches: mismatches,
+		Reconnects: conn.Reconnects(),
+		Runtime:    runtimeStats(*pprofAddr != "" || *statsInterval > 0),
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		RPCURL:     rpcURL,
+	}
+
+	// Compare against stored baseline
+	baseline, err := bench.LoadBaseline(*baselinePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if baseline != nil {
+		cmp := bench.Compare(baseline, summary, *maxRegression)
+		result.Comparison = &cmp
+	}
+
+	printSummary(result)
+
+	if err := saveBenchmarkResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Println("📝 Results saved to results_benchmark.json")
+
+	if *saveBaseline {
+		if err := bench.SaveBaseline(*baselinePath, *target, rpcURL, summary); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("📌 Baseline recorded in %s\n", *baselinePath)
+	}
+
+	if mismatches > 0 {
+		log.Fatalf("❌ %d of %d calls returned an unexpected hash", mismatches, *runs)
+	}
+	if result.Comparison != nil && result.Comparison.Regressed {
+		log.Fatalf("❌ Median latency regressed by %.1f%% (threshold %.1f%%)",
+			result.Comparison.DeltaPct, result.Comparison.ThresholdPct)
+	}
+}
+
+// buildCall returns a function performing one measured call for the chosen target.
+func buildCall(ctx context.Context, client *ethclient.Client, target string, input []byte) (func(context.Context, *ethclient.Client) ([32]byte, error), error) {
+	switch target {
+	case "raw":
+		precompile := common.HexToAddress("0x02")
+		msg := ethereum.CallMsg{To: &precompile, Data: input}
+		return func(ctx context.Context, client *ethclient.Client) ([32]byte, error) {
+			var hash [32]byte
+			out, err := client.CallContract(ctx, msg, nil)
+			if err != nil {
+				return hash, err
+			}
+			copy(hash[:], out)
+			return hash, nil
+		}, nil
+
+	case "wrapper":
+		wrapperAddress, version, err := precompile.LoadWrapper(ctx, client)
+		if err != nil {
+			return nil, fmt.Errorf("❌ %v", err)
+		}
+		parsedABI := version.ABI()
+		callData, err := parsedABI.Pack("sha256Hash", input)
+		if err != nil {
+			return nil, fmt.Errorf("❌ Failed to pack ABI call: %v", err)
+		}
+
+		msg := ethereum.CallMsg{To: &wrapperAddress, Data: callData}
+		return func(ctx context.Context, client *ethclient.Client) ([32]byte, error) {
+			var hash [32]byte
+			out, err := client.CallContract(ctx, msg, nil)
+			if err != nil {
+				return hash, err
+			}
+			unpacked, err := parsedABI.Unpack("sha256Hash", out)
+			if err != nil {
+				return hash, fmt.Errorf("failed to unpack result: %v", err)
+			}
+			hash, ok := unpacked[0].([32]byte)
+			if !ok {
+				return hash, fmt.Errorf("unexpected return type: %T", unpacked[0])
+			}
+			return hash, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("❌ Unknown target %q (want raw or wrapper)", target)
+}
+
+// runtimeStats captures the harness's own resource usage when profiling is enabled.
+func runtimeStats(enabled bool) *profiling.Stats {
+	if !enabled {
+		return nil
+	}
+	stats := profiling.Snapshot()
+	return &stats
+}
+
+func printSummary(result BenchmarkResult) {
+	s := result.Summary
+	fmt.Println("\n📊 Latency summary:")
+	fmt.Printf("  Samples: %s kept, %s outliers discarded\n", output.Count(s.Samples), output.Count(s.Discarded))
+	fmt.Printf("  Mean:    %s\n", output.Millis(s.Mean))
+	fmt.Printf("  Median:  %s\n", output.Millis(s.Median))
+	fmt.Printf("  StdDev:  %s\n", output.Millis(s.StdDev))
+	fmt.Printf("  Min/Max: %s / %s\n", output.Millis(s.Min), output.Millis(s.Max))
+	fmt.Printf("  P95:     %s\n", output.Millis(s.P95))
+	if result.Reconnects > 0 {
+		fmt.Printf("  Reconnects: %d\n", result.Reconnects)
+	}
+
+	if cmp := result.Comparison; cmp != nil {
+		status := "✅"
+		if cmp.Regressed {
+			status = "❌"
+		}
+		fmt.Printf("%s Median vs baseline: %s -> %s (%+.1f%%)\n",
+			status, output.Millis(cmp.BaselineMedian), output.Millis(cmp.CurrentMedian), cmp.DeltaPct)
+	}
+	if result.Runtime != nil {
+		fmt.Printf("🩺 Harness at end of run: %s\n", result.Runtime)
+	}
+}
+
+func saveBenchmarkResult(result BenchmarkResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_benchmark.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
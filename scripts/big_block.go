@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/merkle"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// FillerTx is one transaction of the filler, by the nonce it was signed at.
+type FillerTx struct {
+	Nonce     uint64 `json:"nonce"`
+	Hash      string `json:"hash"`
+	SendError string `json:"sendError,omitempty"`
+	Mined     bool   `json:"mined"`
+	Block     uint64 `json:"block,omitempty"`
+	Index     uint   `json:"index,omitempty"`
+	Status    uint64 `json:"status,omitempty"`
+	GasUsed   uint64 `json:"gasUsed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// FilledBlock is a block that included filler transactions. Fill is its
+// gasUsed over its gasLimit.
+type FilledBlock struct {
+	Number       uint64        `json:"number"`
+	Hash         string        `json:"hash"`
+	GasLimit     uint64        `json:"gasLimit"`
+	GasUsed      uint64        `json:"gasUsed"`
+	Fill         float64       `json:"fill"`
+	Transactions int           `json:"transactions"`
+	Filler       int           `json:"filler"`
+	Checks       []chain.Check `json:"checks"`
+}
+
+type BigBlockResult struct {
+	Stage      string `json:"stage"`
+	Mode       string `json:"mode"`
+	Contract   string `json:"contract"`
+	Precompile string `json:"precompile"`
+	From       string `json:"from"`
+	// GasLimit is the latest block's when the filler was planned, Target
+	// the gas the filler transactions' limits add up to.
+	GasLimit uint64 `json:"gasLimit"`
+	Target   uint64 `json:"target"`
+	// Leaves is the size of the tree each transaction builds, taking
+	// Hashes sha256 calls and EstimateGas gas.
+	Leaves       int           `json:"leaves"`
+	Hashes       int           `json:"hashes"`
+	EstimateGas  uint64        `json:"estimateGas"`
+	Transactions []FillerTx    `json:"transactions"`
+	Blocks       []FilledBlock `json:"blocks"`
+	Checks       []chain.Check `json:"checks"`
+	Passed       bool          `json:"passed"`
+	Timestamp    string        `json:"timestamp"`
+	RPCURL       string        `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	mode := flag.String("mode", "many", "how to fill the block: many transactions of --tx-gas each, or one huge one")
+	fill := flag.Float64("fill", 0.9, "fraction of the block gas limit the filler transactions' gas limits add up to")
+	txGas := flag.Uint64("tx-gas", 1_000_000, "gas limit of each transaction in --mode many")
+	contractFlag := flag.String("contract", "", "Sha256Merkle address to use instead of the saved one")
+	timeout := flag.Duration("timeout", 3*time.Minute, "how long to wait for each receipt")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if *mode != "many" && *mode != "one" {
+		log.Fatalf("❌ --mode must be many or one, got %q", *mode)
+	}
+	if *fill <= 0 || *fill > 1 {
+		log.Fatalf("❌ --fill must be in (0, 1], got %g", *fill)
+	}
+	if !tagFilter.Match([]string{tags.Writes, tags.Gas, tags.Slow}) {
+		fmt.Printf("⏭️  Big-block filler skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_big_block.json")
+
+	address, err := fillerContract(ctx, client, *contractFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	contract := merkle.Contract{Client: client, Address: address}
+	fmt.Printf("📌 Using Sha256Merkle at %s\n", address.Hex())
+
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Fatalf("❌ Failed to get latest block: %v", err)
+	}
+	target := uint64(*fill * float64(header.GasLimit))
+	perTx, count := target, 1
+	if *mode == "many" {
+		if *txGas > target {
+			log.Fatalf("❌ --tx-gas %s exceeds the target of %s gas", output.Count(*txGas), output.Count(target))
+		}
+		perTx, count = *txGas, int(target / *txGas)
+	}
+
+	leaves, estimate, err := leavesWithin(ctx, contract, perTx)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	data, err := merkle.PackRoot(fillerLeaves(leaves))
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleInvoke)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	sender.ReceiptTimeout = *timeout
+
+	result := BigBlockResult{
+		Stage:       "Big Block - Precompile Calls Near the Block Gas Limit",
+		Mode:        *mode,
+		Contract:    address.Hex(),
+		Precompile:  precompile.SHA256Address.Hex(),
+		From:        sender.From.Hex(),
+		GasLimit:    header.GasLimit,
+		Target:      perTx * uint64(count),
+		Leaves:      leaves,
+		Hashes:      merkle.Hashes(leaves),
+		EstimateGas: estimate,
+		RPCURL:      rpcURL,
+	}
+	fmt.Printf("🧱 Filling %s of the %s block gas limit: %d transactions of %s gas, each building a %s-leaf root with %s sha256 calls\n",
+		output.Count(result.Target), output.Count(header.GasLimit), count, output.Count(perTx), output.Count(leaves), output.Count(result.Hashes))
+
+	signed, err := submitFiller(ctx, client, sender, address, data, perTx, count, &result)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if ctx.Err() != nil {
+		log.Fatalf("❌ Interrupted: %v", ctx.Err())
+	}
+	result.Checks = fillerChecks(result.Transactions)
+	result.Blocks = filledBlocks(ctx, client, signed, result.Transactions)
+	blocks := make([]string, len(result.Blocks))
+	for i, b := range result.Blocks {
+		blocks[i] = fmt.Sprint(b.Number)
+	}
+	check := chain.Check{Name: "packed into one block", Expected: "1 block", Actual: fmt.Sprintf("%d blocks", len(result.Blocks)), Passed: len(result.Blocks) == 1}
+	if len(blocks) > 0 {
+		check.Note = "blocks " + strings.Join(blocks, ", ")
+	}
+	result.Checks = append(result.Checks, check)
+
+	result.Passed = chain.AllPassed(result.Checks)
+	for _, b := range result.Blocks {
+		result.Passed = result.Passed && chain.AllPassed(b.Checks)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_big_block.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Big-block results:")
+	for _, b := range result.Blocks {
+		fmt.Printf("📦 Block %d: %d of its %d transactions ours, %s of %s gas used (%.1f%%)\n",
+			b.Number, b.Filler, b.Transactions, output.Count(b.GasUsed), output.Count(b.GasLimit), 100*b.Fill)
+		printFillerChecks(b.Checks)
+	}
+	printFillerChecks(result.Checks)
+	fmt.Println("\n📝 Results saved to results_big_block.json")
+	if !result.Passed {
+		os.Exit(1)
+	}
+}
+
+// fillerContract picks the Sha256Merkle to call: the --contract address or
+// the one merkle.go saved in deployed_merkle_address.txt.
+func fillerContract(ctx context.Context, client *ethclient.Client, override string) (common.Address, error) {
+	if override != "" {
+		if !common.IsHexAddress(override) {
+			return common.Address{}, fmt.Errorf("invalid --contract address %q", override)
+		}
+		address := common.HexToAddress(override)
+		if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+			return common.Address{}, err
+		}
+		return address, nil
+	}
+	address, err := paths.ReadAddress(paths.Work("deployed_merkle_address.txt"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("no Sha256Merkle deployed (run scripts/merkle.go first or pass --contract): %v", err)
+	}
+	if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+		return common.Address{}, err
+	}
+	return address, nil
+}
+
+// fillerLeaves are n leaves, the same on every run: the sha256 of each
+// index, as merkle.go builds.
+func fillerLeaves(n int) []common.Hash {
+	leaves := make([]common.Hash, n)
+	for i := range leaves {
+		leaves[i] = sha256.Sum256(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+	return leaves
+}
+
+// leavesWithin finds the largest tree whose root eth_estimateGas says can
+// be built within gas, doubling the leaves until it can't and then
+// bisecting. An estimate failing counts as not fitting, since nodes cap
+// eth_estimateGas at the block gas limit or below.
+func leavesWithin(ctx context.Context, contract merkle.Contract, gas uint64) (int, uint64, error) {
+	fits := func(n int) (uint64, bool) {
+		estimate, err := contract.EstimateRoot(ctx, fillerLeaves(n))
+		return estimate, err == nil && estimate <= gas
+	}
+	best, ok := fits(1)
+	if !ok {
+		return 0, 0, fmt.Errorf("a 1-leaf root doesn't fit in %s gas", output.Count(gas))
+	}
+	lo, hi := 1, 2
+	for {
+		estimate, ok := fits(hi)
+		if !ok {
+			break
+		}
+		lo, best, hi = hi, estimate, hi*2
+	}
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if estimate, ok := fits(mid); ok {
+			lo, best = mid, estimate
+		} else {
+			hi = mid
+		}
+	}
+	return lo, best, ctx.Err()
+}
+
+// submitFiller signs count transactions calling to with data at
+// consecutive nonces before submitting any, so they reach the pool
+// together, then waits for all of them. A rejected transaction stops the
+// submission, as those after it would queue behind the gap.
+func submitFiller(ctx context.Context, client *ethclient.Client, sender *chain.Sender, to common.Address, data []byte, gas uint64, count int, result *BigBlockResult) ([]*types.Transaction, error) {
+	base, err := client.PendingNonceAt(ctx, sender.From)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	signed := make([]*types.Transaction, count)
+	result.Transactions = make([]FillerTx, count)
+	for i := range signed {
+		if signed[i], err = sender.SignAt(ctx, base+uint64(i), &to, data, gas); err != nil {
+			return nil, err
+		}
+		result.Transactions[i] = FillerTx{Nonce: base + uint64(i), Hash: signed[i].Hash().Hex()}
+	}
+
+	sent := 0
+	for i, tx := range signed {
+		if err := sender.Submit(ctx, tx); err != nil {
+			result.Transactions[i].SendError = err.Error()
+			break
+		}
+		sent++
+	}
+	fmt.Printf("📨 Submitted %d of %d transactions from nonce %d\n", sent, count, base)
+
+	for i, tx := range signed[:sent] {
+		receipt, err := sender.Await(ctx, tx)
+		if err != nil {
+			result.Transactions[i].Error = err.Error()
+			continue
+		}
+		t := &result.Transactions[i]
+		t.Mined, t.Block, t.Index, t.Status, t.GasUsed = true, receipt.BlockNumber.Uint64(), receipt.TransactionIndex, receipt.Status, receipt.GasUsed
+	}
+	return signed, nil
+}
+
+// fillerChecks checks every transaction was accepted by the pool, mined
+// and succeeded. Those after a rejected one were never submitted.
+func fillerChecks(txs []FillerTx) []chain.Check {
+	sent := len(txs)
+	var mined, failed int
+	var rejected string
+	for i, tx := range txs {
+		if tx.SendError != "" {
+			sent, rejected = i, fmt.Sprintf("nonce %d: %s", tx.Nonce, tx.SendError)
+			break
+		}
+		if tx.Mined {
+			mined++
+			if tx.Status != types.ReceiptStatusSuccessful {
+				failed++
+			}
+		}
+	}
+	return []chain.Check{
+		{Name: "every transaction accepted", Expected: fmt.Sprint(len(txs)), Actual: fmt.Sprint(sent), Passed: sent == len(txs), Note: rejected},
+		{Name: "every transaction mined", Expected: fmt.Sprint(len(txs)), Actual: fmt.Sprint(mined), Passed: mined == len(txs)},
+		{Name: "every receipt succeeded", Expected: "0 failed", Actual: fmt.Sprintf("%d failed", failed), Passed: mined > 0 && failed == 0},
+	}
+}
+
+// filledBlocks describes each block holding mined filler transactions, in
+// block order, and verifies it was sealed as its header says: the same by
+// number and by hash, with roots and gasUsed matching its body and
+// receipts.
+func filledBlocks(ctx context.Context, client *ethclient.Client, signed []*types.Transaction, txs []FillerTx) []FilledBlock {
+	first := map[uint64]int{}
+	filler := map[uint64]int{}
+	for i, tx := range txs {
+		if !tx.Mined {
+			continue
+		}
+		if _, ok := first[tx.Block]; !ok {
+			first[tx.Block] = i
+		}
+		filler[tx.Block]++
+	}
+	numbers := make([]uint64, 0, len(first))
+	for n := range first {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	var blocks []FilledBlock
+	for _, n := range numbers {
+		b := FilledBlock{Number: n, Filler: filler[n]}
+		receipt, err := client.TransactionReceipt(ctx, signed[first[n]].Hash())
+		if err != nil {
+			b.Checks = []chain.Check{{Name: "filler receipt", Note: err.Error()}}
+			blocks = append(blocks, b)
+			continue
+		}
+		block, err := client.BlockByHash(ctx, receipt.BlockHash)
+		if err != nil {
+			b.Checks = []chain.Check{{Name: "block by hash", Note: err.Error()}}
+			blocks = append(blocks, b)
+			continue
+		}
+		b.Hash, b.GasLimit, b.GasUsed, b.Transactions = block.Hash().Hex(), block.GasLimit(), block.GasUsed(), len(block.Transactions())
+		if b.GasLimit > 0 {
+			b.Fill = float64(b.GasUsed) / float64(b.GasLimit)
+		}
+		b.Checks = chain.CheckBlock(ctx, client, signed[first[n]], receipt)
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func printFillerChecks(checks []chain.Check) {
+	for _, c := range checks {
+		switch {
+		case c.Skipped:
+			fmt.Printf("   ⏭️  %s: %s\n", c.Name, c.Note)
+		case c.Passed:
+			fmt.Printf("   ✅ %s: %s\n", c.Name, c.Actual)
+		default:
+			fmt.Printf("   ❌ %s: got %s, want %s %s\n", c.Name, c.Actual, c.Expected, c.Note)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/blob"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// Outcomes of a blob case.
+const (
+	// OutcomeAccepted is a valid transaction mined with consistent
+	// receipt, transaction and block fields.
+	OutcomeAccepted = "accepted"
+	// OutcomeRejected is a transaction refused with a JSON-RPC error.
+	OutcomeRejected = "rejected"
+	// OutcomeMishandled is an invalid transaction accepted, a valid one
+	// mined with wrong fields or never mined, or a submission answered
+	// without a JSON-RPC error.
+	OutcomeMishandled = "mishandled"
+	// OutcomeInconclusive is a valid transaction refused for reasons
+	// unrelated to blobs: funds, nonce or price.
+	OutcomeInconclusive = "inconclusive"
+)
+
+// blobCase is one transaction offered to the node. build returns the
+// sidecar to send and the versioned hashes to claim, nil for those of the
+// sidecar.
+type blobCase struct {
+	name  string
+	valid bool
+	blobs int
+	build func(sc *types.BlobTxSidecar, other *types.BlobTxSidecar) (*types.BlobTxSidecar, []common.Hash)
+}
+
+func asBuilt(sc, _ *types.BlobTxSidecar) (*types.BlobTxSidecar, []common.Hash) { return sc, nil }
+
+// BlobCaseResult is what the node did with one case.
+type BlobCaseResult struct {
+	Name    string        `json:"name"`
+	Valid   bool          `json:"valid"`
+	Blobs   int           `json:"blobs"`
+	Outcome string        `json:"outcome"`
+	Tx      string        `json:"tx,omitempty"`
+	Block   uint64        `json:"block,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Checks  []chain.Check `json:"checks,omitempty"`
+}
+
+type BlobTxResult struct {
+	Stage string `json:"stage"`
+	// CancunHeader reports whether the head block carries the blob gas
+	// fields.
+	CancunHeader bool             `json:"cancunHeader"`
+	BlobBaseFee  string           `json:"blobBaseFee,omitempty"`
+	BlobFeeCap   string           `json:"blobFeeCap"`
+	Cases        []BlobCaseResult `json:"cases"`
+	// Verdict is supported when every valid case was accepted and every
+	// invalid one rejected, unsupported when all were rejected, mishandled
+	// when any case was, and inconclusive otherwise.
+	Verdict   string `json:"verdict"`
+	Timestamp string `json:"timestamp"`
+	RPCURL    string `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	blobs := flag.Int("blobs", 3, "blobs in the multi-blob case")
+	blobFeeCap := flag.Int64("blob-fee-cap", 0, "blob fee cap in wei (0 is twice eth_blobBaseFee, or 1 Gwei without it)")
+	timeout := flag.Duration("timeout", 2*time.Minute, "how long to wait for each accepted transaction to be mined")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Writes}) {
+		fmt.Printf("⏭️  Blob transactions skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	fmt.Println("🧪 Experimental: blob transactions are not part of the CDK transaction set on every fork")
+
+	result := BlobTxResult{Stage: "Blob Transactions - EIP-4844 Submission", RPCURL: rpcURL}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Fatalf("❌ Failed to get head block: %v", err)
+	}
+	result.CancunHeader = head.ExcessBlobGas != nil && head.BlobGasUsed != nil
+	if !result.CancunHeader {
+		fmt.Println("⚠️  The head block has no blob gas fields: Cancun rules look inactive, expect rejections")
+	}
+
+	feeCap := big.NewInt(*blobFeeCap)
+	if baseFee, err := client.BlobBaseFee(ctx); err == nil {
+		result.BlobBaseFee = baseFee.String()
+		if *blobFeeCap == 0 {
+			feeCap = new(big.Int).Mul(baseFee, big.NewInt(2))
+		}
+	} else {
+		fmt.Printf("⚠️  eth_blobBaseFee failed: %v\n", err)
+		if *blobFeeCap == 0 {
+			feeCap = big.NewInt(params.GWei)
+		}
+	}
+	// A zero base fee doubles to zero, which no pool accepts
+	if feeCap.Sign() == 0 {
+		feeCap.SetInt64(1)
+	}
+	result.BlobFeeCap = feeCap.String()
+	fmt.Printf("⛽ Blob fee cap: %s wei (blob base fee %s)\n", feeCap, orUnknown(result.BlobBaseFee))
+
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleInvoke)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	sender.ReceiptTimeout = *timeout
+
+	cases := []blobCase{
+		{name: "one blob", valid: true, blobs: 1, build: asBuilt},
+		{name: fmt.Sprintf("%d blobs", *blobs), valid: true, blobs: *blobs, build: asBuilt},
+		{name: "versioned hash with version 0x02", blobs: 1, build: func(sc, _ *types.BlobTxSidecar) (*types.BlobTxSidecar, []common.Hash) {
+			return sc, []common.Hash{blob.VersionedHash(sc.Commitments[0], 2)}
+		}},
+		{name: "versioned hash of another blob", blobs: 1, build: func(sc, other *types.BlobTxSidecar) (*types.BlobTxSidecar, []common.Hash) {
+			return sc, other.BlobHashes()
+		}},
+		{name: "proof of another blob", blobs: 1, build: func(sc, other *types.BlobTxSidecar) (*types.BlobTxSidecar, []common.Hash) {
+			bad := *sc
+			bad.Proofs = []kzg4844.Proof{other.Proofs[0]}
+			return &bad, nil
+		}},
+		{name: "blob hashes without a sidecar", blobs: 1, build: func(sc, _ *types.BlobTxSidecar) (*types.BlobTxSidecar, []common.Hash) {
+			return nil, sc.BlobHashes()
+		}},
+	}
+
+	for i, c := range cases {
+		fmt.Printf("\n📦 %s (%s)\n", c.name, validity(c.valid))
+		res := BlobCaseResult{Name: c.name, Valid: c.valid, Blobs: c.blobs}
+		sc, err := payloadSidecar(fmt.Sprintf("case %d", i), c.blobs)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		other, err := payloadSidecar(fmt.Sprintf("case %d other", i), 1)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		sidecar, hashes := c.build(sc, other)
+
+		tx, receipt, err := sender.SendBlobs(ctx, sender.From, nil, params.TxGas, sidecar, hashes, feeCap)
+		if ctx.Err() != nil {
+			log.Fatalf("❌ Interrupted: %v", ctx.Err())
+		}
+		if tx != nil {
+			res.Tx = tx.Hash().Hex()
+		}
+		classify(ctx, client, &res, tx, receipt, err, feeCap)
+		printCase(res)
+		result.Cases = append(result.Cases, res)
+	}
+
+	result.Verdict = verdict(result.Cases)
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_blob_tx.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	fmt.Printf("\n📊 Blob support: %s\n", result.Verdict)
+	fmt.Println("📝 Results saved to results_blob_tx.json")
+	if result.Verdict == OutcomeMishandled {
+		os.Exit(1)
+	}
+}
+
+// payloadSidecar builds a sidecar of n blobs from a payload unique to label,
+// so no two cases send the same blobs.
+func payloadSidecar(label string, n int) (*types.BlobTxSidecar, error) {
+	var payload []byte
+	for i := 0; i < n; i++ {
+		chunk := make([]byte, blob.Capacity)
+		copy(chunk, fmt.Sprintf("cdk-erigon-precompile blob test %s, blob %d, %s", label, i, time.Now().UTC().Format(time.RFC3339Nano)))
+		payload = append(payload, chunk...)
+	}
+	sc, err := blob.Sidecar(blob.Encode(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build blobs: %w", err)
+	}
+	return sc, nil
+}
+
+// classify sets the outcome of res from what submitting it returned.
+func classify(ctx context.Context, client *ethclient.Client, res *BlobCaseResult, tx *types.Transaction, receipt *types.Receipt, err error, feeCap *big.Int) {
+	var rpcErr rpc.Error
+	switch {
+	case err == nil:
+		res.Block = receipt.BlockNumber.Uint64()
+		if !res.Valid {
+			res.Outcome, res.Error = OutcomeMishandled, "invalid transaction was mined"
+			return
+		}
+		res.Checks = inclusionChecks(ctx, client, tx, receipt, feeCap)
+		res.Outcome = OutcomeAccepted
+		if !chain.AllPassed(res.Checks) {
+			res.Outcome = OutcomeMishandled
+		}
+	case tx != nil && receipt == nil && errors.Is(err, chain.ErrReceiptTimeout):
+		// Sent without error but never mined
+		res.Outcome, res.Error = OutcomeMishandled, err.Error()
+		if !res.Valid {
+			res.Error = "invalid transaction was accepted: " + err.Error()
+		}
+	case errors.Is(err, chain.ErrInsufficientFund), errors.Is(err, chain.ErrNonceTooLow), errors.Is(err, chain.ErrUnderpriced):
+		res.Outcome, res.Error = OutcomeInconclusive, err.Error()
+		if !res.Valid {
+			res.Outcome = OutcomeRejected
+		}
+	case errors.As(err, &rpcErr):
+		res.Outcome, res.Error = OutcomeRejected, err.Error()
+	default:
+		res.Outcome, res.Error = OutcomeMishandled, "no JSON-RPC error: "+err.Error()
+	}
+}
+
+// inclusionChecks compares a mined blob transaction's receipt, transaction
+// and block with what was sent.
+func inclusionChecks(ctx context.Context, client *ethclient.Client, tx *types.Transaction, receipt *types.Receipt, feeCap *big.Int) []chain.Check {
+	checks := []chain.Check{
+		{Name: "receipt type", Expected: "3", Actual: fmt.Sprint(receipt.Type), Passed: receipt.Type == types.BlobTxType},
+		{Name: "receipt status", Expected: "1", Actual: fmt.Sprint(receipt.Status), Passed: receipt.Status == types.ReceiptStatusSuccessful},
+		{Name: "receipt blobGasUsed = blobs × 131072", Expected: fmt.Sprint(tx.BlobGas()), Actual: fmt.Sprint(receipt.BlobGasUsed), Passed: receipt.BlobGasUsed == tx.BlobGas()},
+	}
+
+	check := chain.Check{Name: "receipt blobGasPrice ≤ blob fee cap", Expected: "≤ " + feeCap.String()}
+	if receipt.BlobGasPrice == nil {
+		check.Note = "blobGasPrice missing"
+	} else {
+		check.Actual = receipt.BlobGasPrice.String()
+		check.Passed = receipt.BlobGasPrice.Sign() > 0 && receipt.BlobGasPrice.Cmp(feeCap) <= 0
+	}
+	checks = append(checks, check)
+
+	check = chain.Check{Name: "transaction blobVersionedHashes as sent", Expected: fmt.Sprint(tx.BlobHashes())}
+	if mined, _, err := client.TransactionByHash(ctx, tx.Hash()); err != nil {
+		check.Note = err.Error()
+	} else {
+		check.Actual = fmt.Sprint(mined.BlobHashes())
+		check.Passed = mined.Type() == types.BlobTxType && check.Actual == check.Expected
+	}
+	checks = append(checks, check)
+
+	check = chain.Check{Name: "block blobGasUsed covers the transaction", Expected: fmt.Sprintf("≥ %d", receipt.BlobGasUsed)}
+	if header, err := client.HeaderByHash(ctx, receipt.BlockHash); err != nil {
+		check.Note = err.Error()
+	} else if header.BlobGasUsed == nil {
+		check.Note = "block has no blobGasUsed"
+	} else {
+		check.Actual = fmt.Sprint(*header.BlobGasUsed)
+		check.Passed = *header.BlobGasUsed >= receipt.BlobGasUsed
+	}
+	return append(checks, check)
+}
+
+// verdict sums up the cases.
+func verdict(cases []BlobCaseResult) string {
+	valid, accepted, rejected := 0, 0, 0
+	for _, c := range cases {
+		switch {
+		case c.Outcome == OutcomeMishandled:
+			return OutcomeMishandled
+		case c.Valid:
+			valid++
+			if c.Outcome == OutcomeAccepted {
+				accepted++
+			}
+		}
+		if c.Outcome == OutcomeRejected {
+			rejected++
+		}
+	}
+	invalid := len(cases) - valid
+	switch {
+	case rejected == len(cases):
+		return "unsupported"
+	case accepted == valid && rejected == invalid:
+		return "supported"
+	}
+	return OutcomeInconclusive
+}
+
+func printCase(res BlobCaseResult) {
+	icon := map[string]string{OutcomeAccepted: "✅", OutcomeRejected: "🚫", OutcomeMishandled: "❌", OutcomeInconclusive: "⚠️ "}[res.Outcome]
+	if res.Outcome == OutcomeRejected && res.Valid {
+		icon = "⚠️ "
+	}
+	fmt.Printf("%s %s", icon, res.Outcome)
+	if res.Tx != "" {
+		fmt.Printf(" (tx %s", res.Tx)
+		if res.Block > 0 {
+			fmt.Printf(", block %d", res.Block)
+		}
+		fmt.Print(")")
+	}
+	fmt.Println()
+	if res.Error != "" {
+		fmt.Printf("   %s\n", res.Error)
+	}
+	for _, c := range res.Checks {
+		if !c.Passed {
+			fmt.Printf("   ❌ %s: expected %s, got %s %s\n", c.Name, c.Expected, c.Actual, c.Note)
+		}
+	}
+}
+
+func validity(valid bool) string {
+	if valid {
+		return "valid"
+	}
+	return "invalid, must be rejected"
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// BLSCase is one vector run against an available precompile.
+type BLSCase struct {
+	Name          string        `json:"name"`
+	Precompile    string        `json:"precompile"`
+	Input         hexutil.Bytes `json:"input"`
+	ExpectFailure bool          `json:"expectFailure"`
+	Expected      hexutil.Bytes `json:"expected,omitempty"`
+	Returned      hexutil.Bytes `json:"returned,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	// Gas is what EIP-2537 charges for the input, for information only.
+	Gas   uint64 `json:"gas"`
+	Match bool   `json:"match"`
+}
+
+type BLSResult struct {
+	Stage string `json:"stage"`
+	// Layout is prague when every precompile answers at its Prague
+	// address, draft when they all answer at the addresses of the earlier
+	// EIP drafts, partial for anything in between and none when no
+	// precompile answers.
+	Layout       string                     `json:"layout"`
+	Capabilities []precompile.BLSCapability `json:"capabilities"`
+	Cases        []BLSCase                  `json:"cases"`
+	Matches      int                        `json:"matches"`
+	Mismatches   int                        `json:"mismatches"`
+	Skipped      int                        `json:"skipped"`
+	Timestamp    string                     `json:"timestamp"`
+	RPCURL       string                     `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	require := flag.Bool("require", false, "fail unless every BLS precompile is at its Prague address")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Smoke}) {
+		fmt.Printf("⏭️  BLS12-381 checks skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_bls.json")
+
+	vectors := precompile.BLSVectors()
+	result := BLSResult{
+		Stage:  "EIP-2537 - BLS12-381 Precompiles",
+		RPCURL: rpcURL,
+	}
+
+	fmt.Println("\n🔎 Probing BLS12-381 precompiles:")
+	available := map[string]common.Address{}
+	prague, draft := 0, 0
+	for _, p := range precompile.BLSPrecompiles {
+		c := precompile.ProbeBLS(ctx, client, p)
+		result.Capabilities = append(result.Capabilities, c)
+		switch {
+		case c.Status != precompile.BLSSupported:
+			fmt.Printf("➖ %-14s %s %s\n", p.Name, c.Status, c.Detail)
+			continue
+		case c.Draft:
+			draft++
+		default:
+			prague++
+		}
+		if p.Address == p.DraftAddress {
+			// g1add is at 0x0b in both layouts
+			draft++
+		}
+		available[p.Name] = common.HexToAddress(c.Address)
+		fmt.Printf("✅ %-14s at %s\n", p.Name, c.Address)
+	}
+	total := len(precompile.BLSPrecompiles)
+	switch {
+	case prague == total:
+		result.Layout = "prague"
+	case draft == total:
+		result.Layout = "draft"
+	case len(available) == 0:
+		result.Layout = "none"
+	default:
+		result.Layout = "partial"
+	}
+	fmt.Printf("📐 Layout: %s\n", result.Layout)
+
+	for _, v := range vectors {
+		address, ok := available[v.Precompile.Name]
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		c := runBLSCase(ctx, client, address, v)
+		if c.Match {
+			result.Matches++
+		} else {
+			result.Mismatches++
+		}
+		result.Cases = append(result.Cases, c)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_bls.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 BLS12-381 results:")
+	for _, c := range result.Cases {
+		switch {
+		case c.Match:
+			fmt.Printf("✅ %s %s\n", c.Precompile, c.Name)
+		case c.ExpectFailure:
+			fmt.Printf("❌ %s %s: returned %s, expected a failure\n", c.Precompile, c.Name, c.Returned)
+		case c.Error != "":
+			fmt.Printf("❌ %s %s: %s\n", c.Precompile, c.Name, c.Error)
+		default:
+			fmt.Printf("❌ %s %s: returned %s\n", c.Precompile, c.Name, c.Returned)
+		}
+	}
+	fmt.Printf("✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Printf("⏭️  Skipped:    %d (precompile not available)\n", result.Skipped)
+	fmt.Println("\n📝 Results saved to results_bls.json")
+	if result.Mismatches > 0 || (*require && result.Layout != "prague") {
+		os.Exit(1)
+	}
+}
+
+// runBLSCase runs v at address. Invalid input must make the call fail;
+// valid input must return go-ethereum's answer.
+func runBLSCase(ctx context.Context, client *ethclient.Client, address common.Address, v precompile.BLSVector) BLSCase {
+	c := BLSCase{
+		Name:          v.Name,
+		Precompile:    address.Hex(),
+		Input:         v.Input,
+		ExpectFailure: v.Fail,
+		Gas:           v.Gas(),
+	}
+	if !v.Fail {
+		c.Expected, _ = v.Reference()
+	}
+	out, err := precompile.CallBLS(ctx, client, address, v.Input)
+	if err != nil {
+		c.Error = err.Error()
+		c.Match = v.Fail
+		return c
+	}
+	c.Returned = out
+	c.Match = !v.Fail && bytes.Equal(out, c.Expected)
+	return c
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+)
+
+// BroadcastResult tracks one pre-signed transaction from broadcast to receipt.
+type BroadcastResult struct {
+	Line            int           `json:"line"`
+	Raw             string        `json:"raw"`
+	Type            *uint8        `json:"type,omitempty"`
+	DecodeError     string        `json:"decodeError,omitempty"`
+	TransactionHash string        `json:"transactionHash,omitempty"`
+	Accepted        bool          `json:"accepted"`
+	SendError       string        `json:"sendError,omitempty"`
+	Mined           bool          `json:"mined"`
+	BlockNumber     uint64        `json:"blockNumber,omitempty"`
+	Status          *uint64       `json:"status,omitempty"`
+	GasUsed         uint64        `json:"gasUsed,omitempty"`
+	ReceiptError    string        `json:"receiptError,omitempty"`
+	FeeChecks       []chain.Check `json:"feeChecks,omitempty"`
+	ReceiptChecks   []chain.Check `json:"receiptChecks,omitempty"`
+	BlockChecks     []chain.Check `json:"blockChecks,omitempty"`
+}
+
+type BroadcastSummary struct {
+	Stage        string            `json:"stage"`
+	Source       string            `json:"source"`
+	Transactions []BroadcastResult `json:"transactions"`
+	Accepted     int               `json:"accepted"`
+	Rejected     int               `json:"rejected"`
+	Mined        int               `json:"mined"`
+	Reverted     int               `json:"reverted"`
+	Timestamp    string            `json:"timestamp"`
+	RPCURL       string            `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	interval := flag.Duration("interval", time.Second, "pause between broadcasts")
+	receiptTimeout := flag.Duration("receipt-timeout", 2*time.Minute, "how long to wait for each receipt (0 skips receipt tracking)")
+	checks := flag.Bool("checks", true, "run fee, receipt and block conformance checks on mined transactions")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: go run scripts/broadcast.go [flags] raw_txs.txt")
+		flag.PrintDefaults()
+	}
+	envFiles := envfile.Flags()
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	source := flag.Arg(0)
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+
+	results, err := readRawTransactions(source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("📂 Loaded %d raw transactions from %s\n", len(results), source)
+
+	summary := BroadcastSummary{
+		Stage:  "Broadcast - Pre-signed Raw Transactions",
+		Source: source,
+		RPCURL: rpcURL,
+	}
+
+	// Broadcast everything first, paced, then track receipts, so dependent
+	// transactions (consecutive nonces) can land in the same block. Once
+	// interrupted, the remaining transactions fail with the cancellation
+	// instead of being sent
+	decoded := make([]*types.Transaction, len(results))
+	for i := range results {
+		if i > 0 && *interval > 0 {
+			time.Sleep(*interval)
+		}
+		decoded[i] = broadcast(ctx, client, &results[i])
+		if results[i].Accepted {
+			summary.Accepted++
+			fmt.Printf("📨 line %d: accepted %s\n", results[i].Line, results[i].TransactionHash)
+		} else {
+			summary.Rejected++
+			fmt.Printf("❌ line %d: rejected: %s\n", results[i].Line, results[i].SendError)
+		}
+	}
+
+	if *receiptTimeout > 0 {
+		fmt.Println("⏳ Waiting for receipts...")
+		for i := range results {
+			res := &results[i]
+			if !res.Accepted {
+				continue
+			}
+			trackReceipt(ctx, client, res, decoded[i], *receiptTimeout, *checks)
+			if res.Mined {
+				summary.Mined++
+				if res.Status != nil && *res.Status != 1 {
+					summary.Reverted++
+				}
+			}
+		}
+	}
+	summary.Transactions = results
+	summary.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	// Save results
+	file, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_broadcast.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+
+	fmt.Println("\n🧪 Broadcast results:")
+	for _, res := range results {
+		switch {
+		case !res.Accepted:
+			fmt.Printf("❌ line %d: rejected (%s)\n", res.Line, res.SendError)
+		case !res.Mined:
+			fmt.Printf("⚠️  line %d: %s not mined (%s)\n", res.Line, res.TransactionHash, res.ReceiptError)
+		default:
+			status := "✅"
+			if *res.Status != 1 {
+				status = "↩️ "
+			}
+			conformance := ""
+			if failed := failedChecks(res); failed > 0 {
+				conformance = fmt.Sprintf(", %d conformance checks failed", failed)
+			}
+			fmt.Printf("%s line %d: %s status %d in block %d, gas %d%s\n",
+				status, res.Line, res.TransactionHash, *res.Status, res.BlockNumber, res.GasUsed, conformance)
+		}
+	}
+	fmt.Printf("\n📊 Accepted: %d, Rejected: %d, Mined: %d (reverted %d)\n",
+		summary.Accepted, summary.Rejected, summary.Mined, summary.Reverted)
+	fmt.Println("📝 Results saved to results_broadcast.json")
+}
+
+// readRawTransactions reads one hex-encoded transaction per line, ignoring
+// blank lines and # comments.
+func readRawTransactions(path string) ([]BroadcastResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var results []BroadcastResult
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), 16<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "0x") {
+			line = "0x" + line
+		}
+		if _, err := hexutil.Decode(line); err != nil {
+			return nil, fmt.Errorf("❌ %s:%d: invalid hex: %v", path, n, err)
+		}
+		results = append(results, BroadcastResult{Line: n, Raw: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("❌ Failed to read %s: %v", path, err)
+	}
+	return results, nil
+}
+
+// broadcast sends the raw bytes exactly as given. Decoding is only used for
+// reporting, so transactions go-ethereum can't parse are still pushed.
+func broadcast(ctx context.Context, client *ethclient.Client, res *BroadcastResult) *types.Transaction {
+	raw := hexutil.MustDecode(res.Raw)
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		res.DecodeError = err.Error()
+		tx = nil
+	} else {
+		txType := tx.Type()
+		res.Type = &txType
+		res.TransactionHash = tx.Hash().Hex()
+	}
+
+	var hash common.Hash
+	err := chain.ClassifySend(client.Client().CallContext(ctx, &hash, "eth_sendRawTransaction", res.Raw))
+	if err != nil && !errors.Is(err, chain.ErrAlreadyKnown) {
+		res.SendError = err.Error()
+		return tx
+	}
+	res.Accepted = true
+	if hash != (common.Hash{}) {
+		res.TransactionHash = hash.Hex()
+	}
+	return tx
+}
+
+func trackReceipt(ctx context.Context, client *ethclient.Client, res *BroadcastResult, tx *types.Transaction, timeout time.Duration, checks bool) {
+	if res.TransactionHash == "" {
+		res.ReceiptError = "no transaction hash to track"
+		return
+	}
+	receipt, err := chain.WaitForReceipt(ctx, client, common.HexToHash(res.TransactionHash), timeout)
+	if err != nil {
+		res.ReceiptError = err.Error()
+		return
+	}
+	res.Mined = true
+	res.BlockNumber = receipt.BlockNumber.Uint64()
+	res.Status = &receipt.Status
+	res.GasUsed = receipt.GasUsed
+
+	if checks && tx != nil {
+		res.FeeChecks = chain.CheckFees(ctx, client, tx, receipt)
+		res.ReceiptChecks = chain.CheckReceipt(ctx, client, tx, receipt)
+		res.BlockChecks = chain.CheckBlock(ctx, client, tx, receipt)
+	}
+}
+
+func failedChecks(res BroadcastResult) int {
+	failed := 0
+	for _, group := range [][]chain.Check{res.FeeChecks, res.ReceiptChecks, res.BlockChecks} {
+		for _, c := range group {
+			if !c.Skipped && !c.Passed {
+				failed++
+			}
+		}
+	}
+	return failed
+}
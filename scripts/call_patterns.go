@@ -0,0 +1,352 @@
+//suite:writes transactions=1 gas=2000000
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/patterns"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// Diagnoses of a case whose answer was wrong.
+const (
+	// diagnosisEVM: the direct call was wrong too, so the precompile or
+	// the EVM around it is at fault whatever calls it.
+	diagnosisEVM = "evm"
+	// diagnosisCompiler: only the compiler-generated staticcall was wrong.
+	diagnosisCompiler = "compiler"
+	// diagnosisAssembly: only hand-written assembly was wrong.
+	diagnosisAssembly = "assembly"
+	// diagnosisPatterns: the direct call was right but both kinds of
+	// pattern were wrong.
+	diagnosisPatterns = "patterns"
+)
+
+// PatternAnswer is what the precompile answered through one pattern.
+type PatternAnswer struct {
+	Pattern patterns.Pattern `json:"pattern"`
+	patterns.Result
+	Error string `json:"error,omitempty"`
+	Match bool   `json:"match"`
+}
+
+// PatternCase is one input sent through every pattern and directly. It
+// passes when every answer is the expected one.
+type PatternCase struct {
+	Name       string          `json:"name"`
+	Precompile string          `json:"precompile"`
+	Input      hexutil.Bytes   `json:"input"`
+	OutSize    uint64          `json:"outSize"`
+	Expected   patterns.Result `json:"expected"`
+	Direct     PatternAnswer   `json:"direct"`
+	Answers    []PatternAnswer `json:"answers"`
+	Match      bool            `json:"match"`
+	Diagnosis  string          `json:"diagnosis,omitempty"`
+}
+
+type PatternResult struct {
+	Stage      string        `json:"stage"`
+	Contract   string        `json:"contract"`
+	Cases      []PatternCase `json:"cases"`
+	Matches    int           `json:"matches"`
+	Mismatches int           `json:"mismatches"`
+	// Diagnoses counts the mismatches by diagnosis.
+	Diagnoses map[string]int `json:"diagnoses,omitempty"`
+	Timestamp string         `json:"timestamp"`
+	RPCURL    string         `json:"rpcUrl"`
+}
+
+// patternInput is a precompile input with the answer expected of it and
+// the output area the sized patterns give it.
+type patternInput struct {
+	name       string
+	precompile common.Address
+	input      []byte
+	outSize    uint64
+	expected   patterns.Result
+}
+
+func main() {
+	output.Setup()
+
+	contractFlag := flag.String("contract", "", "PrecompilePatterns address to use instead of the saved or a freshly deployed one")
+	gasLimit := flag.Uint64("gas", 2_000_000, "gas limit of the PrecompilePatterns deployment")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_call_patterns.json")
+
+	contract, err := resolvePatternsContract(ctx, client, *contractFlag, *gasLimit)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("📌 Using PrecompilePatterns at %s\n", contract.Hex())
+
+	inputs, err := patternInputs(tagFilter)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	result := PatternResult{
+		Stage:     "Call Patterns - Compiler-Generated and Assembly Precompile Calls",
+		Contract:  contract.Hex(),
+		Diagnoses: map[string]int{},
+		RPCURL:    rpcURL,
+	}
+	fmt.Printf("🧩 Sending %d inputs through %d patterns and directly\n", len(inputs), len(patterns.Patterns))
+	for _, in := range inputs {
+		c := runPatternCase(ctx, client, contract, in)
+		if c.Match {
+			result.Matches++
+		} else {
+			result.Mismatches++
+			result.Diagnoses[c.Diagnosis]++
+		}
+		result.Cases = append(result.Cases, c)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := savePatternResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Call pattern results:")
+	for _, c := range result.Cases {
+		if c.Match {
+			continue
+		}
+		fmt.Printf("❌ %s (%s, expected success=%t %s):\n", c.Name, c.Diagnosis, c.Expected.Success, c.Expected.Output)
+		for _, a := range append([]PatternAnswer{c.Direct}, c.Answers...) {
+			mark := "✅"
+			if !a.Match {
+				mark = "❌"
+			}
+			if a.Error != "" {
+				fmt.Printf("   %s %-8s error: %s\n", mark, a.Pattern, a.Error)
+			} else {
+				fmt.Printf("   %s %-8s success=%t size=%d %s\n", mark, a.Pattern, a.Success, a.ReturnSize, a.Output)
+			}
+		}
+	}
+	fmt.Printf("✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	for _, d := range []string{diagnosisEVM, diagnosisCompiler, diagnosisAssembly, diagnosisPatterns} {
+		if n := result.Diagnoses[d]; n > 0 {
+			fmt.Printf("   %-9s %d\n", d+":", n)
+		}
+	}
+	fmt.Println("\n📝 Results saved to results_call_patterns.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// runPatternCase sends in directly and through every pattern, and when an
+// answer is wrong tells from which ones whether the EVM, the compiler's
+// call sequence or the hand-written one is at fault.
+func runPatternCase(ctx context.Context, client *ethclient.Client, contract common.Address, in patternInput) PatternCase {
+	c := PatternCase{
+		Name:       in.name,
+		Precompile: in.precompile.Hex(),
+		Input:      in.input,
+		OutSize:    in.outSize,
+		Expected:   in.expected,
+		Match:      true,
+	}
+	answer := func(p patterns.Pattern, r patterns.Result, err error) PatternAnswer {
+		a := PatternAnswer{Pattern: p, Result: r}
+		if err != nil {
+			a.Error = err.Error()
+		} else {
+			a.Match = r.Equal(in.expected)
+		}
+		if !a.Match {
+			c.Match = false
+		}
+		return a
+	}
+	r, err := patterns.Direct(ctx, client, in.precompile, in.input)
+	c.Direct = answer("direct", r, err)
+	solidity, assembly := true, true
+	for _, p := range patterns.Patterns {
+		r, err := patterns.Call(ctx, client, contract, p, in.precompile, in.input, in.outSize)
+		a := answer(p, r, err)
+		if !a.Match && p == patterns.Solidity {
+			solidity = false
+		} else if !a.Match {
+			assembly = false
+		}
+		c.Answers = append(c.Answers, a)
+	}
+	switch {
+	case c.Match:
+	case !c.Direct.Match:
+		c.Diagnosis = diagnosisEVM
+	case !solidity && !assembly:
+		c.Diagnosis = diagnosisPatterns
+	case !solidity:
+		c.Diagnosis = diagnosisCompiler
+	default:
+		c.Diagnosis = diagnosisAssembly
+	}
+	return c
+}
+
+// patternInputs covers each precompile with answers of its full output
+// size, and with the answers the patterns handle differently: an empty
+// output where the fixed area expects 32 bytes, and a failed call. The
+// SHA-256 and identity inputs honour the tag filters.
+func patternInputs(filter *tags.Filter) ([]patternInput, error) {
+	vectors := vector.Select([]vector.Vector{
+		vector.New([]byte("hello world"), tags.Smoke),
+		vector.New([]byte(""), tags.Smoke),
+		vector.New(bytes.Repeat([]byte{0xab}, 32), tags.Binary),
+		vector.New(bytes.Repeat([]byte{0xcd}, 33), tags.Binary),
+		vector.New([]byte(strings.Repeat("patterns", 40))),
+	}, filter)
+
+	identity := common.HexToAddress("0x04")
+	var inputs []patternInput
+	for _, v := range vectors {
+		input := v.Bytes()
+		sum := sha256.Sum256(input)
+		inputs = append(inputs,
+			patternInput{name: "sha256 " + v.Display(), precompile: precompile.SHA256Address, input: input, outSize: 32,
+				expected: patterns.Expect(true, sum[:])},
+			patternInput{name: "identity " + v.Display(), precompile: identity, input: input, outSize: uint64(len(input)),
+				expected: patterns.Expect(true, input)},
+		)
+	}
+
+	sigs, err := precompile.RandomSignatures(1)
+	if err != nil {
+		return nil, err
+	}
+	inputs = append(inputs, patternInput{name: "ecrecover " + sigs[0].Signer.Hex(), precompile: precompile.ECRecoverAddress,
+		input: sigs[0].Input(), outSize: 32, expected: patterns.Expect(true, common.LeftPadBytes(sigs[0].Signer.Bytes(), 32))})
+	// A v other than 27 or 28 recovers nothing: the call succeeds with no
+	// output, which leaves a fixed area zeroed
+	invalid := sigs[0].Input()
+	invalid[63] = 29
+	inputs = append(inputs, patternInput{name: "ecrecover v=29", precompile: precompile.ECRecoverAddress,
+		input: invalid, outSize: 32, expected: patterns.Expect(true, nil)})
+
+	m := precompile.ModExp{Base: []byte{3}, Exp: []byte{0xff, 0xff}, Mod: big.NewInt(1_000_000_007).Bytes()}
+	inputs = append(inputs, patternInput{name: "modexp 3^65535 % 1000000007", precompile: precompile.ModExpAddress,
+		input: m.Input(), outSize: uint64(len(m.Expected())), expected: patterns.Expect(true, m.Expected())})
+
+	pairing, _ := precompile.PairingInput(2)
+	inputs = append(inputs,
+		patternInput{name: "pairing 2 pairs", precompile: precompile.PairingAddress,
+			input: pairing, outSize: 32, expected: patterns.Expect(true, common.LeftPadBytes([]byte{1}, 32))},
+		// Not a multiple of 192 bytes, so the call fails
+		patternInput{name: "pairing truncated", precompile: precompile.PairingAddress,
+			input: pairing[:100], outSize: 32, expected: patterns.Expect(false, nil)},
+	)
+	return inputs, nil
+}
+
+// resolvePatternsContract picks the PrecompilePatterns to call: the
+// --contract address, the one saved in deployed_patterns_address.txt, or a
+// fresh deployment of artifacts/PrecompilePatterns, in that order.
+func resolvePatternsContract(ctx context.Context, client *ethclient.Client, override string, gas uint64) (common.Address, error) {
+	if override != "" {
+		if !common.IsHexAddress(override) {
+			return common.Address{}, fmt.Errorf("invalid --contract address %q", override)
+		}
+		address := common.HexToAddress(override)
+		if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+			return common.Address{}, err
+		}
+		return address, nil
+	}
+	if address, err := paths.ReadAddress(paths.Work("deployed_patterns_address.txt")); err == nil {
+		if code, err := client.CodeAt(ctx, address, nil); err == nil && len(code) > 0 {
+			return address, nil
+		}
+	}
+
+	bytecode, err := paths.ReadHex(paths.Artifact("PrecompilePatterns.bin"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read bytecode (compile contracts/PrecompilePatterns.sol first): %v", err)
+	}
+	if err := deploy.VerifyArtifact(paths.Artifact("PrecompilePatterns")); err != nil {
+		return common.Address{}, fmt.Errorf("refusing to deploy: %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		return common.Address{}, fmt.Errorf("no PrecompilePatterns deployed and can't deploy one (pass --contract): %v", err)
+	}
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleDeploy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	fmt.Printf("📨 Deploying PrecompilePatterns from %s...\n", sender.From.Hex())
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "PrecompilePatterns"), nil, common.FromHex(bytecode), gas)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return common.Address{}, fmt.Errorf("PrecompilePatterns deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	if err := paths.WriteFile(paths.Work("deployed_patterns_address.txt"), []byte(receipt.ContractAddress.Hex())); err != nil {
+		return common.Address{}, fmt.Errorf("failed to save deployed address: %v", err)
+	}
+	return receipt.ContractAddress, nil
+}
+
+func savePatternResult(result PatternResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_call_patterns.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
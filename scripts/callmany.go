@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/bundle"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// storeABI is the part of contracts/Sha256Store.sol the ordering check
+// calls.
+const storeABI = `[
+{"type":"function","name":"count","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+{"type":"function","name":"hashes","stateMutability":"view","inputs":[{"name":"","type":"uint256"}],"outputs":[{"name":"","type":"bytes32"}]},
+{"type":"function","name":"store","stateMutability":"nonpayable","inputs":[{"name":"input","type":"bytes"}],"outputs":[{"name":"result","type":"bytes32"}]}]`
+
+// BundleMismatch is a wrapper call whose simulated answer was wrong.
+type BundleMismatch struct {
+	Index    int    `json:"index"`
+	Input    string `json:"input"`
+	Expected string `json:"expected"`
+	Returned string `json:"returned"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BundleCheck is a check of the bundle semantics, not of a single answer.
+type BundleCheck struct {
+	Name    string       `json:"name"`
+	Passed  bool         `json:"passed"`
+	Skipped bool         `json:"skipped,omitempty"`
+	Skip    *skip.Reason `json:"skip,omitempty"`
+	Note    string       `json:"note,omitempty"`
+}
+
+// MethodRun is the corpus simulated through one bundle method.
+type MethodRun struct {
+	Method    string `json:"method"`
+	Supported bool   `json:"supported"`
+	// Requests is how many bundles the corpus took, against one eth_call
+	// per input without them.
+	Requests   int              `json:"requests"`
+	Calls      int              `json:"calls"`
+	Matches    int              `json:"matches"`
+	Mismatches []BundleMismatch `json:"mismatches,omitempty"`
+	Checks     []BundleCheck    `json:"checks,omitempty"`
+	DurationMS int64            `json:"durationMs"`
+	Error      string           `json:"error,omitempty"`
+}
+
+type CallManyResult struct {
+	Stage      string      `json:"stage"`
+	Wrapper    string      `json:"wrapper"`
+	Store      string      `json:"store,omitempty"`
+	BundleSize int         `json:"bundleSize"`
+	Seed       int64       `json:"seed"`
+	Methods    []MethodRun `json:"methods"`
+	Timestamp  string      `json:"timestamp"`
+	RPCURL     string      `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	methodsFlag := flag.String("methods", strings.Join(bundle.Methods, ","), "comma-separated bundle methods to use")
+	bundleSize := flag.Int("bundle-size", 100, "calls per bundle; a bundle is one request")
+	random := flag.Int("random", 200, "random inputs to add to the corpus")
+	maxSize := flag.Int("max-size", 1024, "largest random input in bytes")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed of the random inputs")
+	storeFlag := flag.String("store", "", "Sha256Store for the ordering check (default the one in deployed_store_address.txt)")
+	require := flag.Bool("require", false, "fail when the node supports none of the methods")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_callmany.json")
+
+	wrapper, version, err := precompile.LoadWrapper(ctx, client)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if _, err := precompile.CodeSize(ctx, client, wrapper); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	wrapperABI := version.ABI()
+
+	// The built-in inputs honour the tag filter; random ones are fuzz
+	vectors := vector.Select([]vector.Vector{
+		vector.New([]byte("hello world"), tags.Smoke),
+		vector.New([]byte(""), tags.Smoke),
+		vector.New([]byte("cdk-erigon")),
+		vector.New([]byte{0x00, 0xff, 0xfe, 0x80}, tags.Binary),
+	}, tagFilter)
+	if tagFilter.Match([]string{tags.Fuzz}) {
+		rng := rand.New(rand.NewSource(*seed))
+		for range *random {
+			input := make([]byte, rng.Intn(*maxSize+1))
+			rng.Read(input)
+			vectors = append(vectors, vector.New(input, tags.Fuzz))
+		}
+	}
+	calls := make([]bundle.Call, len(vectors))
+	for i, v := range vectors {
+		data, err := wrapperABI.Pack("sha256Hash", v.Bytes())
+		if err != nil {
+			log.Fatalf("❌ Failed to pack sha256Hash: %v", err)
+		}
+		calls[i] = bundle.Call{To: wrapper, Data: data}
+	}
+
+	result := CallManyResult{
+		Stage:      "Bundle Simulation - Wrapper Calls Through eth_callMany and trace_callMany",
+		Wrapper:    wrapper.Hex(),
+		BundleSize: *bundleSize,
+		Seed:       *seed,
+		RPCURL:     rpcURL,
+	}
+	store, storeNote := resolveStore(ctx, client, *storeFlag)
+	if store != nil {
+		result.Store = store.Hex()
+	}
+
+	for _, method := range tags.Parse(*methodsFlag) {
+		run := MethodRun{Method: method, Calls: len(calls)}
+		fmt.Printf("\n📦 Simulating %d wrapper calls through %s in bundles of %d\n", len(calls), method, *bundleSize)
+		start := time.Now()
+		results, requests, err := bundle.Simulate(ctx, client.Client(), method, calls, *bundleSize, "latest")
+		run.DurationMS = time.Since(start).Milliseconds()
+		run.Requests = requests
+		if errors.Is(err, bundle.ErrUnsupported) {
+			fmt.Printf("⏭️  %s not supported by the node\n", method)
+			run.Error = err.Error()
+			result.Methods = append(result.Methods, run)
+			continue
+		}
+		run.Supported = true
+		if err != nil {
+			run.Error = err.Error()
+			fmt.Printf("❌ %s: %v\n", method, err)
+		}
+		// Answers are matched by position, so one out of order is a mismatch
+		for i, r := range results {
+			input := vectors[i].Bytes()
+			sum := sha256.Sum256(input)
+			if r.Error == "" && string(r.Output) == string(sum[:]) {
+				run.Matches++
+				continue
+			}
+			run.Mismatches = append(run.Mismatches, BundleMismatch{
+				Index: i, Input: hexutil.Encode(input), Expected: fmt.Sprintf("%x", sum), Returned: fmt.Sprintf("%x", r.Output), Error: r.Error,
+			})
+		}
+		if err == nil {
+			run.Checks = append(run.Checks, checkSharedState(ctx, client, method, store, storeNote))
+		}
+		fmt.Printf("✅ %d/%d answers matched in %d requests (%d ms)\n", run.Matches, run.Calls, run.Requests, run.DurationMS)
+		result.Methods = append(result.Methods, run)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_callmany.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Bundle simulation results:")
+	failed, supported := false, 0
+	for _, run := range result.Methods {
+		if !run.Supported {
+			fmt.Printf("⏭️  %s: not supported\n", run.Method)
+			continue
+		}
+		supported++
+		if run.Error != "" {
+			fmt.Printf("❌ %s: %s\n", run.Method, run.Error)
+			failed = true
+		}
+		for _, m := range run.Mismatches {
+			fmt.Printf("❌ %s #%d %s: returned %s %s\n", run.Method, m.Index, vectors[m.Index].Display(), m.Returned, m.Error)
+			failed = true
+		}
+		for _, check := range run.Checks {
+			status := "✅"
+			switch {
+			case check.Skipped:
+				status = "⏭️ "
+			case !check.Passed:
+				status = "❌"
+				failed = true
+			}
+			fmt.Printf("%s %s %s %s\n", status, run.Method, check.Name, check.Note)
+		}
+		fmt.Printf("📊 %s: %d calls in %d requests instead of %d\n", run.Method, run.Calls, run.Requests, run.Calls)
+	}
+	fmt.Println("\n📝 Results saved to results_callmany.json")
+	if failed || (*require && supported == 0) {
+		os.Exit(1)
+	}
+}
+
+// resolveStore finds the Sha256Store of stage 4, which the ordering check
+// writes to in simulation. Without one the check is skipped with the
+// returned note.
+func resolveStore(ctx context.Context, client *ethclient.Client, override string) (*common.Address, string) {
+	var address common.Address
+	switch {
+	case override != "":
+		if !common.IsHexAddress(override) {
+			log.Fatalf("❌ invalid --store address %q", override)
+		}
+		address = common.HexToAddress(override)
+	default:
+		saved, err := paths.ReadAddress(paths.Work("deployed_store_address.txt"))
+		if err != nil {
+			return nil, "no Sha256Store (run stage 4 or pass --store)"
+		}
+		address = saved
+	}
+	if code, err := client.CodeAt(ctx, address, nil); err != nil || len(code) == 0 {
+		return nil, fmt.Sprintf("no code at Sha256Store %s", address.Hex())
+	}
+	return &address, ""
+}
+
+// checkSharedState simulates two stores with reads of the counter and the
+// stored hashes between them. Each call must see the state the previous
+// ones left, in bundle order, and none of it may be committed.
+func checkSharedState(ctx context.Context, client *ethclient.Client, method string, store *common.Address, note string) BundleCheck {
+	check := BundleCheck{Name: "calls share state in order"}
+	if store == nil {
+		check.Skipped, check.Skip, check.Note = true, skip.New(skip.Prerequisite, ""), note
+		return check
+	}
+	parsed, err := abi.JSON(strings.NewReader(storeABI))
+	if err != nil {
+		panic(err)
+	}
+	pack := func(name string, args ...any) []byte {
+		data, err := parsed.Pack(name, args...)
+		if err != nil {
+			panic(err)
+		}
+		return data
+	}
+	countData := pack("count")
+	out, err := client.CallContract(ctx, ethereum.CallMsg{To: store, Data: countData}, nil)
+	if err != nil {
+		check.Note = fmt.Sprintf("count call failed: %v", err)
+		return check
+	}
+	before := new(big.Int).SetBytes(out)
+	next := new(big.Int).Add(before, big.NewInt(1))
+	a, b := []byte("bundle a"), []byte("bundle b")
+	sumA, sumB := sha256.Sum256(a), sha256.Sum256(b)
+
+	steps := []struct {
+		data []byte
+		want []byte
+	}{
+		{countData, common.LeftPadBytes(before.Bytes(), 32)},
+		{pack("store", a), sumA[:]},
+		{countData, common.LeftPadBytes(next.Bytes(), 32)},
+		{pack("hashes", before), sumA[:]},
+		{pack("store", b), sumB[:]},
+		{countData, common.LeftPadBytes(new(big.Int).Add(next, big.NewInt(1)).Bytes(), 32)},
+		{pack("hashes", next), sumB[:]},
+	}
+	calls := make([]bundle.Call, len(steps))
+	for i, s := range steps {
+		calls[i] = bundle.Call{To: *store, Data: s.data, Gas: 200_000}
+	}
+	results, _, err := bundle.Simulate(ctx, client.Client(), method, calls, len(calls), "latest")
+	if err != nil {
+		check.Note = err.Error()
+		return check
+	}
+	for i, s := range steps {
+		if results[i].Error != "" || string(results[i].Output) != string(s.want) {
+			check.Note = fmt.Sprintf("call %d returned %x %s, want %x", i, results[i].Output, results[i].Error, s.want)
+			return check
+		}
+	}
+
+	out, err = client.CallContract(ctx, ethereum.CallMsg{To: store, Data: countData}, nil)
+	if err != nil {
+		check.Note = fmt.Sprintf("count call failed: %v", err)
+		return check
+	}
+	if after := new(big.Int).SetBytes(out); after.Cmp(before) != 0 {
+		check.Note = fmt.Sprintf("the simulation was committed: count went from %s to %s", before, after)
+		return check
+	}
+	check.Passed = true
+	check.Note = fmt.Sprintf("count %s → %s in simulation, unchanged on chain", before, new(big.Int).Add(next, big.NewInt(1)))
+	return check
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chaos"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// ClassificationCheck asserts that a single fault type, injected on every
+// request with retries disabled, surfaces as the expected error class.
+type ClassificationCheck struct {
+	Fault    string `json:"fault"`
+	Expected string `json:"expectedClass"`
+	Observed string `json:"observedClass"`
+	Error    string `json:"error,omitempty"`
+	Passed   bool   `json:"passed"`
+}
+
+// RetryRun is the outcome of calling the precompile through the proxy with
+// all faults enabled and the retry layer on.
+type RetryRun struct {
+	Calls      int             `json:"calls"`
+	Succeeded  int             `json:"succeeded"`
+	WrongHash  int             `json:"wrongHash"`
+	Failed     map[string]int  `json:"failedByClass"`
+	Transport  rpcclient.Stats `json:"transport"`
+	Injected   chaos.Stats     `json:"injected"`
+	MinSuccess float64         `json:"minSuccessRate"`
+	Passed     bool            `json:"passed"`
+	Config     chaos.Config    `json:"config"`
+}
+
+type ChaosResult struct {
+	Stage          string                `json:"stage"`
+	Classification []ClassificationCheck `json:"classification"`
+	Retry          RetryRun              `json:"retry"`
+	Passed         bool                  `json:"passed"`
+	Timestamp      string                `json:"timestamp"`
+	RPCURL         string                `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	serve := flag.String("serve", "", "only run the fault-injection proxy on this address (e.g. :8124) until interrupted")
+	calls := flag.Int("calls", 200, "precompile calls made through the proxy with retries enabled")
+	latency := flag.Duration("latency", 20*time.Millisecond, "latency added to every proxied request")
+	jitter := flag.Duration("jitter", 30*time.Millisecond, "random extra latency up to this value")
+	dropRate := flag.Float64("drop-rate", 0.05, "probability of dropping the connection")
+	rateLimitRate := flag.Float64("429-rate", 0.05, "probability of answering 429 Too Many Requests")
+	malformedRate := flag.Float64("malformed-rate", 0.05, "probability of answering truncated JSON")
+	serverErrorRate := flag.Float64("5xx-rate", 0.05, "probability of answering 502 Bad Gateway")
+	retries := flag.Int("retries", 5, "retries per request in the retry run")
+	minSuccess := flag.Float64("min-success", 0.99, "minimum fraction of calls that must succeed in the retry run")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed for fault injection")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Slow}) {
+		fmt.Printf("⏭️  Chaos skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	cfg := chaos.Config{
+		Latency:         *latency,
+		Jitter:          *jitter,
+		DropRate:        *dropRate,
+		RateLimitRate:   *rateLimitRate,
+		MalformedRate:   *malformedRate,
+		ServerErrorRate: *serverErrorRate,
+		Seed:            *seed,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *serve != "" {
+		runProxy(ctx, *serve, rpcURL, cfg)
+		return
+	}
+
+	result := ChaosResult{
+		Stage:  "Chaos - RPC Fault Injection",
+		RPCURL: rpcURL,
+	}
+
+	fmt.Println("🧪 Checking error classification (one fault type at a time, no retries)")
+	result.Classification = checkClassification(ctx, rpcURL)
+
+	fmt.Printf("\n🌪️  Calling precompile 0x02 %d times through the chaos proxy (seed %d)\n", *calls, *seed)
+	run, err := runWithRetries(ctx, rpcURL, cfg, *calls, *retries, *minSuccess)
+	if err != nil {
+		log.Fatal(err)
+	}
+	result.Retry = *run
+
+	result.Passed = run.Passed
+	for _, c := range result.Classification {
+		result.Passed = result.Passed && c.Passed
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	// Save results
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_chaos.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	fmt.Println("\n📝 Results saved to results_chaos.json")
+
+	if !result.Passed {
+		log.Fatal("❌ Harness did not behave correctly under injected faults")
+	}
+	fmt.Println("✅ Retry layer and error classification behaved correctly")
+}
+
+// runProxy serves the fault-injection proxy so other stages can be pointed
+// at it via RPC_HOST/RPC_PORT, until ctx is cancelled.
+func runProxy(ctx context.Context, addr, rpcURL string, cfg chaos.Config) {
+	proxy, err := chaos.Start(addr, rpcURL, cfg)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer proxy.Close()
+	fmt.Printf("🌪️  Chaos proxy on %s → %s\n", proxy.URL, rpcURL)
+
+	<-ctx.Done()
+
+	stats := proxy.Stats()
+	fmt.Printf("\n📊 %d requests, faults injected: %v\n", stats.Requests, stats.Faults)
+}
+
+// checkClassification injects each fault on every request with retries
+// disabled and checks the error lands in the expected class.
+func checkClassification(ctx context.Context, rpcURL string) []ClassificationCheck {
+	cases := []struct {
+		fault    string
+		cfg      chaos.Config
+		expected string
+	}{
+		{chaos.FaultDrop, chaos.Config{DropRate: 1}, rpcclient.ClassConnection},
+		{chaos.FaultRateLimit, chaos.Config{RateLimitRate: 1}, rpcclient.ClassRateLimited},
+		{chaos.FaultMalformed, chaos.Config{MalformedRate: 1}, rpcclient.ClassMalformed},
+		{chaos.FaultServerError, chaos.Config{ServerErrorRate: 1}, rpcclient.ClassHTTP5xx},
+		{"timeout", chaos.Config{Latency: 2 * time.Second}, rpcclient.ClassTimeout},
+	}
+
+	var checks []ClassificationCheck
+	for _, tc := range cases {
+		check := ClassificationCheck{Fault: tc.fault, Expected: tc.expected}
+		err := faultCall(ctx, rpcURL, tc.cfg)
+		check.Observed = rpcclient.Classify(err)
+		if err != nil {
+			check.Error = err.Error()
+		}
+		check.Passed = check.Observed == check.Expected
+
+		status := "✅"
+		if !check.Passed {
+			status = "❌"
+		}
+		fmt.Printf("%s %-12s → %s (expected %s)\n", status, tc.fault, check.Observed, check.Expected)
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func faultCall(ctx context.Context, rpcURL string, cfg chaos.Config) error {
+	proxy, err := chaos.Start("127.0.0.1:0", rpcURL, cfg)
+	if err != nil {
+		return err
+	}
+	defer proxy.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	client, _, err := rpcclient.Dial(ctx, proxy.URL, rpcclient.Options{})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.ChainID(ctx)
+	return err
+}
+
+// runWithRetries calls the precompile through a proxy injecting all faults
+// and checks the retry layer recovers without ever accepting a wrong hash.
+func runWithRetries(ctx context.Context, rpcURL string, cfg chaos.Config, calls, retries int, minSuccess float64) (*RetryRun, error) {
+	proxy, err := chaos.Start("127.0.0.1:0", rpcURL, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("❌ %v", err)
+	}
+	defer proxy.Close()
+
+	opts := rpcclient.Options{MaxRetries: retries, Backoff: 50 * time.Millisecond}
+	client, transport, err := rpcclient.Dial(ctx, proxy.URL, opts)
+	if err != nil {
+		return nil, fmt.Errorf("❌ %v", err)
+	}
+	defer client.Close()
+
+	run := &RetryRun{
+		Calls:      calls,
+		Failed:     map[string]int{},
+		MinSuccess: minSuccess,
+		Config:     cfg,
+	}
+	precompile := common.HexToAddress("0x02")
+	for i := 0; i < calls; i++ {
+		input := []byte(fmt.Sprintf("chaos-%d", i))
+		ok, err := callPrecompile(ctx, client, precompile, input)
+		switch {
+		case err != nil:
+			run.Failed[rpcclient.Classify(err)]++
+		case ok:
+			run.Succeeded++
+		default:
+			run.WrongHash++
+		}
+	}
+	run.Transport = transport.Stats()
+	run.Injected = proxy.Stats()
+
+	faults := run.Injected.Requests - run.Injected.Faults[chaos.FaultNone]
+	successRate := float64(run.Succeeded) / float64(calls)
+	fmt.Printf("📊 Injected %d faults over %d requests\n", faults, run.Injected.Requests)
+	fmt.Printf("🔁 Retries: %d, recovered: %d, gave up: %d\n", run.Transport.Retries, run.Transport.Recovered, run.Transport.GaveUp)
+	fmt.Printf("✅ Succeeded: %d/%d (%.1f%%)\n", run.Succeeded, calls, successRate*100)
+
+	run.Passed = true
+	if run.WrongHash > 0 {
+		fmt.Printf("❌ %d calls returned a wrong hash instead of an error\n", run.WrongHash)
+		run.Passed = false
+	}
+	if run.Failed[rpcclient.ClassUnknown] > 0 {
+		fmt.Printf("❌ %d failures could not be classified\n", run.Failed[rpcclient.ClassUnknown])
+		run.Passed = false
+	}
+	if faults > 0 && run.Transport.Retries == 0 {
+		fmt.Println("❌ Faults were injected but the retry layer never retried")
+		run.Passed = false
+	}
+	if successRate < minSuccess {
+		fmt.Printf("❌ Success rate %.1f%% below %.1f%%\n", successRate*100, minSuccess*100)
+		run.Passed = false
+	}
+	return run, nil
+}
+
+func callPrecompile(ctx context.Context, client *ethclient.Client, precompile common.Address, input []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &precompile, Data: input}, nil)
+	if err != nil {
+		return false, err
+	}
+	expected := sha256.Sum256(input)
+	return len(result) == 32 && [32]byte(result) == expected, nil
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// destructors are the no-argument functions cleanup calls, in order of
+// preference, on contracts whose ABI has one.
+var destructors = []string{"destroy", "retire"}
+
+// Cleaned is what cleanup did with one deployment.
+type Cleaned struct {
+	Address  string `json:"address"`
+	Contract string `json:"contract,omitempty"`
+	// Action is destroyed when a destructor call removed the code, gone
+	// when the code was already gone, retired when the contract was only
+	// marked retired in the ledger, and failed when a destructor call
+	// failed and the contract was left active.
+	Action string `json:"action"`
+	Tx     string `json:"tx,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Swept is the residual balance moved off one role's account.
+type Swept struct {
+	Role    chain.Role `json:"role"`
+	Address string     `json:"address"`
+	Tx      string     `json:"tx,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+type CleanupResult struct {
+	Stage        string    `json:"stage"`
+	ChainID      string    `json:"chainId"`
+	DryRun       bool      `json:"dryRun"`
+	Deployments  []Cleaned `json:"deployments"`
+	RemovedFiles []string  `json:"removedFiles,omitempty"`
+	Sweeps       []Swept   `json:"sweeps,omitempty"`
+	Timestamp    string    `json:"timestamp"`
+	RPCURL       string    `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	olderThan := flag.Duration("older-than", 0, "only clean up deployments at least this old (0 cleans up all)")
+	contracts := flag.String("contract", "", "comma-separated contract names to clean up (default all)")
+	dryRun := flag.Bool("dry-run", false, "report what would be cleaned up without sending or changing anything")
+	keepFiles := flag.Bool("keep-files", false, "keep deployed_*address* files that point at retired contracts")
+	sweep := flag.Bool("sweep", false, "sweep the residual balances of the deploy and invoke roles")
+	sweepTo := flag.String("sweep-to", "", "account to sweep to (default the fund role)")
+	gasLimit := flag.Uint64("gas", 200_000, "gas limit of each destructor call")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Writes}) {
+		fmt.Printf("⏭️  Cleanup skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if !*dryRun {
+		if err := chain.CheckWritable(); err != nil {
+			log.Fatalf("❌ %v (pass --dry-run to see what would be cleaned up)", err)
+		}
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatalf("❌ Failed to get chain ID: %v", err)
+	}
+	ledgerPath := paths.Work(chain.DeploymentsFile)
+	ledger, err := chain.LoadLedger(ledgerPath)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	result := CleanupResult{
+		Stage:   "Cleanup of Deployed Contracts",
+		ChainID: chainID.String(),
+		DryRun:  *dryRun,
+		RPCURL:  rpcURL,
+	}
+	names := map[string]bool{}
+	for _, name := range tags.Parse(*contracts) {
+		names[name] = true
+	}
+	now := time.Now().UTC()
+	if *dryRun {
+		fmt.Println("🔍 Dry run: nothing is sent, saved or removed")
+	}
+
+	var sender *chain.Sender
+	retired := map[common.Address]bool{}
+	for _, d := range ledger.Active(result.ChainID) {
+		if len(names) > 0 && !names[contractBase(d.Contract)] {
+			continue
+		}
+		if *olderThan > 0 {
+			deployedAt, err := time.Parse(time.RFC3339, d.DeployedAt)
+			if err == nil && now.Sub(deployedAt) < *olderThan {
+				continue
+			}
+		}
+		if sender == nil && !*dryRun {
+			if sender, err = chain.NewRoleSender(ctx, client, chain.RoleDeploy); err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+		}
+		c := cleanDeployment(ctx, client, sender, d, *gasLimit, *dryRun)
+		if ctx.Err() != nil {
+			log.Fatalf("❌ Interrupted: %v", ctx.Err())
+		}
+		if c.Action != "failed" {
+			retired[common.HexToAddress(d.Address)] = true
+			if !*dryRun {
+				d.RetiredAt = now.Format(time.RFC3339)
+				d.Destroyed = c.Action == "destroyed" || c.Action == "gone"
+			}
+		}
+		result.Deployments = append(result.Deployments, c)
+		printCleaned(c)
+	}
+	if len(result.Deployments) == 0 {
+		fmt.Printf("✅ No active deployments on chain %s to clean up\n", result.ChainID)
+	}
+	if !*dryRun {
+		if err := ledger.Save(ledgerPath); err != nil {
+			log.Fatalf("❌ Failed to save %s: %v", chain.DeploymentsFile, err)
+		}
+	}
+
+	if !*keepFiles && len(retired) > 0 {
+		removed, err := removeAddressFiles(retired, *dryRun)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		for _, name := range removed {
+			fmt.Printf("🗑️  %s\n", name)
+		}
+		result.RemovedFiles = removed
+	}
+
+	if *sweep {
+		result.Sweeps = sweepRoles(ctx, client, *sweepTo, *dryRun)
+	}
+	result.Timestamp = now.Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_cleanup.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	fmt.Println("\n📝 Results saved to results_cleanup.json")
+
+	for _, c := range result.Deployments {
+		if c.Action == "failed" {
+			os.Exit(1)
+		}
+	}
+	for _, s := range result.Sweeps {
+		if s.Error != "" {
+			os.Exit(1)
+		}
+	}
+}
+
+// contractBase is the contract name without the build setting some callers
+// append to it, e.g. "Sha256Wrapper" for "Sha256Wrapper runs=200".
+func contractBase(name string) string {
+	if i := strings.IndexByte(name, ' '); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// destructor returns the name of the no-argument destructor in the
+// contract's artifact ABI, or "" when it has none or there is no artifact.
+func destructor(contract string) (string, *abi.ABI) {
+	if contract == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(paths.Artifact(contractBase(contract) + ".abi"))
+	if err != nil {
+		return "", nil
+	}
+	parsed, err := abi.JSON(strings.NewReader(paths.Clean(data)))
+	if err != nil {
+		return "", nil
+	}
+	for _, name := range destructors {
+		if m, ok := parsed.Methods[name]; ok && len(m.Inputs) == 0 {
+			return name, &parsed
+		}
+	}
+	return "", nil
+}
+
+// cleanDeployment destroys d when its contract has a destructor the deploy
+// role may call, and otherwise only retires it. Contracts whose code is
+// already gone are retired as gone. Since EIP-6780 a destructor only
+// removes code created in the same transaction, so the code is checked
+// again after the call rather than assumed gone.
+func cleanDeployment(ctx context.Context, client *ethclient.Client, sender *chain.Sender, d *chain.Deployment, gas uint64, dryRun bool) Cleaned {
+	c := Cleaned{Address: d.Address, Contract: d.Contract}
+	address := common.HexToAddress(d.Address)
+	code, err := client.CodeAt(ctx, address, nil)
+	if err != nil {
+		c.Action, c.Error = "failed", fmt.Sprintf("failed to get code: %v", err)
+		return c
+	}
+	if len(code) == 0 {
+		c.Action = "gone"
+		return c
+	}
+	name, parsed := destructor(d.Contract)
+	if name == "" {
+		c.Action = "retired"
+		return c
+	}
+	if dryRun {
+		c.Action = "destroyed"
+		return c
+	}
+	if sender.From != common.HexToAddress(d.Deployer) {
+		c.Action = "retired"
+		c.Error = fmt.Sprintf("deployed by %s, not the deploy role %s", d.Deployer, sender.From.Hex())
+		return c
+	}
+	data, err := parsed.Pack(name)
+	if err != nil {
+		c.Action, c.Error = "failed", fmt.Sprintf("failed to pack %s: %v", name, err)
+		return c
+	}
+	tx, receipt, err := sender.Send(ctx, &address, data, gas)
+	if err != nil {
+		c.Action, c.Error = "failed", fmt.Sprintf("%s failed: %v", name, err)
+		return c
+	}
+	c.Tx = tx.Hash().Hex()
+	if receipt.Status != 1 {
+		c.Action, c.Error = "failed", fmt.Sprintf("%s reverted in block %d", name, receipt.BlockNumber.Uint64())
+		return c
+	}
+	if code, err = client.CodeAt(ctx, address, nil); err == nil && len(code) == 0 {
+		c.Action = "destroyed"
+	} else {
+		c.Action = "retired"
+		c.Error = name + " succeeded but the code remains (EIP-6780)"
+	}
+	return c
+}
+
+func printCleaned(c Cleaned) {
+	label := c.Address
+	if c.Contract != "" {
+		label = fmt.Sprintf("%s (%s)", c.Address, c.Contract)
+	}
+	switch c.Action {
+	case "destroyed":
+		fmt.Printf("💥 %s destroyed\n", label)
+	case "gone":
+		fmt.Printf("✅ %s has no code left, retired\n", label)
+	case "failed":
+		fmt.Printf("❌ %s: %s\n", label, c.Error)
+	default:
+		if c.Error != "" {
+			fmt.Printf("📦 %s retired: %s\n", label, c.Error)
+		} else {
+			fmt.Printf("📦 %s retired\n", label)
+		}
+	}
+}
+
+// removeAddressFiles removes the deployed_*address* files in the work
+// directory that point at a retired contract, so later runs deploy afresh
+// instead of reusing it. It returns the names of the files removed, or
+// that would be with dryRun.
+func removeAddressFiles(retired map[common.Address]bool, dryRun bool) ([]string, error) {
+	matches, err := filepath.Glob(paths.Work("deployed_*address*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, path := range matches {
+		address, err := paths.ReadAddress(path)
+		if err != nil || !retired[address] {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return removed, fmt.Errorf("failed to remove %s: %v", path, err)
+			}
+		}
+		removed = append(removed, filepath.Base(path))
+	}
+	return removed, nil
+}
+
+// sweepRoles moves what the deploy and invoke roles hold, less the fee, to
+// to or the fund role. Roles that share an account with the destination or
+// with each other are swept once at most.
+func sweepRoles(ctx context.Context, client *ethclient.Client, to string, dryRun bool) []Swept {
+	var dest common.Address
+	if to != "" {
+		if !common.IsHexAddress(to) {
+			log.Fatalf("❌ --sweep-to %q is not an address", to)
+		}
+		dest = common.HexToAddress(to)
+	} else {
+		var err error
+		if dest, err = chain.RoleAddress(ctx, chain.RoleFund); err != nil {
+			log.Fatalf("❌ fund role: %v (pass --sweep-to)", err)
+		}
+	}
+	fmt.Printf("\n🧹 Sweeping residual balances to %s\n", dest.Hex())
+
+	var sweeps []Swept
+	seen := map[common.Address]bool{dest: true}
+	for _, role := range []chain.Role{chain.RoleDeploy, chain.RoleInvoke} {
+		address, err := chain.RoleAddress(ctx, role)
+		if err != nil {
+			sweeps = append(sweeps, Swept{Role: role, Error: err.Error()})
+			fmt.Printf("❌ %s role: %v\n", role, err)
+			continue
+		}
+		if seen[address] {
+			fmt.Printf("⏭️  %s role uses %s, nothing to sweep\n", role, address.Hex())
+			continue
+		}
+		seen[address] = true
+		s := Swept{Role: role, Address: address.Hex()}
+		if dryRun {
+			fmt.Printf("🧹 %s role (%s) would be swept\n", role, address.Hex())
+			sweeps = append(sweeps, s)
+			continue
+		}
+		sender, err := chain.NewRoleSender(ctx, client, role)
+		if err != nil {
+			s.Error = err.Error()
+		} else if tx, receipt, err := chain.Sweep(ctx, sender, dest); err != nil {
+			s.Error = err.Error()
+		} else if tx == nil {
+			fmt.Printf("✅ %s role (%s) holds too little to sweep\n", role, address.Hex())
+		} else if s.Tx = tx.Hash().Hex(); receipt.Status != 1 {
+			s.Error = fmt.Sprintf("sweep %s reverted", s.Tx)
+		} else {
+			fmt.Printf("✅ %s role (%s) swept in block %d\n", role, address.Hex(), receipt.BlockNumber.Uint64())
+		}
+		if s.Error != "" {
+			fmt.Printf("❌ %s role: %s\n", role, s.Error)
+		}
+		sweeps = append(sweeps, s)
+	}
+	return sweeps
+}
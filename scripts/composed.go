@@ -0,0 +1,232 @@
+//suite:writes transactions=1 gas=1000000
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/composed"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// ComposedCase is one input run through one composition of the SHA-256
+// precompile with keccak256 and ABI encoding.
+type ComposedCase struct {
+	Name        string               `json:"name"`
+	Precompile  string               `json:"precompile"`
+	Composition composed.Composition `json:"composition"`
+	Input       hexutil.Bytes        `json:"input"`
+	Expected    hexutil.Bytes        `json:"expected"`
+	Returned    hexutil.Bytes        `json:"returned,omitempty"`
+	Match       bool                 `json:"match"`
+	Error       string               `json:"error,omitempty"`
+}
+
+type ComposedResult struct {
+	Stage      string         `json:"stage"`
+	Contract   string         `json:"contract"`
+	Cases      []ComposedCase `json:"cases"`
+	Matches    int            `json:"matches"`
+	Mismatches int            `json:"mismatches"`
+	Errors     int            `json:"errors"`
+	Timestamp  string         `json:"timestamp"`
+	RPCURL     string         `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	contractFlag := flag.String("contract", "", "ComposedHashes address to use instead of the saved or a freshly deployed one")
+	gasLimit := flag.Uint64("gas", 1_000_000, "gas limit of the ComposedHashes deployment")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_composed.json")
+
+	contract, err := resolveComposedContract(ctx, client, *contractFlag, *gasLimit)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("📌 Using ComposedHashes at %s\n", contract.Hex())
+
+	// Lengths around the 55-byte single-block limit of SHA-256 padding and
+	// the 136-byte keccak256 rate
+	vectors := vector.Select([]vector.Vector{
+		vector.New([]byte("hello world"), tags.Smoke),
+		vector.New([]byte(""), tags.Smoke),
+		vector.New(bytes.Repeat([]byte{0xab}, 55), tags.Binary),
+		vector.New(bytes.Repeat([]byte{0xcd}, 56), tags.Binary),
+		vector.New(bytes.Repeat([]byte{0xef}, 136), tags.Binary),
+		vector.New([]byte(strings.Repeat("composed", 100))),
+	}, tagFilter)
+
+	result := ComposedResult{
+		Stage:    "Composed - SHA-256 With Keccak256 and ABI Encoding",
+		Contract: contract.Hex(),
+		RPCURL:   rpcURL,
+	}
+	fmt.Printf("🔗 Running %d inputs through %d compositions\n", len(vectors), len(composed.Compositions))
+	for _, v := range vectors {
+		for _, comp := range composed.Compositions {
+			c := runComposedCase(ctx, client, contract, comp, v)
+			switch {
+			case c.Error != "":
+				result.Errors++
+			case c.Match:
+				result.Matches++
+			default:
+				result.Mismatches++
+			}
+			result.Cases = append(result.Cases, c)
+		}
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := saveComposedResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Composition results:")
+	for _, c := range result.Cases {
+		switch {
+		case c.Error != "":
+			fmt.Printf("⚠️  %s: %s\n", c.Name, c.Error)
+		case !c.Match:
+			fmt.Printf("❌ %s\n  Returned: %s\n  Expected: %s\n", c.Name, c.Returned, c.Expected)
+		}
+	}
+	fmt.Printf("✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Printf("⚠️  Errors:     %d\n", result.Errors)
+	fmt.Println("\n📝 Results saved to results_composed.json")
+	if result.Mismatches > 0 || result.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// runComposedCase calls comp over v and compares the answer with the local
+// composition.
+func runComposedCase(ctx context.Context, client *ethclient.Client, contract common.Address, comp composed.Composition, v vector.Vector) ComposedCase {
+	c := ComposedCase{
+		Name:        fmt.Sprintf("%s %s", comp, v.Display()),
+		Precompile:  precompile.SHA256Address.Hex(),
+		Composition: comp,
+		Input:       v.Bytes(),
+	}
+	expected, err := comp.Expected(v.Bytes())
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	c.Expected = expected
+	returned, err := composed.Call(ctx, client, contract, comp, v.Bytes())
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	c.Returned = returned
+	c.Match = bytes.Equal(returned, expected)
+	return c
+}
+
+// resolveComposedContract picks the ComposedHashes to call: the --contract
+// address, the one saved in deployed_composed_address.txt, or a fresh
+// deployment of artifacts/ComposedHashes, in that order.
+func resolveComposedContract(ctx context.Context, client *ethclient.Client, override string, gas uint64) (common.Address, error) {
+	if override != "" {
+		if !common.IsHexAddress(override) {
+			return common.Address{}, fmt.Errorf("invalid --contract address %q", override)
+		}
+		address := common.HexToAddress(override)
+		if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+			return common.Address{}, err
+		}
+		return address, nil
+	}
+	if address, err := paths.ReadAddress(paths.Work("deployed_composed_address.txt")); err == nil {
+		if code, err := client.CodeAt(ctx, address, nil); err == nil && len(code) > 0 {
+			return address, nil
+		}
+	}
+
+	bytecode, err := paths.ReadHex(paths.Artifact("ComposedHashes.bin"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read bytecode (compile contracts/ComposedHashes.sol first): %v", err)
+	}
+	if err := deploy.VerifyArtifact(paths.Artifact("ComposedHashes")); err != nil {
+		return common.Address{}, fmt.Errorf("refusing to deploy: %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		return common.Address{}, fmt.Errorf("no ComposedHashes deployed and can't deploy one (pass --contract): %v", err)
+	}
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleDeploy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	fmt.Printf("📨 Deploying ComposedHashes from %s...\n", sender.From.Hex())
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "ComposedHashes"), nil, common.FromHex(bytecode), gas)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return common.Address{}, fmt.Errorf("ComposedHashes deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	if err := paths.WriteFile(paths.Work("deployed_composed_address.txt"), []byte(receipt.ContractAddress.Hex())); err != nil {
+		return common.Address{}, fmt.Errorf("failed to save deployed address: %v", err)
+	}
+	return receipt.ContractAddress, nil
+}
+
+func saveComposedResult(result ComposedResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_composed.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
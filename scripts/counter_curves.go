@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/gascap"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/zkcounters"
+)
+
+// curveTarget is a precompile whose input can be grown to a given size.
+type curveTarget struct {
+	name    string
+	address common.Address
+	// input builds an input of about size bytes, or nil if the precompile
+	// takes no input that small.
+	input func(size int) []byte
+}
+
+var curveTargets = []curveTarget{
+	{"sha256", precompile.SHA256Address, filler},
+	{"ripemd160", common.HexToAddress("0x03"), filler},
+	{"identity", common.HexToAddress("0x04"), filler},
+	{"modexp", precompile.ModExpAddress, func(size int) []byte {
+		// Three equal operands after the 96-byte length header
+		n := (size - 96) / 3
+		if n <= 0 {
+			return nil
+		}
+		base := filler(n)
+		mod := filler(n)
+		mod[n-1] |= 1
+		return precompile.ModExp{Base: base, Exp: filler(n), Mod: mod}.Input()
+	}},
+	{"pairing", precompile.PairingAddress, func(size int) []byte {
+		if size < 192 {
+			return nil
+		}
+		input, _ := precompile.PairingInput(size / 192)
+		return input
+	}},
+}
+
+// filler is size non-zero bytes.
+func filler(size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(i%251 + 1)
+	}
+	return b
+}
+
+// Curve is one precompile's counter usage across input sizes.
+type Curve struct {
+	Precompile string             `json:"precompile"`
+	Address    string             `json:"address"`
+	Points     []zkcounters.Point `json:"points"`
+	Fits       []zkcounters.Fit   `json:"fits,omitempty"`
+	// PerBatch is how many calls with the largest fitting input a batch
+	// holds, and Bottleneck the counter that runs out first.
+	LargestBytes int    `json:"largestBytes,omitempty"`
+	PerBatch     int    `json:"perBatch,omitempty"`
+	Bottleneck   string `json:"bottleneck,omitempty"`
+}
+
+type CounterCurvesResult struct {
+	Stage     string  `json:"stage"`
+	Supported bool    `json:"supported"`
+	Reason    string  `json:"reason,omitempty"`
+	Sizes     []int   `json:"sizes"`
+	Curves    []Curve `json:"curves"`
+	Timestamp string  `json:"timestamp"`
+	RPCURL    string  `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	sizesFlag := flag.String("sizes", "0,32,64,128,256,512,1024,2048,4096,8192", "comma-separated input sizes in bytes")
+	targetsFlag := flag.String("targets", "", "comma-separated precompiles to sweep (default: sha256,ripemd160,identity,modexp,pairing)")
+	csvPath := flag.String("csv", "", "also write the points as precompile,bytes,counter,used rows for plotting")
+	gasCapFile := flag.String("gas-cap-file", paths.Work(gascap.DefaultPath), "gas cap report of scripts/gas_cap.go; inputs beyond the cap are skipped")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.ZKCounters}) {
+		fmt.Printf("⏭️  Counter curves skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	sizes, err := parseByteSizes(*sizesFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	targets, err := selectCurveTargets(*targetsFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_counters.json")
+
+	gasCap, err := gascap.Load(*gasCapFile, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	result := CounterCurvesResult{
+		Stage:     "Counter Curves - zk Counters per Precompile and Input Size",
+		Supported: true,
+		Sizes:     sizes,
+		RPCURL:    rpcURL,
+	}
+sweep:
+	for _, t := range targets {
+		curve := Curve{Precompile: t.name, Address: t.address.Hex()}
+		seen := map[int]bool{}
+		for _, size := range sizes {
+			input := t.input(size)
+			if input == nil || seen[len(input)] {
+				continue
+			}
+			seen[len(input)] = true
+			if reason := gasCap.SkipReason(t.name, input); reason != "" {
+				fmt.Printf("⏭️  %s %d bytes: %s\n", t.name, len(input), reason)
+				continue
+			}
+			point, estimate, err := measureCounters(ctx, client, t.address, input)
+			var rpcErr rpc.Error
+			if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+				result.Supported, result.Reason = false, err.Error()
+				fmt.Printf("⏭️  %v\n", err)
+				break sweep
+			}
+			if ctx.Err() != nil {
+				log.Fatalf("❌ Interrupted: %v", ctx.Err())
+			}
+			curve.Points = append(curve.Points, point)
+			switch {
+			case point.Error != "":
+				fmt.Printf("❌ %s %d bytes: %s\n", t.name, point.Bytes, point.Error)
+			case point.OOC != "":
+				fmt.Printf("🛑 %s %d bytes: out of counters: %s\n", t.name, point.Bytes, point.OOC)
+			default:
+				curve.LargestBytes = point.Bytes
+				curve.PerBatch, curve.Bottleneck = estimate.PerBatch()
+				fmt.Printf("🧮 %s %d bytes: %s\n", t.name, point.Bytes, formatUsed(point.Used))
+			}
+		}
+		curve.Fits = zkcounters.Fits(curve.Points)
+		result.Curves = append(result.Curves, curve)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_counters.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+	if *csvPath != "" {
+		if err := writeCurvesCSV(*csvPath, result.Curves); err != nil {
+			log.Fatalf("❌ Failed to save CSV: %v", err)
+		}
+		fmt.Printf("📝 Points saved to %s\n", *csvPath)
+	}
+
+	if result.Supported {
+		printCurves(result.Curves)
+	}
+	fmt.Println("\n📝 Results saved to results_counters.json")
+}
+
+func measureCounters(ctx context.Context, client *ethclient.Client, to common.Address, input []byte) (zkcounters.Point, *zkcounters.Estimate, error) {
+	point := zkcounters.Point{Bytes: len(input)}
+	estimate, err := zkcounters.EstimateCall(ctx, client, ethereum.CallMsg{To: &to, Data: input})
+	if err != nil {
+		point.Error = err.Error()
+		return point, nil, err
+	}
+	point.Used, point.OOC = estimate.Used, estimate.OOC
+	return point, estimate, nil
+}
+
+func printCurves(curves []Curve) {
+	fmt.Println("\n📈 Counters per input byte (least-squares fit):")
+	for _, c := range curves {
+		var parts []string
+		for _, f := range c.Fits {
+			if f.PerByte != 0 {
+				parts = append(parts, fmt.Sprintf("%s %.2f", f.Counter, f.PerByte))
+			}
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "flat")
+		}
+		fmt.Printf("   %s: %s\n", c.Precompile, strings.Join(parts, ", "))
+		if c.Bottleneck != "" {
+			fmt.Printf("      %d calls of %d bytes per batch, limited by %s\n", c.PerBatch, c.LargestBytes, c.Bottleneck)
+		}
+	}
+}
+
+func formatUsed(used map[string]uint64) string {
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, used[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeCurvesCSV(path string, curves []Curve) error {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{"precompile", "bytes", "counter", "used"})
+	for _, c := range curves {
+		for _, p := range c.Points {
+			if p.Error != "" {
+				continue
+			}
+			names := make([]string, 0, len(p.Used))
+			for name := range p.Used {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				w.Write([]string{c.Precompile, strconv.Itoa(p.Bytes), name, strconv.FormatUint(p.Used[name], 10)})
+			}
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return paths.WriteFile(path, []byte(b.String()))
+}
+
+func parseByteSizes(spec string) ([]int, error) {
+	var sizes []int
+	for _, s := range tags.Parse(spec) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid input size %q in --sizes", s)
+		}
+		sizes = append(sizes, n)
+	}
+	sort.Ints(sizes)
+	return sizes, nil
+}
+
+func selectCurveTargets(spec string) ([]curveTarget, error) {
+	if spec == "" {
+		return curveTargets, nil
+	}
+	var selected []curveTarget
+	for _, name := range tags.Parse(spec) {
+		found := false
+		for _, t := range curveTargets {
+			if t.name == name {
+				selected = append(selected, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown precompile %q in --targets", name)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/bench"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+type ECRecoverResult struct {
+	Stage       string `json:"stage"`
+	Precompile  string `json:"precompile"`
+	Keys        int    `json:"keys"`
+	Concurrency int    `json:"concurrency"`
+	Warmup      int    `json:"warmup"`
+	// Recoveries counts calls answered with the signer's address.
+	Recoveries       int                `json:"recoveries"`
+	Mismatches       int                `json:"mismatches"`
+	Errors           int                `json:"errors"`
+	DurationS        float64            `json:"durationS"`
+	RecoveriesPerSec float64            `json:"recoveriesPerSec"`
+	Latency          bench.Summary      `json:"latency"`
+	Failures         []ECRecoverFailure `json:"failures,omitempty"`
+	Timestamp        string             `json:"timestamp"`
+	RPCURL           string             `json:"rpcUrl"`
+}
+
+// ECRecoverFailure is a signature the node didn't recover correctly.
+type ECRecoverFailure struct {
+	Hash      common.Hash    `json:"hash"`
+	Signature string         `json:"signature"`
+	Expected  common.Address `json:"expected"`
+	Recovered common.Address `json:"recovered"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// maxFailures caps how many failures are kept in the results file.
+const maxFailures = 20
+
+func main() {
+	output.Setup()
+
+	keys := flag.Int("keys", 1000, "number of keys to generate and signatures to verify")
+	concurrency := flag.Int("concurrency", 32, "number of concurrent eth_call workers")
+	warmup := flag.Int("warmup", 10, "number of unmeasured warm-up recoveries")
+	outlierK := flag.Float64("outlier-k", 1.5, "Tukey fence multiplier for outlier rejection (0 disables)")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Slow}) {
+		fmt.Printf("⏭️  ecrecover benchmark skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	if *keys <= 0 || *concurrency <= 0 {
+		log.Fatal("❌ --keys and --concurrency must be positive")
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_ecrecover.json")
+
+	// Sign everything up front so only the recoveries are timed
+	fmt.Printf("🔑 Generating %d keys and signatures...\n", *keys)
+	sigs, err := precompile.RandomSignatures(*keys + *warmup)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	warm, sigs := sigs[:*warmup], sigs[*warmup:]
+
+	fmt.Printf("🔥 Warm-up: %d recoveries\n", len(warm))
+	for _, sig := range warm {
+		if _, err := precompile.CallECRecover(ctx, client, sig); err != nil {
+			log.Fatalf("❌ Warm-up call failed: %v", err)
+		}
+	}
+
+	fmt.Printf("⏱️  Verifying %d signatures with %d workers...\n", len(sigs), *concurrency)
+	result := ECRecoverResult{
+		Stage:       "ECRecoverBenchmark",
+		Precompile:  "0x01",
+		Keys:        *keys,
+		Concurrency: *concurrency,
+		Warmup:      *warmup,
+		RPCURL:      rpcURL,
+	}
+	latencies := recoverAll(ctx, client, sigs, *concurrency, &result)
+	result.Latency = bench.Summarize(bench.Millis(latencies), *outlierK)
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	printECRecoverSummary(result)
+
+	if err := saveECRecoverResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Println("📝 Results saved to results_ecrecover.json")
+
+	if result.Mismatches > 0 || result.Errors > 0 {
+		log.Fatalf("❌ %d of %d signatures were not recovered (%d wrong, %d errors)",
+			result.Mismatches+result.Errors, len(sigs), result.Mismatches, result.Errors)
+	}
+}
+
+// recoverAll verifies sigs on concurrency workers and tallies the outcome
+// into result. It returns the latency of every call.
+func recoverAll(ctx context.Context, client *ethclient.Client, sigs []precompile.Signature, concurrency int, result *ECRecoverResult) []time.Duration {
+	jobs := make(chan int)
+	latencies := make([]time.Duration, len(sigs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				callStart := time.Now()
+				recovered, err := precompile.CallECRecover(ctx, client, sigs[i])
+				latencies[i] = time.Since(callStart)
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					result.Errors++
+				case recovered != sigs[i].Signer:
+					result.Mismatches++
+				default:
+					result.Recoveries++
+				}
+				if (err != nil || recovered != sigs[i].Signer) && len(result.Failures) < maxFailures {
+					failure := ECRecoverFailure{
+						Hash:      sigs[i].Hash,
+						Signature: fmt.Sprintf("0x%x", sigs[i].Sig),
+						Expected:  sigs[i].Signer,
+						Recovered: recovered,
+					}
+					if err != nil {
+						failure.Error = err.Error()
+					}
+					result.Failures = append(result.Failures, failure)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range sigs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	result.DurationS = elapsed.Seconds()
+	result.RecoveriesPerSec = float64(len(sigs)) / elapsed.Seconds()
+	return latencies
+}
+
+func printECRecoverSummary(result ECRecoverResult) {
+	s := result.Latency
+	fmt.Printf("\n📊 Recovered %s of %s signatures in %s: %.1f recoveries/sec\n",
+		output.Count(result.Recoveries), output.Count(result.Keys),
+		output.Duration(time.Duration(result.DurationS*float64(time.Second))), result.RecoveriesPerSec)
+	fmt.Println("📊 Latency per call:")
+	fmt.Printf("  Samples: %s kept, %s outliers discarded\n", output.Count(s.Samples), output.Count(s.Discarded))
+	fmt.Printf("  Median:  %s\n", output.Millis(s.Median))
+	fmt.Printf("  P95:     %s\n", output.Millis(s.P95))
+	fmt.Printf("  Min/Max: %s / %s\n", output.Millis(s.Min), output.Millis(s.Max))
+	for _, f := range result.Failures {
+		if f.Error != "" {
+			fmt.Printf("❌ %s: %s\n", f.Hash.Hex(), f.Error)
+		} else {
+			fmt.Printf("❌ %s: recovered %s, expected %s\n", f.Hash.Hex(), f.Recovered.Hex(), f.Expected.Hex())
+		}
+	}
+}
+
+func saveECRecoverResult(result ECRecoverResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_ecrecover.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+//suite:writes transactions=1 gas=1500000
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/signer"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/typeddata"
+)
+
+// TypedDataCase is one check of the on-chain verifier against the local
+// EIP-712 implementation.
+type TypedDataCase struct {
+	Name       string `json:"name"`
+	Precompile string `json:"precompile"`
+	Expected   string `json:"expected"`
+	Actual     string `json:"actual,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Match      bool   `json:"match"`
+}
+
+type TypedDataResult struct {
+	Stage      string          `json:"stage"`
+	Contract   string          `json:"contract"`
+	ChainID    string          `json:"chainId"`
+	Signer     string          `json:"signer"`
+	Separator  string          `json:"domainSeparator"`
+	Digest     string          `json:"digest"`
+	Cases      []TypedDataCase `json:"cases"`
+	Matches    int             `json:"matches"`
+	Mismatches int             `json:"mismatches"`
+	Timestamp  string          `json:"timestamp"`
+	RPCURL     string          `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	contractFlag := flag.String("contract", "", "TypedDataVerifier address to use instead of the saved or a freshly deployed one")
+	gasLimit := flag.Uint64("gas", 1_500_000, "gas limit of the TypedDataVerifier deployment")
+	role := flag.String("sign-with", "", "sign with this role's key (deploy, invoke) instead of a throwaway key, e.g. to test a KMS signer")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Smoke}) {
+		fmt.Printf("⏭️  EIP-712 verification skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_eip712.json")
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatalf("❌ Failed to get chain ID: %v", err)
+	}
+	contract, err := resolveVerifierContract(ctx, client, *contractFlag, *gasLimit)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("📌 Using TypedDataVerifier at %s\n", contract.Hex())
+
+	s, err := typedDataSigner(ctx, *role)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("🔑 Signing as %s\n", s.Address().Hex())
+
+	domain := typeddata.VerifierDomain(chainID, contract)
+	mail := typeddata.Mail{
+		From:     typeddata.Person{Name: "Signer", Wallet: s.Address()},
+		To:       typeddata.Person{Name: "Verifier", Wallet: contract},
+		Contents: "ecrecover through EIP-712",
+	}
+	separator, digest, err := typeddata.Hash(domain, mail)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	sig, err := typeddata.Sign(ctx, s, digest)
+	if err != nil {
+		log.Fatalf("❌ Failed to sign typed data: %v", err)
+	}
+
+	result := TypedDataResult{
+		Stage:     "EIP-712 - Typed Data Signed Locally, Verified On-chain",
+		Contract:  contract.Hex(),
+		ChainID:   chainID.String(),
+		Signer:    s.Address().Hex(),
+		Separator: separator.Hex(),
+		Digest:    digest.Hex(),
+		RPCURL:    rpcURL,
+	}
+	result.Cases = typedDataCases(ctx, typeddata.Verifier{Client: client, Contract: contract}, s, domain, mail, sig)
+	for _, c := range result.Cases {
+		if c.Match {
+			result.Matches++
+		} else {
+			result.Mismatches++
+		}
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_eip712.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 EIP-712 results:")
+	for _, c := range result.Cases {
+		switch {
+		case c.Match:
+			fmt.Printf("✅ %s\n", c.Name)
+		case c.Error != "":
+			fmt.Printf("❌ %s: %s\n", c.Name, c.Error)
+		default:
+			fmt.Printf("❌ %s: got %s, expected %s\n", c.Name, c.Actual, c.Expected)
+		}
+	}
+	fmt.Printf("✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Println("\n📝 Results saved to results_eip712.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// typedDataSigner is the role's signer, or a throwaway key: everything is
+// checked with eth_call, so the signer needs no funds.
+func typedDataSigner(ctx context.Context, role string) (signer.Signer, error) {
+	if role != "" {
+		return chain.RoleSigner(ctx, chain.Role(role))
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewLocal(key), nil
+}
+
+// typedDataCases checks that the contract hashes the typed data as the
+// local implementation does, that ecrecover inside it returns the signer,
+// and that verify rejects tampered mail, another chain's domain and the
+// non-canonical forms of the signature.
+func typedDataCases(ctx context.Context, v typeddata.Verifier, s signer.Signer, domain typeddata.Domain, mail typeddata.Mail, sig typeddata.Signature) []TypedDataCase {
+	ecrecover := precompile.ECRecoverAddress.Hex()
+	var out []TypedDataCase
+	check := func(name, expected string, actual func() (string, error)) {
+		c := TypedDataCase{Name: name, Precompile: ecrecover, Expected: expected}
+		got, err := actual()
+		if err != nil {
+			c.Error = err.Error()
+		} else {
+			c.Actual, c.Match = got, got == expected
+		}
+		out = append(out, c)
+	}
+	verify := func(m typeddata.Mail, signer common.Address, sig typeddata.Signature) func() (string, error) {
+		return func() (string, error) {
+			ok, err := v.Verify(ctx, m, signer, sig)
+			return fmt.Sprint(ok), err
+		}
+	}
+	recover := func(sig typeddata.Signature) func() (string, error) {
+		return func() (string, error) {
+			a, err := v.Recover(ctx, mail, sig)
+			return a.Hex(), err
+		}
+	}
+
+	separator, digest, _ := typeddata.Hash(domain, mail)
+	check("domain separator", separator.Hex(), func() (string, error) {
+		sep, err := v.DomainSeparator(ctx)
+		return sep.Hex(), err
+	})
+	check("typed data digest", digest.Hex(), func() (string, error) {
+		d, err := v.Digest(ctx, mail)
+		return d.Hex(), err
+	})
+	check("ecrecover returns the signer", s.Address().Hex(), recover(sig))
+	check("signature verifies", "true", verify(mail, s.Address(), sig))
+	check("signature of another account rejected", "false", verify(mail, mail.To.Wallet, sig))
+
+	tampered := mail
+	tampered.Contents += "!"
+	check("tampered contents rejected", "false", verify(tampered, s.Address(), sig))
+	tampered = mail
+	tampered.From.Name = "Signer "
+	check("tampered nested struct rejected", "false", verify(tampered, s.Address(), sig))
+
+	other := domain
+	other.ChainID = new(big.Int).Add(domain.ChainID, big.NewInt(1))
+	if _, otherDigest, err := typeddata.Hash(other, mail); err == nil {
+		if otherSig, err := typeddata.Sign(ctx, s, otherDigest); err == nil {
+			check("signature for another chain rejected", "false", verify(mail, s.Address(), otherSig))
+		}
+	}
+
+	// ecrecover itself accepts the high-s twin; only the contract's EIP-2
+	// check refuses it
+	high := sig.Malleate()
+	check("ecrecover accepts high-s signature", s.Address().Hex(), recover(high))
+	check("high-s signature rejected", "false", verify(mail, s.Address(), high))
+
+	bad := sig
+	bad.V = 29
+	check("ecrecover of v=29 returns zero address", common.Address{}.Hex(), recover(bad))
+	return out
+}
+
+// resolveVerifierContract picks the TypedDataVerifier to call: the
+// --contract address, the one saved in deployed_eip712_address.txt, or a
+// fresh deployment of artifacts/TypedDataVerifier, in that order.
+func resolveVerifierContract(ctx context.Context, client *ethclient.Client, override string, gas uint64) (common.Address, error) {
+	if override != "" {
+		if !common.IsHexAddress(override) {
+			return common.Address{}, fmt.Errorf("invalid --contract address %q", override)
+		}
+		address := common.HexToAddress(override)
+		if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+			return common.Address{}, err
+		}
+		return address, nil
+	}
+	if address, err := paths.ReadAddress(paths.Work("deployed_eip712_address.txt")); err == nil {
+		if code, err := client.CodeAt(ctx, address, nil); err == nil && len(code) > 0 {
+			return address, nil
+		}
+	}
+
+	bytecode, err := paths.ReadHex(paths.Artifact("TypedDataVerifier.bin"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read bytecode (compile contracts/TypedDataVerifier.sol first): %v", err)
+	}
+	if err := deploy.VerifyArtifact(paths.Artifact("TypedDataVerifier")); err != nil {
+		return common.Address{}, fmt.Errorf("refusing to deploy: %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		return common.Address{}, fmt.Errorf("no TypedDataVerifier deployed and can't deploy one (pass --contract): %v", err)
+	}
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleDeploy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	fmt.Printf("📨 Deploying TypedDataVerifier from %s...\n", sender.From.Hex())
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "TypedDataVerifier"), nil, common.FromHex(bytecode), gas)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return common.Address{}, fmt.Errorf("TypedDataVerifier deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	if err := paths.WriteFile(paths.Work("deployed_eip712_address.txt"), []byte(receipt.ContractAddress.Hex())); err != nil {
+		return common.Address{}, fmt.Errorf("failed to save deployed address: %v", err)
+	}
+	return receipt.ContractAddress, nil
+}
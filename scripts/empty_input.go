@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/crypto/ripemd160"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/memexp"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/reference"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// EmptyInputCase is one precompile called with zero-length input, directly
+// and from inside the EVM.
+type EmptyInputCase struct {
+	Name       string `json:"name"`
+	Precompile string `json:"precompile"`
+	// ExpectedSuccess and Expected are the specification's answer.
+	ExpectedSuccess bool          `json:"expectedSuccess"`
+	Expected        hexutil.Bytes `json:"expected"`
+	// Success and Returned are the direct eth_call's.
+	Success  bool          `json:"success"`
+	Returned hexutil.Bytes `json:"returned,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	// InnerSuccess and InnerReturned are the STATICCALL's flag and return
+	// data; InnerGas must equal go-ethereum's ReferenceInnerGas.
+	InnerSuccess      bool          `json:"innerSuccess"`
+	InnerReturned     hexutil.Bytes `json:"innerReturned,omitempty"`
+	InnerGas          uint64        `json:"innerGas"`
+	ReferenceInnerGas uint64        `json:"referenceInnerGas"`
+	Failures          []string      `json:"failures,omitempty"`
+	Match             bool          `json:"match"`
+}
+
+type EmptyInputResult struct {
+	Stage      string           `json:"stage"`
+	Cases      []EmptyInputCase `json:"cases"`
+	Matches    int              `json:"matches"`
+	Mismatches int              `json:"mismatches"`
+	Timestamp  string           `json:"timestamp"`
+	RPCURL     string           `json:"rpcUrl"`
+}
+
+// emptyExpectation is what a precompile must answer for empty input.
+type emptyExpectation struct {
+	name    string
+	address common.Address
+	success bool
+	output  []byte
+}
+
+// emptyExpectations spells out the specification for every precompile up
+// to Cancun. Short inputs are zero-padded where the precompile reads fixed
+// fields, so the curve operations see the point at infinity and the
+// pairing an empty product; blake2f and point evaluation take exact
+// lengths and must fail.
+func emptyExpectations() []emptyExpectation {
+	digest := sha256.Sum256(nil)
+	ripemd := ripemd160.New()
+	one := make([]byte, 32)
+	one[31] = 1
+	address := func(b byte) common.Address { return common.BytesToAddress([]byte{b}) }
+	return []emptyExpectation{
+		{"ecrecover", address(0x01), true, []byte{}},
+		{"sha256", address(0x02), true, digest[:]},
+		{"ripemd160", address(0x03), true, common.LeftPadBytes(ripemd.Sum(nil), 32)},
+		{"identity", address(0x04), true, []byte{}},
+		{"modexp", address(0x05), true, []byte{}},
+		{"bn256add", address(0x06), true, make([]byte, 64)},
+		{"bn256mul", address(0x07), true, make([]byte, 64)},
+		{"bn256pairing", address(0x08), true, one},
+		{"blake2f", address(0x09), false, nil},
+		{"point_evaluation", address(0x0a), false, nil},
+	}
+}
+
+func main() {
+	output.Setup()
+
+	gas := flag.Uint64("gas", 1_000_000, "gas of each eth_call")
+	skipList := flag.String("skip", "", "comma-separated precompile addresses the chain doesn't define, e.g. 0x0a before Cancun")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Smoke}) {
+		fmt.Printf("⏭️  Empty input checks skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	skipped := map[common.Address]bool{}
+	for _, s := range tags.Parse(*skipList) {
+		n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+		if !ok || n.BitLen() > 160 {
+			log.Fatalf("❌ invalid --skip address %q", s)
+		}
+		skipped[common.BigToAddress(n)] = true
+	}
+
+	// The expectations are checked against the reference implementations
+	// first, so a mismatch below is always the node's
+	expectations := emptyExpectations()
+	for _, e := range expectations {
+		out, err := reference.Run(e.address, nil)
+		if (err == nil) != e.success || (err == nil && !bytes.Equal(out, e.output)) {
+			log.Fatalf("❌ The reference %s disagrees with the specification for empty input: %x, %v", e.name, out, err)
+		}
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_empty_input.json")
+
+	result := EmptyInputResult{
+		Stage:  "Empty Input - Every Precompile With Zero-Length Input",
+		RPCURL: rpcURL,
+	}
+	fmt.Printf("🫙 Calling %d precompiles with empty input\n", len(expectations)-len(skipped))
+	for _, e := range expectations {
+		if skipped[e.address] {
+			fmt.Printf("⏭️  %-16s skipped\n", e.name)
+			continue
+		}
+		c, err := checkEmptyInput(ctx, client, e, *gas)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if c.Match {
+			result.Matches++
+			fmt.Printf("✅ %-16s %s\n", e.name, describeEmpty(c.Success, c.Returned))
+		} else {
+			result.Mismatches++
+			fmt.Printf("❌ %-16s %s\n", e.name, strings.Join(c.Failures, "; "))
+		}
+		result.Cases = append(result.Cases, c)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := saveEmptyInputResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Printf("\n✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Println("\n📝 Results saved to results_empty_input.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkEmptyInput calls e's precompile with no input in a direct eth_call,
+// which must fail or return the expected output, and in a STATICCALL from
+// inside the EVM, whose success flag and return data must agree and whose
+// gas must match go-ethereum's.
+func checkEmptyInput(ctx context.Context, client *ethclient.Client, e emptyExpectation, gas uint64) (EmptyInputCase, error) {
+	c := EmptyInputCase{Name: e.name, Precompile: e.address.Hex(), ExpectedSuccess: e.success, Expected: e.output}
+	fail := func(format string, args ...any) {
+		c.Failures = append(c.Failures, fmt.Sprintf(format, args...))
+	}
+	want := describeEmpty(e.success, e.output)
+
+	returned, err := client.CallContract(ctx, ethereum.CallMsg{To: &e.address, Gas: gas}, nil)
+	var rpcErr rpc.Error
+	switch {
+	case errors.As(err, &rpcErr):
+		c.Error = err.Error()
+	case err != nil:
+		return EmptyInputCase{}, fmt.Errorf("%s: %w", e.name, err)
+	default:
+		c.Success, c.Returned = true, returned
+	}
+	if c.Success != e.success || (c.Success && !bytes.Equal(c.Returned, e.output)) {
+		fail("eth_call %s, want %s", describeEmpty(c.Success, c.Returned), want)
+	}
+
+	// 64 bytes hold the longest expected output
+	inner := memexp.Case{Name: e.name, Precompile: e.address, OutSize: 64}
+	node, err := memexp.Call(ctx, client, inner, gas)
+	if err != nil {
+		return EmptyInputCase{}, err
+	}
+	ref, err := memexp.Reference(inner, gas)
+	if err != nil {
+		return EmptyInputCase{}, err
+	}
+	if node.Failed {
+		fail("inner call failed: %s", node.Error)
+		return c, nil
+	}
+	c.InnerSuccess, c.InnerGas, c.ReferenceInnerGas = node.Success, node.GasUsed, ref.GasUsed
+	c.InnerReturned = node.Output[:min(node.ReturnSize, inner.OutSize)]
+	if c.InnerSuccess != e.success || (c.InnerSuccess && (node.ReturnSize != uint64(len(e.output)) || !bytes.Equal(c.InnerReturned, e.output))) {
+		fail("inner call %s, want %s", describeEmpty(c.InnerSuccess, c.InnerReturned), want)
+	}
+	if !ref.Failed && node.GasUsed != ref.GasUsed {
+		fail("inner call cost %d gas, reference %d", node.GasUsed, ref.GasUsed)
+	}
+	c.Match = len(c.Failures) == 0
+	return c, nil
+}
+
+func describeEmpty(success bool, out []byte) string {
+	switch {
+	case !success:
+		return "failed"
+	case len(out) == 0:
+		return "succeeded with no output"
+	default:
+		return fmt.Sprintf("returned %x", out)
+	}
+}
+
+func saveEmptyInputResult(result EmptyInputResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_empty_input.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
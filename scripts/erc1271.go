@@ -0,0 +1,307 @@
+//suite:writes transactions=2 gas=2000000
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/erc1271"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/signer"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/typeddata"
+)
+
+// WalletCase is one signature handed to a wallet's isValidSignature.
+type WalletCase struct {
+	Name       string        `json:"name"`
+	Precompile string        `json:"precompile"`
+	Wallet     string        `json:"wallet"`
+	Hash       string        `json:"hash"`
+	Signature  hexutil.Bytes `json:"signature"`
+	Expected   string        `json:"expected"`
+	Actual     string        `json:"actual,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Match      bool          `json:"match"`
+}
+
+// Wallets are the deployed wallets of one owner: Wallet owned by the
+// owner's key and Nested owned by Wallet.
+type Wallets struct {
+	Owner  string `json:"owner"`
+	Wallet string `json:"wallet"`
+	Nested string `json:"nested"`
+}
+
+type ERC1271Result struct {
+	Stage      string       `json:"stage"`
+	Wallets    Wallets      `json:"wallets"`
+	Cases      []WalletCase `json:"cases"`
+	Matches    int          `json:"matches"`
+	Mismatches int          `json:"mismatches"`
+	Timestamp  string       `json:"timestamp"`
+	RPCURL     string       `json:"rpcUrl"`
+}
+
+const walletsFile = "deployed_erc1271.json"
+
+func main() {
+	output.Setup()
+
+	ownerRole := flag.String("owner-role", string(chain.RoleInvoke), "role whose key owns the wallets and signs")
+	gasLimit := flag.Uint64("gas", 1_000_000, "gas limit of each wallet deployment")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Smoke}) {
+		fmt.Printf("⏭️  ERC-1271 validation skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_erc1271.json")
+
+	owner, err := chain.RoleSigner(ctx, chain.Role(*ownerRole))
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	wallets, err := resolveWallets(ctx, client, owner.Address(), *gasLimit)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("👛 Wallet %s owned by %s, nested wallet %s owned by it\n", wallets.Wallet, wallets.Owner, wallets.Nested)
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatalf("❌ Failed to get chain ID: %v", err)
+	}
+	cases, err := walletCases(ctx, owner, chainID, wallets)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	result := ERC1271Result{
+		Stage:   "ERC-1271 - Smart Wallet Signature Validation",
+		Wallets: wallets,
+		RPCURL:  rpcURL,
+	}
+	for _, c := range cases {
+		runWalletCase(ctx, client, &c)
+		if c.Match {
+			result.Matches++
+		} else {
+			result.Mismatches++
+		}
+		result.Cases = append(result.Cases, c)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_erc1271.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 ERC-1271 results:")
+	for _, c := range result.Cases {
+		switch {
+		case c.Match:
+			fmt.Printf("✅ %s\n", c.Name)
+		case c.Error != "":
+			fmt.Printf("❌ %s: %s\n", c.Name, c.Error)
+		default:
+			fmt.Printf("❌ %s: returned %s, expected %s\n", c.Name, c.Actual, c.Expected)
+		}
+	}
+	fmt.Printf("✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Println("\n📝 Results saved to results_erc1271.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// walletCases signs a plain hash and an EIP-712 digest bound to the wallet
+// with the owner's key. Both wallets must accept them and reject another
+// key's signature, a different hash and non-canonical encodings.
+func walletCases(ctx context.Context, owner signer.Signer, chainID *big.Int, w Wallets) ([]WalletCase, error) {
+	wallet, nested := common.HexToAddress(w.Wallet), common.HexToAddress(w.Nested)
+	hash := crypto.Keccak256Hash([]byte("ERC-1271 precompile check"))
+	sig, err := typeddata.Sign(ctx, owner, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %v", err)
+	}
+
+	// Smart accounts usually validate EIP-712 digests bound to themselves
+	mail := typeddata.Mail{
+		From:     typeddata.Person{Name: "Owner", Wallet: owner.Address()},
+		To:       typeddata.Person{Name: "Wallet", Wallet: wallet},
+		Contents: "signed for a smart account",
+	}
+	_, digest, err := typeddata.Hash(typeddata.VerifierDomain(chainID, wallet), mail)
+	if err != nil {
+		return nil, err
+	}
+	typedSig, err := typeddata.Sign(ctx, owner, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign typed data: %v", err)
+	}
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	otherSig, err := typeddata.Sign(ctx, signer.NewLocal(key), hash)
+	if err != nil {
+		return nil, err
+	}
+	badV := sig
+	badV.V = 29
+
+	magic, invalid := hexutil.Encode(erc1271.MagicValue[:]), hexutil.Encode(erc1271.Invalid[:])
+	var cases []WalletCase
+	add := func(name string, wallet common.Address, hash common.Hash, signature []byte, expected string) {
+		cases = append(cases, WalletCase{Name: name, Precompile: precompile.ECRecoverAddress.Hex(),
+			Wallet: wallet.Hex(), Hash: hash.Hex(), Signature: signature, Expected: expected})
+	}
+	for _, target := range []struct {
+		label   string
+		address common.Address
+	}{{"wallet", wallet}, {"nested wallet", nested}} {
+		add(target.label+": owner's signature accepted", target.address, hash, erc1271.Encode(sig), magic)
+		add(target.label+": owner's EIP-712 signature accepted", target.address, digest, erc1271.Encode(typedSig), magic)
+		add(target.label+": another key rejected", target.address, hash, erc1271.Encode(otherSig), invalid)
+		add(target.label+": other hash rejected", target.address, digest, erc1271.Encode(sig), invalid)
+	}
+	add("wallet: high-s signature rejected", wallet, hash, erc1271.Encode(sig.Malleate()), invalid)
+	add("wallet: v=29 rejected", wallet, hash, erc1271.Encode(badV), invalid)
+	add("wallet: 64-byte signature rejected", wallet, hash, erc1271.Encode(sig)[:64], invalid)
+	add("wallet: empty signature rejected", wallet, hash, nil, invalid)
+	return cases, nil
+}
+
+func runWalletCase(ctx context.Context, client *ethclient.Client, c *WalletCase) {
+	got, err := erc1271.IsValidSignature(ctx, client, common.HexToAddress(c.Wallet), common.HexToHash(c.Hash), c.Signature)
+	if err != nil {
+		c.Error = err.Error()
+		return
+	}
+	c.Actual = hexutil.Encode(got[:])
+	c.Match = c.Actual == c.Expected
+}
+
+// resolveWallets reuses the wallets saved in deployed_erc1271.json when they
+// belong to owner and still have code, and deploys them otherwise.
+func resolveWallets(ctx context.Context, client *ethclient.Client, owner common.Address, gas uint64) (Wallets, error) {
+	var saved Wallets
+	if data, err := os.ReadFile(paths.Work(walletsFile)); err == nil && json.Unmarshal(data, &saved) == nil &&
+		common.HexToAddress(saved.Owner) == owner {
+		if hasCode(ctx, client, saved.Wallet) && hasCode(ctx, client, saved.Nested) {
+			return saved, nil
+		}
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Wallets{}, err
+	}
+
+	bytecode, err := paths.ReadHex(paths.Artifact("ERC1271Wallet.bin"))
+	if err != nil {
+		return Wallets{}, fmt.Errorf("failed to read bytecode (compile contracts/ERC1271Wallet.sol first): %v", err)
+	}
+	if err := deploy.VerifyArtifact(paths.Artifact("ERC1271Wallet")); err != nil {
+		return Wallets{}, fmt.Errorf("refusing to deploy: %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		return Wallets{}, fmt.Errorf("no ERC1271Wallet deployed and can't deploy one: %v", err)
+	}
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleDeploy)
+	if err != nil {
+		return Wallets{}, err
+	}
+	deployWallet := func(walletOwner common.Address) (common.Address, error) {
+		data, err := erc1271.DeployData(common.FromHex(bytecode), walletOwner)
+		if err != nil {
+			return common.Address{}, err
+		}
+		fmt.Printf("📨 Deploying ERC1271Wallet owned by %s from %s...\n", walletOwner.Hex(), sender.From.Hex())
+		_, receipt, err := sender.Send(chain.WithContract(ctx, "ERC1271Wallet"), nil, data, gas)
+		if err != nil {
+			return common.Address{}, fmt.Errorf("deployment failed: %v", err)
+		}
+		if receipt.Status != 1 {
+			return common.Address{}, fmt.Errorf("ERC1271Wallet deployment reverted in block %d", receipt.BlockNumber.Uint64())
+		}
+		return receipt.ContractAddress, nil
+	}
+	wallet, err := deployWallet(owner)
+	if err != nil {
+		return Wallets{}, err
+	}
+	nested, err := deployWallet(wallet)
+	if err != nil {
+		return Wallets{}, err
+	}
+	w := Wallets{Owner: owner.Hex(), Wallet: wallet.Hex(), Nested: nested.Hex()}
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return Wallets{}, err
+	}
+	if err := paths.WriteFile(paths.Work(walletsFile), data); err != nil {
+		return Wallets{}, fmt.Errorf("failed to save deployed wallets: %v", err)
+	}
+	return w, nil
+}
+
+func hasCode(ctx context.Context, client *ethclient.Client, address string) bool {
+	if !common.IsHexAddress(address) {
+		return false
+	}
+	code, err := client.CodeAt(ctx, common.HexToAddress(address), nil)
+	return err == nil && len(code) > 0
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/profile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// FeeEntry is the fee breakdown of one test transaction.
+type FeeEntry struct {
+	chain.FeeBreakdown
+	// Source is the results file the transaction was taken from.
+	Source string `json:"source,omitempty"`
+	// TotalFormatted is Total in the chain's native currency.
+	TotalFormatted string `json:"totalFormatted,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+type FeeBreakdownResult struct {
+	Stage        string     `json:"stage"`
+	Profile      string     `json:"profile"`
+	Transactions []FeeEntry `json:"transactions"`
+	// L2Fee, L1Fee and Total sum the breakdowns, in wei.
+	L2Fee     string `json:"l2Fee"`
+	L1Fee     string `json:"l1Fee"`
+	Total     string `json:"total"`
+	Timestamp string `json:"timestamp"`
+	RPCURL    string `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	txList := flag.String("tx", "", "comma-separated transaction hashes to break down instead of those in the stage results")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Gas}) {
+		fmt.Printf("⏭️  Fee breakdown skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	txs, err := feeTransactions(*txList)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if len(txs) == 0 {
+		fmt.Println("⏭️  No mined test transactions: run stage 2, stage 4 or broadcast.go first, or pass --tx")
+		if err := skip.Record(skip.New(skip.Prerequisite, "no mined test transactions")); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_fees.json")
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatalf("❌ Failed to get chain ID: %v", err)
+	}
+	chainProfile, err := profile.Resolve(os.Getenv("CHAIN_PROFILE"), chainID.Uint64())
+	if err != nil {
+		log.Printf("⚠️  %v, assuming ETH as the gas token", err)
+		chainProfile = profile.Detect(chainID.Uint64())
+	}
+
+	result := FeeBreakdownResult{
+		Stage:   "Fee Breakdown - L2 Execution and L1 Components of Test Transactions",
+		Profile: chainProfile.Name,
+		RPCURL:  rpcURL,
+	}
+	l2Total, l1Total, total := new(big.Int), new(big.Int), new(big.Int)
+	for _, tx := range txs {
+		entry := FeeEntry{Source: tx.source}
+		entry.FeeBreakdown, err = chain.BreakDownFee(ctx, client, tx.hash)
+		if err != nil {
+			entry.Error = err.Error()
+			fmt.Printf("❌ %s: %v\n", tx.hash.Hex(), err)
+			result.Transactions = append(result.Transactions, entry)
+			continue
+		}
+		addWei(l2Total, entry.L2Fee)
+		addWei(l1Total, entry.L1Fee)
+		if t, ok := addWei(total, entry.Total); ok {
+			entry.TotalFormatted = chainProfile.NativeCurrency.Format(t)
+		}
+		fmt.Printf("💸 %s (block %d): %s wei\n", tx.hash.Hex(), entry.Block, entry.FeeBreakdown)
+		for _, note := range entry.Notes {
+			fmt.Printf("   ⚠️  %s\n", note)
+		}
+		result.Transactions = append(result.Transactions, entry)
+	}
+	result.L2Fee, result.L1Fee, result.Total = l2Total.String(), l1Total.String(), total.String()
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_fees.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+	fmt.Printf("\n📊 %d transactions paid %s: %s wei L2, %s wei L1\n",
+		len(txs), chainProfile.NativeCurrency.Format(total), result.L2Fee, result.L1Fee)
+	fmt.Println("\n📝 Results saved to results_fees.json")
+}
+
+// addWei adds the decimal amount s to sum, returning the amount parsed.
+func addWei(sum *big.Int, s string) (*big.Int, bool) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if ok {
+		sum.Add(sum, v)
+	}
+	return v, ok
+}
+
+type feeTx struct {
+	hash   common.Hash
+	source string
+}
+
+// feeTransactions returns the hashes of --tx, or else the transactions the
+// stage 2, stage 4 and broadcast results mined.
+func feeTransactions(list string) ([]feeTx, error) {
+	var txs []feeTx
+	if list != "" {
+		for _, s := range tags.Parse(list) {
+			if len(common.FromHex(s)) != common.HashLength {
+				return nil, fmt.Errorf("invalid transaction hash %q in --tx", s)
+			}
+			txs = append(txs, feeTx{hash: common.HexToHash(s)})
+		}
+		return txs, nil
+	}
+
+	type mined struct {
+		TransactionHash string `json:"transactionHash"`
+		BlockNumber     uint64 `json:"blockNumber"`
+	}
+	read := func(name string, v any) error {
+		data, err := os.ReadFile(paths.Work(name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return nil
+	}
+	var deployment mined
+	var stage4 []mined
+	var broadcast struct {
+		Transactions []mined `json:"transactions"`
+	}
+	if err := read("results_stage2.json", &deployment); err != nil {
+		return nil, err
+	}
+	if err := read("results_stage4.json", &stage4); err != nil {
+		return nil, err
+	}
+	if err := read("results_broadcast.json", &broadcast); err != nil {
+		return nil, err
+	}
+	add := func(source string, ms ...mined) {
+		for _, m := range ms {
+			if m.TransactionHash != "" && m.BlockNumber > 0 {
+				txs = append(txs, feeTx{hash: common.HexToHash(m.TransactionHash), source: source})
+			}
+		}
+	}
+	add("results_stage2.json", deployment)
+	add("results_stage4.json", stage4...)
+	add("results_broadcast.json", broadcast.Transactions...)
+	return txs, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/registry"
+)
+
+func main() {
+	output.Setup()
+
+	index := flag.String("index", "", "registry index to install, local path or URL, optionally suffixed with #sha256=<hex>")
+	cacheDir := flag.String("cache-dir", "", "download cache (defaults to the user cache directory)")
+	allowUnpinned := flag.Bool("allow-unpinned", false, "install entries that have no sha256 checksum")
+	flag.Parse()
+
+	if *index == "" {
+		log.Fatal("❌ --index is required")
+	}
+
+	fetcher := registry.NewFetcher(*cacheDir)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	idx, err := fetcher.LoadIndex(ctx, registry.ParseSource(*index))
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("📦 Registry index lists %d entries (cache: %s)\n", len(idx.Entries), fetcher.CacheDir)
+
+	failed, artifacts := 0, 0
+	for _, e := range idx.Entries {
+		if !e.Pinned() && !*allowUnpinned {
+			fmt.Printf("❌ %s: no sha256 pin (use --allow-unpinned to install anyway)\n", e.Name)
+			failed++
+			continue
+		}
+
+		data, sum, err := fetcher.Fetch(ctx, e.Source)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", e.Name, err)
+			failed++
+			continue
+		}
+
+		if err := paths.WriteFile(e.Dest, data); err != nil {
+			fmt.Printf("❌ %s: %v\n", e.Name, err)
+			failed++
+			continue
+		}
+
+		pin := "pinned"
+		if !e.Pinned() {
+			pin = "⚠️  unpinned"
+		}
+		fmt.Printf("✅ %s → %s (%s, sha256 %s, %s)\n", e.Name, e.Dest, output.Bytes(len(data)), sum[:12], pin)
+		if strings.HasPrefix(filepath.ToSlash(e.Dest), "artifacts/") {
+			artifacts++
+		}
+	}
+	if artifacts > 0 {
+		fmt.Printf("🔒 %d artifacts installed; review them, then regenerate artifacts.lock with go run scripts/artifacts_lock.go\n", artifacts)
+	}
+
+	if failed > 0 {
+		log.Fatalf("❌ %d entries failed", failed)
+	}
+}
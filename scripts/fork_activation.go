@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"time"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/capability"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/forks"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/profile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+type ForkActivationResult struct {
+	Stage   string `json:"stage"`
+	Profile string `json:"profile"`
+	Head    uint64 `json:"head"`
+	// Forks are the configured fork blocks the observations are checked
+	// against.
+	Forks       forks.Blocks       `json:"forks,omitempty"`
+	Heights     []uint64           `json:"heights,omitempty"`
+	Activations []forks.Activation `json:"activations,omitempty"`
+	Agreed      int                `json:"agreed"`
+	Disagreed   int                `json:"disagreed"`
+	SkipReason  string             `json:"skipReason,omitempty"`
+	Skip        *skip.Reason       `json:"skip,omitempty"`
+	Timestamp   string             `json:"timestamp"`
+	RPCURL      string             `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	forkSpec := flag.String("forks", "", "comma-separated fork blocks, e.g. byzantium=4370000,istanbul=9069000 (default the chain profile's)")
+	window := flag.Uint64("window", 1, "blocks either side of each fork block to observe")
+	samples := flag.Int("samples", 8, "evenly spread heights to observe when no fork blocks are configured")
+	bisect := flag.Bool("bisect", true, "narrow each activation down to a single block")
+	only := flag.String("behaviors", "", "comma-separated behaviors to probe (default all)")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Archive}) {
+		fmt.Printf("⏭️  Fork activation probe skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_fork_activation.json")
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatalf("❌ Failed to get chain ID: %v", err)
+	}
+	chainProfile, err := profile.Resolve(os.Getenv("CHAIN_PROFILE"), chainID.Uint64())
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	blocks := forks.Blocks(chainProfile.Forks)
+	if *forkSpec != "" {
+		if blocks, err = forks.ParseBlocks(*forkSpec); err != nil {
+			log.Fatalf("❌ --forks: %v", err)
+		}
+	}
+
+	result := ForkActivationResult{
+		Stage:   "Fork Activation - Precompiles Across Block Heights",
+		Profile: chainProfile.Name,
+		Forks:   blocks,
+		RPCURL:  rpcURL,
+	}
+
+	caps, err := capability.Detect(ctx, client)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	result.Head = caps.Head
+	if !caps.Has(capability.HistoricalState) {
+		result.SkipReason = "no historical state: " + caps.Probes[capability.HistoricalState].Reason
+		result.Skip = skip.New(skip.Capability, result.SkipReason)
+		fmt.Printf("⏭️  Fork activation probe skipped (%s)\n", result.SkipReason)
+		if err := skip.Record(result.Skip); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		saveForkActivation(result)
+		anchors.Finish(ctx)
+		return
+	}
+
+	result.Heights = forks.Heights(blocks, caps.Head, *window, *samples)
+	fmt.Printf("🔎 Observing %d heights up to block %d\n", len(result.Heights), caps.Head)
+	selected := tags.Parse(*only)
+	for _, b := range forks.Behaviors() {
+		if len(selected) > 0 && !slices.Contains(selected, b.Name) {
+			continue
+		}
+		a := forks.Find(ctx, client, b, blocks, result.Heights, *bisect)
+		if ctx.Err() != nil {
+			log.Fatalf("❌ Interrupted: %v", ctx.Err())
+		}
+		if a.Agrees(caps.Head) {
+			result.Agreed++
+		} else {
+			result.Disagreed++
+		}
+		result.Activations = append(result.Activations, a)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	saveForkActivation(result)
+	anchors.Finish(ctx)
+
+	printActivations(result)
+	fmt.Println("\n📝 Results saved to results_fork_activation.json")
+	if result.Disagreed > 0 {
+		os.Exit(1)
+	}
+}
+
+func saveForkActivation(result ForkActivationResult) {
+	if result.Timestamp == "" {
+		result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_fork_activation.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+}
+
+// printActivations prints the fork-activation table: where each behavior's
+// fork is configured and where the behavior was observed to switch on.
+func printActivations(result ForkActivationResult) {
+	block := func(n *uint64) string {
+		switch {
+		case n == nil:
+			return "-"
+		case *n == 0:
+			return "genesis"
+		}
+		return strconv.FormatUint(*n, 10)
+	}
+	fmt.Printf("\n📊 %-20s %-10s %5s %12s %12s\n", "behavior", "fork", "EIP", "configured", "observed")
+	for _, a := range result.Activations {
+		eip := "-"
+		if a.EIP != 0 {
+			eip = strconv.Itoa(a.EIP)
+		}
+		observed := block(a.Block)
+		switch {
+		case a.Error != "":
+			observed = "error"
+		case a.Inconsistent:
+			observed = "inconsistent"
+		case a.Block == nil:
+			observed = "not active"
+		}
+		mark := "✅"
+		if !a.Agrees(result.Head) {
+			mark = "❌"
+		} else if a.ForkBlock == nil {
+			mark = "  "
+		}
+		fmt.Printf("%s %-20s %-10s %5s %12s %12s\n", mark, a.Behavior, a.Fork, eip, block(a.ForkBlock), observed)
+		if a.Error != "" {
+			fmt.Printf("   %s\n", a.Error)
+		}
+	}
+	fmt.Printf("\n✅ Agreed:    %d\n", result.Agreed)
+	fmt.Printf("❌ Disagreed: %d\n", result.Disagreed)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/profile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+)
+
+func main() {
+	output.Setup()
+
+	roles := flag.String("roles", "deploy,invoke", "comma-separated roles whose accounts to top up")
+	balance := flag.String("balance", "", "balance to top each account up to, in base units or suffixed with the native currency symbol (default 0.1 of the native currency)")
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+
+	funder, err := chain.NewRoleSender(ctx, client, chain.RoleFund)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("🏦 Funding from %s\n", funder.From.Hex())
+
+	currency, err := nativeCurrency(funder.ChainID.Uint64())
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *balance == "" {
+		*balance = "0.1" + currency.Symbol
+	}
+	target, err := currency.Parse(*balance)
+	if err != nil {
+		log.Fatalf("❌ --balance: %v", err)
+	}
+
+	// The invoke role falls back to the deployer account; fund it once
+	funded := map[common.Address]bool{}
+	failed := 0
+	for _, name := range strings.Split(*roles, ",") {
+		role := chain.Role(strings.TrimSpace(name))
+		if role != chain.RoleDeploy && role != chain.RoleInvoke {
+			log.Fatalf("❌ Unknown role %q (want deploy or invoke)", role)
+		}
+		address, err := chain.RoleAddress(ctx, role)
+		if err != nil {
+			log.Fatalf("❌ %s role: %v", role, err)
+		}
+		if funded[address] {
+			fmt.Printf("⏭️  %s role uses %s, already topped up\n", role, address.Hex())
+			continue
+		}
+		funded[address] = true
+
+		current, err := client.BalanceAt(ctx, address, nil)
+		if err != nil {
+			log.Fatalf("❌ Failed to get balance of %s: %v", address.Hex(), err)
+		}
+		if current.Cmp(target) >= 0 {
+			fmt.Printf("✅ %s role (%s) holds %s\n", role, address.Hex(), currency.Format(current))
+			continue
+		}
+		amount := new(big.Int).Sub(target, current)
+		fmt.Printf("📨 Sending %s to the %s role (%s)...\n", currency.Format(amount), role, address.Hex())
+		tx, receipt, err := funder.Transfer(ctx, address, amount)
+		if err != nil {
+			fmt.Printf("❌ %s role: %v\n", role, err)
+			failed++
+			continue
+		}
+		if receipt.Status != 1 {
+			fmt.Printf("❌ %s role: transfer %s reverted\n", role, tx.Hash().Hex())
+			failed++
+			continue
+		}
+		fmt.Printf("✅ %s role topped up to %s in block %d\n", role, currency.Format(target), receipt.BlockNumber.Uint64())
+	}
+
+	fmt.Printf("\n💸 Funding account spent %s this run\n", currency.Format(chain.Spent(chain.RoleFund)))
+	if failed > 0 {
+		log.Fatalf("❌ %d top-ups failed", failed)
+	}
+}
+
+// nativeCurrency is the currency of the chain profile (CHAIN_PROFILE or
+// detected from the chain ID), which must describe the node's chain.
+func nativeCurrency(chainID uint64) (profile.Currency, error) {
+	chainProfile, err := profile.Resolve(os.Getenv("CHAIN_PROFILE"), chainID)
+	if err != nil {
+		return profile.Currency{}, err
+	}
+	if err := chainProfile.CheckChain(chainID); err != nil {
+		return profile.Currency{}, err
+	}
+	return chainProfile.NativeCurrency, nil
+}
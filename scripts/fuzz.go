@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/bench"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/gascap"
+	"cdk-erigon-precompile/pkg/hashref"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/stream"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vcache"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// FuzzCase is the outcome of one random input, streamed as NDJSON when
+// --stream is set.
+type FuzzCase struct {
+	Seq        int    `json:"seq"`
+	Precompile string `json:"precompile"`
+	vector.Vector
+	InputLength  int     `json:"inputLength"`
+	ExpectedHash string  `json:"expectedHash"`
+	ReturnedHash string  `json:"returnedHash"`
+	Match        bool    `json:"match"`
+	LatencyMs    float64 `json:"latencyMs"`
+	// Gas is the call's eth_estimateGas, with --estimate-gas.
+	Gas       uint64 `json:"gas,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// LengthAggregate counts the cases of one input length bucket. Latency and
+// Gas only cover the cases that were sent and answered.
+type LengthAggregate struct {
+	bench.LengthBucket
+	Cases      int            `json:"cases"`
+	Matches    int            `json:"matches"`
+	Mismatches int            `json:"mismatches"`
+	Errors     int            `json:"errors"`
+	Cached     int            `json:"cached"`
+	Skipped    int            `json:"skipped"`
+	Latency    bench.Running  `json:"latencyMs"`
+	Gas        *bench.Running `json:"gas,omitempty"`
+}
+
+type FuzzSummary struct {
+	Stage      string `json:"stage"`
+	Precompile string `json:"precompile"`
+	Seed       int64  `json:"seed"`
+	Cases      int    `json:"cases"`
+	MaxLength  int    `json:"maxLength"`
+	Matches    int    `json:"matches"`
+	Mismatches int    `json:"mismatches"`
+	Errors     int    `json:"errors"`
+	// Cached counts matches skipped because the same node build already
+	// answered them correctly; they are included in Matches.
+	Cached int `json:"cached"`
+	// Skipped counts inputs larger than the node accepts in an eth_call,
+	// which are not sent; SkipReason explains the first.
+	Skipped    int    `json:"skipped,omitempty"`
+	SkipReason string `json:"skipReason,omitempty"`
+	// HashImpl and ReferenceMs show which local hasher computed the
+	// expected hashes and how long it spent doing so.
+	HashImpl    string  `json:"hashImpl"`
+	ReferenceMs float64 `json:"referenceMs"`
+	// Sparse is set when only failing cases were streamed.
+	Sparse bool `json:"sparse,omitempty"`
+	// Latency and Gas aggregate every answered case, ByLength the cases
+	// of each input length bucket.
+	Latency  bench.Running     `json:"latencyMs"`
+	Gas      *bench.Running    `json:"gas,omitempty"`
+	ByLength []LengthAggregate `json:"byLength"`
+	Failures []FuzzCase        `json:"failures,omitempty"`
+	// FailuresDropped counts failing cases beyond --max-failures, counted
+	// but not kept in Failures.
+	FailuresDropped int    `json:"failuresDropped,omitempty"`
+	Timestamp       string `json:"timestamp"`
+	RPCURL          string `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	cases := flag.Int("cases", 1000, "number of random inputs to send")
+	maxLen := flag.Int("max-len", 1024, "maximum input length in bytes")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed (printed so failing runs can be reproduced)")
+	streamPath := flag.String("stream", "", "stream per-case results as NDJSON to this file (- for stdout)")
+	hashImpl := flag.String("hash-impl", hashref.Stdlib, "local SHA-256 implementation for expected hashes: "+strings.Join(hashref.Names(), ", "))
+	hashWorkers := flag.Int("hash-workers", 0, "goroutines computing expected hashes (0 uses every CPU)")
+	batchSize := flag.Int("batch", 4096, "inputs generated and hashed locally at a time")
+	sparse := flag.Bool("sparse", false, "for huge corpora: stream only failing cases and keep at most --max-failures of them in the results, with aggregates by input length")
+	maxFailures := flag.Int("max-failures", 0, "failing cases kept in results_fuzz.json, the rest only counted (0 keeps all, or 1000 with --sparse)")
+	estimateGas := flag.Bool("estimate-gas", false, "also estimate each call's gas, aggregated by input length")
+	gasCapFile := flag.String("gas-cap-file", paths.Work(gascap.DefaultPath), "gas cap report of scripts/gas_cap.go; inputs beyond the cap are skipped")
+	cacheOpts := vcache.Flags()
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Fuzz, tags.Slow}) {
+		fmt.Printf("⏭️  Fuzz skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	if *batchSize <= 0 {
+		log.Fatal("❌ --batch must be positive")
+	}
+	if *maxFailures == 0 && *sparse {
+		*maxFailures = 1000
+	}
+	reference, err := hashref.Lookup(*hashImpl)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_fuzz.json")
+
+	cache, err := cacheOpts.Open(ctx, client)
+	if err != nil {
+		log.Printf("⚠️  Vector cache disabled: %v", err)
+	}
+	if cache != nil {
+		loaded, _, _ := cache.Stats()
+		fmt.Printf("🗃️  Vector cache: %d inputs already verified against %s\n", loaded, cache.Build().Key())
+	}
+
+	gasCap, err := gascap.Load(*gasCapFile, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	var casesOut *stream.Writer
+	if *streamPath != "" {
+		casesOut, err = stream.Open(*streamPath)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		defer casesOut.Close()
+	}
+
+	summary := FuzzSummary{
+		Stage:      "Fuzz - Random SHA256 Inputs",
+		Precompile: "0x02",
+		Seed:       *seed,
+		Cases:      *cases,
+		MaxLength:  *maxLen,
+		HashImpl:   *hashImpl,
+		Sparse:     *sparse,
+		RPCURL:     rpcURL,
+	}
+	if *estimateGas {
+		summary.Gas = &bench.Running{}
+	}
+	byLength := map[bench.LengthBucket]*LengthAggregate{}
+	bucket := func(input []byte) *LengthAggregate {
+		b := bench.BucketOf(len(input))
+		agg := byLength[b]
+		if agg == nil {
+			agg = &LengthAggregate{LengthBucket: b}
+			if *estimateGas {
+				agg.Gas = &bench.Running{}
+			}
+			byLength[b] = agg
+		}
+		agg.Cases++
+		return agg
+	}
+	fail := func(fc FuzzCase) {
+		if *maxFailures > 0 && len(summary.Failures) >= *maxFailures {
+			summary.FailuresDropped++
+			return
+		}
+		summary.Failures = append(summary.Failures, fc)
+	}
+
+	fmt.Printf("🎲 Fuzzing precompile %s with %d cases (seed %d)\n", summary.Precompile, *cases, *seed)
+	rng := rand.New(rand.NewSource(*seed))
+	precompile := common.HexToAddress(summary.Precompile)
+
+	// Inputs are drawn in the same order as one at a time, so a seed
+	// reproduces the same cases whatever the batch size
+run:
+	for first := 0; first < *cases; first += *batchSize {
+		inputs := make([][]byte, min(*batchSize, *cases-first))
+		for j := range inputs {
+			inputs[j] = make([]byte, rng.Intn(*maxLen+1))
+			rng.Read(inputs[j])
+		}
+		hashStart := time.Now()
+		expected, err := hashref.Digest(ctx, reference, inputs, *hashWorkers)
+		summary.ReferenceMs += float64(time.Since(hashStart)) / float64(time.Millisecond)
+		if err != nil {
+			summary.Cases = interrupted(first, *cases)
+			break
+		}
+
+		for j, input := range inputs {
+			i := first + j
+			if ctx.Err() != nil {
+				summary.Cases = interrupted(i, *cases)
+				break run
+			}
+			agg := bucket(input)
+			if reason := gasCap.SkipReason(gascap.SHA256.Name, input); reason != "" {
+				agg.Skipped++
+				summary.Skipped++
+				if summary.SkipReason == "" {
+					summary.SkipReason = reason
+				}
+				continue
+			}
+			if cache.Verified(precompile.Hex(), input) {
+				agg.Cached++
+				agg.Matches++
+				summary.Cached++
+				summary.Matches++
+				continue
+			}
+			fc := runCase(ctx, client, precompile, input, expected[j], *estimateGas)
+			fc.Seq = i
+			fc.Precompile = summary.Precompile
+
+			switch {
+			case fc.Error != "":
+				agg.Errors++
+				summary.Errors++
+				fail(fc)
+			case fc.Match:
+				agg.Matches++
+				summary.Matches++
+				cache.Record(precompile.Hex(), input)
+			default:
+				agg.Mismatches++
+				summary.Mismatches++
+				fail(fc)
+			}
+			if fc.Error == "" {
+				agg.Latency.Add(fc.LatencyMs)
+				summary.Latency.Add(fc.LatencyMs)
+				if fc.Gas > 0 {
+					agg.Gas.Add(float64(fc.Gas))
+					summary.Gas.Add(float64(fc.Gas))
+				}
+			}
+
+			if casesOut != nil && (!*sparse || fc.Error != "" || !fc.Match) {
+				if err := casesOut.Write(fc); err != nil {
+					log.Printf("⚠️  %v", err)
+				}
+			}
+		}
+	}
+	for _, agg := range byLength {
+		summary.ByLength = append(summary.ByLength, *agg)
+	}
+	sort.Slice(summary.ByLength, func(i, j int) bool { return summary.ByLength[i].MinLength < summary.ByLength[j].MinLength })
+	summary.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := cache.Save(); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	// Save results
+	file, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_fuzz.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Fuzz results:")
+	fmt.Printf("✅ Matches:    %d (%d cached)\n", summary.Matches, summary.Cached)
+	fmt.Printf("❌ Mismatches: %d\n", summary.Mismatches)
+	fmt.Printf("⚠️  Errors:     %d\n", summary.Errors)
+	if summary.Skipped > 0 {
+		fmt.Printf("⏭️  Skipped:    %d beyond the eth_call cap (%s)\n", summary.Skipped, summary.SkipReason)
+	}
+	if summary.FailuresDropped > 0 {
+		fmt.Printf("✂️  Kept %d failing cases, %d more only counted\n", len(summary.Failures), summary.FailuresDropped)
+	}
+	fmt.Printf("⏱️  Latency: mean %.2f ms, stddev %.2f ms, max %.2f ms\n", summary.Latency.Mean, summary.Latency.StdDev, summary.Latency.Max)
+	if summary.Gas != nil && summary.Gas.Count > 0 {
+		fmt.Printf("⛽ Gas: %.0f to %.0f, mean %.0f\n", summary.Gas.Min, summary.Gas.Max, summary.Gas.Mean)
+	}
+	fmt.Printf("#️⃣  Expected hashes: %.1f ms with %s\n", summary.ReferenceMs, summary.HashImpl)
+	fmt.Println("\n📝 Results saved to results_fuzz.json")
+
+	if summary.Mismatches > 0 || summary.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// interrupted reports how far the run got. What ran so far is kept rather
+// than counting every remaining case as an error.
+func interrupted(done, cases int) int {
+	log.Printf("⚠️  Interrupted after %d of %d cases", done, cases)
+	return done
+}
+
+func runCase(ctx context.Context, client *ethclient.Client, precompile common.Address, input []byte, expected [32]byte, estimateGas bool) FuzzCase {
+	fc := FuzzCase{
+		Vector:       vector.New(input),
+		InputLength:  len(input),
+		ExpectedHash: fmt.Sprintf("%x", expected),
+	}
+
+	msg := ethereum.CallMsg{To: &precompile, Data: input}
+	start := time.Now()
+	out, err := client.CallContract(ctx, msg, nil)
+	fc.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+	fc.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	if err != nil {
+		fc.Error = err.Error()
+		return fc
+	}
+
+	fc.ReturnedHash = fmt.Sprintf("%x", out)
+	fc.Match = fc.ReturnedHash == fc.ExpectedHash
+
+	if estimateGas {
+		var gas hexutil.Uint64
+		args := map[string]any{"to": precompile, "input": hexutil.Bytes(input)}
+		if err := client.Client().CallContext(ctx, &gas, "eth_estimateGas", args); err != nil {
+			fc.Error = fmt.Sprintf("gas estimate failed: %v", err)
+			return fc
+		}
+		fc.Gas = uint64(gas)
+	}
+	return fc
+}
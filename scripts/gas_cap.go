@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/gascap"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+func main() {
+	output.Setup()
+
+	targetList := flag.String("targets", "identity,sha256", "comma-separated precompiles to probe: identity, sha256")
+	start := flag.Int("start", 1024, "first input size in bytes, doubled until a call fails")
+	limit := flag.Int("limit", 8<<20, "largest input size to try in bytes")
+	resolution := flag.Int("resolution", 1024, "stop bisecting once the cap is bracketed this closely, in bytes")
+	out := flag.String("out", paths.Work(gascap.DefaultPath), "report read by the stages to skip vectors beyond the cap")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Gas}) {
+		fmt.Printf("⏭️  Gas cap probe skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	var targets []gascap.Target
+	for _, name := range tags.Parse(*targetList) {
+		t, ok := gascap.Targets[name]
+		if !ok {
+			log.Fatalf("❌ Unknown target %q (want identity or sha256)", name)
+		}
+		targets = append(targets, t)
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+
+	report := gascap.Report{RPCURL: rpcURL, Targets: map[string]gascap.Result{}}
+	for _, t := range targets {
+		fmt.Printf("📏 Probing %s (%s) from %s up to %s\n", t.Name, t.Address.Hex(), output.Bytes(*start), output.Bytes(*limit))
+		r, err := gascap.Probe(ctx, client, t, *start, *limit, *resolution)
+		if err != nil {
+			log.Fatalf("❌ Probe of %s stopped: %v", t.Name, err)
+		}
+		report.Targets[t.Name] = r
+		if r.Capped() {
+			fmt.Printf("🧱 %s: largest accepted input %s (%s gas), %s (%s gas) fails after %d calls\n",
+				t.Name, output.Bytes(r.MaxInput), output.Count(r.MaxGas), output.Bytes(r.FailedInput), output.Count(r.FailedGas), r.Calls)
+			fmt.Printf("   %s\n", r.Reason)
+		} else {
+			fmt.Printf("✅ %s: every input up to %s (%s gas) accepted\n", t.Name, output.Bytes(r.MaxInput), output.Count(r.MaxGas))
+		}
+	}
+	report.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := saveGasCapReport(*out, report); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\n📝 Results saved to %s\n", *out)
+}
+
+func saveGasCapReport(path string, report gascap.Report) error {
+	file, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(path, file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
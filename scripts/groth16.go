@@ -0,0 +1,308 @@
+//suite:writes transactions=1 gas=1500000
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/groth16"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// Groth16Case is one proof sent to the verifier contract. It passes when
+// the contract's verdict is the local verifier's.
+type Groth16Case struct {
+	Name       string        `json:"name"`
+	Precompile string        `json:"precompile"`
+	Calldata   hexutil.Bytes `json:"calldata"`
+	Expected   bool          `json:"expected"`
+	Actual     bool          `json:"actual"`
+	// Batched is the verdict of the same proof in the verifyBatch call.
+	Batched *bool  `json:"batched,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Match   bool   `json:"match"`
+}
+
+type Groth16Result struct {
+	Stage    string `json:"stage"`
+	Contract string `json:"contract"`
+	Circuit  string `json:"circuit"`
+	Seed     string `json:"seed"`
+	// VerifyGas is eth_estimateGas of verifying the first valid proof.
+	VerifyGas  uint64        `json:"verifyGas,omitempty"`
+	Cases      []Groth16Case `json:"cases"`
+	Matches    int           `json:"matches"`
+	Mismatches int           `json:"mismatches"`
+	Timestamp  string        `json:"timestamp"`
+	RPCURL     string        `json:"rpcUrl"`
+}
+
+// groth16Proof is a proof with the name of what it tests.
+type groth16Proof struct {
+	name     string
+	calldata groth16.Calldata
+}
+
+func main() {
+	output.Setup()
+
+	contractFlag := flag.String("contract", "", "Groth16Verifier address to use instead of the saved or a freshly deployed one")
+	gasLimit := flag.Uint64("gas", 1_500_000, "gas limit of the Groth16Verifier deployment")
+	writeVerifier := flag.String("write-verifier", "", "write the verifier contract of the toy setup to this path and exit")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	pk, vk := groth16.Toy()
+	if *writeVerifier != "" {
+		source, err := groth16.Solidity(vk, groth16.Cubic, groth16.ToySeed)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if err := paths.WriteFile(*writeVerifier, source); err != nil {
+			log.Fatalf("❌ Failed to write verifier: %v", err)
+		}
+		fmt.Printf("📝 Verifier written to %s\n", *writeVerifier)
+		return
+	}
+
+	if !tagFilter.Match([]string{tags.Smoke}) {
+		fmt.Printf("⏭️  Groth16 verification skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	proofs, err := groth16Proofs(pk)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_groth16.json")
+
+	contract, err := resolveGroth16Contract(ctx, client, *contractFlag, *gasLimit)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("📌 Using Groth16Verifier at %s\n", contract.Hex())
+
+	result := Groth16Result{
+		Stage:    "Groth16 - Proofs Verified On-chain with ecAdd, ecMul and ecPairing",
+		Contract: contract.Hex(),
+		Circuit:  groth16.Cubic.Name,
+		Seed:     groth16.ToySeed,
+		RPCURL:   rpcURL,
+	}
+	fmt.Printf("🔐 Verifying %d proofs one by one and in a batch\n", len(proofs))
+	result.Cases = groth16Cases(ctx, client, contract, vk, proofs)
+	for _, c := range result.Cases {
+		if c.Match {
+			result.Matches++
+		} else {
+			result.Mismatches++
+		}
+	}
+	if data, err := groth16.PackVerify(proofs[0].calldata); err == nil {
+		gas, err := client.EstimateGas(ctx, ethereum.CallMsg{To: &contract, Data: data})
+		if err != nil {
+			log.Printf("⚠️  Verification gas not estimated: %v", err)
+		}
+		result.VerifyGas = gas
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_groth16.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Groth16 results:")
+	for _, c := range result.Cases {
+		switch {
+		case c.Match:
+			fmt.Printf("✅ %s: %t\n", c.Name, c.Actual)
+		case c.Error != "":
+			fmt.Printf("❌ %s: %s\n", c.Name, c.Error)
+		case c.Batched != nil && *c.Batched != c.Expected:
+			fmt.Printf("❌ %s: %t in the batch, expected %t\n", c.Name, *c.Batched, c.Expected)
+		default:
+			fmt.Printf("❌ %s: %t, expected %t\n", c.Name, c.Actual, c.Expected)
+		}
+	}
+	if result.VerifyGas > 0 {
+		fmt.Printf("⛽ Verifying one proof: %s gas\n", output.Count(result.VerifyGas))
+	}
+	fmt.Printf("✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Println("\n📝 Results saved to results_groth16.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// groth16Proofs proves two statements of the toy circuit, and tampers with
+// the first proof the ways a verifier must catch: a wrong public input, the
+// input plus the group order (the same scalar to ecMul), another
+// statement's proof, a C that is a valid point but the wrong one, an A off
+// the curve and a B with the halves of its coordinates swapped, as a prover
+// writing G2 points in the wrong order would send.
+func groth16Proofs(pk *groth16.ProvingKey) ([]groth16Proof, error) {
+	var valid []groth16.Calldata
+	for _, x := range []int64{3, 7} {
+		w := groth16.CubicWitness(big.NewInt(x))
+		proof, err := groth16.Prove(pk, groth16.Cubic, w, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prove x=%d: %v", x, err)
+		}
+		valid = append(valid, proof.Calldata(groth16.Cubic.PublicInputs(w)))
+	}
+	out := []groth16Proof{
+		{fmt.Sprintf("valid proof (out=%s)", valid[0].Input[0]), valid[0]},
+		{fmt.Sprintf("valid proof (out=%s)", valid[1].Input[0]), valid[1]},
+	}
+	tamper := func(name string, f func(d *groth16.Calldata)) {
+		d := valid[0].Copy()
+		f(&d)
+		out = append(out, groth16Proof{name, d})
+	}
+	tamper("wrong public input", func(d *groth16.Calldata) { d.Input[0].Add(d.Input[0], big.NewInt(1)) })
+	tamper("public input plus the group order", func(d *groth16.Calldata) { d.Input[0].Add(d.Input[0], groth16.Order) })
+	tamper("another statement's proof", func(d *groth16.Calldata) { d.Input = valid[1].Copy().Input })
+	tamper("C replaced by the generator", func(d *groth16.Calldata) { d.C = [2]*big.Int{big.NewInt(1), big.NewInt(2)} })
+	tamper("A off the curve", func(d *groth16.Calldata) { d.A[1].Add(d.A[1], big.NewInt(1)) })
+	tamper("B with swapped coordinate halves", func(d *groth16.Calldata) {
+		d.B[0][0], d.B[0][1] = d.B[0][1], d.B[0][0]
+		d.B[1][0], d.B[1][1] = d.B[1][1], d.B[1][0]
+	})
+	return out, nil
+}
+
+// groth16Cases sends every proof to verifyProof and all of them together
+// to verifyBatch, expecting the local verifier's verdict from both.
+func groth16Cases(ctx context.Context, client *ethclient.Client, contract common.Address, vk *groth16.VerifyingKey, proofs []groth16Proof) []Groth16Case {
+	pairing := precompile.PairingAddress.Hex()
+	batch := make([]groth16.Calldata, len(proofs))
+	for i, p := range proofs {
+		batch[i] = p.calldata
+	}
+	verdicts, batchErr := groth16.CallBatch(ctx, client, contract, batch)
+
+	var out []Groth16Case
+	for i, p := range proofs {
+		c := Groth16Case{Name: p.name, Precompile: pairing}
+		c.Calldata, _ = groth16.PackVerify(p.calldata)
+		if err := groth16.Verify(vk, p.calldata); err != nil {
+			c.Reason = err.Error()
+		} else {
+			c.Expected = true
+		}
+		actual, err := groth16.Call(ctx, client, contract, p.calldata)
+		switch {
+		case err != nil:
+			c.Error = err.Error()
+		case batchErr != nil:
+			c.Actual = actual
+			c.Error = "verifyBatch: " + batchErr.Error()
+		default:
+			c.Actual, c.Batched = actual, &verdicts[i]
+			c.Match = actual == c.Expected && verdicts[i] == c.Expected
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// resolveGroth16Contract picks the Groth16Verifier to call: the --contract
+// address, the one saved in deployed_groth16_address.txt, or a fresh
+// deployment of artifacts/Groth16Verifier, in that order.
+func resolveGroth16Contract(ctx context.Context, client *ethclient.Client, override string, gas uint64) (common.Address, error) {
+	if override != "" {
+		if !common.IsHexAddress(override) {
+			return common.Address{}, fmt.Errorf("invalid --contract address %q", override)
+		}
+		address := common.HexToAddress(override)
+		if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+			return common.Address{}, err
+		}
+		return address, nil
+	}
+	if address, err := paths.ReadAddress(paths.Work("deployed_groth16_address.txt")); err == nil {
+		if code, err := client.CodeAt(ctx, address, nil); err == nil && len(code) > 0 {
+			return address, nil
+		}
+	}
+
+	bytecode, err := paths.ReadHex(paths.Artifact("Groth16Verifier.bin"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read bytecode (compile contracts/Groth16Verifier.sol first): %v", err)
+	}
+	if err := deploy.VerifyArtifact(paths.Artifact("Groth16Verifier")); err != nil {
+		return common.Address{}, fmt.Errorf("refusing to deploy: %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		return common.Address{}, fmt.Errorf("no Groth16Verifier deployed and can't deploy one (pass --contract): %v", err)
+	}
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleDeploy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	fmt.Printf("📨 Deploying Groth16Verifier from %s...\n", sender.From.Hex())
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "Groth16Verifier"), nil, common.FromHex(bytecode), gas)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return common.Address{}, fmt.Errorf("Groth16Verifier deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	if err := paths.WriteFile(paths.Work("deployed_groth16_address.txt"), []byte(receipt.ContractAddress.Hex())); err != nil {
+		return common.Address{}, fmt.Errorf("failed to save deployed address: %v", err)
+	}
+	return receipt.ContractAddress, nil
+}
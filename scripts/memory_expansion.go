@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/memexp"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// MemExpOutcome is how one side ran a case.
+type MemExpOutcome struct {
+	Failed     bool          `json:"failed"`
+	Error      string        `json:"error,omitempty"`
+	Success    bool          `json:"success"`
+	GasUsed    uint64        `json:"gasUsed"`
+	ReturnSize uint64        `json:"returnSize"`
+	Output     hexutil.Bytes `json:"output,omitempty"`
+}
+
+type MemExpCase struct {
+	Name       string        `json:"name"`
+	Precompile string        `json:"precompile"`
+	InOffset   uint64        `json:"inOffset"`
+	OutOffset  uint64        `json:"outOffset"`
+	OutSize    uint64        `json:"outSize"`
+	Node       MemExpOutcome `json:"node"`
+	Reference  MemExpOutcome `json:"reference"`
+	Match      bool          `json:"match"`
+}
+
+type MemExpResult struct {
+	Stage      string       `json:"stage"`
+	Gas        uint64       `json:"gas"`
+	Cases      []MemExpCase `json:"cases"`
+	Matches    int          `json:"matches"`
+	Mismatches int          `json:"mismatches"`
+	Timestamp  string       `json:"timestamp"`
+	RPCURL     string       `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	gas := flag.Uint64("gas", 30_000_000, "gas of each eth_call; keep it within the node's RPC gas cap")
+	inputFlag := flag.String("input", "precompile memory expansion boundary!!!!", "input handed to the precompiles")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Gas}) {
+		fmt.Printf("⏭️  Memory expansion cases skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_memexp.json")
+
+	result := MemExpResult{
+		Stage:  "Memory Expansion - Precompile Buffers at Extreme Offsets",
+		Gas:    *gas,
+		RPCURL: rpcURL,
+	}
+	cases := memexp.Cases([]byte(*inputFlag))
+	fmt.Printf("📐 Running %d buffer placements against go-ethereum's EVM\n", len(cases))
+	for _, c := range cases {
+		node, err := memexp.Call(ctx, client, c, *gas)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		ref, err := memexp.Reference(c, *gas)
+		if err != nil {
+			log.Fatalf("❌ Reference run of %s failed: %v", c.Name, err)
+		}
+		mc := MemExpCase{
+			Name:       c.Name,
+			Precompile: c.Precompile.Hex(),
+			InOffset:   c.InOffset,
+			OutOffset:  c.OutOffset,
+			OutSize:    c.OutSize,
+			Node:       memExpOutcome(node),
+			Reference:  memExpOutcome(ref),
+			Match:      node.Equal(ref),
+		}
+		if mc.Match {
+			result.Matches++
+			fmt.Printf("✅ %-36s %s\n", c.Name, describeOutcome(node))
+		} else {
+			result.Mismatches++
+			fmt.Printf("❌ %-36s node %s, reference %s\n", c.Name, describeOutcome(node), describeOutcome(ref))
+		}
+		result.Cases = append(result.Cases, mc)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := saveMemExpResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Printf("\n✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Println("\n📝 Results saved to results_memexp.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+func memExpOutcome(o memexp.Outcome) MemExpOutcome {
+	return MemExpOutcome{Failed: o.Failed, Error: o.Error, Success: o.Success, GasUsed: o.GasUsed, ReturnSize: o.ReturnSize, Output: o.Output}
+}
+
+func describeOutcome(o memexp.Outcome) string {
+	switch {
+	case o.Failed:
+		return "failed (" + o.Error + ")"
+	case !o.Success:
+		return fmt.Sprintf("call failed, gas %d", o.GasUsed)
+	default:
+		return fmt.Sprintf("gas %d", o.GasUsed)
+	}
+}
+
+func saveMemExpResult(result MemExpResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_memexp.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+//suite:writes transactions=1 gas=1000000
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/merkle"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// MerkleCase is one root built or one branch checked by the contract,
+// compared with the local tree.
+type MerkleCase struct {
+	Name       string `json:"name"`
+	Precompile string `json:"precompile"`
+	Leaves     int    `json:"leaves"`
+	// Hashes is how many sha256 calls the contract makes.
+	Hashes   int    `json:"hashes"`
+	Expected string `json:"expected"`
+	Returned string `json:"returned,omitempty"`
+	// Gas is eth_estimateGas of building the root in a transaction.
+	Gas   uint64 `json:"gas,omitempty"`
+	Match bool   `json:"match"`
+	Error string `json:"error,omitempty"`
+}
+
+type MerkleResult struct {
+	Stage      string       `json:"stage"`
+	Contract   string       `json:"contract"`
+	Cases      []MerkleCase `json:"cases"`
+	Matches    int          `json:"matches"`
+	Mismatches int          `json:"mismatches"`
+	Errors     int          `json:"errors"`
+	Timestamp  string       `json:"timestamp"`
+	RPCURL     string       `json:"rpcUrl"`
+}
+
+// smokeLeaves is the largest tree tagged smoke; larger ones are tagged
+// gas.
+const smokeLeaves = 16
+
+func main() {
+	output.Setup()
+
+	contractFlag := flag.String("contract", "", "Sha256Merkle address to use instead of the saved or a freshly deployed one")
+	gasLimit := flag.Uint64("gas", 1_000_000, "gas limit of the Sha256Merkle deployment")
+	leafList := flag.String("leaves", "1,2,3,5,8,13,16,33,64,128", "comma-separated leaf counts of the trees to build")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	var counts []int
+	for _, s := range tags.Parse(*leafList) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			log.Fatalf("❌ invalid --leaves count %q", s)
+		}
+		tag := tags.Smoke
+		if n > smokeLeaves {
+			tag = tags.Gas
+		}
+		if tagFilter.Match([]string{tag}) {
+			counts = append(counts, n)
+		}
+	}
+	if len(counts) == 0 {
+		fmt.Printf("⏭️  Merkle trees skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_merkle.json")
+
+	address, err := resolveMerkleContract(ctx, client, *contractFlag, *gasLimit)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("📌 Using Sha256Merkle at %s\n", address.Hex())
+	contract := merkle.Contract{Client: client, Address: address}
+
+	result := MerkleResult{
+		Stage:    "Merkle - SHA-256 Trees Built On-chain and Off-chain",
+		Contract: address.Hex(),
+		RPCURL:   rpcURL,
+	}
+	fmt.Printf("🌳 Building %d trees of up to %s leaves\n", len(counts), output.Count(slices.Max(counts)))
+	for _, n := range counts {
+		for _, c := range merkleCases(ctx, contract, n) {
+			switch {
+			case c.Error != "":
+				result.Errors++
+			case c.Match:
+				result.Matches++
+			default:
+				result.Mismatches++
+			}
+			result.Cases = append(result.Cases, c)
+		}
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_merkle.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Merkle results:")
+	for _, c := range result.Cases {
+		switch {
+		case c.Error != "":
+			fmt.Printf("⚠️  %s: %s\n", c.Name, c.Error)
+		case !c.Match:
+			fmt.Printf("❌ %s\n  Returned: %s\n  Expected: %s\n", c.Name, c.Returned, c.Expected)
+		case c.Gas > 0:
+			fmt.Printf("✅ %s: %s sha256 calls, %s gas\n", c.Name, output.Count(c.Hashes), output.Count(c.Gas))
+		default:
+			fmt.Printf("✅ %s\n", c.Name)
+		}
+	}
+	fmt.Printf("✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Printf("⚠️  Errors:     %d\n", result.Errors)
+	fmt.Println("\n📝 Results saved to results_merkle.json")
+	if result.Mismatches > 0 || result.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// merkleLeaves are n leaves, the same on every run so results can be
+// compared: the sha256 of each index.
+func merkleLeaves(n int) []common.Hash {
+	leaves := make([]common.Hash, n)
+	for i := range leaves {
+		leaves[i] = sha256.Sum256(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+	return leaves
+}
+
+// merkleCases has the contract build the root of n leaves, then check the
+// branch of the last leaf, whose siblings include the zero padding of a
+// tree that isn't full, and the same branch with its first sibling
+// tampered with.
+func merkleCases(ctx context.Context, contract merkle.Contract, n int) []MerkleCase {
+	sha := precompile.SHA256Address.Hex()
+	leaves := merkleLeaves(n)
+	root := merkle.Root(leaves)
+
+	rootCase := MerkleCase{
+		Name:       fmt.Sprintf("root of %d leaves", n),
+		Precompile: sha,
+		Leaves:     n,
+		Hashes:     merkle.Hashes(n),
+		Expected:   root.Hex(),
+	}
+	if got, err := contract.Root(ctx, leaves); err != nil {
+		rootCase.Error = err.Error()
+	} else {
+		rootCase.Returned, rootCase.Match = got.Hex(), got == root
+	}
+	if gas, err := contract.EstimateRoot(ctx, leaves); err == nil {
+		rootCase.Gas = gas
+	}
+	out := []MerkleCase{rootCase}
+
+	index := n - 1
+	branch, err := merkle.Branch(leaves, index)
+	if err != nil || len(branch) == 0 {
+		return out
+	}
+	verify := func(name string, branch []common.Hash) MerkleCase {
+		expected := merkle.Verify(leaves[index], branch, uint64(index), root)
+		c := MerkleCase{
+			Name:       name,
+			Precompile: sha,
+			Leaves:     n,
+			Hashes:     len(branch),
+			Expected:   strconv.FormatBool(expected),
+		}
+		got, err := contract.Verify(ctx, leaves[index], branch, uint64(index), root)
+		if err != nil {
+			c.Error = err.Error()
+		} else {
+			c.Returned, c.Match = strconv.FormatBool(got), got == expected
+		}
+		return c
+	}
+	out = append(out, verify(fmt.Sprintf("branch of leaf %d of %d", index, n), branch))
+	tampered := append([]common.Hash(nil), branch...)
+	tampered[0][31] ^= 1
+	out = append(out, verify(fmt.Sprintf("tampered branch of leaf %d of %d", index, n), tampered))
+	return out
+}
+
+// resolveMerkleContract picks the Sha256Merkle to call: the --contract
+// address, the one saved in deployed_merkle_address.txt, or a fresh
+// deployment of artifacts/Sha256Merkle, in that order.
+func resolveMerkleContract(ctx context.Context, client *ethclient.Client, override string, gas uint64) (common.Address, error) {
+	if override != "" {
+		if !common.IsHexAddress(override) {
+			return common.Address{}, fmt.Errorf("invalid --contract address %q", override)
+		}
+		address := common.HexToAddress(override)
+		if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+			return common.Address{}, err
+		}
+		return address, nil
+	}
+	if address, err := paths.ReadAddress(paths.Work("deployed_merkle_address.txt")); err == nil {
+		if code, err := client.CodeAt(ctx, address, nil); err == nil && len(code) > 0 {
+			return address, nil
+		}
+	}
+
+	bytecode, err := paths.ReadHex(paths.Artifact("Sha256Merkle.bin"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read bytecode (compile contracts/Sha256Merkle.sol first): %v", err)
+	}
+	if err := deploy.VerifyArtifact(paths.Artifact("Sha256Merkle")); err != nil {
+		return common.Address{}, fmt.Errorf("refusing to deploy: %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		return common.Address{}, fmt.Errorf("no Sha256Merkle deployed and can't deploy one (pass --contract): %v", err)
+	}
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleDeploy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	fmt.Printf("📨 Deploying Sha256Merkle from %s...\n", sender.From.Hex())
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "Sha256Merkle"), nil, common.FromHex(bytecode), gas)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return common.Address{}, fmt.Errorf("Sha256Merkle deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	if err := paths.WriteFile(paths.Work("deployed_merkle_address.txt"), []byte(receipt.ContractAddress.Hex())); err != nil {
+		return common.Address{}, fmt.Errorf("failed to save deployed address: %v", err)
+	}
+	return receipt.ContractAddress, nil
+}
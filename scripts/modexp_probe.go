@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/bench"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+type ModExpResult struct {
+	Stage      string  `json:"stage"`
+	Precompile string  `json:"precompile"`
+	Runs       int     `json:"runs"`
+	MaxRatio   float64 `json:"maxRatio"`
+	// OverheadMs is the median round trip of a trivial call, subtracted
+	// from every probe to estimate the time spent in the node.
+	OverheadMs float64       `json:"overheadMs"`
+	Probes     []ProbeResult `json:"probes"`
+	Matches    int           `json:"matches"`
+	Mismatches int           `json:"mismatches"`
+	Slow       int           `json:"slow"`
+	Timestamp  string        `json:"timestamp"`
+	RPCURL     string        `json:"rpcUrl"`
+}
+
+// ProbeResult is the timing and correctness of one probe.
+type ProbeResult struct {
+	Name        string  `json:"name"`
+	Bits        int     `json:"bits"`
+	InputBytes  int     `json:"inputBytes"`
+	ExpectedGas uint64  `json:"expectedGas"`
+	ChargedGas  *uint64 `json:"chargedGas,omitempty"`
+	Match       bool    `json:"match"`
+	MedianMs    float64 `json:"medianMs"`
+	NodeMs      float64 `json:"nodeMs"`
+	NsPerGas    float64 `json:"nsPerGas"`
+	// Ratio is NsPerGas relative to the reference probe; a probe above
+	// the threshold costs the node more time than its gas pays for.
+	Ratio     float64 `json:"ratio"`
+	SlowProbe bool    `json:"slowProbe"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func main() {
+	output.Setup()
+
+	sizesFlag := flag.String("sizes", "1024,2048,4096", "comma-separated operand sizes in bits")
+	runs := flag.Int("runs", 5, "timed calls per probe; the median is reported")
+	maxRatio := flag.Float64("max-ratio", 4, "fail probes whose time per gas exceeds the reference's by this factor")
+	checkGas := flag.Bool("check-gas", true, "compare the gas the node charges (via eth_estimateGas) with EIP-2565 pricing")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Slow}) {
+		fmt.Printf("⏭️  modexp probes skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	if *runs <= 0 {
+		log.Fatal("❌ --runs must be positive")
+	}
+	sizes, err := parseSizes(*sizesFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_modexp.json")
+
+	// The round trip of an empty identity call approximates RPC overhead
+	identity := common.HexToAddress("0x04")
+	overhead, err := medianLatency(*runs, func() error {
+		_, err := client.CallContract(ctx, ethereum.CallMsg{To: &identity}, nil)
+		return err
+	})
+	if err != nil {
+		log.Fatalf("❌ Overhead call failed: %v", err)
+	}
+	fmt.Printf("⏱️  RPC overhead: %.3f ms\n", overhead)
+
+	result := ModExpResult{
+		Stage:      "ModExpProbes",
+		Precompile: "0x05",
+		Runs:       *runs,
+		MaxRatio:   *maxRatio,
+		OverheadMs: overhead,
+		RPCURL:     rpcURL,
+	}
+
+	var referenceNsPerGas float64
+	for i, probe := range precompile.ModExpProbes(sizes) {
+		pr := runProbe(ctx, client, probe, *runs, overhead, *checkGas)
+		// The first probe is the reference the others are measured against
+		if i == 0 {
+			referenceNsPerGas = pr.NsPerGas
+		}
+		if referenceNsPerGas > 0 && pr.Error == "" {
+			pr.Ratio = pr.NsPerGas / referenceNsPerGas
+			pr.SlowProbe = i > 0 && pr.Ratio > *maxRatio
+		}
+		printProbe(pr)
+
+		if pr.Match {
+			result.Matches++
+		} else {
+			result.Mismatches++
+		}
+		if pr.SlowProbe {
+			result.Slow++
+		}
+		result.Probes = append(result.Probes, pr)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	fmt.Printf("\n📊 %d probes: %d correct, %d wrong, %d slower than their gas\n",
+		len(result.Probes), result.Matches, result.Mismatches, result.Slow)
+
+	if err := saveModExpResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Println("📝 Results saved to results_modexp.json")
+
+	if result.Mismatches > 0 || result.Slow > 0 {
+		log.Fatalf("❌ %d probes returned a wrong result, %d exceeded %.1fx the reference time per gas",
+			result.Mismatches, result.Slow, *maxRatio)
+	}
+}
+
+// runProbe checks the probe's result once and then times it.
+func runProbe(ctx context.Context, client *ethclient.Client, probe precompile.ModExpProbe, runs int, overhead float64, checkGas bool) ProbeResult {
+	pr := ProbeResult{
+		Name:        probe.Name,
+		Bits:        probe.Bits,
+		InputBytes:  len(probe.Input()),
+		ExpectedGas: probe.Gas(),
+	}
+
+	got, err := precompile.CallModExp(ctx, client, probe.ModExp)
+	if err != nil {
+		pr.Error = err.Error()
+		return pr
+	}
+	pr.Match = bytes.Equal(got, probe.Expected())
+
+	median, err := medianLatency(runs, func() error {
+		_, err := precompile.CallModExp(ctx, client, probe.ModExp)
+		return err
+	})
+	if err != nil {
+		pr.Error = err.Error()
+		return pr
+	}
+	pr.MedianMs = median
+	pr.NodeMs = max(median-overhead, 0)
+	pr.NsPerGas = pr.NodeMs * 1e6 / float64(pr.ExpectedGas)
+
+	if checkGas {
+		charged, err := chargedGas(ctx, client, probe.Input())
+		if err != nil {
+			log.Printf("⚠️  %s: gas estimate failed: %v", probe.Name, err)
+		} else {
+			pr.ChargedGas = &charged
+		}
+	}
+	return pr
+}
+
+// chargedGas estimates a transaction calling the precompile and subtracts
+// the intrinsic cost, leaving what the precompile charges.
+func chargedGas(ctx context.Context, client *ethclient.Client, input []byte) (uint64, error) {
+	to := precompile.ModExpAddress
+	estimate, err := client.EstimateGas(ctx, ethereum.CallMsg{To: &to, Data: input})
+	if err != nil {
+		return 0, err
+	}
+	intrinsic := uint64(21000)
+	for _, b := range input {
+		if b == 0 {
+			intrinsic += 4
+		} else {
+			intrinsic += 16
+		}
+	}
+	if estimate < intrinsic {
+		return 0, fmt.Errorf("estimate %d is below the intrinsic gas %d", estimate, intrinsic)
+	}
+	return estimate - intrinsic, nil
+}
+
+// medianLatency times call runs times and returns the median in ms.
+func medianLatency(runs int, call func() error) (float64, error) {
+	samples := make([]time.Duration, 0, runs)
+	for i := 0; i < runs; i++ {
+		start := time.Now()
+		if err := call(); err != nil {
+			return 0, err
+		}
+		samples = append(samples, time.Since(start))
+	}
+	return bench.Summarize(bench.Millis(samples), 0).Median, nil
+}
+
+func printProbe(pr ProbeResult) {
+	switch {
+	case pr.Error != "":
+		fmt.Printf("❌ %s: %s\n", pr.Name, pr.Error)
+		return
+	case !pr.Match:
+		fmt.Printf("❌ %s: wrong result\n", pr.Name)
+	case pr.SlowProbe:
+		fmt.Printf("⚠️  %s: %.3f ms for %d gas, %.1fx the reference time per gas\n", pr.Name, pr.NodeMs, pr.ExpectedGas, pr.Ratio)
+	default:
+		fmt.Printf("✅ %s: %.3f ms for %d gas (%.1f ns/gas, %.1fx reference)\n", pr.Name, pr.NodeMs, pr.ExpectedGas, pr.NsPerGas, pr.Ratio)
+	}
+	if pr.ChargedGas != nil && *pr.ChargedGas != pr.ExpectedGas {
+		fmt.Printf("⚠️  %s: node charges %d gas, EIP-2565 prices it at %d\n", pr.Name, *pr.ChargedGas, pr.ExpectedGas)
+	}
+}
+
+func parseSizes(spec string) ([]int, error) {
+	var sizes []int
+	for _, s := range strings.Split(spec, ",") {
+		bits, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || bits <= 0 || bits%8 != 0 {
+			return nil, fmt.Errorf("invalid operand size %q (want a positive multiple of 8 bits)", s)
+		}
+		sizes = append(sizes, bits)
+	}
+	return sizes, nil
+}
+
+func saveModExpResult(result ModExpResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_modexp.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+//suite:writes transactions=1 gas=3000000
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/multicall"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// MulticallCall is one call of the batch and how the aggregator answered it.
+type MulticallCall struct {
+	Index        int    `json:"index"`
+	Name         string `json:"name"`
+	Precompile   string `json:"precompile"`
+	Target       string `json:"target"`
+	AllowFailure bool   `json:"allowFailure"`
+	Input        string `json:"input"`
+	WantSuccess  bool   `json:"wantSuccess"`
+	Expected     string `json:"expected"`
+	Success      bool   `json:"success"`
+	Returned     string `json:"returned"`
+	Match        bool   `json:"match"`
+}
+
+// MulticallCheck is a batch-level check: a disallowed failure must revert
+// the batch, and the batch must also execute as a transaction.
+type MulticallCheck struct {
+	Name    string       `json:"name"`
+	Passed  bool         `json:"passed"`
+	Skipped bool         `json:"skipped,omitempty"`
+	Skip    *skip.Reason `json:"skip,omitempty"`
+	Note    string       `json:"note,omitempty"`
+}
+
+type MulticallResult struct {
+	Stage      string `json:"stage"`
+	Aggregator string `json:"aggregator"`
+	// Source is how the aggregator was found: flag, canonical, saved or
+	// deployed.
+	Source     string           `json:"source"`
+	Calls      []MulticallCall  `json:"calls"`
+	Matches    int              `json:"matches"`
+	Mismatches int              `json:"mismatches"`
+	Checks     []MulticallCheck `json:"checks"`
+	// TransactionHash and GasUsed are set when the batch was also sent as a
+	// transaction.
+	TransactionHash string `json:"transactionHash,omitempty"`
+	GasUsed         uint64 `json:"gasUsed,omitempty"`
+	Error           string `json:"error,omitempty"`
+	Timestamp       string `json:"timestamp"`
+	RPCURL          string `json:"rpcUrl"`
+}
+
+// batchCall is a call of the batch with the answer expected of it.
+type batchCall struct {
+	name        string
+	precompile  common.Address
+	call        multicall.Call
+	wantSuccess bool
+	expected    []byte
+}
+
+func main() {
+	output.Setup()
+
+	aggregatorFlag := flag.String("aggregator", "", "Multicall3 address to use instead of the canonical or a freshly deployed one")
+	send := flag.Bool("send", false, "also send the batch as a transaction and check it is mined successfully")
+	gasLimit := flag.Uint64("gas", 3_000_000, "gas limit of the deployment and of the --send transaction")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *send {
+		if err := chain.CheckWritable(); err != nil {
+			log.Fatalf("❌ --send needs a transaction: %v", err)
+		}
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_multicall.json")
+
+	// Keys are only needed to deploy the aggregator (deployer) or send the
+	// batch (invoker)
+	newSender := func(role chain.Role) (*chain.Sender, error) {
+		sender, err := chain.NewRoleSender(ctx, client, role)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("🔐 Using %s account: %s\n", role, sender.From.Hex())
+		return sender, nil
+	}
+
+	aggregator, source, err := resolveAggregator(ctx, client, *aggregatorFlag, *gasLimit, newSender)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("📌 Using Multicall3 at %s (%s)\n", aggregator.Hex(), source)
+
+	calls, err := buildBatch(ctx, client, tagFilter)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	result := MulticallResult{
+		Stage:      "Multicall - Precompiles Through aggregate3",
+		Aggregator: aggregator.Hex(),
+		Source:     source,
+		RPCURL:     rpcURL,
+	}
+
+	fmt.Printf("📦 Sending %d precompile calls in one aggregate3 eth_call\n", len(calls))
+	batch := make([]multicall.Call, len(calls))
+	for i, c := range calls {
+		batch[i] = c.call
+	}
+	returned, err := multicall.Aggregate3(ctx, client, aggregator, batch)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	for i, c := range calls {
+		mc := MulticallCall{
+			Index:        i,
+			Name:         c.name,
+			Precompile:   c.precompile.Hex(),
+			Target:       c.call.Target.Hex(),
+			AllowFailure: c.call.AllowFailure,
+			Input:        fmt.Sprintf("%x", c.call.CallData),
+			WantSuccess:  c.wantSuccess,
+			Expected:     fmt.Sprintf("%x", c.expected),
+		}
+		// A batch that failed as a whole fails every call in it
+		if returned != nil {
+			mc.Success = returned[i].Success
+			mc.Returned = fmt.Sprintf("%x", returned[i].ReturnData)
+			mc.Match = mc.Success == mc.WantSuccess && mc.Returned == mc.Expected
+		}
+		if mc.Match {
+			result.Matches++
+		} else {
+			result.Mismatches++
+		}
+		result.Calls = append(result.Calls, mc)
+	}
+
+	result.Checks = append(result.Checks, checkRevert(ctx, client, aggregator, calls))
+
+	if *send {
+		check := MulticallCheck{Name: "transaction"}
+		s, err := newSender(chain.RoleInvoke)
+		if err == nil {
+			var data []byte
+			if data, err = multicall.Pack(batch); err == nil {
+				fmt.Println("📨 Sending the batch as a transaction...")
+				tx, receipt, sendErr := s.Send(ctx, &aggregator, data, *gasLimit)
+				err = sendErr
+				if sendErr == nil {
+					result.TransactionHash = tx.Hash().Hex()
+					result.GasUsed = receipt.GasUsed
+					check.Passed = receipt.Status == 1
+					check.Note = fmt.Sprintf("status %d in block %d", receipt.Status, receipt.BlockNumber.Uint64())
+				}
+			}
+		}
+		if err != nil {
+			check.Note = err.Error()
+		}
+		result.Checks = append(result.Checks, check)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := saveMulticallResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Multicall results:")
+	if result.Error != "" {
+		fmt.Printf("❌ Batch failed: %s\n", result.Error)
+	}
+	for _, c := range result.Calls {
+		if !c.Match {
+			fmt.Printf("❌ #%d %s: expected success=%t %s, got success=%t %s\n", c.Index, c.Name, c.WantSuccess, c.Expected, c.Success, c.Returned)
+		}
+	}
+	fmt.Printf("✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	failed := result.Mismatches > 0
+	for _, check := range result.Checks {
+		status := "✅"
+		switch {
+		case check.Skipped:
+			status = "⏭️ "
+		case !check.Passed:
+			status = "❌"
+			failed = true
+		}
+		fmt.Printf("%s %s %s\n", status, check.Name, check.Note)
+	}
+	if result.TransactionHash != "" {
+		fmt.Printf("⛽ Transaction %s used %d gas\n", result.TransactionHash, result.GasUsed)
+	}
+	fmt.Println("\n📝 Results saved to results_multicall.json")
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// resolveAggregator picks the Multicall3 to call: the --aggregator address,
+// the canonical deployment, the one saved in deployed_multicall_address.txt,
+// or a fresh deployment of artifacts/Multicall3, in that order.
+func resolveAggregator(ctx context.Context, client *ethclient.Client, override string, gas uint64, newSender func(chain.Role) (*chain.Sender, error)) (common.Address, string, error) {
+	if override != "" {
+		if !common.IsHexAddress(override) {
+			return common.Address{}, "", fmt.Errorf("invalid --aggregator address %q", override)
+		}
+		address := common.HexToAddress(override)
+		if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+			return common.Address{}, "", err
+		}
+		return address, "flag", nil
+	}
+	if code, err := client.CodeAt(ctx, multicall.CanonicalAddress, nil); err == nil && len(code) > 0 {
+		return multicall.CanonicalAddress, "canonical", nil
+	}
+	if address, err := paths.ReadAddress(paths.Work("deployed_multicall_address.txt")); err == nil {
+		if code, err := client.CodeAt(ctx, address, nil); err == nil && len(code) > 0 {
+			return address, "saved", nil
+		}
+	}
+
+	bytecode, err := paths.ReadHex(paths.Artifact("Multicall3.bin"))
+	if err != nil {
+		return common.Address{}, "", fmt.Errorf("no Multicall3 on chain and failed to read bytecode (compile contracts/Multicall3.sol first): %v", err)
+	}
+	if err := deploy.VerifyArtifact(paths.Artifact("Multicall3")); err != nil {
+		return common.Address{}, "", fmt.Errorf("refusing to deploy: %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		return common.Address{}, "", fmt.Errorf("no Multicall3 on chain and can't deploy one (pass --aggregator): %v", err)
+	}
+	sender, err := newSender(chain.RoleDeploy)
+	if err != nil {
+		return common.Address{}, "", err
+	}
+	fmt.Println("📨 Deploying Multicall3...")
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "Multicall3"), nil, common.FromHex(bytecode), gas)
+	if err != nil {
+		return common.Address{}, "", fmt.Errorf("deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return common.Address{}, "", fmt.Errorf("Multicall3 deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	if err := paths.WriteFile(paths.Work("deployed_multicall_address.txt"), []byte(receipt.ContractAddress.Hex())); err != nil {
+		return common.Address{}, "", fmt.Errorf("failed to save deployed address: %v", err)
+	}
+	return receipt.ContractAddress, "deployed", nil
+}
+
+// buildBatch mixes calls to every precompile the harness covers, through
+// the wrapper too when it is deployed, with calls expected to fail under
+// allowFailure. The SHA-256 and identity inputs honour the tag filters.
+func buildBatch(ctx context.Context, client *ethclient.Client, filter *tags.Filter) ([]batchCall, error) {
+	vectors := vector.Select([]vector.Vector{
+		vector.New([]byte("hello world"), tags.Smoke),
+		vector.New([]byte(""), tags.Smoke),
+		vector.New([]byte("cdk-erigon")),
+		vector.New([]byte{0x00, 0xff, 0xfe, 0x80}, tags.Binary),
+		vector.New([]byte(strings.Repeat("multicall", 120))),
+	}, filter)
+
+	identity := common.HexToAddress("0x04")
+	var calls []batchCall
+	for _, v := range vectors {
+		input := v.Bytes()
+		sum := sha256.Sum256(input)
+		calls = append(calls,
+			batchCall{name: "sha256 " + v.Display(), precompile: precompile.SHA256Address,
+				call: multicall.Call{Target: precompile.SHA256Address, CallData: input}, wantSuccess: true, expected: sum[:]},
+			batchCall{name: "identity " + v.Display(), precompile: identity,
+				call: multicall.Call{Target: identity, CallData: input}, wantSuccess: true, expected: input},
+		)
+	}
+
+	sigs, err := precompile.RandomSignatures(2)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sigs {
+		calls = append(calls, batchCall{name: "ecrecover " + s.Signer.Hex(), precompile: precompile.ECRecoverAddress,
+			call: multicall.Call{Target: precompile.ECRecoverAddress, CallData: s.Input()}, wantSuccess: true,
+			expected: common.LeftPadBytes(s.Signer.Bytes(), 32)})
+	}
+	// An unrecoverable signature succeeds with empty output
+	badV := sigs[0].Input()
+	badV[63] = 29
+	calls = append(calls, batchCall{name: "ecrecover v=29", precompile: precompile.ECRecoverAddress,
+		call: multicall.Call{Target: precompile.ECRecoverAddress, CallData: badV}, wantSuccess: true})
+
+	m := precompile.ModExp{Base: []byte{3}, Exp: []byte{0xff, 0xff}, Mod: big.NewInt(1_000_000_007).Bytes()}
+	calls = append(calls, batchCall{name: "modexp 3^65535 % 1000000007", precompile: precompile.ModExpAddress,
+		call: multicall.Call{Target: precompile.ModExpAddress, CallData: m.Input()}, wantSuccess: true, expected: m.Expected()})
+
+	for _, n := range []int{1, 2} {
+		input, ok := precompile.PairingInput(n)
+		want := make([]byte, 32)
+		if ok {
+			want[31] = 1
+		}
+		calls = append(calls, batchCall{name: fmt.Sprintf("pairing %d pairs", n), precompile: precompile.PairingAddress,
+			call: multicall.Call{Target: precompile.PairingAddress, CallData: input}, wantSuccess: true, expected: want})
+	}
+	// Input that isn't a whole number of pairs makes the precompile fail
+	calls = append(calls, batchCall{name: "pairing malformed", precompile: precompile.PairingAddress,
+		call: multicall.Call{Target: precompile.PairingAddress, AllowFailure: true, CallData: make([]byte, 100)}})
+
+	if wrapper, version, err := precompile.LoadWrapper(ctx, client); err != nil {
+		fmt.Printf("⏭️  Skipping wrapper calls: %v\n", err)
+	} else if _, err := precompile.CodeSize(ctx, client, wrapper); err != nil {
+		fmt.Printf("⏭️  Skipping wrapper calls: %v\n", err)
+	} else {
+		for _, v := range vectors {
+			data, err := version.ABI().Pack("sha256Hash", v.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("failed to pack sha256Hash: %v", err)
+			}
+			sum := sha256.Sum256(v.Bytes())
+			calls = append(calls, batchCall{name: "wrapper " + v.Display(), precompile: precompile.SHA256Address,
+				call: multicall.Call{Target: wrapper, CallData: data}, wantSuccess: true, expected: sum[:]})
+		}
+	}
+	return calls, nil
+}
+
+// checkRevert resends the batch with the failing calls no longer allowed
+// to fail, which must revert the whole aggregate3 call.
+func checkRevert(ctx context.Context, client *ethclient.Client, aggregator common.Address, calls []batchCall) MulticallCheck {
+	check := MulticallCheck{Name: "disallowed failure reverts the batch"}
+	var batch []multicall.Call
+	failing := 0
+	for _, c := range calls {
+		call := c.call
+		if !c.wantSuccess {
+			call.AllowFailure = false
+			failing++
+		}
+		batch = append(batch, call)
+	}
+	if failing == 0 {
+		check.Skipped, check.Skip, check.Note = true, skip.New(skip.NotApplicable, ""), "no failing call in the batch"
+		return check
+	}
+	_, err := multicall.Aggregate3(ctx, client, aggregator, batch)
+	if err == nil {
+		check.Note = "the batch succeeded"
+		return check
+	}
+	check.Note = err.Error()
+	check.Passed = strings.Contains(strings.ToLower(check.Note), "revert")
+	return check
+}
+
+func saveMulticallResult(result MulticallResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_multicall.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
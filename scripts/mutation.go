@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/mutate"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/registry"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vcache"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// MutationCase is one mutated input and how the node answered it.
+type MutationCase struct {
+	Target     string `json:"target"`
+	Precompile string `json:"precompile"`
+	vector.Vector
+	Mutation        string        `json:"mutation"`
+	Sent            hexutil.Bytes `json:"sent"`
+	ExpectedFailure bool          `json:"expectedFailure"`
+	Expected        hexutil.Bytes `json:"expected,omitempty"`
+	Returned        hexutil.Bytes `json:"returned,omitempty"`
+	Error           string        `json:"error,omitempty"`
+	Match           bool          `json:"match"`
+	Cached          bool          `json:"cached,omitempty"`
+}
+
+type MutationSummary struct {
+	Stage       string                    `json:"stage"`
+	Targets     []string                  `json:"targets"`
+	Cases       int                       `json:"cases"`
+	Matches     int                       `json:"matches"`
+	Mismatches  int                       `json:"mismatches"`
+	Cached      int                       `json:"cached"`
+	Precompiles map[string]*MutationTally `json:"precompiles"`
+	Failures    []MutationCase            `json:"failures,omitempty"`
+	Timestamp   string                    `json:"timestamp"`
+	RPCURL      string                    `json:"rpcUrl"`
+}
+
+// MutationTally counts the cases of one precompile.
+type MutationTally struct {
+	Matches    int `json:"matches"`
+	Mismatches int `json:"mismatches"`
+}
+
+// mutationTarget is a target with where to call it and what to feed it.
+type mutationTarget struct {
+	mutate.Target
+	precompile string
+	address    common.Address
+	vectors    []vector.Vector
+	// cacheKey identifies the target in the vector cache. It includes
+	// anything besides the input that decides the expected answer.
+	cacheKey string
+}
+
+func main() {
+	output.Setup()
+
+	targetsFlag := flag.String("targets", "sha256,wrapper,modexp", "comma-separated targets: sha256, wrapper, modexp")
+	vectorsFrom := flag.String("vectors-from", "", "load the sha256 vectors from a file or registry URL instead of the built-in set")
+	gasCap := flag.Uint64("gas-cap", 50_000_000, "the node's eth_call gas cap, used to predict which modexp mutations run out of gas")
+	cacheOpts := vcache.Flags()
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_mutation.json")
+
+	cache, err := cacheOpts.Open(ctx, client)
+	if err != nil {
+		log.Printf("⚠️  Vector cache disabled: %v", err)
+	}
+	if cache != nil {
+		loaded, _, _ := cache.Stats()
+		fmt.Printf("🗃️  Vector cache: %d inputs already verified against %s\n", loaded, cache.Build().Key())
+	}
+
+	// Canonical vectors, as in stage 3
+	sha256Vectors := []vector.Vector{
+		vector.New([]byte("hello world"), tags.Smoke, tags.Gas),
+		vector.New([]byte(""), tags.Smoke, tags.Gas),
+		vector.New([]byte("The quick brown fox jumps over the lazy dog"), tags.Gas),
+		vector.New([]byte("cdk-erigon"), tags.Gas),
+		vector.New([]byte{0x00, 0xff, 0xfe, 0x80}, tags.Binary),
+	}
+	if *vectorsFrom != "" {
+		name, loaded, err := registry.NewFetcher("").LoadVectors(ctx, registry.ParseSource(*vectorsFrom))
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		sha256Vectors = loaded
+		fmt.Printf("📥 Loaded vector set %q: %d vectors\n", name, len(sha256Vectors))
+	}
+	sha256Vectors = vector.Select(sha256Vectors, tagFilter)
+
+	targets, err := buildTargets(ctx, client, strings.Split(*targetsFlag, ","), sha256Vectors, vector.Select(modexpVectors(), tagFilter), *gasCap)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	summary := MutationSummary{Stage: "Mutation", Precompiles: map[string]*MutationTally{}, RPCURL: rpcURL}
+	for _, target := range targets {
+		summary.Targets = append(summary.Targets, target.Name)
+		fmt.Printf("\n🧬 %s: %d vectors\n", target.Name, len(target.vectors))
+		tally := summary.Precompiles[target.precompile]
+		if tally == nil {
+			tally = &MutationTally{}
+			summary.Precompiles[target.precompile] = tally
+		}
+		for _, v := range target.vectors {
+			for _, c := range runMatrix(ctx, client, cache, target, v) {
+				summary.Cases++
+				if c.Cached {
+					summary.Cached++
+				}
+				if c.Match {
+					summary.Matches++
+					tally.Matches++
+					continue
+				}
+				summary.Mismatches++
+				tally.Mismatches++
+				summary.Failures = append(summary.Failures, c)
+				fmt.Printf("❌ %s %s: %s\n", v.Display(), c.Mutation, describe(c))
+			}
+		}
+	}
+	summary.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := cache.Save(); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	fmt.Printf("\n📊 %d mutations: %d as expected (%d cached), %d mismatched\n", summary.Cases, summary.Matches, summary.Cached, summary.Mismatches)
+
+	if err := saveMutationSummary(summary); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Println("📝 Results saved to results_mutation.json")
+
+	if summary.Mismatches > 0 {
+		log.Fatalf("❌ %d mutated inputs were answered differently from the reference", summary.Mismatches)
+	}
+}
+
+// buildTargets resolves the requested targets. The wrapper is skipped with
+// a warning when it hasn't been deployed.
+func buildTargets(ctx context.Context, client *ethclient.Client, names []string, sha256Vectors, modexpVectors []vector.Vector, gasCap uint64) ([]mutationTarget, error) {
+	var targets []mutationTarget
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "sha256":
+			targets = append(targets, mutationTarget{
+				Target:     mutate.SHA256(),
+				precompile: "0x02",
+				address:    precompile.SHA256Address,
+				vectors:    sha256Vectors,
+				cacheKey:   precompile.SHA256Address.Hex(),
+			})
+		case "wrapper":
+			address, version, err := precompile.LoadWrapper(ctx, client)
+			if err != nil {
+				log.Printf("⚠️  Skipping wrapper mutations: %v", err)
+				continue
+			}
+			targets = append(targets, mutationTarget{
+				Target:     mutate.Wrapper(version.ABI()),
+				precompile: "0x02",
+				address:    address,
+				vectors:    sha256Vectors,
+				cacheKey:   "wrapper@" + address.Hex(),
+			})
+		case "modexp":
+			targets = append(targets, mutationTarget{
+				Target:     mutate.ModExp(gasCap),
+				precompile: "0x05",
+				address:    precompile.ModExpAddress,
+				vectors:    modexpVectors,
+				cacheKey:   fmt.Sprintf("%s?gas-cap=%d", precompile.ModExpAddress.Hex(), gasCap),
+			})
+		default:
+			return nil, fmt.Errorf("❌ Unknown target %q (want sha256, wrapper or modexp)", name)
+		}
+	}
+	return targets, nil
+}
+
+// modexpVectors are known-answer modexp inputs: a small one, Fermat's
+// little theorem on the secp256k1 field prime from EIP-198, and a
+// 256-bit one.
+func modexpVectors() []vector.Vector {
+	p := common.FromHex("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f")
+	pMinus1 := common.FromHex("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2e")
+	inputs := []precompile.ModExp{
+		{Base: []byte{3}, Exp: []byte{5}, Mod: []byte{0, 100}},
+		{Base: []byte{3}, Exp: pMinus1, Mod: p},
+		precompile.ModExpProbes(nil)[0].ModExp,
+	}
+	vectors := make([]vector.Vector, len(inputs))
+	for i, in := range inputs {
+		vectors[i] = vector.New(in.Input(), tags.Binary)
+	}
+	return vectors
+}
+
+// runMatrix sends the vector and each of its mutations, comparing every
+// answer with the reference. Mutations the cache has seen answered as
+// expected by this node build aren't sent again.
+func runMatrix(ctx context.Context, client *ethclient.Client, cache *vcache.Cache, target mutationTarget, v vector.Vector) []MutationCase {
+	input := target.Encode(v.Bytes())
+	mutations := append([]mutate.Mutation{{Name: "original", Input: input}}, mutate.Matrix(input, target.Prefixes)...)
+
+	cases := make([]MutationCase, 0, len(mutations))
+	for _, m := range mutations {
+		expected := target.Reference(m.Input)
+		c := MutationCase{
+			Target:          target.Name,
+			Precompile:      target.precompile,
+			Vector:          v,
+			Mutation:        m.Name,
+			Sent:            m.Input,
+			ExpectedFailure: expected.Fails,
+			Expected:        expected.Output,
+		}
+		if cache.Verified(target.cacheKey, m.Input) {
+			c.Match, c.Cached = true, true
+			cases = append(cases, c)
+			continue
+		}
+		to := target.address
+		returned, err := client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: m.Input}, nil)
+		if err != nil {
+			c.Error = err.Error()
+			c.Match = expected.Fails
+		} else {
+			c.Returned = returned
+			c.Match = !expected.Fails && bytes.Equal(returned, expected.Output)
+		}
+		if c.Match {
+			cache.Record(target.cacheKey, m.Input)
+		}
+		cases = append(cases, c)
+	}
+	return cases
+}
+
+func describe(c MutationCase) string {
+	switch {
+	case c.ExpectedFailure:
+		return fmt.Sprintf("expected a failure, got %s", c.Returned)
+	case c.Error != "":
+		return fmt.Sprintf("expected %s, got error: %s", c.Expected, c.Error)
+	}
+	return fmt.Sprintf("expected %s, got %s", c.Expected, c.Returned)
+}
+
+func saveMutationSummary(summary MutationSummary) error {
+	file, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_mutation.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"cdk-erigon-precompile/pkg/nightly"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+func main() {
+	output.Setup()
+
+	networksPath := flag.String("networks", "networks.json", "file listing the networks to run the suite against")
+	outDir := flag.String("out", paths.Work("nightly"), "directory holding each network's work directory and the consolidated report")
+	only := flag.String("only", "", "comma-separated names of the networks to run, of those in --networks (default all)")
+	parallel := flag.Int("parallel", 0, "how many networks to run at once (0 runs them all at once)")
+	flag.Parse()
+
+	config, err := nightly.Load(*networksPath)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	networks, err := config.Select(tags.Parse(*only))
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	out, err := filepath.Abs(*outDir)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	limit := *parallel
+	if limit <= 0 || limit > len(networks) {
+		limit = len(networks)
+	}
+
+	// Ctrl-C stops every network's run; what they saved is still
+	// consolidated
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("🌐 Running the suite against %d networks, %d at a time, in %s\n", len(networks), limit, out)
+	start := time.Now()
+	results := make([]nightly.NetworkResult, len(networks))
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, n := range networks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = runNetwork(ctx, n, config.Args, filepath.Join(out, n.Name))
+			printNetwork(results[i])
+		}()
+	}
+	wg.Wait()
+
+	report := nightly.Consolidate(results)
+	report.DurationS = time.Since(start).Seconds()
+	report.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	path := filepath.Join(out, "nightly_report.json")
+	file, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal report: %v", err)
+	}
+	if err := paths.WriteFile(path, file); err != nil {
+		log.Fatalf("❌ Failed to save report: %v", err)
+	}
+
+	printReport(report)
+	fmt.Printf("📝 Report saved to %s; each network's results and run.log are in its directory next to it\n", path)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runNetwork runs the suite against n with dir as its work directory,
+// deploying the wrapper first when asked to and none is there yet. The
+// output of both goes to run.log in dir.
+func runNetwork(ctx context.Context, n nightly.Network, shared []string, dir string) nightly.NetworkResult {
+	logPath := filepath.Join(dir, "run.log")
+	if err := os.MkdirAll(dir, paths.DirMode); err != nil {
+		return nightly.NetworkResult{Name: n.Name, WorkDir: dir, Status: nightly.Error, Error: err.Error()}
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, paths.FileMode)
+	if err != nil {
+		return nightly.NetworkResult{Name: n.Name, WorkDir: dir, Status: nightly.Error, Error: err.Error()}
+	}
+	defer logFile.Close()
+	fmt.Printf("🚀 Started %s\n", n.Name)
+
+	env := n.Environ(dir)
+	if _, err := paths.ReadAddress(filepath.Join(dir, "deployed_address.txt")); n.Deploy && err != nil {
+		var envArgs []string
+		for _, f := range n.EnvFiles {
+			envArgs = append(envArgs, "--env-file", f)
+		}
+		if err := runLogged(ctx, logFile, env, "scripts/stage2_deploy_wrapper.go", envArgs); err != nil {
+			// The wrapper groups fail too, which the report shows
+			fmt.Fprintf(logFile, "⚠️  Wrapper deployment failed: %v\n", err)
+		}
+	}
+	runErr := runLogged(ctx, logFile, env, "scripts/run.go", n.RunArgs(shared))
+	return nightly.Collect(n, dir, logPath, runErr)
+}
+
+// runLogged runs a script with env added to the inherited environment and
+// its output written to w. The script is killed if ctx is cancelled.
+func runLogged(ctx context.Context, w io.Writer, env []string, script string, args []string) error {
+	fmt.Fprintf(w, "$ go run %s %s\n", script, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "go", append([]string{"run", script}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return cmd.Run()
+}
+
+func printNetwork(r nightly.NetworkResult) {
+	switch r.Status {
+	case nightly.Passed:
+		fmt.Printf("✅ %s: %d groups passed, %d skipped in %s\n", r.Name, r.Passed, r.Skipped, seconds(r.DurationS))
+	case nightly.Failed:
+		fmt.Printf("❌ %s: %d groups failed, %d passed in %s\n", r.Name, r.Failed, r.Passed, seconds(r.DurationS))
+	default:
+		fmt.Printf("❌ %s: %s (see %s)\n", r.Name, r.Error, r.Log)
+	}
+}
+
+// printReport prints each network's outcome and score, then the groups
+// and categories that differ between networks.
+func printReport(r nightly.Report) {
+	fmt.Println("\n🌐 Nightly results:")
+	fmt.Printf("   %-16s %-7s %6s %6s %7s %8s\n", "network", "status", "passed", "failed", "skipped", "score")
+	for _, n := range r.Networks {
+		percent := n.Percent
+		if percent == "" {
+			percent = "-"
+		}
+		fmt.Printf("   %-16s %-7s %6d %6d %7d %8s\n", n.Name, n.Status, n.Passed, n.Failed, n.Skipped, percent)
+	}
+	if r.Divergent > 0 {
+		fmt.Println("\n🔀 Groups passing on some networks and failing on others:")
+		for _, g := range r.Groups {
+			if !g.Diverges {
+				continue
+			}
+			var on []string
+			for _, n := range r.Networks {
+				if s, ok := g.Status[n.Name]; ok {
+					on = append(on, n.Name+" "+s)
+				}
+			}
+			fmt.Printf("❌ %s: %s\n", g.Name, strings.Join(on, ", "))
+		}
+	}
+	for _, c := range r.Categories {
+		var scores []string
+		var first string
+		differs := false
+		for _, n := range r.Networks {
+			if p, ok := c.Percent[n.Name]; ok {
+				if first == "" {
+					first = p
+				}
+				differs = differs || p != first
+				scores = append(scores, n.Name+" "+p)
+			}
+		}
+		if differs {
+			fmt.Printf("📉 %s: %s\n", c.Name, strings.Join(scores, ", "))
+		}
+	}
+	fmt.Printf("\n📊 Networks passed: %d, failed: %d in %s\n", r.Passed, r.Failed, seconds(r.DurationS))
+}
+
+// seconds formats a duration recorded in seconds for the console.
+func seconds(s float64) string {
+	return output.Duration(time.Duration(s * float64(time.Second)))
+}
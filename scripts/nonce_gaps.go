@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// NonceTx is one transaction of a scenario, by the nonce it was signed at.
+type NonceTx struct {
+	Nonce     uint64 `json:"nonce"`
+	Hash      string `json:"hash"`
+	SendError string `json:"sendError,omitempty"`
+	Mined     bool   `json:"mined"`
+	Block     uint64 `json:"block,omitempty"`
+	Index     uint   `json:"index,omitempty"`
+	Status    uint64 `json:"status,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// NonceScenario submits transactions at consecutive nonces from BaseNonce
+// in Order, given as offsets from it.
+type NonceScenario struct {
+	Name         string        `json:"name"`
+	BaseNonce    uint64        `json:"baseNonce"`
+	Order        []uint64      `json:"order"`
+	Transactions []NonceTx     `json:"transactions"`
+	Checks       []chain.Check `json:"checks"`
+	Passed       bool          `json:"passed"`
+	Error        string        `json:"error,omitempty"`
+}
+
+type NonceGapResult struct {
+	Stage     string          `json:"stage"`
+	From      string          `json:"from"`
+	Scenarios []NonceScenario `json:"scenarios"`
+	Passed    int             `json:"passed"`
+	Failed    int             `json:"failed"`
+	Timestamp string          `json:"timestamp"`
+	RPCURL    string          `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	depth := flag.Int("depth", 4, "transactions in the reversed and interleaved scenarios")
+	settle := flag.Duration("settle", 10*time.Second, "how long transactions behind a nonce gap must stay unmined")
+	timeout := flag.Duration("timeout", 2*time.Minute, "how long to wait for each transaction once the gap is filled")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if *depth < 2 {
+		log.Fatalf("❌ --depth must be at least 2, got %d", *depth)
+	}
+	if !tagFilter.Match([]string{tags.Writes}) {
+		fmt.Printf("⏭️  Nonce gap tests skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_nonce_gaps.json")
+
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleInvoke)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	sender.ReceiptTimeout = *timeout
+	fmt.Printf("🔐 Sending zero-value self-transfers from %s\n", sender.From.Hex())
+
+	reversed := make([]uint64, *depth)
+	interleaved := make([]uint64, *depth)
+	for i := range reversed {
+		reversed[i] = uint64(*depth - 1 - i)
+		// Pairs swapped: 1, 0, 3, 2, ...
+		interleaved[i] = uint64(i ^ 1)
+		if int(interleaved[i]) >= *depth {
+			interleaved[i] = uint64(i)
+		}
+	}
+	scenarios := []struct {
+		name  string
+		order []uint64
+	}{
+		{"gap filled later", []uint64{1, 0}},
+		{"reversed", reversed},
+		{"interleaved", interleaved},
+	}
+
+	result := NonceGapResult{Stage: "Nonce Gaps - Out-of-Order Submission", From: sender.From.Hex(), RPCURL: rpcURL}
+	for _, s := range scenarios {
+		fmt.Printf("\n🔢 %s: offsets %v\n", s.name, s.order)
+		sc := runScenario(ctx, client, sender, s.name, s.order, *settle)
+		if ctx.Err() != nil {
+			log.Fatalf("❌ Interrupted: %v", ctx.Err())
+		}
+		printScenario(sc)
+		result.Scenarios = append(result.Scenarios, sc)
+		if !allMined(sc) {
+			// Later scenarios would queue behind the unmined transactions
+			fmt.Println("⚠️  Transactions left unmined; the remaining scenarios would queue behind them")
+			break
+		}
+	}
+	if len(result.Scenarios) == len(scenarios) {
+		for _, sc := range []NonceScenario{harnessNonce(ctx, client, sender), staleNonce(ctx, client, sender)} {
+			printScenario(sc)
+			result.Scenarios = append(result.Scenarios, sc)
+		}
+	}
+
+	for _, sc := range result.Scenarios {
+		if sc.Passed {
+			result.Passed++
+		} else {
+			result.Failed++
+		}
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_nonce_gaps.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+	fmt.Printf("\n📊 Passed: %d, Failed: %d\n", result.Passed, result.Failed)
+	fmt.Println("📝 Results saved to results_nonce_gaps.json")
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runScenario signs a self-transfer at every nonce from the account's next
+// one, submits them in order and waits for all of them. Before the
+// transaction at the base nonce goes out, the ones already submitted sit
+// behind a gap and are checked to stay queued for settle.
+func runScenario(ctx context.Context, client *ethclient.Client, sender *chain.Sender, name string, order []uint64, settle time.Duration) NonceScenario {
+	sc := NonceScenario{Name: name, Order: order}
+	base, err := client.PendingNonceAt(ctx, sender.From)
+	if err != nil {
+		sc.Error = fmt.Sprintf("failed to get nonce: %v", err)
+		return sc
+	}
+	sc.BaseNonce = base
+
+	signed := make([]*types.Transaction, len(order))
+	sc.Transactions = make([]NonceTx, len(order))
+	for i := range signed {
+		if signed[i], err = sender.SignAt(ctx, base+uint64(i), &sender.From, nil, params.TxGas); err != nil {
+			sc.Error = err.Error()
+			return sc
+		}
+		sc.Transactions[i] = NonceTx{Nonce: base + uint64(i), Hash: signed[i].Hash().Hex()}
+	}
+
+	var queued []uint64
+	for _, offset := range order {
+		if offset == 0 && len(queued) > 0 {
+			sc.Checks = append(sc.Checks, gapChecks(ctx, client, sender.From, base, queued, signed, settle)...)
+		}
+		if err := sender.Submit(ctx, signed[offset]); err != nil {
+			sc.Transactions[offset].SendError = err.Error()
+			continue
+		}
+		queued = append(queued, offset)
+	}
+
+	check := chain.Check{Name: "every nonce accepted", Expected: fmt.Sprint(len(order)), Actual: fmt.Sprint(len(queued)), Passed: len(queued) == len(order)}
+	sc.Checks = append(sc.Checks, check)
+
+	for i, tx := range signed {
+		if sc.Transactions[i].SendError != "" {
+			continue
+		}
+		receipt, err := sender.Await(ctx, tx)
+		if err != nil {
+			sc.Transactions[i].Error = err.Error()
+			continue
+		}
+		sc.Transactions[i].Mined = true
+		sc.Transactions[i].Block = receipt.BlockNumber.Uint64()
+		sc.Transactions[i].Index = receipt.TransactionIndex
+		sc.Transactions[i].Status = receipt.Status
+	}
+	sc.Checks = append(sc.Checks, inclusionChecks(ctx, client, sender.From, sc)...)
+	sc.Passed = chain.AllPassed(sc.Checks)
+	return sc
+}
+
+// gapChecks waits settle and checks that the transactions at the queued
+// offsets, all past the base nonce, are held back: not mined, left out of
+// the pending nonce and, where the node has txpool_content, listed as
+// queued.
+func gapChecks(ctx context.Context, client *ethclient.Client, from common.Address, base uint64, queued []uint64, signed []*types.Transaction, settle time.Duration) []chain.Check {
+	fmt.Printf("⏳ Holding %d transactions behind nonce %d for %s\n", len(queued), base, settle)
+	select {
+	case <-time.After(settle):
+	case <-ctx.Done():
+		return nil
+	}
+
+	check := chain.Check{Name: "nothing mined behind the gap", Expected: "no receipts", Passed: true}
+	var mined []string
+	for _, offset := range queued {
+		if receipt, err := client.TransactionReceipt(ctx, signed[offset].Hash()); err == nil && receipt != nil {
+			mined = append(mined, fmt.Sprint(base+offset))
+		}
+	}
+	if len(mined) > 0 {
+		check.Actual = "mined nonces " + strings.Join(mined, ", ")
+		check.Passed = false
+	}
+	checks := []chain.Check{check}
+
+	check = chain.Check{Name: "pending nonce stops at the gap", Expected: fmt.Sprint(base)}
+	if nonce, err := client.PendingNonceAt(ctx, from); err != nil {
+		check.Note = err.Error()
+	} else {
+		check.Actual = fmt.Sprint(nonce)
+		check.Passed = nonce == base
+	}
+	checks = append(checks, check)
+
+	check = chain.Check{Name: "txpool_content lists them as queued", Expected: fmt.Sprintf("%d queued", len(queued))}
+	var content struct {
+		Queued map[string]map[string]json.RawMessage `json:"queued"`
+	}
+	if err := client.Client().CallContext(ctx, &content, "txpool_content"); err != nil {
+		check.MarkSkipped(skip.Capability, fmt.Sprintf("txpool_content unavailable: %v", err))
+		return append(checks, check)
+	}
+	found := 0
+	for addr, txs := range content.Queued {
+		if !strings.EqualFold(addr, from.Hex()) {
+			continue
+		}
+		for _, offset := range queued {
+			if _, ok := txs[fmt.Sprint(base+offset)]; ok {
+				found++
+			}
+		}
+	}
+	check.Actual = fmt.Sprintf("%d queued", found)
+	check.Passed = found == len(queued)
+	return append(checks, check)
+}
+
+// inclusionChecks checks a scenario's transactions were all mined,
+// successfully and in nonce order, and that the pending nonce moved past
+// them.
+func inclusionChecks(ctx context.Context, client *ethclient.Client, from common.Address, sc NonceScenario) []chain.Check {
+	var mined []NonceTx
+	failed := 0
+	for _, tx := range sc.Transactions {
+		if tx.Mined {
+			mined = append(mined, tx)
+			if tx.Status != types.ReceiptStatusSuccessful {
+				failed++
+			}
+		}
+	}
+	checks := []chain.Check{
+		{Name: "every transaction mined", Expected: fmt.Sprint(len(sc.Transactions)), Actual: fmt.Sprint(len(mined)), Passed: len(mined) == len(sc.Transactions)},
+		{Name: "every transaction succeeded", Expected: "0 failed", Actual: fmt.Sprintf("%d failed", failed), Passed: failed == 0},
+	}
+
+	byPosition := append([]NonceTx(nil), mined...)
+	sort.SliceStable(byPosition, func(i, j int) bool {
+		if byPosition[i].Block != byPosition[j].Block {
+			return byPosition[i].Block < byPosition[j].Block
+		}
+		return byPosition[i].Index < byPosition[j].Index
+	})
+	var want, got []string
+	for i := range mined {
+		want = append(want, fmt.Sprint(mined[i].Nonce))
+		got = append(got, fmt.Sprint(byPosition[i].Nonce))
+	}
+	check := chain.Check{Name: "mined in nonce order", Expected: strings.Join(want, ", "), Actual: strings.Join(got, ", ")}
+	check.Passed = check.Expected == check.Actual
+	checks = append(checks, check)
+
+	next := sc.BaseNonce + uint64(len(sc.Transactions))
+	check = chain.Check{Name: "pending nonce past the scenario", Expected: fmt.Sprint(next)}
+	if nonce, err := client.PendingNonceAt(ctx, from); err != nil {
+		check.Note = err.Error()
+	} else {
+		check.Actual = fmt.Sprint(nonce)
+		check.Passed = nonce == next
+	}
+	return append(checks, check)
+}
+
+// harnessNonce sends one transaction the way every other stage does,
+// letting Sender pick the nonce, which must be the next one after the
+// out-of-order scenarios.
+func harnessNonce(ctx context.Context, client *ethclient.Client, sender *chain.Sender) NonceScenario {
+	sc := NonceScenario{Name: "harness picks the next nonce"}
+	fmt.Printf("\n🔢 %s\n", sc.Name)
+	latest, err := client.NonceAt(ctx, sender.From, nil)
+	if err != nil {
+		sc.Error = fmt.Sprintf("failed to get nonce: %v", err)
+		return sc
+	}
+	sc.BaseNonce = latest
+	tx, receipt, err := sender.Send(ctx, &sender.From, nil, params.TxGas)
+	if tx == nil {
+		sc.Error = err.Error()
+		return sc
+	}
+	sent := NonceTx{Nonce: tx.Nonce(), Hash: tx.Hash().Hex()}
+	if err != nil {
+		sent.Error = err.Error()
+	} else {
+		sent.Mined, sent.Block, sent.Index, sent.Status = true, receipt.BlockNumber.Uint64(), receipt.TransactionIndex, receipt.Status
+	}
+	sc.Transactions = []NonceTx{sent}
+	sc.Checks = []chain.Check{
+		{Name: "nonce follows the last mined one", Expected: fmt.Sprint(latest), Actual: fmt.Sprint(tx.Nonce()), Passed: tx.Nonce() == latest},
+		{Name: "mined", Expected: "status 1", Actual: fmt.Sprintf("status %d", sent.Status), Passed: sent.Mined && sent.Status == types.ReceiptStatusSuccessful, Note: sent.Error},
+	}
+	sc.Passed = chain.AllPassed(sc.Checks)
+	return sc
+}
+
+// staleNonce offers a new transaction at a nonce already mined, which the
+// pool must refuse as too low rather than accept and never mine.
+func staleNonce(ctx context.Context, client *ethclient.Client, sender *chain.Sender) NonceScenario {
+	sc := NonceScenario{Name: "stale nonce refused"}
+	fmt.Printf("\n🔢 %s\n", sc.Name)
+	latest, err := client.NonceAt(ctx, sender.From, nil)
+	if err != nil || latest == 0 {
+		sc.Error = fmt.Sprintf("no mined nonce to reuse: %v", err)
+		return sc
+	}
+	sc.BaseNonce = latest - 1
+	// A different gas limit gives a different hash than the mined one
+	tx, err := sender.SignAt(ctx, sc.BaseNonce, &sender.From, nil, params.TxGas+1)
+	if err != nil {
+		sc.Error = err.Error()
+		return sc
+	}
+	sent := NonceTx{Nonce: tx.Nonce(), Hash: tx.Hash().Hex()}
+	check := chain.Check{Name: "refused as nonce too low", Expected: chain.ErrNonceTooLow.Error()}
+	switch err := sender.Submit(ctx, tx); {
+	case err == nil:
+		check.Actual = "accepted"
+	case errors.Is(err, chain.ErrNonceTooLow):
+		sent.SendError = err.Error()
+		check.Actual, check.Passed = chain.ErrNonceTooLow.Error(), true
+	default:
+		sent.SendError = err.Error()
+		check.Actual = err.Error()
+	}
+	sc.Transactions = []NonceTx{sent}
+	sc.Checks = []chain.Check{check}
+	sc.Passed = check.Passed
+	return sc
+}
+
+// allMined reports whether a scenario left nothing in the pool.
+func allMined(sc NonceScenario) bool {
+	if sc.Error != "" {
+		return false
+	}
+	for _, tx := range sc.Transactions {
+		if tx.SendError == "" && !tx.Mined {
+			return false
+		}
+	}
+	return true
+}
+
+func printScenario(sc NonceScenario) {
+	if sc.Error != "" {
+		fmt.Printf("❌ %s: %s\n", sc.Name, sc.Error)
+		return
+	}
+	for _, c := range sc.Checks {
+		switch {
+		case c.Skipped:
+			fmt.Printf("   ⏭️  %s: %s\n", c.Name, c.Note)
+		case c.Passed:
+			fmt.Printf("   ✅ %s: %s\n", c.Name, c.Actual)
+		default:
+			fmt.Printf("   ❌ %s: got %s, want %s %s\n", c.Name, c.Actual, c.Expected, c.Note)
+		}
+	}
+}
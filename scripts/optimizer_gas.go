@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// Invocation is the cost of one sha256Hash call on a build of the wrapper.
+type Invocation struct {
+	InputBytes int    `json:"inputBytes"`
+	Gas        uint64 `json:"gas,omitempty"`
+	Match      bool   `json:"match"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Build is the wrapper compiled with one optimizer setting.
+type Build struct {
+	Setting  string          `json:"setting"`
+	Compiler deploy.Compiler `json:"compiler"`
+	CodeSize int             `json:"codeSize,omitempty"`
+	// DeployGas is the gas used by the deployment, or its estimate with
+	// --estimate-only.
+	DeployGas   uint64       `json:"deployGas,omitempty"`
+	Address     string       `json:"address,omitempty"`
+	Tx          string       `json:"tx,omitempty"`
+	Invocations []Invocation `json:"invocations,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+type OptimizerResult struct {
+	Stage        string  `json:"stage"`
+	Contract     string  `json:"contract"`
+	Source       string  `json:"source"`
+	EstimateOnly bool    `json:"estimateOnly"`
+	Builds       []Build `json:"builds"`
+	Timestamp    string  `json:"timestamp"`
+	RPCURL       string  `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	solc := flag.String("solc", "solc", "solc binary to compile with")
+	source := flag.String("source", "contracts/Sha256Wrapper.sol", "contract source to compile")
+	contract := flag.String("contract", "Sha256Wrapper", "contract to compile, deploy and call")
+	runsFlag := flag.String("runs", "1,200,10000", "comma-separated optimizer runs; 0 compiles unoptimized")
+	viaIRFlag := flag.String("via-ir", "both", "via-IR pipeline: off, on or both")
+	sizesFlag := flag.String("sizes", "0,32,1024", "comma-separated sha256Hash input sizes to price")
+	estimateOnly := flag.Bool("estimate-only", false, "estimate the deployments instead of sending them")
+	gasLimit := flag.Uint64("gas", 3_000_000, "gas limit of each deployment")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Gas, tags.Writes}) {
+		fmt.Printf("⏭️  Optimizer gas report skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	if _, err := exec.LookPath(*solc); err != nil {
+		log.Fatalf("❌ %s not found: the optimizer report compiles the contract itself (%v)", *solc, err)
+	}
+
+	runs, err := parseIntList(*runsFlag, "--runs")
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	sizes, err := parseIntList(*sizesFlag, "--sizes")
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	var viaIR []bool
+	switch *viaIRFlag {
+	case "off":
+		viaIR = []bool{false}
+	case "on":
+		viaIR = []bool{true}
+	case "both":
+		viaIR = []bool{false, true}
+	default:
+		log.Fatalf("❌ invalid --via-ir %q (want off, on or both)", *viaIRFlag)
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if !*estimateOnly {
+		if err := chain.CheckWritable(); err != nil {
+			log.Fatalf("❌ %v (pass --estimate-only to price the deployments without sending them)", err)
+		}
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+
+	var sender *chain.Sender
+	if !*estimateOnly {
+		if sender, err = chain.NewRoleSender(ctx, client, chain.RoleDeploy); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	result := OptimizerResult{
+		Stage:        "Deployment Gas Across Optimizer Settings",
+		Contract:     *contract,
+		Source:       *source,
+		EstimateOnly: *estimateOnly,
+		RPCURL:       rpcURL,
+	}
+	for _, c := range deploy.OptimizerMatrix(runs, viaIR) {
+		b := Build{Setting: c.Label(), Compiler: c}
+		if err := measureBuild(ctx, client, sender, *solc, *source, *contract, *gasLimit, sizes, &b); err != nil {
+			if ctx.Err() != nil {
+				log.Fatalf("❌ Interrupted: %v", ctx.Err())
+			}
+			b.Error = err.Error()
+			fmt.Printf("❌ %s: %v\n", b.Setting, err)
+		} else {
+			fmt.Printf("⛽ %s: %s, deployment %s gas\n", b.Setting, output.Bytes(b.CodeSize), output.Count(b.DeployGas))
+		}
+		result.Builds = append(result.Builds, b)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_optimizer.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+
+	printBuilds(result.Builds, sizes)
+	fmt.Println("\n📝 Results saved to results_optimizer.json")
+	for _, b := range result.Builds {
+		if b.Error != "" {
+			os.Exit(1)
+		}
+		for _, inv := range b.Invocations {
+			if !inv.Match {
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+// measureBuild compiles the contract with b's settings, deploys it (or
+// estimates the deployment) and prices sha256Hash on inputs of each size.
+// Invocations are estimated and checked with eth_call, so they cost
+// nothing; with --estimate-only there is no deployment to call.
+func measureBuild(ctx context.Context, client *ethclient.Client, sender *chain.Sender, solc, source, contract string, gas uint64, sizes []int, b *Build) error {
+	compiled, err := deploy.Compile(ctx, solc, source, contract, b.Compiler)
+	if err != nil {
+		return err
+	}
+	b.Compiler.Version = compiled.Solc
+	parsedABI, err := abi.JSON(strings.NewReader(string(compiled.ABI)))
+	if err != nil {
+		return fmt.Errorf("failed to parse ABI: %v", err)
+	}
+
+	if sender == nil {
+		b.DeployGas, err = client.EstimateGas(ctx, ethereum.CallMsg{Data: compiled.Bin})
+		if err != nil {
+			return fmt.Errorf("failed to estimate deployment: %v", err)
+		}
+		// The runtime code is what the init code returns
+		code, err := client.CallContract(ctx, ethereum.CallMsg{Data: compiled.Bin}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to run init code: %v", err)
+		}
+		b.CodeSize = len(code)
+		return nil
+	}
+
+	tx, receipt, err := sender.Send(chain.WithContract(ctx, contract+" "+b.Setting), nil, compiled.Bin, gas)
+	if err != nil {
+		return fmt.Errorf("deployment failed: %v", err)
+	}
+	b.Tx, b.DeployGas = tx.Hash().Hex(), receipt.GasUsed
+	if receipt.Status != 1 {
+		return fmt.Errorf("deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	address := receipt.ContractAddress
+	b.Address = address.Hex()
+	if b.CodeSize, err = precompile.CodeSize(ctx, client, address); err != nil {
+		return err
+	}
+
+	for _, size := range sizes {
+		input := make([]byte, size)
+		for i := range input {
+			input[i] = byte(i)
+		}
+		inv := Invocation{InputBytes: size}
+		data, err := parsedABI.Pack("sha256Hash", input)
+		if err != nil {
+			return fmt.Errorf("failed to pack sha256Hash: %v", err)
+		}
+		if inv.Gas, err = client.EstimateGas(ctx, ethereum.CallMsg{From: sender.From, To: &address, Data: data}); err != nil {
+			inv.Error = err.Error()
+		} else if outcome, err := precompile.CallWrapper(ctx, client, &parsedABI, address, input); err != nil {
+			inv.Error = err.Error()
+		} else {
+			inv.Match = outcome.Match()
+		}
+		b.Invocations = append(b.Invocations, inv)
+	}
+	return nil
+}
+
+// printBuilds prints one row per setting and marks the cheapest deployment
+// and the cheapest call of each size.
+func printBuilds(builds []Build, sizes []int) {
+	cheapest := func(cost func(Build) uint64) string {
+		best, label := uint64(0), ""
+		for _, b := range builds {
+			if c := cost(b); c > 0 && (best == 0 || c < best) {
+				best, label = c, b.Setting
+			}
+		}
+		return label
+	}
+	deployBest := cheapest(func(b Build) uint64 { return b.DeployGas })
+	callBest := make([]string, len(sizes))
+	for i := range sizes {
+		callBest[i] = cheapest(func(b Build) uint64 {
+			if i < len(b.Invocations) {
+				return b.Invocations[i].Gas
+			}
+			return 0
+		})
+	}
+
+	mark := func(label, best string) string {
+		if label == best {
+			return "*"
+		}
+		return " "
+	}
+	fmt.Printf("\n📊 %-20s %9s %12s", "setting", "code", "deploy")
+	for _, size := range sizes {
+		fmt.Printf(" %12s", fmt.Sprintf("call %dB", size))
+	}
+	fmt.Println()
+	for _, b := range builds {
+		if b.Error != "" {
+			fmt.Printf("   %-20s %s\n", b.Setting, b.Error)
+			continue
+		}
+		fmt.Printf("   %-20s %9d %11d%s", b.Setting, b.CodeSize, b.DeployGas, mark(b.Setting, deployBest))
+		for i, inv := range b.Invocations {
+			switch {
+			case inv.Error != "":
+				fmt.Printf(" %12s", "error")
+			case !inv.Match:
+				fmt.Printf(" %12s", "mismatch")
+			default:
+				fmt.Printf(" %11d%s", inv.Gas, mark(b.Setting, callBest[i]))
+			}
+		}
+		fmt.Println()
+	}
+	fmt.Println("   * cheapest")
+}
+
+func parseIntList(spec, name string) ([]int, error) {
+	var out []int
+	for _, s := range tags.Parse(spec) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid value %q in %s", s, name)
+		}
+		out = append(out, n)
+	}
+	if len(out) == 0 {
+		return nil, errors.New(name + " is empty")
+	}
+	return out, nil
+}
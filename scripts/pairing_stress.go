@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/bench"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/zkcounters"
+)
+
+type PairingResult struct {
+	Stage      string `json:"stage"`
+	Precompile string `json:"precompile"`
+	Runs       int    `json:"runs"`
+	Steps      []Step `json:"steps"`
+	// MaxPairsPerCall is the largest pair count accepted; FirstRejected
+	// the smallest rejected, zero if the sweep hit --max-pairs first.
+	MaxPairsPerCall int       `json:"maxPairsPerCall"`
+	FirstRejected   int       `json:"firstRejected,omitempty"`
+	PerBatch        *Capacity `json:"perBatch,omitempty"`
+	PerBlock        *Capacity `json:"perBlock,omitempty"`
+	WrongResults    int       `json:"wrongResults"`
+	CountersMissing string    `json:"countersMissing,omitempty"`
+	Timestamp       string    `json:"timestamp"`
+	RPCURL          string    `json:"rpcUrl"`
+}
+
+// Step is the measurement at one pair count.
+type Step struct {
+	Pairs       int                      `json:"pairs"`
+	InputBytes  int                      `json:"inputBytes"`
+	ExpectedGas uint64                   `json:"expectedGas"`
+	EstimateGas uint64                   `json:"estimateGas,omitempty"`
+	MedianMs    float64                  `json:"medianMs,omitempty"`
+	Accepted    bool                     `json:"accepted"`
+	Reason      string                   `json:"reason,omitempty"`
+	Counters    []zkcounters.Utilization `json:"counters,omitempty"`
+
+	estimate *zkcounters.Estimate
+	wrong    bool
+}
+
+// Capacity is how many maximal pairing calls fit in a batch or block.
+type Capacity struct {
+	Calls      int    `json:"calls"`
+	Pairs      int    `json:"pairs"`
+	LimitedBy  string `json:"limitedBy"`
+	PairsEach  int    `json:"pairsPerCall"`
+	GasLimit   uint64 `json:"gasLimit,omitempty"`
+	GasPerCall uint64 `json:"gasPerCall,omitempty"`
+}
+
+func main() {
+	output.Setup()
+
+	start := flag.Int("start", 1, "pair count to start the sweep at")
+	maxPairs := flag.Int("max-pairs", 2048, "stop the sweep at this many pairs")
+	runs := flag.Int("runs", 3, "timed calls per pair count; the median is reported")
+	useCounters := flag.Bool("counters", true, "measure zk counters with zkevm_estimateCounters")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Slow}) {
+		fmt.Printf("⏭️  Pairing stress skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+	if *start <= 0 || *maxPairs < *start || *runs <= 0 {
+		log.Fatal("❌ --start, --runs must be positive and --max-pairs at least --start")
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_pairing.json")
+
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Fatalf("❌ Failed to get latest block: %v", err)
+	}
+
+	result := PairingResult{Stage: "PairingStress", Precompile: "0x08", Runs: *runs, RPCURL: rpcURL}
+	sweep := &sweeper{ctx: ctx, client: client, runs: *runs, counters: *useCounters, result: &result}
+
+	// Double the pair count until the node rejects it, then bisect
+	// between the last accepted and the first rejected count
+	fmt.Printf("🔍 Sweeping pair counts from %d to %d...\n", *start, *maxPairs)
+	accepted, rejected := 0, 0
+	for n := *start; ; n = min(n*2, *maxPairs) {
+		if !sweep.measure(n).Accepted {
+			rejected = n
+			break
+		}
+		accepted = n
+		if n == *maxPairs {
+			break
+		}
+	}
+	if rejected > 0 && accepted > 0 {
+		fmt.Printf("🔍 Bisecting between %d and %d pairs...\n", accepted, rejected)
+		for rejected-accepted > 1 {
+			mid := (accepted + rejected) / 2
+			if sweep.measure(mid).Accepted {
+				accepted = mid
+			} else {
+				rejected = mid
+			}
+		}
+	}
+	result.MaxPairsPerCall = accepted
+	result.FirstRejected = rejected
+
+	if accepted > 0 {
+		best := sweep.steps[accepted]
+		result.PerBlock = perBlock(best, header.GasLimit)
+		result.PerBatch = perBatch(best)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	printPairingSummary(result)
+
+	if err := savePairingResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Println("📝 Results saved to results_pairing.json")
+
+	if result.WrongResults > 0 {
+		log.Fatalf("❌ %d pair counts returned a wrong pairing result", result.WrongResults)
+	}
+}
+
+// sweeper measures pair counts, remembering each one.
+type sweeper struct {
+	ctx      context.Context
+	client   *ethclient.Client
+	runs     int
+	counters bool
+	result   *PairingResult
+	steps    map[int]*Step
+}
+
+func (s *sweeper) measure(n int) *Step {
+	if s.steps == nil {
+		s.steps = map[int]*Step{}
+	}
+	input, expected := precompile.PairingInput(n)
+	step := &Step{Pairs: n, InputBytes: len(input), ExpectedGas: precompile.PairingGas(n)}
+	s.steps[n] = step
+	defer func() {
+		s.result.Steps = append(s.result.Steps, *step)
+		printStep(step)
+	}()
+
+	// Timed calls, every answer checked
+	samples := make([]time.Duration, 0, s.runs)
+	for i := 0; i < s.runs; i++ {
+		callStart := time.Now()
+		got, err := precompile.CallPairing(s.ctx, s.client, input)
+		if err != nil {
+			step.Reason = err.Error()
+			return step
+		}
+		samples = append(samples, time.Since(callStart))
+		if got != expected {
+			step.wrong = true
+			step.Reason = fmt.Sprintf("returned %v, expected %v", got, expected)
+			s.result.WrongResults++
+			return step
+		}
+	}
+	step.MedianMs = bench.Summarize(bench.Millis(samples), 0).Median
+
+	to := precompile.PairingAddress
+	msg := ethereum.CallMsg{To: &to, Data: input}
+	gas, err := s.client.EstimateGas(s.ctx, msg)
+	if err != nil {
+		step.Reason = fmt.Sprintf("gas estimate failed: %v", err)
+		return step
+	}
+	step.EstimateGas = gas
+
+	if s.counters {
+		estimate, err := zkcounters.EstimateCall(s.ctx, s.client, msg)
+		if err != nil {
+			// Not a cdk-erigon node, or the method isn't exposed
+			log.Printf("⚠️  zk counters unavailable, continuing without: %v", err)
+			s.counters = false
+			s.result.CountersMissing = err.Error()
+		} else {
+			step.estimate = estimate
+			step.Counters = estimate.Utilization()
+			if estimate.OOC != "" {
+				step.Reason = "out of counters: " + estimate.OOC
+				return step
+			}
+		}
+	}
+	step.Accepted = true
+	return step
+}
+
+// perBlock fills the block gas limit with copies of the call.
+func perBlock(step *Step, gasLimit uint64) *Capacity {
+	if step.EstimateGas == 0 {
+		return nil
+	}
+	calls := int(gasLimit / step.EstimateGas)
+	return &Capacity{
+		Calls:      calls,
+		Pairs:      calls * step.Pairs,
+		LimitedBy:  "gas",
+		PairsEach:  step.Pairs,
+		GasLimit:   gasLimit,
+		GasPerCall: step.EstimateGas,
+	}
+}
+
+// perBatch fills the batch's zk counters with copies of the call.
+func perBatch(step *Step) *Capacity {
+	if step.estimate == nil {
+		return nil
+	}
+	calls, counter := step.estimate.PerBatch()
+	if counter == "" {
+		return nil
+	}
+	return &Capacity{Calls: calls, Pairs: calls * step.Pairs, LimitedBy: counter, PairsEach: step.Pairs}
+}
+
+func printStep(step *Step) {
+	switch {
+	case step.Accepted:
+		line := fmt.Sprintf("✅ %d pairs: %.3f ms, %d gas", step.Pairs, step.MedianMs, step.EstimateGas)
+		if len(step.Counters) > 0 {
+			top := step.Counters[0]
+			line += fmt.Sprintf(", %s at %.1f%% of batch", top.Counter, top.Fraction*100)
+		}
+		fmt.Println(line)
+	case step.wrong:
+		fmt.Printf("❌ %d pairs: %s\n", step.Pairs, step.Reason)
+	default:
+		fmt.Printf("🛑 %d pairs rejected: %s\n", step.Pairs, step.Reason)
+	}
+}
+
+func printPairingSummary(result PairingResult) {
+	fmt.Println()
+	if result.MaxPairsPerCall == 0 {
+		fmt.Println("📊 No pair count was accepted")
+		return
+	}
+	if result.FirstRejected > 0 {
+		fmt.Printf("📊 Max pairs per call: %d (%d rejected)\n", result.MaxPairsPerCall, result.FirstRejected)
+	} else {
+		fmt.Printf("📊 Max pairs per call: at least %d (sweep limit)\n", result.MaxPairsPerCall)
+	}
+	if c := result.PerBatch; c != nil {
+		fmt.Printf("📊 Per batch: %d calls, %d pairs (limited by %s)\n", c.Calls, c.Pairs, c.LimitedBy)
+	}
+	if c := result.PerBlock; c != nil {
+		fmt.Printf("📊 Per block: %d calls, %d pairs (%d gas each, limit %d)\n", c.Calls, c.Pairs, c.GasPerCall, c.GasLimit)
+	}
+}
+
+func savePairingResult(result PairingResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_pairing.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
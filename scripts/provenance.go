@@ -0,0 +1,299 @@
+//suite:writes transactions=1 gas=2000000
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/cases"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// ProvenanceAnswer is what the precompile answered with its input taken
+// from one source.
+type ProvenanceAnswer struct {
+	Source   cases.Source  `json:"source"`
+	Success  bool          `json:"success"`
+	Returned hexutil.Bytes `json:"returned,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ProvenanceCase is one input fed to a precompile from every source. It
+// passes when every source answered alike and as expected.
+type ProvenanceCase struct {
+	Name       string             `json:"name"`
+	Precompile string             `json:"precompile"`
+	Input      hexutil.Bytes      `json:"input"`
+	Expected   hexutil.Bytes      `json:"expected"`
+	Answers    []ProvenanceAnswer `json:"answers"`
+	// Consistent is set when every source answered the same, right or
+	// wrong.
+	Consistent bool `json:"consistent"`
+	Match      bool `json:"match"`
+}
+
+type ProvenanceResult struct {
+	Stage      string           `json:"stage"`
+	Contract   string           `json:"contract"`
+	Cases      []ProvenanceCase `json:"cases"`
+	Matches    int              `json:"matches"`
+	Mismatches int              `json:"mismatches"`
+	// Inconsistent counts the cases whose answer depended on the source.
+	Inconsistent int    `json:"inconsistent"`
+	Timestamp    string `json:"timestamp"`
+	RPCURL       string `json:"rpcUrl"`
+}
+
+// provenanceInput is a precompile input with the answer expected of it.
+type provenanceInput struct {
+	name       string
+	precompile common.Address
+	input      []byte
+	expected   []byte
+}
+
+func main() {
+	output.Setup()
+
+	contractFlag := flag.String("contract", "", "PrecompileCases address to use instead of the saved or a freshly deployed one")
+	gasLimit := flag.Uint64("gas", 2_000_000, "gas limit of the PrecompileCases deployment")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_provenance.json")
+
+	contract, err := resolveCasesContract(ctx, client, *contractFlag, *gasLimit)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("📌 Using PrecompileCases at %s\n", contract.Hex())
+
+	inputs, err := provenanceInputs(tagFilter)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	result := ProvenanceResult{
+		Stage:    "Provenance - Precompile Input From Calldata, Memory and Storage",
+		Contract: contract.Hex(),
+		RPCURL:   rpcURL,
+	}
+	fmt.Printf("🔀 Feeding %d inputs from %d sources\n", len(inputs), len(cases.Sources))
+	for _, in := range inputs {
+		c := runProvenanceCase(ctx, client, contract, in)
+		if !c.Consistent {
+			result.Inconsistent++
+		}
+		if c.Match {
+			result.Matches++
+		} else {
+			result.Mismatches++
+		}
+		result.Cases = append(result.Cases, c)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := saveProvenanceResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Provenance results:")
+	for _, c := range result.Cases {
+		if c.Match {
+			continue
+		}
+		fmt.Printf("❌ %s (expected %s):\n", c.Name, c.Expected)
+		for _, a := range c.Answers {
+			if a.Error != "" {
+				fmt.Printf("   %-8s error: %s\n", a.Source, a.Error)
+			} else {
+				fmt.Printf("   %-8s success=%t %s\n", a.Source, a.Success, a.Returned)
+			}
+		}
+	}
+	fmt.Printf("✅ Matches:      %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches:   %d\n", result.Mismatches)
+	fmt.Printf("🔀 Inconsistent: %d\n", result.Inconsistent)
+	fmt.Println("\n📝 Results saved to results_provenance.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// runProvenanceCase feeds in from every source. Every source must succeed
+// with the expected output; an answer that depends on the source is also
+// reported as inconsistent.
+func runProvenanceCase(ctx context.Context, client *ethclient.Client, contract common.Address, in provenanceInput) ProvenanceCase {
+	c := ProvenanceCase{
+		Name:       in.name,
+		Precompile: in.precompile.Hex(),
+		Input:      in.input,
+		Expected:   in.expected,
+		Consistent: true,
+		Match:      true,
+	}
+	for i, src := range cases.Sources {
+		a := ProvenanceAnswer{Source: src}
+		res, err := cases.Call(ctx, client, contract, src, in.precompile, in.input)
+		if err != nil {
+			a.Error = err.Error()
+		} else {
+			a.Success, a.Returned = res.Success, res.Output
+		}
+		if i > 0 {
+			first := c.Answers[0]
+			if a.Error != first.Error || a.Success != first.Success || !bytes.Equal(a.Returned, first.Returned) {
+				c.Consistent = false
+			}
+		}
+		if a.Error != "" || !a.Success || !bytes.Equal(a.Returned, in.expected) {
+			c.Match = false
+		}
+		c.Answers = append(c.Answers, a)
+	}
+	return c
+}
+
+// provenanceInputs covers each precompile with inputs below, at and above
+// a word boundary, since the memory and storage paths copy whole words.
+// The SHA-256 and identity inputs honour the tag filters.
+func provenanceInputs(filter *tags.Filter) ([]provenanceInput, error) {
+	vectors := vector.Select([]vector.Vector{
+		vector.New([]byte("hello world"), tags.Smoke),
+		vector.New([]byte(""), tags.Smoke),
+		vector.New(bytes.Repeat([]byte{0xab}, 32), tags.Binary),
+		vector.New(bytes.Repeat([]byte{0xcd}, 33), tags.Binary),
+		vector.New([]byte(strings.Repeat("provenance", 30))),
+	}, filter)
+
+	identity := common.HexToAddress("0x04")
+	var inputs []provenanceInput
+	for _, v := range vectors {
+		input := v.Bytes()
+		sum := sha256.Sum256(input)
+		inputs = append(inputs,
+			provenanceInput{name: "sha256 " + v.Display(), precompile: precompile.SHA256Address, input: input, expected: sum[:]},
+			provenanceInput{name: "identity " + v.Display(), precompile: identity, input: input, expected: input},
+		)
+	}
+
+	sigs, err := precompile.RandomSignatures(1)
+	if err != nil {
+		return nil, err
+	}
+	inputs = append(inputs, provenanceInput{name: "ecrecover " + sigs[0].Signer.Hex(), precompile: precompile.ECRecoverAddress,
+		input: sigs[0].Input(), expected: common.LeftPadBytes(sigs[0].Signer.Bytes(), 32)})
+
+	m := precompile.ModExp{Base: []byte{3}, Exp: []byte{0xff, 0xff}, Mod: big.NewInt(1_000_000_007).Bytes()}
+	inputs = append(inputs, provenanceInput{name: "modexp 3^65535 % 1000000007", precompile: precompile.ModExpAddress,
+		input: m.Input(), expected: m.Expected()})
+
+	pairing, _ := precompile.PairingInput(2)
+	inputs = append(inputs, provenanceInput{name: "pairing 2 pairs", precompile: precompile.PairingAddress,
+		input: pairing, expected: common.LeftPadBytes([]byte{1}, 32)})
+	return inputs, nil
+}
+
+// resolveCasesContract picks the PrecompileCases to call: the --contract
+// address, the one saved in deployed_cases_address.txt, or a fresh
+// deployment of artifacts/PrecompileCases, in that order.
+func resolveCasesContract(ctx context.Context, client *ethclient.Client, override string, gas uint64) (common.Address, error) {
+	if override != "" {
+		if !common.IsHexAddress(override) {
+			return common.Address{}, fmt.Errorf("invalid --contract address %q", override)
+		}
+		address := common.HexToAddress(override)
+		if _, err := precompile.CodeSize(ctx, client, address); err != nil {
+			return common.Address{}, err
+		}
+		return address, nil
+	}
+	if address, err := paths.ReadAddress(paths.Work("deployed_cases_address.txt")); err == nil {
+		if code, err := client.CodeAt(ctx, address, nil); err == nil && len(code) > 0 {
+			return address, nil
+		}
+	}
+
+	bytecode, err := paths.ReadHex(paths.Artifact("PrecompileCases.bin"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to read bytecode (compile contracts/PrecompileCases.sol first): %v", err)
+	}
+	if err := deploy.VerifyArtifact(paths.Artifact("PrecompileCases")); err != nil {
+		return common.Address{}, fmt.Errorf("refusing to deploy: %v", err)
+	}
+	if err := chain.CheckWritable(); err != nil {
+		return common.Address{}, fmt.Errorf("no PrecompileCases deployed and can't deploy one (pass --contract): %v", err)
+	}
+	sender, err := chain.NewRoleSender(ctx, client, chain.RoleDeploy)
+	if err != nil {
+		return common.Address{}, err
+	}
+	fmt.Printf("📨 Deploying PrecompileCases from %s...\n", sender.From.Hex())
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "PrecompileCases"), nil, common.FromHex(bytecode), gas)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return common.Address{}, fmt.Errorf("PrecompileCases deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	if err := paths.WriteFile(paths.Work("deployed_cases_address.txt"), []byte(receipt.ContractAddress.Hex())); err != nil {
+		return common.Address{}, fmt.Errorf("failed to save deployed address: %v", err)
+	}
+	return receipt.ContractAddress, nil
+}
+
+func saveProvenanceResult(result ProvenanceResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_provenance.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/capability"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/history"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// recordedResult is the part of a stage 3 result needed to replay it. Input
+// is decoded loosely so results written before inputs were hex-encoded can
+// still be replayed.
+type recordedResult struct {
+	Input           string   `json:"input"`
+	ExpectedHash    string   `json:"expectedHash"`
+	ContractHash    string   `json:"contractHash"`
+	Match           bool     `json:"match"`
+	ContractAddress string   `json:"contractAddress"`
+	Tags            []string `json:"tags"`
+}
+
+// ReplayCase compares one recorded result with the same input re-executed.
+type ReplayCase struct {
+	vector.Vector
+	Target       string `json:"target"`
+	ExpectedHash string `json:"expectedHash"`
+	RecordedHash string `json:"recordedHash"`
+	ReplayedHash string `json:"replayedHash,omitempty"`
+	RecordedOK   bool   `json:"recordedMatch"`
+	ReplayedOK   bool   `json:"replayedMatch"`
+	SameAsBefore bool   `json:"sameAsRecorded"`
+	Error        string `json:"error,omitempty"`
+}
+
+type ReplayResult struct {
+	Stage  string `json:"stage"`
+	Source string `json:"source"`
+	RPCURL string `json:"rpcUrl"`
+	// PinnedTo is the anchor of the recorded run calls were pinned to
+	// with --pin.
+	PinnedTo  *anchor.Point `json:"pinnedTo,omitempty"`
+	Cases     []ReplayCase  `json:"cases"`
+	Same      int           `json:"same"`
+	Different int           `json:"different"`
+	Errors    int           `json:"errors"`
+	// Fixed and Regressed count cases whose correctness flipped.
+	Fixed     int    `json:"fixed"`
+	Regressed int    `json:"regressed"`
+	Timestamp string `json:"timestamp"`
+}
+
+func main() {
+	output.Setup()
+
+	rpcFlag := flag.String("rpc", "", "endpoint to replay against (defaults to RPC_HOST/RPC_PORT from .env)")
+	wrapperFlag := flag.String("wrapper", "", "call this wrapper address instead of the recorded one (for a different network)")
+	raw := flag.Bool("raw", false, "replay against precompile 0x02 directly instead of a wrapper")
+	pin := flag.String("pin", "", "call in the state of the recorded run's start or end anchor block (start, end), by block hash")
+	out := flag.String("out", paths.Work("results_replay.json"), "comparison output file")
+	sinceDeployment := flag.Bool("since-deployment", false, "instead of replaying results, call the wrapper with a canary input at every --every-th block since its deployment (needs historical state)")
+	every := flag.Uint64("every", 100, "with --since-deployment, blocks between two sampled heights")
+	inputFlag := flag.String("input", "hello world", "with --since-deployment, canary input passed to sha256 (0x-prefixed values are hex-decoded)")
+	bisect := flag.Bool("bisect", true, "with --since-deployment, narrow each change of the answer down to a single block")
+	historyOut := flag.String("history-out", paths.Work("results_replay_history.json"), "with --since-deployment, output file, also compared with the previous run's")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: go run scripts/replay.go [flags] results_stage3.json")
+		fmt.Fprintln(flag.CommandLine.Output(), "       go run scripts/replay.go --since-deployment [flags]")
+		flag.PrintDefaults()
+	}
+	envFiles := envfile.Flags()
+	flag.Parse()
+	if *sinceDeployment != (flag.NArg() == 0) || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *sinceDeployment && *raw {
+		log.Fatal("❌ --since-deployment replays a wrapper, not the raw precompile")
+	}
+
+	var source string
+	var recorded []recordedResult
+	if !*sinceDeployment {
+		source = flag.Arg(0)
+		var err error
+		if recorded, err = loadRecorded(source); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("📂 Loaded %d recorded results from %s\n", len(recorded), source)
+	}
+
+	var pinned *anchor.Point
+	if *pin != "" {
+		if *sinceDeployment {
+			log.Fatal("❌ --pin replays a results file, not --since-deployment")
+		}
+		run, err := anchor.Lookup(source)
+		if err != nil {
+			log.Fatalf("❌ --pin: %v", err)
+		}
+		switch *pin {
+		case "start":
+			pinned = run.Start
+		case "end":
+			pinned = run.End
+		default:
+			log.Fatalf("❌ --pin must be start or end, not %q", *pin)
+		}
+		if pinned == nil {
+			log.Fatalf("❌ --pin: no %s anchor recorded for %s", *pin, source)
+		}
+		fmt.Printf("📌 Pinned to the %s anchor of %s: block %d (%s)\n", *pin, source, pinned.Block, pinned.Hash)
+	}
+
+	rpcURL := *rpcFlag
+	if rpcURL == "" {
+		// Load environment variables
+		if err := envFiles.Load(); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		rpcURL = fmt.Sprintf("http://%s:%s", os.Getenv("RPC_HOST"), os.Getenv("RPC_PORT"))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+
+	if *sinceDeployment {
+		input, err := vector.Parse(*inputFlag)
+		if err != nil {
+			log.Fatalf("❌ --input: %v", err)
+		}
+		replayHistory(ctx, client, rpcURL, *wrapperFlag, input, *every, *bisect, *historyOut)
+		return
+	}
+
+	result := ReplayResult{
+		Stage:    "Replay - Stage 3 Results",
+		Source:   source,
+		RPCURL:   rpcURL,
+		PinnedTo: pinned,
+	}
+	// Recorded results may come from wrappers of different ABI versions
+	wrappers := map[common.Address]*abi.ABI{}
+	for _, rec := range recorded {
+		target := rec.ContractAddress
+		switch {
+		case *raw:
+			target = "0x0000000000000000000000000000000000000002"
+		case *wrapperFlag != "":
+			target = *wrapperFlag
+		}
+
+		var parsedABI *abi.ABI
+		if !*raw {
+			if parsedABI, err = wrapperABI(ctx, client, wrappers, common.HexToAddress(target)); err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+		}
+		rc, err := replayCase(ctx, client, parsedABI, common.HexToAddress(target), rec, pinned)
+		if err != nil {
+			log.Fatal(err)
+		}
+		switch {
+		case rc.Error != "":
+			result.Errors++
+		case rc.SameAsBefore:
+			result.Same++
+		default:
+			result.Different++
+		}
+		if rc.Error == "" && rc.RecordedOK != rc.ReplayedOK {
+			if rc.ReplayedOK {
+				result.Fixed++
+			} else {
+				result.Regressed++
+			}
+		}
+		result.Cases = append(result.Cases, rc)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	// Save results
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(*out, file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+
+	fmt.Println("\n🔁 Replay comparison:")
+	for _, rc := range result.Cases {
+		switch {
+		case rc.Error != "":
+			fmt.Printf("⚠️  %s via %s: %s\n", rc.Display(), rc.Target, rc.Error)
+		case rc.SameAsBefore:
+			fmt.Printf("✅ %s via %s: same as recorded\n", rc.Display(), rc.Target)
+		default:
+			fmt.Printf("❌ %s via %s\n  Recorded: %s\n  Replayed: %s\n  Expected: %s\n",
+				rc.Display(), rc.Target, rc.RecordedHash, rc.ReplayedHash, rc.ExpectedHash)
+		}
+	}
+	fmt.Printf("\n📊 Same: %d, Different: %d, Errors: %d (fixed %d, regressed %d)\n",
+		result.Same, result.Different, result.Errors, result.Fixed, result.Regressed)
+	fmt.Printf("📝 Results saved to %s\n", *out)
+
+	if result.Different > 0 || result.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func loadRecorded(path string) ([]recordedResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to read %s: %v", path, err)
+	}
+	var recorded []recordedResult
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("❌ %s is not a stage 3 results file: %v", path, err)
+	}
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("❌ %s contains no results", path)
+	}
+	return recorded, nil
+}
+
+// replayCase re-executes rec at the head, or in the state of the block of at
+// when the replay is pinned.
+func replayCase(ctx context.Context, client *ethclient.Client, parsedABI *abi.ABI, target common.Address, rec recordedResult, at *anchor.Point) (ReplayCase, error) {
+	input, err := vector.Parse(rec.Input)
+	if err != nil {
+		return ReplayCase{}, fmt.Errorf("❌ %v", err)
+	}
+	expected := fmt.Sprintf("%x", sha256.Sum256(input))
+	rc := ReplayCase{
+		Vector:       vector.New(input, rec.Tags...),
+		Target:       target.Hex(),
+		ExpectedHash: expected,
+		RecordedHash: rec.ContractHash,
+		RecordedOK:   rec.ContractHash == expected,
+	}
+
+	callData := input
+	if parsedABI != nil {
+		if callData, err = parsedABI.Pack("sha256Hash", input); err != nil {
+			return rc, fmt.Errorf("failed to pack ABI call: %v", err)
+		}
+	}
+	var out []byte
+	if at != nil {
+		// Pinned by hash: a block reorganized away fails the call rather
+		// than answering from another state
+		var ret hexutil.Bytes
+		args := map[string]any{"to": target, "data": hexutil.Bytes(callData)}
+		err = client.Client().CallContext(ctx, &ret, "eth_call", args, at.Ref())
+		out = ret
+	} else {
+		out, err = client.CallContract(ctx, ethereum.CallMsg{To: &target, Data: callData}, nil)
+	}
+	if err != nil {
+		rc.Error = fmt.Sprintf("call failed: %v", err)
+		return rc, nil
+	}
+
+	if parsedABI != nil {
+		unpacked, err := parsedABI.Unpack("sha256Hash", out)
+		if err != nil {
+			rc.Error = fmt.Sprintf("failed to unpack result: %v", err)
+			return rc, nil
+		}
+		hash, ok := unpacked[0].([32]byte)
+		if !ok {
+			rc.Error = fmt.Sprintf("unexpected return type: %T", unpacked[0])
+			return rc, nil
+		}
+		out = hash[:]
+	}
+
+	rc.ReplayedHash = fmt.Sprintf("%x", out)
+	rc.ReplayedOK = rc.ReplayedHash == expected
+	rc.SameAsBefore = rc.ReplayedHash == rc.RecordedHash
+	return rc, nil
+}
+
+// wrapperABI detects the ABI version of the wrapper at address, once per
+// address.
+func wrapperABI(ctx context.Context, client *ethclient.Client, known map[common.Address]*abi.ABI, address common.Address) (*abi.ABI, error) {
+	if parsed, ok := known[address]; ok {
+		return parsed, nil
+	}
+	version, err := precompile.ResolveWrapper(ctx, client, address)
+	if err != nil {
+		return nil, err
+	}
+	if !version.Current() {
+		fmt.Printf("⚠️  Wrapper at %s predates the current artifacts, using ABI %s\n", address.Hex(), version)
+	}
+	known[address] = version.ABI()
+	return known[address], nil
+}
+
+// HistoryResult is the outcome of --since-deployment: the canary answered
+// at heights from the wrapper's deployment to the head.
+type HistoryResult struct {
+	Stage        string `json:"stage"`
+	RPCURL       string `json:"rpcUrl"`
+	ChainID      string `json:"chainId"`
+	Wrapper      string `json:"wrapper"`
+	ABI          string `json:"abi"`
+	Input        string `json:"input"`
+	ExpectedHash string `json:"expectedHash"`
+	Deployment   uint64 `json:"deploymentBlock"`
+	// DeploymentSource is "ledger" when the block came from the deployment
+	// ledger and "bisected" when it was found from the wrapper's code.
+	DeploymentSource string `json:"deploymentSource"`
+	Head             uint64 `json:"head"`
+	Every            uint64 `json:"every"`
+	history.Report
+	// Changes are heights sampled by the previous run too whose state root
+	// or answer is different now.
+	Changes   []history.Change `json:"changes,omitempty"`
+	Timestamp string           `json:"timestamp"`
+}
+
+// replayHistory calls the wrapper with input at every every-th block since
+// its deployment and reports each height the answer changed at.
+func replayHistory(ctx context.Context, client *ethclient.Client, rpcURL, wrapper string, input []byte, every uint64, bisect bool, out string) {
+	if wrapper == "" {
+		address, err := paths.ReadAddress(paths.Work("deployed_address.txt"))
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		wrapper = address.Hex()
+	}
+	address := common.HexToAddress(wrapper)
+
+	caps, err := capability.Detect(ctx, client)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if !caps.Has(capability.HistoricalState) {
+		log.Fatalf("❌ --since-deployment needs historical state: %s", caps.Probes[capability.HistoricalState].Reason)
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatalf("❌ Failed to get chain ID: %v", err)
+	}
+
+	expected := sha256.Sum256(input)
+	result := HistoryResult{
+		Stage:            "Replay - Canary Since Deployment",
+		RPCURL:           rpcURL,
+		ChainID:          chainID.String(),
+		Wrapper:          address.Hex(),
+		Input:            hexutil.Encode(input),
+		ExpectedHash:     fmt.Sprintf("%x", expected),
+		Head:             caps.Head,
+		Every:            every,
+		DeploymentSource: "ledger",
+	}
+	if d := ledgerDeployment(result.ChainID, address); d != nil {
+		result.Deployment = d.Block
+	} else {
+		result.DeploymentSource = "bisected"
+		if result.Deployment, err = history.DeploymentBlock(ctx, client, address, caps.Head); err != nil {
+			log.Fatalf("❌ Failed to find the deployment block of %s: %v", address.Hex(), err)
+		}
+	}
+	fmt.Printf("📦 Wrapper %s deployed at block %d (%s)\n", address.Hex(), result.Deployment, result.DeploymentSource)
+
+	version, err := precompile.ResolveWrapper(ctx, client, address)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if !version.Current() {
+		fmt.Printf("⚠️  Wrapper at %s predates the current artifacts, using ABI %s\n", address.Hex(), version)
+	}
+	result.ABI = version.String()
+	parsedABI := version.ABI()
+	callData, err := parsedABI.Pack(precompile.WrapperMethod, input)
+	if err != nil {
+		log.Fatalf("❌ Failed to pack ABI call: %v", err)
+	}
+	call := func(ctx context.Context, block *big.Int) ([]byte, error) {
+		out, err := client.CallContract(ctx, ethereum.CallMsg{To: &address, Data: callData}, block)
+		if err != nil {
+			return nil, err
+		}
+		unpacked, err := parsedABI.Unpack(precompile.WrapperMethod, out)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack result: %v", err)
+		}
+		hash, ok := unpacked[0].([32]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected return type: %T", unpacked[0])
+		}
+		return hash[:], nil
+	}
+
+	heights := history.Heights(result.Deployment, caps.Head, every)
+	fmt.Printf("🔎 Replaying the canary at %d heights from block %d to %d\n", len(heights), result.Deployment, caps.Head)
+	result.Report = history.Replay(ctx, client, call, expected[:], heights, bisect)
+	if ctx.Err() != nil {
+		log.Fatalf("❌ Interrupted: %v", ctx.Err())
+	}
+
+	// The previous run of the same wrapper sampled some of the same heights
+	if data, err := os.ReadFile(out); err == nil {
+		var previous HistoryResult
+		if json.Unmarshal(data, &previous) == nil && previous.ChainID == result.ChainID && previous.Wrapper == result.Wrapper && previous.Input == result.Input {
+			result.Changes = result.Report.Changes(previous.Report)
+		}
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(out, file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+
+	fmt.Println("\n🔁 Canary since deployment:")
+	for _, t := range result.Transitions {
+		mark := "⚠️ "
+		if t.Regression {
+			mark = "❌"
+		}
+		fmt.Printf("%s Block %d changed the answer (state root %s)\n  Before (block %d): %s\n  After:  %s\n",
+			mark, t.Block, t.After.StateRoot, t.Before.Block, describeSample(t.Before), describeSample(t.After))
+		if t.Error != "" {
+			fmt.Printf("  %s\n", t.Error)
+		}
+	}
+	for _, c := range result.Changes {
+		fmt.Printf("❌ Block %d changed since the previous run\n  Previous: %s %s\n  Now:      %s %s\n",
+			c.Block, c.Previous.StateRoot, describeSample(c.Previous), c.Current.StateRoot, describeSample(c.Current))
+	}
+	fmt.Printf("\n📊 Heights: %d, Mismatches: %d, Changes of answer: %d, Changed since previous run: %d\n",
+		len(result.Samples), result.Mismatches, len(result.Transitions), len(result.Changes))
+	fmt.Printf("📝 Results saved to %s\n", out)
+
+	if !result.Consistent() || len(result.Changes) > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("✅ Same answer at every height since deployment")
+}
+
+// ledgerDeployment is the ledger's record of address on chainID, if any.
+func ledgerDeployment(chainID string, address common.Address) *chain.Deployment {
+	ledger, err := chain.LoadLedger(paths.Work(chain.DeploymentsFile))
+	if err != nil {
+		return nil
+	}
+	for i, d := range ledger.Deployments {
+		if d.ChainID == chainID && common.HexToAddress(d.Address) == address && d.Block > 0 {
+			return &ledger.Deployments[i]
+		}
+	}
+	return nil
+}
+
+func describeSample(s history.Sample) string {
+	if s.Error != "" {
+		return "error: " + s.Error
+	}
+	if s.Match {
+		return s.Output + " (expected)"
+	}
+	return s.Output
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"cdk-erigon-precompile/pkg/capture"
+	"cdk-erigon-precompile/pkg/output"
+)
+
+func main() {
+	output.Setup()
+
+	addr := flag.String("addr", "127.0.0.1:8546", "address to serve the capture on")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: go run scripts/replay_rpc.go [flags] capture.har")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	source := flag.Arg(0)
+
+	file, err := capture.Load(source)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	replay := capture.NewReplay(file)
+	fmt.Printf("📂 Loaded %d exchanges (%d distinct requests) from %s\n", len(file.Log.Entries), replay.Len(), source)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("❌ Failed to listen on %s: %v", *addr, err)
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	fmt.Printf("🎞️  Replaying on http://%s:%s\n", host, port)
+	fmt.Printf("📌 Point a script at it with RPC_HOST=%s RPC_PORT=%s\n", host, port)
+
+	server := &http.Server{Handler: replay}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("❌ Replay server failed: %v", err)
+		}
+	}()
+
+	// Serve until interrupted, then report what the capture couldn't answer
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	server.Close()
+
+	stats := replay.Stats()
+	fmt.Printf("\n📊 Served %d requests from the capture\n", stats.Served)
+	if len(stats.Missed) > 0 {
+		methods := make([]string, 0, len(stats.Missed))
+		for m := range stats.Missed {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		for _, m := range methods {
+			fmt.Printf("⚠️  %s: %d requests not in capture\n", m, stats.Missed[m])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpccache"
+)
+
+func main() {
+	output.Setup()
+
+	addr := flag.String("addr", "127.0.0.1:8547", "address to serve the caching proxy on")
+	dir := flag.String("dir", paths.Work("rpc_cache"), "cache directory, shared with --rpc-cache")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: go run scripts/rpc_cache.go [flags] http://reference-node:8545")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	upstream, err := url.Parse(flag.Arg(0))
+	if err != nil || upstream.Host == "" {
+		log.Fatalf("❌ Invalid endpoint %q", flag.Arg(0))
+	}
+
+	// Entries are keyed by the upstream endpoint, as the proxy asks it
+	cache := &rpccache.Transport{Dir: *dir}
+	proxy := httputil.NewSingleHostReverseProxy(upstream)
+	proxy.Transport = cache
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = upstream.Host
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("❌ Failed to listen on %s: %v", *addr, err)
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	fmt.Printf("🗄️  Caching %s on http://%s:%s into %s\n", upstream, host, port, *dir)
+	fmt.Printf("📌 Point a script at it with RPC_HOST=%s RPC_PORT=%s\n", host, port)
+
+	server := &http.Server{Handler: proxy}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("❌ Caching proxy failed: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	server.Close()
+
+	stats := cache.Stats()
+	fmt.Printf("\n📊 %d answered from the cache, %d forwarded and cached, %d not cacheable\n", stats.Hits, stats.Misses, stats.Bypassed)
+}
@@ -0,0 +1,1200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/big"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/internal/hooks"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/ephemeral"
+	"cdk-erigon-precompile/pkg/failfast"
+	"cdk-erigon-precompile/pkg/metrics"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/profile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/score"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/suite"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/triage"
+)
+
+// GroupRun is the outcome of one test group in a suite run. A skipped
+// group has its reason code in Skip and the explanation in SkipReason.
+type GroupRun struct {
+	Name       string       `json:"name"`
+	Priority   int          `json:"priority"`
+	Command    string       `json:"command"`
+	EstimateS  float64      `json:"estimateSeconds"`
+	FromRuns   int          `json:"estimateFromRuns"`
+	Status     string       `json:"status"`
+	DurationS  float64      `json:"durationSeconds,omitempty"`
+	SkipReason string       `json:"skipReason,omitempty"`
+	Skip       *skip.Reason `json:"skip,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+type RunResult struct {
+	Stage     string     `json:"stage"`
+	BudgetS   float64    `json:"budgetSeconds,omitempty"`
+	Groups    []GroupRun `json:"groups"`
+	Passed    int        `json:"passed"`
+	Failed    int        `json:"failed"`
+	Skipped   int        `json:"skipped"`
+	DurationS float64    `json:"durationSeconds"`
+	Timestamp string     `json:"timestamp"`
+	// SkippedBy counts the skipped groups by reason code.
+	SkippedBy skip.Counts `json:"skippedBy,omitempty"`
+	// Transactions and GasUsed total what the groups mined. Estimated
+	// counts those with an eth_estimateGas answer; Underestimated lists
+	// those using more gas.
+	Transactions   int                 `json:"transactions"`
+	GasUsed        uint64              `json:"gasUsed"`
+	Estimated      int                 `json:"estimated"`
+	Underestimated []chain.GasEstimate `json:"underestimated,omitempty"`
+	// HookError is set when an AfterRun hook failed the run.
+	HookError string `json:"hookError,omitempty"`
+	// Failures are the failed checks in the results files the run wrote,
+	// those a known issue tracks with its URL. NewFailures counts the
+	// others, the new regressions.
+	Failures      []triage.Annotated `json:"failures,omitempty"`
+	KnownFailures int                `json:"knownFailures,omitempty"`
+	NewFailures   int                `json:"newFailures,omitempty"`
+}
+
+// failed reports whether a group or an AfterRun hook failed the run.
+func (r RunResult) failed() bool {
+	return r.Failed > 0 || r.HookError != ""
+}
+
+// groups lists the suite in priority order: the canary first, then the
+// conformance stages, then the long sweeps. Stage 2 deployment is a setup
+// step and isn't part of the suite. The transactions a group may send are
+// declared by its script (see suite.WritesDirective): storage proof sends
+// five stores, and it and the contract groups deploy their contract when
+// none is recorded.
+var groups = []suite.Group{
+	{Name: "canary", Priority: 0, Script: "scripts/stage1_precompile.go", Estimate: 5 * time.Second, Requests: 5,
+		Tags: []string{tags.Smoke}},
+	{Name: "gas-cap", Priority: 5, Script: "scripts/gas_cap.go", Estimate: 20 * time.Second, Requests: 40,
+		Tags: []string{tags.Gas}},
+	{Name: "wrapper", Priority: 10, Script: "scripts/stage3_invoke_wrapper.go", Estimate: 15 * time.Second, Requests: 60,
+		Contains: []string{tags.Smoke, tags.Gas, tags.Binary}},
+	{Name: "storage-proof", Priority: 20, Script: "scripts/stage4_storage_proof.go", Estimate: time.Minute, Requests: 80,
+		Contains: []string{tags.Smoke, tags.Binary}},
+	{Name: "mutation", Priority: 25, Script: "scripts/mutation.go", Estimate: 20 * time.Second, Requests: 300,
+		Contains: []string{tags.Smoke, tags.Gas, tags.Binary}},
+	{Name: "multicall", Priority: 27, Script: "scripts/multicall.go", Estimate: 10 * time.Second, Requests: 20,
+		Contains: []string{tags.Smoke, tags.Binary}},
+	{Name: "callmany", Priority: 27, Script: "scripts/callmany.go", Estimate: 15 * time.Second, Requests: 20,
+		Contains: []string{tags.Smoke, tags.Binary, tags.Fuzz}},
+	{Name: "provenance", Priority: 28, Script: "scripts/provenance.go", Estimate: 15 * time.Second, Requests: 40,
+		Contains: []string{tags.Smoke, tags.Binary}},
+	{Name: "call-patterns", Priority: 28, Script: "scripts/call_patterns.go", Estimate: 15 * time.Second, Requests: 80,
+		Contains: []string{tags.Smoke, tags.Binary}},
+	{Name: "composed", Priority: 28, Script: "scripts/composed.go", Estimate: 15 * time.Second, Requests: 40,
+		Contains: []string{tags.Smoke, tags.Binary}},
+	{Name: "eip712", Priority: 28, Script: "scripts/eip712.go", Estimate: 15 * time.Second, Requests: 30,
+		Tags: []string{tags.Smoke}},
+	{Name: "erc1271", Priority: 28, Script: "scripts/erc1271.go", Estimate: 15 * time.Second, Requests: 30,
+		Tags: []string{tags.Smoke}},
+	{Name: "bls12-381", Priority: 29, Script: "scripts/bls12381.go", Estimate: 10 * time.Second, Requests: 30,
+		Tags: []string{tags.Smoke}},
+	{Name: "groth16", Priority: 29, Script: "scripts/groth16.go", Estimate: 10 * time.Second, Requests: 30,
+		Tags: []string{tags.Smoke}},
+	{Name: "merkle", Priority: 29, Script: "scripts/merkle.go", Estimate: 15 * time.Second, Requests: 40,
+		Contains: []string{tags.Smoke, tags.Gas}},
+	{Name: "memory-expansion", Priority: 29, Script: "scripts/memory_expansion.go", Estimate: 10 * time.Second, Requests: 60,
+		Tags: []string{tags.Gas}},
+	{Name: "undefined-precompiles", Priority: 29, Script: "scripts/undefined_precompiles.go", Estimate: 15 * time.Second, Requests: 60,
+		Contains: []string{tags.Smoke, tags.Gas}},
+	{Name: "empty-input", Priority: 29, Script: "scripts/empty_input.go", Estimate: 10 * time.Second, Requests: 30,
+		Tags: []string{tags.Smoke}},
+	{Name: "zero-gas", Priority: 29, Script: "scripts/zero_gas.go", Estimate: 10 * time.Second, Requests: 40,
+		Tags: []string{tags.Gas}},
+	{Name: "archive", Priority: 30, Script: "scripts/archive.go", Estimate: 15 * time.Second, Requests: 40,
+		Tags: []string{tags.Archive}},
+	{Name: "fork-activation", Priority: 31, Script: "scripts/fork_activation.go", Estimate: time.Minute, Requests: 100,
+		Tags: []string{tags.Archive}},
+	{Name: "fees", Priority: 35, Script: "scripts/fee_breakdown.go", Estimate: 10 * time.Second, Requests: 30,
+		Tags: []string{tags.Gas}},
+	{Name: "witness", Priority: 35, Script: "scripts/witness.go", Estimate: 20 * time.Second, Requests: 30,
+		Tags: []string{tags.ZKCounters}},
+	{Name: "fuzz", Priority: 40, Script: "scripts/fuzz.go", Args: []string{"--cases", "1000"}, Estimate: 2 * time.Minute, Requests: 1_100,
+		Tags: []string{tags.Fuzz, tags.Slow}},
+	{Name: "benchmark", Priority: 50, Script: "scripts/benchmark.go", Estimate: time.Minute, Requests: 2_000,
+		Tags: []string{tags.Slow}},
+	{Name: "ecrecover-bench", Priority: 55, Script: "scripts/ecrecover_bench.go", Estimate: 30 * time.Second, Requests: 600,
+		Tags: []string{tags.Slow}},
+	{Name: "modexp-probe", Priority: 56, Script: "scripts/modexp_probe.go", Estimate: time.Minute, Requests: 300,
+		Tags: []string{tags.Slow}},
+	{Name: "pairing-stress", Priority: 57, Script: "scripts/pairing_stress.go", Estimate: 2 * time.Minute, Requests: 300,
+		Tags: []string{tags.Slow}},
+	{Name: "counter-curves", Priority: 58, Script: "scripts/counter_curves.go", Estimate: time.Minute, Requests: 300,
+		Tags: []string{tags.ZKCounters, tags.Slow}},
+	{Name: "chaos", Priority: 60, Script: "scripts/chaos.go", Estimate: 2 * time.Minute, Requests: 500,
+		Tags: []string{tags.Slow}},
+}
+
+func main() {
+	output.Setup()
+
+	budget := flag.Duration("time-budget", 0, "only run the highest-priority groups expected to finish within this time (0 runs everything)")
+	historyPath := flag.String("history", paths.Work("run_history.json"), "file recording past group durations used for estimates")
+	dryRun := flag.Bool("dry-run", false, "print the plan without running anything")
+	scoreWeights := flag.String("score-weights", "", "override conformance score category weights, e.g. wrapper=5,fuzz=1")
+	badgeLabel := flag.String("badge-label", score.DefaultLabel, "left-hand text of the conformance badge")
+	ephemeralKind := flag.String("ephemeral-node", "", "run the suite against a throwaway local node instead: "+strings.Join(ephemeral.Kinds(), " or "))
+	diff := flag.Bool("diff", false, "with --ephemeral-node, then run against the configured node and diff the outcomes")
+	useEphemeralAccount := flag.Bool("ephemeral-account", false, "generate a fresh in-memory deployer account, fund it and use it for every stage")
+	accountBalance := flag.String("account-balance", "", "with --ephemeral-account, what the funding account sends it, in base units or suffixed with the native currency symbol (default 0.1 of the native currency)")
+	faucet := flag.String("faucet", os.Getenv("FAUCET_URL"), "with --ephemeral-account, request funds from this faucet instead of the funding account")
+	sweep := flag.Bool("sweep", false, "with --ephemeral-account, send what is left back to the funding account afterwards")
+	yes := flag.Bool("yes", false, "start without asking for confirmation of the run's estimated cost")
+	pushgateway := flag.String("pushgateway", os.Getenv(metrics.EnvPushgateway), "push the run's outcome to this Prometheus Pushgateway, e.g. http://pushgateway:9091")
+	pushJob := flag.String("push-job", "precompile_suite", "job name of the metrics pushed to --pushgateway")
+	metricsDir := flag.String("metrics-dir", os.Getenv(metrics.EnvTextfileDir), "also write the run's outcome as an OpenMetrics textfile to this directory, for node_exporter's textfile collector")
+	knownIssues := flag.String("known-issues", triage.DefaultPath, "file mapping failure signatures to the issues tracking them, to tell known failures from new regressions")
+	readOnly := flag.Bool("read-only", false, "never sign or send a transaction, failing if a selected group needs one (sets "+chain.ReadOnlyEnv+" for every stage)")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	failures := failfast.Flags()
+	flag.Parse()
+	// The groups inherit the policy for their own checks
+	failures.Export()
+
+	if *diff && *ephemeralKind == "" {
+		log.Fatal("❌ --diff needs --ephemeral-node")
+	}
+	if *readOnly && *ephemeralKind != "" {
+		log.Fatal("❌ --read-only can't deploy the wrapper to an ephemeral node")
+	}
+	if *useEphemeralAccount && (*readOnly || *ephemeralKind != "") {
+		log.Fatal("❌ --ephemeral-account needs a writable configured node, not --read-only or --ephemeral-node")
+	}
+	if *sweep && !*useEphemeralAccount {
+		log.Fatal("❌ --sweep needs --ephemeral-account")
+	}
+
+	history, err := suite.LoadHistory(*historyPath)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	weights, err := score.ParseWeights(*scoreWeights)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	// Only the default known issues file may be missing
+	loadIssues := triage.Load
+	if *knownIssues == triage.DefaultPath {
+		loadIssues = triage.LoadOptional
+	}
+	issues, err := loadIssues(*knownIssues)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Groups whose scripts may send transactions are tagged writes, with
+	// what they send
+	for i, g := range groups {
+		if groups[i], err = suite.Declare(g); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+	selected, skipped := suite.Plan(groups, history, *budget, tagFilter)
+	if *readOnly {
+		if err := checkReadOnly(selected); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		// The stages inherit it, and refuse to sign even if a group
+		// wasn't tagged
+		os.Setenv(chain.ReadOnlyEnv, "true")
+		fmt.Println("🔒 Read-only mode: no transaction will be signed or sent")
+	}
+
+	fmt.Println("📋 Run plan:")
+	for _, p := range selected {
+		fmt.Printf("▶️  %-14s ~%s%s\n", p.Group.Name, output.Duration(p.Estimate.Round(time.Second)), estimateSource(p))
+	}
+	for _, p := range skipped {
+		fmt.Printf("⏭️  %-14s skipped: %s\n", p.Group.Name, p.Skip.Detail)
+	}
+	cost := suite.Total(selected)
+	spend := ""
+	if cost.Transactions > 0 && *ephemeralKind == "" {
+		spend = estimateSpend(envFiles, cost.Gas)
+	}
+	printCost(cost, spend)
+	if *dryRun {
+		return
+	}
+	if !*yes && !confirm() {
+		fmt.Println("🛑 Not started")
+		return
+	}
+
+	// Ctrl-C stops the running group and skips the rest; the partial
+	// results and history are still saved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	plan := suitePlan{selected: selected, skipped: skipped, budget: *budget, filter: tagFilter, failures: *failures, issues: issues}
+
+	var account *ephemeralAccount
+	if *useEphemeralAccount {
+		if err := envFiles.Load(); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		rpcURL := fmt.Sprintf("http://%s:%s", os.Getenv("RPC_HOST"), os.Getenv("RPC_PORT"))
+		client, err := rpcclient.Connect(ctx, rpcURL)
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+		}
+		defer client.Close()
+		if account, err = setupEphemeralAccount(ctx, client, *accountBalance, *faucet); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+
+	// The ephemeral node is the reference the configured node is diffed
+	// against
+	var reference *suitePass
+	if *ephemeralKind != "" {
+		reference, err = runEphemeral(ctx, *ephemeralKind, plan, weights, *badgeLabel)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if !*diff {
+			saveMetrics(*metricsDir, reference)
+			pushMetrics(*pushgateway, *pushJob, reference)
+			if reference.result.failed() {
+				os.Exit(1)
+			}
+			return
+		}
+		fmt.Println("\n🔁 Running the suite against the configured node")
+	}
+
+	pass := runPass(ctx, suiteTarget{dir: ".", args: envFiles.Args()}, plan, history, weights, *badgeLabel)
+	if err := history.Save(); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if account != nil && *sweep {
+		// Also after Ctrl-C, so an interrupted run leaves nothing behind
+		account.sweep(context.WithoutCancel(ctx))
+	}
+
+	if *pushgateway != "" {
+		// Only the pushed metrics are labelled with the node
+		if err := envFiles.Load(); err == nil {
+			pass.network, pass.nodeVersion = identifyNode(context.WithoutCancel(ctx), fmt.Sprintf("http://%s:%s", os.Getenv("RPC_HOST"), os.Getenv("RPC_PORT")), os.Getenv("CHAIN_PROFILE"))
+		}
+	}
+	if reference != nil {
+		saveMetrics(*metricsDir, reference, pass)
+		pushMetrics(*pushgateway, *pushJob, reference, pass)
+		d := diffPasses(reference, pass)
+		if err := saveDiff(d); err != nil {
+			log.Fatal(err)
+		}
+		printDiff(d)
+		if d.Divergences > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	saveMetrics(*metricsDir, pass)
+	pushMetrics(*pushgateway, *pushJob, pass)
+	if pass.result.failed() {
+		os.Exit(1)
+	}
+}
+
+// ephemeralAccount is a deployer account generated for one run.
+type ephemeralAccount struct {
+	sender   *chain.Sender
+	currency profile.Currency
+}
+
+// setupEphemeralAccount generates a key, has it funded by the faucet or
+// else the funding role, and hands it to every stage as their deployer and
+// invoker. The key only exists in this process and the environment of its
+// children.
+func setupEphemeralAccount(ctx context.Context, client *ethclient.Client, balance, faucetURL string) (*ephemeralAccount, error) {
+	local, hexKey, err := chain.GenerateAccount()
+	if err != nil {
+		return nil, err
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+	chainProfile, err := profile.Resolve(os.Getenv("CHAIN_PROFILE"), chainID.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	if err := chainProfile.CheckChain(chainID.Uint64()); err != nil {
+		return nil, err
+	}
+	currency := chainProfile.NativeCurrency
+	if balance == "" {
+		balance = "0.1" + currency.Symbol
+	}
+	amount, err := currency.Parse(balance)
+	if err != nil {
+		return nil, fmt.Errorf("--account-balance: %w", err)
+	}
+	account := &ephemeralAccount{
+		sender:   &chain.Sender{Client: client, Signer: local, From: local.Address(), ChainID: chainID},
+		currency: currency,
+	}
+	fmt.Printf("\n🎲 Ephemeral account %s\n", local.Address().Hex())
+
+	if faucetURL != "" {
+		fmt.Printf("🚰 Requesting funds from %s...\n", faucetURL)
+		if err := chain.RequestFaucet(ctx, faucetURL, local.Address()); err != nil {
+			return nil, err
+		}
+	} else {
+		funder, err := chain.NewRoleSender(ctx, client, chain.RoleFund)
+		if err != nil {
+			return nil, fmt.Errorf("funding the ephemeral account: %w (or pass --faucet)", err)
+		}
+		fmt.Printf("📨 Sending %s from %s...\n", currency.Format(amount), funder.From.Hex())
+		if _, receipt, err := funder.Transfer(ctx, local.Address(), amount); err != nil {
+			return nil, err
+		} else if receipt.Status != 1 {
+			return nil, fmt.Errorf("funding transfer %s reverted", receipt.TxHash.Hex())
+		}
+	}
+	// A faucet may send less than asked for; any balance will do
+	funded, err := chain.WaitForBalance(ctx, client, local.Address(), big.NewInt(1), 3*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("✅ Funded with %s\n", currency.Format(funded))
+
+	// The stages read .env, but variables already set take precedence; the
+	// invoke role falls back to the deployer key when its own is empty
+	for k, v := range map[string]string{
+		chain.RoleDeploy.KeyEnv():     hexKey,
+		chain.RoleDeploy.AddressEnv(): "",
+		chain.RoleInvoke.KeyEnv():     "",
+		chain.RoleInvoke.AddressEnv(): "",
+	} {
+		os.Setenv(k, v)
+	}
+	return account, nil
+}
+
+// sweep returns the account's balance to the funding account, logging
+// rather than failing the run when it can't.
+func (a *ephemeralAccount) sweep(ctx context.Context) {
+	to, err := chain.RoleAddress(ctx, chain.RoleFund)
+	if err != nil {
+		log.Printf("⚠️  Leftover funds of %s not swept: %v", a.sender.From.Hex(), err)
+		return
+	}
+	fmt.Printf("\n🧹 Sweeping %s back to %s...\n", a.sender.From.Hex(), to.Hex())
+	tx, receipt, err := chain.Sweep(ctx, a.sender, to)
+	switch {
+	case err != nil:
+		log.Printf("⚠️  Sweep failed: %v", err)
+	case tx == nil:
+		fmt.Println("✅ Nothing worth sweeping")
+	case receipt.Status != 1:
+		log.Printf("⚠️  Sweep %s reverted", tx.Hash().Hex())
+	default:
+		fmt.Printf("✅ Swept %s in block %d\n", a.currency.Format(tx.Value()), receipt.BlockNumber.Uint64())
+	}
+}
+
+// estimateSpend prices gas at the node's current gas price in its native
+// currency. It returns "" when the node can't be asked, as nothing else
+// needs it before the run starts.
+func estimateSpend(envFiles *envfile.Options, gas uint64) string {
+	if err := envFiles.Load(); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rpcURL := fmt.Sprintf("http://%s:%s", os.Getenv("RPC_HOST"), os.Getenv("RPC_PORT"))
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		return ""
+	}
+	defer client.Close()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return ""
+	}
+	price, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return ""
+	}
+	chainProfile, err := profile.Resolve(os.Getenv("CHAIN_PROFILE"), chainID.Uint64())
+	if err != nil {
+		return ""
+	}
+	wei := new(big.Int).Mul(new(big.Int).SetUint64(gas), price)
+	return chainProfile.NativeCurrency.Format(wei)
+}
+
+// seconds formats a duration recorded in seconds for the console.
+func seconds(s float64) string {
+	return output.Duration(time.Duration(s * float64(time.Second)))
+}
+
+// printCost prints what the selected groups are expected to cost.
+func printCost(c suite.Cost, spend string) {
+	fmt.Printf("🧮 Estimate: ~%s RPC requests, %s transactions", output.Count(c.Requests), output.Count(c.Transactions))
+	if c.Transactions > 0 {
+		fmt.Printf(" using up to %s gas", output.Count(c.Gas))
+		if spend != "" {
+			fmt.Printf(" (~%s at the current gas price)", spend)
+		}
+	}
+	fmt.Printf(", ~%s\n", output.Duration(c.Duration.Round(time.Second)))
+}
+
+// confirm asks whether to start the run when stdin is a terminal. Runs
+// without one, such as in CI, have nobody to ask and start right away.
+func confirm() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return true
+	}
+	fmt.Print("❓ Start the run? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// suitePlan is what a pass runs: the planned groups and the limits.
+type suitePlan struct {
+	selected []suite.Planned
+	skipped  []suite.Planned
+	budget   time.Duration
+	filter   *tags.Filter
+	// failures stops a pass after its failed groups reach the limit; each
+	// pass counts its own.
+	failures failfast.Policy
+	// issues annotate the failures each pass finds.
+	issues triage.Issues
+}
+
+// checkReadOnly fails if any selected group may send transactions, as its
+// script declares with suite.WritesDirective.
+func checkReadOnly(selected []suite.Planned) error {
+	var writers []string
+	for _, p := range selected {
+		if slices.Contains(p.Group.Tags, tags.Writes) {
+			writers = append(writers, p.Group.Name)
+		}
+	}
+	if len(writers) > 0 {
+		return fmt.Errorf("--read-only, but these groups send transactions: %s; deselect them with --exclude-tags %s", strings.Join(writers, ", "), tags.Writes)
+	}
+	return nil
+}
+
+// suiteTarget is where a pass runs: the stage commands' working directory
+// and the environment pointing them at a node, plus arguments passed to
+// every stage. The zero value is the configured node in the current
+// directory.
+type suiteTarget struct {
+	label string
+	dir   string
+	env   []string
+	args  []string
+}
+
+// workDir is where the pass's results are written. Ephemeral targets pin
+// WORK_DIR to their own directory.
+func (t suiteTarget) workDir() string {
+	if t.env != nil {
+		return t.dir
+	}
+	return paths.Under(t.dir, paths.WorkDir())
+}
+
+// suitePass is the outcome of one run of the suite against one node.
+type suitePass struct {
+	label   string
+	dir     string
+	result  RunResult
+	report  *score.Report
+	latency []score.Latency
+	// network and nodeVersion label the pass's metrics when known.
+	network, nodeVersion string
+}
+
+// runPass runs the plan, saves and prints the results and scores them.
+// Durations are recorded in history unless it is nil.
+func runPass(ctx context.Context, target suiteTarget, plan suitePlan, history *suite.History, weights map[string]float64, badgeLabel string) *suitePass {
+	result := RunResult{Stage: "Suite Run", BudgetS: plan.budget.Seconds(), SkippedBy: skip.Counts{}}
+	failures := plan.failures
+	// Groups that skip themselves say why in a file of their own
+	reports, err := os.MkdirTemp("", "skip-reports-")
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer os.RemoveAll(reports)
+	start := time.Now()
+	for _, p := range plan.selected {
+		run := newGroupRun(p)
+
+		if ctx.Err() != nil {
+			result.skip(run, skip.New(skip.Interrupted, "interrupted"))
+			continue
+		}
+		if failures.Stopped() {
+			result.skip(run, skip.New(skip.FailurePolicy, failures.Reason()))
+			continue
+		}
+		// Estimates can be wrong; stop starting groups once the budget is spent
+		if plan.budget > 0 && time.Since(start)+p.Estimate > plan.budget {
+			result.skip(run, skip.New(skip.Budget, "budget exhausted by earlier groups"))
+			continue
+		}
+
+		if target.label != "" {
+			fmt.Printf("\n🚀 Running %s on %s\n", p.Group.Name, target.label)
+		} else {
+			fmt.Printf("\n🚀 Running %s\n", p.Group.Name)
+		}
+		groupStart := time.Now()
+		report := filepath.Join(reports, p.Group.Name+".json")
+		err := runScript(ctx, target, p.Group.Script, append(append(append([]string(nil), p.Group.Args...), plan.filter.Args()...), target.args...),
+			skip.EnvReport+"="+report)
+		elapsed := time.Since(groupStart)
+		run.DurationS = elapsed.Seconds()
+		if history != nil && ctx.Err() == nil {
+			// An interrupted group's duration says nothing about the next run
+			history.Record(p.Group.Name, elapsed)
+		}
+
+		if err != nil {
+			run.Status = "failed"
+			run.Error = err.Error()
+			result.Failed++
+			failures.Fail()
+			result.Groups = append(result.Groups, run)
+			continue
+		}
+		// A group that ran none of its checks didn't pass
+		reason, err := skip.ReadReport(report)
+		if err != nil {
+			log.Printf("⚠️  Skip reason of %s not read: %v", p.Group.Name, err)
+		}
+		if reason != nil {
+			result.skip(run, reason)
+			continue
+		}
+		run.Status = "passed"
+		result.Passed++
+		result.Groups = append(result.Groups, run)
+	}
+	for _, p := range plan.skipped {
+		result.skip(newGroupRun(p), p.Skip)
+	}
+
+	result.DurationS = time.Since(start).Seconds()
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	if estimates, err := chain.LoadEstimates(filepath.Join(target.workDir(), chain.EstimatesFile)); err != nil {
+		log.Printf("⚠️  Gas estimates not read: %v", err)
+	} else {
+		ran := estimates.Since(start)
+		result.Transactions = len(ran.Transactions)
+		for _, g := range ran.Transactions {
+			result.GasUsed += g.GasUsed
+			if g.EstimateError == "" {
+				result.Estimated++
+			}
+		}
+		result.Underestimated = ran.Underestimated()
+	}
+
+	if found, err := triage.Collect(target.workDir(), start); err != nil {
+		log.Printf("⚠️  Failed checks not collected: %v", err)
+	} else {
+		result.Failures = plan.issues.Annotate(found)
+		for _, f := range result.Failures {
+			if f.Known != "" {
+				result.KnownFailures++
+			} else {
+				result.NewFailures++
+			}
+		}
+	}
+
+	path := filepath.Join(target.workDir(), "results_run.json")
+	run := hooks.Run{Stage: result.Stage, Results: path, Passed: result.Passed, Failed: result.Failed, Skipped: result.Skipped}
+	if err := hooks.AfterRun(ctx, run); err != nil {
+		result.HookError = err.Error()
+	}
+
+	// Save results
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(path, file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+
+	if target.label != "" {
+		fmt.Printf("\n🧪 Suite results on %s:\n", target.label)
+	} else {
+		fmt.Println("\n🧪 Suite results:")
+	}
+	for _, g := range result.Groups {
+		switch g.Status {
+		case "passed":
+			fmt.Printf("✅ %s (%s)\n", g.Name, seconds(g.DurationS))
+		case "failed":
+			fmt.Printf("❌ %s (%s): %s\n", g.Name, seconds(g.DurationS), g.Error)
+		default:
+			fmt.Printf("⏭️  %s: %s\n", g.Name, g.SkipReason)
+		}
+	}
+	fmt.Printf("\n📊 Passed: %d, Failed: %d, Skipped: %d in %s\n", result.Passed, result.Failed, result.Skipped, seconds(result.DurationS))
+	if result.HookError != "" {
+		fmt.Printf("❌ %s\n", result.HookError)
+	}
+	if len(result.SkippedBy) > 0 {
+		fmt.Printf("⏭️  Skipped by reason: %s\n", result.SkippedBy)
+	}
+	printFailures(result)
+	if result.Estimated > 0 {
+		fmt.Printf("⛽ Gas estimates: %d of %d transactions used more than estimated\n", len(result.Underestimated), result.Estimated)
+		for _, g := range result.Underestimated {
+			fmt.Printf("❌ %s: estimate %s, used %s\n", g.Tx, output.Count(g.Estimate), output.Count(g.GasUsed))
+		}
+	}
+	fmt.Printf("📝 Results saved to %s\n", path)
+
+	// Score what this run produced
+	pass := &suitePass{label: target.label, dir: target.workDir(), result: result}
+	if pass.report, err = saveConformance(target.workDir(), start, weights, badgeLabel); err != nil {
+		log.Printf("⚠️  Conformance score not computed: %v", err)
+	}
+	if pass.latency, err = score.CollectLatency(target.workDir(), start); err != nil {
+		log.Printf("⚠️  Latencies not collected: %v", err)
+	}
+	return pass
+}
+
+// maxFailuresListed bounds the failed checks printed; results_run.json
+// has them all.
+const maxFailuresListed = 20
+
+// printFailures lists the failed checks, new regressions first, each
+// known one with the issue tracking it.
+func printFailures(r RunResult) {
+	if len(r.Failures) == 0 {
+		return
+	}
+	fmt.Printf("🔎 Failed checks: %d new regressions, %d known\n", r.NewFailures, r.KnownFailures)
+	listed := slices.Clone(r.Failures)
+	slices.SortStableFunc(listed, func(a, b triage.Annotated) int {
+		return strings.Compare(a.Known, b.Known)
+	})
+	for i, f := range listed {
+		if i == maxFailuresListed {
+			fmt.Printf("   … and %d more in results_run.json\n", len(listed)-i)
+			break
+		}
+		if f.Known == "" {
+			fmt.Printf("🆕 new regression: %s\n", describeFailure(f.Failure))
+		} else {
+			fmt.Printf("📌 known, tracked in %s: %s\n", f.Known, describeFailure(f.Failure))
+		}
+	}
+}
+
+func describeFailure(f triage.Failure) string {
+	var b strings.Builder
+	b.WriteString(f.Source)
+	if f.Case != "" {
+		fmt.Fprintf(&b, " %s", f.Case)
+	}
+	var about []string
+	if f.Precompile != "" {
+		about = append(about, f.Precompile)
+	}
+	if f.Input != "" {
+		input := f.Input
+		if len(input) > 18 {
+			input = input[:18] + "…"
+		}
+		about = append(about, "input "+input)
+	}
+	if len(about) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(about, ", "))
+	}
+	if f.Error != "" {
+		fmt.Fprintf(&b, ": %s", f.Error)
+	}
+	return b.String()
+}
+
+// skip records run as skipped for reason.
+func (r *RunResult) skip(run GroupRun, reason *skip.Reason) {
+	run.Status = "skipped"
+	run.SkipReason = reason.Detail
+	run.Skip = reason
+	r.Skipped++
+	r.SkippedBy.Add(reason)
+	r.Groups = append(r.Groups, run)
+}
+
+func newGroupRun(p suite.Planned) GroupRun {
+	return GroupRun{
+		Name:      p.Group.Name,
+		Priority:  p.Group.Priority,
+		Command:   strings.Join(append([]string{"go", "run", p.Group.Script}, p.Group.Args...), " "),
+		EstimateS: p.Estimate.Seconds(),
+		FromRuns:  p.FromRuns,
+	}
+}
+
+func estimateSource(p suite.Planned) string {
+	if p.FromRuns == 0 {
+		return " (default estimate)"
+	}
+	return fmt.Sprintf(" (median of %d runs)", p.FromRuns)
+}
+
+// runScript runs a stage command in the target's directory and environment
+// with its output passed through. The stage is killed if ctx is cancelled.
+func runScript(ctx context.Context, target suiteTarget, script string, args []string, env ...string) error {
+	cmd := exec.CommandContext(ctx, "go", append([]string{"run", script}, args...)...)
+	cmd.Dir = target.dir
+	cmd.Env = append(append(os.Environ(), target.env...), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// saveConformance scores the results files written to dir since start and
+// saves the report and badges next to them.
+func saveConformance(dir string, start time.Time, weights map[string]float64, label string) (*score.Report, error) {
+	collected, err := score.Collect(dir, start)
+	if err != nil {
+		return nil, err
+	}
+	if len(collected.Tallies) == 0 {
+		return nil, fmt.Errorf("no results files were written by this run")
+	}
+	report := collected.Score(weights)
+
+	fmt.Printf("\n🏅 Precompile conformance: %s\n", report.Percent)
+	for _, p := range report.Precompiles {
+		fmt.Printf("   %s: %s (%d passed, %d failed)\n", p.Name, p.Percent, p.Passed, p.Failed)
+	}
+	for _, c := range report.Categories {
+		fmt.Printf("   %-16s %6s (%d passed, %d failed, weight %g)\n", c.Name, c.Percent, c.Passed, c.Failed, weights[c.Name])
+	}
+	if len(report.Skipped) > 0 {
+		fmt.Printf("   Not run, and not scored: %s\n", report.Skipped)
+	}
+
+	scorePath := filepath.Join(dir, "conformance.json")
+	badgePath := filepath.Join(dir, "conformance_badge.json")
+	svgPath := filepath.Join(dir, "conformance_badge.svg")
+	file, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal score: %v", err)
+	}
+	if err := paths.WriteFile(scorePath, file); err != nil {
+		return nil, fmt.Errorf("failed to save score: %v", err)
+	}
+	badge, err := score.BadgeJSON(label, report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal badge: %v", err)
+	}
+	if err := paths.WriteFile(badgePath, badge); err != nil {
+		return nil, fmt.Errorf("failed to save badge: %v", err)
+	}
+	if err := paths.WriteFile(svgPath, score.BadgeSVG(label, report)); err != nil {
+		return nil, fmt.Errorf("failed to save badge: %v", err)
+	}
+	fmt.Printf("📝 Score saved to %s, badges to %s and %s\n", scorePath, badgePath, svgPath)
+	return &report, nil
+}
+
+// saveMetrics writes the outcome of the passes to precompile_suite.prom in
+// dir, unless dir is empty. A run that can't export its metrics still ran,
+// so a failure is only logged.
+func saveMetrics(dir string, passes ...*suitePass) {
+	if dir == "" {
+		return
+	}
+	path, err := metrics.WriteTextfile(dir, "precompile_suite", passMetrics(passes...))
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+		return
+	}
+	fmt.Printf("📈 Metrics saved to %s\n", path)
+}
+
+// pushMetrics pushes the outcome of each pass to the Pushgateway at
+// gateway, unless it is empty, grouped by network, node version and
+// target. A failed push is only logged.
+func pushMetrics(gateway, job string, passes ...*suitePass) {
+	if gateway == "" {
+		return
+	}
+	for _, p := range passes {
+		grouping := metrics.Labels{"network": p.network, "node_version": p.nodeVersion, "target": passTarget(p)}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := metrics.Push(ctx, gateway, job, grouping, passMetrics(p))
+		cancel()
+		if err != nil {
+			log.Printf("⚠️  %v", err)
+			continue
+		}
+		fmt.Printf("📤 Metrics of %s pushed to %s\n", passTarget(p), gateway)
+	}
+}
+
+// passTarget labels a pass's metrics: configured, or the kind of
+// ephemeral node.
+func passTarget(p *suitePass) string {
+	if p.label == "" {
+		return "configured"
+	}
+	return p.label
+}
+
+// passMetrics are the gauges describing the passes, labelled with their
+// target and, when known, network and node version.
+func passMetrics(passes ...*suitePass) []metrics.Family {
+	gauges := map[string]*metrics.Family{}
+	var order []string
+	add := func(name, help string, value float64, labels metrics.Labels) {
+		f, ok := gauges[name]
+		if !ok {
+			f = &metrics.Family{Name: "precompile_suite_" + name, Help: help}
+			gauges[name] = f
+			order = append(order, name)
+		}
+		f.Samples = append(f.Samples, metrics.Sample{Labels: labels, Value: value})
+	}
+	for _, p := range passes {
+		at := func(labels metrics.Labels) metrics.Labels {
+			labels["target"] = passTarget(p)
+			if p.network != "" {
+				labels["network"] = p.network
+			}
+			if p.nodeVersion != "" {
+				labels["node_version"] = p.nodeVersion
+			}
+			return labels
+		}
+		r := p.result
+		if ts, err := time.Parse(time.RFC3339, r.Timestamp); err == nil {
+			add("last_run_timestamp_seconds", "When the run finished, in Unix seconds.", float64(ts.Unix()), at(metrics.Labels{}))
+		}
+		add("duration_seconds", "How long the run took.", r.DurationS, at(metrics.Labels{}))
+		add("groups", "Test groups by outcome.", float64(r.Passed), at(metrics.Labels{"status": "passed"}))
+		add("groups", "Test groups by outcome.", float64(r.Failed), at(metrics.Labels{"status": "failed"}))
+		add("groups", "Test groups by outcome.", float64(r.Skipped), at(metrics.Labels{"status": "skipped"}))
+		for _, g := range r.Groups {
+			if g.Status != "skipped" {
+				add("group_duration_seconds", "How long each group ran.", g.DurationS, at(metrics.Labels{"group": g.Name, "status": g.Status}))
+			}
+		}
+		add("transactions", "Transactions the groups mined.", float64(r.Transactions), at(metrics.Labels{}))
+		add("gas_used", "Gas used by the transactions the groups mined.", float64(r.GasUsed), at(metrics.Labels{}))
+		add("gas_underestimates", "Transactions using more gas than eth_estimateGas answered.", float64(len(r.Underestimated)), at(metrics.Labels{}))
+		for _, l := range p.latency {
+			labels := func(stat string) metrics.Labels {
+				return at(metrics.Labels{"precompile": l.Precompile, "source": l.Source, "stat": stat})
+			}
+			add("latency_milliseconds", "Call latency each results file measured, by statistic.", l.MeanMs, labels("mean"))
+			add("latency_milliseconds", "Call latency each results file measured, by statistic.", l.MinMs, labels("min"))
+			add("latency_milliseconds", "Call latency each results file measured, by statistic.", l.MaxMs, labels("max"))
+			add("latency_samples", "Calls behind each latency summary.", float64(l.Samples), at(metrics.Labels{"precompile": l.Precompile, "source": l.Source}))
+		}
+		if p.report == nil {
+			continue
+		}
+		add("conformance_ratio", "Weighted conformance score, from 0 to 1.", p.report.Score, at(metrics.Labels{}))
+		for _, c := range p.report.Categories {
+			add("conformance_category_ratio", "Conformance score of each check category.", c.Score, at(metrics.Labels{"category": c.Name}))
+			add("conformance_failures", "Failed checks of each category.", float64(c.Failed), at(metrics.Labels{"category": c.Name}))
+		}
+		for _, pc := range p.report.Precompiles {
+			add("conformance_precompile_ratio", "Conformance score of each precompile.", pc.Score, at(metrics.Labels{"precompile": pc.Name}))
+			add("precompile_checks", "Checks of each precompile by outcome.", float64(pc.Passed), at(metrics.Labels{"precompile": pc.Name, "outcome": "passed"}))
+			add("precompile_checks", "Checks of each precompile by outcome.", float64(pc.Failed), at(metrics.Labels{"precompile": pc.Name, "outcome": "failed"}))
+		}
+	}
+
+	var families []metrics.Family
+	for _, name := range order {
+		families = append(families, *gauges[name])
+	}
+	return families
+}
+
+// identifyNode names the network of the node at rpcURL, by the chain
+// profile named or detected from its chain ID, and its client version. A
+// chain without a profile of its own is named by chain ID; either is left
+// empty when the node can't tell.
+func identifyNode(ctx context.Context, rpcURL, profileName string) (network, version string) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		return "", ""
+	}
+	defer client.Close()
+	if err := client.Client().CallContext(ctx, &version, "web3_clientVersion"); err != nil {
+		version = ""
+	}
+	if chainID, err := client.ChainID(ctx); err == nil {
+		network = chainID.String()
+		if chainProfile, err := profile.Resolve(profileName, chainID.Uint64()); err == nil && chainProfile.Name != "generic" {
+			network = chainProfile.Name
+		}
+	}
+	return network, version
+}
+
+// sharedInputs are linked into an ephemeral node's working directory. Its
+// results, deployment and caches are its own, so the configured node's
+// files are never overwritten.
+var sharedInputs = []string{"go.mod", "go.sum", "pkg", "scripts", "artifacts.lock", "contracts", "vectors", "locales", "deploy_manifest.json"}
+
+// runEphemeral starts a throwaway node, deploys the wrapper to it and runs
+// the plan against it in .ephemeral/<kind>.
+func runEphemeral(ctx context.Context, kind string, plan suitePlan, weights map[string]float64, badgeLabel string) (*suitePass, error) {
+	fmt.Printf("\n🧪 Starting ephemeral %s node...\n", kind)
+	node, err := ephemeral.Start(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer node.Stop()
+	fmt.Printf("✅ %s listening on %s (chain ID %d)\n", kind, node.URL, node.ChainID)
+
+	target, err := ephemeralTarget(kind, node)
+	if err != nil {
+		return nil, err
+	}
+
+	// The chain starts empty; the wrapper stages need their contract
+	fmt.Printf("\n🚀 Deploying the wrapper to %s\n", kind)
+	if err := runScript(ctx, target, "scripts/stage2_deploy_wrapper.go", nil); err != nil {
+		log.Printf("⚠️  Wrapper deployment to %s failed, the wrapper groups will fail too: %v", kind, err)
+	}
+
+	// Durations on a local dev node say nothing about the configured one
+	pass := runPass(ctx, target, plan, nil, weights, badgeLabel)
+	pass.network, pass.nodeVersion = identifyNode(context.WithoutCancel(ctx), node.URL, "")
+	return pass, nil
+}
+
+// ephemeralTarget recreates the node's working directory and points it at
+// the node, with the dev account as deployer.
+func ephemeralTarget(kind string, node *ephemeral.Node) (suiteTarget, error) {
+	dir := filepath.Join(".ephemeral", kind)
+	if err := os.RemoveAll(dir); err != nil {
+		return suiteTarget{}, fmt.Errorf("❌ Failed to clear %s: %v", dir, err)
+	}
+	if err := os.MkdirAll(dir, paths.DirMode); err != nil {
+		return suiteTarget{}, fmt.Errorf("❌ Failed to create %s: %v", dir, err)
+	}
+	for _, name := range sharedInputs {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return suiteTarget{}, err
+		}
+		if _, err := os.Stat(abs); os.IsNotExist(err) {
+			continue
+		}
+		if err := linkOrCopy(abs, filepath.Join(dir, name)); err != nil {
+			return suiteTarget{}, fmt.Errorf("❌ Failed to link %s: %v", name, err)
+		}
+	}
+	artifacts, err := filepath.Abs(paths.ArtifactsDir())
+	if err != nil {
+		return suiteTarget{}, err
+	}
+
+	// The stages read .env, but variables already set take precedence, so
+	// the environment also overrides anything exported in the shell. An
+	// empty CHAIN_PROFILE lets stage 4 detect the profile from the chain ID.
+	env := []string{
+		"RPC_HOST=" + node.Host,
+		"RPC_PORT=" + strconv.Itoa(node.Port),
+		"DEPLOYER_PRIVATE_KEY=" + ephemeral.DevKey,
+		"CHAIN_PROFILE=",
+		"ARTIFACTS_DIR=" + artifacts,
+		"WORK_DIR=.",
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(strings.Join(env, "\n")+"\n"), 0600); err != nil {
+		return suiteTarget{}, fmt.Errorf("❌ Failed to write %s/.env: %v", dir, err)
+	}
+	return suiteTarget{label: kind, dir: dir, env: env}, nil
+}
+
+// linkOrCopy symlinks src to dst, copying it instead where creating
+// symlinks needs privileges (Windows without developer mode).
+func linkOrCopy(src, dst string) error {
+	if err := os.Symlink(src, dst); err == nil {
+		return nil
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, paths.DirMode)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return paths.WriteFile(target, data)
+	})
+}
+
+// DiffResult compares a pass against the configured node with the
+// reference pass against an ephemeral node.
+type DiffResult struct {
+	Stage       string      `json:"stage"`
+	Reference   string      `json:"reference"`
+	Groups      []GroupDiff `json:"groups"`
+	Tallies     []TallyDiff `json:"tallies"`
+	Divergences int         `json:"divergences"`
+	Timestamp   string      `json:"timestamp"`
+}
+
+// GroupDiff is the status of one group on both nodes. Groups skipped on
+// either side aren't compared.
+type GroupDiff struct {
+	Name      string `json:"name"`
+	Reference string `json:"reference"`
+	Node      string `json:"node"`
+	Diverges  bool   `json:"diverges"`
+}
+
+// TallyDiff compares the checks of one precompile and category. They
+// diverge when one node failed checks and the other failed none; counts
+// may differ since random vectors aren't the same on both runs.
+type TallyDiff struct {
+	Precompile      string `json:"precompile"`
+	Category        string `json:"category"`
+	ReferencePassed int    `json:"referencePassed"`
+	ReferenceFailed int    `json:"referenceFailed"`
+	NodePassed      int    `json:"nodePassed"`
+	NodeFailed      int    `json:"nodeFailed"`
+	Diverges        bool   `json:"diverges"`
+}
+
+func diffPasses(reference, node *suitePass) DiffResult {
+	d := DiffResult{Stage: "Differential", Reference: reference.label}
+
+	nodeStatus := map[string]string{}
+	for _, g := range node.result.Groups {
+		nodeStatus[g.Name] = g.Status
+	}
+	for _, g := range reference.result.Groups {
+		gd := GroupDiff{Name: g.Name, Reference: g.Status, Node: nodeStatus[g.Name]}
+		gd.Diverges = gd.Reference != "skipped" && gd.Node != "skipped" && gd.Reference != gd.Node
+		if gd.Diverges {
+			d.Divergences++
+		}
+		d.Groups = append(d.Groups, gd)
+	}
+
+	// Only tallies both passes produced can be compared
+	if reference.report != nil && node.report != nil {
+		nodeTallies := map[string]score.Tally{}
+		for _, t := range node.report.Tallies {
+			nodeTallies[t.Precompile+" "+t.Category] = t
+		}
+		for _, r := range reference.report.Tallies {
+			n, ok := nodeTallies[r.Precompile+" "+r.Category]
+			if !ok {
+				continue
+			}
+			td := TallyDiff{
+				Precompile:      r.Precompile,
+				Category:        r.Category,
+				ReferencePassed: r.Passed,
+				ReferenceFailed: r.Failed,
+				NodePassed:      n.Passed,
+				NodeFailed:      n.Failed,
+				Diverges:        (r.Failed == 0) != (n.Failed == 0),
+			}
+			if td.Diverges {
+				d.Divergences++
+			}
+			d.Tallies = append(d.Tallies, td)
+		}
+	}
+	d.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	return d
+}
+
+func printDiff(d DiffResult) {
+	fmt.Printf("\n🔀 Differential check against %s:\n", d.Reference)
+	for _, g := range d.Groups {
+		if g.Diverges {
+			fmt.Printf("❌ %s: %s on %s, %s on the node\n", g.Name, g.Reference, d.Reference, g.Node)
+		}
+	}
+	for _, t := range d.Tallies {
+		if t.Diverges {
+			fmt.Printf("❌ %s %s: %d of %d failed on %s, %d of %d on the node\n", t.Precompile, t.Category,
+				t.ReferenceFailed, t.ReferencePassed+t.ReferenceFailed, d.Reference, t.NodeFailed, t.NodePassed+t.NodeFailed)
+		}
+	}
+	if d.Divergences == 0 {
+		fmt.Printf("✅ The node behaved like %s in every group and check category\n", d.Reference)
+	} else {
+		fmt.Printf("📊 %d divergences\n", d.Divergences)
+	}
+	fmt.Println("📝 Diff saved to results_diff.json")
+}
+
+func saveDiff(d DiffResult) error {
+	file, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal diff: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_diff.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save diff: %v", err)
+	}
+	return nil
+}
@@ -2,44 +2,64 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"time"
 
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/joho/godotenv"
+	"cdk-erigon-precompile/internal/hooks"
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/failfast"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/plugin"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vector"
 )
 
 type Result struct {
-	Stage         string `json:"stage"`
-	Success       bool   `json:"success"`
-	Precompile    string `json:"precompile"`
-	Input         string `json:"input"`
-	ExpectedHash  string `json:"expected_hash"`
-	ReturnedHash  string `json:"returned_hash"`
-	Match         bool   `json:"match"`
-	Error         string `json:"error,omitempty"`
+	Stage      string `json:"stage"`
+	Success    bool   `json:"success"`
+	Precompile string `json:"precompile"`
+	vector.Vector
+	ExpectedHash string `json:"expected_hash"`
+	ReturnedHash string `json:"returned_hash"`
+	Match        bool   `json:"match"`
+	Error        string `json:"error,omitempty"`
+	// HookError is set when an AfterCase hook failed the case.
+	HookError     string `json:"hookError,omitempty"`
 	Timestamp     string `json:"timestamp"`
 	Network       string `json:"network"`
 	RPCURL        string `json:"rpc_url"`
 	TransactionID string `json:"transaction_id,omitempty"`
 }
 
-// Helper function to get pointer to address
-func addressPtr(addr common.Address) *common.Address {
-	return &addr
-}
-
 func main() {
+	output.Setup()
+
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	failures := failfast.Flags()
+	flag.Parse()
+
 	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	// External validators judge each case alongside the stage's own checks
+	plugins, err := plugin.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
 	}
+	defer plugin.Register(plugins)()
 
 	// Get configuration from environment
 	rpcHost := os.Getenv("RPC_HOST")
@@ -53,23 +73,34 @@ func main() {
 	}
 
 	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
-	inputData := "hello world"
+	inputData := []byte("hello world")
 
 	// Initialize result struct
 	result := Result{
 		Stage:      "Stage 1 - Raw Precompile Invocation",
 		Precompile: "0x02", // SHA256 precompile address
-		Input:      inputData,
+		Vector:     vector.New(inputData, tags.Smoke),
 		Timestamp:  time.Now().UTC().Format(time.RFC3339),
 		Network:    "cdk-erigon",
 		RPCURL:     rpcURL,
 	}
 
-	// Connect to client with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if !tagFilter.Match(result.Tags) {
+		fmt.Printf("Skipping stage 1: vector tags %v not selected (%s)\n", result.Tags, tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Connect to client with timeout, cancelled early on Ctrl-C. Hooks get
+	// the run's context, so plugins keep their own timeouts.
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(runCtx, 10*time.Second)
 	defer cancel()
 
-	client, err := ethclient.DialContext(ctx, rpcURL)
+	client, err := rpcclient.Connect(ctx, rpcURL)
 	if err != nil {
 		result.Error = fmt.Sprintf("Client connection error: %v", err)
 		saveResult(result)
@@ -84,58 +115,75 @@ func main() {
 		log.Fatalf(result.Error)
 	}
 	fmt.Printf("Connected to network with ChainID: %d\n", chainID)
-
-	// Prepare input
-	input := []byte(result.Input)
-	expected := sha256.Sum256(input)
-	result.ExpectedHash = fmt.Sprintf("%x", expected)
-
-	// Create call message using the helper function
-	msg := ethereum.CallMsg{
-		To:   addressPtr(common.HexToAddress(result.Precompile)),
-		Data: input,
+	anchors := anchor.Begin(ctx, client, "results_stage1.json")
+
+	// Call precompile; a failed call stops here with --fail-fast, and is
+	// otherwise reported like a mismatch. A BeforeCase hook may veto it.
+	hookCase := hooks.Case{Stage: result.Stage, ID: result.ID(), Precompile: result.Precompile, Input: result.Bytes()}
+	var outcome precompile.Outcome
+	if err = hooks.BeforeCase(runCtx, hookCase); err == nil {
+		outcome, err = precompile.CallSHA256(ctx, client, result.Bytes())
 	}
-
-	// Call precompile
-	callResult, err := client.CallContract(ctx, msg, nil)
+	result.ExpectedHash = fmt.Sprintf("%x", outcome.Expected)
 	if err != nil {
 		result.Error = fmt.Sprintf("Precompile call error: %v", err)
-		saveResult(result)
-		log.Fatalf(result.Error)
+		if errors.Is(err, hooks.ErrHook) {
+			result.Error = err.Error()
+		}
+		if failures.Fail() {
+			saveResult(result)
+			log.Fatal(result.Error)
+		}
+	} else {
+		// Process results
+		result.ReturnedHash = fmt.Sprintf("%x", outcome.Returned)
+		result.Match = outcome.Match()
+		result.Success = true
+	}
+	if err := hooks.AfterCase(runCtx, hooks.Outcome{Case: hookCase, Passed: result.Match, Error: result.Error, Output: outcome.Returned}); err != nil {
+		result.HookError = err.Error()
 	}
-
-	// Process results
-	result.ReturnedHash = fmt.Sprintf("%x", callResult)
-	result.Match = result.ExpectedHash == result.ReturnedHash
-	result.Success = true
 
 	// Print and save results
 	fmt.Println("\n=== Precompile Call Results ===")
 	fmt.Printf("RPC Endpoint: %s\n", rpcURL)
 	fmt.Printf("Precompile Address: %s\n", result.Precompile)
-	fmt.Printf("Input: %q\n", input)
+	fmt.Printf("Input: %s\n", result.Display())
 	fmt.Printf("Expected SHA256: %s\n", result.ExpectedHash)
 	fmt.Printf("Returned SHA256: %s\n", result.ReturnedHash)
 
-	if result.Match {
+	switch {
+	case result.Error != "":
+		fmt.Printf("❌ %s\n", result.Error)
+	case result.Match:
 		fmt.Println("✅ Result matches expected hash")
-	} else {
+	default:
 		fmt.Println("❌ Result DOES NOT match expected hash")
 	}
+	if result.HookError != "" {
+		fmt.Printf("❌ %s\n", result.HookError)
+	}
 
 	saveResult(result)
+	anchors.Finish(ctx)
+	run := hooks.Run{Stage: result.Stage, Results: paths.Work("results_stage1.json"), Passed: 1}
+	if !result.Match || result.HookError != "" {
+		run.Passed, run.Failed = 0, 1
+	}
+	if err := hooks.AfterRun(runCtx, run); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if result.Error != "" || result.HookError != "" {
+		os.Exit(1)
+	}
 }
 
 func saveResult(result Result) {
-	file, err := os.Create("results_stage1.json")
+	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to create results file: %v", err)
+		log.Fatalf("Failed to marshal results: %v", err)
 	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(result); err != nil {
+	if err := paths.WriteFile(paths.Work("results_stage1.json"), append(data, '\n')); err != nil {
 		log.Fatalf("Failed to write results: %v", err)
 	}
 	fmt.Println("Results saved to results_stage1.json")
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
@@ -15,7 +17,18 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/joho/godotenv"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/offline"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/profile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/signer"
+	"cdk-erigon-precompile/pkg/skip"
 )
 
 type DeploymentResult struct {
@@ -26,12 +39,131 @@ type DeploymentResult struct {
 	BytecodeSize     int    `json:"bytecodeSize"`
 	Status           uint   `json:"status"`
 	VerificationPass bool   `json:"verificationPass"`
+	// Cost is gasUsed × price in the chain's native currency.
+	Cost string `json:"cost,omitempty"`
+
+	AccountChecks []chain.Check     `json:"accountChecks,omitempty"`
+	FeeChecks     []chain.Check     `json:"feeChecks,omitempty"`
+	ReceiptChecks []chain.Check     `json:"receiptChecks,omitempty"`
+	BlockChecks   []chain.Check     `json:"blockChecks,omitempty"`
+	Proxies       []deploy.Deployed `json:"proxies,omitempty"`
+	// SelfTestChecks are the known-answer checks of the wrapper's
+	// selftest(), called right after the deployment.
+	SelfTestChecks []chain.Check `json:"selfTestChecks,omitempty"`
+	// Factory are the wrappers --via-factory had a contract create, with
+	// CREATE then CREATE2, and their address derivation and precompile
+	// checks.
+	Factory []*deploy.FactoryDeployment `json:"factory,omitempty"`
+
+	tx       *types.Transaction
+	receipt  *types.Receipt
+	gasPrice *big.Int
 }
 
 func main() {
+	output.Setup()
+
+	// Ctrl-C cancels whatever is in flight. A deployment already sent may
+	// still be mined; the pending journal keeps its hash
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Offline signing workflow: prepare and broadcast run on the online host,
+	// sign runs where the key lives and, with a local key, never touches the
+	// network
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "prepare":
+			runPrepare(ctx, os.Args[2:])
+			return
+		case "sign":
+			runSign(ctx, os.Args[2:])
+			return
+		case "broadcast":
+			runBroadcast(ctx, os.Args[2:])
+			return
+		}
+	}
+
+	manifestPath := flag.String("manifest", "", "deploy the contract suite described by this manifest instead of the single wrapper")
+	proxies := flag.Int("proxies", 0, "also deploy this many EIP-1167 minimal proxy clones of the wrapper")
+	viaFactory := flag.Bool("via-factory", false, "also deploy wrappers with the CREATE and CREATE2 of artifacts/Sha256WrapperFactory and check them")
+	envFiles = envfile.Flags()
+	flag.Parse()
+
+	client := connect(ctx)
+	defer client.Close()
+
+	// Load deployer credentials
+	deployer, err := loadDeployerCredentials(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fromAddress := deployer.Address()
+	fmt.Printf("🔐 Using deployer address: %s\n", fromAddress.Hex())
+
+	chainID := networkChainID(ctx, client)
+
+	if *manifestPath != "" {
+		deploySuite(ctx, client, deployer, chainID, *manifestPath)
+		return
+	}
+
+	// Load contract bytecode
+	bytecode, err := loadBytecode()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Deploy contract
+	result, err := deployContract(ctx, client, deployer, chainID, bytecode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountsOK, failedConformance := validateDeployment(ctx, client, fromAddress, chainID, result)
+
+	// Clone the wrapper behind minimal proxies
+	var proxyErr error
+	if *proxies > 0 {
+		proxyErr = deployProxies(ctx, client, deployer, chainID, result, *proxies)
+	}
+
+	// Have a contract, not the deployer, create more wrappers
+	var factoryErr error
+	if *viaFactory {
+		factoryErr = deployViaFactory(ctx, client, deployer, chainID, result, bytecode)
+	}
+
+	// Save results
+	if err := saveResults(result); err != nil {
+		log.Fatal(err)
+	}
+	if !accountsOK {
+		log.Fatal("❌ Account state assertions failed (see accountChecks in results_stage2.json)")
+	}
+	if len(failedConformance) > 0 {
+		log.Fatalf("❌ Conformance assertions failed (see %s in results_stage2.json)", strings.Join(failedConformance, ", "))
+	}
+	if proxyErr != nil {
+		log.Fatalf("❌ Proxy deployment failed: %v", proxyErr)
+	}
+	if factoryErr != nil {
+		log.Fatalf("❌ Factory deployment failed: %v", factoryErr)
+	}
+
+	fmt.Println("\n🚀 Deployment successful!")
+	fmt.Printf("📝 Results saved to results_stage2.json\n")
+	fmt.Printf("📌 Contract Address: %s\n", result.ContractAddress)
+}
+
+// envFiles are the dotenv flags of the command or subcommand being run.
+var envFiles *envfile.Options
+
+func connect(ctx context.Context) *ethclient.Client {
 	// Load environment variables
-	if err := godotenv.Load(".env"); err != nil {
-		log.Fatal("❌ Error loading .env file")
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
 	}
 
 	// Initialize Ethereum client
@@ -39,128 +171,519 @@ func main() {
 	rpcPort := os.Getenv("RPC_PORT")
 	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
 
-	client, err := ethclient.Dial(rpcURL)
+	client, err := rpcclient.Connect(ctx, rpcURL)
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
 	}
-	defer client.Close()
 	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	return client
+}
 
-	// Load deployer credentials
-	privateKey, fromAddress, err := loadDeployerCredentials()
-	if err != nil {
-		log.Fatal(err)
-	}
-	fmt.Printf("🔐 Using deployer address: %s\n", fromAddress.Hex())
-
+func networkChainID(ctx context.Context, client *ethclient.Client) *big.Int {
 	// Get chain ID (override if needed)
-	chainID, err := client.ChainID(context.Background())
+	chainID, err := client.ChainID(ctx)
 	if err != nil {
 		log.Printf("⚠️  Failed to get chain ID from node, using default: %v", err)
 		chainID = big.NewInt(10101) // Default for cdk-erigon devnet
 	}
 	fmt.Printf("🔗 Network Chain ID: %d\n", chainID)
+	return chainID
+}
 
-	// Load contract bytecode
-	bytecode, err := os.ReadFile("artifacts/Sha256Wrapper.bin")
+func loadBytecode() (string, error) {
+	if err := deploy.VerifyArtifact(paths.Artifact("Sha256Wrapper")); err != nil {
+		return "", fmt.Errorf("❌ Refusing to deploy: %v", err)
+	}
+	bytecode, err := paths.ReadHex(paths.Artifact("Sha256Wrapper.bin"))
 	if err != nil {
-		log.Fatalf("❌ Failed to read bytecode: %v", err)
+		return "", fmt.Errorf("❌ Failed to read bytecode: %v", err)
 	}
 	fmt.Println("📦 Bytecode loaded")
+	if missing := deploy.Unlinked(bytecode); len(missing) > 0 {
+		return "", fmt.Errorf("❌ Bytecode needs library linking %v; deploy it with --manifest and a libraries entry", missing)
+	}
+	return bytecode, nil
+}
 
-	// Deploy contract
-	result, err := deployContract(client, privateKey, fromAddress, chainID, string(bytecode))
+// validateDeployment verifies the deployed code, then asserts account state
+// accounting and fee, receipt and block conformance around the deployment.
+func validateDeployment(ctx context.Context, client *ethclient.Client, deployer common.Address, chainID *big.Int, result *DeploymentResult) (bool, []string) {
+	// Verify deployment
+	if err := verifyDeployment(ctx, client, result); err != nil {
+		log.Fatal(err)
+	}
+
+	// Assert account state accounting around the deployment
+	accountsOK := checkAccountState(ctx, client, deployer, chainID, result)
+
+	// Validate fee fields, the receipt and the including block
+	failed := checkConformance(ctx, client, result)
+
+	// Confirm the precompile's basics through the new wrapper, without
+	// waiting for stage 3
+	result.SelfTestChecks = selfTest(ctx, client, common.HexToAddress(result.ContractAddress))
+	printChecks("🩺 Self-test:", result.SelfTestChecks)
+	if !chain.AllPassed(result.SelfTestChecks) {
+		failed = append(failed, "selfTestChecks")
+	}
+	return accountsOK, failed
+}
+
+// selfTest calls selftest() on the wrapper at address. A wrapper compiled
+// before selftest() was added has its check skipped.
+func selfTest(ctx context.Context, client *ethclient.Client, address common.Address) []chain.Check {
+	checks, err := precompile.WrapperSelfTest(ctx, client, address)
+	switch {
+	case errors.Is(err, precompile.ErrNoSelfTest):
+		c := chain.Check{Name: "selftest()"}
+		c.MarkSkipped(skip.Capability, "the wrapper artifact predates selftest(); recompile it from contracts/Sha256Wrapper.sol")
+		return []chain.Check{c}
+	case err != nil:
+		return []chain.Check{{Name: "selftest()", Note: err.Error()}}
+	}
+	return checks
+}
+
+// runPrepare writes the unsigned deployment transaction for the deployer
+// address. Only the address is needed, not the key.
+func runPrepare(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("prepare", flag.ExitOnError)
+	from := fs.String("from", "", "deployer address (default DEPLOYER_ADDRESS, or derived from DEPLOYER_PRIVATE_KEY)")
+	out := fs.String("out", "unsigned_deploy_tx.json", "where to write the unsigned transaction")
+	envFiles = envfile.FlagsOn(fs)
+	fs.Parse(args)
+
+	client := connect(ctx)
+	defer client.Close()
+
+	address := *from
+	if address == "" {
+		address = os.Getenv("DEPLOYER_ADDRESS")
+	}
+	var fromAddress common.Address
+	switch {
+	case common.IsHexAddress(address):
+		fromAddress = common.HexToAddress(address)
+	case address == "" && os.Getenv("DEPLOYER_PRIVATE_KEY") != "":
+		deployer, err := loadDeployerCredentials(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fromAddress = deployer.Address()
+	default:
+		log.Fatalf("❌ Invalid or missing deployer address %q (use --from or DEPLOYER_ADDRESS)", address)
+	}
+	fmt.Printf("🔐 Using deployer address: %s\n", fromAddress.Hex())
+
+	chainID := networkChainID(ctx, client)
+	bytecode, err := loadBytecode()
 	if err != nil {
 		log.Fatal(err)
 	}
+	unsigned, err := prepareDeployment(ctx, client, fromAddress, chainID, bytecode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := offline.Write(*out, unsigned); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
 
-	// Verify deployment
-	if err := verifyDeployment(client, result); err != nil {
+	fmt.Printf("📌 Contract will deploy to: %s\n", unsigned.ContractAddress.Hex())
+	fmt.Printf("🔏 Signing hash: %s\n", unsigned.SigningHash().Hex())
+	fmt.Printf("📝 Unsigned transaction saved to %s; sign it offline with `go run scripts/stage2_deploy_wrapper.go sign`\n", *out)
+}
+
+// runSign signs a prepared transaction. The key comes from --key-file or
+// DEPLOYER_PRIVATE_KEY (environment or .env); a hex key needs no network
+// access, a secret backend URI only reaches that backend.
+func runSign(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	in := fs.String("in", "unsigned_deploy_tx.json", "unsigned transaction to sign")
+	out := fs.String("out", "signed_deploy_tx.json", "where to write the signed transaction")
+	keyFile := fs.String("key-file", "", "file holding the hex private key (default DEPLOYER_PRIVATE_KEY)")
+	envFiles = envfile.FlagsOn(fs)
+	fs.Parse(args)
+
+	unsigned, err := offline.ReadUnsigned(*in)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	if *keyFile != "" {
+		key, err := os.ReadFile(*keyFile)
+		if err != nil {
+			log.Fatalf("❌ Failed to read key file: %v", err)
+		}
+		os.Setenv("DEPLOYER_PRIVATE_KEY", strings.TrimSpace(string(key)))
+	} else if os.Getenv("DEPLOYER_PRIVATE_KEY") == "" {
+		if err := envFiles.Load(); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+	deployer, err := loadDeployerCredentials(ctx)
+	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Show what is being signed so it can be compared with the prepare output
+	fmt.Printf("📋 %s\n", unsigned.Description)
+	fmt.Printf("🔗 Chain ID: %d\n", unsigned.ChainID.ToInt())
+	fmt.Printf("🔐 From: %s\n", unsigned.From.Hex())
+	fmt.Printf("🔢 Nonce: %d, gas %d at %s wei\n", unsigned.Nonce, unsigned.Gas, unsigned.GasPrice.ToInt())
+	if unsigned.ContractAddress != nil {
+		fmt.Printf("📌 Creates contract at: %s (%d bytes of init code)\n", unsigned.ContractAddress.Hex(), len(unsigned.Data))
+	}
+	fmt.Printf("🔏 Signing hash: %s\n", unsigned.SigningHash().Hex())
+
+	signed, err := offline.Sign(ctx, unsigned, deployer)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if err := offline.Write(*out, signed); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("✅ Signed transaction %s\n", signed.Hash.Hex())
+	fmt.Printf("📝 Signed transaction saved to %s; broadcast it with `go run scripts/stage2_deploy_wrapper.go broadcast`\n", *out)
+}
+
+// runBroadcast sends a signed deployment and runs the same verification and
+// conformance checks as a regular deployment.
+func runBroadcast(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("broadcast", flag.ExitOnError)
+	in := fs.String("in", "signed_deploy_tx.json", "signed transaction to broadcast")
+	envFiles = envfile.FlagsOn(fs)
+	fs.Parse(args)
+
+	signed, err := offline.ReadSigned(*in)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	client := connect(ctx)
+	defer client.Close()
+
+	if err := chain.CheckChainID(ctx, client, signed.ChainID.ToInt()); err != nil {
+		var mismatch *chain.ChainMismatchError
+		if errors.As(err, &mismatch) {
+			log.Fatalf("❌ Transaction was signed for chain %d, node is on chain %d", mismatch.Expected, mismatch.Actual)
+		}
+		log.Fatalf("❌ %v", err)
+	}
+	chainID := signed.ChainID.ToInt()
+	fmt.Printf("🔗 Network Chain ID: %d\n", chainID)
+	nonce, err := client.PendingNonceAt(ctx, signed.From)
+	if err != nil {
+		log.Fatalf("❌ Failed to get nonce: %v", err)
+	}
+	if nonce != uint64(signed.Nonce) {
+		log.Printf("⚠️  Deployer pending nonce is %d, transaction uses %d; it may be rejected or stuck", nonce, signed.Nonce)
+	}
+
+	result, err := broadcastDeployment(ctx, client, signed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	accountsOK, failedConformance := validateDeployment(ctx, client, signed.From, chainID, result)
+
 	// Save results
 	if err := saveResults(result); err != nil {
 		log.Fatal(err)
 	}
+	if !accountsOK {
+		log.Fatal("❌ Account state assertions failed (see accountChecks in results_stage2.json)")
+	}
+	if len(failedConformance) > 0 {
+		log.Fatalf("❌ Conformance assertions failed (see %s in results_stage2.json)", strings.Join(failedConformance, ", "))
+	}
 
 	fmt.Println("\n🚀 Deployment successful!")
 	fmt.Printf("📝 Results saved to results_stage2.json\n")
 	fmt.Printf("📌 Contract Address: %s\n", result.ContractAddress)
 }
 
-func loadDeployerCredentials() (*ecdsa.PrivateKey, common.Address, error) {
-	privateKeyHex := os.Getenv("DEPLOYER_PRIVATE_KEY")
-	if privateKeyHex == "" {
-		return nil, common.Address{}, fmt.Errorf("❌ DEPLOYER_PRIVATE_KEY not set in .env")
+// deployProxies deploys n EIP-1167 clones of the wrapper and saves their
+// addresses, one per line, to deployed_proxies.txt.
+func deployProxies(ctx context.Context, client *ethclient.Client, deployer signer.Signer, chainID *big.Int, result *DeploymentResult, n int) error {
+	fmt.Printf("\n🪞 Deploying %d minimal proxies for %s...\n", n, result.ContractAddress)
+	sender := &chain.Sender{Client: client, Signer: deployer, From: deployer.Address(), ChainID: chainID, Role: chain.RoleDeploy}
+	deployed, err := deploy.DeployProxies(ctx, sender, common.HexToAddress(result.ContractAddress), n)
+	result.Proxies = deployed
+
+	var lines []string
+	for _, d := range deployed {
+		fmt.Printf("✅ Proxy %s (gas %s)\n", d.Address, output.Count(d.GasUsed))
+		lines = append(lines, d.Address)
+	}
+	if werr := paths.WriteFile(paths.Work("deployed_proxies.txt"), []byte(strings.Join(lines, "\n"))); werr != nil {
+		return fmt.Errorf("failed to save proxy addresses: %v", werr)
+	}
+	return err
+}
+
+// factoryInput is hashed through the factory-created wrappers, on top of
+// their self-test.
+var factoryInput = []byte("created by a contract")
+
+// factorySalt is the CREATE2 salt. Each run deploys a fresh factory, so
+// the same salt never collides.
+var factorySalt = crypto.Keccak256Hash([]byte("Sha256Wrapper"))
+
+// deployViaFactory deploys artifacts/Sha256WrapperFactory and has it create
+// a wrapper from bytecode with CREATE, then another with CREATE2. Beyond the
+// address derivation and nonce checks, the precompile must answer through
+// the new wrappers as it does through one an EOA deployed.
+func deployViaFactory(ctx context.Context, client *ethclient.Client, deployer signer.Signer, chainID *big.Int, result *DeploymentResult, bytecode string) error {
+	fmt.Println("\n🏭 Deploying a wrapper through Sha256WrapperFactory...")
+	if err := deploy.VerifyArtifact(paths.Artifact("Sha256WrapperFactory")); err != nil {
+		return fmt.Errorf("refusing to deploy the factory: %v", err)
+	}
+	factoryCode, err := paths.ReadHex(paths.Artifact("Sha256WrapperFactory.bin"))
+	if err != nil {
+		return fmt.Errorf("failed to read factory bytecode (compile contracts/Sha256WrapperFactory.sol first): %v", err)
+	}
+	wrapperABI, err := deploy.LoadArtifact(paths.Artifact("Sha256Wrapper"))
+	if err != nil {
+		return err
+	}
+
+	sender := &chain.Sender{Client: client, Signer: deployer, From: deployer.Address(), ChainID: chainID, Role: chain.RoleDeploy}
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "Sha256WrapperFactory"), nil, common.FromHex(factoryCode), deploy.DefaultGasLimit)
+	if err != nil {
+		return fmt.Errorf("factory deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return fmt.Errorf("factory deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+	fmt.Printf("✅ Factory at %s\n", receipt.ContractAddress.Hex())
+
+	var failed []string
+	for _, salt := range []*common.Hash{nil, &factorySalt} {
+		created, err := deploy.DeployViaFactory(chain.WithContract(ctx, "Sha256Wrapper"), sender, receipt.ContractAddress, common.FromHex(bytecode), salt, deploy.FactoryGasLimit)
+		if created != nil {
+			result.Factory = append(result.Factory, created)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ %s wrapper at %s (block %d, gas %s)\n", created.Opcode, created.Address, created.BlockNumber, output.Count(created.GasUsed))
+
+		wrapper := common.HexToAddress(created.Address)
+		call := chain.Check{Name: "sha256Hash through the created wrapper"}
+		outcome, err := precompile.CallWrapper(ctx, client, &wrapperABI.ABI, wrapper, factoryInput)
+		call.Expected = common.Bytes2Hex(outcome.Expected[:])
+		if err != nil {
+			call.Note = err.Error()
+		} else {
+			call.Actual = common.Bytes2Hex(outcome.Returned)
+			call.Passed = outcome.Match()
+		}
+		created.Checks = append(created.Checks, call)
+		created.Checks = append(created.Checks, selfTest(ctx, client, wrapper)...)
+
+		printChecks(fmt.Sprintf("🏭 %s checks:", created.Opcode), created.Checks)
+		if !chain.AllPassed(created.Checks) {
+			failed = append(failed, created.Opcode)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s checks failed (see factory in results_stage2.json)", strings.Join(failed, " and "))
+	}
+	return nil
+}
+
+// deploySuite deploys every contract of a manifest in dependency order and
+// saves their addresses to deployed_addresses.json.
+func deploySuite(ctx context.Context, client *ethclient.Client, deployer signer.Signer, chainID *big.Int, manifestPath string) {
+	manifest, err := deploy.LoadManifest(manifestPath)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	ordered, err := manifest.Order()
+	if err != nil {
+		log.Fatalf("❌ Invalid manifest: %v", err)
+	}
+	fmt.Printf("📋 Deployment order:")
+	for _, c := range ordered {
+		fmt.Printf(" %s", c.Name)
+	}
+	fmt.Println()
+
+	sender := &chain.Sender{Client: client, Signer: deployer, From: deployer.Address(), ChainID: chainID, Role: chain.RoleDeploy}
+	deployed, deployErr := deploy.DeployAll(ctx, sender, manifest, func(c deploy.Contract) {
+		fmt.Printf("📨 Deploying %s...\n", c.Name)
+	})
+	for _, d := range deployed {
+		fmt.Printf("✅ %s at %s (block %d, gas %s, code %s)\n", d.Name, d.Address, d.BlockNumber, output.Count(d.GasUsed), output.Bytes(d.CodeSize))
+	}
+
+	// Save whatever was deployed, even on partial failure
+	addresses := map[string]string{}
+	for _, d := range deployed {
+		addresses[d.Name] = d.Address
+		if d.Name == "Sha256Wrapper" {
+			if err := paths.WriteFile(paths.Work("deployed_address.txt"), []byte(d.Address)); err != nil {
+				log.Fatalf("❌ Failed to save deployed address: %v", err)
+			}
+		}
+	}
+	file, err := json.MarshalIndent(addresses, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal addresses: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("deployed_addresses.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save addresses: %v", err)
+	}
+	file, err = json.MarshalIndent(deployed, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_stage2_suite.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+
+	if deployErr != nil {
+		log.Fatalf("❌ Suite deployment failed: %v", deployErr)
 	}
+	fmt.Println("\n🚀 Suite deployment successful!")
+	fmt.Println("📝 Results saved to results_stage2_suite.json, addresses to deployed_addresses.json")
+}
 
-	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+// loadDeployerCredentials returns the signer DEPLOYER_PRIVATE_KEY holds: a
+// hex private key, or a vault://, awskms:// or gcpkms:// URI.
+func loadDeployerCredentials(ctx context.Context) (signer.Signer, error) {
+	value := os.Getenv("DEPLOYER_PRIVATE_KEY")
+	if value == "" {
+		return nil, fmt.Errorf("❌ DEPLOYER_PRIVATE_KEY not set in .env")
+	}
+	deployer, err := signer.Load(ctx, value)
 	if err != nil {
-		return nil, common.Address{}, fmt.Errorf("❌ Invalid private key: %v", err)
+		return nil, fmt.Errorf("❌ Invalid deployer key: %v", err)
 	}
+	return deployer, nil
+}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, common.Address{}, fmt.Errorf("❌ Failed to cast public key to ECDSA")
+// deployContract deploys the wrapper as a legacy (TxType 0) transaction
+// through chain.Sender, so the BeforeDeploy hooks, spend limit, pending
+// journal and ledger cover it like every other deployment.
+func deployContract(ctx context.Context, client *ethclient.Client, deployer signer.Signer, chainID *big.Int, bytecode string) (*DeploymentResult, error) {
+	sender := &chain.Sender{Client: client, Signer: deployer, From: deployer.Address(), ChainID: chainID, Role: chain.RoleDeploy,
+		GasPrice: big.NewInt(1e9), Type: signer.Legacy}
+
+	fmt.Println("📨 Sending deployment transaction...")
+	// The address saved for stage 3 is the derived one, so Send fails
+	// unless the node agrees on it
+	tx, receipt, err := sender.Send(chain.WithContract(ctx, "Sha256Wrapper"), nil, common.FromHex(strings.TrimSpace(bytecode)),
+		2_000_000) // Fixed gas limit as required
+	if err != nil {
+		if tx != nil {
+			return nil, fmt.Errorf("❌ Deployment %s failed: %v", tx.Hash().Hex(), err)
+		}
+		return nil, fmt.Errorf("❌ Failed to send transaction: %v", err)
 	}
+	fmt.Printf("🔢 Nonce: %d\n", tx.Nonce())
+	fmt.Printf("⛏️  Transaction %s mined\n", tx.Hash().Hex())
 
-	return privateKey, crypto.PubkeyToAddress(*publicKeyECDSA), nil
+	return &DeploymentResult{
+		BlockNumber:     receipt.BlockNumber.Uint64(),
+		TransactionHash: tx.Hash().Hex(),
+		ContractAddress: receipt.ContractAddress.Hex(),
+		GasUsed:         receipt.GasUsed,
+		Status:          uint(receipt.Status),
+		tx:              tx,
+		receipt:         receipt,
+		gasPrice:        sender.GasPrice,
+	}, nil
 }
 
-func deployContract(client *ethclient.Client, privateKey *ecdsa.PrivateKey, fromAddress common.Address, chainID *big.Int, bytecode string) (*DeploymentResult, error) {
+// prepareDeployment builds the unsigned legacy (TxType 0) deployment
+// transaction at the deployer's pending nonce.
+func prepareDeployment(ctx context.Context, client *ethclient.Client, fromAddress common.Address, chainID *big.Int, bytecode string) (*offline.UnsignedTx, error) {
 	// Get nonce
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	nonce, err := client.PendingNonceAt(ctx, fromAddress)
 	if err != nil {
 		return nil, fmt.Errorf("❌ Failed to get nonce: %v", err)
 	}
 	fmt.Printf("🔢 Nonce: %d\n", nonce)
 
-	// Create legacy transaction (TxType 0)
-	txData := &types.LegacyTx{
-		Nonce:    nonce,
-		GasPrice: big.NewInt(1e9), // 1 Gwei
-		Gas:      2_000_000,       // Fixed gas limit as required
-		Value:    big.NewInt(0),
-		Data:     common.FromHex(strings.TrimSpace(bytecode)),
-	}
+	gasPrice := big.NewInt(1e9) // 1 Gwei
+	unsigned := offline.NewUnsigned(chainID, fromAddress, nonce, gasPrice,
+		2_000_000, // Fixed gas limit as required
+		nil, big.NewInt(0), common.FromHex(strings.TrimSpace(bytecode)))
+	unsigned.Description = "Deploy Sha256Wrapper"
+	return unsigned, nil
+}
 
-	tx := types.NewTx(txData)
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+// broadcastDeployment sends a signed deployment transaction and waits for it
+// to be mined.
+func broadcastDeployment(ctx context.Context, client *ethclient.Client, signed *offline.SignedTx) (*DeploymentResult, error) {
+	signedTx, err := signed.Decode()
 	if err != nil {
-		return nil, fmt.Errorf("❌ Failed to sign transaction: %v", err)
+		return nil, fmt.Errorf("❌ %v", err)
+	}
+	if signed.ContractAddress == nil {
+		return nil, fmt.Errorf("❌ Transaction %s is not a contract creation", signed.Hash.Hex())
+	}
+
+	if err := chain.CheckWritable(); err != nil {
+		return nil, fmt.Errorf("❌ %v", err)
+	}
+	cost := new(big.Int).Mul(signed.GasPrice.ToInt(), new(big.Int).SetUint64(uint64(signed.Gas)))
+	cost.Add(cost, signed.Value.ToInt())
+	if err := chain.Charge(chain.RoleDeploy, cost); err != nil {
+		return nil, fmt.Errorf("❌ %v", err)
 	}
 
 	// Send transaction
 	fmt.Println("📨 Sending deployment transaction...")
-	if err := client.SendTransaction(context.Background(), signedTx); err != nil {
-		if !strings.Contains(err.Error(), "already known") {
+	output.Logf(output.ModuleDeploy, output.Verbose, "sending %s: nonce %d, gas %d, %d bytes of init code", signed.Hash.Hex(), signed.Nonce, signed.Gas, len(signed.Data))
+	output.Logf(output.ModuleDeploy, output.Debug, "raw %s", signed.Raw)
+	if err := chain.ClassifySend(client.SendTransaction(ctx, signedTx)); err != nil {
+		if !errors.Is(err, chain.ErrAlreadyKnown) {
+			chain.Refund(chain.RoleDeploy, cost)
 			return nil, fmt.Errorf("❌ Failed to send transaction: %v", err)
 		}
 		fmt.Println("⚠️  Transaction already known by node")
 	}
 
 	// Wait for receipt
-	fmt.Println("⏳ Waiting for transaction to be mined...")
-	receipt, err := waitForReceipt(client, signedTx.Hash())
+	fmt.Printf("⏳ Waiting for transaction %s to be mined...\n", signedTx.Hash().Hex())
+	receipt, err := waitForReceipt(ctx, client, signedTx.Hash())
 	if err != nil {
 		return nil, fmt.Errorf("❌ Failed to get receipt: %v", err)
 	}
-
-	// Get deployed address
-	deployedAddress := crypto.CreateAddress(fromAddress, nonce)
+	// The address saved for stage 3 is the derived one, so the node must
+	// agree on it
+	if err := chain.VerifyCreation(signed.From, signedTx, receipt); err != nil {
+		return nil, fmt.Errorf("❌ %v", err)
+	}
+	if receipt.Status == 1 {
+		err := chain.RecordDeployment(chain.Deployment{
+			Address:  signed.ContractAddress.Hex(),
+			Contract: "Sha256Wrapper",
+			ChainID:  signed.ChainID.ToInt().String(),
+			Deployer: signed.From.Hex(),
+			Role:     chain.RoleDeploy,
+			Tx:       signedTx.Hash().Hex(),
+			Block:    receipt.BlockNumber.Uint64(),
+		})
+		if err != nil {
+			fmt.Printf("⚠️  Deployment not recorded in %s: %v\n", chain.DeploymentsFile, err)
+		}
+	}
 
 	return &DeploymentResult{
 		BlockNumber:     receipt.BlockNumber.Uint64(),
 		TransactionHash: signedTx.Hash().Hex(),
-		ContractAddress: deployedAddress.Hex(),
+		ContractAddress: signed.ContractAddress.Hex(),
 		GasUsed:         receipt.GasUsed,
 		Status:          uint(receipt.Status),
+		tx:              signedTx,
+		receipt:         receipt,
+		gasPrice:        signed.GasPrice.ToInt(),
 	}, nil
 }
 
-func verifyDeployment(client *ethclient.Client, result *DeploymentResult) error {
+func verifyDeployment(ctx context.Context, client *ethclient.Client, result *DeploymentResult) error {
 	// Check transaction status
 	if result.Status != 1 {
 		return fmt.Errorf("❌ Contract deployment failed (reverted)! Status: %d, Gas used: %d", result.Status, result.GasUsed)
@@ -168,7 +691,7 @@ func verifyDeployment(client *ethclient.Client, result *DeploymentResult) error
 	fmt.Printf("✅ Transaction mined in block %d\n", result.BlockNumber)
 
 	// Verify contract code exists
-	code, err := client.CodeAt(context.Background(), common.HexToAddress(result.ContractAddress), nil)
+	code, err := client.CodeAt(ctx, common.HexToAddress(result.ContractAddress), nil)
 	if err != nil {
 		return fmt.Errorf("❌ Failed to get contract code: %v", err)
 	}
@@ -179,13 +702,86 @@ func verifyDeployment(client *ethclient.Client, result *DeploymentResult) error
 	}
 
 	result.VerificationPass = true
-	fmt.Printf("✅ Contract verification passed - Code size: %d bytes\n", result.BytecodeSize)
+	fmt.Printf("✅ Contract verification passed - Code size: %s\n", output.Bytes(result.BytecodeSize))
 	return nil
 }
 
+// checkAccountState compares deployer and contract account state before and
+// after the deployment block and prints each assertion.
+func checkAccountState(ctx context.Context, client *ethclient.Client, deployer common.Address, chainID *big.Int, result *DeploymentResult) bool {
+	chainProfile, err := profile.Resolve(os.Getenv("CHAIN_PROFILE"), chainID.Uint64())
+	if err != nil {
+		log.Printf("⚠️  %v, assuming keccak MPT state", err)
+		chainProfile = profile.Detect(chainID.Uint64())
+	}
+	if err := chainProfile.CheckChain(chainID.Uint64()); err != nil {
+		log.Printf("⚠️  %v; costs may be misreported", err)
+	}
+	price := result.receipt.EffectiveGasPrice
+	if price == nil {
+		price = result.gasPrice
+	}
+	if price != nil {
+		cost := new(big.Int).Mul(new(big.Int).SetUint64(result.receipt.GasUsed), price)
+		result.Cost = chainProfile.NativeCurrency.Format(cost)
+		fmt.Printf("💸 Deployment cost: %s\n", result.Cost)
+	}
+
+	result.AccountChecks = chain.CheckDeployment(ctx, client, chain.DeploymentState{
+		Deployer:     deployer,
+		Contract:     common.HexToAddress(result.ContractAddress),
+		Receipt:      result.receipt,
+		GasPrice:     result.gasPrice,
+		ProveStorage: chainProfile.StateTrie == profile.TrieMPT,
+	})
+
+	printChecks("🧾 Account state checks:", result.AccountChecks)
+	return chain.AllPassed(result.AccountChecks)
+}
+
+// checkConformance runs the fee, receipt and block checks on the deployment
+// transaction and returns the result fields of the groups that failed.
+func checkConformance(ctx context.Context, client *ethclient.Client, result *DeploymentResult) []string {
+	result.FeeChecks = chain.CheckFees(ctx, client, result.tx, result.receipt)
+	result.ReceiptChecks = chain.CheckReceipt(ctx, client, result.tx, result.receipt)
+	result.BlockChecks = chain.CheckBlock(ctx, client, result.tx, result.receipt)
+
+	var failed []string
+	for _, group := range []struct {
+		title, field string
+		checks       []chain.Check
+	}{
+		{"⛽ Fee checks:", "feeChecks", result.FeeChecks},
+		{"🧾 Receipt checks:", "receiptChecks", result.ReceiptChecks},
+		{"🧱 Block checks:", "blockChecks", result.BlockChecks},
+	} {
+		printChecks(group.title, group.checks)
+		if !chain.AllPassed(group.checks) {
+			failed = append(failed, group.field)
+		}
+	}
+	return failed
+}
+
+func printChecks(title string, checks []chain.Check) {
+	fmt.Println("\n" + title)
+	for _, check := range checks {
+		switch {
+		case check.Skipped:
+			fmt.Printf("⏭️  %s: skipped (%s)\n", check.Name, check.Note)
+		case check.Passed:
+			fmt.Printf("✅ %s\n", check.Name)
+		case check.Note != "":
+			fmt.Printf("❌ %s: %s\n", check.Name, check.Note)
+		default:
+			fmt.Printf("❌ %s: expected %s, got %s\n", check.Name, check.Expected, check.Actual)
+		}
+	}
+}
+
 func saveResults(result *DeploymentResult) error {
 	// Save deployed address
-	if err := os.WriteFile("deployed_address.txt", []byte(result.ContractAddress), 0644); err != nil {
+	if err := paths.WriteFile(paths.Work("deployed_address.txt"), []byte(result.ContractAddress)); err != nil {
 		return fmt.Errorf("❌ Failed to save deployed address: %v", err)
 	}
 
@@ -195,28 +791,12 @@ func saveResults(result *DeploymentResult) error {
 		return fmt.Errorf("❌ Failed to marshal results: %v", err)
 	}
 
-	if err := os.WriteFile("results_stage2.json", file, 0644); err != nil {
+	if err := paths.WriteFile(paths.Work("results_stage2.json"), file); err != nil {
 		return fmt.Errorf("❌ Failed to save results: %v", err)
 	}
 	return nil
 }
 
-func waitForReceipt(client *ethclient.Client, txHash common.Hash) (*types.Receipt, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
-	defer cancel()
-
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-ticker.C:
-			receipt, err := client.TransactionReceipt(context.Background(), txHash)
-			if err == nil && receipt != nil {
-				return receipt, nil
-			}
-		}
-	}
+func waitForReceipt(ctx context.Context, client *ethclient.Client, txHash common.Hash) (*types.Receipt, error) {
+	return chain.WaitForReceipt(ctx, client, txHash, 3*time.Minute)
 }
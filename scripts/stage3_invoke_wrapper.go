@@ -2,46 +2,155 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/joho/godotenv"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"cdk-erigon-precompile/internal/hooks"
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/explain"
+	"cdk-erigon-precompile/pkg/failfast"
+	"cdk-erigon-precompile/pkg/gascap"
+	"cdk-erigon-precompile/pkg/golden"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/plugin"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/registry"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/table"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/tracediff"
+	"cdk-erigon-precompile/pkg/vector"
 )
 
+// stage3Name names the stage to hooks.
+const stage3Name = "Stage 3 - Invoke Solidity Wrapper"
+
 type TestResult struct {
-	Input              string `json:"input"`
+	vector.Vector
+	CaseID             string `json:"caseId"`
 	ExpectedHash       string `json:"expectedHash"`
 	ContractHash       string `json:"contractHash"`
 	Match              bool   `json:"match"`
 	ContractAddress    string `json:"contractAddress"`
 	WrapperCallSuccess bool   `json:"wrapperCallSuccess"`
+	// SkipReason is set for vectors that were not sent: those larger than
+	// the node accepts in an eth_call, and those left when the failure
+	// policy stopped the run. Skip has its reason code.
+	Skipped    bool         `json:"skipped,omitempty"`
+	SkipReason string       `json:"skipReason,omitempty"`
+	Skip       *skip.Reason `json:"skip,omitempty"`
+	// Error is set when the wrapper call itself failed, or a BeforeCase
+	// hook vetoed it; HookError when an AfterCase hook failed the case.
+	Error     string `json:"error,omitempty"`
+	HookError string `json:"hookError,omitempty"`
+
+	GasEstimate uint64        `json:"gasEstimate,omitempty"`
+	GoldenGas   *golden.Check `json:"goldenGas,omitempty"`
+	LatencyMs   float64       `json:"latencyMs,omitempty"`
+
+	// FromMatrix repeats the call from other senders with --from-matrix;
+	// FromInvariant is false if any of them got a different answer.
+	FromMatrix    []FromCall `json:"fromMatrix,omitempty"`
+	FromInvariant *bool      `json:"fromInvariant,omitempty"`
+}
+
+// passed reports whether the case passed as the stage judges it: a match,
+// the same answer from every caller of the matrix and no hook failure.
+func (r TestResult) passed() bool {
+	return r.Match && r.Error == "" && r.HookError == "" && (r.FromInvariant == nil || *r.FromInvariant)
+}
+
+// FromCall is the wrapper call repeated from one sender.
+type FromCall struct {
+	Caller string `json:"caller"`
+	From   string `json:"from"`
+	Hash   string `json:"hash,omitempty"`
+	Same   bool   `json:"same"`
+	Error  string `json:"error,omitempty"`
+}
+
+// resultColumns are the columns of the result table; defaultColumns are
+// shown without --columns.
+var (
+	resultColumns = []table.Column{
+		{Name: "case"}, {Name: "input"}, {Name: "precompile"}, {Name: "target"}, {Name: "result"},
+		{Name: "gas", Numeric: true}, {Name: "latency", Numeric: true},
+		{Name: "expected"}, {Name: "got"}, {Name: "tags"}, {Name: "note"},
+	}
+	defaultColumns = []string{"case", "input", "target", "result", "gas", "latency"}
+)
+
+// caller is a from address of the matrix.
+type caller struct {
+	label string
+	addr  common.Address
 }
 
 func main() {
+	output.Setup()
+
+	viaProxies := flag.Bool("via-proxies", false, "also run every vector through the minimal proxies in deployed_proxies.txt")
+	goldenDir := flag.String("golden-dir", "golden", "directory holding per-fork gas golden files")
+	updateGolden := flag.Bool("update-golden", false, "record observed gas as the new golden values")
+	vectorsFrom := flag.String("vectors", "", "vector set to use instead of the built-in vectors: path or URL, optionally suffixed with #sha256=<hex>")
+	gasCapFile := flag.String("gas-cap-file", paths.Work(gascap.DefaultPath), "gas cap report of scripts/gas_cap.go; vectors beyond the cap are skipped")
+	fromMatrix := flag.Bool("from-matrix", false, "repeat every call from the zero address, a precompile, the wrapper and funded and unfunded EOAs, failing if the answer changes")
+	explainCase := flag.String("explain", "", "run only the vector with this case ID (or a unique prefix of it) and print everything about the call")
+	columns := table.Flags(defaultColumns, resultColumns)
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	failures := failfast.Flags()
+	flag.Parse()
+	if err := columns.Check(resultColumns); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
 	// Load environment variables
-	if err := godotenv.Load(".env"); err != nil {
-		log.Fatal("❌ Error loading .env file")
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	// External validators judge each case alongside the stage's own checks
+	plugins, err := plugin.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
 	}
+	defer plugin.Register(plugins)()
 
 	// Initialize Ethereum client
 	rpcHost := os.Getenv("RPC_HOST")
 	rpcPort := os.Getenv("RPC_PORT")
 	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
 
-	client, err := ethclient.Dial(rpcURL)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
 	}
 	defer client.Close()
 	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_stage3.json")
 
 	// Read deployed contract address
 	wrapperAddress, err := getDeployedAddress()
@@ -51,142 +160,558 @@ func main() {
 	fmt.Printf("📌 Using contract at: %s\n", wrapperAddress.Hex())
 
 	// Verify contract is deployed
-	if err := verifyContract(client, wrapperAddress); err != nil {
+	if err := verifyContract(ctx, client, wrapperAddress); err != nil {
 		log.Fatal(err)
 	}
 
-	// Load contract ABI
-	parsedABI, err := loadContractABI()
+	// A wrapper deployed from older artifacts, or an address left over
+	// from another contract, would otherwise fail every call with an
+	// opaque revert or unpack error. Detection reads the dispatch table,
+	// falling back to probe calls for proxies that have none
+	wrapperVersion, err := precompile.ResolveWrapper(ctx, client, wrapperAddress)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("❌ %v; redeploy with stage 2 or add its ABI to %s", err, paths.Artifact(precompile.WrapperABIDir))
+	}
+	parsedABI := wrapperVersion.ABI()
+	if wrapperVersion.Current() {
+		fmt.Println("✅ Deployed code speaks the current wrapper ABI")
+	} else {
+		fmt.Printf("⚠️  Deployed wrapper predates the current artifacts, using ABI %s\n", wrapperVersion)
 	}
 
 	// Test vectors
-	testInputs := []string{
-		"hello world",
-		"",
-		"The quick brown fox jumps over the lazy dog",
-		"cdk-erigon",
+	vectors := []vector.Vector{
+		vector.New([]byte("hello world"), tags.Smoke, tags.Gas),
+		vector.New([]byte(""), tags.Smoke, tags.Gas),
+		vector.New([]byte("The quick brown fox jumps over the lazy dog"), tags.Gas),
+		vector.New([]byte("cdk-erigon"), tags.Gas),
+		vector.New([]byte{0x00, 0xff, 0xfe, 0x80}, tags.Binary),
+	}
+	if *vectorsFrom != "" {
+		name, loaded, err := registry.NewFetcher("").LoadVectors(ctx, registry.ParseSource(*vectorsFrom))
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		vectors = loaded
+		fmt.Printf("📥 Loaded vector set %q: %d vectors\n", name, len(vectors))
+	}
+	if *explainCase != "" {
+		v, err := findCase(vectors, *explainCase)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if !explainVector(ctx, rpcURL, wrapperAddress, parsedABI, v) {
+			os.Exit(1)
+		}
+		return
 	}
 
-	var results []TestResult
+	testInputs := vector.Select(vectors, tagFilter)
+	fmt.Printf("🏷️  Vectors: %d selected (%s)\n", len(testInputs), tagFilter)
+	if len(testInputs) == 0 {
+		return
+	}
 
-	// Test each input
-	for _, input := range testInputs {
-		result, err := testHashFunction(client, wrapperAddress, parsedABI, []byte(input))
+	gasCap, err := gascap.Load(*gasCapFile, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	targets := []common.Address{wrapperAddress}
+	if *viaProxies {
+		proxies, err := getProxyAddresses()
 		if err != nil {
-			log.Printf("⚠️  Test failed for input '%s': %v", input, err)
-			continue
+			log.Fatal(err)
+		}
+		for _, proxy := range proxies {
+			if err := verifyContract(ctx, client, proxy); err != nil {
+				log.Fatal(err)
+			}
 		}
-		results = append(results, *result)
+		fmt.Printf("🪞 Also invoking through %d minimal proxies\n", len(proxies))
+		targets = append(targets, proxies...)
+	}
+
+	var callers []caller
+	if *fromMatrix {
+		callers = matrixCallers(ctx, client, wrapperAddress)
+	}
+
+	var results []TestResult
+
+	// Test each input against every target, unless the failure policy
+	// stopped the run; the cases left are recorded as skipped
+	for _, target := range targets {
+		for _, input := range testInputs {
+			var reason *skip.Reason
+			if failures.Stopped() {
+				reason = skip.New(skip.FailurePolicy, failures.Reason())
+			} else if capped := skipReason(gasCap, parsedABI, input); capped != "" {
+				reason = skip.New(skip.GasCap, capped)
+			}
+			if reason != nil {
+				results = append(results, TestResult{Vector: input, CaseID: input.ID(), ContractAddress: target.Hex(),
+					Skipped: true, SkipReason: reason.Detail, Skip: reason})
+				continue
+			}
+			hookCase := hooks.Case{Stage: stage3Name, ID: input.ID(), Precompile: precompile.SHA256Address.Hex(), Input: input.Bytes(), Target: target.Hex()}
+			var result *TestResult
+			err := hooks.BeforeCase(ctx, hookCase)
+			if err == nil {
+				result, err = testHashFunction(ctx, client, target, parsedABI, input)
+			}
+			if err != nil {
+				log.Printf("⚠️  Test failed for input %s at %s [case %s]: %v", input.Display(), target.Hex(), input.ID(), err)
+				result = &TestResult{Vector: input, CaseID: input.ID(), ContractAddress: target.Hex(), Error: err.Error()}
+			} else if *fromMatrix {
+				invariant := true
+				for _, c := range callers {
+					call := callFrom(ctx, client, target, parsedABI, input, c, result.ContractHash)
+					invariant = invariant && call.Same
+					result.FromMatrix = append(result.FromMatrix, call)
+				}
+				result.FromInvariant = &invariant
+			}
+			passed := result.passed()
+			if err := hooks.AfterCase(ctx, hooks.Outcome{Case: hookCase, Passed: passed, Error: result.Error, Output: common.FromHex(result.ContractHash)}); err != nil {
+				result.HookError = err.Error()
+				passed = false
+			}
+			if !passed {
+				failures.Fail()
+			}
+			results = append(results, *result)
+		}
+	}
+
+	// Compare gas of the canonical vectors against this fork's golden values
+	gasFailures, err := checkGoldenGas(ctx, client, parsedABI, wrapperAddress, results, *goldenDir, *updateGolden)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Save results
 	if err := saveTestResults(results); err != nil {
 		log.Fatal(err)
 	}
+	anchors.Finish(ctx)
 
 	fmt.Println("\n🧪 Test results:")
+	if err := resultTable(results).Render(os.Stdout, *columns); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	for _, res := range results {
+		if res.Error != "" {
+			fmt.Printf("❌ Case %s via %s\n  Error: %s\n", res.CaseID, res.ContractAddress, res.Error)
+		} else if !res.Skipped && !res.Match {
+			fmt.Printf("❌ Case %s via %s\n  Expected: %s\n  Got:      %s\n", res.CaseID, res.ContractAddress, res.ExpectedHash, res.ContractHash)
+		}
+		if res.HookError != "" {
+			fmt.Printf("❌ Case %s via %s\n  %s\n", res.CaseID, res.ContractAddress, res.HookError)
+		}
+		for _, call := range res.FromMatrix {
+			if !call.Same {
+				fmt.Printf("❌ Case %s from %s (%s): %s%s\n", res.CaseID, call.Caller, call.From, call.Hash, call.Error)
+			}
+		}
+	}
+	variant, hookFailures := 0, 0
+	run := hooks.Run{Stage: stage3Name, Results: paths.Work("results_stage3.json")}
 	for _, res := range results {
-		status := "❌"
-		if res.Match {
-			status = "✅"
+		if res.FromInvariant != nil && !*res.FromInvariant {
+			variant++
+		}
+		if res.HookError != "" {
+			hookFailures++
+		}
+		switch {
+		case res.Skipped:
+			run.Skipped++
+		case res.passed():
+			run.Passed++
+		default:
+			run.Failed++
+		}
+	}
+	if *fromMatrix {
+		status := "✅"
+		if variant > 0 {
+			status = "❌"
 		}
-		fmt.Printf("%s Input: '%s'\n  Expected: %s\n  Got:      %s\n",
-			status, res.Input, res.ExpectedHash, res.ContractHash)
+		fmt.Printf("\n%s From-address matrix: %d callers, %d vectors answered differently for some caller\n", status, len(callers), variant)
 	}
 	fmt.Println("\n📝 Results saved to results_stage3.json")
+
+	if err := hooks.AfterRun(ctx, run); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if failures.Stopped() {
+		log.Fatalf("❌ Stage 3 %s", failures.Reason())
+	}
+	if hookFailures > 0 {
+		log.Fatalf("❌ %d cases failed an AfterCase hook", hookFailures)
+	}
+	if variant > 0 {
+		log.Fatalf("❌ %d vectors got answers that depend on the from address", variant)
+	}
+	if gasFailures > 0 {
+		log.Fatalf("❌ %d vectors used different gas than the golden values or have none (rerun with --update-golden if the repricing or the vector is expected)", gasFailures)
+	}
+}
+
+// checkGoldenGas estimates the gas of every gas-tagged vector invoked
+// directly on the wrapper and compares it with the golden file of the node's
+// fork. A vector without a golden value fails like a mismatch unless update
+// is set. Calls through proxies are skipped since their overhead isn't
+// canonical.
+func checkGoldenGas(ctx context.Context, client *ethclient.Client, parsedABI *abi.ABI, wrapperAddress common.Address, results []TestResult, dir string, update bool) (int, error) {
+	label, err := golden.ForkLabel(ctx, client)
+	if err != nil {
+		return 0, fmt.Errorf("❌ %v", err)
+	}
+	table, err := golden.Load(dir, label)
+	if err != nil {
+		return 0, fmt.Errorf("❌ %v", err)
+	}
+	fmt.Printf("\n⛽ Gas golden file: %s\n", table.Path())
+
+	failures := 0
+	for i := range results {
+		res := &results[i]
+		if res.Skipped || res.Error != "" || res.ContractAddress != wrapperAddress.Hex() || !slices.Contains(res.Tags, tags.Gas) {
+			continue
+		}
+		callData, err := parsedABI.Pack("sha256Hash", res.Bytes())
+		if err != nil {
+			return 0, fmt.Errorf("failed to pack ABI call: %v", err)
+		}
+		gas, err := client.EstimateGas(ctx, ethereum.CallMsg{To: &wrapperAddress, Data: callData})
+		if err != nil {
+			log.Printf("⚠️  Gas estimate failed for input %s: %v", res.Display(), err)
+			continue
+		}
+		res.GasEstimate = gas
+
+		check := table.Check(golden.Key("sha256Hash", res.Bytes()), gas, update)
+		res.GoldenGas = &check
+		switch {
+		case check.Status == golden.StatusMatch:
+			fmt.Printf("✅ %s: %d gas\n", check.Key, gas)
+		case check.Status == golden.StatusNew && update:
+			fmt.Printf("🆕 %s: %d gas recorded\n", check.Key, gas)
+		case check.Status == golden.StatusNew:
+			fmt.Printf("❌ %s: %d gas, no golden value\n", check.Key, gas)
+			failures++
+		default:
+			fmt.Printf("❌ %s: %d gas, golden %d\n", check.Key, gas, check.Expected)
+			if !update {
+				failures++
+			}
+		}
+	}
+
+	if err := table.Save(); err != nil {
+		return 0, fmt.Errorf("❌ %v", err)
+	}
+	return failures, nil
 }
 
 func getDeployedAddress() (common.Address, error) {
-	addrBytes, err := os.ReadFile("deployed_address.txt")
+	address, err := paths.ReadAddress(paths.Work("deployed_address.txt"))
 	if err != nil {
 		return common.Address{}, fmt.Errorf("❌ Failed to read deployed address: %v", err)
 	}
-	deployedAddrStr := strings.TrimSpace(string(addrBytes))
-	return common.HexToAddress(deployedAddrStr), nil
+	return address, nil
 }
 
-func verifyContract(client *ethclient.Client, address common.Address) error {
-	code, err := client.CodeAt(context.Background(), address, nil)
+// getProxyAddresses reads the clone addresses written by stage 2 --proxies.
+func getProxyAddresses() ([]common.Address, error) {
+	proxies, err := paths.ReadAddresses(paths.Work("deployed_proxies.txt"))
 	if err != nil {
-		return fmt.Errorf("❌ Failed to get contract code: %v", err)
+		return nil, fmt.Errorf("❌ Failed to read proxy addresses (run stage 2 with --proxies): %v", err)
 	}
-	if len(code) == 0 {
-		return fmt.Errorf("❌ No contract code found at address %s", address.Hex())
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("❌ deployed_proxies.txt lists no proxies")
 	}
-	fmt.Printf("✅ Contract verified (code size: %d bytes)\n", len(code))
-	return nil
+	return proxies, nil
 }
 
-func loadContractABI() (*abi.ABI, error) {
-	abiBytes, err := os.ReadFile("artifacts/Sha256Wrapper.abi")
+func verifyContract(ctx context.Context, client *ethclient.Client, address common.Address) error {
+	size, err := precompile.CodeSize(ctx, client, address)
 	if err != nil {
-		return nil, fmt.Errorf("❌ Failed to read ABI: %v", err)
+		return fmt.Errorf("❌ %v", err)
 	}
+	fmt.Printf("✅ Contract verified (code size: %d bytes)\n", size)
+	return nil
+}
 
-	parsedABI, err := abi.JSON(strings.NewReader(string(abiBytes)))
+func testHashFunction(ctx context.Context, client *ethclient.Client, wrapperAddress common.Address, parsedABI *abi.ABI, v vector.Vector) (*TestResult, error) {
+	start := time.Now()
+	outcome, err := precompile.CallWrapper(ctx, client, parsedABI, wrapperAddress, v.Bytes())
 	if err != nil {
-		return nil, fmt.Errorf("❌ Failed to parse ABI: %v", err)
+		return nil, err
 	}
+	latency := float64(time.Since(start)) / float64(time.Millisecond)
+
+	return &TestResult{
+		Vector:             v,
+		CaseID:             v.ID(),
+		ExpectedHash:       fmt.Sprintf("%x", outcome.Expected),
+		ContractHash:       fmt.Sprintf("%x", outcome.Returned),
+		Match:              outcome.Match(),
+		ContractAddress:    wrapperAddress.Hex(),
+		WrapperCallSuccess: true,
+		LatencyMs:          latency,
+	}, nil
+}
 
-	return &parsedABI, nil
+// resultTable lays the results out one row per vector and target.
+func resultTable(results []TestResult) *table.Table {
+	t := &table.Table{Columns: resultColumns}
+	for _, res := range results {
+		marker, result, gas, latency := "❌", "mismatch", "-", "-"
+		note := res.SkipReason
+		switch {
+		case res.Skipped:
+			marker, result = "⏭️", "skipped"
+		case res.Error != "":
+			result, note = "error", res.Error
+		case res.Match:
+			marker, result = "✅", "match"
+		}
+		if res.GasEstimate > 0 {
+			gas = strconv.FormatUint(res.GasEstimate, 10)
+		}
+		if !res.Skipped {
+			latency = fmt.Sprintf("%.2f", res.LatencyMs)
+		}
+		if res.FromInvariant != nil && !*res.FromInvariant {
+			marker, result = "❌", result+", caller-dependent"
+		}
+		t.Add(marker, map[string]string{
+			"case":       res.CaseID,
+			"input":      table.Shorten(res.Display(), 34),
+			"precompile": "0x02",
+			"target":     res.ContractAddress,
+			"result":     result,
+			"gas":        gas,
+			"latency":    latency,
+			"expected":   res.ExpectedHash,
+			"got":        res.ContractHash,
+			"tags":       strings.Join(res.Tags, ","),
+			"note":       note,
+		})
+	}
+	return t
 }
 
-func testHashFunction(client *ethclient.Client, wrapperAddress common.Address, parsedABI *abi.ABI, input []byte) (*TestResult, error) {
-	// Calculate expected hash locally
-	expected := sha256.Sum256(input)
-	inputStr := string(input)
-	if len(inputStr) > 20 {
-		inputStr = inputStr[:20] + "..."
+// skipReason checks the wrapper's calldata against the sha256 cap; the
+// contract's own overhead is small next to the calldata of inputs that
+// large.
+func skipReason(gasCap *gascap.Report, parsedABI *abi.ABI, v vector.Vector) string {
+	callData, err := parsedABI.Pack("sha256Hash", v.Bytes())
+	if err != nil {
+		return ""
 	}
+	return gasCap.SkipReason(gascap.SHA256.Name, callData)
+}
 
-	// Pack the function call
-	callData, err := parsedABI.Pack("sha256Hash", input)
+func saveTestResults(results []TestResult) error {
+	file, err := json.MarshalIndent(results, "", "  ")
 	if err != nil {
-		return nil, fmt.Errorf("failed to pack ABI call: %v", err)
+		return fmt.Errorf("failed to marshal results: %v", err)
+	}
+
+	if err := paths.WriteFile(paths.Work("results_stage3.json"), file); err != nil {
+		return fmt.Errorf("failed to save results: %v", err)
 	}
+	return nil
+}
 
-	// Execute the call
-	msg := ethereum.CallMsg{
-		To:   &wrapperAddress,
-		Data: callData,
+// findCase returns the vector whose case ID starts with id.
+func findCase(vectors []vector.Vector, id string) (vector.Vector, error) {
+	id = strings.ToLower(strings.TrimPrefix(id, "0x"))
+	var found []vector.Vector
+	for _, v := range vectors {
+		if strings.HasPrefix(v.ID(), id) {
+			found = append(found, v)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		ids := make([]string, len(vectors))
+		for i, v := range vectors {
+			ids[i] = v.ID()
+		}
+		return vector.Vector{}, fmt.Errorf("no vector has case ID %s (have %s)", id, strings.Join(ids, ", "))
+	case len(found) > 1 && found[0].ID() != found[len(found)-1].ID():
+		return vector.Vector{}, fmt.Errorf("case ID %s is ambiguous, give more digits", id)
 	}
+	return found[0], nil
+}
 
-	result, err := client.CallContract(context.Background(), msg, nil)
+// explainVector runs v through the wrapper alone and prints every layer of
+// the call: the calldata and its decoding, the raw JSON-RPC exchanges, the
+// local reference computation, the trace around the precompile call and
+// the answers side by side. It reports whether the wrapper matched.
+func explainVector(ctx context.Context, rpcURL string, wrapperAddress common.Address, parsedABI *abi.ABI, v vector.Vector) bool {
+	recorder := &explain.Recorder{Base: &rpcclient.LogTransport{}}
+	auth, err := rpcclient.AuthOptions()
 	if err != nil {
-		return nil, fmt.Errorf("contract call failed: %v", err)
+		log.Fatalf("❌ %v", err)
 	}
+	rc, err := rpc.DialOptions(ctx, rpcURL, append(auth, rpc.WithHTTPClient(&http.Client{Transport: recorder}))...)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer rc.Close()
+	client := ethclient.NewClient(rc)
+
+	fmt.Printf("\n🔎 Case %s: input %s (%d bytes, tags %v) via %s\n", v.ID(), v.Display(), len(v.Input), v.Tags, wrapperAddress.Hex())
 
-	// Unpack the result
-	unpacked, err := parsedABI.Unpack("sha256Hash", result)
+	method := parsedABI.Methods["sha256Hash"]
+	callData, err := parsedABI.Pack("sha256Hash", v.Bytes())
 	if err != nil {
-		return nil, fmt.Errorf("failed to unpack result: %v", err)
+		log.Fatalf("❌ Failed to pack ABI call: %v", err)
+	}
+	fmt.Printf("\n── Calldata (%d bytes)\n", len(callData))
+	for _, w := range explain.Calldata(method, callData) {
+		fmt.Printf("  0x%04x  %-64x  %s\n", w.Offset, w.Data, w.Meaning)
+	}
+	fmt.Printf("\n── Decoded ABI\n  %s\n", method.Sig)
+	if args, err := method.Inputs.Unpack(callData[4:]); err != nil {
+		fmt.Printf("  ❌ %v\n", err)
+	} else {
+		for i, arg := range args {
+			fmt.Printf("  %s %s = %s\n", method.Inputs[i].Type, method.Inputs[i].Name, formatArg(arg))
+		}
 	}
 
-	hashBytes, ok := unpacked[0].([32]byte)
-	if !ok {
-		return nil, fmt.Errorf("unexpected return type: %T", unpacked[0])
+	wrapper, wrapperErr := precompile.CallWrapper(ctx, client, parsedABI, wrapperAddress, v.Bytes())
+	printExchanges("eth_call through the wrapper", recorder.Take())
+	direct, directErr := precompile.CallSHA256(ctx, client, v.Bytes())
+	printExchanges("eth_call to 0x02 directly", recorder.Take())
+
+	fmt.Println("\n── Local reference computation")
+	for _, step := range explain.SHA256Steps(v.Bytes()) {
+		fmt.Printf("  %-15s %s\n", step.Name, step.Value)
 	}
 
-	return &TestResult{
-		Input:              string(input),
-		ExpectedHash:       fmt.Sprintf("%x", expected),
-		ContractHash:       fmt.Sprintf("%x", hashBytes),
-		Match:              hashBytes == expected,
-		ContractAddress:    wrapperAddress.Hex(),
-		WrapperCallSuccess: true,
-	}, nil
+	fmt.Println("\n── Trace around the precompile call")
+	trace, err := tracediff.Call(ctx, rc, tracediff.CallArgs{To: &wrapperAddress, Data: callData}, "latest")
+	recorder.Take()
+	if err != nil {
+		fmt.Printf("  ⏭️  %v\n", err)
+	} else if window, first, at := explain.TraceWindow(trace, precompile.SHA256Address, 6, 6); at < 0 {
+		fmt.Printf("  ⚠️  The wrapper never called 0x02 in %d steps (failed: %t, return %s)\n", len(trace.StructLogs), trace.Failed, trace.ReturnValue)
+	} else {
+		for i, step := range window {
+			marker := " "
+			if first+i == at {
+				marker = "→"
+			}
+			fmt.Printf("  %s %6d  pc=%-5d %-14s gas=%-9d cost=%-7d depth=%d%s\n",
+				marker, first+i, step.PC, step.Op, step.Gas, step.GasCost, step.Depth, stepError(step))
+		}
+		fmt.Printf("  %d steps, %d gas, failed: %t\n", len(trace.StructLogs), trace.Gas, trace.Failed)
+	}
+
+	fmt.Println("\n── Side by side")
+	expected := fmt.Sprintf("%x", wrapper.Expected)
+	rows := []explain.Row{
+		{Label: "digest via wrapper", Left: expected, Right: answer(wrapper, wrapperErr)},
+		{Label: "digest from 0x02", Left: expected, Right: answer(direct, directErr)},
+	}
+	if directErr == nil {
+		rows = append(rows, explain.Row{Label: "0x02 output length", Left: "32", Right: fmt.Sprint(len(direct.Returned))})
+	}
+	if err := explain.SideBySide(os.Stdout, "local reference", "node", rows); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	matched := wrapperErr == nil && wrapper.Match()
+	if matched {
+		fmt.Printf("\n✅ Case %s matches\n", v.ID())
+	} else {
+		fmt.Printf("\n❌ Case %s does not match\n", v.ID())
+	}
+	return matched
 }
 
-func saveTestResults(results []TestResult) error {
-	file, err := json.MarshalIndent(results, "", "  ")
+func printExchanges(title string, exchanges []explain.Exchange) {
+	fmt.Printf("\n── %s\n", title)
+	for _, e := range exchanges {
+		fmt.Printf("  --> %s\n", strings.ReplaceAll(explain.Indent(e.Request), "\n", "\n      "))
+		if e.Error != "" {
+			fmt.Printf("  ❌ %s\n", e.Error)
+			continue
+		}
+		fmt.Printf("  <-- HTTP %d %s\n", e.Status, strings.ReplaceAll(explain.Indent(e.Response), "\n", "\n      "))
+	}
+}
+
+func formatArg(arg any) string {
+	if b, ok := arg.([]byte); ok {
+		return vector.New(b).Display()
+	}
+	return fmt.Sprint(arg)
+}
+
+func answer(o precompile.Outcome, err error) string {
 	if err != nil {
-		return fmt.Errorf("failed to marshal results: %v", err)
+		return "error: " + err.Error()
 	}
+	return fmt.Sprintf("%x", o.Returned)
+}
 
-	if err := os.WriteFile("results_stage3.json", file, 0644); err != nil {
-		return fmt.Errorf("failed to save results: %v", err)
+func stepError(s tracediff.Step) string {
+	if s.Error == "" {
+		return ""
 	}
-	return nil
+	return "  error: " + s.Error
+}
+
+// matrixCallers are the senders of the from-address matrix. The funded EOA
+// is the fund role's account, left out if it isn't configured; the
+// unfunded one is a fresh key.
+func matrixCallers(ctx context.Context, client *ethclient.Client, wrapperAddress common.Address) []caller {
+	callers := []caller{
+		{"zero address", common.Address{}},
+		{"precompile 0x02", precompile.SHA256Address},
+		{"wrapper contract", wrapperAddress},
+	}
+	if funded, err := chain.RoleAddress(ctx, chain.RoleFund); err != nil {
+		fmt.Printf("⚠️  From-address matrix without a funded EOA: %v\n", err)
+	} else {
+		if balance, err := client.BalanceAt(ctx, funded, nil); err == nil && balance.Sign() == 0 {
+			fmt.Printf("⚠️  Funded EOA %s has no balance\n", funded.Hex())
+		}
+		callers = append(callers, caller{"funded EOA", funded})
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		log.Fatalf("❌ Failed to generate a key: %v", err)
+	}
+	callers = append(callers, caller{"unfunded EOA", crypto.PubkeyToAddress(key.PublicKey)})
+
+	fmt.Printf("🎭 From-address matrix:")
+	for _, c := range callers {
+		fmt.Printf(" %s", c.label)
+	}
+	fmt.Println()
+	return callers
+}
+
+// callFrom repeats the wrapper call as c and compares the digest with
+// want, the one the default call returned.
+func callFrom(ctx context.Context, client *ethclient.Client, target common.Address, parsedABI *abi.ABI, v vector.Vector, c caller, want string) FromCall {
+	call := FromCall{Caller: c.label, From: c.addr.Hex()}
+	outcome, err := precompile.CallWrapperFrom(ctx, client, parsedABI, target, c.addr, v.Bytes())
+	if err != nil {
+		call.Error = err.Error()
+		return call
+	}
+	call.Hash = fmt.Sprintf("%x", outcome.Returned)
+	call.Same = call.Hash == want
+	return call
 }
@@ -0,0 +1,344 @@
+//suite:writes transactions=6 gas=1500000
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/internal/hooks"
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/deploy"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/plugin"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/profile"
+	"cdk-erigon-precompile/pkg/proof"
+	"cdk-erigon-precompile/pkg/registry"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+type StorageProofResult struct {
+	vector.Vector
+	TransactionHash string        `json:"transactionHash"`
+	BlockNumber     uint64        `json:"blockNumber"`
+	Index           uint64        `json:"index"`
+	ExpectedHash    string        `json:"expectedHash"`
+	Proof           *proof.Result `json:"proof,omitempty"`
+	FeeChecks       []chain.Check `json:"feeChecks,omitempty"`
+	ReceiptChecks   []chain.Check `json:"receiptChecks,omitempty"`
+	BlockChecks     []chain.Check `json:"blockChecks,omitempty"`
+	Passed          bool          `json:"passed"`
+	Error           string        `json:"error,omitempty"`
+	// HookError is set when an AfterCase hook failed the case.
+	HookError string `json:"hookError,omitempty"`
+}
+
+// stage4Name names the stage to hooks.
+const stage4Name = "Stage 4 - Storage Proof Verification"
+
+func main() {
+	output.Setup()
+
+	vectorsFrom := flag.String("vectors", "", "vector set to use instead of the built-in vectors: path or URL, optionally suffixed with #sha256=<hex>")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	vectors := []vector.Vector{
+		vector.New([]byte("hello world"), tags.Smoke),
+		vector.New([]byte(""), tags.Smoke),
+		vector.New([]byte("cdk-erigon")),
+		vector.New([]byte{0x00, 0xff, 0xfe, 0x80}, tags.Binary),
+	}
+	if *vectorsFrom != "" {
+		name, loaded, err := registry.NewFetcher("").LoadVectors(ctx, registry.ParseSource(*vectorsFrom))
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		vectors = loaded
+		fmt.Printf("📥 Loaded vector set %q: %d vectors\n", name, len(vectors))
+	}
+
+	// Every vector costs a transaction, so filter before touching the chain
+	testInputs := vector.Select(vectors, tagFilter)
+	if len(testInputs) == 0 {
+		fmt.Printf("⏭️  No storage proof vectors match the tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	// External validators judge each case alongside the stage's own checks
+	plugins, err := plugin.FromEnv()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer plugin.Register(plugins)()
+
+	// Every vector is stored on chain before it is proven
+	if err := chain.CheckWritable(); err != nil {
+		log.Fatalf("❌ Stage 4 needs transactions: %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_stage4.json")
+
+	// The store is deployed by the deployer and written by the invoker
+	deployer, err := chain.NewRoleSender(ctx, client, chain.RoleDeploy)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	invoker, err := chain.NewRoleSender(ctx, client, chain.RoleInvoke)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	chainID := deployer.ChainID
+	fmt.Printf("🔐 Using deployer %s, invoker %s\n", deployer.From.Hex(), invoker.From.Hex())
+
+	// Pick the proof verifier from the chain profile (CHAIN_PROFILE or detected from chain ID)
+	chainProfile, err := profile.Resolve(os.Getenv("CHAIN_PROFILE"), chainID.Uint64())
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	verifier, err := proof.ForProfile(chainProfile)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	fmt.Printf("🌳 Chain profile %s: verifying %s proofs via %s\n", chainProfile.Name, verifier.Trie(), chainProfile.ProofMethod)
+
+	storeABI, err := loadStoreABI()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	storeAddress, err := ensureStoreDeployed(ctx, client, deployer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("📌 Using Sha256Store at: %s\n", storeAddress.Hex())
+
+	var results []StorageProofResult
+	for _, input := range testInputs {
+		hookCase := hooks.Case{Stage: stage4Name, ID: input.ID(), Precompile: precompile.SHA256Address.Hex(), Input: input.Bytes(), Target: storeAddress.Hex()}
+		var result StorageProofResult
+		if err := hooks.BeforeCase(ctx, hookCase); err != nil {
+			result = StorageProofResult{Vector: input, Error: err.Error()}
+		} else {
+			result = storeAndProve(ctx, client, invoker, verifier, storeABI, storeAddress, input)
+		}
+		outcome := hooks.Outcome{Case: hookCase, Passed: result.Passed, Error: result.Error, TxHash: result.TransactionHash, Block: result.BlockNumber}
+		if err := hooks.AfterCase(ctx, outcome); err != nil {
+			result.HookError = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	if err := saveStorageProofResults(results); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+
+	fmt.Println("\n🧪 Storage proof results:")
+	failed := 0
+	for _, res := range results {
+		status := "✅"
+		if !res.Passed || res.HookError != "" {
+			status = "❌"
+			failed++
+		}
+		fmt.Printf("%s Input: %s (index %d, block %d)\n", status, res.Display(), res.Index, res.BlockNumber)
+		if res.Error != "" {
+			fmt.Printf("  Error: %s\n", res.Error)
+		}
+		if res.HookError != "" {
+			fmt.Printf("  %s\n", res.HookError)
+		}
+		if res.Proof != nil {
+			for _, slot := range res.Proof.Slots {
+				if !slot.Verified {
+					fmt.Printf("  Slot %s: %s\n", slot.Slot, slot.Error)
+				}
+			}
+		}
+		for _, check := range res.conformanceChecks() {
+			if !check.Skipped && !check.Passed {
+				fmt.Printf("  Check %s: expected %s, got %s %s\n", check.Name, check.Expected, check.Actual, check.Note)
+			}
+		}
+	}
+	fmt.Println("\n📝 Results saved to results_stage4.json")
+	if err := hooks.AfterRun(ctx, hooks.Run{Stage: stage4Name, Results: paths.Work("results_stage4.json"), Passed: len(results) - failed, Failed: failed}); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func loadStoreABI() (*abi.ABI, error) {
+	abiBytes, err := os.ReadFile(paths.Artifact("Sha256Store.abi"))
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to read ABI (compile contracts/Sha256Store.sol first): %v", err)
+	}
+	parsedABI, err := abi.JSON(strings.NewReader(string(abiBytes)))
+	if err != nil {
+		return nil, fmt.Errorf("❌ Failed to parse ABI: %v", err)
+	}
+	return &parsedABI, nil
+}
+
+// ensureStoreDeployed reuses the address in deployed_store_address.txt when it
+// still has code, deploying a fresh Sha256Store otherwise.
+func ensureStoreDeployed(ctx context.Context, client *ethclient.Client, sender *chain.Sender) (common.Address, error) {
+	if address, err := paths.ReadAddress(paths.Work("deployed_store_address.txt")); err == nil {
+		code, err := client.CodeAt(ctx, address, nil)
+		if err == nil && len(code) > 0 {
+			return address, nil
+		}
+	}
+
+	if err := deploy.VerifyArtifact(paths.Artifact("Sha256Store")); err != nil {
+		return common.Address{}, fmt.Errorf("❌ Refusing to deploy: %v", err)
+	}
+	bytecode, err := paths.ReadHex(paths.Artifact("Sha256Store.bin"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("❌ Failed to read bytecode (compile contracts/Sha256Store.sol first): %v", err)
+	}
+
+	fmt.Println("📨 Deploying Sha256Store...")
+	_, receipt, err := sender.Send(chain.WithContract(ctx, "Sha256Store"), nil, common.FromHex(bytecode), 2_000_000)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("❌ Deployment failed: %v", err)
+	}
+	if receipt.Status != 1 {
+		return common.Address{}, fmt.Errorf("❌ Sha256Store deployment reverted in block %d", receipt.BlockNumber.Uint64())
+	}
+
+	if err := paths.WriteFile(paths.Work("deployed_store_address.txt"), []byte(receipt.ContractAddress.Hex())); err != nil {
+		return common.Address{}, fmt.Errorf("❌ Failed to save deployed address: %v", err)
+	}
+	return receipt.ContractAddress, nil
+}
+
+// storeAndProve stores sha256(input) on-chain and verifies, via a state proof
+// against the including block's state root, that the hash landed in the
+// expected mapping slot and that the counter advanced.
+func storeAndProve(ctx context.Context, client *ethclient.Client, sender *chain.Sender, verifier proof.Verifier, storeABI *abi.ABI, storeAddress common.Address, v vector.Vector) StorageProofResult {
+	input := v.Bytes()
+	expected := sha256.Sum256(input)
+	result := StorageProofResult{Vector: v, ExpectedHash: fmt.Sprintf("%x", expected)}
+
+	// The index written to is the counter value before the transaction
+	countData, err := storeABI.Pack("count")
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to pack count call: %v", err)
+		return result
+	}
+	out, err := client.CallContract(ctx, callMsg(storeAddress, countData), nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("count call failed: %v", err)
+		return result
+	}
+	result.Index = new(big.Int).SetBytes(out).Uint64()
+
+	callData, err := storeABI.Pack("store", input)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to pack store call: %v", err)
+		return result
+	}
+	tx, receipt, err := sender.Send(ctx, &storeAddress, callData, 200_000)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.TransactionHash = tx.Hash().Hex()
+	result.BlockNumber = receipt.BlockNumber.Uint64()
+	if receipt.Status != 1 {
+		result.Error = "store transaction reverted"
+		return result
+	}
+	result.FeeChecks = chain.CheckFees(ctx, client, tx, receipt)
+	result.ReceiptChecks = chain.CheckReceipt(ctx, client, tx, receipt)
+	result.BlockChecks = chain.CheckBlock(ctx, client, tx, receipt)
+
+	// Slot 0 holds count, the hashes mapping lives at slot 1
+	index := common.BigToHash(new(big.Int).SetUint64(result.Index))
+	slots := []common.Hash{
+		{},
+		proof.MappingSlot(index, 1),
+	}
+	want := []common.Hash{
+		common.BigToHash(new(big.Int).SetUint64(result.Index + 1)),
+		common.Hash(expected),
+	}
+
+	res, err := verifier.VerifyStorage(ctx, client, storeAddress, slots, want, receipt.BlockNumber)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Proof = res
+	result.Passed = res.Passed() && chain.AllPassed(result.conformanceChecks())
+	return result
+}
+
+// conformanceChecks returns the fee, receipt and block checks together.
+func (r StorageProofResult) conformanceChecks() []chain.Check {
+	var checks []chain.Check
+	checks = append(checks, r.FeeChecks...)
+	checks = append(checks, r.ReceiptChecks...)
+	return append(checks, r.BlockChecks...)
+}
+
+func callMsg(to common.Address, data []byte) ethereum.CallMsg {
+	return ethereum.CallMsg{To: &to, Data: data}
+}
+
+func saveStorageProofResults(results []StorageProofResult) error {
+	file, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_stage4.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/tracediff"
+)
+
+type TraceDiffResult struct {
+	Stage       string              `json:"stage"`
+	EndpointA   string              `json:"endpointA"`
+	EndpointB   string              `json:"endpointB"`
+	Transaction string              `json:"transaction,omitempty"`
+	Call        *tracediff.CallArgs `json:"call,omitempty"`
+	Block       string              `json:"block,omitempty"`
+	Diff        tracediff.Diff      `json:"diff"`
+	Identical   bool                `json:"identical"`
+	Timestamp   string              `json:"timestamp"`
+}
+
+func main() {
+	output.Setup()
+
+	endpointB := flag.String("b", "", "RPC URL of the endpoint to compare with (required)")
+	endpointA := flag.String("a", "", "RPC URL of the first endpoint (default http://RPC_HOST:RPC_PORT)")
+	txFlag := flag.String("tx", "", "hash of a transaction to trace with debug_traceTransaction on both endpoints")
+	toFlag := flag.String("to", "", "address to trace a call to with debug_traceCall instead of a transaction")
+	dataFlag := flag.String("data", "0x", "calldata of the traced call")
+	fromFlag := flag.String("from", "", "sender of the traced call")
+	gasFlag := flag.Uint64("gas", 0, "gas of the traced call (default: the node's)")
+	block := flag.String("block", "latest", "block the call is traced at")
+	maxDeltas := flag.Int("max-deltas", 20, "differently priced steps to report")
+	out := flag.String("out", paths.Work("results_tracediff.json"), "where to save the diff")
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *endpointB == "" {
+		log.Fatal("❌ --b is required: the endpoint to compare with")
+	}
+	if *endpointA == "" {
+		*endpointA = fmt.Sprintf("http://%s:%s", os.Getenv("RPC_HOST"), os.Getenv("RPC_PORT"))
+	}
+
+	result := TraceDiffResult{
+		Stage:     "Trace Diff - Step-level Comparison of Two Endpoints",
+		EndpointA: *endpointA,
+		EndpointB: *endpointB,
+	}
+	var trace func(context.Context, *ethclient.Client) (tracediff.Trace, error)
+	switch {
+	case *txFlag != "" && *toFlag != "":
+		log.Fatal("❌ Pass either --tx or --to, not both")
+	case *txFlag != "":
+		hash := common.HexToHash(*txFlag)
+		result.Transaction = hash.Hex()
+		trace = func(ctx context.Context, c *ethclient.Client) (tracediff.Trace, error) {
+			return tracediff.Transaction(ctx, c.Client(), hash)
+		}
+	case *toFlag != "":
+		args, err := traceCallArgs(*toFlag, *dataFlag, *fromFlag, *gasFlag)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		result.Call, result.Block = &args, *block
+		trace = func(ctx context.Context, c *ethclient.Client) (tracediff.Trace, error) {
+			return tracediff.Call(ctx, c.Client(), args, *block)
+		}
+	default:
+		log.Fatal("❌ Pass --tx to trace a transaction or --to to trace a call")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var traces [2]tracediff.Trace
+	for i, url := range []string{*endpointA, *endpointB} {
+		client, err := rpcclient.Connect(ctx, url)
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", url, err)
+		}
+		traces[i], err = trace(ctx, client)
+		client.Close()
+		if err != nil {
+			log.Fatalf("❌ %s: %v", url, err)
+		}
+		fmt.Printf("🔎 Traced %d steps on %s\n", len(traces[i].StructLogs), url)
+	}
+
+	result.Diff = tracediff.Compare(traces[0], traces[1], *maxDeltas)
+	result.Identical = result.Diff.Identical()
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(*out, file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+
+	printTraceDiff(result.Diff)
+	fmt.Printf("\n📝 Diff saved to %s\n", *out)
+	if !result.Identical {
+		os.Exit(1)
+	}
+}
+
+func traceCallArgs(to, data, from string, gas uint64) (tracediff.CallArgs, error) {
+	if !common.IsHexAddress(to) {
+		return tracediff.CallArgs{}, fmt.Errorf("invalid --to address %q", to)
+	}
+	input, err := hexutil.Decode(data)
+	if err != nil {
+		return tracediff.CallArgs{}, fmt.Errorf("invalid --data: %v", err)
+	}
+	address := common.HexToAddress(to)
+	args := tracediff.CallArgs{To: &address, Data: input}
+	if from != "" {
+		if !common.IsHexAddress(from) {
+			return tracediff.CallArgs{}, fmt.Errorf("invalid --from address %q", from)
+		}
+		sender := common.HexToAddress(from)
+		args.From = &sender
+	}
+	if gas > 0 {
+		g := hexutil.Uint64(gas)
+		args.Gas = &g
+	}
+	return args, nil
+}
+
+func printTraceDiff(d tracediff.Diff) {
+	fmt.Printf("\n📊 Steps: %d vs %d, gas: %d vs %d, failed: %t vs %t\n", d.StepsA, d.StepsB, d.GasA, d.GasB, d.FailedA, d.FailedB)
+	if d.Identical() {
+		fmt.Println("✅ Traces are identical")
+		return
+	}
+	if d.ReturnA != d.ReturnB {
+		fmt.Printf("❌ Return data: %s vs %s\n", d.ReturnA, d.ReturnB)
+	}
+	for _, div := range []*tracediff.Divergence{d.FirstStackDivergence, d.FirstDivergence} {
+		if div == nil {
+			continue
+		}
+		fmt.Printf("❌ Step %d: %s\n", div.Index, div.Reason)
+		for i, s := range []*tracediff.Step{div.A, div.B} {
+			if s != nil {
+				fmt.Printf("   %c: pc %d %s depth %d gas %d cost %d %v\n", 'a'+i, s.PC, s.Op, s.Depth, s.Gas, s.GasCost, s.Stack)
+			}
+		}
+	}
+	if d.GasDeltaCount > 0 {
+		fmt.Printf("⛽ %d steps priced differently before control flow split:\n", d.GasDeltaCount)
+		for _, g := range d.GasDeltas {
+			fmt.Printf("   step %d pc %d %s (depth %d): %d vs %d\n", g.Index, g.PC, g.Op, g.Depth, g.GasCostA, g.GasCostB)
+		}
+	}
+}
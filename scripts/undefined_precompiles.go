@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/memexp"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// UndefinedCase is one address without a precompile, called directly and
+// from inside the EVM.
+type UndefinedCase struct {
+	Address string        `json:"address"`
+	Input   hexutil.Bytes `json:"input"`
+	// Returned is what a direct eth_call to the address answered.
+	Returned hexutil.Bytes `json:"returned,omitempty"`
+	// EstimatedGas and IntrinsicGas must agree: nothing but the
+	// transaction's own cost may be charged.
+	EstimatedGas uint64 `json:"estimatedGas"`
+	IntrinsicGas uint64 `json:"intrinsicGas"`
+	// InnerGas is the gas of the STATICCALL from inside the EVM, compared
+	// with go-ethereum's EVM.
+	InnerGas          uint64   `json:"innerGas"`
+	ReferenceInnerGas uint64   `json:"referenceInnerGas"`
+	Failures          []string `json:"failures,omitempty"`
+	Match             bool     `json:"match"`
+}
+
+type UndefinedResult struct {
+	Stage string          `json:"stage"`
+	Cases []UndefinedCase `json:"cases"`
+	// Defined are the BLS12-381 precompiles the probe found; their
+	// addresses are left out of the range.
+	Defined    []precompile.BLSCapability `json:"defined,omitempty"`
+	Matches    int                        `json:"matches"`
+	Mismatches int                        `json:"mismatches"`
+	Timestamp  string                     `json:"timestamp"`
+	RPCURL     string                     `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	from := flag.Uint64("from", 0x0b, "first address of the undefined range")
+	to := flag.Uint64("to", 0x1f, "last address of the undefined range")
+	random := flag.Int("random", 8, "random addresses below 0x10000 to add above the range")
+	skipList := flag.String("skip", "", "comma-separated addresses the chain defines after all, e.g. 0x100 for P256VERIFY")
+	inputSize := flag.Int("input-size", 68, "random bytes sent to each address")
+	gas := flag.Uint64("gas", 1_000_000, "gas of each eth_call")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Smoke, tags.Gas}) {
+		fmt.Printf("⏭️  Undefined address checks skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	addresses, err := undefinedAddresses(*from, *to, *random, *skipList)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_undefined.json")
+
+	result := UndefinedResult{
+		Stage:  "Undefined Precompile Addresses - Empty Account Semantics",
+		RPCURL: rpcURL,
+	}
+	// Prague, and cdk-erigon builds following the EIP's drafts, define the
+	// BLS12-381 precompiles inside the default range. Anything answering
+	// the capability probe there is not an empty account.
+	defined := map[common.Address]precompile.BLSCapability{}
+	for _, p := range precompile.BLSPrecompiles {
+		if c := precompile.ProbeBLS(ctx, client, p); c.Address != "" {
+			result.Defined = append(result.Defined, c)
+			defined[common.HexToAddress(c.Address)] = c
+		}
+	}
+	addresses = slices.DeleteFunc(addresses, func(a common.Address) bool {
+		c, ok := defined[a]
+		if ok {
+			fmt.Printf("⏭️  Skipping %s: BLS12-381 %s is %s there\n", c.Address, c.Name, c.Status)
+		}
+		return ok
+	})
+	fmt.Printf("🕳️  Calling %d addresses without a precompile\n", len(addresses))
+	for _, address := range addresses {
+		input := make([]byte, *inputSize)
+		if _, err := rand.Read(input); err != nil {
+			log.Fatalf("❌ Failed to generate input: %v", err)
+		}
+		c, err := checkUndefined(ctx, client, address, input, *gas)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if c.Match {
+			result.Matches++
+			fmt.Printf("✅ %s behaves as an empty account\n", c.Address)
+		} else {
+			result.Mismatches++
+			fmt.Printf("❌ %s: %s\n", c.Address, strings.Join(c.Failures, "; "))
+		}
+		result.Cases = append(result.Cases, c)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := saveUndefinedResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Printf("\n✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Println("\n📝 Results saved to results_undefined.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkUndefined asserts that address acts like an account without code:
+// a direct call succeeds with no output and costs only the intrinsic gas,
+// and a call from inside the EVM succeeds with no return data at the cost
+// of a cold account access, as in go-ethereum's EVM.
+func checkUndefined(ctx context.Context, client *ethclient.Client, address common.Address, input []byte, gas uint64) (UndefinedCase, error) {
+	c := UndefinedCase{Address: address.Hex(), Input: input, IntrinsicGas: intrinsicGas(input)}
+	fail := func(format string, args ...any) {
+		c.Failures = append(c.Failures, fmt.Sprintf(format, args...))
+	}
+
+	msg := ethereum.CallMsg{To: &address, Gas: gas, Data: input}
+	returned, err := client.CallContract(ctx, msg, nil)
+	if err != nil {
+		fail("eth_call failed: %v", err)
+	} else if len(returned) > 0 {
+		c.Returned = returned
+		fail("eth_call returned %d bytes", len(returned))
+	}
+
+	if c.EstimatedGas, err = client.EstimateGas(ctx, msg); err != nil {
+		fail("eth_estimateGas failed: %v", err)
+	} else if c.EstimatedGas != c.IntrinsicGas {
+		fail("estimated %d gas, want the intrinsic %d", c.EstimatedGas, c.IntrinsicGas)
+	}
+
+	inner := memexp.Case{Name: address.Hex(), Precompile: address, Input: input, OutSize: 32}
+	node, err := memexp.Call(ctx, client, inner, gas)
+	if err != nil {
+		return UndefinedCase{}, err
+	}
+	ref, err := memexp.Reference(inner, gas)
+	if err != nil {
+		return UndefinedCase{}, err
+	}
+	c.InnerGas, c.ReferenceInnerGas = node.GasUsed, ref.GasUsed
+	switch {
+	case node.Failed:
+		fail("inner call failed: %s", node.Error)
+	case !node.Success:
+		fail("inner call returned success=false")
+	case node.ReturnSize != 0:
+		fail("inner call returned %d bytes", node.ReturnSize)
+	case !node.Equal(ref):
+		fail("inner call cost %d gas, reference %d", node.GasUsed, ref.GasUsed)
+	}
+	c.Match = len(c.Failures) == 0
+	return c, nil
+}
+
+// intrinsicGas is the cost of a call transaction carrying data, which is
+// all a call to an account without code may cost.
+func intrinsicGas(data []byte) uint64 {
+	gas := params.TxGas
+	for _, b := range data {
+		if b == 0 {
+			gas += params.TxDataZeroGas
+		} else {
+			gas += params.TxDataNonZeroGasEIP2028
+		}
+	}
+	return gas
+}
+
+// undefinedAddresses lists from..to and random addresses above it below
+// 0x10000, leaving out the skipped ones.
+func undefinedAddresses(from, to uint64, random int, skip string) ([]common.Address, error) {
+	if from <= 0x0a || from > to {
+		return nil, fmt.Errorf("invalid range 0x%x-0x%x: addresses up to 0x0a are defined precompiles", from, to)
+	}
+	skipped := map[common.Address]bool{}
+	for _, s := range tags.Parse(skip) {
+		n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+		if !ok || n.BitLen() > 160 {
+			return nil, fmt.Errorf("invalid --skip address %q", s)
+		}
+		skipped[common.BigToAddress(n)] = true
+	}
+
+	seen := map[common.Address]bool{}
+	var addresses []common.Address
+	add := func(n uint64) {
+		a := common.BytesToAddress(binary.BigEndian.AppendUint64(nil, n))
+		if !skipped[a] && !seen[a] {
+			seen[a] = true
+			addresses = append(addresses, a)
+		}
+	}
+	for n := from; n <= to; n++ {
+		add(n)
+	}
+	if to < 0xffff {
+		for i := 0; i < random; i++ {
+			var b [8]byte
+			if _, err := rand.Read(b[:]); err != nil {
+				return nil, err
+			}
+			add(to + 1 + binary.BigEndian.Uint64(b[:])%(0xffff-to))
+		}
+	}
+	return addresses, nil
+}
+
+func saveUndefinedResult(result UndefinedResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_undefined.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+)
+
+// maxRange bounds how many blocks a from-to argument may span.
+const maxRange = 1000
+
+// BlockVerification is the outcome of re-deriving one block's commitments.
+type BlockVerification struct {
+	Block        string        `json:"block"`
+	Number       uint64        `json:"number,omitempty"`
+	Hash         string        `json:"hash,omitempty"`
+	Transactions int           `json:"transactions"`
+	Checks       []chain.Check `json:"checks,omitempty"`
+	Passed       bool          `json:"passed"`
+	Error        string        `json:"error,omitempty"`
+}
+
+type VerifyBlockResult struct {
+	Stage     string              `json:"stage"`
+	Blocks    []BlockVerification `json:"blocks"`
+	Passed    int                 `json:"passed"`
+	Failed    int                 `json:"failed"`
+	Timestamp string              `json:"timestamp"`
+	RPCURL    string              `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	envFiles := envfile.Flags()
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: go run scripts/verify_block.go [flags] <block>...\n\n"+
+			"A block is a number, a from-to range of numbers, a block hash or latest.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+
+	var refs []string
+	for _, arg := range flag.Args() {
+		expanded, err := expandBlockArg(arg)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		refs = append(refs, expanded...)
+	}
+
+	result := VerifyBlockResult{Stage: "Block Verification - Roots, Blooms and Log Indexes", RPCURL: rpcURL}
+	for _, ref := range refs {
+		v := verifyBlock(ctx, client, ref)
+		if ctx.Err() != nil {
+			log.Fatalf("❌ Interrupted: %v", ctx.Err())
+		}
+		if v.Passed {
+			result.Passed++
+		} else {
+			result.Failed++
+		}
+		result.Blocks = append(result.Blocks, v)
+		printVerification(v)
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_verify_block.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	fmt.Printf("\n📊 Passed: %d, Failed: %d\n", result.Passed, result.Failed)
+	fmt.Println("📝 Results saved to results_verify_block.json")
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// expandBlockArg turns a from-to range into its block numbers and checks
+// every other argument is a number, a hash or latest.
+func expandBlockArg(arg string) ([]string, error) {
+	if arg == "latest" || (strings.HasPrefix(arg, "0x") && len(arg) == 66) {
+		return []string{arg}, nil
+	}
+	if from, to, ok := strings.Cut(arg, "-"); ok {
+		lo, err1 := strconv.ParseUint(from, 10, 64)
+		hi, err2 := strconv.ParseUint(to, 10, 64)
+		if err1 != nil || err2 != nil || hi < lo {
+			return nil, fmt.Errorf("invalid block range %q", arg)
+		}
+		if hi-lo >= maxRange {
+			return nil, fmt.Errorf("block range %q spans more than %d blocks", arg, maxRange)
+		}
+		var refs []string
+		for n := lo; n <= hi; n++ {
+			refs = append(refs, strconv.FormatUint(n, 10))
+		}
+		return refs, nil
+	}
+	if _, err := strconv.ParseUint(arg, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid block %q (want a number, a from-to range, a hash or latest)", arg)
+	}
+	return []string{arg}, nil
+}
+
+// verifyBlock fetches the block and its receipts and re-derives the
+// header's commitments with the checks the stages run on their own blocks.
+func verifyBlock(ctx context.Context, client *ethclient.Client, ref string) BlockVerification {
+	v := BlockVerification{Block: ref}
+	var (
+		block *types.Block
+		err   error
+	)
+	switch {
+	case ref == "latest":
+		block, err = client.BlockByNumber(ctx, nil)
+	case strings.HasPrefix(ref, "0x"):
+		block, err = client.BlockByHash(ctx, common.HexToHash(ref))
+	default:
+		n, _ := new(big.Int).SetString(ref, 10)
+		block, err = client.BlockByNumber(ctx, n)
+	}
+	if err != nil {
+		v.Error = fmt.Sprintf("failed to get block: %v", err)
+		return v
+	}
+	v.Number, v.Hash, v.Transactions = block.NumberU64(), block.Hash().Hex(), len(block.Transactions())
+
+	receipts, err := chain.BlockReceipts(ctx, client, block.Hash())
+	if err != nil {
+		v.Error = fmt.Sprintf("failed to get receipts: %v", err)
+		v.Checks = chain.VerifyBlock(block, nil)
+		return v
+	}
+	v.Checks = chain.VerifyBlock(block, receipts)
+	v.Passed = chain.AllPassed(v.Checks)
+	return v
+}
+
+func printVerification(v BlockVerification) {
+	if v.Hash == "" {
+		fmt.Printf("\n❌ Block %s: %s\n", v.Block, v.Error)
+		return
+	}
+	status := "✅"
+	if !v.Passed {
+		status = "❌"
+	}
+	fmt.Printf("\n%s Block %d %s (%d transactions)\n", status, v.Number, v.Hash, v.Transactions)
+	if v.Error != "" {
+		fmt.Printf("   %s\n", v.Error)
+	}
+	for _, c := range v.Checks {
+		switch {
+		case c.Skipped:
+			fmt.Printf("   ⏭️  %s %s\n", c.Name, c.Note)
+		case !c.Passed:
+			fmt.Printf("   ❌ %s: expected %s, got %s %s\n", c.Name, c.Expected, c.Actual, c.Note)
+		default:
+			fmt.Printf("   ✅ %s\n", c.Name)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/bench"
+	"cdk-erigon-precompile/pkg/chain"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/stream"
+	"cdk-erigon-precompile/pkg/vector"
+)
+
+// WatchSample is one canary call made by the monitor.
+type WatchSample struct {
+	Seq        int    `json:"seq"`
+	Precompile string `json:"precompile"`
+	// Block is the head that triggered the call with --heads.
+	Block uint64 `json:"block,omitempty"`
+	vector.Vector
+	ReturnedHash string  `json:"returnedHash,omitempty"`
+	Match        bool    `json:"match"`
+	LatencyMs    float64 `json:"latencyMs"`
+	Error        string  `json:"error,omitempty"`
+	Timestamp    string  `json:"timestamp"`
+}
+
+// WatchMetrics aggregates the samples of one reporting window.
+type WatchMetrics struct {
+	WindowStart string        `json:"windowStart"`
+	WindowEnd   string        `json:"windowEnd"`
+	Calls       int           `json:"calls"`
+	Failures    int           `json:"failures"`
+	Reconnects  int64         `json:"reconnects,omitempty"`
+	Latency     bench.Summary `json:"latency"`
+}
+
+func main() {
+	output.Setup()
+
+	interval := flag.Duration("interval", 10*time.Second, "time between canary calls")
+	followHeads := flag.Bool("heads", false, "make a canary call on every new block instead of every --interval (subscribes over RPC_WS_URL when set, polls otherwise)")
+	resumeTimeout := flag.Duration("resume-timeout", 3*time.Minute, "first wait this long for the receipts of transactions a previous run left pending (0 skips)")
+	window := flag.Duration("window", 5*time.Minute, "aggregate metrics over windows of this length")
+	duration := flag.Duration("duration", 0, "stop after this long (0 runs until interrupted)")
+	inputFlag := flag.String("input", "hello world", "canary input passed to sha256 (0x-prefixed values are hex-decoded)")
+	outPath := flag.String("out", "watch.ndjson", "NDJSON file receiving every sample")
+	metricsPath := flag.String("metrics", "watch_metrics.ndjson", "NDJSON file receiving per-window aggregates")
+	rotateMB := flag.Int64("rotate-size", 100, "rotate output files after this many MiB (0 disables)")
+	rotateEvery := flag.Duration("rotate-every", 24*time.Hour, "rotate output files after this long (0 disables)")
+	maxBackups := flag.Int("max-backups", 14, "keep at most this many rotated segments per file (0 keeps all)")
+	compress := flag.Bool("compress", true, "gzip rotated segments")
+	heatmapPath := flag.String("heatmap", "watch_heatmap.json", "JSON file receiving latencies bucketed by day and hour, rewritten every window (empty disables)")
+	heatmapPNG := flag.String("heatmap-png", "", "also render the heatmap as a PNG to this file")
+	heatmapTZ := flag.String("heatmap-tz", "Local", "time zone the heatmap hours are in, e.g. UTC or Europe/Berlin")
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	loc, err := time.LoadLocation(*heatmapTZ)
+	if err != nil {
+		log.Fatalf("❌ Invalid --heatmap-tz: %v", err)
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+	if wsURL := os.Getenv("RPC_WS_URL"); wsURL != "" && *followHeads {
+		rpcURL = wsURL
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Ride out node restarts: head subscriptions and pending receipts are
+	// picked up again once the node answers
+	reconnect := rpcclient.DefaultReconnectOptions
+	reconnect.OnReconnect = func(attempts int, cause error) {
+		fmt.Printf("🔌 Reconnected to %s after %d attempts (%v)\n", rpcURL, attempts, cause)
+	}
+	conn, err := rpcclient.DialConn(ctx, rpcURL, reconnect)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer conn.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+
+	if *resumeTimeout > 0 {
+		resumePending(ctx, conn, *resumeTimeout)
+	}
+
+	rotation := stream.RotateOptions{
+		MaxBytes:   *rotateMB << 20,
+		MaxAge:     *rotateEvery,
+		MaxBackups: *maxBackups,
+		Compress:   *compress,
+	}
+	samplesOut, err := stream.OpenRotated(*outPath, rotation)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer samplesOut.Close()
+	metricsOut, err := stream.OpenRotated(*metricsPath, rotation)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	defer metricsOut.Close()
+
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	if *followHeads {
+		fmt.Printf("👀 Watching precompile 0x02 on every new block (samples -> %s, metrics -> %s)\n", *outPath, *metricsPath)
+	} else {
+		fmt.Printf("👀 Watching precompile 0x02 every %s (samples -> %s, metrics -> %s)\n", *interval, *outPath, *metricsPath)
+	}
+
+	precompile := common.HexToAddress("0x02")
+	input, err := vector.Parse(*inputFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	expected := fmt.Sprintf("%x", sha256.Sum256(input))
+	ticks := make(chan uint64)
+	go func() {
+		if err := produceTicks(ctx, conn, *followHeads, *interval, ticks); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️  No longer following heads: %v", err)
+			stop()
+		}
+	}()
+
+	windowStart := time.Now()
+	var latencies []float64
+	failures := 0
+	seq := 0
+	reconnects := conn.Reconnects()
+	heat := bench.NewHeatmap(loc)
+
+	flush := func(end time.Time) {
+		if len(latencies) == 0 && failures == 0 {
+			return
+		}
+		metrics := WatchMetrics{
+			WindowStart: windowStart.UTC().Format(time.RFC3339),
+			WindowEnd:   end.UTC().Format(time.RFC3339),
+			Calls:       len(latencies) + failures,
+			Failures:    failures,
+			Reconnects:  conn.Reconnects() - reconnects,
+			Latency:     bench.Summarize(latencies, 0),
+		}
+		if err := metricsOut.Write(metrics); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		fmt.Printf("📊 %s: %d calls, %d failures, median %.2f ms\n",
+			metrics.WindowEnd, metrics.Calls, metrics.Failures, metrics.Latency.Median)
+		if err := writeHeatmap(heat, *heatmapPath, *heatmapPNG); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		windowStart = end
+		latencies = nil
+		failures = 0
+		reconnects = conn.Reconnects()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush(time.Now())
+			fmt.Println("\n🛑 Watch stopped")
+			return
+		case block := <-ticks:
+			now := time.Now()
+			sample := canaryCall(ctx, conn, precompile, input, expected)
+			sample.Seq = seq
+			sample.Block = block
+			seq++
+
+			ok := sample.Error == "" && sample.Match
+			heat.Add(now, sample.LatencyMs, !ok)
+			if ok {
+				latencies = append(latencies, sample.LatencyMs)
+			} else {
+				failures++
+				fmt.Printf("❌ %s canary failed: %s%s\n", sample.Timestamp, sample.Error, mismatchNote(sample))
+			}
+			if err := samplesOut.Write(sample); err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+
+			if now.Sub(windowStart) >= *window {
+				flush(now)
+			}
+		}
+	}
+}
+
+// writeHeatmap rewrites the heatmap dataset and image, skipping those whose
+// path is empty.
+func writeHeatmap(heat *bench.Heatmap, dataPath, pngPath string) error {
+	if dataPath != "" {
+		data, err := json.MarshalIndent(heat.Data(), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := paths.WriteFile(dataPath, data); err != nil {
+			return fmt.Errorf("failed to save heatmap: %w", err)
+		}
+	}
+	if pngPath != "" {
+		var buf bytes.Buffer
+		if err := heat.WritePNG(&buf); err != nil {
+			return err
+		}
+		if err := paths.WriteFile(pngPath, buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to save heatmap image: %w", err)
+		}
+	}
+	return nil
+}
+
+// produceTicks sends a tick every interval, or the number of every new
+// block with heads, until ctx is done.
+func produceTicks(ctx context.Context, conn *rpcclient.Conn, heads bool, interval time.Duration, ticks chan<- uint64) error {
+	if heads {
+		headers := make(chan *types.Header)
+		go func() {
+			for h := range headers {
+				select {
+				case ticks <- h.Number.Uint64():
+				case <-ctx.Done():
+				}
+			}
+		}()
+		defer close(headers)
+		return conn.FollowHeads(ctx, headers)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			select {
+			case ticks <- 0:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// resumePending waits for the receipts of transactions an earlier run
+// submitted but lost track of, so a soak restarted after a drop doesn't
+// leave them unaccounted for.
+func resumePending(ctx context.Context, conn *rpcclient.Conn, timeout time.Duration) {
+	var resumed []chain.ResumedTx
+	err := conn.Do(ctx, func(client *ethclient.Client) error {
+		var err error
+		resumed, err = chain.ResumePending(ctx, client, timeout)
+		return err
+	})
+	for _, r := range resumed {
+		if r.Error != "" {
+			fmt.Printf("⏳ %s (nonce %d) still pending: %s\n", r.Hash, r.Nonce, r.Error)
+			continue
+		}
+		fmt.Printf("📬 %s (nonce %d) mined in block %d, status %d\n", r.Hash, r.Nonce, r.Block, r.Status)
+	}
+	if err != nil {
+		log.Printf("⚠️  Failed to resume pending transactions from %s: %v", chain.PendingFile, err)
+	}
+}
+
+func canaryCall(ctx context.Context, conn *rpcclient.Conn, precompile common.Address, input []byte, expected string) WatchSample {
+	sample := WatchSample{Precompile: "0x02", Vector: vector.New(input)}
+
+	msg := ethereum.CallMsg{To: &precompile, Data: input}
+	start := time.Now()
+	// Not retried through conn.Do: a node that is down must show up as
+	// failed samples
+	out, err := conn.Client().CallContract(ctx, msg, nil)
+	sample.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+	sample.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	sample.ReturnedHash = fmt.Sprintf("%x", out)
+	sample.Match = sample.ReturnedHash == expected
+	return sample
+}
+
+func mismatchNote(sample WatchSample) string {
+	if sample.Error != "" {
+		return ""
+	}
+	return "hash mismatch, got " + sample.ReturnedHash
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+	"cdk-erigon-precompile/pkg/witness"
+)
+
+// WitnessBlock is the witness of one block with what the block contains.
+type WitnessBlock struct {
+	witness.Measurement
+	// Baseline is set for the untested parent of a tested block.
+	Baseline bool `json:"baseline"`
+	// Tested lists the test transactions in the block, unless it was
+	// passed with --blocks.
+	Tested       []string `json:"tested,omitempty"`
+	Transactions uint     `json:"transactions"`
+	GasUsed      uint64   `json:"gasUsed"`
+	// BytesPerGas is the witness size over the block's gas.
+	BytesPerGas float64 `json:"bytesPerGas"`
+	Error       string  `json:"error,omitempty"`
+}
+
+type WitnessResult struct {
+	Stage     string         `json:"stage"`
+	Method    string         `json:"method"`
+	Supported bool           `json:"supported"`
+	Reason    string         `json:"reason,omitempty"`
+	Blocks    []WitnessBlock `json:"blocks"`
+	// TestedAvgSize and BaselineAvgSize compare blocks with test
+	// transactions with their untested parents.
+	TestedAvgSize   float64 `json:"testedAvgSize"`
+	BaselineAvgSize float64 `json:"baselineAvgSize"`
+	Timestamp       string  `json:"timestamp"`
+	RPCURL          string  `json:"rpcUrl"`
+}
+
+func main() {
+	output.Setup()
+
+	method := flag.String("method", witness.DefaultMethod, "witness RPC, e.g. debug_executionWitness on go-ethereum based nodes")
+	blockList := flag.String("blocks", "", "comma-separated blocks to measure instead of those holding the tested transactions")
+	baseline := flag.Bool("baseline", true, "also measure the parent of each tested block when it holds no test transaction")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.ZKCounters}) {
+		fmt.Printf("⏭️  Witness measurement skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	tested, err := testedBlocks(*blockList)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if len(tested) == 0 {
+		fmt.Println("⏭️  No tested blocks: run stage 2, stage 4 or broadcast.go first, or pass --blocks")
+		if err := skip.Record(skip.New(skip.Prerequisite, "no tested blocks")); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_witness.json")
+
+	result := WitnessResult{
+		Stage:     "Witness - Prover Input Size of Tested Blocks",
+		Method:    *method,
+		Supported: true,
+		RPCURL:    rpcURL,
+	}
+	var blocks []uint64
+	for b := range tested {
+		blocks = append(blocks, b)
+		if _, ok := tested[b-1]; *baseline && b > 0 && !ok {
+			blocks = append(blocks, b-1)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+
+	var testedSizes, baselineSizes []int
+	for _, b := range blocks {
+		txs, isTested := tested[b]
+		wb, err := measureWitness(ctx, client, *method, b, txs)
+		wb.Baseline = !isTested
+		if errors.Is(err, witness.ErrUnsupported) {
+			result.Supported, result.Reason = false, err.Error()
+			fmt.Printf("⏭️  %v\n", err)
+			if err := skip.Record(skip.New(skip.Capability, err.Error())); err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+			break
+		}
+		if err != nil {
+			wb.Error = err.Error()
+			fmt.Printf("❌ Block %d: %v\n", b, err)
+		} else {
+			kind := "tested"
+			if wb.Baseline {
+				kind = "baseline"
+				baselineSizes = append(baselineSizes, wb.Size)
+			} else {
+				testedSizes = append(testedSizes, wb.Size)
+			}
+			fmt.Printf("🧾 Block %d (%s, %d txs, %s gas): %s in %s\n",
+				b, kind, wb.Transactions, output.Count(wb.GasUsed), output.Bytes(wb.Size), output.Millis(wb.DurationMs))
+		}
+		result.Blocks = append(result.Blocks, wb)
+	}
+	result.TestedAvgSize = average(testedSizes)
+	result.BaselineAvgSize = average(baselineSizes)
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_witness.json"), file); err != nil {
+		log.Fatalf("❌ Failed to save results: %v", err)
+	}
+	anchors.Finish(ctx)
+	if len(testedSizes) > 0 && len(baselineSizes) > 0 {
+		fmt.Printf("\n📈 Average witness: %s for tested blocks, %s for their parents\n",
+			output.Bytes(int64(result.TestedAvgSize)), output.Bytes(int64(result.BaselineAvgSize)))
+	}
+	fmt.Println("\n📝 Results saved to results_witness.json")
+}
+
+func measureWitness(ctx context.Context, client *ethclient.Client, method string, block uint64, tested []string) (WitnessBlock, error) {
+	wb := WitnessBlock{Measurement: witness.Measurement{Block: block}, Tested: tested}
+	header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+	if err != nil {
+		return wb, fmt.Errorf("failed to get block: %w", err)
+	}
+	wb.GasUsed = header.GasUsed
+	if wb.Transactions, err = client.TransactionCount(ctx, header.Hash()); err != nil {
+		return wb, fmt.Errorf("failed to count transactions: %w", err)
+	}
+	m, err := witness.Fetch(ctx, client.Client(), method, block)
+	if err != nil {
+		return wb, err
+	}
+	wb.Measurement = m
+	if wb.GasUsed > 0 {
+		wb.BytesPerGas = float64(m.Size) / float64(wb.GasUsed)
+	}
+	return wb, nil
+}
+
+// testedBlocks maps the blocks of --blocks, or else the blocks the stage 2,
+// stage 4 and broadcast results mined test transactions in, to the hashes
+// of those transactions.
+func testedBlocks(list string) (map[uint64][]string, error) {
+	blocks := map[uint64][]string{}
+	if list != "" {
+		for _, s := range tags.Parse(list) {
+			b, err := strconv.ParseUint(s, 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid block %q in --blocks", s)
+			}
+			blocks[b] = []string{}
+		}
+		return blocks, nil
+	}
+
+	type mined struct {
+		TransactionHash string `json:"transactionHash"`
+		BlockNumber     uint64 `json:"blockNumber"`
+	}
+	var txs []mined
+	read := func(name string, v any) error {
+		data, err := os.ReadFile(paths.Work(name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		return nil
+	}
+	var deployment mined
+	var stage4 []mined
+	var broadcast struct {
+		Transactions []mined `json:"transactions"`
+	}
+	if err := read("results_stage2.json", &deployment); err != nil {
+		return nil, err
+	}
+	if err := read("results_stage4.json", &stage4); err != nil {
+		return nil, err
+	}
+	if err := read("results_broadcast.json", &broadcast); err != nil {
+		return nil, err
+	}
+	txs = append(append(append(txs, deployment), stage4...), broadcast.Transactions...)
+	for _, tx := range txs {
+		if tx.TransactionHash != "" && tx.BlockNumber > 0 {
+			blocks[tx.BlockNumber] = append(blocks[tx.BlockNumber], tx.TransactionHash)
+		}
+	}
+	return blocks, nil
+}
+
+func average(sizes []int) float64 {
+	if len(sizes) == 0 {
+		return 0
+	}
+	total := 0
+	for _, s := range sizes {
+		total += s
+	}
+	return float64(total) / float64(len(sizes))
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+
+	"cdk-erigon-precompile/pkg/anchor"
+	"cdk-erigon-precompile/pkg/envfile"
+	"cdk-erigon-precompile/pkg/memexp"
+	"cdk-erigon-precompile/pkg/output"
+	"cdk-erigon-precompile/pkg/paths"
+	"cdk-erigon-precompile/pkg/precompile"
+	"cdk-erigon-precompile/pkg/rpcclient"
+	"cdk-erigon-precompile/pkg/skip"
+	"cdk-erigon-precompile/pkg/tags"
+)
+
+// ZeroGasCase is one precompile called with a fixed amount of gas.
+type ZeroGasCase struct {
+	Name       string `json:"name"`
+	Precompile string `json:"precompile"`
+	// Cost is the precompile's price for the input; CallGas below it must
+	// fail the call and consume all of CallGas.
+	Cost    uint64 `json:"cost"`
+	CallGas uint64 `json:"callGas"`
+	// Success and GasUsed are the node's; GasUsed counts everything between
+	// the GAS readings around the call.
+	Success          bool     `json:"success"`
+	GasUsed          uint64   `json:"gasUsed"`
+	ReferenceSuccess bool     `json:"referenceSuccess"`
+	ReferenceGasUsed uint64   `json:"referenceGasUsed"`
+	Failures         []string `json:"failures,omitempty"`
+	Match            bool     `json:"match"`
+}
+
+type ZeroGasResult struct {
+	Stage      string        `json:"stage"`
+	Cases      []ZeroGasCase `json:"cases"`
+	Matches    int           `json:"matches"`
+	Mismatches int           `json:"mismatches"`
+	Timestamp  string        `json:"timestamp"`
+	RPCURL     string        `json:"rpcUrl"`
+}
+
+// zeroGasTarget is a precompile with a valid input. The output buffer
+// overlaps the input, which is at least as long, so the call never expands
+// memory and its gas is the precompile's alone.
+type zeroGasTarget struct {
+	name    string
+	address common.Address
+	input   []byte
+	outSize uint64
+}
+
+func main() {
+	output.Setup()
+
+	gas := flag.Uint64("gas", 1_000_000, "gas of each eth_call")
+	tagFilter := tags.Flags()
+	envFiles := envfile.Flags()
+	flag.Parse()
+
+	if !tagFilter.Match([]string{tags.Gas}) {
+		fmt.Printf("⏭️  Zero gas checks skipped by tag filter (%s)\n", tagFilter)
+		if err := skip.Record(skip.New(skip.TagFilter, tagFilter.String())); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+		return
+	}
+
+	// Load environment variables
+	if err := envFiles.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	targets, err := zeroGasTargets()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// Initialize Ethereum client
+	rpcHost := os.Getenv("RPC_HOST")
+	rpcPort := os.Getenv("RPC_PORT")
+	rpcURL := fmt.Sprintf("http://%s:%s", rpcHost, rpcPort)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := rpcclient.Connect(ctx, rpcURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Ethereum node at %s: %v", rpcURL, err)
+	}
+	defer client.Close()
+	fmt.Printf("✅ Connected to Ethereum node at %s\n", rpcURL)
+	anchors := anchor.Begin(ctx, client, "results_zerogas.json")
+
+	result := ZeroGasResult{
+		Stage:  "Zero Gas - Precompiles Called With No or Too Little Gas",
+		RPCURL: rpcURL,
+	}
+	for _, t := range targets {
+		cost := vm.PrecompiledContractsCancun[t.address].RequiredGas(t.input)
+		levels := []uint64{0, 1, cost - 1, cost}
+		if cost <= 2 {
+			levels = []uint64{0, cost}
+		}
+		for _, callGas := range levels {
+			c := memexp.Case{
+				Name:       fmt.Sprintf("%s/%d-gas", t.name, callGas),
+				Precompile: t.address,
+				Input:      t.input,
+				OutSize:    t.outSize,
+				Limited:    true,
+				CallGas:    callGas,
+			}
+			node, err := memexp.Call(ctx, client, c, *gas)
+			if err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+			ref, err := memexp.Reference(c, *gas)
+			if err != nil {
+				log.Fatalf("❌ Reference run of %s failed: %v", c.Name, err)
+			}
+			zc := checkZeroGas(c, cost, node, ref)
+			if zc.Match {
+				result.Matches++
+				fmt.Printf("✅ %-28s success=%t gas %d\n", zc.Name, zc.Success, zc.GasUsed)
+			} else {
+				result.Mismatches++
+				fmt.Printf("❌ %-28s %v\n", zc.Name, zc.Failures)
+			}
+			result.Cases = append(result.Cases, zc)
+		}
+	}
+	result.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	if err := saveZeroGasResult(result); err != nil {
+		log.Fatal(err)
+	}
+	anchors.Finish(ctx)
+	fmt.Printf("\n✅ Matches:    %d\n", result.Matches)
+	fmt.Printf("❌ Mismatches: %d\n", result.Mismatches)
+	fmt.Println("\n📝 Results saved to results_zerogas.json")
+	if result.Mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkZeroGas compares the node with go-ethereum's EVM and with the spec:
+// a call handed less than the cost fails with no return data and consumes
+// all the gas it was handed, and one handed the cost succeeds and consumes
+// exactly the cost.
+func checkZeroGas(c memexp.Case, cost uint64, node, ref memexp.Outcome) ZeroGasCase {
+	zc := ZeroGasCase{
+		Name:             c.Name,
+		Precompile:       c.Precompile.Hex(),
+		Cost:             cost,
+		CallGas:          c.CallGas,
+		Success:          node.Success,
+		GasUsed:          node.GasUsed,
+		ReferenceSuccess: ref.Success,
+		ReferenceGasUsed: ref.GasUsed,
+	}
+	fail := func(format string, args ...any) {
+		zc.Failures = append(zc.Failures, fmt.Sprintf(format, args...))
+	}
+	if node.Failed {
+		fail("program failed: %s", node.Error)
+		return zc
+	}
+	if sufficient := c.CallGas >= cost; node.Success != sufficient {
+		fail("success=%t with %d gas for a cost of %d", node.Success, c.CallGas, cost)
+	}
+	want := memexp.LimitedCallOverhead + min(c.CallGas, cost)
+	if node.GasUsed != want {
+		fail("used %d gas, want %d", node.GasUsed, want)
+	}
+	if !node.Success && node.ReturnSize != 0 {
+		fail("failed call returned %d bytes", node.ReturnSize)
+	}
+	if !node.Equal(ref) {
+		fail("reference success=%t gas %d", ref.Success, ref.GasUsed)
+	}
+	zc.Match = len(zc.Failures) == 0
+	return zc
+}
+
+// zeroGasTargets gives every precompile up to blake2f a valid input.
+func zeroGasTargets() ([]zeroGasTarget, error) {
+	sigs, err := precompile.RandomSignatures(1)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 64)
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+	word := func(n int64) []byte { return common.LeftPadBytes(big.NewInt(n).Bytes(), 32) }
+	// G1 is the bn256 generator (1, 2)
+	g1 := append(word(1), word(2)...)
+	pairing, _ := precompile.PairingInput(1)
+	modexp := precompile.ModExp{Base: []byte{3}, Exp: []byte{0xff, 0xff}, Mod: big.NewInt(1_000_000_007).Bytes()}
+	// BLAKE2 F with 12 rounds and zero state, message and counter; the
+	// final block flag is set
+	blake2f := binary.BigEndian.AppendUint32(nil, 12)
+	blake2f = append(blake2f, make([]byte, 64+128+16)...)
+	blake2f = append(blake2f, 1)
+
+	return []zeroGasTarget{
+		{"ecrecover", precompile.ECRecoverAddress, sigs[0].Input(), 32},
+		{"sha256", precompile.SHA256Address, data, 32},
+		{"ripemd160", common.BytesToAddress([]byte{3}), data, 32},
+		{"identity", common.BytesToAddress([]byte{4}), data, 64},
+		{"modexp", precompile.ModExpAddress, modexp.Input(), uint64(len(modexp.Mod))},
+		{"bn256add", common.BytesToAddress([]byte{6}), bytes.Repeat(g1, 2), 64},
+		{"bn256mul", common.BytesToAddress([]byte{7}), append(g1, word(2)...), 64},
+		{"bn256pairing", precompile.PairingAddress, pairing, 32},
+		{"blake2f", common.BytesToAddress([]byte{9}), blake2f, 64},
+	}, nil
+}
+
+func saveZeroGasResult(result ZeroGasResult) error {
+	file, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("❌ Failed to marshal results: %v", err)
+	}
+	if err := paths.WriteFile(paths.Work("results_zerogas.json"), file); err != nil {
+		return fmt.Errorf("❌ Failed to save results: %v", err)
+	}
+	return nil
+}